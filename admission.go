@@ -0,0 +1,223 @@
+package quic
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// AdmissionAction is the action the server takes for a new connection attempt.
+type AdmissionAction uint8
+
+const (
+	// AdmissionAccept accepts the connection attempt.
+	AdmissionAccept AdmissionAction = iota
+	// AdmissionRetry sends a Retry packet, forcing the client to prove ownership of its address.
+	// If the client's address was already validated, the connection attempt is accepted.
+	AdmissionRetry
+	// AdmissionRefuse refuses the connection attempt with a CONNECTION_REFUSED error.
+	AdmissionRefuse
+	// AdmissionDrop silently drops the packet.
+	AdmissionDrop
+)
+
+func (a AdmissionAction) String() string {
+	switch a {
+	case AdmissionAccept:
+		return "accept"
+	case AdmissionRetry:
+		return "retry"
+	case AdmissionRefuse:
+		return "refuse"
+	case AdmissionDrop:
+		return "drop"
+	default:
+		return fmt.Sprintf("unknown admission action: %d", a)
+	}
+}
+
+// AdmissionInfo contains information about a new connection attempt.
+type AdmissionInfo struct {
+	// RemoteAddr is the remote address on the Initial packet.
+	// Unless AddrVerified is set, the address might be spoofed.
+	RemoteAddr net.Addr
+	// AddrVerified says if the remote address was verified using an address validation token,
+	// either from a Retry packet or from a NEW_TOKEN frame.
+	AddrVerified bool
+	// HandshakesInFlight is the number of connections that are currently handshaking.
+	HandshakesInFlight int
+}
+
+// An AdmissionDecision is the decision of an [AdmissionController].
+type AdmissionDecision struct {
+	Action AdmissionAction
+	// Reason is sent as the reason phrase of the CONNECTION_CLOSE frame when refusing a connection attempt.
+	// It is also logged to the qlog.
+	Reason string
+}
+
+// An AdmissionController decides which new connection attempts a server accepts.
+// It is called for every Initial packet that would create a new connection,
+// after the address validation token was checked, and after [Transport.VerifySourceAddress] was called.
+// It is called from the server's packet handling loop, and should therefore return quickly.
+type AdmissionController interface {
+	Admit(*AdmissionInfo) AdmissionDecision
+}
+
+const (
+	defaultAdmissionIPv4PrefixLen = 24
+	defaultAdmissionIPv6PrefixLen = 48
+	// the number of tracked source prefixes above which idle token buckets are cleaned up
+	admissionBucketCleanupThreshold = 1 << 12
+)
+
+// An AdmissionLimiter is an [AdmissionController] that
+//  1. refuses connection attempts if too many handshakes are in flight,
+//  2. sends a Retry to clients with unvalidated addresses when the server is busy handshaking, and
+//  3. rate limits new connections per source address prefix, using a token bucket for every prefix.
+//
+// Since the source address of clients that haven't validated their address might be spoofed,
+// these connection attempts are accounted for separately from the attempts of validated clients.
+// Once the limit for unvalidated attempts is exceeded, these clients have to validate their address
+// using a Retry. Spoofed packets therefore can't be used to exhaust the limit of a victim's prefix.
+//
+// The zero value accepts all connection attempts.
+// The fields must not be modified after the AdmissionLimiter was first used.
+type AdmissionLimiter struct {
+	// MaxHandshakes is the maximum number of concurrent handshakes.
+	// Connection attempts exceeding this limit are refused.
+	// If zero, the number of concurrent handshakes is not limited.
+	MaxHandshakes int
+	// RetryThreshold is the number of concurrent handshakes above which
+	// clients have to validate their address using a Retry before the handshake is started.
+	// If zero, no Retry packets are sent.
+	RetryThreshold int
+
+	// Rate is the number of new connections per second allowed from a single source prefix.
+	// If zero, new connections are not rate limited.
+	Rate float64
+	// Burst is the number of new connections from a single source prefix that are allowed in quick succession.
+	// If zero, it defaults to Rate (but at least 1).
+	Burst int
+	// IPv4PrefixLen is the length of the IPv4 prefix used for rate limiting.
+	// If zero, it defaults to 24.
+	IPv4PrefixLen int
+	// IPv6PrefixLen is the length of the IPv6 prefix used for rate limiting.
+	// If zero, it defaults to 48.
+	IPv6PrefixLen int
+
+	mutex       sync.Mutex
+	buckets     map[bucketKey]*tokenBucket
+	lastCleanup time.Time
+}
+
+var _ AdmissionController = &AdmissionLimiter{}
+
+type bucketKey struct {
+	prefix       netip.Prefix
+	addrVerified bool
+}
+
+type tokenBucket struct {
+	tokens     float64
+	lastUpdate time.Time
+}
+
+// Admit implements the [AdmissionController] interface.
+func (l *AdmissionLimiter) Admit(info *AdmissionInfo) AdmissionDecision {
+	if l.MaxHandshakes > 0 && info.HandshakesInFlight >= l.MaxHandshakes {
+		return AdmissionDecision{Action: AdmissionRefuse, Reason: "too many handshakes"}
+	}
+	// Retry is handled before rate limiting.
+	// Otherwise, an attacker could use spoofed addresses to exhaust the token bucket of a victim.
+	if l.RetryThreshold > 0 && info.HandshakesInFlight >= l.RetryThreshold && !info.AddrVerified {
+		return AdmissionDecision{Action: AdmissionRetry}
+	}
+	if l.Rate > 0 && !l.allow(info.RemoteAddr, info.AddrVerified, time.Now()) {
+		if !info.AddrVerified {
+			return AdmissionDecision{Action: AdmissionRetry}
+		}
+		return AdmissionDecision{Action: AdmissionRefuse, Reason: "rate limited"}
+	}
+	return AdmissionDecision{Action: AdmissionAccept}
+}
+
+func (l *AdmissionLimiter) allow(addr net.Addr, addrVerified bool, now time.Time) bool {
+	prefix, ok := l.prefix(addr)
+	if !ok {
+		return true
+	}
+	burst := float64(l.Burst)
+	if burst == 0 {
+		burst = max(1, l.Rate)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = make(map[bucketKey]*tokenBucket)
+	}
+	if len(l.buckets) >= admissionBucketCleanupThreshold && now.Sub(l.lastCleanup) > time.Second {
+		l.cleanup(now, burst)
+	}
+	key := bucketKey{prefix: prefix, addrVerified: addrVerified}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, lastUpdate: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.lastUpdate).Seconds()*l.Rate)
+	b.lastUpdate = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// cleanup removes the token buckets that are full again.
+// Removing them doesn't change the rate limiting behavior.
+func (l *AdmissionLimiter) cleanup(now time.Time, burst float64) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.lastUpdate).Seconds()*l.Rate >= burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastCleanup = now
+}
+
+func (l *AdmissionLimiter) prefix(addr net.Addr) (netip.Prefix, bool) {
+	var ip netip.Addr
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip, _ = netip.AddrFromSlice(a.IP)
+	default:
+		addrPort, err := netip.ParseAddrPort(addr.String())
+		if err != nil {
+			return netip.Prefix{}, false
+		}
+		ip = addrPort.Addr()
+	}
+	if !ip.IsValid() {
+		return netip.Prefix{}, false
+	}
+	ip = ip.Unmap()
+	bits := l.IPv6PrefixLen
+	if bits == 0 {
+		bits = defaultAdmissionIPv6PrefixLen
+	}
+	if ip.Is4() {
+		bits = l.IPv4PrefixLen
+		if bits == 0 {
+			bits = defaultAdmissionIPv4PrefixLen
+		}
+	}
+	prefix, err := ip.Prefix(bits)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
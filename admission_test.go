@@ -0,0 +1,131 @@
+package quic
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmissionLimiterZeroValue(t *testing.T) {
+	var l AdmissionLimiter
+	for i := range 100 {
+		require.Equal(t,
+			AdmissionDecision{Action: AdmissionAccept},
+			l.Admit(&AdmissionInfo{RemoteAddr: &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}, HandshakesInFlight: i}),
+		)
+	}
+}
+
+func TestAdmissionLimiterHandshakeLimits(t *testing.T) {
+	l := &AdmissionLimiter{MaxHandshakes: 10, RetryThreshold: 5}
+	addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+
+	require.Equal(t, AdmissionAccept, l.Admit(&AdmissionInfo{RemoteAddr: addr, HandshakesInFlight: 4}).Action)
+	require.Equal(t, AdmissionRetry, l.Admit(&AdmissionInfo{RemoteAddr: addr, HandshakesInFlight: 5}).Action)
+	// clients that validated their address don't need to do a Retry
+	require.Equal(t, AdmissionAccept, l.Admit(&AdmissionInfo{RemoteAddr: addr, HandshakesInFlight: 5, AddrVerified: true}).Action)
+	require.Equal(t,
+		AdmissionDecision{Action: AdmissionRefuse, Reason: "too many handshakes"},
+		l.Admit(&AdmissionInfo{RemoteAddr: addr, HandshakesInFlight: 10, AddrVerified: true}),
+	)
+}
+
+func TestAdmissionLimiterRateLimiting(t *testing.T) {
+	l := &AdmissionLimiter{Rate: 2, Burst: 3}
+	now := time.Now()
+	addr1 := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+	addr2 := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 200), Port: 4321} // same /24 as addr1
+	addr3 := &net.UDPAddr{IP: net.IPv4(198, 51, 100, 1), Port: 1234}
+
+	require.True(t, l.allow(addr1, true, now))
+	require.True(t, l.allow(addr2, true, now))
+	require.True(t, l.allow(addr1, true, now))
+	require.False(t, l.allow(addr2, true, now))
+	// other prefixes are not affected
+	require.True(t, l.allow(addr3, true, now))
+
+	// tokens are replenished over time
+	require.True(t, l.allow(addr1, true, now.Add(500*time.Millisecond)))
+	require.False(t, l.allow(addr1, true, now.Add(500*time.Millisecond)))
+	// but never beyond the burst size
+	now = now.Add(time.Hour)
+	for range 3 {
+		require.True(t, l.allow(addr1, true, now))
+	}
+	require.False(t, l.allow(addr1, true, now))
+}
+
+func TestAdmissionLimiterRefuseReason(t *testing.T) {
+	l := &AdmissionLimiter{Rate: 1}
+	addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+	require.Equal(t, AdmissionAccept, l.Admit(&AdmissionInfo{RemoteAddr: addr, AddrVerified: true}).Action)
+	require.Equal(t,
+		AdmissionDecision{Action: AdmissionRefuse, Reason: "rate limited"},
+		l.Admit(&AdmissionInfo{RemoteAddr: addr, AddrVerified: true}),
+	)
+}
+
+func TestAdmissionLimiterUnverifiedAddresses(t *testing.T) {
+	l := &AdmissionLimiter{Rate: 1, Burst: 2}
+	addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+	// Connection attempts from (potentially spoofed) unverified addresses exhaust their own limit ...
+	require.Equal(t, AdmissionAccept, l.Admit(&AdmissionInfo{RemoteAddr: addr}).Action)
+	require.Equal(t, AdmissionAccept, l.Admit(&AdmissionInfo{RemoteAddr: addr}).Action)
+	// ... after which the client needs to validate its address using a Retry,
+	require.Equal(t, AdmissionDecision{Action: AdmissionRetry}, l.Admit(&AdmissionInfo{RemoteAddr: addr}))
+	// ... but they don't count towards the limit for verified addresses.
+	require.Equal(t, AdmissionAccept, l.Admit(&AdmissionInfo{RemoteAddr: addr, AddrVerified: true}).Action)
+	require.Equal(t, AdmissionAccept, l.Admit(&AdmissionInfo{RemoteAddr: addr, AddrVerified: true}).Action)
+	require.Equal(t, AdmissionRefuse, l.Admit(&AdmissionInfo{RemoteAddr: addr, AddrVerified: true}).Action)
+}
+
+func TestAdmissionLimiterPrefixes(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		var l AdmissionLimiter
+		prefix, ok := l.prefix(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 42), Port: 1234})
+		require.True(t, ok)
+		require.Equal(t, netip.MustParsePrefix("192.0.2.0/24"), prefix)
+
+		prefix, ok = l.prefix(&net.UDPAddr{IP: net.ParseIP("2001:db8:1:2::1"), Port: 1234})
+		require.True(t, ok)
+		require.Equal(t, netip.MustParsePrefix("2001:db8:1::/48"), prefix)
+	})
+
+	t.Run("custom prefix lengths", func(t *testing.T) {
+		l := &AdmissionLimiter{IPv4PrefixLen: 32, IPv6PrefixLen: 64}
+		prefix, ok := l.prefix(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 42), Port: 1234})
+		require.True(t, ok)
+		require.Equal(t, netip.MustParsePrefix("192.0.2.42/32"), prefix)
+
+		prefix, ok = l.prefix(&net.UDPAddr{IP: net.ParseIP("2001:db8:1:2::1"), Port: 1234})
+		require.True(t, ok)
+		require.Equal(t, netip.MustParsePrefix("2001:db8:1:2::/64"), prefix)
+	})
+
+	t.Run("non-UDP addresses", func(t *testing.T) {
+		var l AdmissionLimiter
+		prefix, ok := l.prefix(&net.TCPAddr{IP: net.IPv4(192, 0, 2, 42), Port: 1234})
+		require.True(t, ok)
+		require.Equal(t, netip.MustParsePrefix("192.0.2.0/24"), prefix)
+
+		_, ok = l.prefix(&net.UnixAddr{Name: "/tmp/sock", Net: "unix"})
+		require.False(t, ok)
+	})
+}
+
+func TestAdmissionLimiterCleanup(t *testing.T) {
+	l := &AdmissionLimiter{Rate: 10}
+	now := time.Now()
+	for i := range admissionBucketCleanupThreshold {
+		addr := &net.UDPAddr{IP: net.IPv4(10, byte(i>>8), byte(i), 1), Port: 1234}
+		require.True(t, l.allow(addr, true, now))
+	}
+	require.Len(t, l.buckets, admissionBucketCleanupThreshold)
+
+	// once the buckets are refilled, they are removed
+	require.True(t, l.allow(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}, true, now.Add(time.Minute)))
+	require.Len(t, l.buckets, 1)
+}
@@ -0,0 +1,80 @@
+// qlogconvert converts binary qlog traces (written by qlogwriter.BinaryFileSeq) to JSON-SEQ qlog files.
+//
+// Usage:
+//
+//	qlogconvert trace.bqlog...
+//	qlogconvert - < trace.bqlog > trace.sqlog
+//
+// Every trace is converted to a file with the same name and the .sqlog extension.
+// If the file name is -, the trace is read from stdin and written to stdout.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nukilabs/quic-go/qlogwriter"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s trace.bqlog...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var failed bool
+	for _, path := range flag.Args() {
+		if path == "-" {
+			if err := qlogwriter.ConvertBinary(os.Stdout, os.Stdin); err != nil {
+				log.Printf("stdin: %s", err)
+				failed = true
+			}
+			continue
+		}
+		out, err := convert(path)
+		if err != nil {
+			log.Printf("%s: %s", path, err)
+			failed = true
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				log.Printf("%s: the trace is truncated, converted the complete events to %s", path, out)
+			}
+			continue
+		}
+		log.Printf("%s: converted to %s", path, out)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func convert(path string) (string, error) {
+	out := strings.TrimSuffix(path, filepath.Ext(path)) + ".sqlog"
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	f, err := os.Create(out)
+	if err != nil {
+		return "", err
+	}
+	if err := qlogwriter.ConvertBinary(f, in); err != nil {
+		f.Close()
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			os.Remove(out)
+		}
+		return out, err
+	}
+	return out, f.Close()
+}
@@ -0,0 +1,225 @@
+// qlogstat summarizes qlog traces written by quic-go.
+//
+// Usage:
+//
+//	qlogstat [-json] [-samples n] trace.sqlog...
+//
+// For every trace, it prints the handshake timeline, the evolution of the RTT and the congestion window,
+// loss episodes, stream lifetimes and the reason the connection was closed.
+// Traces compressed using gzip (.sqlog.gz) or zstd (.sqlog.zst) are decompressed transparently.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nukilabs/quic-go/qlog"
+	"github.com/nukilabs/quic-go/qlogreader"
+)
+
+// jsonOutput is printed when the -json flag is set.
+// Durations are encoded in nanoseconds.
+type jsonOutput struct {
+	File          string
+	GroupID       string `json:",omitempty"`
+	ReferenceTime time.Time
+	*qlogreader.Summary
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the summary as JSON (durations are in nanoseconds)")
+	samples := flag.Int("samples", 20, "maximum number of RTT / congestion window samples to print (0 prints all samples)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] trace.sqlog...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var failed bool
+	for i, path := range flag.Args() {
+		trace, err := qlogreader.ReadFile(path)
+		if err != nil {
+			if trace == nil {
+				log.Printf("%s: %s", path, err)
+				failed = true
+				continue
+			}
+			// print the events that were parsed before the error
+			log.Printf("%s: %s (showing the first %d events)", path, err, len(trace.Events))
+			failed = true
+		}
+		summary := qlogreader.Summarize(trace)
+		if *asJSON {
+			out := jsonOutput{
+				File:          path,
+				ReferenceTime: trace.Header.ReferenceTime,
+				Summary:       summary,
+			}
+			if trace.Header.GroupID != nil {
+				out.GroupID = trace.Header.GroupID.String()
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(out); err != nil {
+				log.Fatal(err)
+			}
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		printSummary(os.Stdout, path, trace.Header, summary, *samples)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func printSummary(w io.Writer, path string, hdr qlogreader.Header, s *qlogreader.Summary, maxSamples int) {
+	fmt.Fprintf(w, "%s\n", path)
+	fmt.Fprintf(w, "vantage point: %s\n", hdr.VantagePoint)
+	if hdr.GroupID != nil {
+		fmt.Fprintf(w, "group ID: %s\n", hdr.GroupID)
+	}
+	if !hdr.ReferenceTime.IsZero() {
+		fmt.Fprintf(w, "reference time: %s\n", hdr.ReferenceTime.Format(time.RFC3339Nano))
+	}
+	fmt.Fprintf(w, "duration: %s\n", formatDuration(s.Duration))
+	fmt.Fprintf(w, "packets: %d sent (%d bytes), %d received (%d bytes), %d lost, %d dropped\n",
+		s.PacketsSent, s.BytesSent, s.PacketsReceived, s.BytesReceived, s.PacketsLost, s.PacketsDropped)
+	if s.MaxPTOCount > 0 {
+		fmt.Fprintf(w, "max PTO count: %d\n", s.MaxPTOCount)
+	}
+
+	fmt.Fprintf(w, "\nhandshake:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, ev := range s.Handshake {
+		fmt.Fprintf(tw, "  %s\t  %s\t\n", formatDuration(ev.Time), ev.Milestone)
+	}
+	tw.Flush()
+
+	if len(s.Metrics) > 0 {
+		fmt.Fprintf(w, "\nRTT / congestion window:\n")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "  time\tmin RTT\tsmoothed RTT\tlatest RTT\tRTT variance\tcwnd\tbytes in flight\t\n")
+		for _, m := range downsample(s.Metrics, maxSamples) {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%d\t%d\t\n",
+				formatDuration(m.Time),
+				formatDuration(m.MinRTT),
+				formatDuration(m.SmoothedRTT),
+				formatDuration(m.LatestRTT),
+				formatDuration(m.RTTVariance),
+				m.CongestionWindow,
+				m.BytesInFlight,
+			)
+		}
+		tw.Flush()
+	}
+
+	if len(s.LossEpisodes) > 0 {
+		fmt.Fprintf(w, "\nloss episodes:\n")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "  start\tend\tpackets\t\n")
+		for _, l := range s.LossEpisodes {
+			fmt.Fprintf(tw, "  %s\t%s\t%d\t\n", formatDuration(l.Start), formatDuration(l.End), l.Packets)
+		}
+		tw.Flush()
+	}
+
+	if len(s.Streams) > 0 {
+		fmt.Fprintf(w, "\nstreams:\n")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "  ID\topened\tclosed\tsent\treceived\t  state\t\n")
+		for _, str := range s.Streams {
+			fmt.Fprintf(tw, "  %d\t%s\t%s\t%d\t%d\t  %s\t\n",
+				str.ID,
+				formatDuration(str.Opened),
+				formatDuration(str.Closed),
+				str.BytesSent,
+				str.BytesReceived,
+				streamState(str),
+			)
+		}
+		tw.Flush()
+	}
+
+	fmt.Fprintf(w, "\nclose: %s\n", closeReason(s.Close))
+}
+
+// downsample returns at most n samples, evenly spaced.
+// The last sample is always included.
+func downsample(samples []qlogreader.MetricsSample, n int) []qlogreader.MetricsSample {
+	if n <= 0 || len(samples) <= n {
+		return samples
+	}
+	if n == 1 {
+		return samples[len(samples)-1:]
+	}
+	res := make([]qlogreader.MetricsSample, 0, n)
+	for i := range n {
+		res = append(res, samples[i*(len(samples)-1)/(n-1)])
+	}
+	return res
+}
+
+func streamState(s qlogreader.StreamLifetime) string {
+	var state string
+	add := func(str string) {
+		if state != "" {
+			state += ", "
+		}
+		state += str
+	}
+	if s.FinSent {
+		add("FIN sent")
+	}
+	if s.ResetSent {
+		add("reset sent")
+	}
+	if s.FinReceived {
+		add("FIN received")
+	}
+	if s.ResetReceived {
+		add("reset received")
+	}
+	if state == "" {
+		return "open"
+	}
+	return state
+}
+
+func closeReason(c *qlog.ConnectionClosed) string {
+	if c == nil {
+		return "not closed"
+	}
+	var reason string
+	switch {
+	case c.ConnectionError != nil:
+		reason = fmt.Sprintf("%s transport error %s", c.Initiator, c.ConnectionError)
+	case c.ApplicationError != nil:
+		reason = fmt.Sprintf("%s application error %#x", c.Initiator, uint64(*c.ApplicationError))
+	default:
+		reason = string(c.Initiator)
+	}
+	if c.Trigger != "" {
+		reason += fmt.Sprintf(" (%s)", c.Trigger)
+	}
+	if c.Reason != "" {
+		reason += fmt.Sprintf(": %q", c.Reason)
+	}
+	return reason
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3fms", float64(d.Nanoseconds())/1e6)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/nukilabs/quic-go/internal/handshake"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/quicvarint"
 )
@@ -22,6 +23,14 @@ func (c *Config) maxRetryTokenAge() time.Duration {
 	return c.handshakeTimeout()
 }
 
+func (c *Config) keyUpdatePolicy() handshake.KeyUpdatePolicy {
+	return handshake.KeyUpdatePolicy{
+		MaxPackets: c.KeyUpdateMaxPackets,
+		MaxBytes:   c.KeyUpdateMaxBytes,
+		MaxAge:     c.KeyUpdateMaxAge,
+	}
+}
+
 func validateConfig(config *Config) error {
 	if config == nil {
 		return nil
@@ -39,6 +48,9 @@ func validateConfig(config *Config) error {
 	if config.MaxConnectionReceiveWindow > quicvarint.Max {
 		config.MaxConnectionReceiveWindow = quicvarint.Max
 	}
+	if config.KeyUpdateMaxPackets > protocol.KeyUpdateInterval {
+		config.KeyUpdateMaxPackets = protocol.KeyUpdateInterval
+	}
 	if config.InitialPacketSize > 0 && config.InitialPacketSize < protocol.MinInitialPacketSize {
 		config.InitialPacketSize = protocol.MinInitialPacketSize
 	}
@@ -100,6 +112,14 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	datagramSendQueueLen := config.DatagramSendQueueLen
+	if datagramSendQueueLen <= 0 {
+		datagramSendQueueLen = defaultDatagramSendQueueLen
+	}
+	datagramRcvQueueLen := config.DatagramReceiveQueueLen
+	if datagramRcvQueueLen <= 0 {
+		datagramRcvQueueLen = defaultDatagramRcvQueueLen
+	}
 	initialPacketSize := config.InitialPacketSize
 	if initialPacketSize == 0 {
 		initialPacketSize = protocol.InitialPacketSize
@@ -116,14 +136,22 @@ func populateConfig(config *Config) *Config {
 		InitialConnectionReceiveWindow:   initialConnectionReceiveWindow,
 		MaxConnectionReceiveWindow:       maxConnectionReceiveWindow,
 		AllowConnectionWindowIncrease:    config.AllowConnectionWindowIncrease,
+		MemoryBudget:                     config.MemoryBudget,
 		MaxIncomingStreams:               maxIncomingStreams,
 		MaxIncomingUniStreams:            maxIncomingUniStreams,
 		TokenStore:                       config.TokenStore,
 		EnableDatagrams:                  config.EnableDatagrams,
+		DatagramSendQueueLen:             datagramSendQueueLen,
+		DatagramReceiveQueueLen:          datagramRcvQueueLen,
+		DatagramQueuePolicy:              config.DatagramQueuePolicy,
 		InitialPacketSize:                initialPacketSize,
 		DisablePathMTUDiscovery:          config.DisablePathMTUDiscovery,
 		EnableStreamResetPartialDelivery: config.EnableStreamResetPartialDelivery,
 		Allow0RTT:                        config.Allow0RTT,
+		ZeroRTTReplayFilter:              config.ZeroRTTReplayFilter,
+		KeyUpdateMaxPackets:              config.KeyUpdateMaxPackets,
+		KeyUpdateMaxBytes:                config.KeyUpdateMaxBytes,
+		KeyUpdateMaxAge:                  config.KeyUpdateMaxAge,
 		Tracer:                           config.Tracer,
 	}
 }
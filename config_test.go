@@ -65,6 +65,16 @@ func TestConfigValidation(t *testing.T) {
 		require.NoError(t, validateConfig(conf))
 		require.Equal(t, uint16(protocol.MaxPacketBufferSize), conf.InitialPacketSize)
 	})
+
+	t.Run("key update packet limit", func(t *testing.T) {
+		conf := &Config{KeyUpdateMaxPackets: 1000}
+		require.NoError(t, validateConfig(conf))
+		require.Equal(t, uint64(1000), conf.KeyUpdateMaxPackets)
+
+		conf = &Config{KeyUpdateMaxPackets: protocol.KeyUpdateInterval + 1}
+		require.NoError(t, validateConfig(conf))
+		require.Equal(t, uint64(protocol.KeyUpdateInterval), conf.KeyUpdateMaxPackets)
+	})
 }
 
 func TestConfigHandshakeIdleTimeout(t *testing.T) {
@@ -118,6 +128,12 @@ func configWithNonZeroNonFunctionFields(t *testing.T) *Config {
 			f.Set(reflect.ValueOf(time.Second))
 		case "EnableDatagrams":
 			f.Set(reflect.ValueOf(true))
+		case "DatagramSendQueueLen":
+			f.Set(reflect.ValueOf(64))
+		case "DatagramReceiveQueueLen":
+			f.Set(reflect.ValueOf(256))
+		case "DatagramQueuePolicy":
+			f.Set(reflect.ValueOf(DatagramQueueDropOldest))
 		case "DisableVersionNegotiationPackets":
 			f.Set(reflect.ValueOf(true))
 		case "InitialPacketSize":
@@ -126,8 +142,18 @@ func configWithNonZeroNonFunctionFields(t *testing.T) *Config {
 			f.Set(reflect.ValueOf(true))
 		case "Allow0RTT":
 			f.Set(reflect.ValueOf(true))
+		case "ZeroRTTReplayFilter":
+			f.Set(reflect.ValueOf(NewReplayFilter(time.Minute, 100)))
 		case "EnableStreamResetPartialDelivery":
 			f.Set(reflect.ValueOf(true))
+		case "KeyUpdateMaxPackets":
+			f.Set(reflect.ValueOf(uint64(1000)))
+		case "KeyUpdateMaxBytes":
+			f.Set(reflect.ValueOf(uint64(1 << 30)))
+		case "KeyUpdateMaxAge":
+			f.Set(reflect.ValueOf(time.Hour))
+		case "MemoryBudget":
+			f.Set(reflect.ValueOf(NewMemoryBudget(1 << 30)))
 		default:
 			t.Fatalf("all fields must be accounted for, but saw unknown field %q", fn)
 		}
@@ -184,6 +210,9 @@ func TestConfigDefaultValues(t *testing.T) {
 	require.EqualValues(t, protocol.DefaultMaxReceiveConnectionFlowControlWindow, c.MaxConnectionReceiveWindow)
 	require.EqualValues(t, protocol.DefaultMaxIncomingStreams, c.MaxIncomingStreams)
 	require.EqualValues(t, protocol.DefaultMaxIncomingUniStreams, c.MaxIncomingUniStreams)
+	require.Equal(t, defaultDatagramSendQueueLen, c.DatagramSendQueueLen)
+	require.Equal(t, defaultDatagramRcvQueueLen, c.DatagramReceiveQueueLen)
+	require.Equal(t, DatagramQueueBlock, c.DatagramQueuePolicy)
 	require.False(t, c.DisablePathMTUDiscovery)
 	require.Nil(t, c.GetConfigForClient)
 }
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/nukilabs/quic-go/internal/ackhandler"
+	"github.com/nukilabs/quic-go/internal/congestion"
 	"github.com/nukilabs/quic-go/internal/flowcontrol"
 	"github.com/nukilabs/quic-go/internal/handshake"
 	"github.com/nukilabs/quic-go/internal/monotime"
@@ -35,8 +36,10 @@ type unpacker interface {
 type cryptoStreamHandler interface {
 	StartHandshake(context.Context) error
 	ChangeConnectionID(protocol.ConnectionID)
+	ChangeVersion(protocol.Version)
 	SetLargest1RTTAcked(protocol.PacketNumber) error
 	SetHandshakeConfirmed()
+	InitiateKeyUpdate()
 	GetSessionTicket() ([]byte, error)
 	NextEvent() handshake.Event
 	DiscardInitialKeys()
@@ -136,9 +139,16 @@ type Conn struct {
 	version     protocol.Version
 	config      *Config
 
-	conn      sendConn
+	conn      *pathSendConn
 	sendQueue sender
 
+	// statistics for the current path on the Transport used during the handshake
+	initialPathStats     atomic.Pointer[pathStats]
+	initialPathLocalAddr net.Addr
+	// the paths the server migrated away from, starting with the path used during the handshake
+	previousPathsMx sync.Mutex
+	previousPaths   []previousPath
+
 	// lazily initialzed: most connections never migrate
 	pathManager         *pathManager
 	largestRcvdAppData  protocol.PacketNumber
@@ -160,12 +170,20 @@ type Conn struct {
 	tokenStoreKey         string                    // only set for the client
 	tokenGenerator        *handshake.TokenGenerator // only set for the server
 
+	// The initial receive windows advertised to the peer.
+	// They are smaller than configured if the MemoryBudget is under pressure.
+	connReceiveWindow   protocol.ByteCount
+	streamReceiveWindow protocol.ByteCount
+	reservedMemory      protocol.ByteCount // reserved from the MemoryBudget, only accessed from the run loop
+
 	unpacker      unpacker
 	frameParser   wire.FrameParser
 	packer        packer
 	mtuDiscoverer mtuDiscoverer // initialized when the transport parameters are received
 
 	currentMTUEstimate atomic.Uint32
+	// set by UpdateKeys, and passed to the crypto setup once the handshake is complete
+	keyUpdateRequested atomic.Bool
 
 	initialStream       *initialCryptoStream
 	handshakeStream     *cryptoStream
@@ -196,7 +214,12 @@ type Conn struct {
 
 	receivedRetry       bool
 	versionNegotiated   bool
+	versionUpgraded     bool // a compatible version upgrade was performed (RFC 9368)
 	receivedFirstPacket bool
+	// Only set for the server after a compatible version upgrade.
+	// Initial packets sent using the original version are accepted until the first packet
+	// sent using the negotiated version has been processed.
+	originalVersion protocol.Version
 
 	blocked blockMode
 
@@ -223,6 +246,7 @@ type Conn struct {
 	connStateMutex sync.Mutex
 	connState      ConnectionState
 
+	tracingID ConnectionTracingID
 	logID     string
 	qlogTrace qlogwriter.Trace
 	qlogger   qlogwriter.Recorder
@@ -231,6 +255,14 @@ type Conn struct {
 
 var _ streamSender = &Conn{}
 
+// A ConnectionTracingID identifies a connection within the process.
+// IDs are assigned sequentially when the connection is created, starting at 1, and are never reused.
+type ConnectionTracingID uint64
+
+var lastConnTracingID atomic.Uint64
+
+func nextConnTracingID() ConnectionTracingID { return ConnectionTracingID(lastConnTracingID.Add(1)) }
+
 type connTestHooks struct {
 	run                     func() error
 	earlyConnReady          func() <-chan struct{}
@@ -270,7 +302,6 @@ var newConnection = func(
 	s := &Conn{
 		ctx:                 ctx,
 		ctxCancel:           ctxCancel,
-		conn:                conn,
 		config:              conf,
 		handshakeDestConnID: destConnID,
 		srcConnIDLen:        srcConnID.Len(),
@@ -280,7 +311,11 @@ var newConnection = func(
 		qlogTrace:           qlogTrace,
 		logger:              logger,
 		version:             v,
+		tracingID:           nextConnTracingID(),
 	}
+	s.initialPathStats.Store(&pathStats{})
+	s.conn = newPathSendConn(conn, s.initialPathStats.Load())
+	s.initialPathLocalAddr = conn.LocalAddr()
 	if qlogTrace != nil {
 		s.qlogger = qlogTrace.AddProducer()
 	}
@@ -325,10 +360,10 @@ var newConnection = func(
 	s.currentMTUEstimate.Store(uint32(estimateMaxPayloadSize(protocol.ByteCount(s.config.InitialPacketSize))))
 	statelessResetToken := statelessResetter.GetStatelessResetToken(srcConnID)
 	params := &wire.TransportParameters{
-		InitialMaxStreamDataBidiLocal:   protocol.ByteCount(s.config.InitialStreamReceiveWindow),
-		InitialMaxStreamDataBidiRemote:  protocol.ByteCount(s.config.InitialStreamReceiveWindow),
-		InitialMaxStreamDataUni:         protocol.ByteCount(s.config.InitialStreamReceiveWindow),
-		InitialMaxData:                  protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
+		InitialMaxStreamDataBidiLocal:   s.streamReceiveWindow,
+		InitialMaxStreamDataBidiRemote:  s.streamReceiveWindow,
+		InitialMaxStreamDataUni:         s.streamReceiveWindow,
+		InitialMaxData:                  s.connReceiveWindow,
 		MaxIdleTimeout:                  s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:                protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                 protocol.StreamNum(s.config.MaxIncomingUniStreams),
@@ -346,6 +381,10 @@ var newConnection = func(
 		InitialSourceConnectionID: srcConnID,
 		RetrySourceConnectionID:   retrySrcConnID,
 		EnableResetStreamAt:       conf.EnableStreamResetPartialDelivery,
+		VersionInformation: &wire.VersionInformation{
+			ChosenVersion:     v,
+			AvailableVersions: conf.Versions,
+		},
 	}
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = wire.MaxDatagramSize
@@ -362,6 +401,8 @@ var newConnection = func(
 		params,
 		tlsConf,
 		conf.Allow0RTT,
+		conf.ZeroRTTReplayFilter,
+		s.keyUpdatePolicy(),
 		s.rttStats,
 		s.qlogger,
 		logger,
@@ -393,7 +434,6 @@ var newClientConnection = func(
 	v protocol.Version,
 ) *wrappedConn {
 	s := &Conn{
-		conn:                conn,
 		config:              conf,
 		origDestConnID:      destConnID,
 		handshakeDestConnID: destConnID,
@@ -404,7 +444,11 @@ var newClientConnection = func(
 		qlogTrace:           qlogTrace,
 		versionNegotiated:   hasNegotiatedVersion,
 		version:             v,
+		tracingID:           nextConnTracingID(),
 	}
+	s.initialPathStats.Store(&pathStats{})
+	s.conn = newPathSendConn(conn, s.initialPathStats.Load())
+	s.initialPathLocalAddr = conn.LocalAddr()
 	if qlogTrace != nil {
 		s.qlogger = qlogTrace.AddProducer()
 	}
@@ -454,10 +498,10 @@ var newClientConnection = func(
 	s.currentMTUEstimate.Store(uint32(estimateMaxPayloadSize(protocol.ByteCount(s.config.InitialPacketSize))))
 	oneRTTStream := newCryptoStream()
 	params := &wire.TransportParameters{
-		InitialMaxStreamDataBidiRemote: protocol.ByteCount(s.config.InitialStreamReceiveWindow),
-		InitialMaxStreamDataBidiLocal:  protocol.ByteCount(s.config.InitialStreamReceiveWindow),
-		InitialMaxStreamDataUni:        protocol.ByteCount(s.config.InitialStreamReceiveWindow),
-		InitialMaxData:                 protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
+		InitialMaxStreamDataBidiRemote: s.streamReceiveWindow,
+		InitialMaxStreamDataBidiLocal:  s.streamReceiveWindow,
+		InitialMaxStreamDataUni:        s.streamReceiveWindow,
+		InitialMaxData:                 s.connReceiveWindow,
 		MaxIdleTimeout:                 s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:               protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                protocol.StreamNum(s.config.MaxIncomingUniStreams),
@@ -472,6 +516,10 @@ var newClientConnection = func(
 		ActiveConnectionIDLimit:   protocol.MaxActiveConnectionIDs,
 		InitialSourceConnectionID: srcConnID,
 		EnableResetStreamAt:       conf.EnableStreamResetPartialDelivery,
+		VersionInformation: &wire.VersionInformation{
+			ChosenVersion:     v,
+			AvailableVersions: conf.Versions,
+		},
 	}
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = wire.MaxDatagramSize
@@ -486,6 +534,7 @@ var newClientConnection = func(
 		params,
 		tlsConf,
 		enable0RTT,
+		s.keyUpdatePolicy(),
 		s.rttStats,
 		s.qlogger,
 		logger,
@@ -521,14 +570,26 @@ func (c *Conn) preSetup() {
 		false, // ACK_FREQUENCY is not supported yet
 	)
 	c.rttStats = utils.NewRTTStats()
+	c.connReceiveWindow = protocol.ByteCount(c.config.InitialConnectionReceiveWindow)
+	c.streamReceiveWindow = protocol.ByteCount(c.config.InitialStreamReceiveWindow)
+	if c.config.MemoryBudget != nil {
+		// The memory is only reserved when the connection is run.
+		c.connReceiveWindow, c.streamReceiveWindow = c.config.MemoryBudget.receiveWindows(c.connReceiveWindow, c.streamReceiveWindow)
+	}
 	c.connFlowController = flowcontrol.NewConnectionFlowController(
-		protocol.ByteCount(c.config.InitialConnectionReceiveWindow),
+		c.connReceiveWindow,
 		protocol.ByteCount(c.config.MaxConnectionReceiveWindow),
 		func(size protocol.ByteCount) bool {
-			if c.config.AllowConnectionWindowIncrease == nil {
-				return true
+			if c.config.AllowConnectionWindowIncrease != nil && !c.config.AllowConnectionWindowIncrease(c, uint64(size)) {
+				return false
+			}
+			if c.config.MemoryBudget != nil {
+				if !c.config.MemoryBudget.tryReserve(size) {
+					return false
+				}
+				c.reservedMemory += size
 			}
-			return c.config.AllowConnectionWindowIncrease(c, uint64(size))
+			return true
 		},
 		c.rttStats,
 		c.logger,
@@ -556,7 +617,13 @@ func (c *Conn) preSetup() {
 
 	c.receivedPacketHandler = *ackhandler.NewReceivedPacketHandler(c.logger)
 
-	c.datagramQueue = newDatagramQueue(c.scheduleSending, c.logger)
+	c.datagramQueue = newDatagramQueue(
+		c.scheduleSending,
+		c.config.DatagramSendQueueLen,
+		c.config.DatagramReceiveQueueLen,
+		c.config.DatagramQueuePolicy,
+		c.logger,
+	)
 	c.connState.Version = c.version
 }
 
@@ -576,6 +643,12 @@ func (c *Conn) run() (err error) {
 		}
 	}()
 
+	if c.config.MemoryBudget != nil {
+		c.config.MemoryBudget.reserve(c.connReceiveWindow)
+		c.reservedMemory = c.connReceiveWindow
+		defer func() { c.config.MemoryBudget.release(c.reservedMemory) }()
+	}
+
 	c.timer = time.NewTimer(monotime.Until(c.idleTimeoutStartTime().Add(c.config.HandshakeIdleTimeout)))
 
 	if err := c.cryptoStreamHandler.StartHandshake(c.ctx); err != nil {
@@ -705,9 +778,9 @@ runLoop:
 		if c.perspective == protocol.PerspectiveClient {
 			pm := c.pathManagerOutgoing.Load()
 			if pm != nil {
-				tr, ok := pm.ShouldSwitchPath()
+				tr, stats, ok := pm.ShouldSwitchPath()
 				if ok {
-					c.switchToNewPath(tr, now)
+					c.switchToNewPath(tr, stats, now)
 				}
 			}
 		}
@@ -788,6 +861,12 @@ func (c *Conn) ConnectionState() ConnectionState {
 	return c.connState
 }
 
+func (c *Conn) keyUpdatePolicy() handshake.KeyUpdatePolicy {
+	policy := c.config.keyUpdatePolicy()
+	policy.OnKeyUpdate = func() { c.connStats.KeyUpdates.Add(1) }
+	return policy
+}
+
 // ConnectionStats contains statistics about the QUIC connection
 type ConnectionStats struct {
 	// MinRTT is the estimate of the minimum RTT observed on the active network
@@ -825,9 +904,55 @@ type ConnectionStats struct {
 	// (does not monotonically increase, because packets that are declared lost
 	// can subsequently be received).
 	PacketsLost uint64
+	// PacketsSpuriouslyLost is the number of packets that were declared lost,
+	// and were acknowledged afterwards.
+	PacketsSpuriouslyLost uint64
+	// BytesRetransmitted is the number of bytes of stream data that were retransmitted.
+	BytesRetransmitted uint64
+	// PTOCount is the number of probe timeouts (PTOs) that fired.
+	PTOCount uint64
+
+	// CongestionWindow is the current congestion window, in bytes.
+	CongestionWindow uint64
+	// BytesInFlight is the number of bytes sent in packets that have not yet been
+	// acknowledged or declared lost.
+	BytesInFlight uint64
+	// PacingRate is the rate at which packets are paced, in bytes per second.
+	PacingRate uint64
+	// MTU is the current maximum datagram size, as determined by DPLPMTUD.
+	MTU uint64
+
+	// ECT0Count, ECT1Count and ECNCECount are the ECN counts reported by the peer
+	// in ACK frames for 1-RTT packets.
+	ECT0Count  uint64
+	ECT1Count  uint64
+	ECNCECount uint64
+
+	// KeyUpdates is the number of 1-RTT key updates, initiated by either endpoint.
+	KeyUpdates uint64
+
+	// ConnectionFlowControlBlockedTime is the total time that sending was blocked by
+	// connection-level flow control.
+	ConnectionFlowControlBlockedTime time.Duration
+	// StreamFlowControlBlockedTime is the total time that streams were blocked by
+	// stream-level flow control, summed over all streams.
+	// Only blocked periods that have already ended are taken into account.
+	StreamFlowControlBlockedTime time.Duration
+
+	// DatagramsQueuedForSending is the number of DATAGRAM frames that are queued for sending.
+	DatagramsQueuedForSending uint64
+	// DatagramsQueuedForReading is the number of received DATAGRAM frames
+	// that haven't been read by the application yet.
+	DatagramsQueuedForReading uint64
 }
 
 func (c *Conn) ConnectionStats() ConnectionStats {
+	var datagramsQueuedForSending, datagramsQueuedForReading int
+	if c.datagramQueue != nil {
+		datagramsQueuedForSending, datagramsQueuedForReading = c.datagramQueue.Len()
+	}
+	// The pacing rate is derived from the congestion window and the RTT when the stats are requested.
+	cwnd := c.connStats.CongestionWindow.Load()
 	return ConnectionStats{
 		MinRTT:        c.rttStats.MinRTT(),
 		LatestRTT:     c.rttStats.LatestRTT(),
@@ -840,6 +965,27 @@ func (c *Conn) ConnectionStats() ConnectionStats {
 		PacketsReceived: c.connStats.PacketsReceived.Load(),
 		BytesLost:       c.connStats.BytesLost.Load(),
 		PacketsLost:     c.connStats.PacketsLost.Load(),
+
+		PacketsSpuriouslyLost: c.connStats.PacketsSpuriouslyLost.Load(),
+		BytesRetransmitted:    c.connStats.BytesRetransmitted.Load(),
+		PTOCount:              c.connStats.PTOCount.Load(),
+
+		CongestionWindow: cwnd,
+		BytesInFlight:    c.connStats.BytesInFlight.Load(),
+		PacingRate:       congestion.PacingRate(protocol.ByteCount(cwnd), c.rttStats.SmoothedRTT()),
+		MTU:              c.connStats.MTU.Load(),
+
+		ECT0Count:  c.connStats.ECT0.Load(),
+		ECT1Count:  c.connStats.ECT1.Load(),
+		ECNCECount: c.connStats.ECNCE.Load(),
+
+		KeyUpdates: c.connStats.KeyUpdates.Load(),
+
+		ConnectionFlowControlBlockedTime: c.connFlowController.BlockedTime(),
+		StreamFlowControlBlockedTime:     c.connFlowController.StreamsBlockedTime(),
+
+		DatagramsQueuedForSending: uint64(datagramsQueuedForSending),
+		DatagramsQueuedForReading: uint64(datagramsQueuedForReading),
 	}
 }
 
@@ -911,7 +1057,7 @@ func (c *Conn) idleTimeoutStartTime() monotime.Time {
 	return startTime
 }
 
-func (c *Conn) switchToNewPath(tr *Transport, now monotime.Time) {
+func (c *Conn) switchToNewPath(tr *Transport, stats *pathStats, now monotime.Time) {
 	initialPacketSize := protocol.ByteCount(c.config.InitialPacketSize)
 	c.sentPacketHandler.MigratedPath(now, initialPacketSize)
 	maxPacketSize := protocol.ByteCount(protocol.MaxPacketBufferSize)
@@ -919,7 +1065,10 @@ func (c *Conn) switchToNewPath(tr *Transport, now monotime.Time) {
 		maxPacketSize = c.peerParams.MaxUDPPayloadSize
 	}
 	c.mtuDiscoverer.Reset(now, initialPacketSize, maxPacketSize)
-	c.conn = newSendConn(tr.conn, c.conn.RemoteAddr(), packetInfo{}, utils.DefaultLogger) // TODO: find a better way
+	c.conn = newPathSendConn(
+		newSendConn(tr.conn, c.conn.RemoteAddr(), packetInfo{}, utils.DefaultLogger), // TODO: find a better way
+		stats,
+	)
 	c.sendQueue.Close()
 	c.sendQueue = newSendQueue(c.conn)
 	go func() {
@@ -1115,7 +1264,9 @@ func (c *Conn) handleOnePacket(rp receivedPacket, datagramID qlog.DatagramID) (w
 			}
 			lastConnID = hdr.DestConnectionID
 
-			if hdr.Version != c.version {
+			// A compatible version upgrade is only performed once the packet was successfully unpacked,
+			// see handleLongHeaderPacket.
+			if hdr.Version != c.version && !c.isCompatibleVersionUpgrade(hdr) && !c.isOriginalVersionInitial(hdr) {
 				if c.qlogger != nil {
 					c.qlogger.RecordEvent(qlog.PacketDropped{
 						Raw:        qlog.RawInfo{Length: len(data)},
@@ -1145,6 +1296,9 @@ func (c *Conn) handleOnePacket(rp receivedPacket, datagramID qlog.DatagramID) (w
 			}
 			if processed {
 				wasProcessed = true
+				if hdr.Version == c.version {
+					c.originalVersion = 0
+				}
 			}
 			data = rest
 		} else {
@@ -1301,7 +1455,15 @@ func (c *Conn) handleShortHeaderPacket(
 		protocol.ByteCount(c.config.InitialPacketSize),
 		maxPacketSize,
 	)
-	c.conn.ChangeRemoteAddr(p.remoteAddr, p.info)
+	// The statistics of the old path are kept, and the new path is counted separately.
+	stats := &pathStats{}
+	c.previousPathsMx.Lock()
+	c.previousPaths = append(c.previousPaths, previousPath{
+		remoteAddr: c.conn.RemoteAddr(),
+		stats:      c.initialPathStats.Swap(stats),
+	})
+	c.previousPathsMx.Unlock()
+	c.conn.ChangeRemoteAddrAndStats(p.remoteAddr, p.info, stats)
 	return true, nil
 }
 
@@ -1357,6 +1519,11 @@ func (c *Conn) handleLongHeaderPacket(p receivedPacket, hdr *wire.Header, datagr
 		wasQueued, err = c.handleUnpackError(err, p, toQlogPacketType(hdr.Type), datagramID)
 		return false, err
 	}
+	// Only switch to the new version once the packet was authenticated.
+	// Otherwise, an attacker could inject an Initial packet using a compatible version.
+	if hdr.Version != c.version && c.isCompatibleVersionUpgrade(hdr) {
+		c.upgradeVersion(hdr.Version)
+	}
 
 	if c.logger.Debug() {
 		c.logger.Debugf("<- Reading packet %d (%d bytes) for connection %s, %s", packet.hdr.PacketNumber, p.Size(), hdr.DestConnectionID, packet.encryptionLevel)
@@ -1639,6 +1806,44 @@ func (c *Conn) handleVersionNegotiationPacket(p receivedPacket) error {
 	}
 }
 
+// isCompatibleVersionUpgrade checks if the server performed a compatible version upgrade (RFC 9368).
+// This is only possible for the client, for the server's first Initial packet.
+func (c *Conn) isCompatibleVersionUpgrade(hdr *wire.Header) bool {
+	return c.perspective == protocol.PerspectiveClient &&
+		hdr.Type == protocol.PacketTypeInitial &&
+		!c.versionUpgraded &&
+		!c.receivedFirstPacket &&
+		!c.handshakeComplete &&
+		protocol.IsCompatibleVersion(c.version, hdr.Version) &&
+		slices.Contains(c.config.Versions, hdr.Version)
+}
+
+func (c *Conn) upgradeVersion(v protocol.Version) {
+	c.logger.Infof("Server performed a compatible version upgrade from %s to %s.", c.version, v)
+	c.versionUpgraded = true
+	c.version = v
+	c.connStateMutex.Lock()
+	c.connState.Version = v
+	c.connStateMutex.Unlock()
+	c.cryptoStreamHandler.ChangeVersion(v)
+	if c.qlogger != nil {
+		// The server's available versions are only known once its transport parameters are received.
+		c.qlogger.RecordEvent(qlog.VersionInformation{
+			ChosenVersion:  v,
+			ClientVersions: c.config.Versions,
+		})
+	}
+}
+
+// isOriginalVersionInitial checks if the packet is an Initial packet sent using the original version,
+// after the server performed a compatible version upgrade (RFC 9368).
+// The client keeps using the original version until it receives the server's first Initial packet.
+func (c *Conn) isOriginalVersionInitial(hdr *wire.Header) bool {
+	return c.originalVersion != 0 &&
+		hdr.Version == c.originalVersion &&
+		hdr.Type == protocol.PacketTypeInitial
+}
+
 func (c *Conn) handleUnpackedLongHeaderPacket(
 	packet *unpackedPacket,
 	ecn protocol.ECN,
@@ -1648,7 +1853,7 @@ func (c *Conn) handleUnpackedLongHeaderPacket(
 ) error {
 	if !c.receivedFirstPacket {
 		c.receivedFirstPacket = true
-		if !c.versionNegotiated && c.qlogger != nil {
+		if !c.versionNegotiated && !c.versionUpgraded && c.qlogger != nil {
 			var clientVersions, serverVersions []Version
 			switch c.perspective {
 			case protocol.PerspectiveClient:
@@ -1821,7 +2026,11 @@ func (c *Conn) handleFrames(
 				continue
 			}
 			wire.LogFrame(c.logger, streamFrame, false)
-			handleErr = c.streamsMap.HandleStreamFrame(streamFrame, rcvTime)
+			if encLevel == protocol.Encryption0RTT {
+				handleErr = c.streamsMap.Handle0RTTStreamFrame(streamFrame, rcvTime)
+			} else {
+				handleErr = c.streamsMap.HandleStreamFrame(streamFrame, rcvTime)
+			}
 		} else if frameType.IsAckFrameType() {
 			ackFrame, l, err := c.frameParser.ParseAckFrame(frameType, data, encLevel, c.version)
 			if err != nil {
@@ -1951,6 +2160,11 @@ func (c *Conn) handleFrame(
 
 // handlePacket is called by the server with a new packet
 func (c *Conn) handlePacket(p receivedPacket) {
+	c.initialPathStats.Load().onReceived(p.Size())
+	c.handlePacketImpl(p)
+}
+
+func (c *Conn) handlePacketImpl(p receivedPacket) {
 	c.receivedPacketMx.Lock()
 	// Discard packets once the amount of queued packets is larger than
 	// the channel size, protocol.MaxConnUnprocessedPackets
@@ -2023,6 +2237,8 @@ func (c *Conn) handleHandshakeEvents(now monotime.Time) error {
 			c.handshakeComplete = true
 		case handshake.EventReceivedTransportParameters:
 			err = c.handleTransportParameters(ev.TransportParameters)
+		case handshake.EventVersionUpgraded:
+			c.upgradeVersionServer(ev.Version)
 		case handshake.EventRestoredTransportParameters:
 			c.restoreTransportParameters(ev.TransportParameters)
 			close(c.earlyConnReadyChan)
@@ -2043,6 +2259,28 @@ func (c *Conn) handleHandshakeEvents(now monotime.Time) error {
 	}
 }
 
+// upgradeVersionServer is called for the server when it performs a compatible version upgrade (RFC 9368).
+func (c *Conn) upgradeVersionServer(v protocol.Version) {
+	c.logger.Infof("Performing a compatible version upgrade from %s to %s.", c.version, v)
+	c.versionUpgraded = true
+	c.originalVersion = c.version
+	c.version = v
+	c.connStateMutex.Lock()
+	c.connState.Version = v
+	c.connStateMutex.Unlock()
+	if c.qlogger != nil {
+		var clientVersions []Version
+		if c.peerParams != nil && c.peerParams.VersionInformation != nil {
+			clientVersions = c.peerParams.VersionInformation.AvailableVersions
+		}
+		c.qlogger.RecordEvent(qlog.VersionInformation{
+			ChosenVersion:  v,
+			ClientVersions: clientVersions,
+			ServerVersions: c.config.Versions,
+		})
+	}
+}
+
 func (c *Conn) handlePathChallengeFrame(f *wire.PathChallengeFrame) {
 	if c.perspective == protocol.PerspectiveClient {
 		c.queueControlFrame(&wire.PathResponseFrame{Data: f.Data})
@@ -2360,6 +2598,12 @@ func (c *Conn) handleTransportParameters(params *wire.TransportParameters) error
 			ErrorMessage: err.Error(),
 		}
 	}
+	if err := c.checkVersionInformation(params.VersionInformation); err != nil {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.VersionNegotiationErrorCode,
+			ErrorMessage: err.Error(),
+		}
+	}
 
 	if c.perspective == protocol.PerspectiveClient && c.peerParams != nil && c.ConnectionState().Used0RTT && !params.ValidForUpdate(c.peerParams) {
 		return &qerr.TransportError{
@@ -2410,6 +2654,30 @@ func (c *Conn) checkTransportParameters(params *wire.TransportParameters) error
 	return nil
 }
 
+// checkVersionInformation validates the peer's version_information transport parameter (RFC 9368).
+func (c *Conn) checkVersionInformation(vi *wire.VersionInformation) error {
+	if vi == nil {
+		// If the client received a Version Negotiation packet, the server's
+		// version information is needed to protect against downgrade attacks.
+		if c.perspective == protocol.PerspectiveClient && c.versionNegotiated {
+			return errors.New("missing version_information after version negotiation")
+		}
+		return nil
+	}
+	if vi.ChosenVersion != c.version {
+		return fmt.Errorf("expected chosen version to equal %s, is %s", c.version, vi.ChosenVersion)
+	}
+	if c.perspective == protocol.PerspectiveServer || !c.versionNegotiated {
+		return nil
+	}
+	// Check that we would have chosen the same version, had we known the server's available versions.
+	v, ok := protocol.ChooseSupportedVersion(c.config.Versions, vi.AvailableVersions)
+	if !ok || (v != c.version && !protocol.IsCompatibleVersion(v, c.version)) {
+		return fmt.Errorf("version downgrade detected: would have chosen %s, chose %s", v, c.version)
+	}
+	return nil
+}
+
 func (c *Conn) applyTransportParameters() {
 	params := c.peerParams
 	// Our local idle timeout will always be > 0.
@@ -2485,9 +2753,12 @@ func (c *Conn) triggerSending(now monotime.Time) error {
 }
 
 func (c *Conn) sendPackets(now monotime.Time) error {
+	if c.handshakeComplete && c.keyUpdateRequested.CompareAndSwap(true, false) {
+		c.cryptoStreamHandler.InitiateKeyUpdate()
+	}
 	if c.perspective == protocol.PerspectiveClient && c.handshakeConfirmed {
 		if pm := c.pathManagerOutgoing.Load(); pm != nil {
-			connID, frame, tr, ok := pm.NextPathToProbe()
+			connID, frame, tr, stats, ok := pm.NextPathToProbe()
 			if ok {
 				probe, buf, err := c.packer.PackPathProbePacket(connID, []ackhandler.Frame{frame}, c.version)
 				if err != nil {
@@ -2496,7 +2767,9 @@ func (c *Conn) sendPackets(now monotime.Time) error {
 				c.logger.Debugf("sending path probe packet from %s", c.LocalAddr())
 				c.logShortHeaderPacket(probe, protocol.ECNNon, buf.Len())
 				c.registerPackedShortHeaderPacket(probe, protocol.ECNNon, now)
-				tr.WriteTo(buf.Data, c.conn.RemoteAddr())
+				if _, err := tr.WriteTo(buf.Data, c.conn.RemoteAddr()); err == nil {
+					stats.onSent(len(buf.Data), 0)
+				}
 				// There's (likely) more data to send. Loop around again.
 				c.scheduleSending()
 				return nil
@@ -2937,10 +3210,17 @@ func (c *Conn) newFlowController(id protocol.StreamID) flowcontrol.StreamFlowCon
 			initialSendWindow = c.peerParams.InitialMaxStreamDataBidiLocal
 		}
 	}
+	// The initial receive window was advertised in the transport parameters, and can't be reduced.
+	// Under memory pressure, the stream uses a smaller window for subsequent window updates.
+	receiveWindowSize := c.streamReceiveWindow
+	if c.config.MemoryBudget != nil {
+		receiveWindowSize = c.config.MemoryBudget.streamReceiveWindow(receiveWindowSize)
+	}
 	return flowcontrol.NewStreamFlowController(
 		id,
 		c.connFlowController,
-		protocol.ByteCount(c.config.InitialStreamReceiveWindow),
+		c.streamReceiveWindow,
+		receiveWindowSize,
 		protocol.ByteCount(c.config.MaxStreamReceiveWindow),
 		initialSendWindow,
 		c.rttStats,
@@ -3008,6 +3288,10 @@ func (c *Conn) onHasStreamControlFrame(id protocol.StreamID, str streamControlFr
 	c.scheduleSending()
 }
 
+func (c *Conn) onStreamDataRetransmitted(n protocol.ByteCount) {
+	c.connStats.BytesRetransmitted.Add(uint64(n))
+}
+
 func (c *Conn) onStreamCompleted(id protocol.StreamID) {
 	if err := c.streamsMap.DeleteStream(id); err != nil {
 		c.closeLocal(err)
@@ -3021,7 +3305,21 @@ func (c *Conn) onStreamCompleted(id protocol.StreamID) {
 // The payload of the datagram needs to fit into a single QUIC packet.
 // In addition, a datagram may be dropped before being sent out if the available packet size suddenly decreases.
 // If the payload is too large to be sent at the current time, a DatagramTooLargeError is returned.
+// If the send queue is full, the behavior depends on the Config.DatagramQueuePolicy.
 func (c *Conn) SendDatagram(p []byte) error {
+	return c.sendDatagram(p, nil)
+}
+
+// SendDatagramWithFeedback sends a message using a QUIC datagram, like SendDatagram.
+// onStatus is called when the datagram is sent, and when it is acknowledged or declared lost.
+// If the datagram is dropped before being sent, it is called with DatagramDropped.
+// onStatus is called from the connection's run loop, and must not block.
+// To avoid deadlocks, it is not valid to call other functions on the connection in this callback.
+func (c *Conn) SendDatagramWithFeedback(p []byte, onStatus func(DatagramStatus)) error {
+	return c.sendDatagram(p, onStatus)
+}
+
+func (c *Conn) sendDatagram(p []byte, onStatus func(DatagramStatus)) error {
 	if !c.supportsDatagrams() {
 		return errors.New("datagram support disabled")
 	}
@@ -3038,7 +3336,7 @@ func (c *Conn) SendDatagram(p []byte) error {
 	}
 	f.Data = make([]byte, len(p))
 	copy(f.Data, p)
-	return c.datagramQueue.Add(f)
+	return c.datagramQueue.Add(f, onStatus)
 }
 
 // ReceiveDatagram gets a message received in a QUIC datagram, as specified in RFC 9221.
@@ -3055,6 +3353,9 @@ func (c *Conn) LocalAddr() net.Addr { return c.conn.LocalAddr() }
 // RemoteAddr returns the remote address of the QUIC connection.
 func (c *Conn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
 
+// TracingID returns the ID that identifies the connection within the process.
+func (c *Conn) TracingID() ConnectionTracingID { return c.tracingID }
+
 // getPathManager lazily initializes the Conn's pathManagerOutgoing.
 // May create multiple pathManagerOutgoing objects if called concurrently.
 func (c *Conn) getPathManager() *pathManagerOutgoing {
@@ -3088,7 +3389,8 @@ func (c *Conn) AddPath(t *Transport) (*Path, error) {
 	if err := t.init(false); err != nil {
 		return nil, err
 	}
-	return c.getPathManager().NewPath(
+	var handler *pathPacketHandler
+	p := c.getPathManager().NewPath(
 		t,
 		200*time.Millisecond, // initial RTT estimate
 		func() {
@@ -3096,13 +3398,64 @@ func (c *Conn) AddPath(t *Transport) (*Path, error) {
 			c.connIDGenerator.AddConnRunner(
 				runner,
 				connRunnerCallbacks{
-					AddConnectionID:    func(connID protocol.ConnectionID) { runner.Add(connID, c) },
+					AddConnectionID:    func(connID protocol.ConnectionID) { runner.Add(connID, handler) },
 					RemoveConnectionID: runner.Remove,
 					ReplaceWithClosed:  runner.ReplaceWithClosed,
 				},
 			)
 		},
-	), nil
+	)
+	p.conn = c
+	handler = &pathPacketHandler{Conn: c, stats: p.stats}
+	return p, nil
+}
+
+// Paths returns statistics about all network paths of the connection.
+// The first element is the path used during the handshake.
+// For the server, it is followed by the paths that the client migrated to,
+// for example due to a NAT rebinding.
+// For the client, it is followed by the paths added using [Conn.AddPath] that have not been closed.
+func (c *Conn) Paths() []PathStats {
+	c.previousPathsMx.Lock()
+	paths := make([]PathStats, 0, len(c.previousPaths)+1)
+	for _, p := range c.previousPaths {
+		s := PathStats{
+			LocalAddr:  c.initialPathLocalAddr,
+			RemoteAddr: p.remoteAddr,
+			Validated:  true,
+		}
+		p.stats.populate(&s)
+		paths = append(paths, s)
+	}
+	c.previousPathsMx.Unlock()
+
+	remoteAddr := c.RemoteAddr()
+	currentPath := PathStats{
+		LocalAddr:  c.initialPathLocalAddr,
+		RemoteAddr: remoteAddr,
+		Validated:  true,
+		Active:     true,
+	}
+	c.initialPathStats.Load().populate(&currentPath)
+	paths = append(paths, currentPath)
+	if pm := c.pathManagerOutgoing.Load(); pm != nil {
+		paths = pm.AppendPathStats(paths, remoteAddr)
+	}
+	for i := range paths {
+		if paths[i].Active {
+			paths[i].SmoothedRTT = c.rttStats.SmoothedRTT()
+			paths[i].LatestRTT = c.rttStats.LatestRTT()
+		}
+	}
+	return paths
+}
+
+// Streams returns information about the streams of the connection that are currently open,
+// ordered by stream ID.
+// This includes streams opened by the peer that haven't been accepted yet.
+// It is safe to call Streams concurrently with other methods on the connection and its streams.
+func (c *Conn) Streams() []StreamInfo {
+	return c.streamsMap.Streams()
 }
 
 // HandshakeComplete blocks until the handshake completes (or fails).
@@ -3113,6 +3466,18 @@ func (c *Conn) HandshakeComplete() <-chan struct{} {
 	return c.handshakeCompleteChan
 }
 
+// UpdateKeys initiates a key update (see section 6 of RFC 9001).
+// The key update is performed when the next packet is sent,
+// as soon as the handshake is confirmed and the peer has acknowledged a packet sent with the current keys.
+func (c *Conn) UpdateKeys() error {
+	if closeErr := c.closeErr.Load(); closeErr != nil {
+		return closeErr.err
+	}
+	c.keyUpdateRequested.Store(true)
+	c.scheduleSending()
+	return nil
+}
+
 // QlogTrace returns the qlog trace of the QUIC connection.
 // It is nil if qlog is not enabled.
 func (c *Conn) QlogTrace() qlogwriter.Trace {
@@ -1231,6 +1231,161 @@ func TestConnectionTransportParameterValidationFailureClient(t *testing.T) {
 	})
 }
 
+func TestConnectionCompatibleVersionUpgradeClient(t *testing.T) {
+	getInitial := func(t *testing.T, tc *testConnection, v protocol.Version) receivedPacket {
+		t.Helper()
+		hdr := &wire.ExtendedHeader{
+			Header: wire.Header{
+				Type:             protocol.PacketTypeInitial,
+				DestConnectionID: tc.srcConnID,
+				SrcConnectionID:  tc.destConnID,
+				Version:          v,
+				Length:           3,
+			},
+			PacketNumber:    1,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}
+		b, err := hdr.Append(nil, v)
+		require.NoError(t, err)
+		return receivedPacket{
+			remoteAddr: tc.remoteAddr,
+			data:       append(b, 0),
+			buffer:     getPacketBuffer(),
+			rcvTime:    monotime.Now(),
+		}
+	}
+	unpacked := func(v protocol.Version) *unpackedPacket {
+		return &unpackedPacket{
+			encryptionLevel: protocol.EncryptionInitial,
+			hdr: &wire.ExtendedHeader{
+				Header:          wire.Header{Type: protocol.PacketTypeInitial, Version: v},
+				PacketNumber:    1,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			},
+			data: []byte{0}, // one PADDING frame
+		}
+	}
+
+	t.Run("valid upgrade", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		cs := mocks.NewMockCryptoSetup(mockCtrl)
+		unpacker := NewMockUnpacker(mockCtrl)
+		var eventRecorder events.Recorder
+		tc := newClientTestConnection(t, mockCtrl, nil, false,
+			connectionOptCryptoSetup(cs),
+			connectionOptUnpacker(unpacker),
+			connectionOptTracer(&eventRecorder),
+		)
+
+		gomock.InOrder(
+			unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any()).Return(unpacked(protocol.Version2), nil),
+			cs.EXPECT().ChangeVersion(protocol.Version2),
+		)
+		wasProcessed, err := tc.conn.handleOnePacket(getInitial(t, tc, protocol.Version2), 0)
+		require.NoError(t, err)
+		require.True(t, wasProcessed)
+		require.Equal(t, protocol.Version2, tc.conn.version)
+		require.Equal(t,
+			[]qlogwriter.Event{
+				qlog.VersionInformation{
+					ClientVersions: protocol.SupportedVersions,
+					ChosenVersion:  protocol.Version2,
+				},
+			},
+			eventRecorder.Events(qlog.VersionInformation{}),
+		)
+	})
+
+	t.Run("corrupted Initial", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		cs := mocks.NewMockCryptoSetup(mockCtrl)
+		unpacker := NewMockUnpacker(mockCtrl)
+		var eventRecorder events.Recorder
+		tc := newClientTestConnection(t, mockCtrl, nil, false,
+			connectionOptCryptoSetup(cs),
+			connectionOptUnpacker(unpacker),
+			connectionOptTracer(&eventRecorder),
+		)
+
+		// a corrupted Initial packet using a compatible version doesn't trigger a version upgrade
+		unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
+		wasProcessed, err := tc.conn.handleOnePacket(getInitial(t, tc, protocol.Version2), 0)
+		require.NoError(t, err)
+		require.False(t, wasProcessed)
+		require.Equal(t, protocol.Version1, tc.conn.version)
+
+		// the valid Initial packet sent using the original version is processed
+		unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any()).Return(unpacked(protocol.Version1), nil)
+		wasProcessed, err = tc.conn.handleOnePacket(getInitial(t, tc, protocol.Version1), 0)
+		require.NoError(t, err)
+		require.True(t, wasProcessed)
+		require.Equal(t, protocol.Version1, tc.conn.version)
+
+		// once a packet was processed, the server can't upgrade the version any more
+		wasProcessed, err = tc.conn.handleOnePacket(getInitial(t, tc, protocol.Version2), 0)
+		require.NoError(t, err)
+		require.False(t, wasProcessed)
+		require.Equal(t, protocol.Version1, tc.conn.version)
+		for _, ev := range eventRecorder.Events(qlog.VersionInformation{}) {
+			require.Equal(t, protocol.Version1, ev.(qlog.VersionInformation).ChosenVersion)
+		}
+	})
+}
+
+func TestConnectionVersionInformationValidation(t *testing.T) {
+	t.Run("server: wrong chosen version", func(t *testing.T) {
+		tc := newServerTestConnection(t, nil, nil, false)
+		err := tc.conn.handleTransportParameters(&wire.TransportParameters{
+			VersionInformation: &wire.VersionInformation{
+				ChosenVersion:     protocol.Version2,
+				AvailableVersions: []protocol.Version{protocol.Version2},
+			},
+		})
+		assert.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.VersionNegotiationErrorCode})
+		assert.ErrorContains(t, err, "expected chosen version to equal")
+	})
+
+	t.Run("client: missing after version negotiation", func(t *testing.T) {
+		tc := newClientTestConnection(t, nil, nil, false)
+		tc.conn.versionNegotiated = true
+		err := tc.conn.handleTransportParameters(&wire.TransportParameters{
+			InitialSourceConnectionID:       tc.destConnID,
+			OriginalDestinationConnectionID: tc.destConnID,
+		})
+		assert.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.VersionNegotiationErrorCode})
+		assert.ErrorContains(t, err, "missing version_information")
+	})
+
+	t.Run("client: downgrade", func(t *testing.T) {
+		tc := newClientTestConnection(t, nil, nil, false)
+		tc.conn.versionNegotiated = true
+		tc.conn.config.Versions = []protocol.Version{0x1337, protocol.Version1}
+		err := tc.conn.handleTransportParameters(&wire.TransportParameters{
+			InitialSourceConnectionID:       tc.destConnID,
+			OriginalDestinationConnectionID: tc.destConnID,
+			VersionInformation: &wire.VersionInformation{
+				ChosenVersion:     protocol.Version1,
+				AvailableVersions: []protocol.Version{0x1337, protocol.Version1},
+			},
+		})
+		assert.ErrorIs(t, err, &qerr.TransportError{ErrorCode: qerr.VersionNegotiationErrorCode})
+		assert.ErrorContains(t, err, "version downgrade detected")
+	})
+
+	t.Run("client: valid", func(t *testing.T) {
+		tc := newClientTestConnection(t, nil, nil, false)
+		tc.conn.versionNegotiated = true
+		require.NoError(t, tc.conn.handleTransportParameters(&wire.TransportParameters{
+			InitialSourceConnectionID:       tc.destConnID,
+			OriginalDestinationConnectionID: tc.destConnID,
+			VersionInformation: &wire.VersionInformation{
+				ChosenVersion:     protocol.Version1,
+				AvailableVersions: []protocol.Version{protocol.Version1},
+			},
+		}))
+	})
+}
+
 func TestConnectionHandshakeServer(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	cs := mocks.NewMockCryptoSetup(mockCtrl)
@@ -10,6 +10,7 @@ import (
 
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/qerr"
+	"github.com/nukilabs/quic-go/internal/qtls"
 	"github.com/nukilabs/quic-go/internal/wire"
 )
 
@@ -143,7 +144,7 @@ func (s *initialCryptoStream) Write(p []byte) (int, error) {
 		return len(p), nil
 	}
 	if s.cuts[0].start == protocol.InvalidByteCount {
-		sniPos, sniLen, echPos, err := findSNIAndECH(s.writeBuf)
+		sniPos, sniLen, echPos, err := qtls.FindSNIAndECH(s.writeBuf)
 		if errors.Is(err, io.ErrUnexpectedEOF) {
 			return len(p), nil
 		}
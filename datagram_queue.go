@@ -4,24 +4,47 @@ import (
 	"context"
 	"sync"
 
+	"github.com/nukilabs/quic-go/internal/ackhandler"
 	"github.com/nukilabs/quic-go/internal/utils"
 	"github.com/nukilabs/quic-go/internal/utils/ringbuffer"
 	"github.com/nukilabs/quic-go/internal/wire"
 )
 
 const (
-	maxDatagramSendQueueLen = 32
-	maxDatagramRcvQueueLen  = 128
+	defaultDatagramSendQueueLen = 32
+	defaultDatagramRcvQueueLen  = 128
 )
 
+// datagramFrameHandler reports the acknowledgement and the loss of a DATAGRAM frame to the application.
+type datagramFrameHandler func(DatagramStatus)
+
+var _ ackhandler.FrameHandler = datagramFrameHandler(nil)
+
+func (h datagramFrameHandler) OnAcked(wire.Frame) { h(DatagramAcked) }
+func (h datagramFrameHandler) OnLost(wire.Frame)  { h(DatagramLost) }
+
+type queuedDatagram struct {
+	frame    *wire.DatagramFrame
+	onStatus func(DatagramStatus) // might be nil
+}
+
+func (d queuedDatagram) reportStatus(s DatagramStatus) {
+	if d.onStatus != nil {
+		d.onStatus(s)
+	}
+}
+
 type datagramQueue struct {
-	sendMx    sync.Mutex
-	sendQueue ringbuffer.RingBuffer[*wire.DatagramFrame]
-	sent      chan struct{} // used to notify Add that a datagram was dequeued
+	sendMx       sync.Mutex
+	sendQueue    ringbuffer.RingBuffer[queuedDatagram]
+	sendQueueLen int
+	policy       DatagramQueuePolicy
+	sent         chan struct{} // used to notify Add that a datagram was dequeued
 
-	rcvMx    sync.Mutex
-	rcvQueue [][]byte
-	rcvd     chan struct{} // used to notify Receive that a new datagram was received
+	rcvMx       sync.Mutex
+	rcvQueue    [][]byte
+	rcvQueueLen int
+	rcvd        chan struct{} // used to notify Receive that a new datagram was received
 
 	closeErr error
 	closed   chan struct{}
@@ -31,26 +54,63 @@ type datagramQueue struct {
 	logger utils.Logger
 }
 
-func newDatagramQueue(hasData func(), logger utils.Logger) *datagramQueue {
+func newDatagramQueue(
+	hasData func(),
+	sendQueueLen, rcvQueueLen int,
+	policy DatagramQueuePolicy,
+	logger utils.Logger,
+) *datagramQueue {
 	return &datagramQueue{
-		hasData: hasData,
-		rcvd:    make(chan struct{}, 1),
-		sent:    make(chan struct{}, 1),
-		closed:  make(chan struct{}),
-		logger:  logger,
+		hasData:      hasData,
+		sendQueueLen: sendQueueLen,
+		rcvQueueLen:  rcvQueueLen,
+		policy:       policy,
+		rcvd:         make(chan struct{}, 1),
+		sent:         make(chan struct{}, 1),
+		closed:       make(chan struct{}),
+		logger:       logger,
 	}
 }
 
 // Add queues a new DATAGRAM frame for sending.
-// Up to 32 DATAGRAM frames will be queued.
-// Once that limit is reached, Add blocks until the queue size has reduced.
-func (h *datagramQueue) Add(f *wire.DatagramFrame) error {
+// Up to sendQueueLen DATAGRAM frames will be queued.
+// Once that limit is reached, the behavior depends on the policy:
+// Add either blocks until the queue size has reduced, or drops a DATAGRAM frame.
+// onStatus may be nil.
+func (h *datagramQueue) Add(f *wire.DatagramFrame, onStatus func(DatagramStatus)) error {
+	d := queuedDatagram{frame: f, onStatus: onStatus}
 	h.sendMx.Lock()
 
 	for {
-		if h.sendQueue.Len() < maxDatagramSendQueueLen {
-			h.sendQueue.PushBack(f)
+		select {
+		case <-h.closed:
+			h.sendMx.Unlock()
+			d.reportStatus(DatagramDropped)
+			return h.closeErr
+		default:
+		}
+		if h.sendQueue.Len() < h.sendQueueLen {
+			h.sendQueue.PushBack(d)
+			h.sendMx.Unlock()
+			h.hasData()
+			return nil
+		}
+		switch h.policy {
+		case DatagramQueueDropNewest:
+			h.sendMx.Unlock()
+			if h.logger.Debug() {
+				h.logger.Debugf("Datagram send queue full. Dropping new DATAGRAM frame (%d bytes payload)", len(f.Data))
+			}
+			d.reportStatus(DatagramDropped)
+			return nil
+		case DatagramQueueDropOldest:
+			dropped := h.sendQueue.PopFront()
+			h.sendQueue.PushBack(d)
 			h.sendMx.Unlock()
+			if h.logger.Debug() {
+				h.logger.Debugf("Datagram send queue full. Dropping oldest DATAGRAM frame (%d bytes payload)", len(dropped.frame.Data))
+			}
+			dropped.reportStatus(DatagramDropped)
 			h.hasData()
 			return nil
 		}
@@ -61,7 +121,6 @@ func (h *datagramQueue) Add(f *wire.DatagramFrame) error {
 		h.sendMx.Unlock()
 		select {
 		case <-h.closed:
-			return h.closeErr
 		case <-h.sent:
 		}
 		h.sendMx.Lock()
@@ -70,23 +129,49 @@ func (h *datagramQueue) Add(f *wire.DatagramFrame) error {
 
 // Peek gets the next DATAGRAM frame for sending.
 // If actually sent out, Pop needs to be called before the next call to Peek.
+// If it can't be sent, Drop needs to be called.
 func (h *datagramQueue) Peek() *wire.DatagramFrame {
 	h.sendMx.Lock()
 	defer h.sendMx.Unlock()
 	if h.sendQueue.Empty() {
 		return nil
 	}
-	return h.sendQueue.PeekFront()
+	return h.sendQueue.PeekFront().frame
+}
+
+// Pop removes the DATAGRAM frame returned by Peek, after it was packed into a packet.
+// It returns the handler that needs to be notified of the acknowledgement or loss of the frame,
+// or nil if the application didn't ask to be notified.
+func (h *datagramQueue) Pop(f *wire.DatagramFrame) ackhandler.FrameHandler {
+	d, ok := h.pop(f)
+	if !ok || d.onStatus == nil {
+		return nil
+	}
+	d.onStatus(DatagramSent)
+	return datagramFrameHandler(d.onStatus)
 }
 
-func (h *datagramQueue) Pop() {
+// Drop removes the DATAGRAM frame returned by Peek, without sending it.
+func (h *datagramQueue) Drop(f *wire.DatagramFrame) {
+	if d, ok := h.pop(f); ok {
+		d.reportStatus(DatagramDropped)
+	}
+}
+
+// pop removes the frame from the front of the queue.
+// Between Peek and pop, the frame might already have been removed by Add, if the drop-oldest policy is used.
+func (h *datagramQueue) pop(f *wire.DatagramFrame) (queuedDatagram, bool) {
 	h.sendMx.Lock()
 	defer h.sendMx.Unlock()
-	_ = h.sendQueue.PopFront()
+	if h.sendQueue.Empty() || h.sendQueue.PeekFront().frame != f {
+		return queuedDatagram{}, false
+	}
+	d := h.sendQueue.PopFront()
 	select {
 	case h.sent <- struct{}{}:
 	default:
 	}
+	return d, true
 }
 
 // HandleDatagramFrame handles a received DATAGRAM frame.
@@ -95,7 +180,7 @@ func (h *datagramQueue) HandleDatagramFrame(f *wire.DatagramFrame) {
 	copy(data, f.Data)
 	var queued bool
 	h.rcvMx.Lock()
-	if len(h.rcvQueue) < maxDatagramRcvQueueLen {
+	if len(h.rcvQueue) < h.rcvQueueLen {
 		h.rcvQueue = append(h.rcvQueue, data)
 		queued = true
 		select {
@@ -131,7 +216,29 @@ func (h *datagramQueue) Receive(ctx context.Context) ([]byte, error) {
 	}
 }
 
+// Len returns the number of DATAGRAM frames queued for sending,
+// and the number of received DATAGRAM frames that haven't been read by the application yet.
+func (h *datagramQueue) Len() (send, receive int) {
+	h.sendMx.Lock()
+	send = h.sendQueue.Len()
+	h.sendMx.Unlock()
+	h.rcvMx.Lock()
+	receive = len(h.rcvQueue)
+	h.rcvMx.Unlock()
+	return send, receive
+}
+
 func (h *datagramQueue) CloseWithError(e error) {
 	h.closeErr = e
 	close(h.closed)
+
+	h.sendMx.Lock()
+	var dropped []queuedDatagram
+	for !h.sendQueue.Empty() {
+		dropped = append(dropped, h.sendQueue.PopFront())
+	}
+	h.sendMx.Unlock()
+	for _, d := range dropped {
+		d.reportStatus(DatagramDropped)
+	}
 }
@@ -3,6 +3,7 @@ package quic
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/nukilabs/quic-go/internal/synctest"
 	"github.com/nukilabs/quic-go/internal/utils"
@@ -14,27 +15,31 @@ import (
 
 func TestDatagramQueuePeekAndPop(t *testing.T) {
 	var queued []struct{}
-	queue := newDatagramQueue(func() { queued = append(queued, struct{}{}) }, utils.DefaultLogger)
+	queue := newDatagramQueue(func() { queued = append(queued, struct{}{}) }, defaultDatagramSendQueueLen, defaultDatagramRcvQueueLen, DatagramQueueBlock, utils.DefaultLogger)
 	require.Nil(t, queue.Peek())
 	require.Empty(t, queued)
-	require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte("foo")}))
+	require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, nil))
 	require.Len(t, queued, 1)
 	require.Equal(t, &wire.DatagramFrame{Data: []byte("foo")}, queue.Peek())
 	// calling peek again returns the same datagram
 	require.Equal(t, &wire.DatagramFrame{Data: []byte("foo")}, queue.Peek())
-	queue.Pop()
+	send, _ := queue.Len()
+	require.Equal(t, 1, send)
+	queue.Pop(queue.Peek())
 	require.Nil(t, queue.Peek())
+	send, _ = queue.Len()
+	require.Zero(t, send)
 }
 
 func TestDatagramQueueSendQueueLength(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
-		queue := newDatagramQueue(func() {}, utils.DefaultLogger)
+		queue := newDatagramQueue(func() {}, defaultDatagramSendQueueLen, defaultDatagramRcvQueueLen, DatagramQueueBlock, utils.DefaultLogger)
 
-		for range maxDatagramSendQueueLen {
-			require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte{0}}))
+		for range defaultDatagramSendQueueLen {
+			require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte{0}}, nil))
 		}
 		errChan := make(chan error, 1)
-		go func() { errChan <- queue.Add(&wire.DatagramFrame{Data: []byte("foobar")}) }()
+		go func() { errChan <- queue.Add(&wire.DatagramFrame{Data: []byte("foobar")}, nil) }()
 
 		synctest.Wait()
 
@@ -54,7 +59,7 @@ func TestDatagramQueueSendQueueLength(t *testing.T) {
 		}
 
 		// ...but popping does
-		queue.Pop()
+		queue.Pop(queue.Peek())
 		synctest.Wait()
 		select {
 		case err := <-errChan:
@@ -63,8 +68,8 @@ func TestDatagramQueueSendQueueLength(t *testing.T) {
 			t.Fatal("timeout")
 		}
 		// pop all the remaining datagrams
-		for range maxDatagramSendQueueLen - 1 {
-			queue.Pop()
+		for range defaultDatagramSendQueueLen - 1 {
+			queue.Pop(queue.Peek())
 		}
 		f := queue.Peek()
 		require.NotNil(t, f)
@@ -73,22 +78,26 @@ func TestDatagramQueueSendQueueLength(t *testing.T) {
 }
 
 func TestDatagramQueueReceive(t *testing.T) {
-	queue := newDatagramQueue(func() {}, utils.DefaultLogger)
+	queue := newDatagramQueue(func() {}, defaultDatagramSendQueueLen, defaultDatagramRcvQueueLen, DatagramQueueBlock, utils.DefaultLogger)
 
 	// receive frames that were received earlier
 	queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
 	queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("bar")})
+	_, receive := queue.Len()
+	require.Equal(t, 2, receive)
 	data, err := queue.Receive(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, []byte("foo"), data)
 	data, err = queue.Receive(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, []byte("bar"), data)
+	_, receive = queue.Len()
+	require.Zero(t, receive)
 }
 
 func TestDatagramQueueReceiveBlocking(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
-		queue := newDatagramQueue(func() {}, utils.DefaultLogger)
+		queue := newDatagramQueue(func() {}, defaultDatagramSendQueueLen, defaultDatagramRcvQueueLen, DatagramQueueBlock, utils.DefaultLogger)
 
 		// block until a new frame is received
 		type result struct {
@@ -147,13 +156,13 @@ func TestDatagramQueueReceiveBlocking(t *testing.T) {
 
 func TestDatagramQueueClose(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
-		queue := newDatagramQueue(func() {}, utils.DefaultLogger)
+		queue := newDatagramQueue(func() {}, defaultDatagramSendQueueLen, defaultDatagramRcvQueueLen, DatagramQueueBlock, utils.DefaultLogger)
 
-		for range maxDatagramSendQueueLen {
-			require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte{0}}))
+		for range defaultDatagramSendQueueLen {
+			require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte{0}}, nil))
 		}
 		errChan1 := make(chan error, 1)
-		go func() { errChan1 <- queue.Add(&wire.DatagramFrame{Data: []byte("foobar")}) }()
+		go func() { errChan1 <- queue.Add(&wire.DatagramFrame{Data: []byte("foobar")}, nil) }()
 		errChan2 := make(chan error, 1)
 		go func() {
 			_, err := queue.Receive(context.Background())
@@ -178,3 +187,88 @@ func TestDatagramQueueClose(t *testing.T) {
 		}
 	})
 }
+
+func TestDatagramQueueDropPolicies(t *testing.T) {
+	t.Run("drop oldest", func(t *testing.T) {
+		var dropped []string
+		queue := newDatagramQueue(func() {}, 2, defaultDatagramRcvQueueLen, DatagramQueueDropOldest, utils.DefaultLogger)
+		for _, d := range []string{"foo", "bar", "baz"} {
+			require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte(d)}, func(s DatagramStatus) {
+				if s == DatagramDropped {
+					dropped = append(dropped, d)
+				}
+			}))
+		}
+		require.Equal(t, []string{"foo"}, dropped)
+		require.Equal(t, []byte("bar"), queue.Peek().Data)
+		queue.Pop(queue.Peek())
+		require.Equal(t, []byte("baz"), queue.Peek().Data)
+	})
+
+	t.Run("drop newest", func(t *testing.T) {
+		var dropped []string
+		queue := newDatagramQueue(func() {}, 2, defaultDatagramRcvQueueLen, DatagramQueueDropNewest, utils.DefaultLogger)
+		for _, d := range []string{"foo", "bar", "baz"} {
+			require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte(d)}, func(s DatagramStatus) {
+				if s == DatagramDropped {
+					dropped = append(dropped, d)
+				}
+			}))
+		}
+		require.Equal(t, []string{"baz"}, dropped)
+		require.Equal(t, []byte("foo"), queue.Peek().Data)
+		queue.Pop(queue.Peek())
+		require.Equal(t, []byte("bar"), queue.Peek().Data)
+	})
+
+	t.Run("frame dropped after peeking", func(t *testing.T) {
+		queue := newDatagramQueue(func() {}, 1, defaultDatagramRcvQueueLen, DatagramQueueDropOldest, utils.DefaultLogger)
+		var statuses []DatagramStatus
+		require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, func(s DatagramStatus) { statuses = append(statuses, s) }))
+		f := queue.Peek()
+		require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte("bar")}, nil))
+		require.Equal(t, []DatagramStatus{DatagramDropped}, statuses)
+		// popping the frame that was dropped doesn't remove the new frame
+		require.Nil(t, queue.Pop(f))
+		require.Equal(t, []byte("bar"), queue.Peek().Data)
+	})
+}
+
+func TestDatagramQueueStatus(t *testing.T) {
+	queue := newDatagramQueue(func() {}, defaultDatagramSendQueueLen, defaultDatagramRcvQueueLen, DatagramQueueBlock, utils.DefaultLogger)
+	var statuses []DatagramStatus
+	require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte("foo")}, func(s DatagramStatus) { statuses = append(statuses, s) }))
+	require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte("bar")}, nil))
+	require.NoError(t, queue.Add(&wire.DatagramFrame{Data: []byte("baz")}, func(s DatagramStatus) { statuses = append(statuses, s) }))
+
+	f := queue.Peek()
+	handler := queue.Pop(f)
+	require.NotNil(t, handler)
+	require.Equal(t, []DatagramStatus{DatagramSent}, statuses)
+	handler.OnLost(f)
+	require.Equal(t, []DatagramStatus{DatagramSent, DatagramLost}, statuses)
+
+	// no callback, no handler
+	require.Nil(t, queue.Pop(queue.Peek()))
+
+	// queued datagrams are dropped when the connection is closed
+	queue.CloseWithError(assert.AnError)
+	require.Equal(t, []DatagramStatus{DatagramSent, DatagramLost, DatagramDropped}, statuses)
+}
+
+func TestDatagramQueueReceiveQueueLength(t *testing.T) {
+	queue := newDatagramQueue(func() {}, defaultDatagramSendQueueLen, 2, DatagramQueueBlock, utils.DefaultLogger)
+	queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+	queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("bar")})
+	queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("baz")}) // dropped
+
+	for _, expected := range []string{"foo", "bar"} {
+		data, err := queue.Receive(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []byte(expected), data)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := queue.Receive(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
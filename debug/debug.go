@@ -0,0 +1,366 @@
+// Package debug serves live information about QUIC connections over HTTP,
+// in the style of net/http/pprof.
+//
+// Importing the package registers its handlers on the http.DefaultServeMux
+// of github.com/nukilabs/http, which is not the same as the http.DefaultServeMux of net/http:
+//
+//	import _ "github.com/nukilabs/quic-go/debug"
+//
+// If this http.DefaultServeMux isn't served, the handlers can be registered on a different mux.
+//
+// Only the connections of Transports registered using Register are exposed.
+// Connections are identified by their quic.ConnectionTracingID.
+//
+//   - GET /debug/quic/conns returns a JSON array of all live connections.
+//   - GET /debug/quic/conns?id=<id> returns a single connection.
+//   - POST /debug/quic/close closes a connection. The request body is a JSON object containing
+//     the id of the connection, and optionally the application error code and the reason:
+//     {"id": 1, "code": 42, "reason": "closed by operator"}.
+//
+// The handlers expose details about the connections of the process, and allow closing connections.
+// They should only be served on a listener that is not reachable from untrusted networks.
+package debug
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nukilabs/http"
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/quicvarint"
+)
+
+func init() {
+	http.HandleFunc("/debug/quic/conns", Conns)
+	http.HandleFunc("/debug/quic/close", Close)
+}
+
+var (
+	mutex      sync.Mutex
+	transports []*quic.Transport
+)
+
+// Register exposes the connections of the Transport.
+// Registering the same Transport multiple times has no effect.
+func Register(tr *quic.Transport) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if !slices.Contains(transports, tr) {
+		transports = append(transports, tr)
+	}
+}
+
+// Unregister stops exposing the connections of the Transport.
+// It should be called when the Transport is closed.
+func Unregister(tr *quic.Transport) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	transports = slices.DeleteFunc(transports, func(t *quic.Transport) bool { return t == tr })
+}
+
+// conns returns the live connections of all registered Transports, ordered by their tracing ID.
+func conns() []*quic.Conn {
+	mutex.Lock()
+	trs := slices.Clone(transports)
+	mutex.Unlock()
+
+	var conns []*quic.Conn
+	for _, tr := range trs {
+		conns = append(conns, tr.Conns()...)
+	}
+	// a connection can use multiple Transports, if paths were added using quic.Conn.AddPath
+	slices.SortFunc(conns, func(a, b *quic.Conn) int { return cmp.Compare(a.TracingID(), b.TracingID()) })
+	return slices.Compact(conns)
+}
+
+// findConn returns the connection identified by id.
+// If the connection can't be found, it writes an error response.
+func findConn(w http.ResponseWriter, id uint64) (*quic.Conn, bool) {
+	for _, conn := range conns() {
+		if conn.TracingID() == quic.ConnectionTracingID(id) {
+			return conn, true
+		}
+	}
+	http.Error(w, fmt.Sprintf("connection %d not found", id), http.StatusNotFound)
+	return nil, false
+}
+
+// Conns responds with a JSON array of all live connections of the registered Transports.
+// If the id query parameter is set, it responds with the JSON object of this connection.
+// The handler is registered as /debug/quic/conns.
+func Conns(w http.ResponseWriter, r *http.Request) {
+	var v any
+	if s := r.FormValue("id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid connection ID: %q", s), http.StatusBadRequest)
+			return
+		}
+		conn, ok := findConn(w, id)
+		if !ok {
+			return
+		}
+		v = newConnection(conn)
+	} else {
+		connections := []Connection{}
+		for _, conn := range conns() {
+			connections = append(connections, newConnection(conn))
+		}
+		v = connections
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// CloseRequest is the JSON body of a request to close a connection.
+type CloseRequest struct {
+	ID     quic.ConnectionTracingID  `json:"id"`
+	Code   quic.ApplicationErrorCode `json:"code,omitempty"`
+	Reason string                    `json:"reason,omitempty"`
+}
+
+// Close closes the connection identified by the JSON-encoded CloseRequest in the request body.
+// The connection is closed with the application error code and the reason given in the request.
+// If no code is given, the connection is closed with error code 0.
+// Only POST requests with a Content-Type of application/json are accepted.
+// Browsers can't send such requests cross-origin without a CORS preflight,
+// which prevents malicious websites from closing connections (cross-site request forgery).
+// The handler is registered as /debug/quic/close.
+func Close(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		http.Error(w, "expected an application/json request body", http.StatusUnsupportedMediaType)
+		return
+	}
+	var req CloseRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return
+	}
+	if req.Code > quicvarint.Max {
+		http.Error(w, fmt.Sprintf("invalid error code: %d", req.Code), http.StatusBadRequest)
+		return
+	}
+	conn, ok := findConn(w, uint64(req.ID))
+	if !ok {
+		return
+	}
+	conn.CloseWithError(req.Code, req.Reason)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Connection is the JSON representation of a QUIC connection.
+type Connection struct {
+	ID                quic.ConnectionTracingID `json:"id"`
+	LocalAddr         string                   `json:"local_addr"`
+	RemoteAddr        string                   `json:"remote_addr"`
+	Version           string                   `json:"version"`
+	ALPN              string                   `json:"alpn,omitempty"`
+	ServerName        string                   `json:"server_name,omitempty"`
+	HandshakeComplete bool                     `json:"handshake_complete"`
+	Used0RTT          bool                     `json:"used_0rtt"`
+	Stats             Stats                    `json:"stats"`
+	Paths             []Path                   `json:"paths"`
+	Streams           []Stream                 `json:"streams"`
+	Datagrams         Datagrams                `json:"datagrams"`
+}
+
+// Stats is the JSON representation of the quic.ConnectionStats.
+// Durations are given in milliseconds.
+type Stats struct {
+	MinRTT        float64 `json:"min_rtt"`
+	LatestRTT     float64 `json:"latest_rtt"`
+	SmoothedRTT   float64 `json:"smoothed_rtt"`
+	MeanDeviation float64 `json:"mean_deviation"`
+
+	BytesSent             uint64 `json:"bytes_sent"`
+	PacketsSent           uint64 `json:"packets_sent"`
+	BytesReceived         uint64 `json:"bytes_received"`
+	PacketsReceived       uint64 `json:"packets_received"`
+	BytesLost             uint64 `json:"bytes_lost"`
+	PacketsLost           uint64 `json:"packets_lost"`
+	PacketsSpuriouslyLost uint64 `json:"packets_spuriously_lost"`
+	BytesRetransmitted    uint64 `json:"bytes_retransmitted"`
+	PTOCount              uint64 `json:"pto_count"`
+
+	CongestionWindow uint64 `json:"congestion_window"`
+	BytesInFlight    uint64 `json:"bytes_in_flight"`
+	PacingRate       uint64 `json:"pacing_rate"`
+	MTU              uint64 `json:"mtu"`
+
+	ECT0Count  uint64 `json:"ect0_count"`
+	ECT1Count  uint64 `json:"ect1_count"`
+	ECNCECount uint64 `json:"ecn_ce_count"`
+
+	KeyUpdates uint64 `json:"key_updates"`
+
+	ConnectionFlowControlBlockedTime float64 `json:"connection_flow_control_blocked_time"`
+	StreamFlowControlBlockedTime     float64 `json:"stream_flow_control_blocked_time"`
+}
+
+// Path is the JSON representation of the quic.PathStats.
+// Durations are given in milliseconds.
+type Path struct {
+	LocalAddr       string  `json:"local_addr"`
+	RemoteAddr      string  `json:"remote_addr"`
+	Validated       bool    `json:"validated"`
+	Active          bool    `json:"active"`
+	ValidationRTT   float64 `json:"validation_rtt,omitempty"`
+	SmoothedRTT     float64 `json:"smoothed_rtt,omitempty"`
+	LatestRTT       float64 `json:"latest_rtt,omitempty"`
+	BytesSent       uint64  `json:"bytes_sent"`
+	PacketsSent     uint64  `json:"packets_sent"`
+	BytesReceived   uint64  `json:"bytes_received"`
+	PacketsReceived uint64  `json:"packets_received"`
+}
+
+// Stream is the JSON representation of an open stream.
+// Send is nil for unidirectional streams opened by the peer,
+// Receive is nil for unidirectional streams opened by this endpoint.
+type Stream struct {
+	ID      quic.StreamID  `json:"id"`
+	Send    *SendStream    `json:"send,omitempty"`
+	Receive *ReceiveStream `json:"receive,omitempty"`
+}
+
+// SendStream is the JSON representation of the send direction of a stream.
+// Durations are given in milliseconds.
+type SendStream struct {
+	Offset                 uint64  `json:"offset"`
+	BytesRetransmitted     uint64  `json:"bytes_retransmitted"`
+	FlowControlBlocked     bool    `json:"flow_control_blocked"`
+	FlowControlBlockedTime float64 `json:"flow_control_blocked_time"`
+}
+
+// ReceiveStream is the JSON representation of the receive direction of a stream.
+type ReceiveStream struct {
+	ReadOffset            uint64 `json:"read_offset"`
+	HighestReceivedOffset uint64 `json:"highest_received_offset"`
+	FinalSizeKnown        bool   `json:"final_size_known"`
+}
+
+// Datagrams is the JSON representation of the state of the connection's DATAGRAM queues.
+type Datagrams struct {
+	// Supported says if both endpoints support QUIC datagrams (RFC 9221).
+	Supported        bool   `json:"supported"`
+	QueuedForSending uint64 `json:"queued_for_sending"`
+	QueuedForReading uint64 `json:"queued_for_reading"`
+}
+
+func newConnection(conn *quic.Conn) Connection {
+	state := conn.ConnectionState()
+	stats := conn.ConnectionStats()
+	c := Connection{
+		ID:         conn.TracingID(),
+		LocalAddr:  addrString(conn.LocalAddr()),
+		RemoteAddr: addrString(conn.RemoteAddr()),
+		Version:    state.Version.String(),
+		ALPN:       state.TLS.NegotiatedProtocol,
+		ServerName: state.TLS.ServerName,
+		Used0RTT:   state.Used0RTT,
+		Stats: Stats{
+			MinRTT:        milliseconds(stats.MinRTT),
+			LatestRTT:     milliseconds(stats.LatestRTT),
+			SmoothedRTT:   milliseconds(stats.SmoothedRTT),
+			MeanDeviation: milliseconds(stats.MeanDeviation),
+
+			BytesSent:             stats.BytesSent,
+			PacketsSent:           stats.PacketsSent,
+			BytesReceived:         stats.BytesReceived,
+			PacketsReceived:       stats.PacketsReceived,
+			BytesLost:             stats.BytesLost,
+			PacketsLost:           stats.PacketsLost,
+			PacketsSpuriouslyLost: stats.PacketsSpuriouslyLost,
+			BytesRetransmitted:    stats.BytesRetransmitted,
+			PTOCount:              stats.PTOCount,
+
+			CongestionWindow: stats.CongestionWindow,
+			BytesInFlight:    stats.BytesInFlight,
+			PacingRate:       stats.PacingRate,
+			MTU:              stats.MTU,
+
+			ECT0Count:  stats.ECT0Count,
+			ECT1Count:  stats.ECT1Count,
+			ECNCECount: stats.ECNCECount,
+
+			KeyUpdates: stats.KeyUpdates,
+
+			ConnectionFlowControlBlockedTime: milliseconds(stats.ConnectionFlowControlBlockedTime),
+			StreamFlowControlBlockedTime:     milliseconds(stats.StreamFlowControlBlockedTime),
+		},
+		Paths:   []Path{},
+		Streams: []Stream{},
+		Datagrams: Datagrams{
+			Supported:        state.SupportsDatagrams.Local && state.SupportsDatagrams.Remote,
+			QueuedForSending: stats.DatagramsQueuedForSending,
+			QueuedForReading: stats.DatagramsQueuedForReading,
+		},
+	}
+	select {
+	case <-conn.HandshakeComplete():
+		c.HandshakeComplete = true
+	default:
+	}
+	for _, p := range conn.Paths() {
+		c.Paths = append(c.Paths, Path{
+			LocalAddr:       addrString(p.LocalAddr),
+			RemoteAddr:      addrString(p.RemoteAddr),
+			Validated:       p.Validated,
+			Active:          p.Active,
+			ValidationRTT:   milliseconds(p.ValidationRTT),
+			SmoothedRTT:     milliseconds(p.SmoothedRTT),
+			LatestRTT:       milliseconds(p.LatestRTT),
+			BytesSent:       p.BytesSent,
+			PacketsSent:     p.PacketsSent,
+			BytesReceived:   p.BytesReceived,
+			PacketsReceived: p.PacketsReceived,
+		})
+	}
+	for _, str := range conn.Streams() {
+		s := Stream{ID: str.ID}
+		if str.Send != nil {
+			s.Send = &SendStream{
+				Offset:                 str.Send.BytesSent,
+				BytesRetransmitted:     str.Send.BytesRetransmitted,
+				FlowControlBlocked:     str.Send.FlowControlBlocked,
+				FlowControlBlockedTime: milliseconds(str.Send.FlowControlBlockedTime),
+			}
+		}
+		if str.Receive != nil {
+			s.Receive = &ReceiveStream{
+				ReadOffset:            str.Receive.BytesRead,
+				HighestReceivedOffset: str.Receive.HighestReceivedOffset,
+				FinalSizeKnown:        str.Receive.FinalSizeKnown,
+			}
+		}
+		c.Streams = append(c.Streams, s)
+	}
+	return c
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
+
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
@@ -0,0 +1,203 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/http"
+	"github.com/nukilabs/http/httptest"
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/internal/testdata"
+	tls "github.com/nukilabs/utls"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newUDPConnLocalhost(t testing.TB) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// newConn establishes a connection to a server running on a registered Transport.
+// It returns the client's and the server's connection.
+func newConn(t *testing.T) (*quic.Conn, *quic.Conn) {
+	t.Helper()
+
+	tr := &quic.Transport{Conn: newUDPConnLocalhost(t)}
+	Register(tr)
+	t.Cleanup(func() {
+		Unregister(tr)
+		tr.Close()
+	})
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{"debug"}
+	ln, err := tr.Listen(tlsConf, &quic.Config{EnableDatagrams: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), ln.Addr(), &tls.Config{
+		ServerName: "localhost",
+		RootCAs:    testdata.GetRootCA(),
+		NextProtos: []string{"debug"},
+	}, &quic.Config{EnableDatagrams: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.CloseWithError(0, "") })
+	serverConn, err := ln.Accept(ctx)
+	require.NoError(t, err)
+	return conn, serverConn
+}
+
+func getConnections(t *testing.T) []Connection {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Conns(rec, httptest.NewRequest(http.MethodGet, "/debug/quic/conns", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var connections []Connection
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &connections))
+	return connections
+}
+
+func TestConns(t *testing.T) {
+	require.Empty(t, getConnections(t))
+
+	conn, serverConn := newConn(t)
+	str, err := conn.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	serverStr, err := serverConn.AcceptStream(ctx)
+	require.NoError(t, err)
+	_, err = io.ReadFull(serverStr, make([]byte, 3))
+	require.NoError(t, err)
+
+	connections := getConnections(t)
+	require.Len(t, connections, 1)
+	c := connections[0]
+	require.Equal(t, serverConn.TracingID(), c.ID)
+	require.Equal(t, serverConn.LocalAddr().String(), c.LocalAddr)
+	require.Equal(t, conn.LocalAddr().String(), c.RemoteAddr)
+	require.Equal(t, "v1", c.Version)
+	require.Equal(t, "debug", c.ALPN)
+	require.Equal(t, "localhost", c.ServerName)
+	require.True(t, c.HandshakeComplete)
+	require.NotZero(t, c.Stats.SmoothedRTT)
+	require.NotZero(t, c.Stats.PacketsReceived)
+	require.NotZero(t, c.Stats.CongestionWindow)
+	require.Len(t, c.Paths, 1)
+	require.True(t, c.Paths[0].Active)
+	require.True(t, c.Datagrams.Supported)
+	require.Equal(t, []Stream{{
+		ID:      0,
+		Send:    &SendStream{},
+		Receive: &ReceiveStream{ReadOffset: 3, HighestReceivedOffset: 6},
+	}}, c.Streams)
+
+	// request a single connection
+	rec := httptest.NewRecorder()
+	Conns(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/debug/quic/conns?id=%d", c.ID), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var single Connection
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &single))
+	require.Equal(t, c.ID, single.ID)
+	require.Equal(t, c.Streams, single.Streams)
+}
+
+func TestConnsErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Conns(rec, httptest.NewRequest(http.MethodGet, "/debug/quic/conns?id=foo", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	Conns(rec, httptest.NewRequest(http.MethodGet, "/debug/quic/conns?id=1337", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestUnregister(t *testing.T) {
+	_, serverConn := newConn(t)
+	require.Len(t, getConnections(t), 1)
+
+	tr := &quic.Transport{Conn: newUDPConnLocalhost(t)}
+	defer tr.Close()
+	Register(tr)
+	Register(tr) // registering the same Transport twice has no effect
+	Unregister(tr)
+	connections := getConnections(t)
+	require.Len(t, connections, 1)
+	require.Equal(t, serverConn.TracingID(), connections[0].ID)
+}
+
+func closeRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/debug/quic/close", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestClose(t *testing.T) {
+	conn, serverConn := newConn(t)
+
+	rec := httptest.NewRecorder()
+	Close(rec, closeRequest(fmt.Sprintf(`{"id": %d, "code": 66, "reason": "closed by operator"}`, serverConn.TracingID())))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	select {
+	case <-conn.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the connection to be closed")
+	}
+	require.ErrorIs(t, context.Cause(conn.Context()), &quic.ApplicationError{
+		Remote:       true,
+		ErrorCode:    0x42,
+		ErrorMessage: "closed by operator",
+	})
+	require.Eventually(t, func() bool { return len(getConnections(t)) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestCloseErrors(t *testing.T) {
+	_, serverConn := newConn(t)
+	body := fmt.Sprintf(`{"id": %d}`, serverConn.TracingID())
+
+	rec := httptest.NewRecorder()
+	Close(rec, httptest.NewRequest(http.MethodGet, "/debug/quic/close", strings.NewReader(body)))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+
+	// Requests that a browser can send cross-origin without a preflight are refused.
+	for _, contentType := range []string{"", "application/x-www-form-urlencoded", "text/plain"} {
+		req := httptest.NewRequest(http.MethodPost, "/debug/quic/close", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec = httptest.NewRecorder()
+		Close(rec, req)
+		require.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	Close(rec, closeRequest(fmt.Sprintf(`{"id": %d, "code": "foo"}`, serverConn.TracingID())))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	Close(rec, closeRequest(fmt.Sprintf(`{"id": %d, "code": %d}`, serverConn.TracingID(), uint64(1)<<62)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	Close(rec, closeRequest(`{"id": 1337}`))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// the connection is still alive
+	require.Len(t, getConnections(t), 1)
+}
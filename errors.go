@@ -69,6 +69,8 @@ const (
 	AEADLimitReached = qerr.AEADLimitReached
 	// NoViablePathError is the NO_VIABLE_PATH_ERROR transport error code.
 	NoViablePathError = qerr.NoViablePathError
+	// VersionNegotiationErrorCode is the VERSION_NEGOTIATION_ERROR transport error code, see RFC 9368.
+	VersionNegotiationErrorCode = qerr.VersionNegotiationErrorCode
 )
 
 // A StreamError is used to signal stream cancellations.
@@ -281,6 +281,7 @@ func runHandshake(runConfig [confLen]byte, messageConfig uint8, clientConf *tls.
 		clientTP,
 		clientConf,
 		enable0RTTClient,
+		handshake.KeyUpdatePolicy{},
 		&utils.RTTStats{},
 		nil,
 		utils.DefaultLogger.WithPrefix("client"),
@@ -298,6 +299,8 @@ func runHandshake(runConfig [confLen]byte, messageConfig uint8, clientConf *tls.
 		serverTP,
 		serverConf,
 		enable0RTTServer,
+		nil,
+		handshake.KeyUpdatePolicy{},
 		&utils.RTTStats{},
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
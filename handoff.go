@@ -0,0 +1,98 @@
+package quic
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+
+	"github.com/nukilabs/quic-go/internal/monotime"
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/wire"
+)
+
+// A ForwardedPacket is a QUIC packet with a destination connection ID that is not known to the Transport.
+// During a restart, such a packet might belong to a connection handled by a different process
+// that shares the same UDP socket.
+type ForwardedPacket struct {
+	// Data is the UDP datagram.
+	// It is only valid until the callback returns, and needs to be copied if it is retained.
+	Data       []byte
+	RemoteAddr net.Addr
+	// LocalAddr is the address the packet was sent to.
+	// It is only set if the Transport's Conn reports it (which is the case for a *net.UDPConn
+	// bound to an unspecified address), and is used to send responses from the same address.
+	LocalAddr netip.Addr
+	// DestConnectionID is the destination connection ID of the (first) QUIC packet in the datagram.
+	DestConnectionID ConnectionID
+	// IsLongHeader says if the packet is a long header packet.
+	IsLongHeader bool
+}
+
+// File returns a copy of the file descriptor of the underlying UDP socket.
+// It can be passed to a different process (e.g. using os/exec.Cmd.ExtraFiles),
+// which can then create a new Transport using net.FilePacketConn.
+// This allows a new process to take over the socket without disrupting existing connections:
+// The old process keeps handling its connections, and the Transports forward packets for unknown
+// connection IDs to each other, see [Transport.ForwardPacket] and [Transport.InjectPacket].
+//
+// It is the caller's responsibility to close the returned file.
+// File only works if the Transport's Conn has a File method, as a *net.UDPConn has.
+func (t *Transport) File() (*os.File, error) {
+	c, ok := t.Conn.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, errors.New("quic: connection doesn't support exporting its file descriptor")
+	}
+	return c.File()
+}
+
+// InjectPacket passes a UDP datagram to the Transport, as if it had been received on the Transport's Conn.
+// It is used to pass packets that were forwarded from a different Transport, see [Transport.ForwardPacket].
+// Only the Data, RemoteAddr and LocalAddr fields of the ForwardedPacket are used.
+// Packets that are injected are never forwarded again, even if the connection ID is unknown.
+// Responses are sent on the Transport's Conn, from the LocalAddr if it is set.
+// The data is copied, and can be reused after InjectPacket returns.
+func (t *Transport) InjectPacket(p *ForwardedPacket) error {
+	if err := t.init(false); err != nil {
+		return err
+	}
+	if len(p.Data) > protocol.MaxPacketBufferSize {
+		return errors.New("quic: packet too large")
+	}
+	t.mutex.Lock()
+	closeErr := t.closeErr
+	t.mutex.Unlock()
+	if closeErr != nil {
+		return closeErr
+	}
+
+	buffer := getPacketBuffer()
+	buffer.Data = append(buffer.Data, p.Data...)
+	t.handlePacketImpl(receivedPacket{
+		remoteAddr: p.RemoteAddr,
+		rcvTime:    monotime.Now(),
+		data:       buffer.Data,
+		buffer:     buffer,
+		info:       packetInfo{addr: p.LocalAddr},
+	}, false)
+	return nil
+}
+
+// maybeForwardPacket passes the packet to the ForwardPacket callback.
+// It returns true if the packet was forwarded.
+func (t *Transport) maybeForwardPacket(p receivedPacket, connID protocol.ConnectionID) bool {
+	if t.ForwardPacket == nil {
+		return false
+	}
+	forwarded := t.ForwardPacket(&ForwardedPacket{
+		Data:             p.data,
+		RemoteAddr:       p.remoteAddr,
+		LocalAddr:        p.info.addr,
+		DestConnectionID: connID,
+		IsLongHeader:     wire.IsLongHeaderPacket(p.data[0]),
+	})
+	if forwarded {
+		p.buffer.MaybeRelease()
+	}
+	return forwarded
+}
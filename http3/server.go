@@ -151,6 +151,13 @@ type Server struct {
 	// If zero or negative, there is no timeout.
 	IdleTimeout time.Duration
 
+	// RejectUnsafeEarlyData makes the server respond with a 425 (Too Early) status code (RFC 8470)
+	// to requests that were received in 0-RTT, unless the request method is safe
+	// (GET, HEAD, OPTIONS or TRACE, see RFC 9110, section 9.2.1).
+	// 0-RTT data can be replayed by an attacker, and unsafe requests might not be idempotent.
+	// If false, it's the client's responsibility to decide which requests are eligible for 0-RTT.
+	RejectUnsafeEarlyData bool
+
 	// ConnContext optionally specifies a function that modifies the context used for a new connection c.
 	// The provided ctx has a ServerContextKey value.
 	ConnContext func(ctx context.Context, c *quic.Conn) context.Context
@@ -449,6 +456,7 @@ func (s *Server) newRawServerConn(conn *quic.Conn) (*RawServerConn, *quic.SendSt
 		connCtx,
 		s.Handler,
 		s.maxHeaderBytes(),
+		s.RejectUnsafeEarlyData,
 	)
 
 	// open the control stream and send a SETTINGS frame, it's also used to send a GOAWAY frame later
@@ -27,6 +27,8 @@ type RawServerConn struct {
 	requestHandler http.Handler
 	maxHeaderBytes int
 
+	rejectUnsafeEarlyData bool
+
 	decoder *qpack.Decoder
 
 	qlogger qlogwriter.Recorder
@@ -42,15 +44,17 @@ func newRawServerConn(
 	serverContext context.Context,
 	requestHandler http.Handler,
 	maxHeaderBytes int,
+	rejectUnsafeEarlyData bool,
 ) *RawServerConn {
 	c := &RawServerConn{
-		idleTimeout:    idleTimeout,
-		serverContext:  serverContext,
-		requestHandler: requestHandler,
-		maxHeaderBytes: maxHeaderBytes,
-		decoder:        qpack.NewDecoder(),
-		qlogger:        qlogger,
-		logger:         logger,
+		idleTimeout:           idleTimeout,
+		serverContext:         serverContext,
+		requestHandler:        requestHandler,
+		maxHeaderBytes:        maxHeaderBytes,
+		rejectUnsafeEarlyData: rejectUnsafeEarlyData,
+		decoder:               qpack.NewDecoder(),
+		qlogger:               qlogger,
+		logger:                logger,
 	}
 	c.rawConn = *newRawConn(conn, enableDatagrams, c.onStreamsEmpty, nil, qlogger, logger)
 	if idleTimeout > 0 {
@@ -164,6 +168,13 @@ func (c *RawServerConn) handleRequestStream(str *stateTrackingStream) {
 		return
 	}
 
+	// Requests received (even partially) in 0-RTT packets might have been replayed.
+	if c.rejectUnsafeEarlyData && !isSafeMethod(req.Method) && str.ReceivedIn0RTT() {
+		str.CancelRead(quic.StreamErrorCode(ErrCodeRequestRejected))
+		c.rejectWithTooEarly(str)
+		return
+	}
+
 	connState := conn.ConnectionState().TLS
 	req.TLS = &connState
 	req.RemoteAddr = conn.RemoteAddr().String()
@@ -199,7 +210,8 @@ func (c *RawServerConn) handleRequestStream(str *stateTrackingStream) {
 		handler = http.DefaultServeMux
 	}
 
-	// It's the client's responsibility to decide which requests are eligible for 0-RTT.
+	// Unless rejectUnsafeEarlyData is set, it's the client's responsibility
+	// to decide which requests are eligible for 0-RTT.
 	var panicked bool
 	func() {
 		defer func() {
@@ -255,6 +267,25 @@ func (c *RawServerConn) rejectWithHeaderFieldsTooLarge(str *stateTrackingStream)
 	r.Flush()
 }
 
+// rejectWithTooEarly sends a 425 Response (Too Early), see RFC 8470.
+func (c *RawServerConn) rejectWithTooEarly(str *stateTrackingStream) {
+	hstr := newStream(str, &c.rawConn, nil, nil, c.qlogger)
+	defer hstr.Close()
+	r := newResponseWriter(hstr, &c.rawConn, false, c.logger)
+	r.WriteHeader(http.StatusTooEarly)
+	r.Flush()
+}
+
+// isSafeMethod says if the request method is safe, as defined in RFC 9110, section 9.2.1.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
 // HandleUnidirectionalStream handles an incoming unidirectional stream.
 func (c *RawServerConn) HandleUnidirectionalStream(str *quic.ReceiveStream) {
 	c.rawConn.handleUnidirectionalStream(str, true)
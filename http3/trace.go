@@ -31,6 +31,10 @@ func (c *fakeConn) SetWriteDeadline(t time.Time) error { panic("connection opera
 func (c *fakeConn) RemoteAddr() net.Addr               { return c.conn.RemoteAddr() }
 func (c *fakeConn) LocalAddr() net.Addr                { return c.conn.LocalAddr() }
 
+// QUICConn returns the QUIC connection.
+// It allows users of the httptrace.ClientTrace to access the connection in GotConn.
+func (c *fakeConn) QUICConn() *quic.Conn { return c.conn }
+
 func traceGotConn(trace *httptrace.ClientTrace, conn *quic.Conn, reused bool) {
 	if trace != nil && trace.GotConn != nil {
 		trace.GotConn(httptrace.GotConnInfo{
@@ -3,6 +3,7 @@ package self_test
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"fmt"
 	"io"
 	"testing"
@@ -149,3 +150,124 @@ func benchmarkTransfer(b *testing.B, data []byte) {
 		c.CloseWithError(0, "")
 	}
 }
+
+func BenchmarkStreamCopy(b *testing.B) {
+	data := make([]byte, 8<<20)
+	rand.Read(data)
+
+	// Wrapping the stream hides the io.ReaderFrom and io.WriterTo implementations from io.Copy.
+	b.Run("Write", func(b *testing.B) {
+		benchmarkStreamCopy(b, data,
+			func(str *quic.SendStream, r io.Reader) error {
+				_, err := io.Copy(struct{ io.Writer }{str}, r)
+				return err
+			},
+			func(w io.Writer, str *quic.ReceiveStream) error {
+				_, err := io.Copy(w, struct{ io.Reader }{str})
+				return err
+			},
+		)
+	})
+	b.Run("ReadFrom", func(b *testing.B) {
+		benchmarkStreamCopy(b, data,
+			func(str *quic.SendStream, r io.Reader) error {
+				_, err := io.Copy(str, r)
+				return err
+			},
+			func(w io.Writer, str *quic.ReceiveStream) error {
+				_, err := io.Copy(w, str)
+				return err
+			},
+		)
+	})
+	b.Run("WriteBuffers", func(b *testing.B) {
+		benchmarkStreamCopy(b, data,
+			func(str *quic.SendStream, r io.Reader) error {
+				bufs := make([][]byte, 32)
+				for i := range bufs {
+					bufs[i] = make([]byte, 1024)
+				}
+				for {
+					var n int
+					var err error
+					for i := 0; i < len(bufs) && err == nil; i++ {
+						var m int
+						m, err = io.ReadFull(r, bufs[i])
+						bufs[i] = bufs[i][:m]
+						n += m
+					}
+					if n > 0 {
+						if _, err := str.WriteBuffers(bufs); err != nil {
+							return err
+						}
+					}
+					for i := range bufs {
+						bufs[i] = bufs[i][:cap(bufs[i])]
+					}
+					if err == io.EOF || err == io.ErrUnexpectedEOF {
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+				}
+			},
+			func(w io.Writer, str *quic.ReceiveStream) error {
+				_, err := io.Copy(w, str)
+				return err
+			},
+		)
+	})
+}
+
+func benchmarkStreamCopy(
+	b *testing.B,
+	data []byte,
+	send func(*quic.SendStream, io.Reader) error,
+	receive func(io.Writer, *quic.ReceiveStream) error,
+) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	ln, err := quic.Listen(newUDPConnLocalhost(b), tlsConfig, getQuicConfig(nil))
+	require.NoError(b, err)
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(b), ln.Addr(), tlsClientConfig, getQuicConfig(nil))
+	require.NoError(b, err)
+	defer conn.CloseWithError(0, "")
+
+	serverConn, err := ln.Accept(context.Background())
+	require.NoError(b, err)
+	defer serverConn.CloseWithError(0, "")
+
+	for b.Loop() {
+		errChan := make(chan error, 1)
+		go func() {
+			str, err := serverConn.AcceptUniStream(context.Background())
+			if err != nil {
+				errChan <- err
+				return
+			}
+			// hide the io.ReaderFrom implementation of io.Discard
+			errChan <- receive(struct{ io.Writer }{io.Discard}, str)
+		}()
+
+		str, err := conn.OpenUniStreamSync(context.Background())
+		if err != nil {
+			b.Fatalf("error opening stream: %v", err)
+		}
+		// hide the io.WriterTo implementation of bytes.Reader
+		if err := send(str, struct{ io.Reader }{bytes.NewReader(data)}); err != nil {
+			b.Fatalf("error sending data: %v", err)
+		}
+		if err := str.Close(); err != nil {
+			b.Fatalf("error closing stream: %v", err)
+		}
+		if err := <-errChan; err != nil {
+			b.Fatalf("error receiving data: %v", err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package self_test
 import (
 	"context"
 	tls "github.com/nukilabs/utls"
+	"io"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -228,3 +229,185 @@ func testTransportClose(t *testing.T, conn net.PacketConn, closeFn func(), expec
 		require.ErrorIs(t, err, expectedErr)
 	}
 }
+
+func TestTransportShutdown(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 10 * time.Millisecond
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, rtt)
+		defer closeFn(t)
+
+		tr := &quic.Transport{Conn: serverPacketConn}
+		defer tr.Close()
+		server, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientPacketConn, server.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+
+		shutdownDone := make(chan error, 1)
+		go func() { shutdownDone <- tr.Shutdown(context.Background()) }()
+
+		// connections that completed the handshake can still be accepted
+		sconn, err := server.Accept(ctx)
+		require.NoError(t, err)
+		_, err = server.Accept(ctx)
+		require.ErrorIs(t, err, quic.ErrServerClosed)
+
+		// existing connections continue to work
+		str, err := conn.OpenStream()
+		require.NoError(t, err)
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+		require.NoError(t, str.Close())
+		sstr, err := sconn.AcceptStream(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(sstr)
+		require.NoError(t, err)
+		require.Equal(t, []byte("foobar"), data)
+
+		// no new connections are dialed
+		_, err = tr.Dial(ctx, server.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.Error(t, err)
+
+		select {
+		case <-shutdownDone:
+			t.Fatal("shutdown should wait for the connection to be closed")
+		case <-time.After(time.Second):
+		}
+
+		conn.CloseWithError(0, "")
+		select {
+		case err := <-shutdownDone:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	})
+}
+
+func TestTransportShutdownTimeout(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 10 * time.Millisecond
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, rtt)
+		defer closeFn(t)
+
+		tr := &quic.Transport{Conn: serverPacketConn, ShutdownErrorCode: 1337}
+		defer tr.Close()
+		server, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientPacketConn, server.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		_, err = server.Accept(ctx)
+		require.NoError(t, err)
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer shutdownCancel()
+		require.ErrorIs(t, tr.Shutdown(shutdownCtx), context.DeadlineExceeded)
+
+		select {
+		case <-conn.Context().Done():
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+		var appErr *quic.ApplicationError
+		require.ErrorAs(t, context.Cause(conn.Context()), &appErr)
+		require.True(t, appErr.Remote)
+		require.Equal(t, quic.ApplicationErrorCode(1337), appErr.ErrorCode)
+	})
+}
+
+func TestTransportShutdownTimeoutConnectionCloseLost(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 10 * time.Millisecond
+		var dropNextPacketToClient atomic.Bool
+		router := &droppingRouter{Drop: func(p simnet.Packet) bool {
+			return p.To.String() == "1.0.0.1:9001" && dropNextPacketToClient.CompareAndSwap(true, false)
+		}}
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLinkWithRouter(t, rtt, router)
+		defer closeFn(t)
+
+		tr := &quic.Transport{Conn: serverPacketConn, ShutdownErrorCode: 1337}
+		defer tr.Close()
+		server, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientPacketConn, server.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		_, err = server.Accept(ctx)
+		require.NoError(t, err)
+		synctest.Wait()
+
+		// the first CONNECTION_CLOSE packet is lost
+		dropNextPacketToClient.Store(true)
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer shutdownCancel()
+		shutdownDone := make(chan error, 1)
+		go func() { shutdownDone <- tr.Shutdown(shutdownCtx) }()
+
+		time.Sleep(150 * time.Millisecond)
+		require.False(t, dropNextPacketToClient.Load())
+		select {
+		case <-shutdownDone:
+			t.Fatal("shutdown should wait for the closed connection to expire")
+		default:
+		}
+		// the closed connection responds to the client's packet with a CONNECTION_CLOSE
+		str, err := conn.OpenStream()
+		require.NoError(t, err)
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+		select {
+		case <-conn.Context().Done():
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+		var appErr *quic.ApplicationError
+		require.ErrorAs(t, context.Cause(conn.Context()), &appErr)
+		require.Equal(t, quic.ApplicationErrorCode(1337), appErr.ErrorCode)
+
+		select {
+		case err := <-shutdownDone:
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	})
+}
+
+func TestTransportShutdownTimeoutDrainPeriodLimited(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		// with this RTT, three times the PTO is significantly longer than the maximum drain period
+		const rtt = 2 * time.Second
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, rtt)
+		defer closeFn(t)
+
+		tr := &quic.Transport{Conn: serverPacketConn, ShutdownErrorCode: 1337}
+		defer tr.Close()
+		server, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*rtt)
+		defer cancel()
+		conn, err := quic.Dial(ctx, clientPacketConn, server.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		_, err = server.Accept(ctx)
+		require.NoError(t, err)
+		synctest.Wait()
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer shutdownCancel()
+		start := time.Now()
+		require.ErrorIs(t, tr.Shutdown(shutdownCtx), context.DeadlineExceeded)
+		took := time.Since(start)
+		require.Greater(t, took, 3*time.Second)
+		require.LessOrEqual(t, took, 3*time.Second+100*time.Millisecond)
+	})
+}
@@ -12,7 +12,7 @@ import (
 	"time"
 
 	"github.com/nukilabs/quic-go"
-	quicproxy "github.com/nukilabs/quic-go/integrationtests/tools/proxy"
+	"github.com/nukilabs/quic-go/quicproxy"
 
 	"github.com/stretchr/testify/require"
 )
@@ -111,6 +111,17 @@ func TestConnectionMigration(t *testing.T) {
 	require.ErrorIs(t, path.Switch(), quic.ErrPathNotValidated)
 	require.NoError(t, path.Probe(ctx))
 	require.Less(t, int(packetsPath2.Load()), 5)
+	stats := path.Stats()
+	require.True(t, stats.Validated)
+	require.False(t, stats.Active)
+	require.Equal(t, tr2.Conn.LocalAddr(), stats.LocalAddr)
+	require.Equal(t, proxy.LocalAddr(), stats.RemoteAddr)
+	require.GreaterOrEqual(t, stats.ValidationRTT, rtt)
+	require.NotZero(t, stats.PacketsSent)
+	require.NotZero(t, stats.BytesSent)
+	require.NotZero(t, stats.PacketsReceived)
+	require.NotZero(t, stats.BytesReceived)
+	require.Zero(t, stats.SmoothedRTT)
 
 	// make sure that no more packets are sent on path 2 before switching to the path
 	c2 := packetsPath2.Load()
@@ -126,6 +137,20 @@ func TestConnectionMigration(t *testing.T) {
 	require.Equal(t, c1, packetsPath1.Load())
 	require.Greater(t, packetsPath2.Load(), c2)
 	require.Equal(t, tr2.Conn.LocalAddr(), conn.LocalAddr())
+	require.True(t, path.Stats().Active)
+	require.NotZero(t, path.Stats().SmoothedRTT)
+	require.Greater(t, path.Stats().BytesReceived, stats.BytesReceived)
+
+	paths := conn.Paths()
+	require.Len(t, paths, 2)
+	require.Equal(t, tr1.Conn.LocalAddr(), paths[0].LocalAddr)
+	require.True(t, paths[0].Validated)
+	require.False(t, paths[0].Active)
+	require.Zero(t, paths[0].SmoothedRTT)
+	require.NotZero(t, paths[0].BytesSent)
+	require.NotZero(t, paths[0].BytesReceived)
+	require.Equal(t, path.Stats().LocalAddr, paths[1].LocalAddr)
+	require.True(t, paths[1].Active)
 
 	// switch back to the handshake path
 	time.Sleep(3 * rtt) // wait for ACKs
@@ -5,6 +5,7 @@ import (
 	"context"
 	mrand "math/rand/v2"
 	"net"
+	"slices"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -251,3 +252,155 @@ func TestDatagramLoss(t *testing.T) {
 		assert.EqualValues(t, numDatagrams-numDroppedToClient, clientDatagrams, "datagrams received by the client")
 	})
 }
+
+func TestDatagramFeedback(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 100 * time.Millisecond
+		const numDatagrams = 50
+		const datagramSize = 500
+
+		clientAddr := &net.UDPAddr{IP: net.ParseIP("1.0.0.1"), Port: 9001}
+		serverAddr := &net.UDPAddr{IP: net.ParseIP("1.0.0.2"), Port: 9002}
+		var dropped atomic.Int32
+		n := &simnet.Simnet{
+			Router: &directionAwareDroppingRouter{
+				ClientAddr: clientAddr,
+				ServerAddr: serverAddr,
+				Drop: func(d direction, p simnet.Packet) bool {
+					// only drop Short Header packets with DATAGRAM frames sent to the server
+					if d != directionToServer || wire.IsLongHeaderPacket(p.Data[0]) || len(p.Data) < datagramSize {
+						return false
+					}
+					if mrand.Int()%4 == 0 {
+						dropped.Add(1)
+						return true
+					}
+					return false
+				},
+			},
+		}
+		settings := simnet.NodeBiDiLinkSettings{Latency: rtt / 2}
+		clientPacketConn := n.NewEndpoint(clientAddr, settings)
+		defer clientPacketConn.Close()
+		serverPacketConn := n.NewEndpoint(serverAddr, settings)
+		defer serverPacketConn.Close()
+		require.NoError(t, n.Start())
+		defer n.Close()
+
+		server, err := quic.Listen(
+			serverPacketConn,
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true, EnableDatagrams: true}),
+		)
+		require.NoError(t, err)
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		clientConn, err := quic.Dial(
+			ctx,
+			clientPacketConn,
+			serverPacketConn.LocalAddr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true, EnableDatagrams: true}),
+		)
+		require.NoError(t, err)
+		defer clientConn.CloseWithError(0, "")
+
+		serverConn, err := server.Accept(ctx)
+		require.NoError(t, err)
+		defer serverConn.CloseWithError(0, "")
+
+		var received atomic.Int32
+		go func() {
+			for {
+				if _, err := serverConn.ReceiveDatagram(ctx); err != nil {
+					return
+				}
+				received.Add(1)
+			}
+		}()
+
+		var sent, acked, lost atomic.Int32
+		for i := range numDatagrams {
+			payload := bytes.Repeat([]byte{uint8(i)}, datagramSize)
+			require.NoError(t, clientConn.SendDatagramWithFeedback(payload, func(s quic.DatagramStatus) {
+				switch s {
+				case quic.DatagramSent:
+					sent.Add(1)
+				case quic.DatagramAcked:
+					acked.Add(1)
+				case quic.DatagramLost:
+					lost.Add(1)
+				default:
+					t.Errorf("unexpected datagram status: %s", s)
+				}
+			}))
+			time.Sleep(rtt)
+		}
+		// wait for the outstanding datagrams to be acknowledged or declared lost
+		time.Sleep(10 * rtt)
+
+		t.Logf("dropped %d out of %d datagrams", dropped.Load(), numDatagrams)
+		require.NotZero(t, dropped.Load())
+		require.EqualValues(t, numDatagrams, sent.Load())
+		require.Equal(t, received.Load(), acked.Load())
+		require.Equal(t, dropped.Load(), lost.Load())
+	})
+}
+
+func TestDatagramQueuePolicy(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clientConn, serverConn, closeFn := newSimnetLink(t, 10*time.Millisecond)
+		defer closeFn(t)
+
+		server, err := quic.Listen(
+			serverConn,
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{EnableDatagrams: true, DatagramReceiveQueueLen: 1000}),
+		)
+		require.NoError(t, err)
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.Dial(
+			ctx,
+			clientConn,
+			server.Addr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				EnableDatagrams:      true,
+				DatagramSendQueueLen: 4,
+				DatagramQueuePolicy:  quic.DatagramQueueDropOldest,
+			}),
+		)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		sconn, err := server.Accept(ctx)
+		require.NoError(t, err)
+		defer sconn.CloseWithError(0, "")
+
+		// Queueing more datagrams than fit into the queue doesn't block.
+		// Old datagrams that weren't sent yet are dropped.
+		var dropped atomic.Int32
+		for i := range 10 {
+			require.NoError(t, conn.SendDatagramWithFeedback([]byte{byte(i)}, func(s quic.DatagramStatus) {
+				if s == quic.DatagramDropped {
+					dropped.Add(1)
+				}
+			}))
+		}
+		require.NotZero(t, dropped.Load())
+
+		var received []byte
+		for len(received) < 10-int(dropped.Load()) {
+			data, err := sconn.ReceiveDatagram(ctx)
+			require.NoError(t, err)
+			received = append(received, data...)
+		}
+		require.Len(t, received, 10-int(dropped.Load()))
+		require.True(t, slices.IsSorted(received))
+		require.Equal(t, byte(9), received[len(received)-1])
+	})
+}
@@ -8,7 +8,7 @@ import (
 	"time"
 
 	"github.com/nukilabs/quic-go"
-	quicproxy "github.com/nukilabs/quic-go/integrationtests/tools/proxy"
+	"github.com/nukilabs/quic-go/quicproxy"
 
 	"github.com/stretchr/testify/require"
 )
@@ -0,0 +1,168 @@
+package self_test
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+// prefixConnIDGenerator generates connection IDs starting with a fixed byte.
+type prefixConnIDGenerator struct{ prefix byte }
+
+func (g *prefixConnIDGenerator) GenerateConnectionID() (quic.ConnectionID, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return quic.ConnectionID{}, err
+	}
+	b[0] = g.prefix
+	return protocol.ParseConnectionID(b), nil
+}
+
+func (g *prefixConnIDGenerator) ConnectionIDLen() int { return 8 }
+
+// handoffConn is a net.PacketConn that stops reading from the socket once it was handed off.
+// It simulates the kernel delivering all packets to the new process.
+type handoffConn struct {
+	conn *net.UDPConn
+
+	handedOff atomic.Bool
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ net.PacketConn = &handoffConn{}
+
+func newHandoffConn(conn *net.UDPConn) *handoffConn {
+	return &handoffConn{conn: conn, closed: make(chan struct{})}
+}
+
+func (c *handoffConn) handOff() {
+	c.handedOff.Store(true)
+	// unblock the current ReadFrom call
+	c.conn.SetReadDeadline(time.Now())
+}
+
+func (c *handoffConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		if c.handedOff.Load() {
+			<-c.closed
+			return 0, nil, net.ErrClosed
+		}
+		n, addr, err := c.conn.ReadFrom(b)
+		if err != nil && c.handedOff.Load() {
+			continue
+		}
+		return n, addr, err
+	}
+}
+
+func (c *handoffConn) WriteTo(b []byte, addr net.Addr) (int, error) { return c.conn.WriteTo(b, addr) }
+func (c *handoffConn) LocalAddr() net.Addr                          { return c.conn.LocalAddr() }
+func (c *handoffConn) SetDeadline(t time.Time) error                { return c.SetReadDeadline(t) }
+func (c *handoffConn) SetWriteDeadline(t time.Time) error           { return c.conn.SetWriteDeadline(t) }
+func (c *handoffConn) File() (*os.File, error)                      { return c.conn.File() }
+
+func (c *handoffConn) SetReadDeadline(t time.Time) error {
+	if c.handedOff.Load() && !t.IsZero() {
+		c.Close()
+	}
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *handoffConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func TestTransportHandoff(t *testing.T) {
+	oldConn := newHandoffConn(newUDPConnLocalhost(t))
+	oldTr := &quic.Transport{
+		Conn:                  oldConn,
+		ConnectionIDGenerator: &prefixConnIDGenerator{prefix: 1},
+	}
+	defer oldTr.Close()
+	oldLn, err := oldTr.Listen(getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	conn1, err := quic.Dial(ctx, newUDPConnLocalhost(t), oldLn.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn1.CloseWithError(0, "")
+	sconn1, err := oldLn.Accept(ctx)
+	require.NoError(t, err)
+
+	// hand the socket over to the new Transport
+	f, err := oldTr.File()
+	require.NoError(t, err)
+	newPacketConn, err := net.FilePacketConn(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	defer newPacketConn.Close()
+
+	var numForwarded atomic.Int64
+	newTr := &quic.Transport{
+		Conn:                  newPacketConn,
+		ConnectionIDGenerator: &prefixConnIDGenerator{prefix: 2},
+		ForwardPacket: func(p *quic.ForwardedPacket) bool {
+			if p.IsLongHeader || p.DestConnectionID.Bytes()[0] != 1 {
+				return false
+			}
+			numForwarded.Add(1)
+			return oldTr.InjectPacket(p) == nil
+		},
+	}
+	defer newTr.Close()
+	require.NoError(t, oldLn.Close())
+	oldConn.handOff()
+	newLn, err := newTr.Listen(getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer newLn.Close()
+
+	// the old connection continues to work, handled by the old Transport
+	exchangeData(t, ctx, conn1, sconn1)
+	require.NotZero(t, numForwarded.Load())
+
+	// new connections are handled by the new Transport
+	conn2, err := quic.Dial(ctx, newUDPConnLocalhost(t), newLn.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn2.CloseWithError(0, "")
+	sconn2, err := newLn.Accept(ctx)
+	require.NoError(t, err)
+	exchangeData(t, ctx, conn2, sconn2)
+}
+
+func exchangeData(t *testing.T, ctx context.Context, client, server *quic.Conn) {
+	t.Helper()
+
+	data := GeneratePRData(10 * 1024)
+	str, err := client.OpenStreamSync(ctx)
+	require.NoError(t, err)
+	_, err = str.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+
+	sstr, err := server.AcceptStream(ctx)
+	require.NoError(t, err)
+	received, err := io.ReadAll(sstr)
+	require.NoError(t, err)
+	require.Equal(t, data, received)
+	_, err = sstr.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, sstr.Close())
+
+	received, err = io.ReadAll(str)
+	require.NoError(t, err)
+	require.Equal(t, data, received)
+}
@@ -14,10 +14,10 @@ import (
 	"time"
 
 	"github.com/nukilabs/quic-go"
-	quicproxy "github.com/nukilabs/quic-go/integrationtests/tools/proxy"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/qerr"
 	"github.com/nukilabs/quic-go/internal/qtls"
+	"github.com/nukilabs/quic-go/quicproxy"
 
 	"github.com/stretchr/testify/require"
 )
@@ -710,6 +710,38 @@ func TestGetConfigForClientErrorsConnectionRejection(t *testing.T) {
 	require.False(t, <-acceptChan)
 }
 
+func TestAdmissionControlRateLimiting(t *testing.T) {
+	tr := &quic.Transport{
+		Conn:                newUDPConnLocalhost(t),
+		AdmissionController: &quic.AdmissionLimiter{Rate: 0.001, Burst: 1},
+	}
+	addTracer(tr)
+	defer tr.Close()
+	ln, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := quic.Dial(ctx, newUDPConnLocalhost(t), ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+
+	// The second connection attempt from the same /24 exceeds the limit for unverified addresses.
+	// The client is sent a Retry, and is then accepted, since it verified its address.
+	conn2, err := quic.Dial(ctx, newUDPConnLocalhost(t), ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn2.CloseWithError(0, "")
+
+	// the third connection attempt exceeds the limit for verified addresses as well
+	_, err = quic.Dial(ctx, newUDPConnLocalhost(t), ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+	var transportErr *quic.TransportError
+	require.ErrorAs(t, err, &transportErr)
+	require.True(t, transportErr.Remote)
+	require.Equal(t, quic.ConnectionRefused, transportErr.ErrorCode)
+	require.Equal(t, "rate limited", transportErr.ErrorMessage)
+}
+
 func TestNoPacketsSentWhenClientHelloFails(t *testing.T) {
 	conn := newUDPConnLocalhost(t)
 
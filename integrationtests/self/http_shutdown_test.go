@@ -13,7 +13,7 @@ import (
 
 	"github.com/nukilabs/quic-go"
 	"github.com/nukilabs/quic-go/http3"
-	quicproxy "github.com/nukilabs/quic-go/integrationtests/tools/proxy"
+	"github.com/nukilabs/quic-go/quicproxy"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -26,8 +26,8 @@ import (
 	"github.com/nukilabs/quic-go"
 	"github.com/nukilabs/quic-go/http3"
 	"github.com/nukilabs/quic-go/http3/qlog"
-	quicproxy "github.com/nukilabs/quic-go/integrationtests/tools/proxy"
 	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/quicproxy"
 	"github.com/nukilabs/quic-go/testutils/events"
 
 	"github.com/stretchr/testify/assert"
@@ -1135,6 +1135,72 @@ func TestHTTP0RTT(t *testing.T) {
 	require.NotZero(t, num0RTTPackets.Load())
 }
 
+func TestHTTP0RTTRejectUnsafeEarlyData(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/0rtt", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strconv.FormatBool(!r.TLS.HandshakeComplete))
+	})
+	port := startHTTPServer(t, mux, func(s *http3.Server) { s.RejectUnsafeEarlyData = true })
+
+	proxy := quicproxy.Proxy{
+		Conn:       newUDPConnLocalhost(t),
+		ServerAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port},
+		DelayPacket: func(quicproxy.Direction, net.Addr, net.Addr, []byte) time.Duration {
+			return scaleDuration(25 * time.Millisecond)
+		},
+	}
+	require.NoError(t, proxy.Start())
+	defer proxy.Close()
+
+	tlsConf := getTLSClientConfigWithoutServerName()
+	tlsConf.ServerName = "localhost"
+	tlsConf.NextProtos = []string{http3.NextProtoH3}
+	puts := make(chan string, 10)
+	tlsConf.ClientSessionCache = newClientSessionCache(tls.NewLRUClientSessionCache(10), nil, puts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := quic.DialAddr(ctx, proxy.LocalAddr().String(), tlsConf, getQuicConfig(nil))
+	require.NoError(t, err)
+	select {
+	case <-puts:
+	case <-time.After(time.Second):
+		t.Fatal("did not receive session ticket")
+	}
+	conn.CloseWithError(0, "")
+
+	conn, err = quic.DialAddrEarly(ctx, proxy.LocalAddr().String(), tlsConf, getQuicConfig(nil))
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+	cc := (&http3.Transport{}).NewClientConn(conn)
+
+	sendRequest := func(method string) *http3.RequestStream {
+		t.Helper()
+		str, err := cc.OpenRequestStream(ctx)
+		require.NoError(t, err)
+		req, err := http.NewRequest(method, fmt.Sprintf("https://localhost:%d/0rtt", port), nil)
+		require.NoError(t, err)
+		require.NoError(t, str.SendRequestHeader(req))
+		require.NoError(t, str.Close())
+		return str
+	}
+	// both requests are sent in 0-RTT
+	getStr := sendRequest(http.MethodGet)
+	postStr := sendRequest(http.MethodPost)
+
+	rsp, err := postStr.ReadResponse()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTooEarly, rsp.StatusCode)
+
+	rsp, err = getStr.ReadResponse()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rsp.StatusCode)
+	data, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "true", string(data))
+	require.True(t, conn.ConnectionState().Used0RTT)
+}
+
 func TestHTTPStreamer(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/httpstreamer", func(w http.ResponseWriter, r *http.Request) {
@@ -91,3 +91,82 @@ func TestKeyUpdates(t *testing.T) {
 	assert.Greater(t, keyPhasesReceived, 10)
 	assert.InDelta(t, keyPhasesSent, keyPhasesReceived, 2)
 }
+
+func TestKeyUpdateRequestedByApplication(t *testing.T) {
+	var serverEventRecorder events.Recorder
+	server, err := quic.Listen(
+		newUDPConnLocalhost(t),
+		getTLSConfig(),
+		getQuicConfig(&quic.Config{Tracer: newTracer(&serverEventRecorder)}),
+	)
+	require.NoError(t, err)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var clientEventRecorder events.Recorder
+	conn, err := quic.Dial(
+		ctx,
+		newUDPConnLocalhost(t),
+		server.Addr(),
+		getTLSClientConfig(),
+		getQuicConfig(&quic.Config{Tracer: newTracer(&clientEventRecorder)}),
+	)
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+
+	serverConn, err := server.Accept(ctx)
+	require.NoError(t, err)
+	defer serverConn.CloseWithError(0, "")
+
+	go func() {
+		for {
+			str, err := serverConn.AcceptStream(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				defer str.Close()
+				io.Copy(str, str)
+			}()
+		}
+	}()
+
+	echo := func() {
+		t.Helper()
+		str, err := conn.OpenStream()
+		require.NoError(t, err)
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+		require.NoError(t, str.Close())
+		data, err := io.ReadAll(str)
+		require.NoError(t, err)
+		require.Equal(t, []byte("foobar"), data)
+	}
+
+	echo() // make sure the handshake is confirmed
+
+	require.NoError(t, conn.UpdateKeys())
+	echo()
+
+	var localUpdates []qlog.KeyUpdated
+	for _, ev := range clientEventRecorder.Events(qlog.KeyUpdated{}) {
+		if ev := ev.(qlog.KeyUpdated); ev.Trigger == qlog.KeyUpdateLocal {
+			localUpdates = append(localUpdates, ev)
+		}
+	}
+	require.Equal(t,
+		[]qlog.KeyUpdated{
+			{Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonApplication, KeyType: qlog.KeyTypeClient1RTT, KeyPhase: 1},
+			{Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonApplication, KeyType: qlog.KeyTypeServer1RTT, KeyPhase: 1},
+		},
+		localUpdates,
+	)
+	require.Contains(t,
+		serverEventRecorder.Events(qlog.KeyUpdated{}),
+		qlog.KeyUpdated{Trigger: qlog.KeyUpdateRemote, KeyType: qlog.KeyTypeClient1RTT, KeyPhase: 1},
+	)
+
+	require.NoError(t, conn.CloseWithError(0, ""))
+	require.Error(t, conn.UpdateKeys())
+}
@@ -14,9 +14,9 @@ import (
 	"time"
 
 	"github.com/nukilabs/quic-go"
-	quicproxy "github.com/nukilabs/quic-go/integrationtests/tools/proxy"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/wire"
+	"github.com/nukilabs/quic-go/quicproxy"
 	"github.com/nukilabs/quic-go/testutils"
 
 	"github.com/stretchr/testify/require"
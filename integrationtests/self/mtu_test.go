@@ -11,9 +11,9 @@ import (
 	"time"
 
 	"github.com/nukilabs/quic-go"
-	quicproxy "github.com/nukilabs/quic-go/integrationtests/tools/proxy"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/qlog"
+	"github.com/nukilabs/quic-go/quicproxy"
 	"github.com/nukilabs/quic-go/testutils/events"
 
 	"github.com/stretchr/testify/require"
@@ -11,9 +11,11 @@ import (
 	"time"
 
 	"github.com/nukilabs/quic-go"
-	quicproxy "github.com/nukilabs/quic-go/integrationtests/tools/proxy"
+	"github.com/nukilabs/quic-go/internal/synctest"
 	"github.com/nukilabs/quic-go/qlog"
 	"github.com/nukilabs/quic-go/qlogwriter"
+	"github.com/nukilabs/quic-go/quicproxy"
+	"github.com/nukilabs/quic-go/testutils/simnet"
 
 	"github.com/stretchr/testify/require"
 )
@@ -124,3 +126,81 @@ func TestNATRebinding(t *testing.T) {
 	}
 	require.True(t, foundPathResponse)
 }
+
+func TestNATRebindingSimnet(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 10 * time.Millisecond
+		n := &simnet.Simnet{Router: &simnet.PerfectRouter{}}
+		settings := simnet.NodeBiDiLinkSettings{
+			Downlink: simnet.LinkSettings{MTU: 1500, Bandwidth: 10_000_000},
+			Latency:  rtt / 2,
+		}
+		clientAddr := &net.UDPAddr{IP: net.ParseIP("1.0.0.1"), Port: 9001}
+		newClientAddr := &net.UDPAddr{IP: net.ParseIP("1.0.0.3"), Port: 9003}
+		clientPacketConn := n.NewEndpoint(clientAddr, settings)
+		defer clientPacketConn.Close()
+		serverPacketConn := n.NewEndpoint(&net.UDPAddr{IP: net.ParseIP("1.0.0.2"), Port: 9002}, settings)
+		defer serverPacketConn.Close()
+		require.NoError(t, n.Start())
+		defer n.Close()
+
+		ln, err := quic.Listen(serverPacketConn, getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		tr := &quic.Transport{Conn: clientPacketConn}
+		defer tr.Close()
+		conn, err := tr.Dial(ctx, ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		sconn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer sconn.CloseWithError(0, "")
+		require.Equal(t, clientAddr.String(), sconn.RemoteAddr().String())
+
+		// rebind the client in the middle of the transfer
+		scenario := (&simnet.Scenario{}).Rebind(n, clientPacketConn, 50*time.Millisecond, newClientAddr)
+		scenario.Start()
+		defer scenario.Stop()
+
+		str, err := sconn.OpenUniStream()
+		require.NoError(t, err)
+		errChan := make(chan error, 1)
+		go func() {
+			defer str.Close()
+			_, err := str.Write(PRData)
+			errChan <- err
+		}()
+
+		rstr, err := conn.AcceptUniStream(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(rstr)
+		require.NoError(t, err)
+		require.Equal(t, PRData, data)
+		require.NoError(t, <-errChan)
+
+		// the client is not aware of the rebinding
+		require.Equal(t, clientAddr.String(), conn.LocalAddr().String())
+		// the server migrated to the new address
+		require.Equal(t, newClientAddr.String(), sconn.RemoteAddr().String())
+
+		// the statistics of the old and the new path are reported separately
+		paths := sconn.Paths()
+		require.Len(t, paths, 2)
+		require.Equal(t, clientAddr.String(), paths[0].RemoteAddr.String())
+		require.False(t, paths[0].Active)
+		require.Equal(t, newClientAddr.String(), paths[1].RemoteAddr.String())
+		require.True(t, paths[1].Active)
+		for _, p := range paths {
+			require.True(t, p.Validated)
+			require.Equal(t, sconn.LocalAddr(), p.LocalAddr)
+			require.NotZero(t, p.BytesSent)
+			require.NotZero(t, p.BytesReceived)
+		}
+		// the client was rebound in the middle of the transfer
+		require.Less(t, paths[0].BytesSent, uint64(len(PRData)))
+		require.Greater(t, paths[0].BytesSent+paths[1].BytesSent, uint64(len(PRData)))
+	})
+}
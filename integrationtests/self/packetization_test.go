@@ -10,10 +10,10 @@ import (
 	"time"
 
 	"github.com/nukilabs/quic-go"
-	quicproxy "github.com/nukilabs/quic-go/integrationtests/tools/proxy"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/qlog"
 	"github.com/nukilabs/quic-go/qlogwriter"
+	"github.com/nukilabs/quic-go/quicproxy"
 	"github.com/nukilabs/quic-go/quicvarint"
 
 	"github.com/stretchr/testify/assert"
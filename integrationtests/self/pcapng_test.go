@@ -0,0 +1,102 @@
+package self_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/pcapng"
+
+	"github.com/stretchr/testify/require"
+)
+
+type lockedBuffer struct {
+	mx  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return slices.Clone(b.buf.Bytes())
+}
+
+func TestPcapngCapture(t *testing.T) {
+	server, err := quic.Listen(newUDPConnLocalhost(t), getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer server.Close()
+
+	var capture lockedBuffer
+	w, err := pcapng.NewWriter(&capture)
+	require.NoError(t, err)
+	tr := &quic.Transport{Conn: w.WrapConn(newUDPConnLocalhost(t))}
+	addTracer(tr)
+	defer tr.Close()
+
+	tlsConf := getTLSClientConfig()
+	tlsConf.KeyLogWriter = w.KeyLogWriter()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := tr.Dial(ctx, server.Addr(), tlsConf, getQuicConfig(nil))
+	require.NoError(t, err)
+	serverConn, err := server.Accept(ctx)
+	require.NoError(t, err)
+	defer serverConn.CloseWithError(0, "")
+
+	str, err := conn.OpenUniStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	serverStr, err := serverConn.AcceptUniStream(ctx)
+	require.NoError(t, err)
+	b, err := io.ReadAll(serverStr)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foobar"), b)
+	conn.CloseWithError(0, "")
+
+	var numPackets int
+	var secrets []string
+	data := capture.Bytes()
+	for len(data) > 0 {
+		typ := binary.LittleEndian.Uint32(data)
+		length := binary.LittleEndian.Uint32(data[4:])
+		body := data[8 : length-4]
+		switch typ {
+		case 0x6: // Enhanced Packet Block
+			numPackets++
+		case 0xa: // Decryption Secrets Block
+			secretsLen := binary.LittleEndian.Uint32(body[4:])
+			secrets = append(secrets, strings.Split(strings.TrimSpace(string(body[8:8+secretsLen])), "\n")...)
+		}
+		data = data[length:]
+	}
+	require.Greater(t, numPackets, 2)
+	var labels []string
+	for _, line := range secrets {
+		labels = append(labels, strings.Fields(line)[0])
+	}
+	require.ElementsMatch(t,
+		[]string{
+			"CLIENT_HANDSHAKE_TRAFFIC_SECRET",
+			"SERVER_HANDSHAKE_TRAFFIC_SECRET",
+			"CLIENT_TRAFFIC_SECRET_0",
+			"SERVER_TRAFFIC_SECRET_0",
+		},
+		labels,
+	)
+}
@@ -1,13 +1,17 @@
 package self_test
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/nukilabs/quic-go"
 	"github.com/nukilabs/quic-go/internal/synctest"
+	"github.com/nukilabs/quic-go/qlog"
+	"github.com/nukilabs/quic-go/qlogreader"
 	"github.com/nukilabs/quic-go/qlogwriter"
 
 	"github.com/stretchr/testify/assert"
@@ -136,3 +140,166 @@ func TestQlogHandshakeFailed(t *testing.T) {
 		require.Zero(t, clientTrace.OpenRecorders(), "client recorders should be closed after failed handshake")
 	})
 }
+
+type signalingWriteCloser struct {
+	io.Writer
+	closed chan struct{}
+}
+
+func (w *signalingWriteCloser) Close() error {
+	close(w.closed)
+	return nil
+}
+
+func TestQlogTraceAnalysis(t *testing.T) {
+	var buf bytes.Buffer
+	w := &signalingWriteCloser{Writer: &buf, closed: make(chan struct{})}
+
+	server, err := quic.Listen(newUDPConnLocalhost(t), getTLSConfig(), getQuicConfig(nil))
+	require.NoError(t, err)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := quic.Dial(
+		ctx,
+		newUDPConnLocalhost(t),
+		server.Addr(),
+		getTLSClientConfig(),
+		getQuicConfig(&quic.Config{
+			Tracer: func(_ context.Context, isClient bool, connID quic.ConnectionID) qlogwriter.Trace {
+				trace := qlogwriter.NewConnectionFileSeq(w, isClient, connID, []string{qlog.EventSchema})
+				go trace.Run()
+				return trace
+			},
+		}),
+	)
+	require.NoError(t, err)
+
+	serverConn, err := server.Accept(ctx)
+	require.NoError(t, err)
+	defer serverConn.CloseWithError(0, "")
+
+	str, err := conn.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	serverStr, err := serverConn.AcceptStream(ctx)
+	require.NoError(t, err)
+	data, err := io.ReadAll(serverStr)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foobar"), data)
+	conn.CloseWithError(42, "done")
+
+	select {
+	case <-w.closed:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the qlog trace to be closed")
+	}
+
+	trace, err := qlogreader.ReadAll(&buf)
+	require.NoError(t, err)
+	require.Equal(t, "client", trace.Header.VantagePoint)
+	s := qlogreader.Summarize(trace)
+	_, ok := s.HandshakeTime(qlogreader.MilestoneHandshakeConfirmed)
+	require.True(t, ok)
+	require.NotEmpty(t, s.Metrics)
+	require.NotZero(t, s.PacketsSent)
+	require.NotZero(t, s.PacketsReceived)
+	require.Len(t, s.Streams, 1)
+	require.Equal(t, qlog.StreamID(0), s.Streams[0].ID)
+	require.Equal(t, int64(6), s.Streams[0].BytesSent)
+	require.True(t, s.Streams[0].FinSent)
+	require.NotNil(t, s.Close)
+	require.Equal(t, qlog.InitiatorLocal, s.Close.Initiator)
+	require.NotNil(t, s.Close.ApplicationError)
+	require.EqualValues(t, 42, *s.Close.ApplicationError)
+}
+
+func TestQlogFlightRecorder(t *testing.T) {
+	t.Run("failed connection", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := &signalingWriteCloser{Writer: &buf, closed: make(chan struct{})}
+			clientPacketConn, serverPacketConn, closeFn := newSimnetLink(t, time.Millisecond)
+			defer closeFn(t)
+
+			// Don't start a server, so the handshake will timeout
+			_, err := quic.Dial(
+				context.Background(),
+				clientPacketConn,
+				serverPacketConn.LocalAddr(),
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{
+					HandshakeIdleTimeout: 3 * time.Second,
+					Tracer: func(_ context.Context, isClient bool, connID quic.ConnectionID) qlogwriter.Trace {
+						return qlogwriter.NewConnectionFlightRecorder(isClient, connID, []string{qlog.EventSchema}, &qlogwriter.FlightRecorderConfig{
+							ShouldFlush: qlog.ConnectionFailed,
+							Sink:        func() (io.WriteCloser, error) { return w, nil },
+						})
+					},
+				}),
+			)
+			require.ErrorIs(t, err, &quic.IdleTimeoutError{})
+
+			select {
+			case <-w.closed:
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for the qlog trace to be written")
+			}
+			trace, err := qlogreader.ReadAll(&buf)
+			require.NoError(t, err)
+			require.Equal(t, "client", trace.Header.VantagePoint)
+			s := qlogreader.Summarize(trace)
+			require.NotZero(t, s.PacketsSent)
+			require.NotNil(t, s.Close)
+			require.Equal(t, qlog.ConnectionCloseTriggerIdleTimeout, s.Close.Trigger)
+		})
+	})
+
+	t.Run("successful connection", func(t *testing.T) {
+		server, err := quic.Listen(newUDPConnLocalhost(t), getTLSConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		defer server.Close()
+
+		var sinkCalled atomic.Bool
+		recorderChan := make(chan *qlogwriter.FlightRecorder, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.Dial(
+			ctx,
+			newUDPConnLocalhost(t),
+			server.Addr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				Tracer: func(_ context.Context, isClient bool, connID quic.ConnectionID) qlogwriter.Trace {
+					r := qlogwriter.NewConnectionFlightRecorder(isClient, connID, []string{qlog.EventSchema}, &qlogwriter.FlightRecorderConfig{
+						ShouldFlush: qlog.ConnectionFailed,
+						Sink: func() (io.WriteCloser, error) {
+							sinkCalled.Store(true)
+							return &signalingWriteCloser{Writer: io.Discard, closed: make(chan struct{})}, nil
+						},
+					})
+					recorderChan <- r
+					return r
+				},
+			}),
+		)
+		require.NoError(t, err)
+		recorder := <-recorderChan
+
+		// the application can dump the events of a connection that is still alive
+		var buf bytes.Buffer
+		_, err = recorder.WriteTo(&buf)
+		require.NoError(t, err)
+		trace, err := qlogreader.ReadAll(&buf)
+		require.NoError(t, err)
+		require.NotZero(t, qlogreader.Summarize(trace).PacketsReceived)
+
+		conn.CloseWithError(0, "")
+		// once the connection is closed, the trace doesn't accept new producers
+		require.Eventually(t, func() bool { return recorder.AddProducer() == nil }, time.Second, 10*time.Millisecond)
+		require.False(t, sinkCalled.Load())
+	})
+}
@@ -0,0 +1,90 @@
+package self_test
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/internal/synctest"
+	"github.com/nukilabs/quic-go/internal/wire"
+	"github.com/nukilabs/quic-go/testutils/simnet"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionStats(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 10 * time.Millisecond
+		var numPackets atomic.Int64
+		// drop every 10th 1-RTT packet sent by the server
+		clientPacketConn, serverPacketConn, closeFn := newSimnetLinkWithRouter(t, rtt, &droppingRouter{
+			Drop: func(p simnet.Packet) bool {
+				if p.From.String() != "1.0.0.2:9002" || wire.IsLongHeaderPacket(p.Data[0]) {
+					return false
+				}
+				return numPackets.Add(1)%10 == 0
+			},
+		})
+		defer closeFn(t)
+
+		ln, err := quic.Listen(serverPacketConn, getTLSConfig(), getQuicConfig(&quic.Config{
+			InitialStreamReceiveWindow: 10 * 1024,
+			MaxStreamReceiveWindow:     10 * 1024,
+		}))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		tr := &quic.Transport{Conn: clientPacketConn}
+		defer tr.Close()
+		conn, err := tr.Dial(ctx, ln.Addr(), getTLSClientConfig(), getQuicConfig(&quic.Config{
+			InitialStreamReceiveWindow: 10 * 1024,
+			MaxStreamReceiveWindow:     10 * 1024,
+		}))
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		sconn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		defer sconn.CloseWithError(0, "")
+
+		data := GeneratePRData(200 * 1024)
+		str, err := conn.OpenStream()
+		require.NoError(t, err)
+		_, err = str.Write([]byte("request"))
+		require.NoError(t, err)
+		sstr, err := sconn.AcceptStream(ctx)
+		require.NoError(t, err)
+		errChan := make(chan error, 1)
+		go func() {
+			defer sstr.Close()
+			_, err := sstr.Write(data)
+			errChan <- err
+		}()
+
+		received, err := io.ReadAll(str)
+		require.NoError(t, err)
+		require.Equal(t, data, received)
+		require.NoError(t, <-errChan)
+		// wait for the ACKs to be received
+		time.Sleep(rtt)
+
+		stats := sconn.ConnectionStats()
+		t.Logf("server stats: %+v", stats)
+		require.NotZero(t, stats.PacketsLost)
+		require.NotZero(t, stats.BytesRetransmitted)
+		require.NotZero(t, stats.CongestionWindow)
+		require.NotZero(t, stats.PacingRate)
+		require.GreaterOrEqual(t, stats.MTU, uint64(1200))
+		require.NotZero(t, stats.StreamFlowControlBlockedTime)
+
+		sendStats := sstr.Stats().Send
+		require.Equal(t, uint64(len(data)), sendStats.BytesSent)
+		require.NotZero(t, sendStats.BytesRetransmitted)
+		require.NotZero(t, sendStats.FlowControlBlockedTime)
+		require.Equal(t, uint64(len(data)), str.Stats().Receive.BytesRead)
+	})
+}
@@ -9,6 +9,10 @@ import (
 	"time"
 
 	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/synctest"
+	"github.com/nukilabs/quic-go/qlog"
+	"github.com/nukilabs/quic-go/testutils/events"
 
 	"golang.org/x/sync/errgroup"
 
@@ -315,3 +319,100 @@ func TestUnidirectionalStreams(t *testing.T) {
 		client.CloseWithError(0, "")
 	})
 }
+
+func TestMemoryBudget(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		clientConn, serverConn, closeFn := newSimnetLink(t, 10*time.Millisecond)
+		defer closeFn(t)
+
+		const limit = 1 << 20
+		budget := quic.NewMemoryBudget(limit)
+		var serverEventRecorder events.Recorder
+		ln, err := quic.Listen(
+			serverConn,
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				InitialConnectionReceiveWindow: 512 << 10,
+				MaxConnectionReceiveWindow:     8 << 20,
+				MemoryBudget:                   budget,
+				Tracer:                         newTracer(&serverEventRecorder),
+			}),
+		)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		tr := &quic.Transport{Conn: clientConn}
+		defer tr.Close()
+
+		// transfer data from the client to the server
+		transfer := func(t *testing.T, clientConn, serverConn *quic.Conn, data []byte) quic.StreamID {
+			t.Helper()
+
+			errChan := make(chan error, 1)
+			go func() {
+				str, err := clientConn.OpenUniStream()
+				if err != nil {
+					errChan <- err
+					return
+				}
+				if _, err := str.Write(data); err != nil {
+					errChan <- err
+					return
+				}
+				errChan <- str.Close()
+			}()
+
+			str, err := serverConn.AcceptUniStream(context.Background())
+			require.NoError(t, err)
+			b, err := io.ReadAll(str)
+			require.NoError(t, err)
+			require.Equal(t, data, b)
+			require.NoError(t, <-errChan)
+			return str.StreamID()
+		}
+
+		data := GeneratePRData(5 << 20)
+		conn1, err := tr.Dial(context.Background(), ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		serverConn1, err := ln.Accept(context.Background())
+		require.NoError(t, err)
+		// The client's connection uses a different configuration, and doesn't reserve any memory.
+		require.Equal(t, uint64(512<<10), budget.Used())
+
+		transfer(t, conn1, serverConn1, data)
+		// Auto-tuning increased the window until the budget was exhausted.
+		require.Equal(t, uint64(limit), budget.Used())
+		require.Zero(t, budget.Available())
+
+		// A new stream on the existing connection grants less flow control credit.
+		// The initial window was advertised in the transport parameters, and can't be reduced.
+		serverEventRecorder.Clear()
+		strID := transfer(t, conn1, serverConn1, data[:1<<20])
+		var maxStreamData []protocol.ByteCount
+		for _, ev := range serverEventRecorder.Events(qlog.PacketSent{}) {
+			for _, f := range ev.(qlog.PacketSent).Frames {
+				if msd, ok := f.Frame.(*qlog.MaxStreamDataFrame); ok && msd.StreamID == strID {
+					maxStreamData = append(maxStreamData, msd.MaximumStreamData)
+				}
+			}
+		}
+		require.NotEmpty(t, maxStreamData)
+		// Without the budget, the first update would grant a full window of 512 KB beyond the data read.
+		require.Less(t, maxStreamData[0], protocol.ByteCount(512<<10+64<<10))
+
+		// The second connection advertises a smaller window, but can still transfer data.
+		conn2, err := tr.Dial(context.Background(), ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		require.NoError(t, err)
+		serverConn2, err := ln.Accept(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(limit+16<<10), budget.Used())
+		transfer(t, conn2, serverConn2, data[:100<<10])
+		require.Equal(t, uint64(limit+16<<10), budget.Used())
+
+		// Memory is returned to the budget when the connections are closed.
+		conn1.CloseWithError(0, "")
+		conn2.CloseWithError(0, "")
+		time.Sleep(time.Second)
+		require.Zero(t, budget.Used())
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"io"
 	"net"
 	"os"
+	"path/filepath"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -215,6 +217,141 @@ func Test0RTTTransfer(t *testing.T) {
 	})
 }
 
+func Test0RTTAcrossRestarts(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 50 * time.Millisecond
+
+		router := &zeroRTTCountingRouter{Router: &simnet.PerfectRouter{}}
+		clientConn, serverConn, closeFn := newSimnetLinkWithRouter(t, rtt, router)
+		defer closeFn(t)
+
+		tr := &quic.Transport{Conn: serverConn}
+		defer tr.Close()
+		ln, err := tr.ListenEarly(getTLSConfig(), getQuicConfig(&quic.Config{Allow0RTT: true}))
+		require.NoError(t, err)
+		defer ln.Close()
+
+		path := filepath.Join(t.TempDir(), "store")
+		store, err := quic.OpenPersistentStore(path, 10, 4)
+		require.NoError(t, err)
+		clientTLSConf := dialAndReceiveTicket(t, ln, clientConn, store.ClientSessionCache())
+		require.NoError(t, store.Close())
+
+		time.Sleep(time.Hour)
+		synctest.Wait()
+
+		// simulate a restart of the client by loading the store from disk
+		store, err = quic.OpenPersistentStore(path, 10, 4)
+		require.NoError(t, err)
+		defer store.Close()
+		clientTLSConf = clientTLSConf.Clone()
+		clientTLSConf.ClientSessionCache = store.ClientSessionCache()
+
+		transfer0RTTData(t, ln, clientConn, clientTLSConf, getQuicConfig(nil), PRData)
+		require.NotZero(t, router.Num0RTTPackets())
+	})
+}
+
+func Test0RTTReplay(t *testing.T) {
+	t.Run("without replay filter", func(t *testing.T) {
+		test0RTTReplay(t, nil)
+	})
+
+	t.Run("with replay filter", func(t *testing.T) {
+		test0RTTReplay(t, quic.NewReplayFilter(time.Hour, 100))
+	})
+
+	// The replay filter has forgotten about the original connection attempt,
+	// but the ClientHello is not fresh anymore.
+	t.Run("replayed after the replay filter window", func(t *testing.T) {
+		test0RTTReplay(t, quic.NewReplayFilter(10*time.Second, 100))
+	})
+}
+
+// The replay filter window limits the age of the ClientHello, not the age of the session ticket.
+func Test0RTTReplayFilterOldTicket(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		router := &zeroRTTCountingRouter{Router: &simnet.PerfectRouter{}}
+		clientConn, serverConn, closeFn := newSimnetLinkWithRouter(t, 50*time.Millisecond, router)
+		defer closeFn(t)
+
+		tr := &quic.Transport{Conn: serverConn}
+		defer tr.Close()
+		ln, err := tr.ListenEarly(
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{Allow0RTT: true, ZeroRTTReplayFilter: quic.NewReplayFilter(10*time.Second, 100)}),
+		)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		clientTLSConf := dialAndReceiveTicket(t, ln, clientConn, nil)
+
+		time.Sleep(time.Hour)
+		synctest.Wait()
+
+		transfer0RTTData(t, ln, clientConn, clientTLSConf, getQuicConfig(nil), PRData)
+		require.NotZero(t, router.Num0RTTPackets())
+	})
+}
+
+func test0RTTReplay(t *testing.T, replayFilter quic.ReplayFilter) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 50 * time.Millisecond
+
+		var mx sync.Mutex
+		var recording bool
+		var recorded []simnet.Packet
+		router := &callbackRouter{
+			Router: &simnet.PerfectRouter{},
+			OnSendPacket: func(p simnet.Packet) {
+				mx.Lock()
+				defer mx.Unlock()
+				if recording && (containsPacketType(p.Data, protocol.PacketTypeInitial) || containsPacketType(p.Data, protocol.PacketType0RTT)) {
+					recorded = append(recorded, simnet.Packet{From: p.From, To: p.To, Data: slices.Clone(p.Data)})
+				}
+			},
+		}
+		clientConn, serverConn, closeFn := newSimnetLinkWithRouter(t, rtt, router)
+		defer closeFn(t)
+
+		tr := &quic.Transport{Conn: serverConn}
+		defer tr.Close()
+		ln, err := tr.ListenEarly(
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{Allow0RTT: true, ZeroRTTReplayFilter: replayFilter}),
+		)
+		require.NoError(t, err)
+		defer ln.Close()
+
+		clientTLSConf := dialAndReceiveTicket(t, ln, clientConn, nil)
+
+		mx.Lock()
+		recording = true
+		mx.Unlock()
+		transfer0RTTData(t, ln, clientConn, clientTLSConf, getQuicConfig(nil), []byte("foobar"))
+		mx.Lock()
+		recording = false
+		mx.Unlock()
+
+		// wait for the server to forget about the original connection
+		time.Sleep(time.Minute)
+		synctest.Wait()
+
+		// An attacker replays the client's first flight.
+		require.NotEmpty(t, recorded)
+		for _, p := range recorded {
+			require.NoError(t, router.Router.SendPacket(p))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		// Without a replay filter, the server accepts the replayed 0-RTT data.
+		require.Equal(t, replayFilter == nil, conn.ConnectionState().Used0RTT)
+	})
+}
+
 func Test0RTTDisabledOnDial(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		const rtt = 25 * time.Millisecond
@@ -2,16 +2,21 @@ package versionnegotiation
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/nukilabs/quic-go"
 	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/wire"
 	"github.com/nukilabs/quic-go/qlog"
 	"github.com/nukilabs/quic-go/qlogwriter"
+	"github.com/nukilabs/quic-go/quicproxy"
 	"github.com/nukilabs/quic-go/testutils/events"
 
 	"github.com/stretchr/testify/require"
@@ -190,3 +195,150 @@ func TestServerDisablesVersionNegotiation(t *testing.T) {
 	require.True(t, nerr.Timeout())
 	require.Empty(t, clientEventTracer.Events(qlog.VersionNegotiationReceived{}))
 }
+
+func TestCompatibleVersionUpgrade(t *testing.T) {
+	// The client offers QUIC v1 (its first version), but the server prefers QUIC v2.
+	// Since both versions are compatible, the server upgrades the connection without a Version Negotiation packet.
+	var serverEventTracer events.Recorder
+	serverConfig := &quic.Config{
+		Versions: []protocol.Version{quic.Version2, quic.Version1},
+		Tracer: func(context.Context, bool, quic.ConnectionID) qlogwriter.Trace {
+			return &events.Trace{Recorder: &serverEventTracer}
+		},
+	}
+	server, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+	require.NoError(t, err)
+	defer server.Close()
+
+	var clientEventTracer events.Recorder
+	conn, err := quic.DialAddr(
+		context.Background(),
+		fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+		getTLSClientConfig(),
+		maybeAddQLOGTracer(&quic.Config{Tracer: func(context.Context, bool, quic.ConnectionID) qlogwriter.Trace {
+			return &events.Trace{Recorder: &clientEventTracer}
+		}}),
+	)
+	require.NoError(t, err)
+
+	sconn, err := server.Accept(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, quic.Version2, sconn.ConnectionState().Version)
+	require.Equal(t, quic.Version2, conn.ConnectionState().Version)
+
+	// make sure that application data can be exchanged using the new version
+	str, err := conn.OpenUniStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sstr, err := sconn.AcceptUniStream(ctx)
+	require.NoError(t, err)
+	data, err := io.ReadAll(sstr)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foobar"), data)
+
+	require.NoError(t, conn.CloseWithError(0, ""))
+	select {
+	case <-sconn.Context().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout waiting for connection to close")
+	}
+
+	require.Empty(t, clientEventTracer.Events(qlog.VersionNegotiationReceived{}))
+	require.Equal(t,
+		[]qlogwriter.Event{
+			qlog.VersionInformation{
+				ClientVersions: protocol.SupportedVersions,
+				ChosenVersion:  quic.Version2,
+			},
+		},
+		clientEventTracer.Events(qlog.VersionInformation{}),
+	)
+	require.Equal(t,
+		[]qlogwriter.Event{
+			qlog.VersionInformation{
+				ServerVersions: serverConfig.Versions,
+				ChosenVersion:  quic.Version1,
+			},
+			qlog.VersionInformation{
+				ClientVersions: protocol.SupportedVersions,
+				ServerVersions: serverConfig.Versions,
+				ChosenVersion:  quic.Version2,
+			},
+		},
+		serverEventTracer.Events(qlog.VersionInformation{}),
+	)
+}
+
+func TestCompatibleVersionUpgradeServerInitialLost(t *testing.T) {
+	// The server's first flight is dropped until the client has retransmitted its Initial packet.
+	// The server needs to accept the retransmission, although it was sent using the original version.
+	var serverEventTracer events.Recorder
+	server, err := quic.ListenAddr("localhost:0", getTLSConfig(), &quic.Config{
+		Versions: []protocol.Version{quic.Version2, quic.Version1},
+		Tracer: func(context.Context, bool, quic.ConnectionID) qlogwriter.Trace {
+			return &events.Trace{Recorder: &serverEventTracer}
+		},
+	})
+	require.NoError(t, err)
+	defer server.Close()
+
+	proxyConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	defer proxyConn.Close()
+	var droppedServerPacket, clientRetransmitted atomic.Bool
+	proxy := quicproxy.Proxy{
+		Conn:       proxyConn,
+		ServerAddr: server.Addr().(*net.UDPAddr),
+		DropPacket: func(dir quicproxy.Direction, _, _ net.Addr, b []byte) bool {
+			if dir == quicproxy.DirectionIncoming {
+				if droppedServerPacket.Load() && wire.IsLongHeaderPacket(b[0]) && binary.BigEndian.Uint32(b[1:5]) == uint32(quic.Version1) {
+					clientRetransmitted.Store(true)
+				}
+				return false
+			}
+			if clientRetransmitted.Load() {
+				return false
+			}
+			droppedServerPacket.Store(true)
+			return true
+		},
+	}
+	require.NoError(t, proxy.Start())
+	defer proxy.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var clientEventTracer events.Recorder
+	conn, err := quic.DialAddr(
+		ctx,
+		proxy.LocalAddr().String(),
+		getTLSClientConfig(),
+		maybeAddQLOGTracer(&quic.Config{Tracer: func(context.Context, bool, quic.ConnectionID) qlogwriter.Trace {
+			return &events.Trace{Recorder: &clientEventTracer}
+		}}),
+	)
+	require.NoError(t, err)
+	defer conn.CloseWithError(0, "")
+	sconn, err := server.Accept(ctx)
+	require.NoError(t, err)
+	require.Equal(t, quic.Version2, sconn.ConnectionState().Version)
+	require.Equal(t, quic.Version2, conn.ConnectionState().Version)
+	require.True(t, clientRetransmitted.Load())
+	require.Equal(t,
+		[]qlogwriter.Event{
+			qlog.VersionInformation{
+				ClientVersions: protocol.SupportedVersions,
+				ChosenVersion:  quic.Version2,
+			},
+		},
+		clientEventTracer.Events(qlog.VersionInformation{}),
+	)
+
+	for _, ev := range serverEventTracer.Events(qlog.PacketDropped{}) {
+		require.NotEqual(t, qlog.PacketDropUnexpectedVersion, ev.(qlog.PacketDropped).Trigger)
+	}
+}
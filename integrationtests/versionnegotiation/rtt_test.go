@@ -7,8 +7,8 @@ import (
 	"time"
 
 	"github.com/nukilabs/quic-go"
-	quicproxy "github.com/nukilabs/quic-go/integrationtests/tools/proxy"
 	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/quicproxy"
 
 	"github.com/stretchr/testify/require"
 )
@@ -3,6 +3,7 @@ package quic
 import (
 	"context"
 	"errors"
+	"fmt"
 	tls "github.com/nukilabs/utls"
 	"net"
 	"slices"
@@ -68,6 +69,24 @@ type StatelessResetKey [32]byte
 // TokenGeneratorKey is a key used to encrypt session resumption tokens.
 type TokenGeneratorKey = handshake.TokenProtectorKey
 
+// TransportKeys are the keys returned by the Transport's KeySource.
+type TransportKeys struct {
+	StatelessResetKey *StatelessResetKey
+	TokenGeneratorKey *TokenGeneratorKey
+}
+
+// A ReplayFilter is used by the server to detect replayed 0-RTT connection attempts.
+// A 0-RTT connection attempt is identified by the session ticket and the random of the ClientHello.
+type ReplayFilter = handshake.ReplayFilter
+
+// NewReplayFilter creates a ReplayFilter based on a time-bucketed bloom filter.
+// Connection attempts are remembered for at least window, and 0-RTT is rejected
+// for session tickets that were issued more than window ago.
+// capacity is the expected number of 0-RTT connection attempts per window.
+func NewReplayFilter(window time.Duration, capacity int) ReplayFilter {
+	return handshake.NewBloomReplayFilter(window, capacity)
+}
+
 // A ConnectionID is a QUIC Connection ID, as defined in RFC 9000.
 // It is not able to handle QUIC Connection IDs longer than 20 bytes,
 // as they are allowed by RFC 8999.
@@ -143,6 +162,12 @@ type Config struct {
 	// To avoid deadlocks, it is not valid to call other functions on the connection or on streams
 	// in this callback.
 	AllowConnectionWindowIncrease func(conn *Conn, delta uint64) bool
+	// MemoryBudget limits the memory used for buffering received stream data.
+	// It can be shared between connections, to limit the total memory usage of a process.
+	// If set, connections reserve their connection-level flow control window from the budget.
+	// Window increases are only allowed if the budget has enough memory available,
+	// and new connections advertise smaller receive windows if the budget is exhausted.
+	MemoryBudget *MemoryBudget
 	// MaxIncomingStreams is the maximum number of concurrent bidirectional streams that a peer is allowed to open.
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any bidirectional streams.
@@ -170,8 +195,35 @@ type Config struct {
 	// Allow0RTT allows the application to decide if a 0-RTT connection attempt should be accepted.
 	// Only valid for the server.
 	Allow0RTT bool
+	// ZeroRTTReplayFilter is used to detect replayed 0-RTT connection attempts.
+	// If set, 0-RTT is rejected for connection attempts that were already seen.
+	// Only valid for the server, and only relevant if Allow0RTT is set.
+	ZeroRTTReplayFilter ReplayFilter
+	// KeyUpdateMaxPackets is the maximum number of packets sent or received using the same 1-RTT key.
+	// Once this number is reached, a key update is initiated (see section 6 of RFC 9001).
+	// It can't be set to a value larger than 100,000 packets, which is also the default.
+	KeyUpdateMaxPackets uint64
+	// KeyUpdateMaxBytes is the maximum number of payload bytes sent or received using the same 1-RTT key.
+	// Once this number is reached, a key update is initiated.
+	// If not set, the number of bytes is not limited.
+	KeyUpdateMaxBytes uint64
+	// KeyUpdateMaxAge is the maximum duration for which the same 1-RTT key is used.
+	// Once this duration has passed, a key update is initiated.
+	// Note that a key update is only performed when sending a packet.
+	// If not set, the key age is not limited.
+	KeyUpdateMaxAge time.Duration
 	// Enable QUIC datagram support (RFC 9221).
 	EnableDatagrams bool
+	// DatagramSendQueueLen is the maximum number of datagrams queued for sending.
+	// If not set, it defaults to 32.
+	DatagramSendQueueLen int
+	// DatagramReceiveQueueLen is the maximum number of received datagrams queued until they are read
+	// by the application. Datagrams received while the queue is full are dropped.
+	// If not set, it defaults to 128.
+	DatagramReceiveQueueLen int
+	// DatagramQueuePolicy determines what happens when a datagram is sent while the send queue is full.
+	// By default, SendDatagram blocks until there's space in the queue.
+	DatagramQueuePolicy DatagramQueuePolicy
 	// Enable QUIC Stream Resets with Partial Delivery.
 	// See https://datatracker.ietf.org/doc/html/draft-ietf-quic-reliable-stream-reset-07.
 	EnableStreamResetPartialDelivery bool
@@ -179,6 +231,50 @@ type Config struct {
 	Tracer func(ctx context.Context, isClient bool, connID ConnectionID) qlogwriter.Trace
 }
 
+// DatagramQueuePolicy determines what happens when a datagram is sent while the send queue is full.
+type DatagramQueuePolicy uint8
+
+const (
+	// DatagramQueueBlock blocks the call to SendDatagram until there's space in the queue.
+	DatagramQueueBlock DatagramQueuePolicy = iota
+	// DatagramQueueDropOldest drops the datagram that has been queued for the longest time.
+	DatagramQueueDropOldest
+	// DatagramQueueDropNewest drops the datagram that is being sent.
+	DatagramQueueDropNewest
+)
+
+// DatagramStatus is the status of a datagram sent using Conn.SendDatagramWithFeedback.
+type DatagramStatus uint8
+
+const (
+	// DatagramSent means that the datagram was sent in a packet.
+	DatagramSent DatagramStatus = iota + 1
+	// DatagramAcked means that the packet containing the datagram was acknowledged by the peer.
+	DatagramAcked
+	// DatagramLost means that the packet containing the datagram was declared lost.
+	// Datagrams are never retransmitted.
+	DatagramLost
+	// DatagramDropped means that the datagram was dropped before it was sent,
+	// either due to the DatagramQueuePolicy, because it didn't fit into a packet,
+	// or because the connection was closed.
+	DatagramDropped
+)
+
+func (s DatagramStatus) String() string {
+	switch s {
+	case DatagramSent:
+		return "sent"
+	case DatagramAcked:
+		return "acked"
+	case DatagramLost:
+		return "lost"
+	case DatagramDropped:
+		return "dropped"
+	default:
+		return fmt.Sprintf("unknown datagram status: %d", uint8(s))
+	}
+}
+
 // ClientInfo contains information about an incoming connection attempt.
 type ClientInfo struct {
 	// RemoteAddr is the remote address on the Initial packet.
@@ -157,9 +157,18 @@ func NewSentPacketHandler(
 		h.enableECN = true
 		h.ecnTracker = newECNTracker(logger, qlogger)
 	}
+	connStats.MTU.Store(uint64(initialMaxDatagramSize))
+	h.updateConnStats()
 	return h
 }
 
+// updateConnStats updates the connection statistics derived from the congestion controller
+// and the bytes in flight.
+func (h *sentPacketHandler) updateConnStats() {
+	h.connStats.CongestionWindow.Store(uint64(h.congestion.GetCongestionWindow()))
+	h.connStats.BytesInFlight.Store(uint64(h.bytesInFlight))
+}
+
 func (h *sentPacketHandler) removeFromBytesInFlight(p *packet) {
 	if p.includedInBytesInFlight {
 		if p.Length > h.bytesInFlight {
@@ -218,6 +227,7 @@ func (h *sentPacketHandler) DropPackets(encLevel protocol.EncryptionLevel, now m
 	h.ptoCount = 0
 	h.numProbesToSend = 0
 	h.ptoMode = SendNone
+	h.updateConnStats()
 	h.setLossDetectionTimer(now)
 }
 
@@ -298,6 +308,7 @@ func (h *sentPacketHandler) SentPacket(
 		}
 	}
 	h.congestion.OnPacketSent(t, h.bytesInFlight, pn, size, isAckEliciting)
+	h.updateConnStats()
 
 	if encLevel == protocol.Encryption1RTT && h.ecnTracker != nil {
 		h.ecnTracker.SentPacket(pn, ecn)
@@ -422,6 +433,11 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	}
 
 	// Only inform the ECN tracker about new 1-RTT ACKs if the ACK increases the largest acked.
+	if encLevel == protocol.Encryption1RTT && largestAcked > pnSpace.largestAcked {
+		h.connStats.ECT0.Store(ack.ECT0)
+		h.connStats.ECT1.Store(ack.ECT1)
+		h.connStats.ECNCE.Store(ack.ECNCE)
+	}
 	if encLevel == protocol.Encryption1RTT && h.ecnTracker != nil && largestAcked > pnSpace.largestAcked {
 		congested := h.ecnTracker.HandleNewlyAcked(ackedPackets, int64(ack.ECT0), int64(ack.ECT1), int64(ack.ECNCE))
 		if congested {
@@ -474,6 +490,7 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	}
 	h.numProbesToSend = 0
 
+	h.updateConnStats()
 	if h.qlogger != nil {
 		h.qlogMetricsUpdated()
 	}
@@ -520,6 +537,7 @@ func (h *sentPacketHandler) detectSpuriousLosses(ack *wire.AckFrame, ackTime mon
 	for _, pn := range spuriousLosses {
 		h.lostPackets.Delete(pn)
 	}
+	h.connStats.PacketsSpuriouslyLost.Add(uint64(len(spuriousLosses)))
 }
 
 // Packets are returned in ascending packet number order.
@@ -866,6 +884,7 @@ func (h *sentPacketHandler) detectLostPackets(now monotime.Time, encLevel protoc
 
 func (h *sentPacketHandler) OnLossDetectionTimeout(now monotime.Time) error {
 	defer h.setLossDetectionTimer(now)
+	defer h.updateConnStats()
 
 	if h.handshakeConfirmed {
 		h.detectLostPathProbes(now)
@@ -895,6 +914,7 @@ func (h *sentPacketHandler) OnLossDetectionTimeout(now monotime.Time) error {
 	// actually packets outstanding.
 	if h.bytesInFlight == 0 && !h.peerCompletedAddressValidation {
 		h.ptoCount++
+		h.connStats.PTOCount.Add(1)
 		h.numProbesToSend++
 		if h.initialPackets != nil {
 			h.ptoMode = SendPTOInitial
@@ -915,6 +935,7 @@ func (h *sentPacketHandler) OnLossDetectionTimeout(now monotime.Time) error {
 		return nil
 	}
 	h.ptoCount++
+	h.connStats.PTOCount.Add(1)
 	if h.logger.Debug() {
 		h.logger.Debugf("Loss detection alarm for %s fired in PTO mode. PTO count: %d", encLevel, h.ptoCount)
 	}
@@ -1029,6 +1050,8 @@ func (h *sentPacketHandler) TimeUntilSend() monotime.Time {
 
 func (h *sentPacketHandler) SetMaxDatagramSize(s protocol.ByteCount) {
 	h.congestion.SetMaxDatagramSize(s)
+	h.connStats.MTU.Store(uint64(s))
+	h.updateConnStats()
 }
 
 func (h *sentPacketHandler) isAmplificationLimited() bool {
@@ -1115,6 +1138,7 @@ func (h *sentPacketHandler) ResetForRetry(now monotime.Time) {
 		}
 	}
 	h.ptoCount = 0
+	h.updateConnStats()
 }
 
 func (h *sentPacketHandler) MigratedPath(now monotime.Time, initialMaxDatagramSize protocol.ByteCount) {
@@ -1139,5 +1163,7 @@ func (h *sentPacketHandler) MigratedPath(now monotime.Time, initialMaxDatagramSi
 		true, // use Reno
 		h.qlogger,
 	)
+	h.connStats.MTU.Store(uint64(initialMaxDatagramSize))
+	h.updateConnStats()
 	h.setLossDetectionTimer(now)
 }
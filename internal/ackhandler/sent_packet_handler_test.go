@@ -1052,6 +1052,7 @@ func TestSentPacketHandler0RTT(t *testing.T) {
 func TestSentPacketHandlerCongestion(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	cong := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+	cong.EXPECT().GetCongestionWindow().AnyTimes()
 	rttStats := utils.NewRTTStats()
 	sph := NewSentPacketHandler(
 		0,
@@ -1247,6 +1248,7 @@ func TestSentPacketHandlerRetryAfterPTO(t *testing.T) {
 func TestSentPacketHandlerECN(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	cong := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+	cong.EXPECT().GetCongestionWindow().AnyTimes()
 	cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 	cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 	cong.EXPECT().MaybeExitSlowStart().AnyTimes()
@@ -1770,3 +1772,53 @@ func benchmarkSendAndAcknowledge(b *testing.B, ackEvery, inFlight int) {
 		}
 	}
 }
+
+func TestSentPacketHandlerConnectionStats(t *testing.T) {
+	var connStats utils.ConnectionStats
+	sph := NewSentPacketHandler(
+		0,
+		1200,
+		utils.NewRTTStats(),
+		&connStats,
+		true,
+		false,
+		nil,
+		protocol.PerspectiveServer,
+		nil,
+		utils.DefaultLogger,
+	)
+	require.Equal(t, uint64(1200), connStats.MTU.Load())
+	require.NotZero(t, connStats.CongestionWindow.Load())
+	sph.SetMaxDatagramSize(1400)
+	require.Equal(t, uint64(1400), connStats.MTU.Load())
+
+	now := monotime.Now()
+	// confirm the handshake
+	sph.DropPackets(protocol.EncryptionInitial, now)
+	sph.DropPackets(protocol.EncryptionHandshake, now)
+
+	var packets packetTracker
+	var pns []protocol.PacketNumber
+	for range 5 {
+		pn := sph.PopPacketNumber(protocol.Encryption1RTT)
+		sph.SentPacket(now, pn, protocol.InvalidPacketNumber, nil, []Frame{packets.NewPingFrame(pn)}, protocol.Encryption1RTT, protocol.ECT0, 1000, false, false)
+		pns = append(pns, pn)
+	}
+	require.Equal(t, uint64(5000), connStats.BytesInFlight.Load())
+
+	now = now.Add(100 * time.Millisecond)
+	_, err := sph.ReceivedAck(
+		&wire.AckFrame{AckRanges: ackRanges(pns[0], pns[1], pns[2]), ECT0: 2, ECNCE: 1},
+		protocol.Encryption1RTT,
+		now,
+	)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2000), connStats.BytesInFlight.Load())
+	require.Equal(t, uint64(2), connStats.ECT0.Load())
+	require.Zero(t, connStats.ECT1.Load())
+	require.Equal(t, uint64(1), connStats.ECNCE.Load())
+
+	// trigger a PTO
+	require.NoError(t, sph.OnLossDetectionTimeout(sph.GetLossDetectionTimeout()))
+	require.Equal(t, uint64(1), connStats.PTOCount.Load())
+}
@@ -2,6 +2,7 @@ package congestion
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/nukilabs/quic-go/internal/monotime"
 	"github.com/nukilabs/quic-go/internal/protocol"
@@ -276,12 +277,15 @@ func (c *cubicSender) isCwndLimited(bytesInFlight protocol.ByteCount) bool {
 
 // BandwidthEstimate returns the current bandwidth estimate
 func (c *cubicSender) BandwidthEstimate() Bandwidth {
-	srtt := c.rttStats.SmoothedRTT()
+	return bandwidthEstimate(c.GetCongestionWindow(), c.rttStats.SmoothedRTT())
+}
+
+func bandwidthEstimate(congestionWindow protocol.ByteCount, srtt time.Duration) Bandwidth {
 	if srtt == 0 {
 		// This should never happen, but if it does, avoid division by zero.
 		srtt = protocol.TimerGranularity
 	}
-	return BandwidthFromDelta(c.GetCongestionWindow(), srtt)
+	return BandwidthFromDelta(congestionWindow, srtt)
 }
 
 // OnRetransmissionTimeout is called on an retransmission timeout
@@ -20,21 +20,29 @@ type pacer struct {
 
 func newPacer(getBandwidth func() Bandwidth) *pacer {
 	p := &pacer{
-		maxDatagramSize: initialMaxDatagramSize,
-		adjustedBandwidth: func() uint64 {
-			// Bandwidth is in bits/s. We need the value in bytes/s.
-			bw := uint64(getBandwidth() / BytesPerSecond)
-			// Use a slightly higher value than the actual measured bandwidth.
-			// RTT variations then won't result in under-utilization of the congestion window.
-			// Ultimately, this will result in sending packets as acknowledgments are received rather than when timers fire,
-			// provided the congestion window is fully utilized and acknowledgments arrive at regular intervals.
-			return bw * 5 / 4
-		},
+		maxDatagramSize:   initialMaxDatagramSize,
+		adjustedBandwidth: func() uint64 { return adjustBandwidth(getBandwidth()) },
 	}
 	p.budgetAtLastSent = p.maxBurstSize()
 	return p
 }
 
+// adjustBandwidth returns the pacing rate for a bandwidth estimate, in bytes/s.
+func adjustBandwidth(bw Bandwidth) uint64 {
+	// Bandwidth is in bits/s. We need the value in bytes/s.
+	// Use a slightly higher value than the actual measured bandwidth.
+	// RTT variations then won't result in under-utilization of the congestion window.
+	// Ultimately, this will result in sending packets as acknowledgments are received rather than when timers fire,
+	// provided the congestion window is fully utilized and acknowledgments arrive at regular intervals.
+	return uint64(bw/BytesPerSecond) * 5 / 4
+}
+
+// PacingRate returns the rate at which packets are paced, in bytes/s,
+// for a given congestion window and smoothed RTT.
+func PacingRate(congestionWindow protocol.ByteCount, srtt time.Duration) uint64 {
+	return adjustBandwidth(bandwidthEstimate(congestionWindow, srtt))
+}
+
 func (p *pacer) SentPacket(sendTime monotime.Time, size protocol.ByteCount) {
 	budget := p.Budget(sendTime)
 	if size >= budget {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/nukilabs/quic-go/internal/monotime"
 	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/utils"
 
 	"github.com/stretchr/testify/require"
 )
@@ -152,3 +153,13 @@ func BenchmarkPacer(b *testing.B) {
 		}
 	}
 }
+
+func TestPacingRate(t *testing.T) {
+	// 100,000 bytes per 100ms is 1,000,000 bytes/s, which is increased by 25%
+	require.Equal(t, uint64(1_250_000), PacingRate(100_000, 100*time.Millisecond))
+	// the pacing rate of the cubic sender is calculated the same way
+	var rttStats utils.RTTStats
+	rttStats.UpdateRTT(100*time.Millisecond, 0)
+	sender := NewCubicSender(DefaultClock{}, &rttStats, &utils.ConnectionStats{}, protocol.InitialPacketSize, true, nil)
+	require.Equal(t, PacingRate(sender.GetCongestionWindow(), rttStats.SmoothedRTT()), sender.pacer.adjustedBandwidth())
+}
@@ -2,6 +2,7 @@ package flowcontrol
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nukilabs/quic-go/internal/monotime"
@@ -14,6 +15,11 @@ type baseFlowController struct {
 	bytesSent     protocol.ByteCount
 	sendWindow    protocol.ByteCount
 	lastBlockedAt protocol.ByteCount
+	// blockedSince is the time when sending was blocked by flow control (a monotime.Time).
+	// It is zero if sending is not blocked.
+	// blockedSince and blockedTime are accessed atomically, since they're read from outside the run loop.
+	blockedSince atomic.Int64
+	blockedTime  atomic.Int64 // time.Duration
 
 	// for receiving data
 	//nolint:structcheck // The mutex is used both by the stream and the connection flow controller
@@ -41,6 +47,7 @@ func (c *baseFlowController) IsNewlyBlocked() (bool, protocol.ByteCount) {
 		return false, 0
 	}
 	c.lastBlockedAt = c.sendWindow
+	c.blockedSince.CompareAndSwap(0, int64(monotime.Now()))
 	return true, c.sendWindow
 }
 
@@ -50,11 +57,32 @@ func (c *baseFlowController) AddBytesSent(n protocol.ByteCount) {
 
 // UpdateSendWindow is called after receiving a MAX_{STREAM_}DATA frame.
 func (c *baseFlowController) UpdateSendWindow(offset protocol.ByteCount) (updated bool) {
-	if offset > c.sendWindow {
-		c.sendWindow = offset
-		return true
+	updated, _ = c.updateSendWindow(offset)
+	return updated
+}
+
+// updateSendWindow updates the send window.
+// If sending was blocked by flow control, it returns the duration of the blocked period.
+func (c *baseFlowController) updateSendWindow(offset protocol.ByteCount) (updated bool, blocked time.Duration) {
+	if offset <= c.sendWindow {
+		return false, 0
+	}
+	c.sendWindow = offset
+	if since := monotime.Time(c.blockedSince.Swap(0)); !since.IsZero() {
+		blocked = monotime.Since(since)
+		c.blockedTime.Add(int64(blocked))
+	}
+	return true, blocked
+}
+
+// BlockedTime returns the total time that sending was blocked by flow control,
+// including the current blocked period.
+func (c *baseFlowController) BlockedTime() time.Duration {
+	d := time.Duration(c.blockedTime.Load())
+	if since := monotime.Time(c.blockedSince.Load()); !since.IsZero() {
+		d += monotime.Since(since)
 	}
-	return false
+	return d
 }
 
 func (c *baseFlowController) SendWindowSize() protocol.ByteCount {
@@ -3,6 +3,8 @@ package flowcontrol
 import (
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/nukilabs/quic-go/internal/monotime"
 	"github.com/nukilabs/quic-go/internal/protocol"
@@ -12,6 +14,8 @@ import (
 
 type connectionFlowController struct {
 	baseFlowController
+
+	streamsBlockedTime atomic.Int64 // time.Duration
 }
 
 var _ ConnectionFlowController = &connectionFlowController{}
@@ -96,6 +100,16 @@ func (c *connectionFlowController) EnsureMinimumWindowSize(inc protocol.ByteCoun
 	c.startNewAutoTuningEpoch(now)
 }
 
+func (c *connectionFlowController) addStreamBlockedTime(d time.Duration) {
+	c.streamsBlockedTime.Add(int64(d))
+}
+
+// StreamsBlockedTime returns the sum of the time that streams were blocked by stream-level flow control.
+// Only blocked periods that have already ended are taken into account.
+func (c *connectionFlowController) StreamsBlockedTime() time.Duration {
+	return time.Duration(c.streamsBlockedTime.Load())
+}
+
 // Reset rests the flow controller. This happens when 0-RTT is rejected.
 // All stream data is invalidated, it's as if we had never opened a stream and never sent any data.
 // At that point, we only have sent stream data, but we didn't have the keys to open 1-RTT keys yet.
@@ -7,6 +7,7 @@ import (
 	"github.com/nukilabs/quic-go/internal/monotime"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/qerr"
+	"github.com/nukilabs/quic-go/internal/synctest"
 	"github.com/nukilabs/quic-go/internal/utils"
 
 	"github.com/stretchr/testify/require"
@@ -75,3 +76,28 @@ func TestConnectionFlowControllerResetAfterReading(t *testing.T) {
 	fc.AddBytesRead(1)
 	require.EqualError(t, fc.Reset(), "flow controller reset after reading data")
 }
+
+func TestConnectionBlockedTime(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		fc := NewConnectionFlowController(
+			protocol.MaxByteCount,
+			protocol.MaxByteCount,
+			nil,
+			utils.NewRTTStats(),
+			utils.DefaultLogger,
+		)
+		fc.UpdateSendWindow(100)
+		fc.AddBytesSent(100)
+		blocked, offset := fc.IsNewlyBlocked()
+		require.True(t, blocked)
+		require.Equal(t, protocol.ByteCount(100), offset)
+
+		time.Sleep(time.Second)
+		require.Equal(t, time.Second, fc.BlockedTime())
+		time.Sleep(time.Second)
+		require.True(t, fc.UpdateSendWindow(200))
+		time.Sleep(time.Second)
+		require.Equal(t, 2*time.Second, fc.BlockedTime())
+		require.Zero(t, fc.StreamsBlockedTime())
+	})
+}
@@ -1,6 +1,8 @@
 package flowcontrol
 
 import (
+	"time"
+
 	"github.com/nukilabs/quic-go/internal/monotime"
 	"github.com/nukilabs/quic-go/internal/protocol"
 )
@@ -10,6 +12,9 @@ type flowController interface {
 	SendWindowSize() protocol.ByteCount
 	UpdateSendWindow(protocol.ByteCount) (updated bool)
 	AddBytesSent(protocol.ByteCount)
+	// BlockedTime returns the total time that sending was blocked by flow control.
+	// It is safe to call concurrently.
+	BlockedTime() time.Duration
 	// for receiving
 	GetWindowUpdate(monotime.Time) protocol.ByteCount // returns 0 if no update is necessary
 }
@@ -34,6 +39,9 @@ type ConnectionFlowController interface {
 	AddBytesRead(protocol.ByteCount) (hasWindowUpdate bool)
 	Reset() error
 	IsNewlyBlocked() (bool, protocol.ByteCount)
+	// StreamsBlockedTime returns the total time that streams were blocked by stream-level flow control.
+	// It is safe to call concurrently.
+	StreamsBlockedTime() time.Duration
 }
 
 type connectionFlowControllerI interface {
@@ -43,4 +51,5 @@ type connectionFlowControllerI interface {
 	EnsureMinimumWindowSize(protocol.ByteCount, monotime.Time)
 	// for receiving
 	IncrementHighestReceived(protocol.ByteCount, monotime.Time) error
+	addStreamBlockedTime(time.Duration)
 }
@@ -21,11 +21,14 @@ type streamFlowController struct {
 
 var _ StreamFlowController = &streamFlowController{}
 
-// NewStreamFlowController gets a new flow controller for a stream
+// NewStreamFlowController gets a new flow controller for a stream.
+// The receiveWindow is the initial limit advertised to the peer,
+// the receiveWindowSize is the window size used for subsequent window updates.
 func NewStreamFlowController(
 	streamID protocol.StreamID,
 	cfc ConnectionFlowController,
 	receiveWindow protocol.ByteCount,
+	receiveWindowSize protocol.ByteCount,
 	maxReceiveWindow protocol.ByteCount,
 	initialSendWindow protocol.ByteCount,
 	rttStats *utils.RTTStats,
@@ -37,7 +40,7 @@ func NewStreamFlowController(
 		baseFlowController: baseFlowController{
 			rttStats:             rttStats,
 			receiveWindow:        receiveWindow,
-			receiveWindowSize:    receiveWindow,
+			receiveWindowSize:    receiveWindowSize,
 			maxReceiveWindowSize: maxReceiveWindow,
 			sendWindow:           initialSendWindow,
 			logger:               logger,
@@ -126,6 +129,15 @@ func (c *streamFlowController) SendWindowSize() protocol.ByteCount {
 	return min(c.baseFlowController.SendWindowSize(), c.connection.SendWindowSize())
 }
 
+// UpdateSendWindow is called after receiving a MAX_STREAM_DATA frame.
+func (c *streamFlowController) UpdateSendWindow(offset protocol.ByteCount) (updated bool) {
+	updated, blocked := c.updateSendWindow(offset)
+	if blocked > 0 {
+		c.connection.addStreamBlockedTime(blocked)
+	}
+	return updated
+}
+
 func (c *streamFlowController) IsNewlyBlocked() bool {
 	blocked, _ := c.baseFlowController.IsNewlyBlocked()
 	return blocked
@@ -7,6 +7,7 @@ import (
 	"github.com/nukilabs/quic-go/internal/monotime"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/qerr"
+	"github.com/nukilabs/quic-go/internal/synctest"
 	"github.com/nukilabs/quic-go/internal/utils"
 
 	"github.com/stretchr/testify/require"
@@ -23,6 +24,7 @@ func TestStreamFlowControlReceiving(t *testing.T) {
 			utils.DefaultLogger,
 		),
 		100,
+		100,
 		protocol.MaxByteCount,
 		protocol.MaxByteCount,
 		utils.NewRTTStats(),
@@ -58,6 +60,7 @@ func TestStreamFlowControllerFinalOffset(t *testing.T) {
 			protocol.MaxByteCount,
 			protocol.MaxByteCount,
 			protocol.MaxByteCount,
+			protocol.MaxByteCount,
 			utils.NewRTTStats(),
 			utils.DefaultLogger,
 		)
@@ -117,6 +120,7 @@ func TestStreamAbandoning(t *testing.T) {
 		42,
 		connFC,
 		60,
+		60,
 		protocol.MaxByteCount,
 		100,
 		utils.NewRTTStats(),
@@ -149,6 +153,7 @@ func TestStreamSendWindow(t *testing.T) {
 		connFC,
 		protocol.MaxByteCount,
 		protocol.MaxByteCount,
+		protocol.MaxByteCount,
 		100,
 		utils.NewRTTStats(),
 		utils.DefaultLogger,
@@ -188,6 +193,7 @@ func TestStreamWindowUpdate(t *testing.T) {
 		),
 		100,
 		100,
+		100,
 		protocol.MaxByteCount,
 		utils.NewRTTStats(),
 		utils.DefaultLogger,
@@ -228,6 +234,7 @@ func TestStreamConnectionWindowUpdate(t *testing.T) {
 		42,
 		connFC,
 		1000,
+		1000,
 		protocol.MaxByteCount,
 		protocol.MaxByteCount,
 		utils.NewRTTStats(),
@@ -257,6 +264,7 @@ func TestStreamWindowAutoTuning(t *testing.T) {
 	fc := NewStreamFlowController(
 		42,
 		connFC,
+		100,
 		100, // initial send window
 		399, // max send window
 		protocol.MaxByteCount,
@@ -293,3 +301,45 @@ func TestStreamWindowAutoTuning(t *testing.T) {
 	// the connection window is also increased, but it bumps into its maximum value
 	require.Equal(t, protocol.ByteCount(203+350), connFC.GetWindowUpdate(now))
 }
+
+func TestStreamBlockedTime(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		connFC := NewConnectionFlowController(
+			protocol.MaxByteCount,
+			protocol.MaxByteCount,
+			nil,
+			utils.NewRTTStats(),
+			utils.DefaultLogger,
+		)
+		require.True(t, connFC.UpdateSendWindow(1000))
+		fc := NewStreamFlowController(
+			42,
+			connFC,
+			protocol.MaxByteCount,
+			protocol.MaxByteCount,
+			protocol.MaxByteCount,
+			100,
+			utils.NewRTTStats(),
+			utils.DefaultLogger,
+		)
+		fc.AddBytesSent(100)
+		require.True(t, fc.IsNewlyBlocked())
+		time.Sleep(time.Second)
+		// the current blocked period is included
+		require.Equal(t, time.Second, fc.BlockedTime())
+		// but it is only reported to the connection flow controller once it has ended
+		require.Zero(t, connFC.StreamsBlockedTime())
+
+		time.Sleep(time.Second)
+		require.True(t, fc.UpdateSendWindow(200))
+		require.Equal(t, 2*time.Second, fc.BlockedTime())
+		require.Equal(t, 2*time.Second, connFC.StreamsBlockedTime())
+		require.Zero(t, connFC.BlockedTime())
+
+		// updating the window when not blocked doesn't change the blocked time
+		time.Sleep(time.Second)
+		require.True(t, fc.UpdateSendWindow(300))
+		require.Equal(t, 2*time.Second, fc.BlockedTime())
+		require.Equal(t, 2*time.Second, connFC.StreamsBlockedTime())
+	})
+}
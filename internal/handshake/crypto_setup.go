@@ -2,16 +2,20 @@ package handshake
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	tls "github.com/nukilabs/utls"
 	"net"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/qerr"
+	"github.com/nukilabs/quic-go/internal/qtls"
 	"github.com/nukilabs/quic-go/internal/utils"
 	"github.com/nukilabs/quic-go/internal/wire"
 	"github.com/nukilabs/quic-go/qlog"
@@ -31,13 +35,33 @@ type cryptoSetup struct {
 
 	events []Event
 
-	version protocol.Version
+	version       protocol.Version
+	initialConnID protocol.ConnectionID
+
+	// the version information sent by the client, if the client offered a compatible version upgrade
+	// only set for the server, until the version upgrade decision is made
+	pendingVersionInfo *wire.VersionInformation
+	// the Initial opener of the original version, only set for the server after a compatible version upgrade
+	originalInitialOpener LongHeaderOpener
 
 	ourParams  *wire.TransportParameters
 	peerParams *wire.TransportParameters
 
 	zeroRTTParameters *wire.TransportParameters
 	allow0RTT         bool
+	replayFilter      ReplayFilter // only set for the server
+	// The random of the ClientHello.
+	// Only saved if it is needed by the replay filter (server).
+	clientHelloRandom []byte
+	// The obfuscated ticket age of the first PSK identity offered in the ClientHello.
+	// Only saved if it is needed by the replay filter (server).
+	obfuscatedTicketAge    uint32
+	hasObfuscatedTicketAge bool
+	// The ClientHello is buffered until it was received completely.
+	clientHello     []byte
+	clientHelloDone bool
+
+	keyUpdatePolicy KeyUpdatePolicy
 
 	rttStats *utils.RTTStats
 
@@ -72,6 +96,7 @@ func NewCryptoSetupClient(
 	tp *wire.TransportParameters,
 	tlsConf *tls.Config,
 	enable0RTT bool,
+	keyUpdatePolicy KeyUpdatePolicy,
 	rttStats *utils.RTTStats,
 	qlogger qlogwriter.Recorder,
 	logger utils.Logger,
@@ -80,6 +105,7 @@ func NewCryptoSetupClient(
 	cs := newCryptoSetup(
 		connID,
 		tp,
+		keyUpdatePolicy,
 		rttStats,
 		qlogger,
 		logger,
@@ -108,6 +134,8 @@ func NewCryptoSetupServer(
 	tp *wire.TransportParameters,
 	tlsConf *tls.Config,
 	allow0RTT bool,
+	replayFilter ReplayFilter,
+	keyUpdatePolicy KeyUpdatePolicy,
 	rttStats *utils.RTTStats,
 	qlogger qlogwriter.Recorder,
 	logger utils.Logger,
@@ -116,6 +144,7 @@ func NewCryptoSetupServer(
 	cs := newCryptoSetup(
 		connID,
 		tp,
+		keyUpdatePolicy,
 		rttStats,
 		qlogger,
 		logger,
@@ -123,6 +152,7 @@ func NewCryptoSetupServer(
 		version,
 	)
 	cs.allow0RTT = allow0RTT
+	cs.replayFilter = replayFilter
 
 	tlsConf = setupConfigForServer(tlsConf, localAddr, remoteAddr)
 
@@ -137,6 +167,7 @@ func NewCryptoSetupServer(
 func newCryptoSetup(
 	connID protocol.ConnectionID,
 	tp *wire.TransportParameters,
+	keyUpdatePolicy KeyUpdatePolicy,
 	rttStats *utils.RTTStats,
 	qlogger qlogwriter.Recorder,
 	logger utils.Logger,
@@ -154,22 +185,54 @@ func newCryptoSetup(
 			KeyType: encLevelToKeyType(protocol.EncryptionInitial, protocol.PerspectiveServer),
 		})
 	}
+	aead := newUpdatableAEAD(rttStats, qlogger, logger, version)
+	aead.policy = keyUpdatePolicy
 	return &cryptoSetup{
-		initialSealer: initialSealer,
-		initialOpener: initialOpener,
-		aead:          newUpdatableAEAD(rttStats, qlogger, logger, version),
-		events:        make([]Event, 0, 16),
-		ourParams:     tp,
-		rttStats:      rttStats,
-		qlogger:       qlogger,
-		logger:        logger,
-		perspective:   perspective,
-		version:       version,
+		initialSealer:   initialSealer,
+		initialOpener:   initialOpener,
+		aead:            aead,
+		keyUpdatePolicy: keyUpdatePolicy,
+		events:          make([]Event, 0, 16),
+		ourParams:       tp,
+		rttStats:        rttStats,
+		qlogger:         qlogger,
+		logger:          logger,
+		perspective:     perspective,
+		version:         version,
+		initialConnID:   connID,
 	}
 }
 
 func (h *cryptoSetup) ChangeConnectionID(id protocol.ConnectionID) {
-	initialSealer, initialOpener := NewInitialAEAD(id, h.perspective, h.version)
+	h.initialConnID = id
+	h.installInitialKeys()
+}
+
+// ChangeVersion is called by the client when the server performed a compatible version upgrade (RFC 9368).
+func (h *cryptoSetup) ChangeVersion(v protocol.Version) {
+	h.switchVersion(v)
+	// 0-RTT packets are sent using the original version.
+	// A server that performs a compatible version upgrade won't process them.
+	if h.zeroRTTSealer != nil {
+		h.zeroRTTSealer = nil
+		h.logger.Debugf("Dropping 0-RTT keys.")
+		if h.qlogger != nil {
+			h.qlogger.RecordEvent(qlog.KeyDiscarded{KeyType: qlog.KeyTypeClient0RTT})
+		}
+		h.events = append(h.events, Event{Kind: EventDiscard0RTTKeys})
+	}
+}
+
+func (h *cryptoSetup) switchVersion(v protocol.Version) {
+	h.logger.Debugf("Switching to compatible QUIC version %s.", v)
+	h.version = v
+	h.aead = newUpdatableAEAD(h.rttStats, h.qlogger, h.logger, v)
+	h.aead.policy = h.keyUpdatePolicy
+	h.installInitialKeys()
+}
+
+func (h *cryptoSetup) installInitialKeys() {
+	initialSealer, initialOpener := NewInitialAEAD(h.initialConnID, h.perspective, h.version)
 	h.initialSealer = initialSealer
 	h.initialOpener = initialOpener
 	if h.qlogger != nil {
@@ -229,6 +292,9 @@ func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLev
 }
 
 func (h *cryptoSetup) handleMessage(data []byte, encLevel protocol.EncryptionLevel) error {
+	if h.replayFilter != nil && encLevel == protocol.EncryptionInitial {
+		h.saveClientHello(data)
+	}
 	if err := h.conn.HandleData(encLevel.ToTLSEncryptionLevel(), data); err != nil {
 		return err
 	}
@@ -244,6 +310,17 @@ func (h *cryptoSetup) handleMessage(data []byte, encLevel protocol.EncryptionLev
 }
 
 func (h *cryptoSetup) handleEvent(ev tls.QUICEvent) (err error) {
+	if h.pendingVersionInfo != nil {
+		switch ev.Kind {
+		case tls.QUICSetReadSecret, tls.QUICSetWriteSecret, tls.QUICWriteData:
+			// If 0-RTT was accepted, the 0-RTT read key is installed before anything else.
+			// 0-RTT packets use the original version, so we don't upgrade in that case.
+			if ev.Kind != tls.QUICSetReadSecret || ev.Level != tls.QUICEncryptionLevelEarly {
+				h.maybeUpgradeVersion(h.pendingVersionInfo)
+			}
+			h.pendingVersionInfo = nil
+		}
+	}
 	switch ev.Kind {
 	case tls.QUICNoEvent:
 		return nil
@@ -289,7 +366,7 @@ func (h *cryptoSetup) handleEvent(ev tls.QUICEvent) (err error) {
 			// for servers, this event occurs when receiving the client's session ticket
 			allowEarlyData = h.handleSessionTicket(
 				findSessionStateExtraData(ev.SessionState.Extra),
-				ev.SessionState.EarlyData,
+				ev.SessionState,
 			)
 		}
 		if ev.SessionState.EarlyData {
@@ -320,9 +397,35 @@ func (h *cryptoSetup) handleTransportParameters(data []byte) error {
 	}
 	h.peerParams = &tp
 	h.events = append(h.events, Event{Kind: EventReceivedTransportParameters, TransportParameters: h.peerParams})
+	if h.perspective == protocol.PerspectiveServer && tp.VersionInformation != nil && tp.VersionInformation.ChosenVersion == h.version {
+		h.pendingVersionInfo = tp.VersionInformation
+	}
 	return nil
 }
 
+// maybeUpgradeVersion is called for the server before the first Initial packet is sent.
+// It performs a compatible version upgrade, if the client supports a version that we prefer.
+func (h *cryptoSetup) maybeUpgradeVersion(clientInfo *wire.VersionInformation) {
+	if h.ourParams.VersionInformation == nil {
+		return
+	}
+	for _, v := range h.ourParams.VersionInformation.AvailableVersions {
+		if !slices.Contains(clientInfo.AvailableVersions, v) || !protocol.IsCompatibleVersion(h.version, v) {
+			continue
+		}
+		if v == h.version {
+			return
+		}
+		// The client might retransmit its Initial packets using the original version,
+		// until it receives our first Initial packet.
+		h.originalInitialOpener = h.initialOpener
+		h.switchVersion(v)
+		h.ourParams.VersionInformation.ChosenVersion = v
+		h.events = append(h.events, Event{Kind: EventVersionUpgraded, Version: v})
+		return
+	}
+}
+
 // must be called after receiving the transport parameters
 func (h *cryptoSetup) marshalDataForSessionState(earlyData bool) []byte {
 	b := make([]byte, 0, 256)
@@ -369,8 +472,10 @@ func decodeDataFromSessionState(b []byte, earlyData bool) (*wire.TransportParame
 	return &tp, nil
 }
 
-func (h *cryptoSetup) getDataForSessionTicket() []byte {
+func (h *cryptoSetup) getDataForSessionTicket(ageAdd uint32) []byte {
 	return (&sessionTicket{
+		IssuedAt:   time.Now(),
+		AgeAdd:     ageAdd,
 		Parameters: h.ourParams,
 	}).Marshal()
 }
@@ -379,9 +484,15 @@ func (h *cryptoSetup) getDataForSessionTicket() []byte {
 // Due to limitations in crypto/tls, it's only possible to generate a single session ticket per connection.
 // It is only valid for the server.
 func (h *cryptoSetup) GetSessionTicket() ([]byte, error) {
+	// crypto/tls doesn't save the ticket_age_add it sends in the NewSessionTicket message.
+	// We choose the value ourselves, save it in the session ticket, and replace it in the message.
+	// This allows checking the freshness of the ClientHello when the session is resumed.
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	ageAdd := binary.BigEndian.Uint32(b[:])
 	if err := h.conn.SendSessionTicket(tls.QUICSessionTicketOptions{
 		EarlyData: h.allow0RTT,
-		Extra:     [][]byte{addSessionStateExtraPrefix(h.getDataForSessionTicket())},
+		Extra:     [][]byte{addSessionStateExtraPrefix(h.getDataForSessionTicket(ageAdd))},
 	}); err != nil {
 		// Session tickets might be disabled by tls.Config.SessionTicketsDisabled.
 		// We can't check h.tlsConfig here, since the actual config might have been obtained from
@@ -406,7 +517,8 @@ func (h *cryptoSetup) GetSessionTicket() ([]byte, error) {
 				h.logger.Errorf("unexpected multiple session tickets")
 				continue
 			}
-			ticket = ev.Data
+			ticket = slices.Clone(ev.Data)
+			setTicketAgeAdd(ticket, ageAdd)
 		} else {
 			h.logger.Errorf("unexpected event: %v", ev.Kind)
 		}
@@ -418,13 +530,13 @@ func (h *cryptoSetup) GetSessionTicket() ([]byte, error) {
 // It reads parameters from the session ticket and checks whether to accept 0-RTT if the session ticket enabled 0-RTT.
 // Note that the fact that the session ticket allows 0-RTT doesn't mean that the actual TLS handshake enables 0-RTT:
 // A client may use a 0-RTT enabled session to resume a TLS session without using 0-RTT.
-func (h *cryptoSetup) handleSessionTicket(data []byte, using0RTT bool) (allowEarlyData bool) {
+func (h *cryptoSetup) handleSessionTicket(data []byte, state *tls.SessionState) (allowEarlyData bool) {
 	var t sessionTicket
 	if err := t.Unmarshal(data); err != nil {
 		h.logger.Debugf("Unmarshalling session ticket failed: %s", err.Error())
 		return false
 	}
-	if !using0RTT {
+	if !state.EarlyData {
 		return false
 	}
 	valid := h.ourParams.ValidFor0RTT(t.Parameters)
@@ -436,9 +548,93 @@ func (h *cryptoSetup) handleSessionTicket(data []byte, using0RTT bool) (allowEar
 		h.logger.Debugf("0-RTT not allowed. Rejecting 0-RTT.")
 		return false
 	}
+	if h.replayFilter != nil && h.isReplay(&t, state) {
+		return false
+	}
 	return true
 }
 
+// isReplay uses the replay filter to check if this 0-RTT connection attempt is a replay.
+// The ClientHello random is covered by the PSK binder, so it can't be modified by an attacker.
+// Together with the session ticket, it identifies a 0-RTT connection attempt.
+//
+// The replay filter only remembers connection attempts for the duration of its window.
+// ClientHellos that were not sent recently are therefore rejected, see section 8.3 of RFC 8446:
+// The ticket age reported by the client (which is also covered by the PSK binder)
+// needs to match the ticket age observed by the server.
+func (h *cryptoSetup) isReplay(t *sessionTicket, state *tls.SessionState) bool {
+	if h.clientHelloRandom == nil || !h.hasObfuscatedTicketAge {
+		return true
+	}
+	clientTicketAge := time.Duration(h.obfuscatedTicketAge-t.AgeAdd) * time.Millisecond
+	serverTicketAge := time.Since(t.IssuedAt)
+	if diff := serverTicketAge - clientTicketAge; diff > h.replayFilter.Window() || diff < -h.replayFilter.Window() {
+		h.logger.Debugf("ClientHello not fresh (ticket age: %s, expected %s). Rejecting 0-RTT.", clientTicketAge, serverTicketAge)
+		return true
+	}
+	b, err := state.Bytes()
+	if err != nil {
+		return true
+	}
+	if h.replayFilter.Seen(append(b, h.clientHelloRandom...)) {
+		h.logger.Debugf("Detected a replayed 0-RTT connection attempt. Rejecting 0-RTT.")
+		return true
+	}
+	return false
+}
+
+// saveClientHello saves the random and the obfuscated ticket age of the first ClientHello,
+// which are used for the detection of replayed 0-RTT connection attempts.
+// The ClientHello might be split across multiple calls, and is buffered until it is complete.
+func (h *cryptoSetup) saveClientHello(data []byte) {
+	if h.clientHelloRandom != nil || h.clientHelloDone {
+		return
+	}
+	const handshakeTypeClientHello = 1
+	h.clientHello = append(h.clientHello, data...)
+	if len(h.clientHello) == 0 {
+		return
+	}
+	if h.clientHello[0] != handshakeTypeClientHello {
+		h.clientHello = nil
+		h.clientHelloDone = true
+		return
+	}
+	if len(h.clientHello) < 4 {
+		return
+	}
+	msgLen := 4 + (int(h.clientHello[1])<<16 | int(h.clientHello[2])<<8 | int(h.clientHello[3]))
+	if len(h.clientHello) < msgLen {
+		return
+	}
+	msg := h.clientHello[:msgLen]
+	h.clientHello = nil
+	h.clientHelloDone = true
+
+	// handshake message type (1 byte), length (3 bytes), legacy_version (2 bytes), random (32 bytes)
+	const randomOffset = 1 + 3 + 2
+	if len(msg) < randomOffset+32 {
+		return
+	}
+	h.clientHelloRandom = slices.Clone(msg[randomOffset : randomOffset+32])
+	if h.replayFilter != nil {
+		age, ok, err := qtls.FindObfuscatedTicketAge(msg)
+		h.obfuscatedTicketAge = age
+		h.hasObfuscatedTicketAge = ok && err == nil
+	}
+}
+
+// setTicketAgeAdd replaces the ticket_age_add in a NewSessionTicket message, see section 4.6.1 of RFC 8446.
+func setTicketAgeAdd(msg []byte, ageAdd uint32) {
+	const handshakeTypeNewSessionTicket = 4
+	// handshake message type (1 byte), length (3 bytes), ticket_lifetime (4 bytes), ticket_age_add (4 bytes)
+	const ageAddOffset = 1 + 3 + 4
+	if len(msg) < ageAddOffset+4 || msg[0] != handshakeTypeNewSessionTicket {
+		return
+	}
+	binary.BigEndian.PutUint32(msg[ageAddOffset:], ageAdd)
+}
+
 // rejected0RTT is called for the client when the server rejects 0-RTT.
 func (h *cryptoSetup) rejected0RTT() {
 	h.logger.Debugf("0-RTT was rejected. Dropping 0-RTT keys.")
@@ -569,6 +765,7 @@ func (h *cryptoSetup) DiscardInitialKeys() {
 	dropped := h.initialOpener != nil
 	h.initialOpener = nil
 	h.initialSealer = nil
+	h.originalInitialOpener = nil
 	if dropped {
 		h.logger.Debugf("Dropping Initial keys.")
 		if h.qlogger != nil {
@@ -583,6 +780,12 @@ func (h *cryptoSetup) handshakeComplete() {
 	h.events = append(h.events, Event{Kind: EventHandshakeComplete})
 }
 
+// InitiateKeyUpdate requests a 1-RTT key update.
+// It is performed as soon as the handshake is confirmed and the previous key update (if any) has completed.
+func (h *cryptoSetup) InitiateKeyUpdate() {
+	h.aead.InitiateKeyUpdate()
+}
+
 func (h *cryptoSetup) SetHandshakeConfirmed() {
 	h.aead.SetHandshakeConfirmed()
 	// drop Handshake keys
@@ -632,7 +835,22 @@ func (h *cryptoSetup) Get1RTTSealer() (ShortHeaderSealer, error) {
 	return h.aead, nil
 }
 
-func (h *cryptoSetup) GetInitialOpener() (LongHeaderOpener, error) {
+func (h *cryptoSetup) GetInitialOpener(v protocol.Version) (LongHeaderOpener, error) {
+	if v != h.version {
+		if h.perspective == protocol.PerspectiveClient {
+			// The server might have performed a compatible version upgrade.
+			// The client only switches to the new version once a packet was successfully unpacked using these keys.
+			if h.initialOpener == nil {
+				return nil, ErrKeysDropped
+			}
+			_, opener := NewInitialAEAD(h.initialConnID, h.perspective, v)
+			return opener, nil
+		}
+		if h.originalInitialOpener == nil {
+			return nil, ErrKeysDropped
+		}
+		return h.originalInitialOpener, nil
+	}
 	if h.initialOpener == nil {
 		return nil, ErrKeysDropped
 	}
@@ -70,6 +70,7 @@ func TestErrorBeforeClientHelloGeneration(t *testing.T) {
 		&wire.TransportParameters{},
 		tlsConf,
 		false,
+		KeyUpdatePolicy{},
 		utils.NewRTTStats(),
 		nil,
 		utils.DefaultLogger.WithPrefix("client"),
@@ -92,6 +93,8 @@ func TestMessageReceivedAtWrongEncryptionLevel(t *testing.T) {
 		&wire.TransportParameters{StatelessResetToken: &token},
 		testdata.GetTLSConfig(),
 		false,
+		nil,
+		KeyUpdatePolicy{},
 		utils.NewRTTStats(),
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
@@ -190,6 +193,7 @@ func handshakeWithTLSConf(
 		clientTransportParameters,
 		clientConf,
 		enable0RTT,
+		KeyUpdatePolicy{},
 		clientRTTStats,
 		nil,
 		utils.DefaultLogger.WithPrefix("client"),
@@ -207,6 +211,8 @@ func handshakeWithTLSConf(
 		serverTransportParameters,
 		serverConf,
 		enable0RTT,
+		nil,
+		KeyUpdatePolicy{},
 		serverRTTStats,
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
@@ -283,6 +289,7 @@ func TestTransportParameters(t *testing.T) {
 		cTransportParameters,
 		clientConf,
 		false,
+		KeyUpdatePolicy{},
 		utils.NewRTTStats(),
 		nil,
 		utils.DefaultLogger.WithPrefix("client"),
@@ -302,6 +309,8 @@ func TestTransportParameters(t *testing.T) {
 		sTransportParameters,
 		serverConf,
 		false,
+		nil,
+		KeyUpdatePolicy{},
 		utils.NewRTTStats(),
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
@@ -5,6 +5,7 @@ import (
 	"errors"
 	tls "github.com/nukilabs/utls"
 	"io"
+	"time"
 
 	"github.com/nukilabs/quic-go/internal/monotime"
 	"github.com/nukilabs/quic-go/internal/protocol"
@@ -81,6 +82,9 @@ const (
 	EventRestoredTransportParameters
 	// EventHandshakeComplete signals that the TLS handshake was completed.
 	EventHandshakeComplete
+	// EventVersionUpgraded signals that the server switched to a compatible QUIC version (RFC 9368).
+	// It is only used for the server.
+	EventVersionUpgraded
 )
 
 func (k EventKind) String() string {
@@ -101,6 +105,8 @@ func (k EventKind) String() string {
 		return "EventRestoredTransportParameters"
 	case EventHandshakeComplete:
 		return "EventHandshakeComplete"
+	case EventVersionUpgraded:
+		return "EventVersionUpgraded"
 	default:
 		return "Unknown EventKind"
 	}
@@ -111,6 +117,23 @@ type Event struct {
 	Kind                EventKind
 	Data                []byte
 	TransportParameters *wire.TransportParameters
+	Version             protocol.Version // only set for EventVersionUpgraded
+}
+
+// KeyUpdatePolicy determines when a 1-RTT key update is initiated.
+type KeyUpdatePolicy struct {
+	// MaxPackets is the maximum number of packets sent or received with the same key.
+	// If zero, protocol.KeyUpdateInterval is used.
+	MaxPackets uint64
+	// MaxBytes is the maximum number of bytes sent or received with the same key.
+	// If zero, the number of bytes is not limited.
+	MaxBytes uint64
+	// MaxAge is the maximum duration for which the same key is used.
+	// If zero, the key age is not limited.
+	MaxAge time.Duration
+	// OnKeyUpdate is called when the 1-RTT keys are rolled,
+	// both for key updates initiated by us and by the peer.
+	OnKeyUpdate func()
 }
 
 // CryptoSetup handles the handshake and protecting / unprotecting packets
@@ -118,6 +141,7 @@ type CryptoSetup interface {
 	StartHandshake(context.Context) error
 	io.Closer
 	ChangeConnectionID(protocol.ConnectionID)
+	ChangeVersion(protocol.Version)
 	GetSessionTicket() ([]byte, error)
 
 	HandleMessage([]byte, protocol.EncryptionLevel) error
@@ -126,9 +150,13 @@ type CryptoSetup interface {
 	SetLargest1RTTAcked(protocol.PacketNumber) error
 	DiscardInitialKeys()
 	SetHandshakeConfirmed()
+	InitiateKeyUpdate()
 	ConnectionState() ConnectionState
 
-	GetInitialOpener() (LongHeaderOpener, error)
+	// GetInitialOpener returns the opener for Initial packets sent using the given version.
+	// The client can open Initial packets sent using a compatible version before switching to it,
+	// and after a compatible version upgrade, the server can still open Initial packets sent using the original version.
+	GetInitialOpener(protocol.Version) (LongHeaderOpener, error)
 	GetHandshakeOpener() (LongHeaderOpener, error)
 	Get0RTTOpener() (LongHeaderOpener, error)
 	Get1RTTOpener() (ShortHeaderOpener, error)
@@ -0,0 +1,125 @@
+package handshake
+
+import (
+	"hash/maphash"
+	"math"
+	"sync"
+	"time"
+)
+
+// A ReplayFilter is used by the server to detect replayed 0-RTT connection attempts.
+type ReplayFilter interface {
+	// Window is the duration for which the filter remembers entries.
+	// 0-RTT is rejected for session tickets that were issued more than Window ago,
+	// since replays of such connection attempts can't be detected anymore.
+	Window() time.Duration
+	// Seen records the entry, and reports whether it was recorded before.
+	// False positives are acceptable, they only lead to 0-RTT being rejected.
+	// False negatives are not.
+	Seen(entry []byte) bool
+}
+
+// bloomFalsePositiveRate is the target false positive rate of a bloomReplayFilter, for the configured capacity.
+const bloomFalsePositiveRate = 1e-4
+
+type bloomFilter struct {
+	bits []uint64
+	// number of hash functions
+	k uint32
+}
+
+func newBloomFilter(numBits int, k uint32) *bloomFilter {
+	return &bloomFilter{bits: make([]uint64, (numBits+63)/64), k: k}
+}
+
+// The k hashes are derived from the two base hashes using double hashing.
+func (f *bloomFilter) contains(h1, h2 uint64) bool {
+	m := uint64(len(f.bits)) * 64
+	for i := range uint64(f.k) {
+		pos := (h1 + i*h2) % m
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bloomFilter) add(h1, h2 uint64) {
+	m := uint64(len(f.bits)) * 64
+	for i := range uint64(f.k) {
+		pos := (h1 + i*h2) % m
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// bloomReplayFilter is a time-bucketed bloom filter.
+// It uses two bloom filters, each one covering a time interval of the window size.
+// Entries are therefore remembered for at least the window size, and at most for twice the window size.
+type bloomReplayFilter struct {
+	mutex sync.Mutex
+
+	window   time.Duration
+	numBits  int
+	k        uint32
+	seed1    maphash.Seed
+	seed2    maphash.Seed
+	current  *bloomFilter
+	previous *bloomFilter
+	rotateAt time.Time
+}
+
+var _ ReplayFilter = &bloomReplayFilter{}
+
+// NewBloomReplayFilter creates a new ReplayFilter based on a time-bucketed bloom filter.
+// capacity is the expected number of 0-RTT connection attempts per window.
+func NewBloomReplayFilter(window time.Duration, capacity int) ReplayFilter {
+	capacity = max(capacity, 1)
+	// see https://en.wikipedia.org/wiki/Bloom_filter#Optimal_number_of_hash_functions
+	numBits := int(math.Ceil(-float64(capacity) * math.Log(bloomFalsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Round(float64(numBits) / float64(capacity) * math.Ln2))
+	f := &bloomReplayFilter{
+		window:  window,
+		numBits: numBits,
+		k:       max(k, 1),
+		seed1:   maphash.MakeSeed(),
+		seed2:   maphash.MakeSeed(),
+	}
+	f.current = newBloomFilter(f.numBits, f.k)
+	f.previous = newBloomFilter(f.numBits, f.k)
+	f.rotateAt = time.Now().Add(window)
+	return f
+}
+
+func (f *bloomReplayFilter) Window() time.Duration { return f.window }
+
+func (f *bloomReplayFilter) Seen(entry []byte) bool {
+	h1 := maphash.Bytes(f.seed1, entry)
+	// make sure the second hash is odd, such that it is coprime with the (even) number of bits
+	h2 := maphash.Bytes(f.seed2, entry) | 1
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.maybeRotate(time.Now())
+	if f.current.contains(h1, h2) || f.previous.contains(h1, h2) {
+		return true
+	}
+	f.current.add(h1, h2)
+	return false
+}
+
+func (f *bloomReplayFilter) maybeRotate(now time.Time) {
+	if now.Before(f.rotateAt) {
+		return
+	}
+	// If more than two windows have passed, all entries can be forgotten.
+	if now.Sub(f.rotateAt) >= f.window {
+		f.previous = newBloomFilter(f.numBits, f.k)
+		f.current = newBloomFilter(f.numBits, f.k)
+		f.rotateAt = now.Add(f.window)
+		return
+	}
+	f.previous = f.current
+	f.current = newBloomFilter(f.numBits, f.k)
+	f.rotateAt = f.rotateAt.Add(f.window)
+}
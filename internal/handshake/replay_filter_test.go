@@ -0,0 +1,57 @@
+package handshake
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/synctest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBloomReplayFilter(t *testing.T) {
+	f := NewBloomReplayFilter(time.Minute, 1000)
+	require.Equal(t, time.Minute, f.Window())
+	require.False(t, f.Seen([]byte("foo")))
+	require.False(t, f.Seen([]byte("bar")))
+	require.True(t, f.Seen([]byte("foo")))
+	require.True(t, f.Seen([]byte("bar")))
+}
+
+func TestBloomReplayFilterFalsePositives(t *testing.T) {
+	const capacity = 10000
+	f := NewBloomReplayFilter(time.Minute, capacity)
+	for i := range capacity {
+		f.Seen(fmt.Appendf(nil, "entry %d", i))
+	}
+	// Note that every check adds the entry to the filter.
+	var falsePositives int
+	for i := range 1000 {
+		if f.Seen(fmt.Appendf(nil, "other entry %d", i)) {
+			falsePositives++
+		}
+	}
+	require.Less(t, falsePositives, 5)
+}
+
+func TestBloomReplayFilterRotation(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		f := NewBloomReplayFilter(time.Minute, 100)
+		require.False(t, f.Seen([]byte("foo")))
+		time.Sleep(59 * time.Second)
+		require.False(t, f.Seen([]byte("bar")))
+		// entries are remembered for at least the window size
+		time.Sleep(50 * time.Second)
+		require.True(t, f.Seen([]byte("foo")))
+		require.True(t, f.Seen([]byte("bar")))
+		// ... and forgotten after twice the window size
+		time.Sleep(20 * time.Second)
+		require.False(t, f.Seen([]byte("foo")))
+		time.Sleep(time.Minute)
+		require.True(t, f.Seen([]byte("foo")))
+		// all entries are forgotten if the filter wasn't used for a long time
+		time.Sleep(3 * time.Minute)
+		require.False(t, f.Seen([]byte("foo")))
+	})
+}
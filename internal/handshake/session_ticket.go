@@ -4,20 +4,28 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/nukilabs/quic-go/internal/wire"
 	"github.com/nukilabs/quic-go/quicvarint"
 )
 
-const sessionTicketRevision = 5
+const sessionTicketRevision = 7
 
 type sessionTicket struct {
+	IssuedAt time.Time
+	// AgeAdd is the ticket_age_add value sent in the NewSessionTicket message.
+	// It is used to deobfuscate the ticket age that the client sends when resuming the session.
+	AgeAdd     uint32
 	Parameters *wire.TransportParameters
 }
 
 func (t *sessionTicket) Marshal() []byte {
 	b := make([]byte, 0, 256)
 	b = quicvarint.Append(b, sessionTicketRevision)
+	b = quicvarint.Append(b, uint64(t.IssuedAt.UnixMilli()))
+	b = quicvarint.Append(b, uint64(t.AgeAdd))
 	return t.Parameters.MarshalForSessionTicket(b)
 }
 
@@ -30,6 +38,18 @@ func (t *sessionTicket) Unmarshal(b []byte) error {
 	if rev != sessionTicketRevision {
 		return fmt.Errorf("unknown session ticket revision: %d", rev)
 	}
+	issuedAt, l, err := quicvarint.Parse(b)
+	if err != nil {
+		return errors.New("failed to read session ticket issuance time")
+	}
+	b = b[l:]
+	t.IssuedAt = time.UnixMilli(int64(issuedAt))
+	ageAdd, l, err := quicvarint.Parse(b)
+	if err != nil || ageAdd > math.MaxUint32 {
+		return errors.New("failed to read session ticket age add")
+	}
+	b = b[l:]
+	t.AgeAdd = uint32(ageAdd)
 	var tp wire.TransportParameters
 	if err := tp.UnmarshalFromSessionTicket(b); err != nil {
 		return fmt.Errorf("unmarshaling transport parameters from session ticket failed: %s", err.Error())
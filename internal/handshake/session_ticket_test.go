@@ -2,6 +2,7 @@ package handshake
 
 import (
 	"testing"
+	"time"
 
 	"github.com/nukilabs/quic-go/internal/wire"
 	"github.com/nukilabs/quic-go/quicvarint"
@@ -10,7 +11,10 @@ import (
 )
 
 func TestMarshalUnmarshalSessionTicket(t *testing.T) {
+	issuedAt := time.Now().Truncate(time.Millisecond)
 	ticket := &sessionTicket{
+		IssuedAt: issuedAt,
+		AgeAdd:   0xdeadbeef,
 		Parameters: &wire.TransportParameters{
 			InitialMaxStreamDataBidiLocal:  1,
 			InitialMaxStreamDataBidiRemote: 2,
@@ -20,6 +24,8 @@ func TestMarshalUnmarshalSessionTicket(t *testing.T) {
 	}
 	var t2 sessionTicket
 	require.NoError(t, t2.Unmarshal(ticket.Marshal()))
+	require.True(t, t2.IssuedAt.Equal(issuedAt))
+	require.Equal(t, uint32(0xdeadbeef), t2.AgeAdd)
 	require.EqualValues(t, 1, t2.Parameters.InitialMaxStreamDataBidiLocal)
 	require.EqualValues(t, 2, t2.Parameters.InitialMaxStreamDataBidiRemote)
 	require.EqualValues(t, 10, t2.Parameters.ActiveConnectionIDLimit)
@@ -37,8 +43,23 @@ func TestUnmarshalRefusesUnknownRevision(t *testing.T) {
 	require.EqualError(t, err, "unknown session ticket revision: 1337")
 }
 
+func TestUnmarshalRefusesMissingIssuanceTime(t *testing.T) {
+	b := quicvarint.Append(nil, sessionTicketRevision)
+	err := (&sessionTicket{}).Unmarshal(b)
+	require.EqualError(t, err, "failed to read session ticket issuance time")
+}
+
+func TestUnmarshalRefusesMissingAgeAdd(t *testing.T) {
+	b := quicvarint.Append(nil, sessionTicketRevision)
+	b = quicvarint.Append(b, uint64(time.Now().UnixMilli()))
+	err := (&sessionTicket{}).Unmarshal(b)
+	require.EqualError(t, err, "failed to read session ticket age add")
+}
+
 func TestUnmarshal0RTTRefusesInvalidTransportParameters(t *testing.T) {
 	b := quicvarint.Append(nil, sessionTicketRevision)
+	b = quicvarint.Append(b, uint64(time.Now().UnixMilli()))
+	b = quicvarint.Append(b, 1337)
 	b = append(b, []byte("foobar")...)
 	err := (&sessionTicket{}).Unmarshal(b)
 	require.Error(t, err)
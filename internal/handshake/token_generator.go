@@ -44,12 +44,18 @@ type token struct {
 
 // A TokenGenerator generates tokens
 type TokenGenerator struct {
-	tokenProtector tokenProtector
+	tokenProtector *tokenProtector
 }
 
 // NewTokenGenerator initializes a new TokenGenerator
 func NewTokenGenerator(key TokenProtectorKey) *TokenGenerator {
-	return &TokenGenerator{tokenProtector: *newTokenProtector(key)}
+	return &TokenGenerator{tokenProtector: newTokenProtector(key)}
+}
+
+// SetKey replaces the key used to generate new tokens.
+// Tokens generated using the previous key can still be decoded for the duration of the grace period.
+func (g *TokenGenerator) SetKey(key TokenProtectorKey, gracePeriod time.Duration) {
+	g.tokenProtector.SetKey(key, gracePeriod)
 }
 
 // NewRetryToken generates a new token for a Retry for a given source address
@@ -7,6 +7,9 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
+	"slices"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/hkdf"
 )
@@ -16,9 +19,18 @@ type TokenProtectorKey [32]byte
 
 const tokenNonceSize = 32
 
+type expiringTokenProtectorKey struct {
+	key    TokenProtectorKey
+	expiry time.Time
+}
+
 // tokenProtector is used to create and verify a token
 type tokenProtector struct {
-	key TokenProtectorKey
+	mutex sync.Mutex
+	key   TokenProtectorKey
+	// Keys that were replaced by SetKey.
+	// Tokens encrypted with these keys are accepted until the key expires.
+	previousKeys []expiringTokenProtectorKey
 }
 
 // newTokenProtector creates a source for source address tokens
@@ -26,13 +38,55 @@ func newTokenProtector(key TokenProtectorKey) *tokenProtector {
 	return &tokenProtector{key: key}
 }
 
+// SetKey replaces the key used to encrypt new tokens.
+// Tokens encrypted with the previous key are accepted for the grace period.
+func (s *tokenProtector) SetKey(key TokenProtectorKey, gracePeriod time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if key == s.key {
+		return
+	}
+	now := time.Now()
+	s.previousKeys = slices.DeleteFunc(s.previousKeys, func(k expiringTokenProtectorKey) bool {
+		return k.key == key || !now.Before(k.expiry)
+	})
+	if gracePeriod > 0 {
+		s.previousKeys = append(s.previousKeys, expiringTokenProtectorKey{key: s.key, expiry: now.Add(gracePeriod)})
+	}
+	s.key = key
+}
+
+func (s *tokenProtector) currentKey() TokenProtectorKey {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.key
+}
+
+// validKeys returns the current key, followed by all previous keys that haven't expired yet
+func (s *tokenProtector) validKeys() []TokenProtectorKey {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keys := make([]TokenProtectorKey, 1, 1+len(s.previousKeys))
+	keys[0] = s.key
+	now := time.Now()
+	for _, k := range s.previousKeys {
+		if now.Before(k.expiry) {
+			keys = append(keys, k.key)
+		}
+	}
+	return keys
+}
+
 // NewToken encodes data into a new token.
 func (s *tokenProtector) NewToken(data []byte) ([]byte, error) {
 	var nonce [tokenNonceSize]byte
 	if _, err := rand.Read(nonce[:]); err != nil {
 		return nil, err
 	}
-	aead, aeadNonce, err := s.createAEAD(nonce[:])
+	key := s.currentKey()
+	aead, aeadNonce, err := createTokenAEAD(&key, nonce[:])
 	if err != nil {
 		return nil, err
 	}
@@ -45,15 +99,25 @@ func (s *tokenProtector) DecodeToken(p []byte) ([]byte, error) {
 		return nil, fmt.Errorf("token too short: %d", len(p))
 	}
 	nonce := p[:tokenNonceSize]
-	aead, aeadNonce, err := s.createAEAD(nonce)
-	if err != nil {
-		return nil, err
+	var firstErr error
+	for _, key := range s.validKeys() {
+		aead, aeadNonce, err := createTokenAEAD(&key, nonce)
+		if err != nil {
+			return nil, err
+		}
+		data, err := aead.Open(nil, aeadNonce, p[tokenNonceSize:], nil)
+		if err == nil {
+			return data, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
 	}
-	return aead.Open(nil, aeadNonce, p[tokenNonceSize:], nil)
+	return nil, firstErr
 }
 
-func (s *tokenProtector) createAEAD(nonce []byte) (cipher.AEAD, []byte, error) {
-	h := hkdf.New(sha256.New, s.key[:], nonce, []byte("quic-go token source"))
+func createTokenAEAD(secret *TokenProtectorKey, nonce []byte) (cipher.AEAD, []byte, error) {
+	h := hkdf.New(sha256.New, secret[:], nonce, []byte("quic-go token source"))
 	key := make([]byte, 32) // use a 32 byte key, in order to select AES-256
 	if _, err := io.ReadFull(h, key); err != nil {
 		return nil, nil, err
@@ -3,6 +3,9 @@ package handshake
 import (
 	"crypto/rand"
 	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/synctest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -65,3 +68,41 @@ func TestTokenProtectorTooShortTokens(t *testing.T) {
 	_, err := tp.DecodeToken([]byte("foobar"))
 	require.EqualError(t, err, "token too short: 6")
 }
+
+func TestTokenProtectorKeyRotation(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var key1, key2, key3 TokenProtectorKey
+		rand.Read(key1[:])
+		rand.Read(key2[:])
+		rand.Read(key3[:])
+		tp := newTokenProtector(key1)
+
+		t1, err := tp.NewToken([]byte("foo"))
+		require.NoError(t, err)
+		tp.SetKey(key2, time.Minute)
+		t2, err := tp.NewToken([]byte("bar"))
+		require.NoError(t, err)
+		// new tokens are encrypted with the new key
+		_, err = newTokenProtector(key1).DecodeToken(t2)
+		require.Error(t, err)
+		_, err = newTokenProtector(key2).DecodeToken(t2)
+		require.NoError(t, err)
+
+		// tokens encrypted with the previous key are accepted during the grace period
+		time.Sleep(time.Minute - time.Nanosecond)
+		decoded, err := tp.DecodeToken(t1)
+		require.NoError(t, err)
+		require.Equal(t, []byte("foo"), decoded)
+		time.Sleep(time.Nanosecond)
+		_, err = tp.DecodeToken(t1)
+		require.Error(t, err)
+		decoded, err = tp.DecodeToken(t2)
+		require.NoError(t, err)
+		require.Equal(t, []byte("bar"), decoded)
+
+		// without a grace period, tokens encrypted with the previous key are rejected immediately
+		tp.SetKey(key3, 0)
+		_, err = tp.DecodeToken(t2)
+		require.Error(t, err)
+	})
+}
@@ -51,11 +51,18 @@ type updatableAEAD struct {
 	highestRcvdPN           protocol.PacketNumber // highest packet number received (which could be successfully unprotected)
 	numRcvdWithCurrentKey   uint64
 	numSentWithCurrentKey   uint64
-	rcvAEAD                 cipher.AEAD
-	sendAEAD                cipher.AEAD
+	bytesRcvdWithCurrentKey uint64
+	bytesSentWithCurrentKey uint64
+	// time when the current key phase started, or when the handshake was confirmed for the first key phase
+	currentKeyStart monotime.Time
+	rcvAEAD         cipher.AEAD
+	sendAEAD        cipher.AEAD
 	// caches cipher.AEAD.Overhead(). This speeds up calls to Overhead().
 	aeadOverhead int
 
+	policy             KeyUpdatePolicy
+	keyUpdateRequested bool
+
 	nextRcvAEAD           cipher.AEAD
 	nextSendAEAD          cipher.AEAD
 	nextRcvTrafficSecret  []byte
@@ -109,10 +116,18 @@ func (a *updatableAEAD) rollKeys() {
 	}
 
 	a.keyPhase++
+	if a.policy.OnKeyUpdate != nil {
+		a.policy.OnKeyUpdate()
+	}
 	a.firstRcvdWithCurrentKey = protocol.InvalidPacketNumber
 	a.firstSentWithCurrentKey = protocol.InvalidPacketNumber
 	a.numRcvdWithCurrentKey = 0
 	a.numSentWithCurrentKey = 0
+	a.bytesRcvdWithCurrentKey = 0
+	a.bytesSentWithCurrentKey = 0
+	a.currentKeyStart = monotime.Now()
+	// A key update initiated by the peer also satisfies a pending request for a key update.
+	a.keyUpdateRequested = false
 	a.prevRcvAEAD = a.rcvAEAD
 	a.rcvAEAD = a.nextRcvAEAD
 	a.sendAEAD = a.nextSendAEAD
@@ -261,6 +276,7 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime monotime.Time, pn protocol
 		return dec, ErrDecryptionFailed
 	}
 	a.numRcvdWithCurrentKey++
+	a.bytesRcvdWithCurrentKey += uint64(len(dec))
 	if a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber {
 		// We initiated the key updated, and now we received the first packet protected with the new key phase.
 		// Therefore, we are certain that the peer rolled its keys as well. Start a timer to drop the old keys.
@@ -281,6 +297,7 @@ func (a *updatableAEAD) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byt
 		a.firstPacketNumber = pn
 	}
 	a.numSentWithCurrentKey++
+	a.bytesSentWithCurrentKey += uint64(len(src))
 	binary.BigEndian.PutUint64(a.nonceBuf[len(a.nonceBuf)-8:], uint64(pn))
 	// The AEAD we're using here will be the qtls.aeadAESGCM13.
 	// It uses the nonce provided here and XOR it with the IV.
@@ -301,6 +318,14 @@ func (a *updatableAEAD) SetLargestAcked(pn protocol.PacketNumber) error {
 
 func (a *updatableAEAD) SetHandshakeConfirmed() {
 	a.handshakeConfirmed = true
+	a.currentKeyStart = monotime.Now()
+}
+
+// InitiateKeyUpdate requests a key update.
+// The key update is performed as soon as it is allowed,
+// i.e. once the handshake is confirmed and the peer acknowledged a packet sent with the current key phase.
+func (a *updatableAEAD) InitiateKeyUpdate() {
+	a.keyUpdateRequested = true
 }
 
 func (a *updatableAEAD) updateAllowed() bool {
@@ -315,38 +340,64 @@ func (a *updatableAEAD) updateAllowed() bool {
 			a.largestAcked >= a.firstSentWithCurrentKey)
 }
 
-func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
+func (a *updatableAEAD) shouldInitiateKeyUpdate() (bool, qlog.KeyUpdateReason) {
 	if !a.updateAllowed() {
-		return false
+		return false, ""
+	}
+	if a.keyUpdateRequested {
+		a.logger.Debugf("Application requested key update. Initiating key update to the next key phase: %d", a.keyPhase+1)
+		return true, qlog.KeyUpdateReasonApplication
 	}
 	// Initiate the first key update shortly after the handshake, in order to exercise the key update mechanism.
 	if a.keyPhase == 0 {
 		if a.numRcvdWithCurrentKey >= FirstKeyUpdateInterval || a.numSentWithCurrentKey >= FirstKeyUpdateInterval {
-			return true
+			return true, qlog.KeyUpdateReasonPacketLimit
 		}
 	}
-	if a.numRcvdWithCurrentKey >= keyUpdateInterval.Load() {
+	maxPackets := a.policy.MaxPackets
+	if maxPackets == 0 {
+		maxPackets = keyUpdateInterval.Load()
+	}
+	if a.numRcvdWithCurrentKey >= maxPackets {
 		a.logger.Debugf("Received %d packets with current key phase. Initiating key update to the next key phase: %d", a.numRcvdWithCurrentKey, a.keyPhase+1)
-		return true
+		return true, qlog.KeyUpdateReasonPacketLimit
 	}
-	if a.numSentWithCurrentKey >= keyUpdateInterval.Load() {
+	if a.numSentWithCurrentKey >= maxPackets {
 		a.logger.Debugf("Sent %d packets with current key phase. Initiating key update to the next key phase: %d", a.numSentWithCurrentKey, a.keyPhase+1)
-		return true
+		return true, qlog.KeyUpdateReasonPacketLimit
+	}
+	if a.policy.MaxBytes > 0 {
+		if a.bytesRcvdWithCurrentKey >= a.policy.MaxBytes {
+			a.logger.Debugf("Received %d bytes with current key phase. Initiating key update to the next key phase: %d", a.bytesRcvdWithCurrentKey, a.keyPhase+1)
+			return true, qlog.KeyUpdateReasonByteLimit
+		}
+		if a.bytesSentWithCurrentKey >= a.policy.MaxBytes {
+			a.logger.Debugf("Sent %d bytes with current key phase. Initiating key update to the next key phase: %d", a.bytesSentWithCurrentKey, a.keyPhase+1)
+			return true, qlog.KeyUpdateReasonByteLimit
+		}
+	}
+	if a.policy.MaxAge > 0 && !a.currentKeyStart.IsZero() {
+		if age := monotime.Since(a.currentKeyStart); age >= a.policy.MaxAge {
+			a.logger.Debugf("Used current key phase for %s. Initiating key update to the next key phase: %d", age, a.keyPhase+1)
+			return true, qlog.KeyUpdateReasonTimeLimit
+		}
 	}
-	return false
+	return false, ""
 }
 
 func (a *updatableAEAD) KeyPhase() protocol.KeyPhaseBit {
-	if a.shouldInitiateKeyUpdate() {
+	if ok, reason := a.shouldInitiateKeyUpdate(); ok {
 		a.rollKeys()
 		if a.qlogger != nil {
 			a.qlogger.RecordEvent(qlog.KeyUpdated{
 				Trigger:  qlog.KeyUpdateLocal,
+				Reason:   reason,
 				KeyType:  qlog.KeyTypeClient1RTT,
 				KeyPhase: a.keyPhase,
 			})
 			a.qlogger.RecordEvent(qlog.KeyUpdated{
 				Trigger:  qlog.KeyUpdateLocal,
+				Reason:   reason,
 				KeyType:  qlog.KeyTypeServer1RTT,
 				KeyPhase: a.keyPhase,
 			})
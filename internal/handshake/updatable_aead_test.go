@@ -11,6 +11,7 @@ import (
 	"github.com/nukilabs/quic-go/internal/monotime"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/qerr"
+	"github.com/nukilabs/quic-go/internal/synctest"
 	"github.com/nukilabs/quic-go/internal/utils"
 	"github.com/nukilabs/quic-go/qlog"
 	"github.com/nukilabs/quic-go/qlogwriter"
@@ -63,11 +64,13 @@ func bothSides(ev qlogwriter.Event) []qlogwriter.Event {
 				KeyType:  qlog.KeyTypeClient1RTT,
 				KeyPhase: ev.KeyPhase,
 				Trigger:  ev.Trigger,
+				Reason:   ev.Reason,
 			},
 			qlog.KeyUpdated{
 				KeyType:  qlog.KeyTypeServer1RTT,
 				KeyPhase: ev.KeyPhase,
 				Trigger:  ev.Trigger,
+				Reason:   ev.Reason,
 			},
 		}
 	default:
@@ -402,7 +405,7 @@ func TestInitiateKeyUpdateAfterSendingMaxPackets(t *testing.T) {
 	// the first update is allowed without receiving an acknowledgement
 	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
 	require.Equal(t,
-		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal}),
+		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonPacketLimit}),
 		eventRecorder.Events(),
 	)
 	eventRecorder.Clear()
@@ -427,12 +430,110 @@ func TestInitiateKeyUpdateAfterSendingMaxPackets(t *testing.T) {
 	require.Equal(t,
 		append(
 			bothSides(qlog.KeyDiscarded{KeyPhase: 0}),
-			bothSides(qlog.KeyUpdated{KeyPhase: 2, Trigger: qlog.KeyUpdateLocal})...,
+			bothSides(qlog.KeyUpdated{KeyPhase: 2, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonPacketLimit})...,
 		),
 		eventRecorder.Events(),
 	)
 }
 
+func TestInitiateKeyUpdatePolicy(t *testing.T) {
+	t.Run("packet limit", func(t *testing.T) {
+		testInitiateKeyUpdatePolicy(t, KeyUpdatePolicy{MaxPackets: 10}, 10, qlog.KeyUpdateReasonPacketLimit)
+	})
+	t.Run("byte limit", func(t *testing.T) {
+		testInitiateKeyUpdatePolicy(t, KeyUpdatePolicy{MaxBytes: 5 * uint64(len(msg))}, 5, qlog.KeyUpdateReasonByteLimit)
+	})
+}
+
+func testInitiateKeyUpdatePolicy(t *testing.T, policy KeyUpdatePolicy, numPackets int, reason qlog.KeyUpdateReason) {
+	setKeyUpdateIntervals(t, 1000, protocol.KeyUpdateInterval)
+
+	_, server, eventRecorder := setupEndpoints(t, utils.NewRTTStats())
+	server.policy = policy
+	server.SetHandshakeConfirmed()
+
+	for pn := range numPackets {
+		require.Equal(t, protocol.KeyPhaseZero, server.KeyPhase())
+		server.Seal(nil, []byte(msg), protocol.PacketNumber(pn), []byte(ad))
+	}
+	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
+	require.Equal(t,
+		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal, Reason: reason}),
+		eventRecorder.Events(),
+	)
+}
+
+func TestInitiateKeyUpdateAfterMaxAge(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		setKeyUpdateIntervals(t, 1000, protocol.KeyUpdateInterval)
+
+		client, server, eventRecorder := setupEndpoints(t, utils.NewRTTStats())
+		server.policy = KeyUpdatePolicy{MaxAge: time.Hour}
+		require.Equal(t, protocol.KeyPhaseZero, server.KeyPhase())
+		// the key age is counted from the confirmation of the handshake
+		time.Sleep(time.Hour)
+		require.Equal(t, protocol.KeyPhaseZero, server.KeyPhase())
+		server.SetHandshakeConfirmed()
+		time.Sleep(time.Hour - time.Nanosecond)
+		require.Equal(t, protocol.KeyPhaseZero, server.KeyPhase())
+		server.Seal(nil, []byte(msg), 0, []byte(ad))
+		time.Sleep(time.Nanosecond)
+		require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
+		require.Equal(t,
+			bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonTimeLimit}),
+			eventRecorder.Events(),
+		)
+		eventRecorder.Clear()
+
+		// receive an ACK for a packet sent in key phase 1
+		server.Seal(nil, []byte(msg), 1, []byte(ad))
+		client.rollKeys()
+		b := client.Seal(nil, []byte("foobar"), 1, []byte("ad"))
+		_, err := server.Open(nil, b, monotime.Now(), 1, protocol.KeyPhaseOne, []byte("ad"))
+		require.NoError(t, err)
+		require.NoError(t, server.SetLargestAcked(1))
+
+		time.Sleep(time.Hour)
+		require.Equal(t, protocol.KeyPhaseZero, server.KeyPhase())
+		require.Equal(t,
+			bothSides(qlog.KeyUpdated{KeyPhase: 2, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonTimeLimit}),
+			eventRecorder.Events(qlog.KeyUpdated{}),
+		)
+	})
+}
+
+func TestInitiateKeyUpdateRequestedByApplication(t *testing.T) {
+	setKeyUpdateIntervals(t, 1000, protocol.KeyUpdateInterval)
+
+	client, server, eventRecorder := setupEndpoints(t, utils.NewRTTStats())
+	server.InitiateKeyUpdate()
+	// no key update before the handshake is confirmed
+	require.Equal(t, protocol.KeyPhaseZero, server.KeyPhase())
+	server.SetHandshakeConfirmed()
+	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
+	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
+	require.Equal(t,
+		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonApplication}),
+		eventRecorder.Events(),
+	)
+	eventRecorder.Clear()
+
+	// the next key update is delayed until a packet sent in key phase 1 is acknowledged
+	server.Seal(nil, []byte(msg), 0, []byte(ad))
+	server.InitiateKeyUpdate()
+	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
+	client.rollKeys()
+	b := client.Seal(nil, []byte("foobar"), 1, []byte("ad"))
+	_, err := server.Open(nil, b, monotime.Now(), 1, protocol.KeyPhaseOne, []byte("ad"))
+	require.NoError(t, err)
+	require.NoError(t, server.SetLargestAcked(0))
+	require.Equal(t, protocol.KeyPhaseZero, server.KeyPhase())
+	require.Equal(t,
+		bothSides(qlog.KeyUpdated{KeyPhase: 2, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonApplication}),
+		eventRecorder.Events(qlog.KeyUpdated{}),
+	)
+}
+
 func TestKeyUpdateEnforceACKKeyPhase(t *testing.T) {
 	const firstKeyUpdateInterval = 5
 	setKeyUpdateIntervals(t, firstKeyUpdateInterval, protocol.KeyUpdateInterval)
@@ -448,7 +549,7 @@ func TestKeyUpdateEnforceACKKeyPhase(t *testing.T) {
 	}
 	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
 	require.Equal(t,
-		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal}),
+		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonPacketLimit}),
 		eventRecorder.Events(),
 	)
 	eventRecorder.Clear()
@@ -498,7 +599,7 @@ func TestKeyUpdateAfterOpeningMaxPackets(t *testing.T) {
 	// the first update is allowed without receiving an acknowledgement
 	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
 	require.Equal(t,
-		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal}),
+		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonPacketLimit}),
 		eventRecorder.Events(),
 	)
 	eventRecorder.Clear()
@@ -521,7 +622,7 @@ func TestKeyUpdateAfterOpeningMaxPackets(t *testing.T) {
 	require.Equal(t,
 		append(
 			bothSides(qlog.KeyDiscarded{KeyPhase: 0}),
-			bothSides(qlog.KeyUpdated{KeyPhase: 2, Trigger: qlog.KeyUpdateLocal})...,
+			bothSides(qlog.KeyUpdated{KeyPhase: 2, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonPacketLimit})...,
 		),
 		eventRecorder.Events(),
 	)
@@ -549,7 +650,7 @@ func TestKeyUpdateKeyPhaseSkipping(t *testing.T) {
 	}
 	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
 	require.Equal(t,
-		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal}),
+		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonPacketLimit}),
 		eventRecorder.Events(),
 	)
 	eventRecorder.Clear()
@@ -582,7 +683,7 @@ func TestFastKeyUpdatesByPeer(t *testing.T) {
 	require.NoError(t, server.SetLargestAcked(0))
 	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
 	require.Equal(t,
-		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal}),
+		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonPacketLimit}),
 		eventRecorder.Events(),
 	)
 	eventRecorder.Clear()
@@ -635,7 +736,7 @@ func TestFastKeyUpdateByUs(t *testing.T) {
 	require.NoError(t, server.SetLargestAcked(0))
 	require.Equal(t, protocol.KeyPhaseOne, server.KeyPhase())
 	require.Equal(t,
-		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal}),
+		bothSides(qlog.KeyUpdated{KeyPhase: 1, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonPacketLimit}),
 		eventRecorder.Events(),
 	)
 	eventRecorder.Clear()
@@ -656,7 +757,7 @@ func TestFastKeyUpdateByUs(t *testing.T) {
 	require.Equal(t,
 		append(
 			bothSides(qlog.KeyDiscarded{KeyPhase: 0}),
-			bothSides(qlog.KeyUpdated{KeyPhase: 2, Trigger: qlog.KeyUpdateLocal})...,
+			bothSides(qlog.KeyUpdated{KeyPhase: 2, Trigger: qlog.KeyUpdateLocal, Reason: qlog.KeyUpdateReasonPacketLimit})...,
 		),
 		eventRecorder.Events(),
 	)
@@ -78,6 +78,42 @@ func (c *MockCryptoSetupChangeConnectionIDCall) DoAndReturn(f func(protocol.Conn
 	return c
 }
 
+// ChangeVersion mocks base method.
+func (m *MockCryptoSetup) ChangeVersion(arg0 protocol.Version) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ChangeVersion", arg0)
+}
+
+// ChangeVersion indicates an expected call of ChangeVersion.
+func (mr *MockCryptoSetupMockRecorder) ChangeVersion(arg0 any) *MockCryptoSetupChangeVersionCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeVersion", reflect.TypeOf((*MockCryptoSetup)(nil).ChangeVersion), arg0)
+	return &MockCryptoSetupChangeVersionCall{Call: call}
+}
+
+// MockCryptoSetupChangeVersionCall wrap *gomock.Call
+type MockCryptoSetupChangeVersionCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockCryptoSetupChangeVersionCall) Return() *MockCryptoSetupChangeVersionCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockCryptoSetupChangeVersionCall) Do(f func(protocol.Version)) *MockCryptoSetupChangeVersionCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockCryptoSetupChangeVersionCall) DoAndReturn(f func(protocol.Version)) *MockCryptoSetupChangeVersionCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // Close mocks base method.
 func (m *MockCryptoSetup) Close() error {
 	m.ctrl.T.Helper()
@@ -425,18 +461,18 @@ func (c *MockCryptoSetupGetHandshakeSealerCall) DoAndReturn(f func() (handshake.
 }
 
 // GetInitialOpener mocks base method.
-func (m *MockCryptoSetup) GetInitialOpener() (handshake.LongHeaderOpener, error) {
+func (m *MockCryptoSetup) GetInitialOpener(arg0 protocol.Version) (handshake.LongHeaderOpener, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetInitialOpener")
+	ret := m.ctrl.Call(m, "GetInitialOpener", arg0)
 	ret0, _ := ret[0].(handshake.LongHeaderOpener)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetInitialOpener indicates an expected call of GetInitialOpener.
-func (mr *MockCryptoSetupMockRecorder) GetInitialOpener() *MockCryptoSetupGetInitialOpenerCall {
+func (mr *MockCryptoSetupMockRecorder) GetInitialOpener(arg0 any) *MockCryptoSetupGetInitialOpenerCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInitialOpener", reflect.TypeOf((*MockCryptoSetup)(nil).GetInitialOpener))
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInitialOpener", reflect.TypeOf((*MockCryptoSetup)(nil).GetInitialOpener), arg0)
 	return &MockCryptoSetupGetInitialOpenerCall{Call: call}
 }
 
@@ -452,13 +488,13 @@ func (c *MockCryptoSetupGetInitialOpenerCall) Return(arg0 handshake.LongHeaderOp
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockCryptoSetupGetInitialOpenerCall) Do(f func() (handshake.LongHeaderOpener, error)) *MockCryptoSetupGetInitialOpenerCall {
+func (c *MockCryptoSetupGetInitialOpenerCall) Do(f func(protocol.Version) (handshake.LongHeaderOpener, error)) *MockCryptoSetupGetInitialOpenerCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockCryptoSetupGetInitialOpenerCall) DoAndReturn(f func() (handshake.LongHeaderOpener, error)) *MockCryptoSetupGetInitialOpenerCall {
+func (c *MockCryptoSetupGetInitialOpenerCall) DoAndReturn(f func(protocol.Version) (handshake.LongHeaderOpener, error)) *MockCryptoSetupGetInitialOpenerCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
@@ -579,6 +615,42 @@ func (c *MockCryptoSetupHandleMessageCall) DoAndReturn(f func([]byte, protocol.E
 	return c
 }
 
+// InitiateKeyUpdate mocks base method.
+func (m *MockCryptoSetup) InitiateKeyUpdate() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InitiateKeyUpdate")
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate.
+func (mr *MockCryptoSetupMockRecorder) InitiateKeyUpdate() *MockCryptoSetupInitiateKeyUpdateCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockCryptoSetup)(nil).InitiateKeyUpdate))
+	return &MockCryptoSetupInitiateKeyUpdateCall{Call: call}
+}
+
+// MockCryptoSetupInitiateKeyUpdateCall wrap *gomock.Call
+type MockCryptoSetupInitiateKeyUpdateCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockCryptoSetupInitiateKeyUpdateCall) Return() *MockCryptoSetupInitiateKeyUpdateCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockCryptoSetupInitiateKeyUpdateCall) Do(f func()) *MockCryptoSetupInitiateKeyUpdateCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockCryptoSetupInitiateKeyUpdateCall) DoAndReturn(f func()) *MockCryptoSetupInitiateKeyUpdateCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // NextEvent mocks base method.
 func (m *MockCryptoSetup) NextEvent() handshake.Event {
 	m.ctrl.T.Helper()
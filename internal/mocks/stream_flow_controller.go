@@ -11,6 +11,7 @@ package mocks
 
 import (
 	reflect "reflect"
+	time "time"
 
 	monotime "github.com/nukilabs/quic-go/internal/monotime"
 	protocol "github.com/nukilabs/quic-go/internal/protocol"
@@ -152,6 +153,44 @@ func (c *MockStreamFlowControllerAddBytesSentCall) DoAndReturn(f func(protocol.B
 	return c
 }
 
+// BlockedTime mocks base method.
+func (m *MockStreamFlowController) BlockedTime() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockedTime")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// BlockedTime indicates an expected call of BlockedTime.
+func (mr *MockStreamFlowControllerMockRecorder) BlockedTime() *MockStreamFlowControllerBlockedTimeCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockedTime", reflect.TypeOf((*MockStreamFlowController)(nil).BlockedTime))
+	return &MockStreamFlowControllerBlockedTimeCall{Call: call}
+}
+
+// MockStreamFlowControllerBlockedTimeCall wrap *gomock.Call
+type MockStreamFlowControllerBlockedTimeCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockStreamFlowControllerBlockedTimeCall) Return(arg0 time.Duration) *MockStreamFlowControllerBlockedTimeCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockStreamFlowControllerBlockedTimeCall) Do(f func() time.Duration) *MockStreamFlowControllerBlockedTimeCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockStreamFlowControllerBlockedTimeCall) DoAndReturn(f func() time.Duration) *MockStreamFlowControllerBlockedTimeCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // GetWindowUpdate mocks base method.
 func (m *MockStreamFlowController) GetWindowUpdate(arg0 monotime.Time) protocol.ByteCount {
 	m.ctrl.T.Helper()
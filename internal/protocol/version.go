@@ -80,6 +80,16 @@ func ChooseSupportedVersion(ours, theirs []Version) (Version, bool) {
 	return 0, false
 }
 
+// IsCompatibleVersion says if a connection that was started using the original version
+// can be upgraded to the negotiated version using compatible version negotiation (RFC 9368).
+// QUIC v1 and QUIC v2 are compatible with each other, see section 6 of RFC 9369.
+func IsCompatibleVersion(original, negotiated Version) bool {
+	if original == negotiated {
+		return true
+	}
+	return (original == Version1 || original == Version2) && (negotiated == Version1 || negotiated == Version2)
+}
+
 var (
 	versionNegotiationMx   sync.Mutex
 	versionNegotiationRand mrand.Rand
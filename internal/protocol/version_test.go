@@ -96,6 +96,15 @@ func TestVersionSelection(t *testing.T) {
 
 func isReservedVersion(v Version) bool { return v&0x0f0f0f0f == 0x0a0a0a0a }
 
+func TestCompatibleVersions(t *testing.T) {
+	require.True(t, IsCompatibleVersion(Version1, Version1))
+	require.True(t, IsCompatibleVersion(Version1, Version2))
+	require.True(t, IsCompatibleVersion(Version2, Version1))
+	require.False(t, IsCompatibleVersion(Version1, versionDraft29))
+	require.False(t, IsCompatibleVersion(versionDraft29, Version2))
+	require.False(t, IsCompatibleVersion(0x1234, Version1))
+}
+
 func TestVersionGreasing(t *testing.T) {
 	// adding to an empty slice
 	greased := GetGreasedVersions([]Version{})
@@ -0,0 +1,222 @@
+package qtls
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	extTypeSNI          = 0
+	extTypeALPN         = 16
+	extTypePreSharedKey = 41
+	extTypeECH          = 0xfe0d
+)
+
+// FindSNIAndECH parses the given byte slice as a ClientHello, and locates:
+// - the position and length of the Server Name Indication (SNI) extension,
+// - the position of the Encrypted Client Hello (ECH) extension.
+// If no SNI extension is found, it returns -1 for the SNI position.
+// If no ECH extension is found, it returns -1 for the ECH position.
+func FindSNIAndECH(data []byte) (sniPos, sniLen, echPos int, err error) {
+	extensionsStart, extensions, err := findExtensions(data)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	extensionsLen := len(extensions)
+
+	// parse extensions
+	var extPos int
+	sniPos = -1
+	echPos = -1
+	for extPos+4 <= extensionsLen {
+		extType := binary.BigEndian.Uint16(extensions[extPos:])
+		extLen := int(binary.BigEndian.Uint16(extensions[extPos+2:]))
+		if extPos+4+extLen > extensionsLen {
+			return 0, 0, 0, io.ErrUnexpectedEOF
+		}
+		switch extType {
+		case extTypeSNI:
+			if sniPos != -1 {
+				return 0, 0, 0, errors.New("multiple SNI extensions")
+			}
+			sniData := extensions[extPos+4 : extPos+4+extLen]
+			if len(sniData) < 2 {
+				return 0, 0, 0, io.ErrUnexpectedEOF
+			}
+			nameListLen := int(binary.BigEndian.Uint16(sniData))
+			if len(sniData) != 2+nameListLen {
+				return 0, 0, 0, io.ErrUnexpectedEOF
+			}
+			listPos := 2
+			for listPos+3 <= nameListLen+2 {
+				nameType := sniData[listPos]
+				sniLen = int(binary.BigEndian.Uint16(sniData[listPos+1:]))
+				if listPos+3+sniLen > len(sniData) {
+					return 0, 0, 0, io.ErrUnexpectedEOF
+				}
+				if nameType == 0 { // host_name
+					sniPos = extensionsStart + extPos + 4 + listPos + 3
+					break // stop after first host_name
+				}
+				listPos += 3 + sniLen
+			}
+			if sniPos == 0 {
+				return 0, 0, 0, errors.New("SNI host_name not found")
+			}
+		case extTypeECH:
+			if echPos != -1 {
+				return 0, 0, 0, errors.New("multiple ECH extensions")
+			}
+			echPos = extensionsStart + extPos
+		}
+		extPos += 4 + extLen
+		if sniPos != -1 && echPos != -1 {
+			break
+		}
+	}
+	return sniPos, sniLen, echPos, nil
+}
+
+// FindALPN parses the given byte slice as a ClientHello,
+// and returns the protocols offered in the Application-Layer Protocol Negotiation (ALPN) extension.
+// If no ALPN extension is found, it returns nil.
+func FindALPN(data []byte) ([]string, error) {
+	alpnData, ok, err := findExtension(data, extTypeALPN)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return parseALPN(alpnData)
+}
+
+// parseALPN parses the data of the ALPN extension, see section 3.1 of RFC 7301.
+func parseALPN(data []byte) ([]string, error) {
+	if len(data) < 2 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	listLen := int(binary.BigEndian.Uint16(data))
+	if len(data) != 2+listLen || listLen == 0 {
+		return nil, errors.New("invalid ALPN extension")
+	}
+	var protos []string
+	for pos := 2; pos < len(data); {
+		protoLen := int(data[pos])
+		pos++
+		if protoLen == 0 || pos+protoLen > len(data) {
+			return nil, errors.New("invalid ALPN extension")
+		}
+		protos = append(protos, string(data[pos:pos+protoLen]))
+		pos += protoLen
+	}
+	return protos, nil
+}
+
+// FindObfuscatedTicketAge parses the given byte slice as a ClientHello,
+// and returns the obfuscated ticket age of the first identity in the pre_shared_key extension,
+// see section 4.2.11 of RFC 8446.
+// If no pre_shared_key extension is found, it returns false.
+func FindObfuscatedTicketAge(data []byte) (uint32, bool, error) {
+	pskData, ok, err := findExtension(data, extTypePreSharedKey)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	// identities length (2 bytes), identity length (2 bytes)
+	if len(pskData) < 4 {
+		return 0, false, io.ErrUnexpectedEOF
+	}
+	identityLen := int(binary.BigEndian.Uint16(pskData[2:]))
+	if len(pskData) < 4+identityLen+4 {
+		return 0, false, io.ErrUnexpectedEOF
+	}
+	return binary.BigEndian.Uint32(pskData[4+identityLen:]), true, nil
+}
+
+// findExtension parses the given byte slice as a ClientHello,
+// and returns the data of the first extension of the given type.
+func findExtension(data []byte, extType uint16) ([]byte, bool, error) {
+	_, extensions, err := findExtensions(data)
+	if err != nil {
+		return nil, false, err
+	}
+	var extPos int
+	for extPos+4 <= len(extensions) {
+		typ := binary.BigEndian.Uint16(extensions[extPos:])
+		extLen := int(binary.BigEndian.Uint16(extensions[extPos+2:]))
+		if extPos+4+extLen > len(extensions) {
+			return nil, false, io.ErrUnexpectedEOF
+		}
+		if typ == extType {
+			return extensions[extPos+4 : extPos+4+extLen], true, nil
+		}
+		extPos += 4 + extLen
+	}
+	return nil, false, nil
+}
+
+// findExtensions parses the given byte slice as a ClientHello,
+// and returns the extensions block, as well as its position in the ClientHello.
+func findExtensions(data []byte) (extensionsStart int, extensions []byte, err error) {
+	if len(data) < 4 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	if data[0] != 1 {
+		return 0, nil, errors.New("not a ClientHello")
+	}
+	handshakeLen := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if len(data) != 4+handshakeLen {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+
+	parsePos := 4
+	// Skip protocol version (2 bytes)
+	if parsePos+2 > len(data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	parsePos += 2
+	// skip random (32 bytes)
+	if parsePos+32 > len(data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	parsePos += 32
+	// session ID
+	if parsePos+1 > len(data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	sessionIDLen := int(data[parsePos])
+	parsePos++
+	if parsePos+sessionIDLen > len(data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	parsePos += sessionIDLen
+	// cipher suites
+	if parsePos+2 > len(data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	cipherSuitesLen := int(binary.BigEndian.Uint16(data[parsePos:]))
+	parsePos += 2
+	if parsePos+cipherSuitesLen > len(data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	parsePos += cipherSuitesLen
+	// compression methods
+	if parsePos+1 > len(data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	compressionMethodsLen := int(data[parsePos])
+	parsePos++
+	if parsePos+compressionMethodsLen > len(data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	parsePos += compressionMethodsLen
+
+	// extensions
+	if parsePos+2 > len(data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	extensionsLen := int(binary.BigEndian.Uint16(data[parsePos:]))
+	parsePos += 2
+	if parsePos+extensionsLen > len(data) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	return parsePos, data[parsePos : parsePos+extensionsLen], nil
+}
@@ -11,4 +11,15 @@ type ConnectionStats struct {
 	PacketsReceived atomic.Uint64
 	BytesLost       atomic.Uint64
 	PacketsLost     atomic.Uint64
+
+	CongestionWindow      atomic.Uint64
+	BytesInFlight         atomic.Uint64
+	PacketsSpuriouslyLost atomic.Uint64
+	BytesRetransmitted    atomic.Uint64
+	PTOCount              atomic.Uint64
+	ECT0                  atomic.Uint64
+	ECT1                  atomic.Uint64
+	ECNCE                 atomic.Uint64
+	MTU                   atomic.Uint64
+	KeyUpdates            atomic.Uint64
 }
@@ -17,6 +17,7 @@ type StreamFrame struct {
 	DataLenPresent bool
 
 	fromPool bool
+	release  func() // called when the frame is put back, for frames referencing data owned by somebody else
 }
 
 func ParseStreamFrame(b []byte, typ FrameType, _ protocol.Version) (*StreamFrame, int, error) {
@@ -177,6 +178,7 @@ func (f *StreamFrame) MaybeSplitOffFrame(maxSize protocol.ByteCount, version pro
 	// swap the data slices
 	new.Data, f.Data = f.Data, new.Data
 	new.fromPool, f.fromPool = f.fromPool, new.fromPool
+	new.release, f.release = f.release, new.release
 
 	f.Data = f.Data[:protocol.ByteCount(len(new.Data))-n]
 	copy(f.Data, new.Data[n:])
@@ -187,5 +189,15 @@ func (f *StreamFrame) MaybeSplitOffFrame(maxSize protocol.ByteCount, version pro
 }
 
 func (f *StreamFrame) PutBack() {
+	if f.release != nil {
+		f.release()
+		f.release = nil
+	}
 	putStreamFrame(f)
 }
+
+// SetReleaseFunc sets a function that is called when the frame is put back.
+// It is used for frames that don't own their data, but reference a buffer owned by somebody else.
+func (f *StreamFrame) SetReleaseFunc(release func()) {
+	f.release = release
+}
@@ -309,6 +309,27 @@ func TestStreamSplittingNoSplitForShortFrame(t *testing.T) {
 	f.PutBack()
 }
 
+func TestStreamSplittingWithReleaseFunc(t *testing.T) {
+	var released int
+	f := &StreamFrame{
+		StreamID:       0x1337,
+		DataLenPresent: true,
+		Data:           bytes.Repeat([]byte{'f'}, 100),
+	}
+	f.SetReleaseFunc(func() { released++ })
+	frame, needsSplit := f.MaybeSplitOffFrame(50, protocol.Version1)
+	require.True(t, needsSplit)
+	// the release function moves with the data slice
+	require.Nil(t, f.release)
+	require.NotNil(t, frame.release)
+	f.PutBack()
+	require.Zero(t, released)
+	frame.PutBack()
+	require.Equal(t, 1, released)
+	frame.PutBack()
+	require.Equal(t, 1, released)
+}
+
 func TestStreamSplittingPreservesFINBit(t *testing.T) {
 	f := &StreamFrame{
 		StreamID: 0x1337,
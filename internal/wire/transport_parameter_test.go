@@ -419,6 +419,47 @@ func TestTransportParameterErrors(t *testing.T) {
 			perspective:    protocol.PerspectiveClient,
 			expectedErrMsg: "min_ack_delay (2562047h47m16.854775807s) is greater than max_ack_delay (42ms)",
 		},
+		{
+			name: "version_information with invalid length",
+			data: func() []byte {
+				b := quicvarint.Append(nil, uint64(versionInformationParameterID))
+				b = quicvarint.Append(b, 6)
+				return appendInitialSourceConnectionID(append(b, []byte{0, 0, 0, 1, 0, 0}...))
+			}(),
+			perspective:    protocol.PerspectiveClient,
+			expectedErrMsg: "invalid length for version_information: 6",
+		},
+		{
+			name: "version_information without chosen version",
+			data: func() []byte {
+				b := quicvarint.Append(nil, uint64(versionInformationParameterID))
+				b = quicvarint.Append(b, 0)
+				return appendInitialSourceConnectionID(b)
+			}(),
+			perspective:    protocol.PerspectiveClient,
+			expectedErrMsg: "invalid length for version_information: 0",
+		},
+		{
+			name: "version_information with chosen version 0",
+			params: &TransportParameters{
+				ActiveConnectionIDLimit: 2,
+				VersionInformation:      &VersionInformation{AvailableVersions: []protocol.Version{protocol.Version1}},
+			},
+			perspective:    protocol.PerspectiveClient,
+			expectedErrMsg: "version_information contains chosen version 0",
+		},
+		{
+			name: "version_information with available version 0",
+			params: &TransportParameters{
+				ActiveConnectionIDLimit: 2,
+				VersionInformation: &VersionInformation{
+					ChosenVersion:     protocol.Version1,
+					AvailableVersions: []protocol.Version{protocol.Version1, 0},
+				},
+			},
+			perspective:    protocol.PerspectiveClient,
+			expectedErrMsg: "version_information contains available version 0",
+		},
 	}
 
 	for _, tt := range tests {
@@ -439,6 +480,43 @@ func TestTransportParameterErrors(t *testing.T) {
 	}
 }
 
+func TestTransportParameterVersionInformation(t *testing.T) {
+	for _, pers := range []protocol.Perspective{protocol.PerspectiveClient, protocol.PerspectiveServer} {
+		t.Run(pers.String(), func(t *testing.T) {
+			params := &TransportParameters{
+				OriginalDestinationConnectionID: protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+				InitialSourceConnectionID:       protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad}),
+				ActiveConnectionIDLimit:         2,
+				VersionInformation: &VersionInformation{
+					ChosenVersion:     protocol.Version1,
+					AvailableVersions: []protocol.Version{protocol.Version2, protocol.Version1},
+				},
+			}
+			p := &TransportParameters{}
+			require.NoError(t, p.Unmarshal(params.Marshal(pers), pers))
+			require.Equal(t, params.VersionInformation, p.VersionInformation)
+		})
+	}
+
+	t.Run("no available versions", func(t *testing.T) {
+		params := &TransportParameters{
+			ActiveConnectionIDLimit: 2,
+			VersionInformation:      &VersionInformation{ChosenVersion: protocol.Version2},
+		}
+		p := &TransportParameters{}
+		require.NoError(t, p.Unmarshal(params.Marshal(protocol.PerspectiveClient), protocol.PerspectiveClient))
+		require.Equal(t, protocol.Version2, p.VersionInformation.ChosenVersion)
+		require.Empty(t, p.VersionInformation.AvailableVersions)
+	})
+
+	t.Run("not sent", func(t *testing.T) {
+		p := &TransportParameters{}
+		params := &TransportParameters{ActiveConnectionIDLimit: 2}
+		require.NoError(t, p.Unmarshal(params.Marshal(protocol.PerspectiveClient), protocol.PerspectiveClient))
+		require.Nil(t, p.VersionInformation)
+	})
+}
+
 func TestTransportParameterUnknownParameters(t *testing.T) {
 	// write a known parameter
 	b := quicvarint.Append(nil, uint64(initialMaxStreamDataBidiLocalParameterID))
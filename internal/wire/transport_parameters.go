@@ -44,6 +44,8 @@ const (
 	activeConnectionIDLimitParameterID         transportParameterID = 0xe
 	initialSourceConnectionIDParameterID       transportParameterID = 0xf
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
+	// RFC 9368
+	versionInformationParameterID transportParameterID = 0x11
 	// RFC 9221
 	maxDatagramFrameSizeParameterID transportParameterID = 0x20
 	// https://datatracker.ietf.org/doc/draft-ietf-quic-reliable-stream-reset/06/
@@ -59,6 +61,13 @@ type PreferredAddress struct {
 	StatelessResetToken protocol.StatelessResetToken
 }
 
+// VersionInformation is the value encoded in the version_information transport parameter,
+// see section 3 of RFC 9368.
+type VersionInformation struct {
+	ChosenVersion     protocol.Version
+	AvailableVersions []protocol.Version
+}
+
 // TransportParameters are parameters sent to the peer during the handshake
 type TransportParameters struct {
 	InitialMaxStreamDataBidiLocal  protocol.ByteCount
@@ -90,6 +99,8 @@ type TransportParameters struct {
 	MaxDatagramFrameSize protocol.ByteCount // RFC 9221
 	EnableResetStreamAt  bool               // https://datatracker.ietf.org/doc/draft-ietf-quic-reliable-stream-reset/06/
 	MinAckDelay          *time.Duration
+
+	VersionInformation *VersionInformation // RFC 9368
 }
 
 // Unmarshal the transport parameters
@@ -210,6 +221,11 @@ func (p *TransportParameters) unmarshal(b []byte, sentBy protocol.Perspective, f
 				return fmt.Errorf("wrong length for reset_stream_at: %d (expected empty)", paramLen)
 			}
 			p.EnableResetStreamAt = true
+		case versionInformationParameterID:
+			if err := p.readVersionInformation(b, int(paramLen)); err != nil {
+				return err
+			}
+			b = b[paramLen:]
 		default:
 			b = b[paramLen:]
 		}
@@ -286,6 +302,28 @@ func (p *TransportParameters) readPreferredAddress(b []byte, expectedLen int) er
 	return nil
 }
 
+func (p *TransportParameters) readVersionInformation(b []byte, l int) error {
+	// The Chosen Version is mandatory, and each version is encoded as a 32 bit integer.
+	if l < 4 || l%4 != 0 {
+		return fmt.Errorf("invalid length for version_information: %d", l)
+	}
+	vi := &VersionInformation{ChosenVersion: protocol.Version(binary.BigEndian.Uint32(b))}
+	// A Chosen Version of 0 is reserved, and must be treated as a protocol error.
+	if vi.ChosenVersion == 0 {
+		return errors.New("version_information contains chosen version 0")
+	}
+	vi.AvailableVersions = make([]protocol.Version, 0, l/4-1)
+	for i := 4; i < l; i += 4 {
+		v := protocol.Version(binary.BigEndian.Uint32(b[i:]))
+		if v == 0 {
+			return errors.New("version_information contains available version 0")
+		}
+		vi.AvailableVersions = append(vi.AvailableVersions, v)
+	}
+	p.VersionInformation = vi
+	return nil
+}
+
 func (p *TransportParameters) readNumericTransportParameter(b []byte, paramID transportParameterID, expectedLen int) error {
 	val, l, err := quicvarint.Parse(b)
 	if err != nil {
@@ -458,6 +496,15 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	if p.MinAckDelay != nil {
 		b = p.marshalVarintParam(b, minAckDelayParameterID, uint64(*p.MinAckDelay/time.Microsecond))
 	}
+	// version_information
+	if p.VersionInformation != nil {
+		b = quicvarint.Append(b, uint64(versionInformationParameterID))
+		b = quicvarint.Append(b, uint64(4*(1+len(p.VersionInformation.AvailableVersions))))
+		b = binary.BigEndian.AppendUint32(b, uint32(p.VersionInformation.ChosenVersion))
+		for _, v := range p.VersionInformation.AvailableVersions {
+			b = binary.BigEndian.AppendUint32(b, uint32(v))
+		}
+	}
 
 	if pers == protocol.PerspectiveClient && len(AdditionalTransportParametersClient) > 0 {
 		for k, v := range AdditionalTransportParametersClient {
@@ -578,6 +625,10 @@ func (p *TransportParameters) String() string {
 		logString += ", MinAckDelay: %s"
 		logParams = append(logParams, *p.MinAckDelay)
 	}
+	if p.VersionInformation != nil {
+		logString += ", VersionInformation: {ChosenVersion: %s, AvailableVersions: %s}"
+		logParams = append(logParams, p.VersionInformation.ChosenVersion, p.VersionInformation.AvailableVersions)
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
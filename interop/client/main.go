@@ -16,7 +16,6 @@ import (
 
 	"github.com/nukilabs/quic-go"
 	"github.com/nukilabs/quic-go/http3"
-	"github.com/nukilabs/quic-go/internal/handshake"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/qtls"
 	"github.com/nukilabs/quic-go/interop/http09"
@@ -84,7 +83,7 @@ func runTestcase(testcase string) error {
 	switch testcase {
 	case "handshake", "transfer", "retry":
 	case "keyupdate":
-		handshake.FirstKeyUpdateInterval = 100
+		quicConf.KeyUpdateMaxPackets = 100
 	case "chacha20":
 		reset := qtls.SetCipherSuite(tls.TLS_CHACHA20_POLY1305_SHA256)
 		defer reset()
@@ -0,0 +1,96 @@
+package quic
+
+import (
+	"sync/atomic"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+)
+
+// minBudgetReceiveWindow is the smallest receive window that a connection advertises,
+// even if the MemoryBudget is exhausted.
+// This makes sure that connections can still make (slow) progress.
+const minBudgetReceiveWindow protocol.ByteCount = 16 << 10
+
+// A MemoryBudget limits the amount of memory used for buffering received stream data.
+// It can be shared by many connections (and multiple Transports), by setting it on the [Config].
+//
+// Every connection reserves its connection-level flow control window from the budget.
+// Since the peer is not allowed to send more data than permitted by this window,
+// the sum of all reservations is an upper bound for the amount of data buffered by the connections.
+// Once the budget is exhausted, flow control auto-tuning stops increasing the receive windows,
+// new connections advertise smaller initial receive windows for the connection and its streams,
+// and streams opened on existing connections grant less flow control credit.
+// Reservations are returned to the budget when the connection is closed.
+type MemoryBudget struct {
+	limit    protocol.ByteCount
+	reserved atomic.Int64
+}
+
+// NewMemoryBudget creates a new memory budget of limit bytes.
+func NewMemoryBudget(limit uint64) *MemoryBudget {
+	return &MemoryBudget{limit: protocol.ByteCount(min(limit, uint64(protocol.MaxByteCount)))}
+}
+
+// Limit returns the size of the budget, in bytes.
+func (b *MemoryBudget) Limit() uint64 {
+	return uint64(b.limit)
+}
+
+// Used returns the number of bytes currently reserved by connections.
+// Due to the minimum receive window, this value can exceed the limit.
+func (b *MemoryBudget) Used() uint64 {
+	return uint64(b.reserved.Load())
+}
+
+// Available returns the number of bytes that are not yet reserved.
+func (b *MemoryBudget) Available() uint64 {
+	return uint64(b.available())
+}
+
+func (b *MemoryBudget) available() protocol.ByteCount {
+	return max(0, b.limit-protocol.ByteCount(b.reserved.Load()))
+}
+
+// receiveWindows returns the initial receive windows that a new connection advertises.
+// If the budget is under pressure, the windows are reduced.
+// It doesn't reserve any memory.
+func (b *MemoryBudget) receiveWindows(connWindow, streamWindow protocol.ByteCount) (protocol.ByteCount, protocol.ByteCount) {
+	available := b.available()
+	if connWindow <= available {
+		return connWindow, streamWindow
+	}
+	connWindow = max(available, min(connWindow, minBudgetReceiveWindow))
+	return connWindow, min(streamWindow, connWindow)
+}
+
+// streamReceiveWindow returns the receive window size used for a new stream.
+// If the budget is under pressure, the window is reduced.
+// It doesn't reserve any memory.
+func (b *MemoryBudget) streamReceiveWindow(window protocol.ByteCount) protocol.ByteCount {
+	if available := b.available(); window > available {
+		return max(available, min(window, minBudgetReceiveWindow))
+	}
+	return window
+}
+
+// tryReserve reserves n bytes, if they're available.
+func (b *MemoryBudget) tryReserve(n protocol.ByteCount) bool {
+	for {
+		reserved := b.reserved.Load()
+		if protocol.ByteCount(reserved)+n > b.limit {
+			return false
+		}
+		if b.reserved.CompareAndSwap(reserved, reserved+int64(n)) {
+			return true
+		}
+	}
+}
+
+// reserve reserves n bytes, even if this exceeds the limit.
+func (b *MemoryBudget) reserve(n protocol.ByteCount) {
+	b.reserved.Add(int64(n))
+}
+
+func (b *MemoryBudget) release(n protocol.ByteCount) {
+	b.reserved.Add(-int64(n))
+}
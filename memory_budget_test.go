@@ -0,0 +1,65 @@
+package quic
+
+import (
+	"testing"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBudgetReservations(t *testing.T) {
+	b := NewMemoryBudget(1000)
+	require.Equal(t, uint64(1000), b.Limit())
+	require.Equal(t, uint64(1000), b.Available())
+
+	require.True(t, b.tryReserve(600))
+	require.False(t, b.tryReserve(401))
+	require.True(t, b.tryReserve(400))
+	require.Equal(t, uint64(1000), b.Used())
+	require.Zero(t, b.Available())
+
+	// reservations can exceed the limit
+	b.reserve(100)
+	require.Equal(t, uint64(1100), b.Used())
+	require.Zero(t, b.Available())
+	b.release(700)
+	require.Equal(t, uint64(400), b.Used())
+	require.Equal(t, uint64(600), b.Available())
+}
+
+func TestMemoryBudgetReceiveWindows(t *testing.T) {
+	b := NewMemoryBudget(1 << 20)
+
+	// enough memory available
+	connWindow, streamWindow := b.receiveWindows(512<<10, 256<<10)
+	require.Equal(t, protocol.ByteCount(512<<10), connWindow)
+	require.Equal(t, protocol.ByteCount(256<<10), streamWindow)
+
+	// the windows are reduced to the available memory
+	b.reserve(1<<20 - 100<<10)
+	connWindow, streamWindow = b.receiveWindows(512<<10, 256<<10)
+	require.Equal(t, protocol.ByteCount(100<<10), connWindow)
+	require.Equal(t, protocol.ByteCount(100<<10), streamWindow)
+
+	// windows are not reduced below the minimum
+	b.reserve(100 << 10)
+	connWindow, streamWindow = b.receiveWindows(512<<10, 256<<10)
+	require.Equal(t, minBudgetReceiveWindow, connWindow)
+	require.Equal(t, minBudgetReceiveWindow, streamWindow)
+	connWindow, streamWindow = b.receiveWindows(8<<10, 4<<10)
+	require.Equal(t, protocol.ByteCount(8<<10), connWindow)
+	require.Equal(t, protocol.ByteCount(4<<10), streamWindow)
+}
+
+func TestMemoryBudgetStreamReceiveWindow(t *testing.T) {
+	b := NewMemoryBudget(1 << 20)
+	require.Equal(t, protocol.ByteCount(256<<10), b.streamReceiveWindow(256<<10))
+
+	b.reserve(1<<20 - 100<<10)
+	require.Equal(t, protocol.ByteCount(100<<10), b.streamReceiveWindow(256<<10))
+
+	b.reserve(100 << 10)
+	require.Equal(t, minBudgetReceiveWindow, b.streamReceiveWindow(256<<10))
+	require.Equal(t, protocol.ByteCount(4<<10), b.streamReceiveWindow(4<<10))
+}
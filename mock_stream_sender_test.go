@@ -183,3 +183,39 @@ func (c *MockStreamSenderonStreamCompletedCall) DoAndReturn(f func(protocol.Stre
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
+
+// onStreamDataRetransmitted mocks base method.
+func (m *MockStreamSender) onStreamDataRetransmitted(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onStreamDataRetransmitted", arg0)
+}
+
+// onStreamDataRetransmitted indicates an expected call of onStreamDataRetransmitted.
+func (mr *MockStreamSenderMockRecorder) onStreamDataRetransmitted(arg0 any) *MockStreamSenderonStreamDataRetransmittedCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onStreamDataRetransmitted", reflect.TypeOf((*MockStreamSender)(nil).onStreamDataRetransmitted), arg0)
+	return &MockStreamSenderonStreamDataRetransmittedCall{Call: call}
+}
+
+// MockStreamSenderonStreamDataRetransmittedCall wrap *gomock.Call
+type MockStreamSenderonStreamDataRetransmittedCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockStreamSenderonStreamDataRetransmittedCall) Return() *MockStreamSenderonStreamDataRetransmittedCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockStreamSenderonStreamDataRetransmittedCall) Do(f func(protocol.ByteCount)) *MockStreamSenderonStreamDataRetransmittedCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockStreamSenderonStreamDataRetransmittedCall) DoAndReturn(f func(protocol.ByteCount)) *MockStreamSenderonStreamDataRetransmittedCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
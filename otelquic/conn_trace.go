@@ -0,0 +1,257 @@
+package otelquic
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sync"
+
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/qlog"
+	"github.com/nukilabs/quic-go/qlogwriter"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Names of the span events recorded on the connection span.
+const (
+	EventHandshakeKeys      = "handshake_keys"
+	Event1RTTKeys           = "1rtt_keys"
+	EventHandshakeDone      = "handshake_done"
+	EventHandshakeConfirmed = "handshake_confirmed"
+	Event0RTTKeys           = "0rtt_keys"
+	Event0RTTKeysDiscarded  = "0rtt_keys_discarded"
+	EventKeyUpdate          = "key_update"
+	EventPathChallengeSent  = "path_challenge_sent"
+	EventPathChallengeRecvd = "path_challenge_received"
+	EventPathResponseSent   = "path_response_sent"
+	EventPathResponseRecvd  = "path_response_received"
+	EventVersionNegotiation = "version_negotiation"
+	EventConnectionClosed   = "connection_closed"
+)
+
+const (
+	connectionSpanName = "quic.connection"
+
+	attrConnectionID         = "quic.connection_id"
+	attrVersion              = "quic.version"
+	attrALPN                 = "tls.next_protocol"
+	attrKeyPhase             = "quic.key_phase"
+	attrKeyUpdateTrigger     = "quic.key_update.trigger"
+	attrCloseInitiator       = "quic.close.initiator"
+	attrCloseTrigger         = "quic.close.trigger"
+	attrCloseTransportError  = "quic.close.transport_error"
+	attrCloseApplicationCode = "quic.close.application_error"
+	attrCloseReason          = "quic.close.reason"
+)
+
+// connTrace is the qlogwriter.Trace of a QUIC connection.
+// Instead of writing the events, it turns the relevant events into span events and attributes.
+type connTrace struct {
+	span     trace.Span
+	isClient bool
+
+	mx        sync.Mutex
+	producers int
+	closed    bool
+	// events that are only recorded the first time they occur
+	recorded map[string]struct{}
+}
+
+var _ qlogwriter.Trace = &connTrace{}
+
+func newConnTrace(ctx context.Context, tracer trace.Tracer, isClient bool, connID quic.ConnectionID) *connTrace {
+	kind := trace.SpanKindServer
+	if isClient {
+		kind = trace.SpanKindClient
+	}
+	_, span := tracer.Start(ctx, connectionSpanName,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(
+			attribute.String("network.transport", "udp"),
+			attribute.String("network.protocol.name", "quic"),
+			attribute.String(attrConnectionID, connID.String()),
+		),
+	)
+	return &connTrace{
+		span:     span,
+		isClient: isClient,
+		recorded: make(map[string]struct{}),
+	}
+}
+
+func (t *connTrace) AddProducer() qlogwriter.Recorder {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	if t.closed {
+		return nil
+	}
+	t.producers++
+	return &connRecorder{t: t}
+}
+
+// SupportsSchemas only returns true for the QUIC event schema.
+// Other event schemas (e.g. HTTP/3) are not used, so there's no need to generate their events.
+func (t *connTrace) SupportsSchemas(schema string) bool {
+	return schema == qlog.EventSchema
+}
+
+func (t *connTrace) removeProducer() {
+	t.mx.Lock()
+	t.producers--
+	last := t.producers == 0
+	if last {
+		t.closed = true
+	}
+	t.mx.Unlock()
+
+	if last {
+		t.span.End()
+	}
+}
+
+// addEventOnce adds a span event, unless an event with the same name was already added.
+func (t *connTrace) addEventOnce(name string, attrs ...attribute.KeyValue) {
+	t.mx.Lock()
+	_, ok := t.recorded[name]
+	t.recorded[name] = struct{}{}
+	t.mx.Unlock()
+
+	if !ok {
+		t.span.AddEvent(name, trace.WithAttributes(attrs...))
+	}
+}
+
+func (t *connTrace) recordEvent(ev qlogwriter.Event) {
+	switch e := ev.(type) {
+	case qlog.StartedConnection:
+		t.span.SetAttributes(endpointAttributes("network.local", e.Local)...)
+		t.span.SetAttributes(endpointAttributes("network.peer", e.Remote)...)
+	case qlog.VersionInformation:
+		t.span.SetAttributes(attribute.String(attrVersion, fmt.Sprintf("%#x", uint32(e.ChosenVersion))))
+		if t.isClient && len(e.ServerVersions) > 0 {
+			t.addEventOnce(EventVersionNegotiation)
+		}
+	case qlog.ALPNInformation:
+		t.span.SetAttributes(attribute.String(attrALPN, e.ChosenALPN))
+	case qlog.KeyUpdated:
+		switch e.KeyType {
+		case qlog.KeyTypeClientHandshake, qlog.KeyTypeServerHandshake:
+			t.addEventOnce(EventHandshakeKeys)
+		case qlog.KeyTypeClient0RTT, qlog.KeyTypeServer0RTT:
+			t.addEventOnce(Event0RTTKeys)
+		case qlog.KeyTypeClient1RTT, qlog.KeyTypeServer1RTT:
+			if e.Trigger == qlog.KeyUpdateTLS {
+				t.addEventOnce(Event1RTTKeys)
+				return
+			}
+			// The client and the server key are updated at the same time. Only record one event.
+			if (e.KeyType == qlog.KeyTypeClient1RTT) == t.isClient {
+				t.span.AddEvent(EventKeyUpdate, trace.WithAttributes(
+					attribute.Int64(attrKeyPhase, int64(e.KeyPhase)),
+					attribute.String(attrKeyUpdateTrigger, string(e.Trigger)),
+				))
+			}
+		}
+	case qlog.KeyDiscarded:
+		switch e.KeyType {
+		case qlog.KeyTypeClientHandshake, qlog.KeyTypeServerHandshake:
+			t.addEventOnce(EventHandshakeConfirmed)
+		case qlog.KeyTypeClient0RTT, qlog.KeyTypeServer0RTT:
+			t.addEventOnce(Event0RTTKeysDiscarded)
+		}
+	case qlog.PacketSent:
+		for _, f := range e.Frames {
+			switch frame := f.Frame.(type) {
+			case *qlog.HandshakeDoneFrame:
+				t.addEventOnce(EventHandshakeDone)
+			case *qlog.PathChallengeFrame:
+				t.span.AddEvent(EventPathChallengeSent, trace.WithAttributes(pathDataAttribute(frame.Data)))
+			case *qlog.PathResponseFrame:
+				t.span.AddEvent(EventPathResponseSent, trace.WithAttributes(pathDataAttribute(frame.Data)))
+			}
+		}
+	case qlog.PacketReceived:
+		for _, f := range e.Frames {
+			switch frame := f.Frame.(type) {
+			case *qlog.HandshakeDoneFrame:
+				t.addEventOnce(EventHandshakeDone)
+			case *qlog.PathChallengeFrame:
+				t.span.AddEvent(EventPathChallengeRecvd, trace.WithAttributes(pathDataAttribute(frame.Data)))
+			case *qlog.PathResponseFrame:
+				t.span.AddEvent(EventPathResponseRecvd, trace.WithAttributes(pathDataAttribute(frame.Data)))
+			}
+		}
+	case qlog.ConnectionClosed:
+		t.recordClose(e)
+	}
+}
+
+func (t *connTrace) recordClose(e qlog.ConnectionClosed) {
+	attrs := []attribute.KeyValue{attribute.String(attrCloseInitiator, string(e.Initiator))}
+	if e.Trigger != "" {
+		attrs = append(attrs, attribute.String(attrCloseTrigger, string(e.Trigger)))
+	}
+	if e.Reason != "" {
+		attrs = append(attrs, attribute.String(attrCloseReason, e.Reason))
+	}
+	// The meaning of application error codes is defined by the application protocol
+	// (e.g. H3_NO_ERROR is 0x100), so only transport errors set the span status.
+	var isError bool
+	desc := string(e.Trigger)
+	switch {
+	case e.ConnectionError != nil:
+		attrs = append(attrs, attribute.String(attrCloseTransportError, e.ConnectionError.String()))
+		isError = *e.ConnectionError != quic.NoError
+		desc = e.ConnectionError.String()
+	case e.ApplicationError != nil:
+		attrs = append(attrs, attribute.Int64(attrCloseApplicationCode, int64(*e.ApplicationError)))
+	case e.Trigger == qlog.ConnectionCloseTriggerStatelessReset, e.Trigger == qlog.ConnectionCloseTriggerVersionMismatch:
+		isError = true
+	}
+	t.span.AddEvent(EventConnectionClosed, trace.WithAttributes(attrs...))
+	if isError {
+		if e.Reason != "" {
+			desc += ": " + e.Reason
+		}
+		t.span.SetStatus(codes.Error, desc)
+	}
+}
+
+func endpointAttributes(prefix string, info qlog.PathEndpointInfo) []attribute.KeyValue {
+	addr := info.IPv4
+	if !addr.IsValid() {
+		addr = info.IPv6
+	}
+	if !addr.IsValid() {
+		return nil
+	}
+	return addrPortAttributes(prefix, addr)
+}
+
+func addrPortAttributes(prefix string, addr netip.AddrPort) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String(prefix+".address", addr.Addr().String()),
+		attribute.Int(prefix+".port", int(addr.Port())),
+	}
+}
+
+func pathDataAttribute(data [8]byte) attribute.KeyValue {
+	return attribute.String("quic.path.data", fmt.Sprintf("%x", data))
+}
+
+type connRecorder struct {
+	t *connTrace
+}
+
+var _ qlogwriter.Recorder = &connRecorder{}
+
+func (r *connRecorder) RecordEvent(ev qlogwriter.Event) { r.t.recordEvent(ev) }
+
+func (r *connRecorder) Close() error {
+	r.t.removeProducer()
+	return nil
+}
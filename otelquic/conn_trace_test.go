@@ -0,0 +1,181 @@
+package otelquic
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/qlog"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracer(t *testing.T) (*Tracer, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	return NewTracer(&Config{TracerProvider: tp}), exporter
+}
+
+func eventNames(s tracetest.SpanStub) []string {
+	var names []string
+	for _, e := range s.Events {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func attributeMap(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(attrs))
+	for _, a := range attrs {
+		m[a.Key] = a.Value
+	}
+	return m
+}
+
+func TestConnectionSpan(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+	connID := quic.ConnectionIDFromBytes([]byte{1, 2, 3, 4})
+	ctx, parent := tracer.tracer.Start(context.Background(), "parent")
+	tr := tracer.ConnectionTracer(ctx, true, connID)
+	require.True(t, tr.SupportsSchemas(qlog.EventSchema))
+	require.False(t, tr.SupportsSchemas("urn:ietf:params:qlog:events:http3-12"))
+
+	r1 := tr.AddProducer()
+	r2 := tr.AddProducer()
+	r1.RecordEvent(qlog.StartedConnection{
+		Local:  qlog.PathEndpointInfo{IPv4: netip.MustParseAddrPort("127.0.0.1:1234")},
+		Remote: qlog.PathEndpointInfo{IPv6: netip.MustParseAddrPort("[::1]:443")},
+	})
+	r1.RecordEvent(qlog.VersionInformation{ChosenVersion: quic.Version1})
+	r1.RecordEvent(qlog.ALPNInformation{ChosenALPN: "h3"})
+	r1.RecordEvent(qlog.KeyUpdated{Trigger: qlog.KeyUpdateTLS, KeyType: qlog.KeyTypeClientHandshake})
+	r1.RecordEvent(qlog.KeyUpdated{Trigger: qlog.KeyUpdateTLS, KeyType: qlog.KeyTypeServerHandshake})
+	r1.RecordEvent(qlog.KeyUpdated{Trigger: qlog.KeyUpdateTLS, KeyType: qlog.KeyTypeClient1RTT})
+	r1.RecordEvent(qlog.KeyUpdated{Trigger: qlog.KeyUpdateTLS, KeyType: qlog.KeyTypeServer1RTT})
+	r1.RecordEvent(qlog.PacketReceived{Frames: []qlog.Frame{{Frame: &qlog.HandshakeDoneFrame{}}}})
+	r1.RecordEvent(qlog.KeyDiscarded{KeyType: qlog.KeyTypeClientHandshake})
+	r1.RecordEvent(qlog.KeyDiscarded{KeyType: qlog.KeyTypeServerHandshake})
+	r1.RecordEvent(qlog.PacketSent{Frames: []qlog.Frame{{Frame: &qlog.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}}})
+	r1.RecordEvent(qlog.PacketReceived{Frames: []qlog.Frame{{Frame: &qlog.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}}})
+	r1.RecordEvent(qlog.KeyUpdated{Trigger: qlog.KeyUpdateRemote, KeyType: qlog.KeyTypeClient1RTT, KeyPhase: 1})
+	r1.RecordEvent(qlog.KeyUpdated{Trigger: qlog.KeyUpdateRemote, KeyType: qlog.KeyTypeServer1RTT, KeyPhase: 1})
+	appErr := qlog.ApplicationErrorCode(0x100)
+	r1.RecordEvent(qlog.ConnectionClosed{Initiator: qlog.InitiatorLocal, ApplicationError: &appErr, Reason: "bye"})
+
+	require.NoError(t, r1.Close())
+	require.Empty(t, exporter.GetSpans())
+	require.NoError(t, r2.Close())
+	// the trace was closed, no new producers can be added
+	require.Nil(t, tr.AddProducer())
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, "quic.connection", span.Name)
+	require.Equal(t, trace.SpanKindClient, span.SpanKind)
+	require.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+	require.Equal(t, codes.Unset, span.Status.Code)
+
+	attrs := attributeMap(span.Attributes)
+	require.Equal(t, connID.String(), attrs[attrConnectionID].AsString())
+	require.Equal(t, "127.0.0.1", attrs["network.local.address"].AsString())
+	require.Equal(t, int64(1234), attrs["network.local.port"].AsInt64())
+	require.Equal(t, "::1", attrs["network.peer.address"].AsString())
+	require.Equal(t, int64(443), attrs["network.peer.port"].AsInt64())
+	require.Equal(t, "0x1", attrs[attrVersion].AsString())
+	require.Equal(t, "h3", attrs[attrALPN].AsString())
+
+	require.Equal(t,
+		[]string{
+			EventHandshakeKeys,
+			Event1RTTKeys,
+			EventHandshakeDone,
+			EventHandshakeConfirmed,
+			EventPathChallengeSent,
+			EventPathResponseRecvd,
+			EventKeyUpdate,
+			EventConnectionClosed,
+		},
+		eventNames(span),
+	)
+	closeAttrs := attributeMap(span.Events[len(span.Events)-1].Attributes)
+	require.Equal(t, "local", closeAttrs[attrCloseInitiator].AsString())
+	require.Equal(t, int64(0x100), closeAttrs[attrCloseApplicationCode].AsInt64())
+	require.Equal(t, "bye", closeAttrs[attrCloseReason].AsString())
+}
+
+func TestConnectionSpan0RTT(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+	tr := tracer.ConnectionTracer(context.Background(), false, quic.ConnectionIDFromBytes([]byte{1, 2, 3, 4}))
+	r := tr.AddProducer()
+	r.RecordEvent(qlog.KeyUpdated{Trigger: qlog.KeyUpdateTLS, KeyType: qlog.KeyTypeClient0RTT})
+	r.RecordEvent(qlog.KeyDiscarded{KeyType: qlog.KeyTypeClient0RTT})
+	require.NoError(t, r.Close())
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, trace.SpanKindServer, spans[0].SpanKind)
+	require.False(t, spans[0].Parent.IsValid())
+	require.Equal(t, []string{Event0RTTKeys, Event0RTTKeysDiscarded}, eventNames(spans[0]))
+}
+
+func TestConnectionSpanErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		event  qlog.ConnectionClosed
+		status codes.Code
+		desc   string
+	}{
+		{
+			name: "no error",
+			event: qlog.ConnectionClosed{
+				Initiator:       qlog.InitiatorRemote,
+				ConnectionError: func() *qlog.TransportErrorCode { c := quic.NoError; return &c }(),
+			},
+			status: codes.Unset,
+		},
+		{
+			name: "transport error",
+			event: qlog.ConnectionClosed{
+				Initiator:       qlog.InitiatorLocal,
+				ConnectionError: func() *qlog.TransportErrorCode { c := quic.ProtocolViolation; return &c }(),
+				Reason:          "foobar",
+			},
+			status: codes.Error,
+			desc:   "PROTOCOL_VIOLATION: foobar",
+		},
+		{
+			name:   "idle timeout",
+			event:  qlog.ConnectionClosed{Initiator: qlog.InitiatorLocal, Trigger: qlog.ConnectionCloseTriggerIdleTimeout},
+			status: codes.Unset,
+		},
+		{
+			name:   "stateless reset",
+			event:  qlog.ConnectionClosed{Initiator: qlog.InitiatorRemote, Trigger: qlog.ConnectionCloseTriggerStatelessReset},
+			status: codes.Error,
+			desc:   "stateless_reset",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer, exporter := newTestTracer(t)
+			tr := tracer.ConnectionTracer(context.Background(), true, quic.ConnectionIDFromBytes([]byte{1, 2, 3, 4}))
+			r := tr.AddProducer()
+			r.RecordEvent(tc.event)
+			require.NoError(t, r.Close())
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			require.Equal(t, tc.status, spans[0].Status.Code)
+			require.Equal(t, tc.desc, spans[0].Status.Description)
+			require.Equal(t, []string{EventConnectionClosed}, eventNames(spans[0]))
+		})
+	}
+}
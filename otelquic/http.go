@@ -0,0 +1,206 @@
+package otelquic
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nukilabs/http"
+	"github.com/nukilabs/http/httptrace"
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/http3"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler wraps an http.Handler, such that every request is traced by a span.
+//
+// If the request carries a trace context, the span is a child of the remote span, and it is linked to
+// the span of the QUIC connection. Otherwise, it is a child of the span in the request context,
+// which is the span of the QUIC connection if http3.Server.ConnContext is set to ConnContext.
+func (t *Tracer) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		opts := []trace.SpanStartOption{
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(requestAttributes(r)...),
+		}
+		remoteCtx := t.propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+		if remote := trace.SpanContextFromContext(remoteCtx); remote.IsValid() && remote.IsRemote() {
+			if connSpan := trace.SpanContextFromContext(ctx); connSpan.IsValid() {
+				opts = append(opts, trace.WithLinks(trace.Link{SpanContext: connSpan}))
+			}
+			ctx = remoteCtx
+		}
+		ctx, span := t.tracer.Start(ctx, r.Method, opts...)
+		defer span.End()
+
+		rw := &responseWriter{ResponseWriter: w}
+		h.ServeHTTP(rw, r.WithContext(ctx))
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// responseWriter records the status code of the response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+var (
+	_ http.Flusher        = &responseWriter{}
+	_ http3.HTTPStreamer  = &responseWriter{}
+	_ http.ResponseWriter = &responseWriter{}
+)
+
+func (w *responseWriter) WriteHeader(status int) {
+	// 1xx responses are followed by the actual response
+	if w.status == 0 && (status < 100 || status >= 200) {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// HTTPStream returns the stream of the underlying http3 response writer.
+// It panics if the underlying response writer is not a http3.HTTPStreamer.
+func (w *responseWriter) HTTPStream() *http3.Stream {
+	return w.ResponseWriter.(http3.HTTPStreamer).HTTPStream()
+}
+
+// Unwrap is used by the http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// RoundTripper wraps an http.RoundTripper, such that every request is traced by a span,
+// and the trace context is injected into the request headers.
+//
+// If the request is sent on a QUIC connection that is traced (i.e. for an http3.Transport
+// with a quic.Config.Tracer created by this Tracer), the span is a child of the connection span,
+// and it is linked to the span in the request context.
+// Otherwise, it is a child of the span in the request context.
+func (t *Tracer) RoundTripper(rt http.RoundTripper) http.RoundTripper {
+	return &roundTripper{tracer: t, rt: rt}
+}
+
+type roundTripper struct {
+	tracer *Tracer
+	rt     http.RoundTripper
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	t := rt.tracer
+	ctx := req.Context()
+	start := time.Now()
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(requestAttributes(req)...),
+		trace.WithTimestamp(start),
+	}
+
+	// The connection is only known once the http3.Transport calls GotConn.
+	// Therefore, the span (and the trace context injected into the request headers) is created there.
+	// The header map is cloned, since a RoundTripper must not modify the request.
+	var (
+		mx   sync.Mutex
+		span trace.Span
+	)
+	startSpan := func(parent context.Context, extraOpts ...trace.SpanStartOption) {
+		mx.Lock()
+		defer mx.Unlock()
+		// GotConn is called again if the request is retried on a different connection
+		if span != nil {
+			return
+		}
+		var spanCtx context.Context
+		spanCtx, span = t.tracer.Start(parent, req.Method, append(opts, extraOpts...)...)
+		t.propagator.Inject(spanCtx, propagation.HeaderCarrier(req.Header))
+	}
+	req = req.Clone(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			var connSpan trace.Span
+			if c, ok := info.Conn.(interface{ QUICConn() *quic.Conn }); ok {
+				connSpan = SpanFromConn(c.QUICConn())
+			}
+			if connSpan == nil {
+				startSpan(ctx)
+				return
+			}
+			var extraOpts []trace.SpanStartOption
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+				extraOpts = append(extraOpts, trace.WithLinks(trace.Link{SpanContext: sc}))
+			}
+			startSpan(trace.ContextWithSpan(ctx, connSpan), extraOpts...)
+		},
+	}))
+	rsp, err := rt.rt.RoundTrip(req)
+	// GotConn is not called if the request failed before a connection was obtained
+	startSpan(ctx)
+	mx.Lock()
+	defer mx.Unlock()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int("http.response.status_code", rsp.StatusCode))
+		if rsp.StatusCode >= 400 {
+			span.SetStatus(codes.Error, http.StatusText(rsp.StatusCode))
+		}
+	}
+	span.End()
+	return rsp, err
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped RoundTripper, if it supports it.
+func (rt *roundTripper) CloseIdleConnections() {
+	if c, ok := rt.rt.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+func requestAttributes(r *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", r.Method),
+		attribute.String("network.protocol.name", "http"),
+		attribute.String("network.protocol.version", "3"),
+	}
+	if r.URL != nil {
+		attrs = append(attrs, attribute.String("url.path", r.URL.Path))
+		if r.URL.Scheme != "" {
+			attrs = append(attrs, attribute.String("url.scheme", r.URL.Scheme))
+		}
+	}
+	host := r.Host
+	if host == "" && r.URL != nil {
+		host = r.URL.Host
+	}
+	if host != "" {
+		attrs = append(attrs, attribute.String("server.address", host))
+	}
+	if ua := r.UserAgent(); ua != "" {
+		attrs = append(attrs, attribute.String("user_agent.original", ua))
+	}
+	return attrs
+}
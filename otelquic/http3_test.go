@@ -0,0 +1,120 @@
+package otelquic_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/http"
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/http3"
+	"github.com/nukilabs/quic-go/internal/testdata"
+	"github.com/nukilabs/quic-go/otelquic"
+	tls "github.com/nukilabs/utls"
+
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTP3(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := otelquic.NewTracer(&otelquic.Config{TracerProvider: tp})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "Hello, World!\n")
+	})
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	server := &http3.Server{
+		Handler:     tracer.Handler(mux),
+		TLSConfig:   testdata.GetTLSConfig(),
+		QUICConfig:  &quic.Config{Tracer: tracer.ConnectionTracer},
+		ConnContext: otelquic.ConnContext,
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Serve(conn)
+	}()
+	t.Cleanup(func() {
+		conn.Close()
+		<-done
+	})
+
+	tr := &http3.Transport{
+		TLSClientConfig: &tls.Config{ServerName: "localhost", RootCAs: testdata.GetRootCA()},
+		QUICConfig:      &quic.Config{Tracer: tracer.ConnectionTracer},
+	}
+	t.Cleanup(func() { tr.Close() })
+	cl := &http.Client{Transport: tracer.RoundTripper(tr)}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://localhost:%d/hello", conn.LocalAddr().(*net.UDPAddr).Port), nil)
+	require.NoError(t, err)
+	resp, err := cl.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "Hello, World!\n", string(body))
+	parent.End()
+
+	// close the connection, so that the connection spans are ended
+	tr.CloseIdleConnections()
+	require.Eventually(t, func() bool { return len(exporter.GetSpans()) == 5 }, time.Second, 10*time.Millisecond)
+
+	spans := make(map[trace.SpanKind][]tracetest.SpanStub)
+	for _, s := range exporter.GetSpans() {
+		spans[s.SpanKind] = append(spans[s.SpanKind], s)
+	}
+	require.Len(t, spans[trace.SpanKindClient], 2)
+	require.Len(t, spans[trace.SpanKindServer], 2)
+	spanByName := func(kind trace.SpanKind, name string) tracetest.SpanStub {
+		for _, s := range spans[kind] {
+			if s.Name == name {
+				return s
+			}
+		}
+		t.Fatalf("span %s not found", name)
+		return tracetest.SpanStub{}
+	}
+
+	clientConn := spanByName(trace.SpanKindClient, "quic.connection")
+	serverConn := spanByName(trace.SpanKindServer, "quic.connection")
+	clientReq := spanByName(trace.SpanKindClient, http.MethodGet)
+	serverReq := spanByName(trace.SpanKindServer, http.MethodGet)
+
+	eventNames := func(s tracetest.SpanStub) []string {
+		var names []string
+		for _, e := range s.Events {
+			names = append(names, e.Name)
+		}
+		return names
+	}
+	for _, s := range []tracetest.SpanStub{clientConn, serverConn} {
+		require.Contains(t, eventNames(s), otelquic.EventHandshakeDone)
+		require.Contains(t, eventNames(s), otelquic.EventHandshakeConfirmed)
+		require.Contains(t, eventNames(s), otelquic.EventConnectionClosed)
+	}
+
+	// The client connection was dialed in the context of the first request.
+	require.Equal(t, parent.SpanContext().SpanID(), clientConn.Parent.SpanID())
+	// The client request is a child of the client connection, and linked to the parent span.
+	require.Equal(t, clientConn.SpanContext.SpanID(), clientReq.Parent.SpanID())
+	require.Len(t, clientReq.Links, 1)
+	require.Equal(t, parent.SpanContext().SpanID(), clientReq.Links[0].SpanContext.SpanID())
+	// The trace context was propagated to the server.
+	require.Equal(t, clientReq.SpanContext.TraceID(), serverReq.SpanContext.TraceID())
+	require.Equal(t, clientReq.SpanContext.SpanID(), serverReq.Parent.SpanID())
+	require.True(t, serverReq.Parent.IsRemote())
+	// The server request is linked to the server connection.
+	require.Len(t, serverReq.Links, 1)
+	require.Equal(t, serverConn.SpanContext.SpanID(), serverReq.Links[0].SpanContext.SpanID())
+}
@@ -0,0 +1,132 @@
+package otelquic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/nukilabs/http"
+	"github.com/nukilabs/http/httptest"
+	"github.com/nukilabs/http/httptrace"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHandler(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+	h := tracer.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, trace.SpanContextFromContext(r.Context()).IsValid())
+		w.WriteHeader(http.StatusTeapot)
+		w.(http.Flusher).Flush()
+	}))
+
+	ctx, connSpan := tracer.tracer.Start(context.Background(), "conn")
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", nil).WithContext(ctx)
+	req.Header.Set("User-Agent", "test")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusTeapot, rec.Code)
+	require.True(t, rec.Flushed)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, http.MethodGet, span.Name)
+	require.Equal(t, trace.SpanKindServer, span.SpanKind)
+	require.Equal(t, connSpan.SpanContext().SpanID(), span.Parent.SpanID())
+	require.Empty(t, span.Links)
+	require.Equal(t, codes.Unset, span.Status.Code)
+	attrs := attributeMap(span.Attributes)
+	require.Equal(t, int64(http.StatusTeapot), attrs["http.response.status_code"].AsInt64())
+	require.Equal(t, "/foo", attrs["url.path"].AsString())
+	require.Equal(t, "example.com", attrs["server.address"].AsString())
+	require.Equal(t, "test", attrs["user_agent.original"].AsString())
+	require.Equal(t, "3", attrs["network.protocol.version"].AsString())
+}
+
+func TestHandlerRemoteParent(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+	h := tracer.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	remoteCtx, remote := tracer.tracer.Start(context.Background(), "remote")
+	ctx, connSpan := tracer.tracer.Start(context.Background(), "conn")
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/", nil).WithContext(ctx)
+	propagation.TraceContext{}.Inject(remoteCtx, propagation.HeaderCarrier(req.Header))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, remote.SpanContext().TraceID(), span.SpanContext.TraceID())
+	require.Equal(t, remote.SpanContext().SpanID(), span.Parent.SpanID())
+	require.True(t, span.Parent.IsRemote())
+	require.Len(t, span.Links, 1)
+	require.Equal(t, connSpan.SpanContext().SpanID(), span.Links[0].SpanContext.SpanID())
+	require.Equal(t, codes.Error, span.Status.Code)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRoundTripper(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+	var header http.Header
+	rt := tracer.RoundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		// a net.Conn that isn't a QUIC connection
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+		httptrace.ContextClientTrace(req.Context()).GotConn(httptrace.GotConnInfo{Conn: c1})
+		header = req.Header
+		return &http.Response{StatusCode: http.StatusNotFound}, nil
+	}))
+
+	ctx, parent := tracer.tracer.Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com/foo", nil)
+	require.NoError(t, err)
+	rsp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, rsp.StatusCode)
+	// the original request is not modified
+	require.Empty(t, req.Header)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, trace.SpanKindClient, span.SpanKind)
+	require.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+	require.Empty(t, span.Links)
+	require.Equal(t, codes.Error, span.Status.Code)
+	require.Equal(t, int64(http.StatusNotFound), attributeMap(span.Attributes)["http.response.status_code"].AsInt64())
+
+	// the trace context was injected into the request headers
+	injected := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier(header)))
+	require.Equal(t, span.SpanContext.SpanID(), injected.SpanID())
+	require.Equal(t, span.SpanContext.TraceID(), injected.TraceID())
+}
+
+func TestRoundTripperError(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+	testErr := errors.New("test error")
+	rt := tracer.RoundTripper(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, testErr
+	}))
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/foo", nil)
+	require.NoError(t, err)
+	_, err = rt.RoundTrip(req)
+	require.ErrorIs(t, err, testErr)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Equal(t, "test error", spans[0].Status.Description)
+	require.Len(t, spans[0].Events, 1)
+	require.Equal(t, "exception", spans[0].Events[0].Name)
+}
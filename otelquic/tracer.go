@@ -0,0 +1,91 @@
+// Package otelquic emits OpenTelemetry spans for QUIC connections and HTTP/3 requests.
+//
+// Every QUIC connection is traced by a span, which is started when the connection is created
+// and ended when it is closed. Handshake milestones, 0-RTT and connection migration are recorded
+// as span events. To trace connections, use Tracer.ConnectionTracer as the quic.Config.Tracer.
+//
+// HTTP/3 requests are traced by child spans of the connection span:
+//   - On the server, wrap the handler using Tracer.Handler, and set http3.Server.ConnContext to ConnContext.
+//   - On the client, wrap the http3.Transport using Tracer.RoundTripper.
+//
+// The trace context is propagated using the W3C Trace Context headers (traceparent and tracestate).
+//
+// This package is a separate Go module, such that users of quic-go don't depend on OpenTelemetry.
+package otelquic
+
+import (
+	"context"
+
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/qlogwriter"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/nukilabs/quic-go/otelquic"
+
+// Config configures a Tracer.
+type Config struct {
+	// TracerProvider is used to create the spans.
+	// If nil, the global tracer provider is used.
+	TracerProvider trace.TracerProvider
+	// Propagator is used to inject the trace context into HTTP/3 requests (client side),
+	// and to extract it from HTTP/3 requests (server side).
+	// If nil, the W3C Trace Context propagator is used.
+	Propagator propagation.TextMapPropagator
+}
+
+// A Tracer creates spans for QUIC connections and HTTP/3 requests.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer creates a new Tracer.
+// The config may be nil.
+func NewTracer(conf *Config) *Tracer {
+	if conf == nil {
+		conf = &Config{}
+	}
+	tp := conf.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	propagator := conf.Propagator
+	if propagator == nil {
+		propagator = propagation.TraceContext{}
+	}
+	return &Tracer{
+		tracer:     tp.Tracer(instrumentationName),
+		propagator: propagator,
+	}
+}
+
+// ConnectionTracer starts a span for a new QUIC connection.
+// It can be used as the quic.Config.Tracer.
+// The span of ctx (if any) is used as the parent span.
+// For client connections, this is the context passed to Dial.
+func (t *Tracer) ConnectionTracer(ctx context.Context, isClient bool, connID quic.ConnectionID) qlogwriter.Trace {
+	return newConnTrace(ctx, t.tracer, isClient, connID)
+}
+
+// SpanFromConn returns the span of a QUIC connection.
+// It returns nil if the connection is not traced by a Tracer.
+func SpanFromConn(conn *quic.Conn) trace.Span {
+	if ct, ok := conn.QlogTrace().(*connTrace); ok {
+		return ct.span
+	}
+	return nil
+}
+
+// ConnContext returns a copy of ctx that carries the span of the QUIC connection.
+// It can be used as the http3.Server.ConnContext, such that the spans of all requests
+// received on the connection are children of the connection span.
+func ConnContext(ctx context.Context, conn *quic.Conn) context.Context {
+	if span := SpanFromConn(conn); span != nil {
+		return trace.ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
@@ -44,7 +44,7 @@ func newTestPacketPacker(t *testing.T, mockCtrl *gomock.Controller, pers protoco
 	framer := NewMockFrameSource(mockCtrl)
 	ackFramer := NewMockAckFrameSource(mockCtrl)
 	sealingManager := NewMockSealingManager(mockCtrl)
-	datagramQueue := newDatagramQueue(func() {}, utils.DefaultLogger)
+	datagramQueue := newDatagramQueue(func() {}, defaultDatagramSendQueueLen, defaultDatagramRcvQueueLen, DatagramQueueBlock, utils.DefaultLogger)
 	retransmissionQueue := newRetransmissionQueue()
 	return &testPacketPacker{
 		pnManager:           pnManager,
@@ -654,10 +654,11 @@ func TestPackDatagramFrames(t *testing.T) {
 	tp.pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 	tp.pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
 	tp.sealingManager.EXPECT().Get1RTTSealer().Return(newMockShortHeaderSealer(mockCtrl), nil)
+	var statuses []DatagramStatus
 	tp.datagramQueue.Add(&wire.DatagramFrame{
 		DataLenPresent: true,
 		Data:           []byte("foobar"),
-	})
+	}, func(s DatagramStatus) { statuses = append(statuses, s) })
 	tp.framer.EXPECT().HasData()
 	buffer := getPacketBuffer()
 	p, err := tp.packer.AppendPacket(buffer, protocol.MaxByteCount, monotime.Now(), protocol.Version1)
@@ -666,6 +667,10 @@ func TestPackDatagramFrames(t *testing.T) {
 	require.IsType(t, &wire.DatagramFrame{}, p.Frames[0].Frame)
 	require.Equal(t, []byte("foobar"), p.Frames[0].Frame.(*wire.DatagramFrame).Data)
 	require.NotEmpty(t, buffer.Data)
+	require.Equal(t, []DatagramStatus{DatagramSent}, statuses)
+	require.NotNil(t, p.Frames[0].Handler)
+	p.Frames[0].Handler.OnAcked(p.Frames[0].Frame)
+	require.Equal(t, []DatagramStatus{DatagramSent, DatagramAcked}, statuses)
 }
 
 func TestPackLargeDatagramFrame(t *testing.T) {
@@ -679,7 +684,8 @@ func TestPackLargeDatagramFrame(t *testing.T) {
 	tp.pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
 	tp.sealingManager.EXPECT().Get1RTTSealer().Return(newMockShortHeaderSealer(mockCtrl), nil)
 	f := &wire.DatagramFrame{DataLenPresent: true, Data: make([]byte, maxPacketSize-10)}
-	tp.datagramQueue.Add(f)
+	var statuses []DatagramStatus
+	tp.datagramQueue.Add(f, func(s DatagramStatus) { statuses = append(statuses, s) })
 	tp.framer.EXPECT().HasData()
 	buffer := getPacketBuffer()
 	p, err := tp.packer.AppendPacket(buffer, maxPacketSize, monotime.Now(), protocol.Version1)
@@ -700,6 +706,7 @@ func TestPackLargeDatagramFrame(t *testing.T) {
 	p, err = tp.packer.AppendPacket(buffer, newMaxPacketSize, monotime.Now(), protocol.Version1)
 	require.ErrorIs(t, err, errNothingToPack)
 	require.Nil(t, tp.datagramQueue.Peek()) // make sure the frame is gone
+	require.Equal(t, []DatagramStatus{DatagramDropped}, statuses)
 }
 
 func TestPackRetransmissions(t *testing.T) {
@@ -129,7 +129,7 @@ func testUnpackLongHeaderPacket(t *testing.T,
 	var calls []any
 	switch encLevel {
 	case protocol.EncryptionInitial:
-		calls = append(calls, cs.EXPECT().GetInitialOpener().Return(opener, nil))
+		calls = append(calls, cs.EXPECT().GetInitialOpener(protocol.Version1).Return(opener, nil))
 	case protocol.EncryptionHandshake:
 		calls = append(calls, cs.EXPECT().GetHandshakeOpener().Return(opener, nil))
 	case protocol.Encryption0RTT:
@@ -4,12 +4,14 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
+	"net"
 	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/nukilabs/quic-go/internal/ackhandler"
+	"github.com/nukilabs/quic-go/internal/monotime"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/wire"
 )
@@ -29,6 +31,8 @@ type Path struct {
 	pathManager *pathManagerOutgoing
 	tr          *Transport
 	initialRTT  time.Duration
+	conn        *Conn // nil in tests
+	stats       *pathStats
 
 	enablePath func()
 	validated  atomic.Bool
@@ -86,6 +90,27 @@ func (p *Path) Switch() error {
 	return nil
 }
 
+// Stats returns statistics about the path.
+// Once the path is closed, it is neither validated nor active,
+// but the counters retain their values.
+func (p *Path) Stats() PathStats {
+	validated, active := p.pathManager.pathState(p.id)
+	s := PathStats{
+		LocalAddr: p.tr.Conn.LocalAddr(),
+		Validated: validated,
+		Active:    active,
+	}
+	p.stats.populate(&s)
+	if p.conn != nil {
+		s.RemoteAddr = p.conn.RemoteAddr()
+		if active {
+			s.SmoothedRTT = p.conn.rttStats.SmoothedRTT()
+			s.LatestRTT = p.conn.rttStats.LatestRTT()
+		}
+	}
+	return s
+}
+
 // Close abandons a path.
 // It is not possible to close the path that’s currently active.
 // After closing, it is not possible to probe this path again.
@@ -103,9 +128,15 @@ func (p *Path) Close() error {
 	return nil
 }
 
+type pathChallenge struct {
+	data     [8]byte
+	sentTime monotime.Time
+}
+
 type pathOutgoing struct {
-	pathChallenges [][8]byte // length is implicitly limited by exponential backoff
+	pathChallenges []pathChallenge // length is implicitly limited by exponential backoff
 	tr             *Transport
+	stats          *pathStats
 	isValidated    bool
 	probeSent      chan struct{} // receives when a PATH_CHALLENGE is sent
 	validated      chan struct{} // closed when the path the corresponding PATH_RESPONSE is received
@@ -158,6 +189,7 @@ func (pm *pathManagerOutgoing) addPath(p *Path, enablePath func()) *pathOutgoing
 
 	path := &pathOutgoing{
 		tr:         p.tr,
+		stats:      p.stats,
 		probeSent:  make(chan struct{}, 1),
 		validated:  make(chan struct{}),
 		enablePath: enablePath,
@@ -199,6 +231,43 @@ func (pm *pathManagerOutgoing) removePathImpl(id pathID) error {
 	return nil
 }
 
+func (pm *pathManagerOutgoing) pathState(id pathID) (validated, active bool) {
+	pm.mx.Lock()
+	defer pm.mx.Unlock()
+
+	p, ok := pm.paths[id]
+	if !ok {
+		return false, false
+	}
+	return p.isValidated, id == pm.activePath
+}
+
+// AppendPathStats appends the statistics of all paths (apart from the path used during the handshake).
+// It sets the Active flag on the last element, which is expected to be the path on the Transport used during the handshake.
+func (pm *pathManagerOutgoing) AppendPathStats(paths []PathStats, remoteAddr net.Addr) []PathStats {
+	pm.mx.Lock()
+	defer pm.mx.Unlock()
+
+	paths[len(paths)-1].Active = pm.activePath == 0
+	ids := make([]pathID, 0, len(pm.paths))
+	for id := range pm.paths {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		p := pm.paths[id]
+		s := PathStats{
+			LocalAddr:  p.tr.Conn.LocalAddr(),
+			RemoteAddr: remoteAddr,
+			Validated:  p.isValidated,
+			Active:     id == pm.activePath,
+		}
+		p.stats.populate(&s)
+		paths = append(paths, s)
+	}
+	return paths
+}
+
 func (pm *pathManagerOutgoing) switchToPath(id pathID) error {
 	pm.mx.Lock()
 	defer pm.mx.Unlock()
@@ -227,11 +296,12 @@ func (pm *pathManagerOutgoing) NewPath(t *Transport, initialRTT time.Duration, e
 		tr:          t,
 		enablePath:  enablePath,
 		initialRTT:  initialRTT,
+		stats:       &pathStats{},
 		abandon:     make(chan struct{}),
 	}
 }
 
-func (pm *pathManagerOutgoing) NextPathToProbe() (_ protocol.ConnectionID, _ ackhandler.Frame, _ *Transport, hasPath bool) {
+func (pm *pathManagerOutgoing) NextPathToProbe() (_ protocol.ConnectionID, _ ackhandler.Frame, _ *Transport, _ *pathStats, hasPath bool) {
 	pm.mx.Lock()
 	defer pm.mx.Unlock()
 
@@ -248,17 +318,17 @@ func (pm *pathManagerOutgoing) NextPathToProbe() (_ protocol.ConnectionID, _ ack
 		pm.pathsToProbe = pm.pathsToProbe[1:]
 	}
 	if id == invalidPathID {
-		return protocol.ConnectionID{}, ackhandler.Frame{}, nil, false
+		return protocol.ConnectionID{}, ackhandler.Frame{}, nil, nil, false
 	}
 
 	connID, ok := pm.getConnID(id)
 	if !ok {
-		return protocol.ConnectionID{}, ackhandler.Frame{}, nil, false
+		return protocol.ConnectionID{}, ackhandler.Frame{}, nil, nil, false
 	}
 
 	var b [8]byte
 	_, _ = rand.Read(b[:])
-	p.pathChallenges = append(p.pathChallenges, b)
+	p.pathChallenges = append(p.pathChallenges, pathChallenge{data: b, sentTime: monotime.Now()})
 
 	pm.pathsToProbe = pm.pathsToProbe[1:]
 	p.enablePath()
@@ -270,7 +340,7 @@ func (pm *pathManagerOutgoing) NextPathToProbe() (_ protocol.ConnectionID, _ ack
 		Frame:   &wire.PathChallengeFrame{Data: b},
 		Handler: (*pathManagerOutgoingAckHandler)(pm),
 	}
-	return connID, frame, p.tr, true
+	return connID, frame, p.tr, p.stats, true
 }
 
 func (pm *pathManagerOutgoing) HandlePathResponseFrame(f *wire.PathResponseFrame) {
@@ -278,11 +348,12 @@ func (pm *pathManagerOutgoing) HandlePathResponseFrame(f *wire.PathResponseFrame
 	defer pm.mx.Unlock()
 
 	for _, p := range pm.paths {
-		if slices.Contains(p.pathChallenges, f.Data) {
+		if i := slices.IndexFunc(p.pathChallenges, func(c pathChallenge) bool { return c.data == f.Data }); i != -1 {
 			// path validated
 			if !p.isValidated {
 				// make sure that duplicate PATH_RESPONSE frames are ignored
 				p.isValidated = true
+				p.stats.validationRTT.Store(int64(monotime.Since(p.pathChallenges[i].sentTime)))
 				p.pathChallenges = nil
 				close(p.validated)
 			}
@@ -291,16 +362,16 @@ func (pm *pathManagerOutgoing) HandlePathResponseFrame(f *wire.PathResponseFrame
 	}
 }
 
-func (pm *pathManagerOutgoing) ShouldSwitchPath() (*Transport, bool) {
+func (pm *pathManagerOutgoing) ShouldSwitchPath() (*Transport, *pathStats, bool) {
 	pm.mx.Lock()
 	defer pm.mx.Unlock()
 
 	if pm.pathToSwitchTo == nil {
-		return nil, false
+		return nil, nil, false
 	}
 	p := pm.pathToSwitchTo
 	pm.pathToSwitchTo = nil
-	return p.tr, true
+	return p.tr, p.stats, true
 }
 
 type pathManagerOutgoingAckHandler pathManagerOutgoing
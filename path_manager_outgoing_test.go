@@ -2,6 +2,7 @@ package quic
 
 import (
 	"context"
+	"net"
 	"testing"
 	"time"
 
@@ -9,7 +10,9 @@ import (
 	"github.com/nukilabs/quic-go/internal/synctest"
 	"github.com/nukilabs/quic-go/internal/wire"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 )
 
 func TestPathManagerOutgoingPathProbing(t *testing.T) {
@@ -27,7 +30,7 @@ func TestPathManagerOutgoingPathProbing(t *testing.T) {
 			func() {},
 		)
 
-		_, _, _, ok := pm.NextPathToProbe()
+		_, _, _, _, ok := pm.NextPathToProbe()
 		require.False(t, ok)
 
 		tr1 := &Transport{}
@@ -42,7 +45,7 @@ func TestPathManagerOutgoingPathProbing(t *testing.T) {
 		synctest.Wait()
 
 		require.False(t, enabled)
-		connID, f, tr, ok := pm.NextPathToProbe()
+		connID, f, tr, _, ok := pm.NextPathToProbe()
 		require.True(t, ok)
 		require.Equal(t, tr1, tr)
 		require.Equal(t, protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), connID)
@@ -50,7 +53,7 @@ func TestPathManagerOutgoingPathProbing(t *testing.T) {
 		pc := f.Frame.(*wire.PathChallengeFrame)
 		require.True(t, enabled)
 
-		_, _, _, ok = pm.NextPathToProbe()
+		_, _, _, _, ok = pm.NextPathToProbe()
 		require.False(t, ok)
 
 		select {
@@ -68,7 +71,7 @@ func TestPathManagerOutgoingPathProbing(t *testing.T) {
 		}
 
 		require.ErrorIs(t, p.Switch(), ErrPathNotValidated)
-		_, ok = pm.ShouldSwitchPath()
+		_, _, ok = pm.ShouldSwitchPath()
 		require.False(t, ok)
 
 		// ... neither does receiving a random PATH_RESPONSE...
@@ -97,12 +100,12 @@ func TestPathManagerOutgoingPathProbing(t *testing.T) {
 		pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: pc.Data})
 
 		// now switch to the other path
-		_, ok = pm.ShouldSwitchPath()
+		_, _, ok = pm.ShouldSwitchPath()
 		require.False(t, ok)
 		require.NoError(t, p.Switch())
 		// the active path can't be closed
 		require.EqualError(t, p.Close(), "cannot close active path")
-		switchToTransport, ok := pm.ShouldSwitchPath()
+		switchToTransport, _, ok := pm.ShouldSwitchPath()
 		require.True(t, ok)
 		require.Equal(t, tr1, switchToTransport)
 	})
@@ -122,7 +125,7 @@ func TestPathManagerOutgoingRetransmissions(t *testing.T) {
 			func() { scheduledSending <- struct{}{} },
 		)
 
-		_, _, _, ok := pm.NextPathToProbe()
+		_, _, _, _, ok := pm.NextPathToProbe()
 		require.False(t, ok)
 
 		tr1 := &Transport{}
@@ -139,7 +142,7 @@ func TestPathManagerOutgoingRetransmissions(t *testing.T) {
 				case <-done:
 					return
 				}
-				_, f, _, ok := pm.NextPathToProbe()
+				_, f, _, _, ok := pm.NextPathToProbe()
 				if !ok {
 					// should never happen
 					pathChallengeChan <- [8]byte{}
@@ -253,7 +256,7 @@ func TestPathManagerOutgoingAbandonPath(t *testing.T) {
 		// closing the path multiple times is ok
 		require.NoError(t, p1.Close())
 		require.NoError(t, p1.Close())
-		_, _, _, ok := pm.NextPathToProbe()
+		_, _, _, _, ok := pm.NextPathToProbe()
 		require.False(t, ok)
 
 		synctest.Wait()
@@ -272,16 +275,82 @@ func TestPathManagerOutgoingAbandonPath(t *testing.T) {
 		// wait for the path to be queued for probing
 		synctest.Wait()
 
-		connID, f, _, ok := pm.NextPathToProbe()
+		connID, f, _, _, ok := pm.NextPathToProbe()
 		require.True(t, ok)
 		require.Equal(t, protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), connID)
 
 		require.NoError(t, p2.Close())
 		require.Equal(t, []pathID{p2.id}, retiredPaths)
 		pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: f.Frame.(*wire.PathChallengeFrame).Data})
-		_, _, _, ok = pm.NextPathToProbe()
+		_, _, _, _, ok = pm.NextPathToProbe()
 		require.False(t, ok)
 		// it's not possible to switch to an abandoned path
 		require.ErrorIs(t, p2.Switch(), ErrPathClosed)
 	})
 }
+
+func TestPathManagerOutgoingPathStats(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		pm := newPathManagerOutgoing(
+			func(id pathID) (protocol.ConnectionID, bool) {
+				return protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), true
+			},
+			func(id pathID) {},
+			func() {},
+		)
+
+		tr := &Transport{Conn: &mockPacketConn{localAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}}}
+		p := pm.NewPath(tr, time.Second, func() {})
+		require.Equal(t, PathStats{LocalAddr: tr.Conn.LocalAddr()}, p.Stats())
+
+		errChan := make(chan error, 1)
+		go func() { errChan <- p.Probe(context.Background()) }()
+		synctest.Wait()
+
+		_, f, _, stats, ok := pm.NextPathToProbe()
+		require.True(t, ok)
+		require.Same(t, p.stats, stats)
+		stats.onSent(1200, 0)
+		stats.onReceived(1000)
+
+		time.Sleep(42 * time.Millisecond)
+		pm.HandlePathResponseFrame(&wire.PathResponseFrame{Data: f.Frame.(*wire.PathChallengeFrame).Data})
+		synctest.Wait()
+		require.NoError(t, <-errChan)
+
+		require.Equal(t,
+			PathStats{
+				LocalAddr:       tr.Conn.LocalAddr(),
+				Validated:       true,
+				ValidationRTT:   42 * time.Millisecond,
+				BytesSent:       1200,
+				PacketsSent:     1,
+				BytesReceived:   1000,
+				PacketsReceived: 1,
+			},
+			p.Stats(),
+		)
+
+		require.NoError(t, p.Switch())
+		require.True(t, p.Stats().Active)
+		paths := pm.AppendPathStats([]PathStats{{Active: true}}, nil)
+		require.Len(t, paths, 2)
+		require.False(t, paths[0].Active)
+		require.Equal(t, p.Stats(), paths[1])
+	})
+}
+
+func TestPathSendConnGSO(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	c := NewMockSendConn(mockCtrl)
+	var stats pathStats
+	pc := newPathSendConn(c, &stats)
+	c.EXPECT().Write(gomock.Any(), uint16(0), protocol.ECNNon)
+	require.NoError(t, pc.Write(make([]byte, 1000), 0, protocol.ECNNon))
+	c.EXPECT().Write(gomock.Any(), uint16(1200), protocol.ECNNon)
+	require.NoError(t, pc.Write(make([]byte, 3000), 1200, protocol.ECNNon))
+	c.EXPECT().Write(gomock.Any(), uint16(0), protocol.ECNNon).Return(assert.AnError)
+	require.ErrorIs(t, pc.Write(make([]byte, 1000), 0, protocol.ECNNon), assert.AnError)
+	require.Equal(t, uint64(4000), stats.bytesSent.Load())
+	require.Equal(t, uint64(4), stats.packetsSent.Load())
+}
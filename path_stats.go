@@ -0,0 +1,115 @@
+package quic
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+)
+
+// PathStats contains statistics about a network path.
+type PathStats struct {
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+	// Validated says if path validation succeeded.
+	// The path that the handshake was performed on is always validated.
+	Validated bool
+	// Active says if this is the path that the connection is currently using.
+	Active bool
+
+	// ValidationRTT is the RTT measured during path validation,
+	// i.e. the time between sending the PATH_CHALLENGE and receiving the PATH_RESPONSE.
+	// It is zero for the path that the handshake was performed on, and for paths that were not validated yet.
+	ValidationRTT time.Duration
+	// SmoothedRTT and LatestRTT are the RTT estimates of the connection.
+	// They are only set for the active path.
+	SmoothedRTT time.Duration
+	LatestRTT   time.Duration
+
+	// BytesSent and PacketsSent count the UDP payloads sent on this path.
+	BytesSent   uint64
+	PacketsSent uint64
+	// BytesReceived and PacketsReceived count the UDP payloads received on this path,
+	// including packets that could not be processed.
+	BytesReceived   uint64
+	PacketsReceived uint64
+}
+
+type pathStats struct {
+	bytesSent       atomic.Uint64
+	packetsSent     atomic.Uint64
+	bytesReceived   atomic.Uint64
+	packetsReceived atomic.Uint64
+	validationRTT   atomic.Int64 // time.Duration
+}
+
+func (s *pathStats) onSent(size, gsoSize int) {
+	s.bytesSent.Add(uint64(size))
+	numPackets := 1
+	if gsoSize > 0 {
+		numPackets = (size + gsoSize - 1) / gsoSize
+	}
+	s.packetsSent.Add(uint64(numPackets))
+}
+
+func (s *pathStats) onReceived(size protocol.ByteCount) {
+	s.bytesReceived.Add(uint64(size))
+	s.packetsReceived.Add(1)
+}
+
+func (s *pathStats) populate(ps *PathStats) {
+	ps.ValidationRTT = time.Duration(s.validationRTT.Load())
+	ps.BytesSent = s.bytesSent.Load()
+	ps.PacketsSent = s.packetsSent.Load()
+	ps.BytesReceived = s.bytesReceived.Load()
+	ps.PacketsReceived = s.packetsReceived.Load()
+}
+
+// A previousPath is a path that the server migrated away from.
+type previousPath struct {
+	remoteAddr net.Addr
+	stats      *pathStats
+}
+
+// A pathSendConn counts the packets sent on a path.
+// Packets sent using WriteTo are not counted, since they're sent to a different remote address.
+type pathSendConn struct {
+	sendConn
+	stats atomic.Pointer[pathStats]
+}
+
+func newPathSendConn(c sendConn, stats *pathStats) *pathSendConn {
+	pc := &pathSendConn{sendConn: c}
+	pc.stats.Store(stats)
+	return pc
+}
+
+func (c *pathSendConn) Write(b []byte, gsoSize uint16, ecn protocol.ECN) error {
+	if err := c.sendConn.Write(b, gsoSize, ecn); err != nil {
+		return err
+	}
+	c.stats.Load().onSent(len(b), int(gsoSize))
+	return nil
+}
+
+// ChangeRemoteAddrAndStats changes the remote address.
+// Packets sent afterwards are counted in stats.
+func (c *pathSendConn) ChangeRemoteAddrAndStats(addr net.Addr, info packetInfo, stats *pathStats) {
+	c.stats.Store(stats)
+	c.sendConn.ChangeRemoteAddr(addr, info)
+}
+
+// A pathPacketHandler counts the packets received on a path,
+// and passes them to the connection.
+type pathPacketHandler struct {
+	*Conn
+	stats *pathStats
+}
+
+var _ packetHandler = &pathPacketHandler{}
+
+func (h *pathPacketHandler) handlePacket(p receivedPacket) {
+	h.stats.onReceived(p.Size())
+	h.Conn.handlePacketImpl(p)
+}
@@ -0,0 +1,52 @@
+package pcapng
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+type packetConn struct {
+	net.PacketConn
+	w *Writer
+}
+
+// WrapConn wraps a net.PacketConn, such that every datagram read from and written to it is captured.
+// Errors writing the capture don't affect the connection. They are returned by WriteDatagram and WriteSecrets.
+//
+// The wrapped connection doesn't expose any of the optimizations of the net.UDPConn to the Transport:
+// GSO, ECN and Path MTU Discovery are disabled.
+// If the connection is bound to an unspecified address, the local address of the captured packets is unspecified.
+func (w *Writer) WrapConn(conn net.PacketConn) net.PacketConn {
+	return &packetConn{PacketConn: conn, w: w}
+}
+
+func (c *packetConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(b)
+	if n > 0 {
+		c.w.WriteDatagram(time.Now(), addr, c.LocalAddr(), b[:n])
+	}
+	return n, addr, err
+}
+
+func (c *packetConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(b, addr)
+	if n > 0 {
+		c.w.WriteDatagram(time.Now(), c.LocalAddr(), addr, b[:n])
+	}
+	return n, err
+}
+
+func (c *packetConn) SetReadBuffer(bytes int) error {
+	if conn, ok := c.PacketConn.(interface{ SetReadBuffer(int) error }); ok {
+		return conn.SetReadBuffer(bytes)
+	}
+	return errors.New("pcapng: underlying connection doesn't allow setting the receive buffer size")
+}
+
+func (c *packetConn) SetWriteBuffer(bytes int) error {
+	if conn, ok := c.PacketConn.(interface{ SetWriteBuffer(int) error }); ok {
+		return conn.SetWriteBuffer(bytes)
+	}
+	return errors.New("pcapng: underlying connection doesn't allow setting the send buffer size")
+}
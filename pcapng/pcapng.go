@@ -0,0 +1,255 @@
+// Package pcapng writes the UDP datagrams sent and received by a QUIC transport to a pcapng file,
+// together with the TLS secrets of its connections, stored in Decryption Secrets Blocks.
+// Wireshark uses these secrets to decrypt the captured QUIC packets.
+//
+// This package is intended for debugging only. Anybody with access to the capture file can decrypt
+// all traffic it contains. It should never be enabled in production.
+//
+// A capture is set up by wrapping the transport's connection and setting the key log writer
+// of the TLS configuration. The TLS stack writes the secrets of QUIC connections to it:
+//
+//	w, err := pcapng.NewWriter(f)
+//	// handle err
+//	tr := &quic.Transport{Conn: w.WrapConn(udpConn)}
+//	tlsConf.KeyLogWriter = w.KeyLogWriter()
+//
+// Since IP headers are not available to the application, the IP and UDP headers of the captured
+// packets are synthesized from the addresses of the datagram.
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	blockTypeSectionHeader        = 0x0a0d0d0a
+	blockTypeInterfaceDescription = 0x00000001
+	blockTypeEnhancedPacket       = 0x00000006
+	blockTypeDecryptionSecrets    = 0x0000000a
+
+	byteOrderMagic = 0x1a2b3c4d
+
+	// linkTypeRaw means that packets start with an IPv4 or IPv6 header
+	linkTypeRaw = 101
+
+	// secretsTypeTLSKeyLog is the secrets type of the NSS key log format
+	secretsTypeTLSKeyLog = 0x544c534b
+
+	ipProtocolUDP = 17
+)
+
+// A Writer writes a pcapng file.
+// It is safe for concurrent use.
+type Writer struct {
+	mx sync.Mutex
+	w  io.Writer
+	// err is the first error that occurred while writing.
+	// Once an error occurs, nothing is written anymore.
+	err error
+	// keyLog holds a partially written line of the key log
+	keyLog []byte
+}
+
+// NewWriter creates a new pcapng Writer.
+// It writes the Section Header Block and the Interface Description Block to w.
+// Blocks are written to w as they are created, w is never buffered or closed.
+func NewWriter(w io.Writer) (*Writer, error) {
+	pw := &Writer{w: w}
+	var body []byte
+	body = binary.LittleEndian.AppendUint32(body, byteOrderMagic)
+	body = binary.LittleEndian.AppendUint16(body, 1) // major version
+	body = binary.LittleEndian.AppendUint16(body, 0) // minor version
+	// the length of the section is not specified
+	body = binary.LittleEndian.AppendUint64(body, 0xffffffffffffffff)
+	if err := pw.writeBlock(blockTypeSectionHeader, body); err != nil {
+		return nil, err
+	}
+
+	body = body[:0]
+	body = binary.LittleEndian.AppendUint16(body, linkTypeRaw)
+	body = binary.LittleEndian.AppendUint16(body, 0) // reserved
+	body = binary.LittleEndian.AppendUint32(body, 0) // snap length: no limit
+	if err := pw.writeBlock(blockTypeInterfaceDescription, body); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// writeBlock writes a block, adding the block type, the block length and the padding.
+// The body must not contain any options.
+func (w *Writer) writeBlock(blockType uint32, body []byte) error {
+	padding := (4 - len(body)%4) % 4
+	length := uint32(12 + len(body) + padding)
+	b := make([]byte, 0, length)
+	b = binary.LittleEndian.AppendUint32(b, blockType)
+	b = binary.LittleEndian.AppendUint32(b, length)
+	b = append(b, body...)
+	b = append(b, make([]byte, padding)...)
+	b = binary.LittleEndian.AppendUint32(b, length)
+	_, err := w.w.Write(b)
+	return err
+}
+
+func (w *Writer) write(blockType uint32, body []byte) error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.writeBlock(blockType, body)
+	return w.err
+}
+
+// WriteDatagram writes an Enhanced Packet Block containing a UDP datagram sent from src to dst.
+func (w *Writer) WriteDatagram(t time.Time, src, dst net.Addr, data []byte) error {
+	packet := appendIPPacket(nil, toAddrPort(src), toAddrPort(dst), data)
+	ts := uint64(t.UnixMicro()) // the default timestamp resolution is microseconds
+	body := make([]byte, 0, 20+len(packet))
+	body = binary.LittleEndian.AppendUint32(body, 0) // interface ID
+	body = binary.LittleEndian.AppendUint32(body, uint32(ts>>32))
+	body = binary.LittleEndian.AppendUint32(body, uint32(ts))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(packet))) // captured length
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(packet))) // original length
+	body = append(body, packet...)
+	return w.write(blockTypeEnhancedPacket, body)
+}
+
+// WriteSecrets writes a Decryption Secrets Block containing TLS secrets in NSS key log format.
+func (w *Writer) WriteSecrets(keyLog []byte) error {
+	body := make([]byte, 0, 8+len(keyLog))
+	body = binary.LittleEndian.AppendUint32(body, secretsTypeTLSKeyLog)
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(keyLog)))
+	body = append(body, keyLog...)
+	return w.write(blockTypeDecryptionSecrets, body)
+}
+
+// KeyLogWriter returns an io.Writer that is intended to be used as tls.Config.KeyLogWriter.
+// Every complete line written to it is stored in a Decryption Secrets Block.
+//
+// Wireshark only applies the secrets to packets that follow the Decryption Secrets Block in the file.
+// Handshake packets that were received before the secrets were derived are decrypted when
+// the capture is dissected again, which Wireshark does when the file is opened in the GUI.
+// For tshark, use the -2 flag to perform a two-pass analysis.
+func (w *Writer) KeyLogWriter() io.Writer {
+	return keyLogWriter{w}
+}
+
+type keyLogWriter struct{ w *Writer }
+
+func (k keyLogWriter) Write(b []byte) (int, error) {
+	w := k.w
+	w.mx.Lock()
+	w.keyLog = append(w.keyLog, b...)
+	i := bytes.LastIndexByte(w.keyLog, '\n')
+	if i < 0 {
+		w.mx.Unlock()
+		return len(b), nil
+	}
+	lines := bytes.Clone(w.keyLog[:i+1])
+	w.keyLog = append(w.keyLog[:0], w.keyLog[i+1:]...)
+	w.mx.Unlock()
+
+	if err := w.WriteSecrets(lines); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func toAddrPort(addr net.Addr) netip.AddrPort {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.AddrPort()
+	case interface{ AddrPort() netip.AddrPort }:
+		return a.AddrPort()
+	}
+	if addr == nil {
+		return netip.AddrPort{}
+	}
+	ap, _ := netip.ParseAddrPort(addr.String())
+	return ap
+}
+
+// appendIPPacket appends an IPv4 or IPv6 packet containing the UDP datagram.
+// IPv4 is used unless one of the addresses is an IPv6 address (IPv4-mapped IPv6 addresses are treated as IPv4).
+// Unspecified addresses are encoded as 0.0.0.0 and ::, respectively.
+func appendIPPacket(b []byte, src, dst netip.AddrPort, data []byte) []byte {
+	srcIP := src.Addr().Unmap()
+	dstIP := dst.Addr().Unmap()
+	udpLen := 8 + len(data)
+	var pseudoHeader []byte
+	if (srcIP.Is6() && !srcIP.IsUnspecified()) || (dstIP.Is6() && !dstIP.IsUnspecified()) {
+		if !srcIP.Is6() {
+			srcIP = netip.IPv6Unspecified()
+		}
+		if !dstIP.Is6() {
+			dstIP = netip.IPv6Unspecified()
+		}
+		b = append(b, 0x60, 0, 0, 0) // version 6, traffic class and flow label 0
+		b = binary.BigEndian.AppendUint16(b, uint16(udpLen))
+		b = append(b, ipProtocolUDP, 64) // next header, hop limit
+		b = append(b, srcIP.AsSlice()...)
+		b = append(b, dstIP.AsSlice()...)
+		pseudoHeader = append(pseudoHeader, srcIP.AsSlice()...)
+		pseudoHeader = append(pseudoHeader, dstIP.AsSlice()...)
+		pseudoHeader = binary.BigEndian.AppendUint32(pseudoHeader, uint32(udpLen))
+		pseudoHeader = append(pseudoHeader, 0, 0, 0, ipProtocolUDP)
+	} else {
+		if !srcIP.Is4() {
+			srcIP = netip.IPv4Unspecified()
+		}
+		if !dstIP.Is4() {
+			dstIP = netip.IPv4Unspecified()
+		}
+		start := len(b)
+		b = append(b, 0x45, 0) // version 4, header length 20 bytes, DSCP / ECN 0
+		b = binary.BigEndian.AppendUint16(b, uint16(20+udpLen))
+		b = append(b, 0, 0, 0x40, 0) // identification 0, Don't Fragment
+		b = append(b, 64, ipProtocolUDP, 0, 0)
+		b = append(b, srcIP.AsSlice()...)
+		b = append(b, dstIP.AsSlice()...)
+		binary.BigEndian.PutUint16(b[start+10:], checksum(b[start:]))
+		pseudoHeader = append(pseudoHeader, srcIP.AsSlice()...)
+		pseudoHeader = append(pseudoHeader, dstIP.AsSlice()...)
+		pseudoHeader = append(pseudoHeader, 0, ipProtocolUDP)
+		pseudoHeader = binary.BigEndian.AppendUint16(pseudoHeader, uint16(udpLen))
+	}
+	start := len(b)
+	b = binary.BigEndian.AppendUint16(b, src.Port())
+	b = binary.BigEndian.AppendUint16(b, dst.Port())
+	b = binary.BigEndian.AppendUint16(b, uint16(udpLen))
+	b = append(b, 0, 0) // checksum, calculated below
+	b = append(b, data...)
+	sum := checksum(pseudoHeader, b[start:])
+	if sum == 0 {
+		// a checksum of 0 means that no checksum was calculated
+		sum = 0xffff
+	}
+	binary.BigEndian.PutUint16(b[start+6:], sum)
+	return b
+}
+
+// checksum calculates the Internet checksum (RFC 1071) over the concatenation of the slices.
+// All slices but the last one must have an even length.
+func checksum(slices ...[]byte) uint16 {
+	var sum uint32
+	for _, b := range slices {
+		for i := 0; i < len(b); i += 2 {
+			if i+1 < len(b) {
+				sum += uint32(binary.BigEndian.Uint16(b[i:]))
+			} else {
+				sum += uint32(b[i]) << 8
+			}
+		}
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
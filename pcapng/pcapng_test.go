@@ -0,0 +1,224 @@
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type block struct {
+	Type uint32
+	Body []byte
+}
+
+func parseBlocks(t *testing.T, data []byte) []block {
+	t.Helper()
+
+	var blocks []block
+	for len(data) > 0 {
+		require.GreaterOrEqual(t, len(data), 12)
+		typ := binary.LittleEndian.Uint32(data)
+		length := binary.LittleEndian.Uint32(data[4:])
+		require.Zero(t, length%4)
+		require.LessOrEqual(t, int(length), len(data))
+		require.Equal(t, length, binary.LittleEndian.Uint32(data[length-4:]))
+		blocks = append(blocks, block{Type: typ, Body: data[8 : length-4]})
+		data = data[length:]
+	}
+	return blocks
+}
+
+func TestWriterHeader(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := NewWriter(&buf)
+	require.NoError(t, err)
+	blocks := parseBlocks(t, buf.Bytes())
+	require.Len(t, blocks, 2)
+	require.Equal(t, uint32(blockTypeSectionHeader), blocks[0].Type)
+	require.Equal(t, uint32(byteOrderMagic), binary.LittleEndian.Uint32(blocks[0].Body))
+	require.Equal(t, uint16(1), binary.LittleEndian.Uint16(blocks[0].Body[4:]))
+	require.Equal(t, uint32(blockTypeInterfaceDescription), blocks[1].Type)
+	require.Equal(t, uint16(linkTypeRaw), binary.LittleEndian.Uint16(blocks[1].Body))
+}
+
+func TestWriteDatagramIPv4(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	require.NoError(t, err)
+	now := time.Unix(1234567890, 123456789)
+	require.NoError(t, w.WriteDatagram(
+		now,
+		&net.UDPAddr{IP: net.IPv4(192, 168, 1, 1), Port: 1234},
+		&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443},
+		[]byte("foobar"),
+	))
+
+	blocks := parseBlocks(t, buf.Bytes())
+	require.Len(t, blocks, 3)
+	require.Equal(t, uint32(blockTypeEnhancedPacket), blocks[2].Type)
+	body := blocks[2].Body
+	require.Zero(t, binary.LittleEndian.Uint32(body)) // interface ID
+	ts := uint64(binary.LittleEndian.Uint32(body[4:]))<<32 | uint64(binary.LittleEndian.Uint32(body[8:]))
+	require.Equal(t, uint64(now.UnixMicro()), ts)
+	capLen := binary.LittleEndian.Uint32(body[12:])
+	require.Equal(t, uint32(20+8+6), capLen)
+	require.Equal(t, capLen, binary.LittleEndian.Uint32(body[16:]))
+
+	packet := body[20 : 20+capLen]
+	require.Equal(t, byte(0x45), packet[0])
+	require.Equal(t, uint16(34), binary.BigEndian.Uint16(packet[2:]))
+	require.Equal(t, byte(ipProtocolUDP), packet[9])
+	require.Zero(t, checksum(packet[:20]))
+	require.Equal(t, []byte{192, 168, 1, 1}, packet[12:16])
+	require.Equal(t, []byte{10, 0, 0, 1}, packet[16:20])
+	udp := packet[20:]
+	require.Equal(t, uint16(1234), binary.BigEndian.Uint16(udp))
+	require.Equal(t, uint16(443), binary.BigEndian.Uint16(udp[2:]))
+	require.Equal(t, uint16(14), binary.BigEndian.Uint16(udp[4:]))
+	pseudoHeader := append(append([]byte{}, packet[12:20]...), 0, ipProtocolUDP, 0, 14)
+	require.Zero(t, checksum(pseudoHeader, udp))
+	require.Equal(t, []byte("foobar"), udp[8:])
+}
+
+func TestWriteDatagramIPv6(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	require.NoError(t, err)
+	src := netip.MustParseAddrPort("[2001:db8::1]:1234")
+	require.NoError(t, w.WriteDatagram(
+		time.Now(),
+		net.UDPAddrFromAddrPort(src),
+		&net.UDPAddr{IP: net.IPv6unspecified, Port: 443},
+		[]byte("foo"),
+	))
+
+	blocks := parseBlocks(t, buf.Bytes())
+	require.Len(t, blocks, 3)
+	body := blocks[2].Body
+	capLen := binary.LittleEndian.Uint32(body[12:])
+	require.Equal(t, uint32(40+8+3), capLen)
+	packet := body[20 : 20+capLen]
+	require.Equal(t, byte(0x60), packet[0])
+	require.Equal(t, uint16(11), binary.BigEndian.Uint16(packet[4:]))
+	require.Equal(t, byte(ipProtocolUDP), packet[6])
+	require.Equal(t, src.Addr().AsSlice(), packet[8:24])
+	require.Equal(t, net.IPv6unspecified.To16(), net.IP(packet[24:40]))
+	udp := packet[40:]
+	pseudoHeader := append(append([]byte{}, packet[8:40]...), 0, 0, 0, 11, 0, 0, 0, ipProtocolUDP)
+	require.Zero(t, checksum(pseudoHeader, udp))
+	require.Equal(t, []byte("foo"), udp[8:])
+}
+
+func TestWriteDatagramIPv4Mapped(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteDatagram(
+		time.Now(),
+		net.UDPAddrFromAddrPort(netip.MustParseAddrPort("[::ffff:192.168.1.1]:1234")),
+		&net.UDPAddr{IP: net.IPv6unspecified, Port: 443},
+		[]byte("foo"),
+	))
+	blocks := parseBlocks(t, buf.Bytes())
+	require.Len(t, blocks, 3)
+	packet := blocks[2].Body[20:]
+	require.Equal(t, byte(0x45), packet[0])
+	require.Equal(t, []byte{192, 168, 1, 1}, packet[12:16])
+	require.Equal(t, []byte{0, 0, 0, 0}, packet[16:20])
+}
+
+func TestKeyLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	require.NoError(t, err)
+	kl := w.KeyLogWriter()
+
+	// incomplete lines are buffered
+	n, err := kl.Write([]byte("CLIENT_HANDSHAKE_TRAFFIC_SECRET 0102 "))
+	require.NoError(t, err)
+	require.Equal(t, 37, n)
+	require.Len(t, parseBlocks(t, buf.Bytes()), 2)
+	_, err = kl.Write([]byte("0304\nSERVER_HANDSHAKE_TRAFFIC_SECRET 0102 0506\nCLIENT_TRAFFIC"))
+	require.NoError(t, err)
+
+	blocks := parseBlocks(t, buf.Bytes())
+	require.Len(t, blocks, 3)
+	require.Equal(t, uint32(blockTypeDecryptionSecrets), blocks[2].Type)
+	body := blocks[2].Body
+	require.Equal(t, uint32(secretsTypeTLSKeyLog), binary.LittleEndian.Uint32(body))
+	secretsLen := binary.LittleEndian.Uint32(body[4:])
+	require.Equal(t,
+		"CLIENT_HANDSHAKE_TRAFFIC_SECRET 0102 0304\nSERVER_HANDSHAKE_TRAFFIC_SECRET 0102 0506\n",
+		string(body[8:8+secretsLen]),
+	)
+	// the block is padded to 32 bits
+	require.Len(t, body, 8+int(secretsLen+3)/4*4)
+}
+
+type errorWriter struct {
+	bytes.Buffer
+	err error
+}
+
+func (w *errorWriter) Write(b []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.Buffer.Write(b)
+}
+
+func TestWriterErrors(t *testing.T) {
+	ew := &errorWriter{}
+	w, err := NewWriter(ew)
+	require.NoError(t, err)
+	ew.err = errors.New("test error")
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+	require.ErrorIs(t, w.WriteDatagram(time.Now(), addr, addr, []byte("foo")), ew.err)
+	// the writer doesn't write anything after an error occurred
+	ew.err = nil
+	require.Error(t, w.WriteDatagram(time.Now(), addr, addr, []byte("foo")))
+	require.Len(t, parseBlocks(t, ew.Bytes()), 2)
+}
+
+func TestWrapConn(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	require.NoError(t, err)
+
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	defer server.Close()
+	c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	conn := w.WrapConn(c)
+	defer conn.Close()
+	require.NoError(t, conn.(interface{ SetReadBuffer(int) error }).SetReadBuffer(1<<16))
+
+	_, err = conn.WriteTo([]byte("ping"), server.LocalAddr())
+	require.NoError(t, err)
+	b := make([]byte, 100)
+	_, addr, err := server.ReadFrom(b)
+	require.NoError(t, err)
+	_, err = server.WriteTo([]byte("pong!"), addr)
+	require.NoError(t, err)
+	n, _, err := conn.ReadFrom(b)
+	require.NoError(t, err)
+	require.Equal(t, "pong!", string(b[:n]))
+
+	blocks := parseBlocks(t, buf.Bytes())
+	require.Len(t, blocks, 4)
+	sent := blocks[2].Body[20:]
+	require.Equal(t, uint16(c.LocalAddr().(*net.UDPAddr).Port), binary.BigEndian.Uint16(sent[20:]))
+	require.Equal(t, uint16(server.LocalAddr().(*net.UDPAddr).Port), binary.BigEndian.Uint16(sent[22:]))
+	require.Equal(t, []byte("ping"), sent[28:32])
+	received := blocks[3].Body[20:]
+	require.Equal(t, uint16(server.LocalAddr().(*net.UDPAddr).Port), binary.BigEndian.Uint16(received[20:]))
+	require.Equal(t, uint16(c.LocalAddr().(*net.UDPAddr).Port), binary.BigEndian.Uint16(received[22:]))
+	require.Equal(t, []byte("pong!"), received[28:33])
+}
@@ -0,0 +1,594 @@
+package quic
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	tls "github.com/nukilabs/utls"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nukilabs/quic-go/quicvarint"
+)
+
+const (
+	// persistentTokenMaxAge is the time after which a stored address validation token is discarded.
+	persistentTokenMaxAge = 24 * time.Hour
+	// persistentSessionMaxAge is the time after which a stored TLS session is discarded.
+	// TLS 1.3 doesn't allow session tickets to be used for longer than 7 days (RFC 8446, section 4.6.1).
+	// If the server announced a shorter ticket lifetime, the TLS stack refuses to resume the session earlier.
+	persistentSessionMaxAge = 7 * 24 * time.Hour
+	// persistentStoreCompactionThreshold is the size of the log above which a file-backed store is compacted.
+	// The log is only compacted if it has grown to at least twice the size of the compacted store.
+	persistentStoreCompactionThreshold = 1 << 20
+)
+
+const persistentStoreVersion = 1
+
+var persistentStoreMagic = []byte("quic-go store")
+
+type persistentRecordType uint8
+
+const (
+	recordTypeTokenPut persistentRecordType = 1 + iota
+	recordTypeTokenPop
+	recordTypeSessionPut
+	recordTypeSessionDelete
+)
+
+// A persistentRecord is a single modification of the store.
+// The store is serialized as a log of records, allowing modifications to be appended.
+type persistentRecord struct {
+	typ    persistentRecordType
+	key    string
+	expiry time.Time
+
+	// only set for recordTypeTokenPut
+	token []byte
+	rtt   time.Duration
+
+	// only set for recordTypeSessionPut
+	ticket []byte
+	state  []byte
+}
+
+func (r *persistentRecord) append(b []byte) []byte {
+	body := make([]byte, 0, 64+len(r.key)+len(r.token)+len(r.ticket)+len(r.state))
+	body = append(body, byte(r.typ))
+	body = quicvarint.Append(body, uint64(len(r.key)))
+	body = append(body, r.key...)
+	switch r.typ {
+	case recordTypeTokenPut:
+		body = quicvarint.Append(body, uint64(r.expiry.Unix()))
+		body = quicvarint.Append(body, uint64(r.rtt.Microseconds()))
+		body = quicvarint.Append(body, uint64(len(r.token)))
+		body = append(body, r.token...)
+	case recordTypeSessionPut:
+		body = quicvarint.Append(body, uint64(r.expiry.Unix()))
+		body = quicvarint.Append(body, uint64(len(r.ticket)))
+		body = append(body, r.ticket...)
+		body = quicvarint.Append(body, uint64(len(r.state)))
+		body = append(body, r.state...)
+	}
+	b = quicvarint.Append(b, uint64(len(body)))
+	return append(b, body...)
+}
+
+func (r *persistentRecord) parse(b []byte) error {
+	if len(b) == 0 {
+		return io.EOF
+	}
+	r.typ = persistentRecordType(b[0])
+	b = b[1:]
+	key, b, err := readLengthPrefixed(b)
+	if err != nil {
+		return err
+	}
+	r.key = string(key)
+	switch r.typ {
+	case recordTypeTokenPop, recordTypeSessionDelete:
+	case recordTypeTokenPut:
+		expiry, l, err := quicvarint.Parse(b)
+		if err != nil {
+			return err
+		}
+		b = b[l:]
+		rtt, l, err := quicvarint.Parse(b)
+		if err != nil {
+			return err
+		}
+		b = b[l:]
+		r.expiry = time.Unix(int64(expiry), 0)
+		r.rtt = time.Duration(rtt) * time.Microsecond
+		if r.token, b, err = readLengthPrefixed(b); err != nil {
+			return err
+		}
+	case recordTypeSessionPut:
+		expiry, l, err := quicvarint.Parse(b)
+		if err != nil {
+			return err
+		}
+		b = b[l:]
+		r.expiry = time.Unix(int64(expiry), 0)
+		if r.ticket, b, err = readLengthPrefixed(b); err != nil {
+			return err
+		}
+		if r.state, b, err = readLengthPrefixed(b); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown record type: %d", r.typ)
+	}
+	if len(b) > 0 {
+		return errors.New("record has trailing data")
+	}
+	return nil
+}
+
+func readLengthPrefixed(b []byte) (data, rest []byte, _ error) {
+	l, n, err := quicvarint.Parse(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	b = b[n:]
+	if uint64(len(b)) < l {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	return b[:l:l], b[l:], nil
+}
+
+type persistedToken struct {
+	data   []byte
+	rtt    time.Duration
+	expiry time.Time
+}
+
+type persistedTokenOrigin struct {
+	tokens  []persistedToken
+	lastUse uint64
+}
+
+type persistedSession struct {
+	ticket  []byte
+	state   []byte
+	expiry  time.Time
+	lastUse uint64
+}
+
+// A PersistentStore stores address validation tokens and TLS session tickets,
+// such that they survive a restart of the process.
+// This allows clients to skip address validation and to use 0-RTT on connections
+// established after a restart.
+//
+// The store is serialized as an append-only log of modifications.
+// Stored TLS sessions include the QUIC transport parameters that are required for 0-RTT.
+// Tokens and sessions are discarded after they expire,
+// and the oldest origins are evicted when the configured number of origins is exceeded.
+//
+// It is safe to use a PersistentStore from multiple goroutines concurrently.
+type PersistentStore struct {
+	mutex sync.Mutex
+
+	w    io.Writer
+	file *os.File // only set for stores opened with OpenPersistentStore
+	path string   // only set for stores opened with OpenPersistentStore
+	err  error    // the first error that occurred when writing to w
+
+	// the number of bytes written to the log, and the size of the log after the last compaction
+	logSize       int
+	compactedSize int
+
+	maxOrigins      int
+	tokensPerOrigin int
+
+	counter  uint64
+	tokens   map[string]*persistedTokenOrigin
+	sessions map[string]*persistedSession
+}
+
+// NewPersistentStore creates a new PersistentStore.
+// The current state is read from rw. Modifications are appended to rw.
+// If rw is empty, a new store is initialized.
+// Since rw can't be rewritten, the log is never compacted.
+// maxOrigins specifies how many origins tokens and sessions are saved for.
+// tokensPerOrigin specifies the maximum number of tokens per origin.
+// Both values must be positive.
+func NewPersistentStore(rw io.ReadWriter, maxOrigins, tokensPerOrigin int) (*PersistentStore, error) {
+	s, err := newPersistentStore(maxOrigins, tokensPerOrigin)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(rw)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		if _, err := rw.Write(appendPersistentStoreHeader(nil)); err != nil {
+			return nil, err
+		}
+	} else if err := s.load(data); err != nil {
+		return nil, err
+	}
+	s.w = rw
+	return s, nil
+}
+
+// OpenPersistentStore opens the file-backed PersistentStore at path.
+// The file is created if it doesn't exist yet.
+// When opening the store, expired entries are removed and the file is compacted.
+// The file is compacted again once the log of modifications grows large.
+// maxOrigins specifies how many origins tokens and sessions are saved for.
+// tokensPerOrigin specifies the maximum number of tokens per origin.
+// Both values must be positive.
+func OpenPersistentStore(path string, maxOrigins, tokensPerOrigin int) (*PersistentStore, error) {
+	s, err := newPersistentStore(maxOrigins, tokensPerOrigin)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := s.load(data); err != nil {
+			return nil, fmt.Errorf("loading %s: %w", path, err)
+		}
+	}
+	s.path = path
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func newPersistentStore(maxOrigins, tokensPerOrigin int) (*PersistentStore, error) {
+	if maxOrigins <= 0 {
+		return nil, fmt.Errorf("invalid number of origins: %d", maxOrigins)
+	}
+	if tokensPerOrigin <= 0 {
+		return nil, fmt.Errorf("invalid number of tokens per origin: %d", tokensPerOrigin)
+	}
+	return &PersistentStore{
+		maxOrigins:      maxOrigins,
+		tokensPerOrigin: tokensPerOrigin,
+		tokens:          make(map[string]*persistedTokenOrigin),
+		sessions:        make(map[string]*persistedSession),
+	}, nil
+}
+
+// compact writes the current state of the store to a temporary file,
+// and atomically replaces the file at s.path.
+// It must be called with the mutex held (or before the store is returned to the user).
+func (s *PersistentStore) compact() error {
+	s.removeExpired(time.Now())
+	snapshot := s.snapshot()
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(snapshot); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.w = f
+	s.file = f
+	s.logSize = len(snapshot)
+	s.compactedSize = len(snapshot)
+	return nil
+}
+
+func appendPersistentStoreHeader(b []byte) []byte {
+	b = append(b, persistentStoreMagic...)
+	return quicvarint.Append(b, persistentStoreVersion)
+}
+
+func (s *PersistentStore) load(data []byte) error {
+	if !bytes.HasPrefix(data, persistentStoreMagic) {
+		return errors.New("not a quic-go store")
+	}
+	data = data[len(persistentStoreMagic):]
+	v, l, err := quicvarint.Parse(data)
+	if err != nil {
+		return err
+	}
+	if v != persistentStoreVersion {
+		return fmt.Errorf("unsupported store version: %d", v)
+	}
+	data = data[l:]
+	for len(data) > 0 {
+		body, rest, err := readLengthPrefixed(data)
+		if err != nil {
+			// The last record might have been written partially, e.g. if the process crashed.
+			break
+		}
+		data = rest
+		var r persistentRecord
+		if err := r.parse(body); err != nil {
+			return err
+		}
+		s.apply(&r)
+	}
+	s.removeExpired(time.Now())
+	return nil
+}
+
+// snapshot serializes the current state of the store
+func (s *PersistentStore) snapshot() []byte {
+	b := appendPersistentStoreHeader(nil)
+	// Write origins in the order they were used, such that the eviction order is preserved.
+	type entry struct {
+		lastUse uint64
+		records []persistentRecord
+	}
+	entries := make([]entry, 0, len(s.tokens)+len(s.sessions))
+	for key, origin := range s.tokens {
+		e := entry{lastUse: origin.lastUse}
+		// tokens are popped in LIFO order, so they need to be added in the same order
+		for _, t := range origin.tokens {
+			e.records = append(e.records, persistentRecord{
+				typ:    recordTypeTokenPut,
+				key:    key,
+				expiry: t.expiry,
+				token:  t.data,
+				rtt:    t.rtt,
+			})
+		}
+		entries = append(entries, e)
+	}
+	for key, sess := range s.sessions {
+		entries = append(entries, entry{
+			lastUse: sess.lastUse,
+			records: []persistentRecord{{
+				typ:    recordTypeSessionPut,
+				key:    key,
+				expiry: sess.expiry,
+				ticket: sess.ticket,
+				state:  sess.state,
+			}},
+		})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return cmp.Compare(a.lastUse, b.lastUse) })
+	for _, e := range entries {
+		for _, r := range e.records {
+			b = r.append(b)
+		}
+	}
+	return b
+}
+
+func (s *PersistentStore) apply(r *persistentRecord) {
+	s.counter++
+	switch r.typ {
+	case recordTypeTokenPut:
+		origin, ok := s.tokens[r.key]
+		if !ok {
+			if len(s.tokens) >= s.maxOrigins {
+				s.evictTokenOrigin()
+			}
+			origin = &persistedTokenOrigin{}
+			s.tokens[r.key] = origin
+		}
+		origin.lastUse = s.counter
+		if len(origin.tokens) >= s.tokensPerOrigin {
+			origin.tokens = origin.tokens[1:]
+		}
+		origin.tokens = append(origin.tokens, persistedToken{data: r.token, rtt: r.rtt, expiry: r.expiry})
+	case recordTypeTokenPop:
+		origin, ok := s.tokens[r.key]
+		if !ok {
+			return
+		}
+		origin.lastUse = s.counter
+		origin.tokens = origin.tokens[:len(origin.tokens)-1]
+		if len(origin.tokens) == 0 {
+			delete(s.tokens, r.key)
+		}
+	case recordTypeSessionPut:
+		if _, ok := s.sessions[r.key]; !ok && len(s.sessions) >= s.maxOrigins {
+			s.evictSession()
+		}
+		s.sessions[r.key] = &persistedSession{
+			ticket:  r.ticket,
+			state:   r.state,
+			expiry:  r.expiry,
+			lastUse: s.counter,
+		}
+	case recordTypeSessionDelete:
+		delete(s.sessions, r.key)
+	}
+}
+
+func (s *PersistentStore) evictTokenOrigin() {
+	var oldestKey string
+	oldest := uint64(math.MaxUint64)
+	for key, origin := range s.tokens {
+		if origin.lastUse < oldest {
+			oldest = origin.lastUse
+			oldestKey = key
+		}
+	}
+	delete(s.tokens, oldestKey)
+}
+
+func (s *PersistentStore) evictSession() {
+	var oldestKey string
+	oldest := uint64(math.MaxUint64)
+	for key, sess := range s.sessions {
+		if sess.lastUse < oldest {
+			oldest = sess.lastUse
+			oldestKey = key
+		}
+	}
+	delete(s.sessions, oldestKey)
+}
+
+func (s *PersistentStore) removeExpired(now time.Time) {
+	for key, origin := range s.tokens {
+		origin.tokens = slices.DeleteFunc(origin.tokens, func(t persistedToken) bool { return !now.Before(t.expiry) })
+		if len(origin.tokens) == 0 {
+			delete(s.tokens, key)
+		}
+	}
+	for key, sess := range s.sessions {
+		if !now.Before(sess.expiry) {
+			delete(s.sessions, key)
+		}
+	}
+}
+
+// record applies a modification and appends it to the log.
+// It must be called with the mutex held.
+func (s *PersistentStore) record(r *persistentRecord) {
+	s.apply(r)
+	if s.err != nil {
+		return
+	}
+	n, err := s.w.Write(r.append(nil))
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.logSize += n
+	if s.path != "" && s.logSize >= max(persistentStoreCompactionThreshold, 2*s.compactedSize) {
+		if err := s.compact(); err != nil {
+			s.err = err
+		}
+	}
+}
+
+// TokenStore returns a TokenStore that is backed by this PersistentStore.
+func (s *PersistentStore) TokenStore() TokenStore {
+	return (*persistentTokenStore)(s)
+}
+
+// ClientSessionCache returns a tls.ClientSessionCache that is backed by this PersistentStore.
+func (s *PersistentStore) ClientSessionCache() tls.ClientSessionCache {
+	return (*persistentSessionCache)(s)
+}
+
+// Close closes the underlying file, if the store was opened using OpenPersistentStore.
+// It returns the first error that occurred when persisting modifications.
+func (s *PersistentStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.err
+	if s.file != nil {
+		if cerr := s.file.Close(); err == nil {
+			err = cerr
+		}
+		s.file = nil
+	}
+	if s.err == nil {
+		s.err = errors.New("store closed")
+	}
+	return err
+}
+
+type persistentTokenStore PersistentStore
+
+var _ TokenStore = &persistentTokenStore{}
+
+func (s *persistentTokenStore) Put(key string, token *ClientToken) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	(*PersistentStore)(s).record(&persistentRecord{
+		typ:    recordTypeTokenPut,
+		key:    key,
+		expiry: time.Now().Add(persistentTokenMaxAge),
+		token:  token.data,
+		rtt:    token.rtt,
+	})
+}
+
+func (s *persistentTokenStore) Pop(key string) *ClientToken {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for {
+		origin, ok := s.tokens[key]
+		if !ok {
+			return nil
+		}
+		t := origin.tokens[len(origin.tokens)-1]
+		(*PersistentStore)(s).record(&persistentRecord{typ: recordTypeTokenPop, key: key})
+		if now.Before(t.expiry) {
+			return &ClientToken{data: t.data, rtt: t.rtt}
+		}
+	}
+}
+
+type persistentSessionCache PersistentStore
+
+var _ tls.ClientSessionCache = &persistentSessionCache{}
+
+func (s *persistentSessionCache) Get(key string) (*tls.ClientSessionState, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sess, ok := s.sessions[key]
+	if !ok || !time.Now().Before(sess.expiry) {
+		return nil, false
+	}
+	state, err := tls.ParseSessionState(sess.state)
+	if err != nil {
+		return nil, false
+	}
+	cs, err := tls.NewResumptionState(sess.ticket, state)
+	if err != nil {
+		return nil, false
+	}
+	return cs, true
+}
+
+func (s *persistentSessionCache) Put(key string, cs *tls.ClientSessionState) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if cs == nil {
+		if _, ok := s.sessions[key]; ok {
+			(*PersistentStore)(s).record(&persistentRecord{typ: recordTypeSessionDelete, key: key})
+		}
+		return
+	}
+	ticket, state, err := cs.ResumptionState()
+	if err != nil || state == nil {
+		return
+	}
+	// The session state contains the QUIC transport parameters that are needed for 0-RTT.
+	stateBytes, err := state.Bytes()
+	if err != nil {
+		return
+	}
+	(*PersistentStore)(s).record(&persistentRecord{
+		typ:    recordTypeSessionPut,
+		key:    key,
+		expiry: time.Now().Add(persistentSessionMaxAge),
+		ticket: ticket,
+		state:  stateBytes,
+	})
+}
@@ -0,0 +1,248 @@
+package quic
+
+import (
+	"bytes"
+	"fmt"
+	tls "github.com/nukilabs/utls"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/synctest"
+	"github.com/nukilabs/quic-go/internal/testdata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistentStoreTokens(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewPersistentStore(&buf, 3, 2)
+	require.NoError(t, err)
+	ts := s.TokenStore()
+	ts.Put("host1", mockToken(1))
+	ts.Put("host1", mockToken(2))
+	ts.Put("host1", mockToken(3)) // evicts token 1
+	ts.Put("host2", mockToken(4))
+	require.Equal(t, mockToken(3), ts.Pop("host1"))
+
+	// restore the store from the serialized log
+	s2, err := NewPersistentStore(bytes.NewBuffer(buf.Bytes()), 3, 2)
+	require.NoError(t, err)
+	ts2 := s2.TokenStore()
+	require.Equal(t, mockToken(2), ts2.Pop("host1"))
+	require.Nil(t, ts2.Pop("host1"))
+	require.Equal(t, mockToken(4), ts2.Pop("host2"))
+	require.Nil(t, ts2.Pop("host2"))
+}
+
+func TestPersistentStoreOriginEviction(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewPersistentStore(&buf, 2, 4)
+	require.NoError(t, err)
+	ts := s.TokenStore()
+	ts.Put("host1", mockToken(1))
+	ts.Put("host2", mockToken(2))
+	ts.Put("host1", mockToken(11))
+	ts.Put("host3", mockToken(3)) // evicts host2
+
+	s2, err := NewPersistentStore(bytes.NewBuffer(buf.Bytes()), 2, 4)
+	require.NoError(t, err)
+	for _, ts := range []TokenStore{ts, s2.TokenStore()} {
+		require.Nil(t, ts.Pop("host2"))
+		require.Equal(t, mockToken(11), ts.Pop("host1"))
+		require.Equal(t, mockToken(1), ts.Pop("host1"))
+		require.Equal(t, mockToken(3), ts.Pop("host3"))
+	}
+}
+
+func TestPersistentStoreTokenExpiry(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		s, err := NewPersistentStore(&buf, 10, 10)
+		require.NoError(t, err)
+		ts := s.TokenStore()
+		ts.Put("host", mockToken(1))
+		time.Sleep(persistentTokenMaxAge / 2)
+		ts.Put("host", mockToken(2))
+		ts.Put("host", mockToken(3))
+		time.Sleep(persistentTokenMaxAge/2 + time.Second)
+		require.Equal(t, mockToken(3), ts.Pop("host"))
+
+		// expired tokens are dropped when loading the store
+		s2, err := NewPersistentStore(bytes.NewBuffer(buf.Bytes()), 10, 10)
+		require.NoError(t, err)
+		require.Equal(t, mockToken(2), s2.TokenStore().Pop("host"))
+		require.Nil(t, s2.TokenStore().Pop("host"))
+
+		// expired tokens are skipped
+		time.Sleep(persistentTokenMaxAge)
+		require.Nil(t, ts.Pop("host"))
+	})
+}
+
+func TestPersistentStoreFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+	s, err := OpenPersistentStore(path, 10, 10)
+	require.NoError(t, err)
+	for i := range 5 {
+		s.TokenStore().Put("host", mockToken(i))
+	}
+	require.Equal(t, mockToken(4), s.TokenStore().Pop("host"))
+	require.NoError(t, s.Close())
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	sizeBefore := fi.Size()
+
+	// opening the store compacts the file
+	s, err = OpenPersistentStore(path, 10, 10)
+	require.NoError(t, err)
+	defer s.Close()
+	fi, err = os.Stat(path)
+	require.NoError(t, err)
+	require.Less(t, fi.Size(), sizeBefore)
+	require.Equal(t, mockToken(3), s.TokenStore().Pop("host"))
+	require.Equal(t, mockToken(2), s.TokenStore().Pop("host"))
+}
+
+func TestPersistentStoreFileCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store")
+	s, err := OpenPersistentStore(path, 10, 10)
+	require.NoError(t, err)
+	defer s.Close()
+	ts := s.TokenStore()
+	ts.Put("host", mockToken(1))
+	token := &ClientToken{data: make([]byte, 1000)}
+	for range 2 * persistentStoreCompactionThreshold / len(token.data) {
+		ts.Put("host", token)
+		require.Equal(t, token, ts.Pop("host"))
+	}
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Less(t, fi.Size(), int64(persistentStoreCompactionThreshold))
+
+	// modifications after the compaction are persisted
+	ts.Put("host", mockToken(2))
+	require.NoError(t, s.Close())
+	s, err = OpenPersistentStore(path, 10, 10)
+	require.NoError(t, err)
+	require.Equal(t, mockToken(2), s.TokenStore().Pop("host"))
+	require.Equal(t, mockToken(1), s.TokenStore().Pop("host"))
+	require.Nil(t, s.TokenStore().Pop("host"))
+}
+
+func TestPersistentStoreTruncatedRecord(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewPersistentStore(&buf, 10, 10)
+	require.NoError(t, err)
+	s.TokenStore().Put("host", mockToken(1))
+	s.TokenStore().Put("host", mockToken(2))
+
+	// simulate a crash while writing the last record
+	data := buf.Bytes()[:buf.Len()-3]
+	s2, err := NewPersistentStore(bytes.NewBuffer(data), 10, 10)
+	require.NoError(t, err)
+	require.Equal(t, mockToken(1), s2.TokenStore().Pop("host"))
+	require.Nil(t, s2.TokenStore().Pop("host"))
+}
+
+func TestPersistentStoreInvalidData(t *testing.T) {
+	_, err := NewPersistentStore(bytes.NewBufferString("foobar"), 10, 10)
+	require.EqualError(t, err, "not a quic-go store")
+
+	path := filepath.Join(t.TempDir(), "store")
+	require.NoError(t, os.WriteFile(path, []byte("foobar"), 0o600))
+	_, err = OpenPersistentStore(path, 10, 10)
+	require.ErrorContains(t, err, "not a quic-go store")
+}
+
+func TestPersistentStoreConcurrentUse(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewPersistentStore(&buf, 100, 100)
+	require.NoError(t, err)
+	ts := s.TokenStore()
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("host%d", i)
+			for j := range 50 {
+				ts.Put(key, mockToken(j))
+				if j%2 == 0 {
+					ts.Pop(key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	s2, err := NewPersistentStore(bytes.NewBuffer(buf.Bytes()), 100, 100)
+	require.NoError(t, err)
+	for i := range 10 {
+		key := fmt.Sprintf("host%d", i)
+		for range 25 {
+			require.Equal(t, ts.Pop(key), s2.TokenStore().Pop(key))
+		}
+		require.Nil(t, s2.TokenStore().Pop(key))
+	}
+}
+
+func TestPersistentStoreInvalidLimits(t *testing.T) {
+	_, err := NewPersistentStore(&bytes.Buffer{}, 0, 10)
+	require.EqualError(t, err, "invalid number of origins: 0")
+	_, err = NewPersistentStore(&bytes.Buffer{}, 10, -1)
+	require.EqualError(t, err, "invalid number of tokens per origin: -1")
+
+	path := filepath.Join(t.TempDir(), "store")
+	_, err = OpenPersistentStore(path, 10, 0)
+	require.EqualError(t, err, "invalid number of tokens per origin: 0")
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestPersistentStoreSessionExpiry(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var buf bytes.Buffer
+		s, err := NewPersistentStore(&buf, 10, 10)
+		require.NoError(t, err)
+		cache := s.ClientSessionCache()
+
+		// perform a TLS handshake, such that the server issues a session ticket
+		clientConn, serverConn := net.Pipe()
+		defer clientConn.Close()
+		go func() {
+			defer serverConn.Close()
+			conn := tls.Server(serverConn, testdata.GetTLSConfig())
+			if err := conn.Handshake(); err != nil {
+				return
+			}
+			conn.Write([]byte("foobar"))
+		}()
+		conn := tls.Client(clientConn, &tls.Config{
+			ServerName:         "localhost",
+			InsecureSkipVerify: true, // the certificate is not valid at the time of the synctest bubble
+			ClientSessionCache: cache,
+		})
+		// the session ticket is processed when reading application data
+		_, err = io.ReadFull(conn, make([]byte, 6))
+		require.NoError(t, err)
+		require.Len(t, s.sessions, 1)
+		var key string
+		for k := range s.sessions {
+			key = k
+		}
+
+		time.Sleep(persistentSessionMaxAge - time.Second)
+		_, ok := cache.Get(key)
+		require.True(t, ok)
+		time.Sleep(2 * time.Second)
+		_, ok = cache.Get(key)
+		require.False(t, ok)
+	})
+}
@@ -19,20 +19,37 @@ func nopWriteCloser(w io.Writer) io.WriteCloser {
 	return &nopWriteCloserImpl{Writer: w}
 }
 
+type countingWriter struct{ n int }
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += len(b)
+	return len(b), nil
+}
+
 // BenchmarkConnectionTracing aims to benchmark a somewhat realistic connection that sends and receives packets.
+// It compares the JSON-SEQ encoding with the binary encoding.
 func BenchmarkConnectionTracing(b *testing.B) {
+	odcid := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})
+
+	b.Run("json", func(b *testing.B) {
+		w := &countingWriter{}
+		trace := qlogwriter.NewConnectionFileSeq(nopWriteCloser(w), false, odcid, []string{EventSchema})
+		go trace.Run()
+		benchmarkConnectionTracing(b, trace, w)
+	})
+
+	b.Run("binary", func(b *testing.B) {
+		w := &countingWriter{}
+		trace := qlogwriter.NewConnectionBinaryFileSeq(nopWriteCloser(w), false, odcid, []string{EventSchema})
+		benchmarkConnectionTracing(b, trace, w)
+	})
+}
+
+func benchmarkConnectionTracing(b *testing.B, trace qlogwriter.Trace, w *countingWriter) {
 	b.ReportAllocs()
 
 	srcConnID := protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad})
-	trace := qlogwriter.NewConnectionFileSeq(
-		nopWriteCloser(io.Discard),
-		false,
-		protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
-		[]string{EventSchema},
-	)
-	go trace.Run()
 	tracer := trace.AddProducer()
-	b.Cleanup(func() { tracer.Close() })
 
 	rttStats := utils.NewRTTStats()
 	rttStats.UpdateRTT(1337*time.Millisecond, 0)
@@ -83,4 +100,7 @@ func BenchmarkConnectionTracing(b *testing.B) {
 			})
 		}
 	}
+	// closing the tracer flushes all events
+	tracer.Close()
+	b.ReportMetric(float64(w.n)/float64(b.N), "B/op(written)")
 }
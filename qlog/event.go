@@ -186,6 +186,8 @@ func (e ConnectionClosed) Encode(enc *jsontext.Encoder, _ time.Time) error {
 				h.WriteToken(jsontext.String("aead_limit_reached"))
 			case qerr.NoViablePathError:
 				h.WriteToken(jsontext.String("no_viable_path"))
+			case qerr.VersionNegotiationErrorCode:
+				h.WriteToken(jsontext.String("version_negotiation_error"))
 			default:
 				h.WriteToken(jsontext.String("unknown"))
 				h.WriteToken(jsontext.String("error_code"))
@@ -414,6 +416,39 @@ func (e PacketDropped) Encode(enc *jsontext.Encoder, _ time.Time) error {
 	return h.err
 }
 
+// ConnectionAdmission is emitted by the server when a new connection attempt is not accepted right away.
+// This is a quic-go specific event.
+type ConnectionAdmission struct {
+	Remote             PathEndpointInfo
+	DestConnectionID   ConnectionID
+	Action             AdmissionAction
+	Reason             string
+	HandshakesInFlight int
+}
+
+func (e ConnectionAdmission) Name() string { return "transport:connection_admission" }
+
+func (e ConnectionAdmission) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	h.WriteToken(jsontext.String("remote"))
+	if err := e.Remote.encode(enc); err != nil {
+		return err
+	}
+	h.WriteToken(jsontext.String("dcid"))
+	h.WriteToken(jsontext.String(e.DestConnectionID.String()))
+	h.WriteToken(jsontext.String("action"))
+	h.WriteToken(jsontext.String(string(e.Action)))
+	if e.Reason != "" {
+		h.WriteToken(jsontext.String("reason"))
+		h.WriteToken(jsontext.String(e.Reason))
+	}
+	h.WriteToken(jsontext.String("handshakes_in_flight"))
+	h.WriteToken(jsontext.Int(int64(e.HandshakesInFlight)))
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
+
 type MTUUpdated struct {
 	Value int
 	Done  bool
@@ -544,7 +579,9 @@ func (e SpuriousLoss) Encode(enc *jsontext.Encoder, _ time.Time) error {
 }
 
 type KeyUpdated struct {
-	Trigger  KeyUpdateTrigger
+	Trigger KeyUpdateTrigger
+	// Reason is only set for key updates triggered locally.
+	Reason   KeyUpdateReason
 	KeyType  KeyType
 	KeyPhase KeyPhase // only set for 1-RTT keys
 	// we don't log the keys here, so we don't need `old` and `new`.
@@ -557,6 +594,10 @@ func (e KeyUpdated) Encode(enc *jsontext.Encoder, _ time.Time) error {
 	h.WriteToken(jsontext.BeginObject)
 	h.WriteToken(jsontext.String("trigger"))
 	h.WriteToken(jsontext.String(string(e.Trigger)))
+	if e.Reason != "" {
+		h.WriteToken(jsontext.String("reason"))
+		h.WriteToken(jsontext.String(string(e.Reason)))
+	}
 	h.WriteToken(jsontext.String("key_type"))
 	h.WriteToken(jsontext.String(string(e.KeyType)))
 	if e.KeyType == KeyTypeClient1RTT || e.KeyType == KeyTypeServer1RTT {
@@ -177,6 +177,7 @@ func TestTransportErrors(t *testing.T) {
 		{qerr.KeyUpdateError, "key_update_error"},
 		{qerr.AEADLimitReached, "aead_limit_reached"},
 		{qerr.NoViablePathError, "no_viable_path"},
+		{qerr.VersionNegotiationErrorCode, "version_negotiation_error"},
 	}
 
 	for _, tt := range tests {
@@ -638,6 +639,23 @@ func TestPacketDropped(t *testing.T) {
 	require.Equal(t, "payload_decrypt_error", ev["trigger"])
 }
 
+func TestConnectionAdmission(t *testing.T) {
+	name, ev := testEventEncoding(t, &ConnectionAdmission{
+		Remote:             PathEndpointInfo{IPv4: netip.MustParseAddrPort("192.168.0.1:1337")},
+		DestConnectionID:   protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+		Action:             AdmissionActionRefuse,
+		Reason:             "rate limited",
+		HandshakesInFlight: 42,
+	})
+
+	require.Equal(t, "transport:connection_admission", name)
+	require.Equal(t, map[string]any{"ip_v4": "192.168.0.1", "port_v4": float64(1337)}, ev["remote"])
+	require.Equal(t, "deadbeef", ev["dcid"])
+	require.Equal(t, "refuse", ev["action"])
+	require.Equal(t, "rate limited", ev["reason"])
+	require.Equal(t, float64(42), ev["handshakes_in_flight"])
+}
+
 func TestMetricsUpdated(t *testing.T) {
 	rttStats := utils.NewRTTStats()
 	rttStats.UpdateRTT(15*time.Millisecond, 0)
@@ -760,10 +778,26 @@ func TestKeyUpdated(t *testing.T) {
 	require.Equal(t, "security:key_updated", name)
 	require.Equal(t, float64(1337), ev["key_phase"])
 	require.Equal(t, "remote_update", ev["trigger"])
+	require.NotContains(t, ev, "reason")
 	require.Contains(t, ev, "key_type")
 	require.Equal(t, "client_1rtt_secret", ev["key_type"])
 }
 
+func TestKeyUpdatedLocal(t *testing.T) {
+	name, ev := testEventEncoding(t, &KeyUpdated{
+		Trigger:  KeyUpdateLocal,
+		Reason:   KeyUpdateReasonByteLimit,
+		KeyType:  KeyTypeServer1RTT,
+		KeyPhase: 42,
+	})
+
+	require.Equal(t, "security:key_updated", name)
+	require.Equal(t, float64(42), ev["key_phase"])
+	require.Equal(t, "local_update", ev["trigger"])
+	require.Equal(t, "byte_limit", ev["reason"])
+	require.Equal(t, "server_1rtt_secret", ev["key_type"])
+}
+
 func TestKeyDiscarded0RTT(t *testing.T) {
 	name, ev := testEventEncoding(t, &KeyDiscarded{
 		KeyType:  KeyTypeServer0RTT,
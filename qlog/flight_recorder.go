@@ -0,0 +1,34 @@
+package qlog
+
+import (
+	"github.com/nukilabs/quic-go/internal/qerr"
+	"github.com/nukilabs/quic-go/qlogwriter"
+)
+
+// ConnectionFailed reports whether the event is a ConnectionClosed event of a connection that failed:
+// The connection was closed with a transport error (other than NO_ERROR), it timed out,
+// it was reset statelessly, or version negotiation failed.
+//
+// Connections closed by the application are not considered failed, since the meaning of the
+// application error code is defined by the application protocol (for HTTP/3, H3_NO_ERROR is 0x100).
+//
+// It can be used as the qlogwriter.FlightRecorderConfig.ShouldFlush.
+func ConnectionFailed(ev qlogwriter.Event) bool {
+	var e ConnectionClosed
+	switch ev := ev.(type) {
+	case ConnectionClosed:
+		e = ev
+	case *ConnectionClosed:
+		e = *ev
+	default:
+		return false
+	}
+	if e.ConnectionError != nil {
+		return *e.ConnectionError != qerr.NoError
+	}
+	switch e.Trigger {
+	case ConnectionCloseTriggerIdleTimeout, ConnectionCloseTriggerStatelessReset, ConnectionCloseTriggerVersionMismatch:
+		return true
+	}
+	return false
+}
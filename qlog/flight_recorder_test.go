@@ -0,0 +1,35 @@
+package qlog
+
+import (
+	"testing"
+
+	"github.com/nukilabs/quic-go/internal/qerr"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionFailed(t *testing.T) {
+	noError := qerr.NoError
+	protocolViolation := qerr.ProtocolViolation
+	appErr := qerr.ApplicationErrorCode(1337)
+
+	for _, tc := range []struct {
+		name   string
+		ev     ConnectionClosed
+		failed bool
+	}{
+		{name: "NO_ERROR", ev: ConnectionClosed{ConnectionError: &noError}, failed: false},
+		{name: "transport error", ev: ConnectionClosed{ConnectionError: &protocolViolation}, failed: true},
+		{name: "application error", ev: ConnectionClosed{ApplicationError: &appErr, Trigger: ConnectionCloseTriggerApplication}, failed: false},
+		{name: "idle timeout", ev: ConnectionClosed{Trigger: ConnectionCloseTriggerIdleTimeout}, failed: true},
+		{name: "stateless reset", ev: ConnectionClosed{Trigger: ConnectionCloseTriggerStatelessReset}, failed: true},
+		{name: "version mismatch", ev: ConnectionClosed{Trigger: ConnectionCloseTriggerVersionMismatch}, failed: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.failed, ConnectionFailed(tc.ev))
+			require.Equal(t, tc.failed, ConnectionFailed(&tc.ev))
+		})
+	}
+
+	require.False(t, ConnectionFailed(VersionInformation{}))
+}
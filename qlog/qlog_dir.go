@@ -19,6 +19,7 @@ const EventSchema = "urn:ietf:params:qlog:events:quic-12"
 // DefaultConnectionTracer creates a qlog file in the qlog directory specified by the QLOGDIR environment variable.
 // File names are <odcid>_<perspective>.sqlog.
 // Returns nil if QLOGDIR is not set.
+// NewConnectionTracer allows configuring sampling, event filtering, compression and size limits.
 func DefaultConnectionTracer(_ context.Context, isClient bool, connID ConnectionID) qlogwriter.Trace {
 	return defaultConnectionTracerWithSchemas(isClient, connID, []string{EventSchema})
 }
@@ -37,7 +38,8 @@ func defaultConnectionTracerWithSchemas(isClient bool, connID ConnectionID, even
 	}
 	if _, err := os.Stat(qlogDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(qlogDir, 0o755); err != nil {
-			log.Fatalf("failed to create qlog dir %s: %v", qlogDir, err)
+			log.Printf("Failed to create qlog dir %s: %s", qlogDir, err.Error())
+			return nil
 		}
 	}
 	label := "server"
@@ -0,0 +1,423 @@
+package qlog
+
+import (
+	"bufio"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/nukilabs/quic-go/qlogwriter"
+)
+
+// Compression is the compression algorithm applied to qlog files.
+type Compression uint8
+
+const (
+	// CompressionNone writes uncompressed .sqlog files.
+	CompressionNone Compression = iota
+	// CompressionGzip writes gzip-compressed .sqlog.gz files.
+	CompressionGzip
+	// CompressionZstd writes zstd-compressed .sqlog.zst files.
+	CompressionZstd
+)
+
+func (c Compression) extension() string {
+	switch c {
+	case CompressionGzip:
+		return ".sqlog.gz"
+	case CompressionZstd:
+		return ".sqlog.zst"
+	default:
+		return ".sqlog"
+	}
+}
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown compression (%d)", uint8(c))
+	}
+}
+
+// A TracerConfig configures the connection tracer created by NewConnectionTracer.
+type TracerConfig struct {
+	// Dir is the directory the qlog files are written to.
+	// It is created if it doesn't exist.
+	// If empty, the directory specified by the QLOGDIR environment variable is used.
+	// If neither is set, no connections are traced.
+	Dir string
+	// EventSchemas are the event schemas announced in the trace header, in addition to EventSchema.
+	EventSchemas []string
+	// SampleRate is the fraction of connections that are traced, between 0 and 1.
+	// If nil, all connections are traced.
+	SampleRate *float64
+	// IncludeEvents is a list of event names, e.g. "transport:packet_sent".
+	// A name ending in ":*" matches all events of a category, e.g. "recovery:*".
+	// If set, only events matching an entry of the list are recorded.
+	IncludeEvents []string
+	// ExcludeEvents is a list of event names, using the same syntax as IncludeEvents.
+	// Events matching an entry of the list are not recorded.
+	ExcludeEvents []string
+	// Compression is the compression algorithm applied to the qlog files.
+	Compression Compression
+	// MaxFileSize is the maximum number of bytes of (uncompressed) qlog data written to a qlog file.
+	// When writing the next record would exceed the limit, the file is rotated:
+	// it is renamed to <odcid>_<perspective>.1.sqlog (replacing the previously rotated file),
+	// and a new file starting with the trace header is created.
+	// This way, the most recent events of a connection are always kept,
+	// and at most twice the limit is used per connection.
+	// Every file contains a whole number of records, and can only exceed the limit if a single record does.
+	// If zero, the size of the files is not limited.
+	MaxFileSize int64
+	// MaxDirSize is the maximum total size of the qlog files in Dir.
+	// Whenever a trace is closed, the oldest qlog files are removed until the total size
+	// is below the limit. Files that are still being written are not taken into account.
+	// If zero, no files are removed.
+	MaxDirSize int64
+	// ErrorHandler is called when creating, writing or removing a qlog file fails.
+	// It may be called concurrently from multiple goroutines.
+	// If nil, errors are logged using the log package.
+	ErrorHandler func(error)
+}
+
+// NewConnectionTracer creates a connection tracer that can be used as quic.Config.Tracer.
+// File names are <odcid>_<perspective>.sqlog, followed by the extension of the compression algorithm.
+// Errors are reported to the ErrorHandler, they never terminate the process.
+func NewConnectionTracer(conf *TracerConfig) func(context.Context, bool, ConnectionID) qlogwriter.Trace {
+	t := &tracer{conf: *conf}
+	if !slices.Contains(t.conf.EventSchemas, EventSchema) {
+		t.conf.EventSchemas = append([]string{EventSchema}, t.conf.EventSchemas...)
+	}
+	if t.conf.ErrorHandler == nil {
+		t.conf.ErrorHandler = func(err error) { log.Printf("qlog: %s", err) }
+	}
+	if t.conf.Dir == "" {
+		t.conf.Dir = os.Getenv("QLOGDIR")
+	}
+	if t.conf.Dir != "" && t.conf.MaxDirSize > 0 {
+		t.scanDir()
+	}
+	return t.newTrace
+}
+
+type qlogFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+type tracer struct {
+	conf TracerConfig
+
+	mx sync.Mutex
+	// files is the list of closed qlog files in the directory, oldest first.
+	// It is only maintained if MaxDirSize is set.
+	files   []qlogFile
+	dirSize int64
+}
+
+func (t *tracer) newTrace(_ context.Context, isClient bool, connID ConnectionID) qlogwriter.Trace {
+	if t.conf.Dir == "" {
+		return nil
+	}
+	if t.conf.SampleRate != nil && rand.Float64() >= *t.conf.SampleRate {
+		return nil
+	}
+	if err := os.MkdirAll(t.conf.Dir, 0o755); err != nil {
+		t.conf.ErrorHandler(fmt.Errorf("failed to create qlog dir %s: %w", t.conf.Dir, err))
+		return nil
+	}
+	label := "server"
+	if isClient {
+		label = "client"
+	}
+	ext := t.conf.Compression.extension()
+	name := fmt.Sprintf("%s_%s", connID, label)
+	w := &fileWriter{
+		path:        filepath.Join(t.conf.Dir, name+ext),
+		rotatedPath: filepath.Join(t.conf.Dir, name+".1"+ext),
+		compression: t.conf.Compression,
+		maxSize:     t.conf.MaxFileSize,
+		rotatedSize: -1,
+		onError:     t.conf.ErrorHandler,
+		onClose:     t.fileClosed,
+	}
+	if err := w.open(); err != nil {
+		t.conf.ErrorHandler(fmt.Errorf("failed to create qlog file %s: %w", w.path, err))
+		return nil
+	}
+	fileSeq := qlogwriter.NewConnectionFileSeq(w, isClient, connID, t.conf.EventSchemas)
+	go fileSeq.Run()
+	if len(t.conf.IncludeEvents) == 0 && len(t.conf.ExcludeEvents) == 0 {
+		return fileSeq
+	}
+	return &filteredTrace{Trace: fileSeq, include: t.conf.IncludeEvents, exclude: t.conf.ExcludeEvents}
+}
+
+// scanDir adds the qlog files already present in the directory to the list of files,
+// such that they are removed first when the directory grows too large.
+func (t *tracer) scanDir() {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	entries, err := os.ReadDir(t.conf.Dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			t.conf.ErrorHandler(fmt.Errorf("failed to read qlog dir %s: %w", t.conf.Dir, err))
+		}
+		return
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !isQlogFile(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		t.files = append(t.files, qlogFile{
+			path:    filepath.Join(t.conf.Dir, e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		t.dirSize += info.Size()
+	}
+	slices.SortStableFunc(t.files, func(a, b qlogFile) int { return a.modTime.Compare(b.modTime) })
+	t.removeOldFiles()
+}
+
+func isQlogFile(name string) bool {
+	for _, c := range []Compression{CompressionNone, CompressionGzip, CompressionZstd} {
+		if strings.HasSuffix(name, c.extension()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tracer) fileClosed(path string, size int64) {
+	if t.conf.MaxDirSize <= 0 {
+		return
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	t.files = append(t.files, qlogFile{path: path, size: size, modTime: time.Now()})
+	t.dirSize += size
+	t.removeOldFiles()
+}
+
+// removeOldFiles must be called with the mutex held.
+func (t *tracer) removeOldFiles() {
+	for t.dirSize > t.conf.MaxDirSize && len(t.files) > 0 {
+		f := t.files[0]
+		t.files = t.files[1:]
+		t.dirSize -= f.size
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			t.conf.ErrorHandler(fmt.Errorf("failed to remove qlog file %s: %w", f.path, err))
+		}
+	}
+}
+
+// countingFile counts the bytes written to the file.
+type countingFile struct {
+	*os.File
+	n int64
+}
+
+func (f *countingFile) Write(b []byte) (int, error) {
+	n, err := f.File.Write(b)
+	f.n += int64(n)
+	return n, err
+}
+
+// fileWriter is the io.WriteCloser passed to the qlogwriter.FileSeq.
+// It enforces the file size limit and reports errors to the error handler.
+// Errors are never returned to the FileSeq, which would log them.
+type fileWriter struct {
+	path, rotatedPath string
+	compression       Compression
+
+	buf        *bufio.Writer
+	compressor io.WriteCloser // nil if no compression is used
+	file       *countingFile
+
+	// The current record is buffered until it is complete,
+	// such that the size limit can be checked before it is written to the file.
+	record []byte
+	// header is the first record, which is repeated at the beginning of every file
+	header []byte
+
+	maxSize     int64
+	written     int64 // the number of (uncompressed) bytes written to the current file
+	rotatedSize int64 // the size of the rotated file, -1 if the file wasn't rotated yet
+	err         error
+
+	onError func(error)
+	onClose func(path string, size int64)
+}
+
+// open creates a new file at path
+func (w *fileWriter) open() error {
+	f, err := os.Create(w.path)
+	if err != nil {
+		return err
+	}
+	w.file = &countingFile{File: f}
+	w.written = 0
+	switch w.compression {
+	case CompressionNone:
+		w.compressor = nil
+		w.buf = bufio.NewWriter(w.file)
+	case CompressionGzip:
+		w.compressor = gzip.NewWriter(w.file)
+		w.buf = bufio.NewWriter(w.compressor)
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(w.file, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			f.Close()
+			os.Remove(w.path)
+			return err
+		}
+		w.compressor = enc
+		w.buf = bufio.NewWriter(w.compressor)
+	default:
+		f.Close()
+		os.Remove(w.path)
+		return fmt.Errorf("unknown compression: %s", w.compression)
+	}
+	return nil
+}
+
+// closeFile flushes and closes the current file.
+func (w *fileWriter) closeFile() error {
+	err := w.buf.Flush()
+	if w.compressor != nil {
+		err = cmp.Or(err, w.compressor.Close())
+	}
+	return cmp.Or(err, w.file.Close())
+}
+
+// rotate moves the current file to rotatedPath, and starts a new file beginning with the trace header.
+func (w *fileWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	if err := os.Rename(w.path, w.rotatedPath); err != nil {
+		return err
+	}
+	w.rotatedSize = w.file.n
+	if err := w.open(); err != nil {
+		return err
+	}
+	return w.writeRecord(w.header)
+}
+
+func (w *fileWriter) writeRecord(b []byte) error {
+	n, err := w.buf.Write(b)
+	w.written += int64(n)
+	return err
+}
+
+// flushRecord writes the buffered record to the file, rotating the file if necessary.
+func (w *fileWriter) flushRecord() {
+	if len(w.record) == 0 || w.err != nil {
+		return
+	}
+	record := w.record
+	w.record = w.record[:0]
+	if w.header == nil {
+		w.header = slices.Clone(record)
+	} else if w.maxSize > 0 && w.written > int64(len(w.header)) && w.written+int64(len(record)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			w.err = err
+			w.onError(fmt.Errorf("failed to rotate qlog file %s: %w", w.path, err))
+			return
+		}
+	}
+	if err := w.writeRecord(record); err != nil {
+		w.err = err
+		w.onError(fmt.Errorf("failed to write qlog file %s: %w", w.path, err))
+	}
+}
+
+func (w *fileWriter) Write(b []byte) (int, error) {
+	if w.err != nil {
+		return len(b), nil
+	}
+	// Every record starts with the record separator, which can't occur anywhere else in the JSON text.
+	if len(b) > 0 && b[0] == qlogwriter.RecordSeparator {
+		w.flushRecord()
+	}
+	w.record = append(w.record, b...)
+	return len(b), nil
+}
+
+func (w *fileWriter) Close() error {
+	w.flushRecord()
+	if err := w.closeFile(); err != nil && w.err == nil {
+		w.onError(fmt.Errorf("failed to write qlog file %s: %w", w.path, err))
+	}
+	if w.rotatedSize >= 0 {
+		w.onClose(w.rotatedPath, w.rotatedSize)
+	}
+	w.onClose(w.path, w.file.n)
+	return nil
+}
+
+// filteredTrace only passes the events selected by the include and exclude lists
+// to the underlying trace.
+type filteredTrace struct {
+	qlogwriter.Trace
+	include, exclude []string
+}
+
+func (t *filteredTrace) AddProducer() qlogwriter.Recorder {
+	r := t.Trace.AddProducer()
+	if r == nil {
+		return nil
+	}
+	return &filteredRecorder{Recorder: r, trace: t}
+}
+
+func (t *filteredTrace) shouldRecord(name string) bool {
+	if len(t.include) > 0 && !slices.ContainsFunc(t.include, func(p string) bool { return matchEventName(p, name) }) {
+		return false
+	}
+	return !slices.ContainsFunc(t.exclude, func(p string) bool { return matchEventName(p, name) })
+}
+
+func matchEventName(pattern, name string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasSuffix(prefix, ":") {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
+
+type filteredRecorder struct {
+	qlogwriter.Recorder
+	trace *filteredTrace
+}
+
+func (r *filteredRecorder) RecordEvent(ev qlogwriter.Event) {
+	if r.trace.shouldRecord(ev.Name()) {
+		r.Recorder.RecordEvent(ev)
+	}
+}
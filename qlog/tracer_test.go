@@ -0,0 +1,294 @@
+package qlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/qlogwriter"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTracedConnection(t *testing.T, tracer func(context.Context, bool, ConnectionID) qlogwriter.Trace, events ...qlogwriter.Event) bool {
+	t.Helper()
+
+	connID, err := protocol.GenerateConnectionIDForInitial()
+	require.NoError(t, err)
+	trace := tracer(context.Background(), true, connID)
+	if trace == nil {
+		return false
+	}
+	recorder := trace.AddProducer()
+	for _, ev := range events {
+		recorder.RecordEvent(ev)
+	}
+	// closing the last producer closes the file
+	require.NoError(t, recorder.Close())
+	return true
+}
+
+// readRecords returns the event names of all records of a JSON-SEQ file, excluding the header.
+func readRecords(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	records := bytes.Split(data, []byte{qlogwriter.RecordSeparator})
+	require.Empty(t, records[0])
+	require.GreaterOrEqual(t, len(records), 2)
+	var names []string
+	for i, record := range records[1:] {
+		var obj map[string]any
+		require.NoError(t, json.Unmarshal(record, &obj))
+		if i == 0 {
+			require.Contains(t, obj, "trace")
+			continue
+		}
+		names = append(names, obj["name"].(string))
+	}
+	return names
+}
+
+func readSingleFile(t *testing.T, dir string) (string, []byte) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	return entries[0].Name(), data
+}
+
+func TestConnectionTracerQLOGDIR(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "qlogs")
+	t.Setenv("QLOGDIR", dir)
+
+	tracer := NewConnectionTracer(&TracerConfig{})
+	require.True(t, newTracedConnection(t, tracer, StartedConnection{}))
+	name, data := readSingleFile(t, dir)
+	require.True(t, strings.HasSuffix(name, "_client.sqlog"))
+	require.Equal(t, []string{"transport:connection_started"}, readRecords(t, data))
+
+	t.Setenv("QLOGDIR", "")
+	require.False(t, newTracedConnection(t, NewConnectionTracer(&TracerConfig{})))
+}
+
+func TestConnectionTracerSampling(t *testing.T) {
+	dir := t.TempDir()
+	sampleRate := 0.25
+	tracer := NewConnectionTracer(&TracerConfig{Dir: dir, SampleRate: &sampleRate})
+	var traced int
+	for range 400 {
+		if newTracedConnection(t, tracer) {
+			traced++
+		}
+	}
+	require.Greater(t, traced, 50)
+	require.Less(t, traced, 150)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, traced)
+
+	// a sample rate of 0 disables tracing
+	var zero float64
+	tracer = NewConnectionTracer(&TracerConfig{Dir: dir, SampleRate: &zero})
+	for range 100 {
+		require.False(t, newTracedConnection(t, tracer))
+	}
+}
+
+func TestConnectionTracerEventFilter(t *testing.T) {
+	events := []qlogwriter.Event{
+		StartedConnection{},
+		PacketSent{Header: PacketHeader{PacketType: PacketType1RTT, PacketNumber: 1}},
+		PacketReceived{Header: PacketHeader{PacketType: PacketType1RTT, PacketNumber: 1}},
+		MetricsUpdated{CongestionWindow: 1000},
+		CongestionStateUpdated{State: CongestionStateSlowStart},
+	}
+
+	t.Run("include", func(t *testing.T) {
+		dir := t.TempDir()
+		tracer := NewConnectionTracer(&TracerConfig{
+			Dir:           dir,
+			IncludeEvents: []string{"transport:packet_sent", "recovery:*"},
+		})
+		require.True(t, newTracedConnection(t, tracer, events...))
+		_, data := readSingleFile(t, dir)
+		require.Equal(t,
+			[]string{"transport:packet_sent", "recovery:metrics_updated", "recovery:congestion_state_updated"},
+			readRecords(t, data),
+		)
+	})
+
+	t.Run("exclude", func(t *testing.T) {
+		dir := t.TempDir()
+		tracer := NewConnectionTracer(&TracerConfig{
+			Dir:           dir,
+			ExcludeEvents: []string{"transport:packet_sent", "recovery:*"},
+		})
+		require.True(t, newTracedConnection(t, tracer, events...))
+		_, data := readSingleFile(t, dir)
+		require.Equal(t, []string{"transport:connection_started", "transport:packet_received"}, readRecords(t, data))
+	})
+
+	t.Run("include and exclude", func(t *testing.T) {
+		dir := t.TempDir()
+		tracer := NewConnectionTracer(&TracerConfig{
+			Dir:           dir,
+			IncludeEvents: []string{"transport:*"},
+			ExcludeEvents: []string{"transport:connection_started"},
+		})
+		require.True(t, newTracedConnection(t, tracer, events...))
+		_, data := readSingleFile(t, dir)
+		require.Equal(t, []string{"transport:packet_sent", "transport:packet_received"}, readRecords(t, data))
+	})
+}
+
+func TestConnectionTracerCompression(t *testing.T) {
+	t.Run("gzip", func(t *testing.T) {
+		testConnectionTracerCompression(t, CompressionGzip, ".sqlog.gz", func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		})
+	})
+	t.Run("zstd", func(t *testing.T) {
+		testConnectionTracerCompression(t, CompressionZstd, ".sqlog.zst", func(r io.Reader) (io.Reader, error) {
+			return zstd.NewReader(r)
+		})
+	})
+}
+
+func testConnectionTracerCompression(t *testing.T, c Compression, ext string, newReader func(io.Reader) (io.Reader, error)) {
+	dir := t.TempDir()
+	tracer := NewConnectionTracer(&TracerConfig{Dir: dir, Compression: c})
+	var events []qlogwriter.Event
+	for i := range 100 {
+		events = append(events, PacketSent{Header: PacketHeader{PacketType: PacketType1RTT, PacketNumber: protocol.PacketNumber(i)}})
+	}
+	require.True(t, newTracedConnection(t, tracer, events...))
+
+	name, data := readSingleFile(t, dir)
+	require.True(t, strings.HasSuffix(name, ext))
+	r, err := newReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Less(t, len(data), len(decompressed))
+	require.Len(t, readRecords(t, decompressed), 100)
+}
+
+func TestConnectionTracerMaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	const maxSize = 2000
+	tracer := NewConnectionTracer(&TracerConfig{Dir: dir, MaxFileSize: maxSize})
+	var events []qlogwriter.Event
+	for i := range 100 {
+		events = append(events, PacketSent{Header: PacketHeader{PacketType: PacketType1RTT, PacketNumber: protocol.PacketNumber(i)}})
+	}
+	require.True(t, newTracedConnection(t, tracer, events...))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	var current, rotated []byte
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(data), maxSize)
+		if strings.HasSuffix(e.Name(), "_client.1.sqlog") {
+			rotated = data
+		} else {
+			require.True(t, strings.HasSuffix(e.Name(), "_client.sqlog"))
+			current = data
+		}
+	}
+	// both files start with the trace header, and contain a whole number of records
+	rotatedNames := readRecords(t, rotated)
+	currentNames := readRecords(t, current)
+	require.NotEmpty(t, rotatedNames)
+	require.NotEmpty(t, currentNames)
+	require.Less(t, len(rotatedNames)+len(currentNames), 100)
+	// the most recent events are kept
+	lastRecord := current[bytes.LastIndexByte(current, qlogwriter.RecordSeparator)+1:]
+	var obj map[string]any
+	require.NoError(t, json.Unmarshal(lastRecord, &obj))
+	require.EqualValues(t, 99, obj["data"].(map[string]any)["header"].(map[string]any)["packet_number"])
+}
+
+func TestConnectionTracerMaxDirSize(t *testing.T) {
+	dir := t.TempDir()
+	// this file was written before the tracer was created
+	oldFile := filepath.Join(dir, "old_client.sqlog")
+	require.NoError(t, os.WriteFile(oldFile, bytes.Repeat([]byte{'a'}, 10000), 0o644))
+	require.NoError(t, os.Chtimes(oldFile, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)))
+	// files that are not qlog files are never removed
+	otherFile := filepath.Join(dir, "other.txt")
+	require.NoError(t, os.WriteFile(otherFile, bytes.Repeat([]byte{'a'}, 100000), 0o644))
+
+	var events []qlogwriter.Event
+	for i := range 10 {
+		events = append(events, PacketSent{Header: PacketHeader{PacketType: PacketType1RTT, PacketNumber: protocol.PacketNumber(i)}})
+	}
+	// determine the (approximate) size of a single trace
+	sizeDir := t.TempDir()
+	require.True(t, newTracedConnection(t, NewConnectionTracer(&TracerConfig{Dir: sizeDir}), events...))
+	_, data := readSingleFile(t, sizeDir)
+	traceSize := int64(len(data))
+
+	maxDirSize := 10000 + 2*traceSize + traceSize/2
+	tracer := NewConnectionTracer(&TracerConfig{Dir: dir, MaxDirSize: maxDirSize})
+	require.True(t, newTracedConnection(t, tracer, events...))
+	require.True(t, newTracedConnection(t, tracer, events...))
+	require.FileExists(t, oldFile)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	// the third trace pushes the directory over the limit, and the oldest file is removed
+	require.True(t, newTracedConnection(t, tracer, events...))
+	require.NoFileExists(t, oldFile)
+	require.FileExists(t, otherFile)
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+
+	for range 20 {
+		require.True(t, newTracedConnection(t, tracer, events...))
+	}
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	var total int64
+	for _, e := range entries {
+		if e.Name() == "other.txt" {
+			continue
+		}
+		info, err := e.Info()
+		require.NoError(t, err)
+		total += info.Size()
+	}
+	require.LessOrEqual(t, total, maxDirSize)
+	require.Greater(t, total, maxDirSize-2*traceSize)
+}
+
+func TestConnectionTracerErrors(t *testing.T) {
+	// the qlog directory can't be created, since a file with the same name exists
+	dir := filepath.Join(t.TempDir(), "qlogs")
+	require.NoError(t, os.WriteFile(dir, nil, 0o644))
+
+	var errs []error
+	tracer := NewConnectionTracer(&TracerConfig{
+		Dir:          dir,
+		ErrorHandler: func(err error) { errs = append(errs, err) },
+	})
+	require.False(t, newTracedConnection(t, tracer))
+	require.Len(t, errs, 1)
+	require.ErrorContains(t, errs[0], "failed to create qlog dir")
+}
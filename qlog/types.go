@@ -117,6 +117,37 @@ const (
 	KeyUpdateLocal KeyUpdateTrigger = "local_update"
 )
 
+// KeyUpdateReason describes why a local key update was initiated.
+// This is a quic-go specific extension of the key_updated event.
+type KeyUpdateReason string
+
+const (
+	// KeyUpdateReasonApplication indicates the key update was requested by the application.
+	KeyUpdateReasonApplication KeyUpdateReason = "application"
+	// KeyUpdateReasonPacketLimit indicates the key update was initiated
+	// because the maximum number of packets for the current key was reached.
+	KeyUpdateReasonPacketLimit KeyUpdateReason = "packet_limit"
+	// KeyUpdateReasonByteLimit indicates the key update was initiated
+	// because the maximum number of bytes for the current key was reached.
+	KeyUpdateReasonByteLimit KeyUpdateReason = "byte_limit"
+	// KeyUpdateReasonTimeLimit indicates the key update was initiated
+	// because the current key was used for the maximum duration.
+	KeyUpdateReasonTimeLimit KeyUpdateReason = "time_limit"
+)
+
+// AdmissionAction is the action taken by the server for a new connection attempt.
+// This is a quic-go specific type.
+type AdmissionAction string
+
+const (
+	// AdmissionActionRetry indicates that a Retry packet was sent.
+	AdmissionActionRetry AdmissionAction = "retry"
+	// AdmissionActionRefuse indicates that the connection attempt was refused with a CONNECTION_REFUSED error.
+	AdmissionActionRefuse AdmissionAction = "refuse"
+	// AdmissionActionDrop indicates that the packet was dropped.
+	AdmissionActionDrop AdmissionAction = "drop"
+)
+
 type transportError uint64
 
 func (e transportError) String() string {
@@ -155,6 +186,8 @@ func (e transportError) String() string {
 		return "aead_limit_reached"
 	case qerr.NoViablePathError:
 		return "no_viable_path"
+	case qerr.VersionNegotiationErrorCode:
+		return "version_negotiation_error"
 	default:
 		return ""
 	}
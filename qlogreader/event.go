@@ -0,0 +1,797 @@
+package qlogreader
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/qerr"
+	"github.com/nukilabs/quic-go/qlog"
+	"github.com/nukilabs/quic-go/qlogwriter"
+)
+
+func decodeEvent(name string, data json.RawMessage, eventTime time.Time) (qlogwriter.Event, error) {
+	switch name {
+	case "transport:connection_started":
+		return decodeConnectionStarted(data)
+	case "transport:version_information":
+		return decodeVersionInformation(data)
+	case "transport:connection_closed":
+		return decodeConnectionClosed(data)
+	case "transport:packet_sent":
+		return decodePacketSent(data)
+	case "transport:packet_received":
+		return decodePacketReceived(data)
+	case "transport:packet_buffered":
+		return decodePacketBuffered(data)
+	case "transport:packet_dropped":
+		return decodePacketDropped(data)
+	case "transport:connection_admission":
+		return decodeConnectionAdmission(data)
+	case "transport:parameters_set":
+		return decodeParametersSet(data, false)
+	case "transport:parameters_restored":
+		return decodeParametersSet(data, true)
+	case "transport:alpn_information":
+		return decodeALPNInformation(data)
+	case "recovery:mtu_updated":
+		return decodeMTUUpdated(data)
+	case "recovery:metrics_updated":
+		return decodeMetricsUpdated(data)
+	case "recovery:packet_lost":
+		return decodePacketLost(data)
+	case "recovery:spurious_loss":
+		return decodeSpuriousLoss(data)
+	case "recovery:loss_timer_updated":
+		return decodeLossTimerUpdated(data, eventTime)
+	case "recovery:congestion_state_updated":
+		return decodeCongestionStateUpdated(data)
+	case "recovery:ecn_state_updated":
+		return decodeECNStateUpdated(data)
+	case "security:key_updated":
+		return decodeKeyUpdated(data)
+	case "security:key_discarded":
+		return decodeKeyDiscarded(data)
+	case "http3:frame_parsed", "http3:frame_created":
+		return decodeHTTP3FrameEvent(name, data)
+	case "http3:datagram_created", "http3:datagram_parsed":
+		return decodeHTTP3DatagramEvent(name, data)
+	}
+	if strings.HasPrefix(name, "transport:") {
+		if ev, ok := decodeDebugEvent(name, data); ok {
+			return ev, nil
+		}
+	}
+	return newRawEvent(name, data)
+}
+
+type jsonToken struct {
+	Data string `json:"data"`
+}
+
+type jsonPacketHeader struct {
+	PacketType   qlog.PacketType `json:"packet_type"`
+	PacketNumber *int64          `json:"packet_number"`
+	Version      string          `json:"version"`
+	SCID         string          `json:"scid"`
+	DCID         string          `json:"dcid"`
+	KeyPhaseBit  string          `json:"key_phase_bit"`
+	Token        *jsonToken      `json:"token"`
+}
+
+func (h *jsonPacketHeader) toPacketHeader() (qlog.PacketHeader, error) {
+	hdr := qlog.PacketHeader{
+		PacketType:   h.PacketType,
+		PacketNumber: protocol.InvalidPacketNumber,
+	}
+	if h.PacketNumber != nil {
+		hdr.PacketNumber = protocol.PacketNumber(*h.PacketNumber)
+	}
+	if h.Version != "" {
+		v, err := parseVersion(h.Version)
+		if err != nil {
+			return qlog.PacketHeader{}, err
+		}
+		hdr.Version = v
+	}
+	var err error
+	if hdr.SrcConnectionID, err = parseConnectionID(h.SCID); err != nil {
+		return qlog.PacketHeader{}, err
+	}
+	if hdr.DestConnectionID, err = parseConnectionID(h.DCID); err != nil {
+		return qlog.PacketHeader{}, err
+	}
+	switch h.KeyPhaseBit {
+	case "0":
+		hdr.KeyPhaseBit = qlog.KeyPhaseZero
+	case "1":
+		hdr.KeyPhaseBit = qlog.KeyPhaseOne
+	}
+	if h.Token != nil {
+		token, err := parseHex(h.Token.Data)
+		if err != nil {
+			return qlog.PacketHeader{}, err
+		}
+		hdr.Token = &qlog.Token{Raw: token}
+	}
+	return hdr, nil
+}
+
+func (h *jsonPacketHeader) toVersionNegotiationHeader() (qlog.PacketHeaderVersionNegotiation, error) {
+	src, err := parseHex(h.SCID)
+	if err != nil {
+		return qlog.PacketHeaderVersionNegotiation{}, err
+	}
+	dest, err := parseHex(h.DCID)
+	if err != nil {
+		return qlog.PacketHeaderVersionNegotiation{}, err
+	}
+	return qlog.PacketHeaderVersionNegotiation{
+		SrcConnectionID:  protocol.ArbitraryLenConnectionID(src),
+		DestConnectionID: protocol.ArbitraryLenConnectionID(dest),
+	}, nil
+}
+
+type jsonRawInfo struct {
+	Length        int `json:"length"`
+	PayloadLength int `json:"payload_length"`
+}
+
+type jsonPathEndpointInfo struct {
+	IPv4   string `json:"ip_v4"`
+	PortV4 uint16 `json:"port_v4"`
+	IPv6   string `json:"ip_v6"`
+	PortV6 uint16 `json:"port_v6"`
+}
+
+func (p *jsonPathEndpointInfo) toAddrPorts() (ipv4, ipv6 netip.AddrPort, _ error) {
+	if p.IPv4 != "" {
+		addr, err := netip.ParseAddr(p.IPv4)
+		if err != nil {
+			return netip.AddrPort{}, netip.AddrPort{}, err
+		}
+		ipv4 = netip.AddrPortFrom(addr, p.PortV4)
+	}
+	if p.IPv6 != "" {
+		addr, err := netip.ParseAddr(p.IPv6)
+		if err != nil {
+			return netip.AddrPort{}, netip.AddrPort{}, err
+		}
+		ipv6 = netip.AddrPortFrom(addr, p.PortV6)
+	}
+	return ipv4, ipv6, nil
+}
+
+func (p *jsonPathEndpointInfo) toPathEndpointInfo() (qlog.PathEndpointInfo, error) {
+	ipv4, ipv6, err := p.toAddrPorts()
+	if err != nil {
+		return qlog.PathEndpointInfo{}, err
+	}
+	return qlog.PathEndpointInfo{IPv4: ipv4, IPv6: ipv6}, nil
+}
+
+func decodeConnectionStarted(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		Local  jsonPathEndpointInfo `json:"local"`
+		Remote jsonPathEndpointInfo `json:"remote"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	local, err := e.Local.toPathEndpointInfo()
+	if err != nil {
+		return nil, err
+	}
+	remote, err := e.Remote.toPathEndpointInfo()
+	if err != nil {
+		return nil, err
+	}
+	return qlog.StartedConnection{Local: local, Remote: remote}, nil
+}
+
+func decodeVersionInformation(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		ClientVersions []string `json:"client_versions"`
+		ServerVersions []string `json:"server_versions"`
+		ChosenVersion  string   `json:"chosen_version"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	var ev qlog.VersionInformation
+	var err error
+	if ev.ClientVersions, err = parseVersions(e.ClientVersions); err != nil {
+		return nil, err
+	}
+	if ev.ServerVersions, err = parseVersions(e.ServerVersions); err != nil {
+		return nil, err
+	}
+	if ev.ChosenVersion, err = parseVersion(e.ChosenVersion); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+var transportErrorCodes = map[string]qerr.TransportErrorCode{
+	"no_error":                  qerr.NoError,
+	"internal_error":            qerr.InternalError,
+	"connection_refused":        qerr.ConnectionRefused,
+	"flow_control_error":        qerr.FlowControlError,
+	"stream_limit_error":        qerr.StreamLimitError,
+	"stream_state_error":        qerr.StreamStateError,
+	"final_size_error":          qerr.FinalSizeError,
+	"frame_encoding_error":      qerr.FrameEncodingError,
+	"transport_parameter_error": qerr.TransportParameterError,
+	"connection_id_limit_error": qerr.ConnectionIDLimitError,
+	"protocol_violation":        qerr.ProtocolViolation,
+	"invalid_token":             qerr.InvalidToken,
+	"application_error":         qerr.ApplicationErrorErrorCode,
+	"crypto_buffer_exceeded":    qerr.CryptoBufferExceeded,
+	"key_update_error":          qerr.KeyUpdateError,
+	"aead_limit_reached":        qerr.AEADLimitReached,
+	"no_viable_path":            qerr.NoViablePathError,
+	"version_negotiation_error": qerr.VersionNegotiationErrorCode,
+}
+
+func parseTransportErrorCode(s string, errorCode *uint64) (qerr.TransportErrorCode, error) {
+	if code, ok := transportErrorCodes[s]; ok {
+		return code, nil
+	}
+	if rest, ok := strings.CutPrefix(s, "crypto_error_0x"); ok {
+		code, err := strconv.ParseUint(rest, 16, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid crypto error: %s", s)
+		}
+		return qerr.TransportErrorCode(code), nil
+	}
+	if errorCode != nil {
+		return qerr.TransportErrorCode(*errorCode), nil
+	}
+	return 0, fmt.Errorf("unknown transport error: %s", s)
+}
+
+func decodeConnectionClosed(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		Initiator        qlog.Initiator              `json:"initiator"`
+		ConnectionError  string                      `json:"connection_error"`
+		ApplicationError string                      `json:"application_error"`
+		ErrorCode        *uint64                     `json:"error_code"`
+		Reason           string                      `json:"reason"`
+		Trigger          qlog.ConnectionCloseTrigger `json:"trigger"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	ev := qlog.ConnectionClosed{
+		Initiator: e.Initiator,
+		Reason:    e.Reason,
+		Trigger:   e.Trigger,
+	}
+	if e.ConnectionError != "" {
+		code, err := parseTransportErrorCode(e.ConnectionError, e.ErrorCode)
+		if err != nil {
+			return nil, err
+		}
+		ev.ConnectionError = &code
+	}
+	if e.ApplicationError != "" {
+		if e.ErrorCode == nil {
+			return nil, fmt.Errorf("missing application error code")
+		}
+		code := qerr.ApplicationErrorCode(*e.ErrorCode)
+		ev.ApplicationError = &code
+	}
+	return ev, nil
+}
+
+type jsonPacketEvent struct {
+	Header            jsonPacketHeader `json:"header"`
+	Raw               jsonRawInfo      `json:"raw"`
+	DatagramID        qlog.DatagramID  `json:"datagram_id"`
+	Frames            []jsonFrame      `json:"frames"`
+	ECN               qlog.ECN         `json:"ecn"`
+	IsCoalesced       bool             `json:"is_coalesced"`
+	Trigger           string           `json:"trigger"`
+	SupportedVersions []string         `json:"supported_versions"`
+}
+
+func (e *jsonPacketEvent) frames() ([]qlog.Frame, error) {
+	if len(e.Frames) == 0 {
+		return nil, nil
+	}
+	frames := make([]qlog.Frame, 0, len(e.Frames))
+	for _, f := range e.Frames {
+		frame, err := f.toFrame()
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+func decodePacketSent(data json.RawMessage) (qlogwriter.Event, error) {
+	var e jsonPacketEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	if e.Header.PacketType == qlog.PacketTypeVersionNegotiation {
+		hdr, err := e.Header.toVersionNegotiationHeader()
+		if err != nil {
+			return nil, err
+		}
+		versions, err := parseVersions(e.SupportedVersions)
+		if err != nil {
+			return nil, err
+		}
+		return qlog.VersionNegotiationSent{Header: hdr, SupportedVersions: versions}, nil
+	}
+	hdr, err := e.Header.toPacketHeader()
+	if err != nil {
+		return nil, err
+	}
+	frames, err := e.frames()
+	if err != nil {
+		return nil, err
+	}
+	return qlog.PacketSent{
+		Header:      hdr,
+		Raw:         qlog.RawInfo(e.Raw),
+		DatagramID:  e.DatagramID,
+		Frames:      frames,
+		ECN:         e.ECN,
+		IsCoalesced: e.IsCoalesced,
+		Trigger:     e.Trigger,
+	}, nil
+}
+
+func decodePacketReceived(data json.RawMessage) (qlogwriter.Event, error) {
+	var e jsonPacketEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	if e.Header.PacketType == qlog.PacketTypeVersionNegotiation {
+		hdr, err := e.Header.toVersionNegotiationHeader()
+		if err != nil {
+			return nil, err
+		}
+		versions, err := parseVersions(e.SupportedVersions)
+		if err != nil {
+			return nil, err
+		}
+		return qlog.VersionNegotiationReceived{Header: hdr, SupportedVersions: versions}, nil
+	}
+	hdr, err := e.Header.toPacketHeader()
+	if err != nil {
+		return nil, err
+	}
+	frames, err := e.frames()
+	if err != nil {
+		return nil, err
+	}
+	return qlog.PacketReceived{
+		Header:      hdr,
+		Raw:         qlog.RawInfo(e.Raw),
+		DatagramID:  e.DatagramID,
+		Frames:      frames,
+		ECN:         e.ECN,
+		IsCoalesced: e.IsCoalesced,
+		Trigger:     e.Trigger,
+	}, nil
+}
+
+func decodePacketBuffered(data json.RawMessage) (qlogwriter.Event, error) {
+	var e jsonPacketEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	hdr, err := e.Header.toPacketHeader()
+	if err != nil {
+		return nil, err
+	}
+	return qlog.PacketBuffered{
+		Header:     hdr,
+		Raw:        qlog.RawInfo(e.Raw),
+		DatagramID: e.DatagramID,
+	}, nil
+}
+
+func decodePacketDropped(data json.RawMessage) (qlogwriter.Event, error) {
+	var e jsonPacketEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	hdr, err := e.Header.toPacketHeader()
+	if err != nil {
+		return nil, err
+	}
+	return qlog.PacketDropped{
+		Header:     hdr,
+		Raw:        qlog.RawInfo(e.Raw),
+		DatagramID: e.DatagramID,
+		Trigger:    qlog.PacketDropReason(e.Trigger),
+	}, nil
+}
+
+func decodeConnectionAdmission(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		Remote             jsonPathEndpointInfo `json:"remote"`
+		DCID               string               `json:"dcid"`
+		Action             qlog.AdmissionAction `json:"action"`
+		Reason             string               `json:"reason"`
+		HandshakesInFlight int                  `json:"handshakes_in_flight"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	remote, err := e.Remote.toPathEndpointInfo()
+	if err != nil {
+		return nil, err
+	}
+	dcid, err := parseConnectionID(e.DCID)
+	if err != nil {
+		return nil, err
+	}
+	return qlog.ConnectionAdmission{
+		Remote:             remote,
+		DestConnectionID:   dcid,
+		Action:             e.Action,
+		Reason:             e.Reason,
+		HandshakesInFlight: e.HandshakesInFlight,
+	}, nil
+}
+
+func decodeParametersSet(data json.RawMessage, restore bool) (qlogwriter.Event, error) {
+	var e struct {
+		Initiator                       qlog.Initiator `json:"initiator"`
+		OriginalDestinationConnectionID *string        `json:"original_destination_connection_id"`
+		StatelessResetToken             *string        `json:"stateless_reset_token"`
+		RetrySourceConnectionID         *string        `json:"retry_source_connection_id"`
+		InitialSourceConnectionID       string         `json:"initial_source_connection_id"`
+		DisableActiveMigration          bool           `json:"disable_active_migration"`
+		MaxIdleTimeout                  float64        `json:"max_idle_timeout"`
+		MaxUDPPayloadSize               int64          `json:"max_udp_payload_size"`
+		AckDelayExponent                uint8          `json:"ack_delay_exponent"`
+		MaxAckDelay                     float64        `json:"max_ack_delay"`
+		ActiveConnectionIDLimit         uint64         `json:"active_connection_id_limit"`
+		InitialMaxData                  int64          `json:"initial_max_data"`
+		InitialMaxStreamDataBidiLocal   int64          `json:"initial_max_stream_data_bidi_local"`
+		InitialMaxStreamDataBidiRemote  int64          `json:"initial_max_stream_data_bidi_remote"`
+		InitialMaxStreamDataUni         int64          `json:"initial_max_stream_data_uni"`
+		InitialMaxStreamsBidi           int64          `json:"initial_max_streams_bidi"`
+		InitialMaxStreamsUni            int64          `json:"initial_max_streams_uni"`
+		PreferredAddress                *struct {
+			jsonPathEndpointInfo
+			ConnectionID        string `json:"connection_id"`
+			StatelessResetToken string `json:"stateless_reset_token"`
+		} `json:"preferred_address"`
+		MaxDatagramFrameSize *int64 `json:"max_datagram_frame_size"`
+		ResetStreamAt        bool   `json:"reset_stream_at"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	ev := qlog.ParametersSet{
+		Restore:                        restore,
+		Initiator:                      e.Initiator,
+		SentBy:                         protocol.PerspectiveClient,
+		DisableActiveMigration:         e.DisableActiveMigration,
+		MaxIdleTimeout:                 fromMilliseconds(e.MaxIdleTimeout),
+		MaxUDPPayloadSize:              protocol.ByteCount(e.MaxUDPPayloadSize),
+		AckDelayExponent:               e.AckDelayExponent,
+		MaxAckDelay:                    fromMilliseconds(e.MaxAckDelay),
+		ActiveConnectionIDLimit:        e.ActiveConnectionIDLimit,
+		InitialMaxData:                 protocol.ByteCount(e.InitialMaxData),
+		InitialMaxStreamDataBidiLocal:  protocol.ByteCount(e.InitialMaxStreamDataBidiLocal),
+		InitialMaxStreamDataBidiRemote: protocol.ByteCount(e.InitialMaxStreamDataBidiRemote),
+		InitialMaxStreamDataUni:        protocol.ByteCount(e.InitialMaxStreamDataUni),
+		InitialMaxStreamsBidi:          e.InitialMaxStreamsBidi,
+		InitialMaxStreamsUni:           e.InitialMaxStreamsUni,
+		MaxDatagramFrameSize:           protocol.InvalidByteCount,
+		EnableResetStreamAt:            e.ResetStreamAt,
+	}
+	// Only the server sends the original_destination_connection_id,
+	// and restored parameters are always the server's parameters.
+	if e.OriginalDestinationConnectionID != nil || restore {
+		ev.SentBy = protocol.PerspectiveServer
+	}
+	var err error
+	if e.OriginalDestinationConnectionID != nil {
+		if ev.OriginalDestinationConnectionID, err = parseConnectionID(*e.OriginalDestinationConnectionID); err != nil {
+			return nil, err
+		}
+	}
+	if e.StatelessResetToken != nil {
+		token, err := parseStatelessResetToken(*e.StatelessResetToken)
+		if err != nil {
+			return nil, err
+		}
+		ev.StatelessResetToken = &token
+	}
+	if e.RetrySourceConnectionID != nil {
+		connID, err := parseConnectionID(*e.RetrySourceConnectionID)
+		if err != nil {
+			return nil, err
+		}
+		ev.RetrySourceConnectionID = &connID
+	}
+	if !restore {
+		if ev.InitialSourceConnectionID, err = parseConnectionID(e.InitialSourceConnectionID); err != nil {
+			return nil, err
+		}
+	}
+	if e.PreferredAddress != nil {
+		ipv4, ipv6, err := e.PreferredAddress.toAddrPorts()
+		if err != nil {
+			return nil, err
+		}
+		connID, err := parseConnectionID(e.PreferredAddress.ConnectionID)
+		if err != nil {
+			return nil, err
+		}
+		token, err := parseStatelessResetToken(e.PreferredAddress.StatelessResetToken)
+		if err != nil {
+			return nil, err
+		}
+		ev.PreferredAddress = &qlog.PreferredAddress{
+			IPv4:                ipv4,
+			IPv6:                ipv6,
+			ConnectionID:        connID,
+			StatelessResetToken: token,
+		}
+	}
+	if e.MaxDatagramFrameSize != nil {
+		ev.MaxDatagramFrameSize = protocol.ByteCount(*e.MaxDatagramFrameSize)
+	}
+	return ev, nil
+}
+
+func decodeALPNInformation(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		ChosenALPN string `json:"chosen_alpn"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return qlog.ALPNInformation{ChosenALPN: e.ChosenALPN}, nil
+}
+
+func decodeMTUUpdated(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		MTU  int  `json:"mtu"`
+		Done bool `json:"done"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return qlog.MTUUpdated{Value: e.MTU, Done: e.Done}, nil
+}
+
+func decodeMetricsUpdated(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		MinRTT           float64 `json:"min_rtt"`
+		SmoothedRTT      float64 `json:"smoothed_rtt"`
+		LatestRTT        float64 `json:"latest_rtt"`
+		RTTVariance      float64 `json:"rtt_variance"`
+		CongestionWindow int     `json:"congestion_window"`
+		BytesInFlight    int     `json:"bytes_in_flight"`
+		PacketsInFlight  int     `json:"packets_in_flight"`
+		PTOCount         *uint32 `json:"pto_count"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	// quic-go logs the PTO count in a separate recovery:metrics_updated event
+	if e.PTOCount != nil {
+		return qlog.PTOCountUpdated{PTOCount: *e.PTOCount}, nil
+	}
+	return qlog.MetricsUpdated{
+		MinRTT:           fromMilliseconds(e.MinRTT),
+		SmoothedRTT:      fromMilliseconds(e.SmoothedRTT),
+		LatestRTT:        fromMilliseconds(e.LatestRTT),
+		RTTVariance:      fromMilliseconds(e.RTTVariance),
+		CongestionWindow: e.CongestionWindow,
+		BytesInFlight:    e.BytesInFlight,
+		PacketsInFlight:  e.PacketsInFlight,
+	}, nil
+}
+
+func decodePacketLost(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		Header  jsonPacketHeader      `json:"header"`
+		Trigger qlog.PacketLossReason `json:"trigger"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	hdr, err := e.Header.toPacketHeader()
+	if err != nil {
+		return nil, err
+	}
+	return qlog.PacketLost{Header: hdr, Trigger: e.Trigger}, nil
+}
+
+func decodeSpuriousLoss(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		PacketNumberSpace string  `json:"packet_number_space"`
+		PacketNumber      int64   `json:"packet_number"`
+		ReorderingPackets uint64  `json:"reordering_packets"`
+		ReorderingTime    float64 `json:"reordering_time"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	encLevel, err := parsePacketNumberSpace(e.PacketNumberSpace)
+	if err != nil {
+		return nil, err
+	}
+	return qlog.SpuriousLoss{
+		EncryptionLevel:  encLevel,
+		PacketNumber:     protocol.PacketNumber(e.PacketNumber),
+		PacketReordering: e.ReorderingPackets,
+		TimeReordering:   fromMilliseconds(e.ReorderingTime),
+	}, nil
+}
+
+func decodeLossTimerUpdated(data json.RawMessage, eventTime time.Time) (qlogwriter.Event, error) {
+	var e struct {
+		EventType         qlog.LossTimerUpdateType `json:"event_type"`
+		TimerType         qlog.TimerType           `json:"timer_type"`
+		PacketNumberSpace string                   `json:"packet_number_space"`
+		Delta             float64                  `json:"delta"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	ev := qlog.LossTimerUpdated{
+		Type:      e.EventType,
+		TimerType: e.TimerType,
+	}
+	if e.PacketNumberSpace != "" {
+		encLevel, err := parsePacketNumberSpace(e.PacketNumberSpace)
+		if err != nil {
+			return nil, err
+		}
+		ev.EncLevel = encLevel
+	}
+	if e.EventType == qlog.LossTimerUpdateTypeSet {
+		ev.Time = eventTime.Add(fromMilliseconds(e.Delta))
+	}
+	return ev, nil
+}
+
+func decodeCongestionStateUpdated(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		New qlog.CongestionState `json:"new"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return qlog.CongestionStateUpdated{State: e.New}, nil
+}
+
+func decodeECNStateUpdated(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		New     qlog.ECNState `json:"new"`
+		Trigger string        `json:"trigger"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return qlog.ECNStateUpdated{State: e.New, Trigger: e.Trigger}, nil
+}
+
+func decodeKeyUpdated(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		Trigger  qlog.KeyUpdateTrigger `json:"trigger"`
+		Reason   qlog.KeyUpdateReason  `json:"reason"`
+		KeyType  qlog.KeyType          `json:"key_type"`
+		KeyPhase uint64                `json:"key_phase"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return qlog.KeyUpdated{
+		Trigger:  e.Trigger,
+		Reason:   e.Reason,
+		KeyType:  e.KeyType,
+		KeyPhase: qlog.KeyPhase(e.KeyPhase),
+	}, nil
+}
+
+func decodeKeyDiscarded(data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		KeyType  qlog.KeyType `json:"key_type"`
+		KeyPhase uint64       `json:"key_phase"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return qlog.KeyDiscarded{KeyType: e.KeyType, KeyPhase: qlog.KeyPhase(e.KeyPhase)}, nil
+}
+
+// decodeDebugEvent decodes events logged using qlog.DebugEvent.
+// Since the name of these events is arbitrary, they're identified by their payload.
+func decodeDebugEvent(name string, data json.RawMessage) (qlogwriter.Event, bool) {
+	var e map[string]json.RawMessage
+	if err := json.Unmarshal(data, &e); err != nil || len(e) != 1 {
+		return nil, false
+	}
+	var message string
+	if err := json.Unmarshal(e["message"], &message); err != nil {
+		return nil, false
+	}
+	eventName := strings.TrimPrefix(name, "transport:")
+	if eventName == "debug" {
+		eventName = ""
+	}
+	return qlog.DebugEvent{EventName: eventName, Message: message}, true
+}
+
+func parsePacketNumberSpace(s string) (protocol.EncryptionLevel, error) {
+	switch s {
+	case "initial":
+		return protocol.EncryptionInitial, nil
+	case "handshake":
+		return protocol.EncryptionHandshake, nil
+	case "application_data":
+		return protocol.Encryption1RTT, nil
+	default:
+		return 0, fmt.Errorf("unknown packet number space: %s", s)
+	}
+}
+
+func parseVersion(s string) (protocol.Version, error) {
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version: %s", s)
+	}
+	return protocol.Version(v), nil
+}
+
+func parseVersions(s []string) ([]protocol.Version, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	versions := make([]protocol.Version, 0, len(s))
+	for _, str := range s {
+		v, err := parseVersion(str)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// parseHex parses a hex-encoded byte string.
+// Empty connection IDs are encoded as "(empty)".
+func parseHex(s string) ([]byte, error) {
+	if s == "" || s == "(empty)" {
+		return nil, nil
+	}
+	return hex.DecodeString(s)
+}
+
+func parseStatelessResetToken(s string) (protocol.StatelessResetToken, error) {
+	var token protocol.StatelessResetToken
+	b, err := parseHex(s)
+	if err != nil {
+		return token, err
+	}
+	if len(b) != len(token) {
+		return token, fmt.Errorf("invalid stateless reset token length: %d", len(b))
+	}
+	copy(token[:], b)
+	return token, nil
+}
+
+func fromMilliseconds(ms float64) time.Duration {
+	return time.Duration(math.Round(ms * 1e6))
+}
@@ -0,0 +1,235 @@
+package qlogreader
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/qerr"
+	"github.com/nukilabs/quic-go/qlog"
+)
+
+// jsonFrame contains the union of the fields of all QUIC frames.
+type jsonFrame struct {
+	FrameType string `json:"frame_type"`
+
+	StreamID       int64           `json:"stream_id"`
+	ErrorCode      json.RawMessage `json:"error_code"`
+	Offset         int64           `json:"offset"`
+	Length         int64           `json:"length"`
+	Fin            bool            `json:"fin"`
+	FinalSize      int64           `json:"final_size"`
+	ReliableSize   int64           `json:"reliable_size"`
+	Maximum        int64           `json:"maximum"`
+	Limit          int64           `json:"limit"`
+	StreamType     string          `json:"stream_type"`
+	SequenceNumber uint64          `json:"sequence_number"`
+	Data           string          `json:"data"`
+	Token          *jsonToken      `json:"token"`
+
+	// ACK
+	AckDelay    float64   `json:"ack_delay"`
+	AckedRanges [][]int64 `json:"acked_ranges"`
+	ECT0        uint64    `json:"ect0"`
+	ECT1        uint64    `json:"ect1"`
+	CE          uint64    `json:"ce"`
+
+	// NEW_CONNECTION_ID
+	RetirePriorTo       uint64 `json:"retire_prior_to"`
+	ConnectionID        string `json:"connection_id"`
+	StatelessResetToken string `json:"stateless_reset_token"`
+
+	// CONNECTION_CLOSE
+	ErrorSpace   string `json:"error_space"`
+	RawErrorCode uint64 `json:"raw_error_code"`
+	Reason       string `json:"reason"`
+
+	// ACK_FREQUENCY
+	AckElicitingThreshold uint64  `json:"ack_eliciting_threshold"`
+	RequestMaxAckDelay    float64 `json:"request_max_ack_delay"`
+	ReorderingThreshold   int64   `json:"reordering_threshold"`
+}
+
+func (f *jsonFrame) toFrame() (qlog.Frame, error) {
+	switch f.FrameType {
+	case "ping":
+		return qlog.Frame{Frame: &qlog.PingFrame{}}, nil
+	case "ack":
+		return f.toAckFrame()
+	case "reset_stream", "reset_stream_at":
+		errorCode, err := f.streamErrorCode()
+		if err != nil {
+			return qlog.Frame{}, err
+		}
+		return qlog.Frame{Frame: &qlog.ResetStreamFrame{
+			StreamID:     protocol.StreamID(f.StreamID),
+			ErrorCode:    errorCode,
+			FinalSize:    protocol.ByteCount(f.FinalSize),
+			ReliableSize: protocol.ByteCount(f.ReliableSize),
+		}}, nil
+	case "stop_sending":
+		errorCode, err := f.streamErrorCode()
+		if err != nil {
+			return qlog.Frame{}, err
+		}
+		return qlog.Frame{Frame: &qlog.StopSendingFrame{
+			StreamID:  protocol.StreamID(f.StreamID),
+			ErrorCode: errorCode,
+		}}, nil
+	case "crypto":
+		return qlog.Frame{Frame: &qlog.CryptoFrame{Offset: f.Offset, Length: f.Length}}, nil
+	case "new_token":
+		if f.Token == nil {
+			return qlog.Frame{}, fmt.Errorf("NEW_TOKEN frame without token")
+		}
+		token, err := parseHex(f.Token.Data)
+		if err != nil {
+			return qlog.Frame{}, err
+		}
+		return qlog.Frame{Frame: &qlog.NewTokenFrame{Token: token}}, nil
+	case "stream":
+		return qlog.Frame{Frame: &qlog.StreamFrame{
+			StreamID: protocol.StreamID(f.StreamID),
+			Offset:   f.Offset,
+			Length:   f.Length,
+			Fin:      f.Fin,
+		}}, nil
+	case "max_data":
+		return qlog.Frame{Frame: &qlog.MaxDataFrame{MaximumData: protocol.ByteCount(f.Maximum)}}, nil
+	case "max_stream_data":
+		return qlog.Frame{Frame: &qlog.MaxStreamDataFrame{
+			StreamID:          protocol.StreamID(f.StreamID),
+			MaximumStreamData: protocol.ByteCount(f.Maximum),
+		}}, nil
+	case "max_streams":
+		st, err := parseStreamType(f.StreamType)
+		if err != nil {
+			return qlog.Frame{}, err
+		}
+		return qlog.Frame{Frame: &qlog.MaxStreamsFrame{Type: st, MaxStreamNum: protocol.StreamNum(f.Maximum)}}, nil
+	case "data_blocked":
+		return qlog.Frame{Frame: &qlog.DataBlockedFrame{MaximumData: protocol.ByteCount(f.Limit)}}, nil
+	case "stream_data_blocked":
+		return qlog.Frame{Frame: &qlog.StreamDataBlockedFrame{
+			StreamID:          protocol.StreamID(f.StreamID),
+			MaximumStreamData: protocol.ByteCount(f.Limit),
+		}}, nil
+	case "streams_blocked":
+		st, err := parseStreamType(f.StreamType)
+		if err != nil {
+			return qlog.Frame{}, err
+		}
+		return qlog.Frame{Frame: &qlog.StreamsBlockedFrame{Type: st, StreamLimit: protocol.StreamNum(f.Limit)}}, nil
+	case "new_connection_id":
+		connID, err := parseConnectionID(f.ConnectionID)
+		if err != nil {
+			return qlog.Frame{}, err
+		}
+		token, err := parseStatelessResetToken(f.StatelessResetToken)
+		if err != nil {
+			return qlog.Frame{}, err
+		}
+		return qlog.Frame{Frame: &qlog.NewConnectionIDFrame{
+			SequenceNumber:      f.SequenceNumber,
+			RetirePriorTo:       f.RetirePriorTo,
+			ConnectionID:        connID,
+			StatelessResetToken: token,
+		}}, nil
+	case "retire_connection_id":
+		return qlog.Frame{Frame: &qlog.RetireConnectionIDFrame{SequenceNumber: f.SequenceNumber}}, nil
+	case "path_challenge":
+		data, err := f.pathData()
+		if err != nil {
+			return qlog.Frame{}, err
+		}
+		return qlog.Frame{Frame: &qlog.PathChallengeFrame{Data: data}}, nil
+	case "path_response":
+		data, err := f.pathData()
+		if err != nil {
+			return qlog.Frame{}, err
+		}
+		return qlog.Frame{Frame: &qlog.PathResponseFrame{Data: data}}, nil
+	case "connection_close":
+		return qlog.Frame{Frame: &qlog.ConnectionCloseFrame{
+			IsApplicationError: f.ErrorSpace == "application",
+			ErrorCode:          f.RawErrorCode,
+			ReasonPhrase:       f.Reason,
+		}}, nil
+	case "handshake_done":
+		return qlog.Frame{Frame: &qlog.HandshakeDoneFrame{}}, nil
+	case "datagram":
+		return qlog.Frame{Frame: &qlog.DatagramFrame{Length: f.Length}}, nil
+	case "ack_frequency":
+		return qlog.Frame{Frame: &qlog.AckFrequencyFrame{
+			SequenceNumber:        f.SequenceNumber,
+			AckElicitingThreshold: f.AckElicitingThreshold,
+			RequestMaxAckDelay:    fromMilliseconds(f.RequestMaxAckDelay),
+			ReorderingThreshold:   protocol.PacketNumber(f.ReorderingThreshold),
+		}}, nil
+	case "immediate_ack":
+		return qlog.Frame{Frame: &qlog.ImmediateAckFrame{}}, nil
+	default:
+		return qlog.Frame{}, fmt.Errorf("unknown frame type: %s", f.FrameType)
+	}
+}
+
+func (f *jsonFrame) toAckFrame() (qlog.Frame, error) {
+	ack := &qlog.AckFrame{
+		DelayTime: fromMilliseconds(f.AckDelay),
+		ECT0:      f.ECT0,
+		ECT1:      f.ECT1,
+		ECNCE:     f.CE,
+	}
+	if len(f.AckedRanges) > 0 {
+		ack.AckRanges = make([]qlog.AckRange, 0, len(f.AckedRanges))
+	}
+	for _, r := range f.AckedRanges {
+		switch len(r) {
+		case 1:
+			ack.AckRanges = append(ack.AckRanges, qlog.AckRange{
+				Smallest: protocol.PacketNumber(r[0]),
+				Largest:  protocol.PacketNumber(r[0]),
+			})
+		case 2:
+			ack.AckRanges = append(ack.AckRanges, qlog.AckRange{
+				Smallest: protocol.PacketNumber(r[0]),
+				Largest:  protocol.PacketNumber(r[1]),
+			})
+		default:
+			return qlog.Frame{}, fmt.Errorf("invalid ACK range: %v", r)
+		}
+	}
+	return qlog.Frame{Frame: ack}, nil
+}
+
+func (f *jsonFrame) streamErrorCode() (qerr.StreamErrorCode, error) {
+	var code uint64
+	if err := json.Unmarshal(f.ErrorCode, &code); err != nil {
+		return 0, fmt.Errorf("invalid error code: %w", err)
+	}
+	return qerr.StreamErrorCode(code), nil
+}
+
+func (f *jsonFrame) pathData() ([8]byte, error) {
+	var data [8]byte
+	b, err := parseHex(f.Data)
+	if err != nil {
+		return data, err
+	}
+	if len(b) != len(data) {
+		return data, fmt.Errorf("invalid path challenge data length: %d", len(b))
+	}
+	copy(data[:], b)
+	return data, nil
+}
+
+func parseStreamType(s string) (protocol.StreamType, error) {
+	switch s {
+	case "unidirectional":
+		return protocol.StreamTypeUni, nil
+	case "bidirectional":
+		return protocol.StreamTypeBidi, nil
+	default:
+		return 0, fmt.Errorf("unknown stream type: %s", s)
+	}
+}
@@ -0,0 +1,135 @@
+package qlogreader
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nukilabs/quic-go"
+	h3qlog "github.com/nukilabs/quic-go/http3/qlog"
+	"github.com/nukilabs/quic-go/qlogwriter"
+)
+
+type jsonHTTP3Setting struct {
+	Name      string          `json:"name"`
+	NameBytes uint64          `json:"name_bytes"`
+	Value     json.RawMessage `json:"value"`
+}
+
+type jsonHTTP3Frame struct {
+	FrameType    string `json:"frame_type"`
+	HeaderFields []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"header_fields"`
+	ID             uint64             `json:"id"`
+	Settings       []jsonHTTP3Setting `json:"settings"`
+	FrameTypeBytes uint64             `json:"frame_type_bytes"`
+}
+
+func (f *jsonHTTP3Frame) toFrame() (h3qlog.Frame, error) {
+	switch f.FrameType {
+	case "data":
+		return h3qlog.Frame{Frame: h3qlog.DataFrame{}}, nil
+	case "headers":
+		var hf h3qlog.HeadersFrame
+		for _, field := range f.HeaderFields {
+			hf.HeaderFields = append(hf.HeaderFields, h3qlog.HeaderField{Name: field.Name, Value: field.Value})
+		}
+		return h3qlog.Frame{Frame: hf}, nil
+	case "goaway":
+		return h3qlog.Frame{Frame: h3qlog.GoAwayFrame{StreamID: quic.StreamID(f.ID)}}, nil
+	case "settings":
+		sf, err := f.toSettingsFrame()
+		if err != nil {
+			return h3qlog.Frame{}, err
+		}
+		return h3qlog.Frame{Frame: sf}, nil
+	case "push_promise":
+		return h3qlog.Frame{Frame: h3qlog.PushPromiseFrame{}}, nil
+	case "cancel_push":
+		return h3qlog.Frame{Frame: h3qlog.CancelPushFrame{}}, nil
+	case "max_push_id":
+		return h3qlog.Frame{Frame: h3qlog.MaxPushIDFrame{}}, nil
+	case "reserved":
+		return h3qlog.Frame{Frame: h3qlog.ReservedFrame{Type: f.FrameTypeBytes}}, nil
+	case "unknown":
+		return h3qlog.Frame{Frame: h3qlog.UnknownFrame{Type: f.FrameTypeBytes}}, nil
+	default:
+		return h3qlog.Frame{}, fmt.Errorf("unknown HTTP/3 frame type: %s", f.FrameType)
+	}
+}
+
+func (f *jsonHTTP3Frame) toSettingsFrame() (h3qlog.SettingsFrame, error) {
+	sf := h3qlog.SettingsFrame{MaxFieldSectionSize: -1}
+	for _, s := range f.Settings {
+		switch s.Name {
+		case "settings_max_field_section_size":
+			if err := json.Unmarshal(s.Value, &sf.MaxFieldSectionSize); err != nil {
+				return sf, err
+			}
+		case "settings_h3_datagram":
+			var v bool
+			if err := json.Unmarshal(s.Value, &v); err != nil {
+				return sf, err
+			}
+			sf.Datagram = &v
+		case "settings_enable_connect_protocol":
+			var v bool
+			if err := json.Unmarshal(s.Value, &v); err != nil {
+				return sf, err
+			}
+			sf.ExtendedConnect = &v
+		default:
+			var v uint64
+			if err := json.Unmarshal(s.Value, &v); err != nil {
+				return sf, err
+			}
+			if sf.Other == nil {
+				sf.Other = make(map[uint64]uint64)
+			}
+			sf.Other[s.NameBytes] = v
+		}
+	}
+	return sf, nil
+}
+
+func decodeHTTP3FrameEvent(name string, data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		StreamID uint64         `json:"stream_id"`
+		Raw      jsonRawInfo    `json:"raw"`
+		Frame    jsonHTTP3Frame `json:"frame"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	frame, err := e.Frame.toFrame()
+	if err != nil {
+		return nil, err
+	}
+	if name == "http3:frame_created" {
+		return h3qlog.FrameCreated{
+			StreamID: quic.StreamID(e.StreamID),
+			Raw:      h3qlog.RawInfo(e.Raw),
+			Frame:    frame,
+		}, nil
+	}
+	return h3qlog.FrameParsed{
+		StreamID: quic.StreamID(e.StreamID),
+		Raw:      h3qlog.RawInfo(e.Raw),
+		Frame:    frame,
+	}, nil
+}
+
+func decodeHTTP3DatagramEvent(name string, data json.RawMessage) (qlogwriter.Event, error) {
+	var e struct {
+		QuarterStreamID uint64      `json:"quater_stream_id"`
+		Raw             jsonRawInfo `json:"raw"`
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	if name == "http3:datagram_created" {
+		return h3qlog.DatagramCreated{QuaterStreamID: e.QuarterStreamID, Raw: h3qlog.RawInfo(e.Raw)}, nil
+	}
+	return h3qlog.DatagramParsed{QuaterStreamID: e.QuarterStreamID, Raw: h3qlog.RawInfo(e.Raw)}, nil
+}
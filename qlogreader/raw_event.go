@@ -0,0 +1,94 @@
+package qlogreader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nukilabs/quic-go/qlogwriter"
+	"github.com/nukilabs/quic-go/qlogwriter/jsontext"
+)
+
+// A RawEvent is an event that doesn't correspond to any event type of the qlog or http3/qlog packages.
+// Data holds the decoded JSON value of the event's data field.
+// Objects are decoded into map[string]any, arrays into []any, and numbers into json.Number.
+type RawEvent struct {
+	EventName string
+	Data      any
+}
+
+var _ qlogwriter.Event = RawEvent{}
+
+func newRawEvent(name string, data json.RawMessage) (RawEvent, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if len(data) > 0 {
+		if err := dec.Decode(&v); err != nil {
+			return RawEvent{}, err
+		}
+	}
+	return RawEvent{EventName: name, Data: v}, nil
+}
+
+func (e RawEvent) Name() string { return e.EventName }
+
+// Encode encodes the event data.
+// Object keys are sorted, so the original order of the keys is not preserved.
+func (e RawEvent) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	return encodeValue(enc, e.Data)
+}
+
+func encodeValue(enc *jsontext.Encoder, v any) error {
+	switch v := v.(type) {
+	case nil:
+		return enc.WriteToken(jsontext.Null)
+	case bool:
+		return enc.WriteToken(jsontext.Bool(v))
+	case string:
+		return enc.WriteToken(jsontext.String(v))
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return enc.WriteToken(jsontext.Int(i))
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return enc.WriteToken(jsontext.Float(f))
+	case float64:
+		return enc.WriteToken(jsontext.Float(v))
+	case []any:
+		if err := enc.WriteToken(jsontext.BeginArray); err != nil {
+			return err
+		}
+		for _, elem := range v {
+			if err := encodeValue(enc, elem); err != nil {
+				return err
+			}
+		}
+		return enc.WriteToken(jsontext.EndArray)
+	case map[string]any:
+		if err := enc.WriteToken(jsontext.BeginObject); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if err := enc.WriteToken(jsontext.String(k)); err != nil {
+				return err
+			}
+			if err := encodeValue(enc, v[k]); err != nil {
+				return err
+			}
+		}
+		return enc.WriteToken(jsontext.EndObject)
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
@@ -0,0 +1,270 @@
+// Package qlogreader parses qlog traces written in the JSON-SEQ format,
+// as produced by the qlogwriter package.
+// Events are decoded into the event types of the qlog and the http3/qlog packages,
+// allowing traces to be analyzed programmatically.
+package qlogreader
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/qlog"
+	"github.com/nukilabs/quic-go/qlogwriter"
+)
+
+// Header is the header of a qlog trace.
+type Header struct {
+	Title       string
+	CodeVersion string
+	// VantagePoint is the type of the vantage point, i.e. "client", "server" or "transport".
+	VantagePoint string
+	// GroupID is the group ID of the trace.
+	// For connection traces, this is the original destination connection ID.
+	GroupID *qlog.ConnectionID
+	// ReferenceTime is the wall clock time that event times are relative to.
+	ReferenceTime time.Time
+	EventSchemas  []string
+}
+
+// An Event is a single event of a qlog trace.
+type Event struct {
+	// Time is the time of the event, relative to the reference time of the trace.
+	Time time.Duration
+	Name string
+	// Details is the decoded event, e.g. a qlog.PacketSent or an http3/qlog FrameParsed.
+	// Events that don't correspond to an event type of the qlog or http3/qlog packages
+	// are returned as a RawEvent.
+	Details qlogwriter.Event
+}
+
+// A Trace is a fully parsed qlog trace.
+type Trace struct {
+	Header Header
+	Events []Event
+}
+
+// A Reader reads events from a qlog trace.
+type Reader struct {
+	r      *bufio.Reader
+	header Header
+	eof    bool
+}
+
+// NewReader creates a new Reader, and parses the header of the trace.
+func NewReader(r io.Reader) (*Reader, error) {
+	rd := &Reader{r: bufio.NewReader(r)}
+	record, err := rd.nextRecord()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("qlogreader: empty trace")
+		}
+		return nil, err
+	}
+	hdr, err := parseHeader(record)
+	if err != nil {
+		return nil, err
+	}
+	rd.header = hdr
+	return rd, nil
+}
+
+// Header returns the header of the trace.
+func (r *Reader) Header() Header { return r.header }
+
+// ReadEvent reads the next event of the trace.
+// It returns io.EOF when the end of the trace is reached.
+// If the last record of the trace was truncated (e.g. because the process writing the trace crashed),
+// io.ErrUnexpectedEOF is returned.
+func (r *Reader) ReadEvent() (Event, error) {
+	for {
+		record, err := r.nextRecord()
+		if err != nil {
+			return Event{}, err
+		}
+		if len(record) == 0 {
+			continue
+		}
+		ev, err := parseEvent(record, r.header.ReferenceTime)
+		if err != nil {
+			if r.eof && isTruncated(err) {
+				return Event{}, io.ErrUnexpectedEOF
+			}
+			return Event{}, err
+		}
+		return ev, nil
+	}
+}
+
+// nextRecord returns the next JSON-SEQ record, without the record separator and surrounding whitespace.
+func (r *Reader) nextRecord() ([]byte, error) {
+	for {
+		if r.eof {
+			return nil, io.EOF
+		}
+		b, err := r.r.ReadBytes(qlogwriter.RecordSeparator)
+		if err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			r.eof = true
+		} else {
+			b = b[:len(b)-1]
+		}
+		b = bytes.TrimSpace(b)
+		// The first record is preceded by a record separator,
+		// so reading the first record returns an empty slice.
+		if len(b) == 0 {
+			continue
+		}
+		return b, nil
+	}
+}
+
+func isTruncated(err error) bool {
+	var syntaxErr *json.SyntaxError
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &syntaxErr)
+}
+
+// ReadAll reads a complete trace.
+func ReadAll(r io.Reader) (*Trace, error) {
+	rd, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	t := &Trace{Header: rd.Header()}
+	for {
+		ev, err := rd.ReadEvent()
+		if err != nil {
+			if err == io.EOF {
+				return t, nil
+			}
+			return t, err
+		}
+		t.Events = append(t.Events, ev)
+	}
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ReadFile reads a complete trace from a file.
+// Files compressed using gzip or zstd, as written by the qlog package, are decompressed transparently.
+func ReadFile(name string) (*Trace, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	// Peek returns fewer bytes if the file is shorter than the magic number.
+	magic, _ := r.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("qlogreader: failed to decompress trace: %w", err)
+		}
+		defer zr.Close()
+		return ReadAll(zr)
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("qlogreader: failed to decompress trace: %w", err)
+		}
+		defer zr.Close()
+		return ReadAll(zr)
+	default:
+		return ReadAll(r)
+	}
+}
+
+type jsonHeader struct {
+	Title       string `json:"title"`
+	CodeVersion string `json:"code_version"`
+	Trace       struct {
+		EventSchemas []string `json:"event_schemas"`
+		VantagePoint struct {
+			Type string `json:"type"`
+		} `json:"vantage_point"`
+		CommonFields struct {
+			GroupID       *string `json:"group_id"`
+			ReferenceTime struct {
+				WallClockTime string `json:"wall_clock_time"`
+			} `json:"reference_time"`
+		} `json:"common_fields"`
+	} `json:"trace"`
+}
+
+func parseHeader(b []byte) (Header, error) {
+	var h jsonHeader
+	if err := json.Unmarshal(b, &h); err != nil {
+		return Header{}, fmt.Errorf("qlogreader: failed to parse header: %w", err)
+	}
+	hdr := Header{
+		Title:        h.Title,
+		CodeVersion:  h.CodeVersion,
+		VantagePoint: h.Trace.VantagePoint.Type,
+		EventSchemas: h.Trace.EventSchemas,
+	}
+	if s := h.Trace.CommonFields.ReferenceTime.WallClockTime; s != "" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return Header{}, fmt.Errorf("qlogreader: invalid reference time: %w", err)
+		}
+		hdr.ReferenceTime = t
+	}
+	if h.Trace.CommonFields.GroupID != nil {
+		connID, err := parseConnectionID(*h.Trace.CommonFields.GroupID)
+		if err != nil {
+			return Header{}, fmt.Errorf("qlogreader: invalid group ID: %w", err)
+		}
+		hdr.GroupID = &connID
+	}
+	return hdr, nil
+}
+
+type jsonEvent struct {
+	Time float64         `json:"time"`
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+func parseEvent(b []byte, referenceTime time.Time) (Event, error) {
+	var e jsonEvent
+	if err := json.Unmarshal(b, &e); err != nil {
+		return Event{}, fmt.Errorf("qlogreader: failed to parse event: %w", err)
+	}
+	ev := Event{
+		Time: fromMilliseconds(e.Time),
+		Name: e.Name,
+	}
+	details, err := decodeEvent(e.Name, e.Data, referenceTime.Add(ev.Time))
+	if err != nil {
+		return Event{}, fmt.Errorf("qlogreader: failed to decode %s event: %w", e.Name, err)
+	}
+	ev.Details = details
+	return ev, nil
+}
+
+func parseConnectionID(s string) (protocol.ConnectionID, error) {
+	b, err := parseHex(s)
+	if err != nil {
+		return protocol.ConnectionID{}, err
+	}
+	if len(b) > protocol.MaxConnIDLen {
+		return protocol.ConnectionID{}, fmt.Errorf("connection ID too long: %d bytes", len(b))
+	}
+	return protocol.ParseConnectionID(b), nil
+}
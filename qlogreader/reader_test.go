@@ -0,0 +1,485 @@
+package qlogreader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	h3qlog "github.com/nukilabs/quic-go/http3/qlog"
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/qerr"
+	"github.com/nukilabs/quic-go/internal/synctest"
+	"github.com/nukilabs/quic-go/qlog"
+	"github.com/nukilabs/quic-go/qlogwriter"
+	"github.com/nukilabs/quic-go/qlogwriter/jsontext"
+
+	"github.com/stretchr/testify/require"
+)
+
+type nopWriteCloserImpl struct{ io.Writer }
+
+func (nopWriteCloserImpl) Close() error { return nil }
+
+func nopWriteCloser(w io.Writer) io.WriteCloser {
+	return &nopWriteCloserImpl{Writer: w}
+}
+
+// writeTrace writes a client trace containing the events, recorded one second apart.
+func writeTrace(t *testing.T, events ...qlogwriter.Event) []byte {
+	t.Helper()
+	var data []byte
+	synctest.Test(t, func(t *testing.T) { data = writeTraceInBubble(events...) })
+	return data
+}
+
+// writeTraceInBubble is like writeTrace, but must be called from within a synctest bubble.
+func writeTraceInBubble(events ...qlogwriter.Event) []byte {
+	var buf bytes.Buffer
+	tr := qlogwriter.NewConnectionFileSeq(
+		nopWriteCloser(&buf),
+		true,
+		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		[]string{qlog.EventSchema},
+	)
+	go tr.Run()
+	producer := tr.AddProducer()
+	for _, ev := range events {
+		time.Sleep(time.Second)
+		producer.RecordEvent(ev)
+	}
+	producer.Close()
+	return buf.Bytes()
+}
+
+func TestReadHeader(t *testing.T) {
+	var reference time.Time
+	synctest.Test(t, func(t *testing.T) { reference = time.Now() })
+
+	trace, err := ReadAll(bytes.NewReader(writeTrace(t)))
+	require.NoError(t, err)
+	require.Equal(t, "client", trace.Header.VantagePoint)
+	require.Equal(t, "quic-go qlog", trace.Header.Title)
+	require.Equal(t, []string{qlog.EventSchema}, trace.Header.EventSchemas)
+	require.NotNil(t, trace.Header.GroupID)
+	require.Equal(t, protocol.ParseConnectionID([]byte{1, 2, 3, 4}), *trace.Header.GroupID)
+	require.True(t, trace.Header.ReferenceTime.Equal(reference))
+	require.Empty(t, trace.Events)
+}
+
+func TestReadEvents(t *testing.T) {
+	rcid := protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad})
+	connErr := qerr.FlowControlError
+	cryptoErr := qerr.TransportErrorCode(0x12a)
+	unknownErr := qerr.TransportErrorCode(0x1337)
+	appErr := qerr.ApplicationErrorCode(42)
+	yes := true
+
+	events := []qlogwriter.Event{
+		qlog.StartedConnection{
+			Local:  qlog.PathEndpointInfo{IPv4: netip.MustParseAddrPort("192.168.13.37:42")},
+			Remote: qlog.PathEndpointInfo{IPv6: netip.MustParseAddrPort("[2001:db8::1]:24")},
+		},
+		qlog.VersionInformation{
+			ChosenVersion:  0x1337,
+			ClientVersions: []qlog.Version{1, 2, 3},
+			ServerVersions: []qlog.Version{4, 5, 6},
+		},
+		qlog.ParametersSet{
+			Initiator:                       qlog.InitiatorLocal,
+			SentBy:                          protocol.PerspectiveServer,
+			OriginalDestinationConnectionID: protocol.ParseConnectionID([]byte{0xde, 0xad, 0xc0, 0xde}),
+			InitialSourceConnectionID:       protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+			RetrySourceConnectionID:         &rcid,
+			StatelessResetToken:             &protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			DisableActiveMigration:          true,
+			MaxIdleTimeout:                  321 * time.Millisecond,
+			MaxUDPPayloadSize:               1234,
+			AckDelayExponent:                12,
+			MaxAckDelay:                     123 * time.Millisecond,
+			ActiveConnectionIDLimit:         7,
+			InitialMaxData:                  4000,
+			InitialMaxStreamDataBidiLocal:   1000,
+			InitialMaxStreamDataBidiRemote:  2000,
+			InitialMaxStreamDataUni:         3000,
+			InitialMaxStreamsBidi:           10,
+			InitialMaxStreamsUni:            20,
+			PreferredAddress: &qlog.PreferredAddress{
+				IPv4:                netip.MustParseAddrPort("12.34.56.78:123"),
+				IPv6:                netip.MustParseAddrPort("[102:304:506:708:90a:b0c:d0e:f10]:456"),
+				ConnectionID:        protocol.ParseConnectionID([]byte{8, 7, 6, 5, 4, 3, 2, 1}),
+				StatelessResetToken: protocol.StatelessResetToken{15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+			},
+			MaxDatagramFrameSize: 1337,
+			EnableResetStreamAt:  true,
+		},
+		qlog.ParametersSet{
+			Initiator:                 qlog.InitiatorRemote,
+			SentBy:                    protocol.PerspectiveClient,
+			InitialSourceConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			MaxDatagramFrameSize:      protocol.InvalidByteCount,
+		},
+		qlog.ParametersSet{
+			Restore:              true,
+			SentBy:               protocol.PerspectiveServer,
+			InitialMaxData:       100,
+			MaxDatagramFrameSize: protocol.InvalidByteCount,
+		},
+		qlog.ALPNInformation{ChosenALPN: "h3"},
+		qlog.PacketSent{
+			Header: qlog.PacketHeader{
+				PacketType:       qlog.PacketTypeInitial,
+				PacketNumber:     1337,
+				Version:          protocol.Version1,
+				SrcConnectionID:  protocol.ParseConnectionID([]byte{4, 3, 2, 1}),
+				DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}),
+				Token:            &qlog.Token{Raw: []byte{0xde, 0xad}},
+			},
+			Raw:         qlog.RawInfo{Length: 1200, PayloadLength: 1100},
+			DatagramID:  1234,
+			IsCoalesced: true,
+			ECN:         qlog.ECT0,
+			Trigger:     "pto_probe",
+			Frames: []qlog.Frame{
+				{Frame: &qlog.PingFrame{}},
+				{Frame: &qlog.CryptoFrame{Offset: 10, Length: 100}},
+				{Frame: &qlog.AckFrame{
+					AckRanges: []qlog.AckRange{{Smallest: 10, Largest: 15}, {Smallest: 5, Largest: 5}},
+					DelayTime: 12 * time.Millisecond,
+					ECT0:      1, ECT1: 2, ECNCE: 3,
+				}},
+			},
+		},
+		qlog.PacketReceived{
+			Header: qlog.PacketHeader{
+				PacketType:       qlog.PacketType1RTT,
+				PacketNumber:     42,
+				KeyPhaseBit:      qlog.KeyPhaseOne,
+				DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			},
+			Raw: qlog.RawInfo{Length: 1000},
+			ECN: qlog.ECNCE,
+			Frames: []qlog.Frame{
+				{Frame: &qlog.AckFrame{AckRanges: []qlog.AckRange{{Smallest: 1, Largest: 3}}}},
+				{Frame: &qlog.ResetStreamFrame{StreamID: 4, ErrorCode: 5, FinalSize: 6}},
+				{Frame: &qlog.ResetStreamFrame{StreamID: 8, ErrorCode: 9, FinalSize: 10, ReliableSize: 7}},
+				{Frame: &qlog.StopSendingFrame{StreamID: 12, ErrorCode: 13}},
+				{Frame: &qlog.NewTokenFrame{Token: []byte("token")}},
+				{Frame: &qlog.StreamFrame{StreamID: 4, Offset: 100, Length: 200, Fin: true}},
+				{Frame: &qlog.MaxDataFrame{MaximumData: 1000}},
+				{Frame: &qlog.MaxStreamDataFrame{StreamID: 4, MaximumStreamData: 2000}},
+				{Frame: &qlog.MaxStreamsFrame{Type: protocol.StreamTypeBidi, MaxStreamNum: 10}},
+				{Frame: &qlog.DataBlockedFrame{MaximumData: 3000}},
+				{Frame: &qlog.StreamDataBlockedFrame{StreamID: 8, MaximumStreamData: 4000}},
+				{Frame: &qlog.StreamsBlockedFrame{Type: protocol.StreamTypeUni, StreamLimit: 20}},
+				{Frame: &qlog.NewConnectionIDFrame{
+					SequenceNumber:      3,
+					RetirePriorTo:       1,
+					ConnectionID:        protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5}),
+					StatelessResetToken: protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+				}},
+				{Frame: &qlog.RetireConnectionIDFrame{SequenceNumber: 2}},
+				{Frame: &qlog.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}},
+				{Frame: &qlog.PathResponseFrame{Data: [8]byte{8, 7, 6, 5, 4, 3, 2, 1}}},
+				{Frame: &qlog.ConnectionCloseFrame{IsApplicationError: true, ErrorCode: 0x1337, ReasonPhrase: "foobar"}},
+				{Frame: &qlog.ConnectionCloseFrame{ErrorCode: uint64(qerr.ProtocolViolation)}},
+				{Frame: &qlog.HandshakeDoneFrame{}},
+				{Frame: &qlog.DatagramFrame{Length: 100}},
+				{Frame: &qlog.AckFrequencyFrame{
+					SequenceNumber:        1,
+					AckElicitingThreshold: 2,
+					RequestMaxAckDelay:    25 * time.Millisecond,
+					ReorderingThreshold:   3,
+				}},
+				{Frame: &qlog.ImmediateAckFrame{}},
+			},
+		},
+		qlog.PacketReceived{
+			Header: qlog.PacketHeader{
+				PacketType:       qlog.PacketTypeRetry,
+				PacketNumber:     protocol.InvalidPacketNumber,
+				Version:          protocol.Version2,
+				SrcConnectionID:  protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+				DestConnectionID: protocol.ParseConnectionID([]byte{5, 6, 7, 8}),
+				Token:            &qlog.Token{Raw: []byte{1, 2, 3}},
+			},
+			Raw: qlog.RawInfo{Length: 100},
+		},
+		qlog.VersionNegotiationReceived{
+			Header: qlog.PacketHeaderVersionNegotiation{
+				SrcConnectionID:  protocol.ArbitraryLenConnectionID{1, 2, 3, 4},
+				DestConnectionID: protocol.ArbitraryLenConnectionID{5, 6, 7, 8},
+			},
+			SupportedVersions: []qlog.Version{protocol.Version1, protocol.Version2},
+		},
+		qlog.VersionNegotiationSent{
+			Header: qlog.PacketHeaderVersionNegotiation{
+				SrcConnectionID:  protocol.ArbitraryLenConnectionID{1, 2, 3, 4},
+				DestConnectionID: protocol.ArbitraryLenConnectionID{5, 6, 7, 8},
+			},
+			SupportedVersions: []qlog.Version{protocol.Version1},
+		},
+		qlog.PacketBuffered{
+			Header: qlog.PacketHeader{
+				PacketType:   qlog.PacketTypeHandshake,
+				PacketNumber: protocol.InvalidPacketNumber,
+				Version:      protocol.Version1,
+			},
+			Raw:        qlog.RawInfo{Length: 1337},
+			DatagramID: 42,
+		},
+		qlog.PacketDropped{
+			Header: qlog.PacketHeader{
+				PacketType:   qlog.PacketTypeInitial,
+				PacketNumber: protocol.InvalidPacketNumber,
+				Version:      protocol.Version1,
+			},
+			Raw:     qlog.RawInfo{Length: 1337},
+			Trigger: qlog.PacketDropPayloadDecryptError,
+		},
+		qlog.ConnectionAdmission{
+			Remote:             qlog.PathEndpointInfo{IPv4: netip.MustParseAddrPort("1.2.3.4:5678")},
+			DestConnectionID:   protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			Action:             qlog.AdmissionActionRetry,
+			Reason:             "handshake_limit",
+			HandshakesInFlight: 42,
+		},
+		qlog.MTUUpdated{Value: 1337, Done: true},
+		qlog.MetricsUpdated{
+			MinRTT:           15 * time.Millisecond,
+			SmoothedRTT:      25 * time.Millisecond,
+			LatestRTT:        35 * time.Millisecond,
+			RTTVariance:      45 * time.Millisecond,
+			CongestionWindow: 4321,
+			BytesInFlight:    1234,
+			PacketsInFlight:  42,
+		},
+		qlog.PTOCountUpdated{PTOCount: 3},
+		qlog.PacketLost{
+			Header: qlog.PacketHeader{
+				PacketType:   qlog.PacketTypeHandshake,
+				PacketNumber: 42,
+			},
+			Trigger: qlog.PacketLossReorderingThreshold,
+		},
+		qlog.SpuriousLoss{
+			EncryptionLevel:  protocol.EncryptionHandshake,
+			PacketNumber:     42,
+			PacketReordering: 3,
+			TimeReordering:   123 * time.Millisecond,
+		},
+		qlog.LossTimerUpdated{
+			Type:      qlog.LossTimerUpdateTypeExpired,
+			TimerType: qlog.TimerTypeACK,
+			EncLevel:  protocol.EncryptionInitial,
+		},
+		qlog.CongestionStateUpdated{State: qlog.CongestionStateRecovery},
+		qlog.ECNStateUpdated{State: qlog.ECNStateFailed, Trigger: "ACK doesn't contain ECN marks"},
+		qlog.KeyUpdated{Trigger: qlog.KeyUpdateTLS, KeyType: qlog.KeyTypeServerHandshake},
+		qlog.KeyUpdated{
+			Trigger:  qlog.KeyUpdateLocal,
+			Reason:   qlog.KeyUpdateReasonPacketLimit,
+			KeyType:  qlog.KeyTypeClient1RTT,
+			KeyPhase: 1,
+		},
+		qlog.KeyDiscarded{KeyType: qlog.KeyTypeClientHandshake},
+		qlog.KeyDiscarded{KeyType: qlog.KeyTypeServer1RTT, KeyPhase: 42},
+		qlog.DebugEvent{Message: "foo"},
+		qlog.DebugEvent{EventName: "custom", Message: "bar"},
+		qlog.ConnectionClosed{Initiator: qlog.InitiatorLocal, ConnectionError: &connErr, Reason: "flow control"},
+		qlog.ConnectionClosed{Initiator: qlog.InitiatorRemote, ConnectionError: &cryptoErr, Reason: "crypto"},
+		qlog.ConnectionClosed{Initiator: qlog.InitiatorRemote, ConnectionError: &unknownErr, Reason: "unknown"},
+		qlog.ConnectionClosed{Initiator: qlog.InitiatorLocal, ApplicationError: &appErr, Reason: "app"},
+		qlog.ConnectionClosed{Initiator: qlog.InitiatorLocal, Trigger: qlog.ConnectionCloseTriggerIdleTimeout},
+		h3qlog.FrameParsed{
+			StreamID: 4,
+			Raw:      h3qlog.RawInfo{Length: 100, PayloadLength: 98},
+			Frame: h3qlog.Frame{Frame: h3qlog.HeadersFrame{HeaderFields: []h3qlog.HeaderField{
+				{Name: ":method", Value: "GET"},
+				{Name: ":path", Value: "/"},
+			}}},
+		},
+		h3qlog.FrameCreated{StreamID: 4, Frame: h3qlog.Frame{Frame: h3qlog.DataFrame{}}},
+		h3qlog.FrameCreated{StreamID: 3, Frame: h3qlog.Frame{Frame: h3qlog.SettingsFrame{
+			MaxFieldSectionSize: 1337,
+			Datagram:            &yes,
+			ExtendedConnect:     &yes,
+			Other:               map[uint64]uint64{0x42: 1},
+		}}},
+		h3qlog.FrameParsed{StreamID: 3, Frame: h3qlog.Frame{Frame: h3qlog.SettingsFrame{MaxFieldSectionSize: -1}}},
+		h3qlog.FrameParsed{StreamID: 3, Frame: h3qlog.Frame{Frame: h3qlog.GoAwayFrame{StreamID: 8}}},
+		h3qlog.FrameParsed{StreamID: 3, Frame: h3qlog.Frame{Frame: h3qlog.ReservedFrame{Type: 0x21}}},
+		h3qlog.FrameParsed{StreamID: 3, Frame: h3qlog.Frame{Frame: h3qlog.UnknownFrame{Type: 0x1337}}},
+		h3qlog.FrameParsed{StreamID: 3, Frame: h3qlog.Frame{Frame: h3qlog.CancelPushFrame{}}},
+		h3qlog.DatagramCreated{QuaterStreamID: 1, Raw: h3qlog.RawInfo{Length: 10}},
+		h3qlog.DatagramParsed{QuaterStreamID: 2, Raw: h3qlog.RawInfo{Length: 20, PayloadLength: 18}},
+	}
+
+	trace, err := ReadAll(bytes.NewReader(writeTrace(t, events...)))
+	require.NoError(t, err)
+	require.Len(t, trace.Events, len(events))
+	for i, ev := range trace.Events {
+		require.Equal(t, time.Duration(i+1)*time.Second, ev.Time)
+		require.Equal(t, events[i].Name(), ev.Name)
+		require.Equal(t, events[i], ev.Details)
+	}
+}
+
+func TestReadLossTimerUpdated(t *testing.T) {
+	var timerTime time.Time
+	var data []byte
+	synctest.Test(t, func(t *testing.T) {
+		// The event is recorded 1s after the trace is started.
+		timerTime = time.Now().Add(time.Second + 123*time.Millisecond)
+		data = writeTraceInBubble(qlog.LossTimerUpdated{
+			Type:      qlog.LossTimerUpdateTypeSet,
+			TimerType: qlog.TimerTypePTO,
+			EncLevel:  protocol.Encryption1RTT,
+			Time:      timerTime,
+		})
+	})
+
+	trace, err := ReadAll(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, trace.Events, 1)
+	ev, ok := trace.Events[0].Details.(qlog.LossTimerUpdated)
+	require.True(t, ok)
+	require.Equal(t, qlog.LossTimerUpdateTypeSet, ev.Type)
+	require.Equal(t, qlog.TimerTypePTO, ev.TimerType)
+	require.Equal(t, protocol.Encryption1RTT, ev.EncLevel)
+	require.True(t, ev.Time.Equal(timerTime), "expected %s, got %s", timerTime, ev.Time)
+}
+
+type unknownEvent struct{}
+
+func (unknownEvent) Name() string { return "foo:bar" }
+
+func (unknownEvent) Encode(enc *jsontext.Encoder, _ time.Time) error {
+	for _, tok := range []jsontext.Token{
+		jsontext.BeginObject,
+		jsontext.String("foo"),
+		jsontext.BeginArray,
+		jsontext.Int(1),
+		jsontext.Float(2.5),
+		jsontext.String("baz"),
+		jsontext.True,
+		jsontext.Null,
+		jsontext.EndArray,
+		jsontext.String("bar"),
+		jsontext.BeginObject,
+		jsontext.String("nested"),
+		jsontext.Int(42),
+		jsontext.EndObject,
+		jsontext.EndObject,
+	} {
+		if err := enc.WriteToken(tok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestReadUnknownEvent(t *testing.T) {
+	data := writeTrace(t, unknownEvent{})
+	trace, err := ReadAll(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, trace.Events, 1)
+	require.Equal(t, RawEvent{
+		EventName: "foo:bar",
+		Data: map[string]any{
+			"foo": []any{json.Number("1"), json.Number("2.5"), "baz", true, nil},
+			"bar": map[string]any{"nested": json.Number("42")},
+		},
+	}, trace.Events[0].Details)
+
+	// re-encoding the raw event produces the same JSON
+	reencoded := writeTrace(t, trace.Events[0].Details)
+	trace2, err := ReadAll(bytes.NewReader(reencoded))
+	require.NoError(t, err)
+	require.Equal(t, trace.Events[0].Details, trace2.Events[0].Details)
+}
+
+func TestReadTruncatedTrace(t *testing.T) {
+	data := writeTrace(t,
+		qlog.ALPNInformation{ChosenALPN: "h3"},
+		qlog.MTUUpdated{Value: 1337, Done: true},
+	)
+	data = data[:len(data)-10]
+
+	r, err := NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	ev, err := r.ReadEvent()
+	require.NoError(t, err)
+	require.Equal(t, qlog.ALPNInformation{ChosenALPN: "h3"}, ev.Details)
+	_, err = r.ReadEvent()
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestReadInvalidTrace(t *testing.T) {
+	_, err := ReadAll(bytes.NewReader(nil))
+	require.EqualError(t, err, "qlogreader: empty trace")
+
+	_, err = ReadAll(bytes.NewReader([]byte("\x1efoobar\n")))
+	require.ErrorContains(t, err, "qlogreader: failed to parse header")
+
+	// an invalid event in the middle of the trace
+	data := writeTrace(t, qlog.ALPNInformation{ChosenALPN: "h3"})
+	data = append(data, []byte("\x1e{\"time\": 1, \"name\": \"recovery:metrics_updated\", \"data\": {\"min_rtt\": \"foo\"}}\n")...)
+	data = append(data, []byte("\x1e{\"time\": 2, \"name\": \"transport:alpn_information\", \"data\": {\"chosen_alpn\": \"h3\"}}\n")...)
+	_, err = ReadAll(bytes.NewReader(data))
+	require.ErrorContains(t, err, "qlogreader: failed to decode recovery:metrics_updated event")
+}
+
+func TestReadFile(t *testing.T) {
+	data := writeTrace(t,
+		qlog.ALPNInformation{ChosenALPN: "h3"},
+		qlog.MTUUpdated{Value: 1337, Done: true},
+	)
+
+	t.Run("uncompressed", func(t *testing.T) {
+		testReadFile(t, "trace.sqlog", data)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		testReadFile(t, "trace.sqlog.gz", buf.Bytes())
+	})
+
+	t.Run("zstd", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		testReadFile(t, "trace.sqlog.zst", buf.Bytes())
+	})
+
+	t.Run("corrupted gzip", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "trace.sqlog.gz")
+		require.NoError(t, os.WriteFile(filename, []byte{0x1f, 0x8b, 0x42}, 0o644))
+		_, err := ReadFile(filename)
+		require.ErrorContains(t, err, "qlogreader: failed to decompress trace")
+	})
+}
+
+func testReadFile(t *testing.T, name string, data []byte) {
+	filename := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(filename, data, 0o644))
+	trace, err := ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, "client", trace.Header.VantagePoint)
+	require.Len(t, trace.Events, 2)
+	require.Equal(t, qlog.ALPNInformation{ChosenALPN: "h3"}, trace.Events[0].Details)
+	require.Equal(t, qlog.MTUUpdated{Value: 1337, Done: true}, trace.Events[1].Details)
+}
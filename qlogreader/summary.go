@@ -0,0 +1,238 @@
+package qlogreader
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/nukilabs/quic-go/qlog"
+)
+
+// A Milestone is a step of the handshake.
+type Milestone string
+
+const (
+	// MilestoneConnectionStarted is the start of the connection.
+	MilestoneConnectionStarted Milestone = "connection_started"
+	// MilestoneInitialSent is the first Initial packet sent.
+	MilestoneInitialSent Milestone = "initial_sent"
+	// MilestoneInitialReceived is the first Initial packet received.
+	MilestoneInitialReceived Milestone = "initial_received"
+	// MilestoneHandshakeKeys is the installation of the Handshake keys.
+	MilestoneHandshakeKeys Milestone = "handshake_keys"
+	// Milestone1RTTKeys is the installation of the 1-RTT keys.
+	Milestone1RTTKeys Milestone = "1rtt_keys"
+	// MilestoneHandshakeDone is the HANDSHAKE_DONE frame being sent (server) or received (client).
+	MilestoneHandshakeDone Milestone = "handshake_done"
+	// MilestoneHandshakeConfirmed is the handshake being confirmed, i.e. the Handshake keys being discarded.
+	MilestoneHandshakeConfirmed Milestone = "handshake_confirmed"
+)
+
+// A HandshakeEvent is a milestone of the handshake, and the time it was reached.
+type HandshakeEvent struct {
+	Milestone Milestone
+	Time      time.Duration
+}
+
+// A MetricsSample holds the RTT and congestion controller state after a recovery:metrics_updated event.
+// Since metrics_updated events only contain the values that changed,
+// values not contained in the event are carried over from the previous sample.
+type MetricsSample struct {
+	Time             time.Duration
+	MinRTT           time.Duration
+	SmoothedRTT      time.Duration
+	LatestRTT        time.Duration
+	RTTVariance      time.Duration
+	CongestionWindow int
+	BytesInFlight    int
+}
+
+// A LossEpisode is a series of packets declared lost in close succession.
+type LossEpisode struct {
+	Start, End time.Duration
+	Packets    int
+}
+
+// A StreamLifetime describes the life of a stream, as observed from the STREAM, RESET_STREAM and
+// RESET_STREAM_AT frames sent and received on the stream.
+type StreamLifetime struct {
+	ID qlog.StreamID
+	// Opened is the time of the first frame sent or received on the stream.
+	Opened time.Duration
+	// Closed is the time of the last frame sent or received on the stream.
+	Closed        time.Duration
+	BytesSent     int64
+	BytesReceived int64
+	FinSent       bool
+	FinReceived   bool
+	ResetSent     bool
+	ResetReceived bool
+}
+
+// Summary summarizes a connection trace.
+type Summary struct {
+	VantagePoint string
+	// Duration is the time of the last event of the trace.
+	Duration time.Duration
+
+	// Handshake contains the handshake milestones that were reached, in the order they were reached.
+	Handshake []HandshakeEvent
+	// Metrics contains one sample for every recovery:metrics_updated event.
+	Metrics      []MetricsSample
+	LossEpisodes []LossEpisode
+	// Streams is sorted by stream ID.
+	Streams []StreamLifetime
+	// Close is the connection_closed event, or nil if the trace doesn't contain one.
+	Close *qlog.ConnectionClosed
+
+	PacketsSent     int
+	PacketsReceived int
+	PacketsLost     int
+	PacketsDropped  int
+	BytesSent       int
+	BytesReceived   int
+	MaxPTOCount     uint32
+}
+
+// HandshakeTime returns the time the handshake milestone was reached.
+func (s *Summary) HandshakeTime(m Milestone) (time.Duration, bool) {
+	for _, ev := range s.Handshake {
+		if ev.Milestone == m {
+			return ev.Time, true
+		}
+	}
+	return 0, false
+}
+
+// Packets declared lost are considered part of the same loss episode
+// if they're declared lost within one smoothed RTT of the previous loss.
+// Before the first RTT sample, this value is used instead.
+const defaultLossEpisodeGap = 100 * time.Millisecond
+
+// Summarize summarizes a connection trace.
+func Summarize(t *Trace) *Summary {
+	s := &Summary{VantagePoint: t.Header.VantagePoint}
+	milestones := make(map[Milestone]struct{})
+	addMilestone := func(m Milestone, at time.Duration) {
+		if _, ok := milestones[m]; ok {
+			return
+		}
+		milestones[m] = struct{}{}
+		s.Handshake = append(s.Handshake, HandshakeEvent{Milestone: m, Time: at})
+	}
+	streams := make(map[qlog.StreamID]*StreamLifetime)
+	getStream := func(id qlog.StreamID, at time.Duration) *StreamLifetime {
+		str, ok := streams[id]
+		if !ok {
+			str = &StreamLifetime{ID: id, Opened: at}
+			streams[id] = str
+		}
+		str.Closed = at
+		return str
+	}
+	var metrics MetricsSample
+
+	for _, ev := range t.Events {
+		s.Duration = max(s.Duration, ev.Time)
+		switch e := ev.Details.(type) {
+		case qlog.StartedConnection:
+			addMilestone(MilestoneConnectionStarted, ev.Time)
+		case qlog.PacketSent:
+			s.PacketsSent++
+			s.BytesSent += e.Raw.Length
+			if e.Header.PacketType == qlog.PacketTypeInitial {
+				addMilestone(MilestoneInitialSent, ev.Time)
+			}
+			for _, f := range e.Frames {
+				switch frame := f.Frame.(type) {
+				case *qlog.StreamFrame:
+					str := getStream(frame.StreamID, ev.Time)
+					str.BytesSent = max(str.BytesSent, frame.Offset+frame.Length)
+					str.FinSent = str.FinSent || frame.Fin
+				case *qlog.ResetStreamFrame:
+					getStream(frame.StreamID, ev.Time).ResetSent = true
+				case *qlog.HandshakeDoneFrame:
+					addMilestone(MilestoneHandshakeDone, ev.Time)
+				}
+			}
+		case qlog.PacketReceived:
+			s.PacketsReceived++
+			s.BytesReceived += e.Raw.Length
+			if e.Header.PacketType == qlog.PacketTypeInitial {
+				addMilestone(MilestoneInitialReceived, ev.Time)
+			}
+			for _, f := range e.Frames {
+				switch frame := f.Frame.(type) {
+				case *qlog.StreamFrame:
+					str := getStream(frame.StreamID, ev.Time)
+					str.BytesReceived = max(str.BytesReceived, frame.Offset+frame.Length)
+					str.FinReceived = str.FinReceived || frame.Fin
+				case *qlog.ResetStreamFrame:
+					getStream(frame.StreamID, ev.Time).ResetReceived = true
+				case *qlog.HandshakeDoneFrame:
+					addMilestone(MilestoneHandshakeDone, ev.Time)
+				}
+			}
+		case qlog.PacketDropped:
+			s.PacketsDropped++
+		case qlog.PacketLost:
+			s.PacketsLost++
+			gap := metrics.SmoothedRTT
+			if gap == 0 {
+				gap = defaultLossEpisodeGap
+			}
+			if n := len(s.LossEpisodes); n > 0 && ev.Time-s.LossEpisodes[n-1].End <= gap {
+				s.LossEpisodes[n-1].End = ev.Time
+				s.LossEpisodes[n-1].Packets++
+			} else {
+				s.LossEpisodes = append(s.LossEpisodes, LossEpisode{Start: ev.Time, End: ev.Time, Packets: 1})
+			}
+		case qlog.MetricsUpdated:
+			metrics.Time = ev.Time
+			if e.MinRTT != 0 {
+				metrics.MinRTT = e.MinRTT
+			}
+			if e.SmoothedRTT != 0 {
+				metrics.SmoothedRTT = e.SmoothedRTT
+			}
+			if e.LatestRTT != 0 {
+				metrics.LatestRTT = e.LatestRTT
+			}
+			if e.RTTVariance != 0 {
+				metrics.RTTVariance = e.RTTVariance
+			}
+			if e.CongestionWindow != 0 {
+				metrics.CongestionWindow = e.CongestionWindow
+			}
+			// Zero values are omitted from the event, so a bytes_in_flight value dropping to zero
+			// can't be distinguished from an unchanged value.
+			if e.BytesInFlight != 0 {
+				metrics.BytesInFlight = e.BytesInFlight
+			}
+			s.Metrics = append(s.Metrics, metrics)
+		case qlog.PTOCountUpdated:
+			s.MaxPTOCount = max(s.MaxPTOCount, e.PTOCount)
+		case qlog.KeyUpdated:
+			switch e.KeyType {
+			case qlog.KeyTypeClientHandshake, qlog.KeyTypeServerHandshake:
+				addMilestone(MilestoneHandshakeKeys, ev.Time)
+			case qlog.KeyTypeClient1RTT, qlog.KeyTypeServer1RTT:
+				addMilestone(Milestone1RTTKeys, ev.Time)
+			}
+		case qlog.KeyDiscarded:
+			switch e.KeyType {
+			case qlog.KeyTypeClientHandshake, qlog.KeyTypeServerHandshake:
+				addMilestone(MilestoneHandshakeConfirmed, ev.Time)
+			}
+		case qlog.ConnectionClosed:
+			s.Close = &e
+		}
+	}
+
+	s.Streams = make([]StreamLifetime, 0, len(streams))
+	for _, str := range streams {
+		s.Streams = append(s.Streams, *str)
+	}
+	slices.SortFunc(s.Streams, func(a, b StreamLifetime) int { return cmp.Compare(a.ID, b.ID) })
+	return s
+}
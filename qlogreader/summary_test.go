@@ -0,0 +1,180 @@
+package qlogreader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/qerr"
+	"github.com/nukilabs/quic-go/qlog"
+	"github.com/nukilabs/quic-go/qlogwriter"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTrace(events ...qlogwriter.Event) *Trace {
+	t := &Trace{Header: Header{VantagePoint: "client"}}
+	for i, ev := range events {
+		t.Events = append(t.Events, Event{
+			Time:    time.Duration(i) * 10 * time.Millisecond,
+			Name:    ev.Name(),
+			Details: ev,
+		})
+	}
+	return t
+}
+
+func packetSent(typ qlog.PacketType, frames ...qlog.Frame) qlog.PacketSent {
+	return qlog.PacketSent{
+		Header: qlog.PacketHeader{PacketType: typ},
+		Raw:    qlog.RawInfo{Length: 1000},
+		Frames: frames,
+	}
+}
+
+func packetReceived(typ qlog.PacketType, frames ...qlog.Frame) qlog.PacketReceived {
+	return qlog.PacketReceived{
+		Header: qlog.PacketHeader{PacketType: typ},
+		Raw:    qlog.RawInfo{Length: 500},
+		Frames: frames,
+	}
+}
+
+func packetLost() qlog.PacketLost {
+	return qlog.PacketLost{Header: qlog.PacketHeader{PacketType: qlog.PacketType1RTT}}
+}
+
+func TestSummarizeHandshake(t *testing.T) {
+	s := Summarize(newTestTrace(
+		qlog.StartedConnection{},                                                           // 0ms
+		packetSent(qlog.PacketTypeInitial),                                                 // 10ms
+		packetSent(qlog.PacketTypeInitial),                                                 // 20ms
+		packetReceived(qlog.PacketTypeInitial),                                             // 30ms
+		qlog.KeyUpdated{KeyType: qlog.KeyTypeClientHandshake},                              // 40ms
+		qlog.KeyUpdated{KeyType: qlog.KeyTypeServerHandshake},                              // 50ms
+		qlog.KeyUpdated{KeyType: qlog.KeyTypeClient1RTT},                                   // 60ms
+		packetReceived(qlog.PacketType1RTT, qlog.Frame{Frame: &qlog.HandshakeDoneFrame{}}), // 70ms
+		qlog.KeyDiscarded{KeyType: qlog.KeyTypeClientHandshake},                            // 80ms
+		qlog.KeyDiscarded{KeyType: qlog.KeyTypeServerHandshake},                            // 90ms
+	))
+	require.Equal(t, "client", s.VantagePoint)
+	require.Equal(t, 90*time.Millisecond, s.Duration)
+	require.Equal(t, []HandshakeEvent{
+		{Milestone: MilestoneConnectionStarted, Time: 0},
+		{Milestone: MilestoneInitialSent, Time: 10 * time.Millisecond},
+		{Milestone: MilestoneInitialReceived, Time: 30 * time.Millisecond},
+		{Milestone: MilestoneHandshakeKeys, Time: 40 * time.Millisecond},
+		{Milestone: Milestone1RTTKeys, Time: 60 * time.Millisecond},
+		{Milestone: MilestoneHandshakeDone, Time: 70 * time.Millisecond},
+		{Milestone: MilestoneHandshakeConfirmed, Time: 80 * time.Millisecond},
+	}, s.Handshake)
+	d, ok := s.HandshakeTime(MilestoneHandshakeConfirmed)
+	require.True(t, ok)
+	require.Equal(t, 80*time.Millisecond, d)
+	require.Equal(t, 2, s.PacketsSent)
+	require.Equal(t, 2000, s.BytesSent)
+	require.Equal(t, 2, s.PacketsReceived)
+	require.Equal(t, 1000, s.BytesReceived)
+	require.Nil(t, s.Close)
+
+	s = Summarize(newTestTrace(qlog.StartedConnection{}, packetSent(qlog.PacketTypeInitial)))
+	_, ok = s.HandshakeTime(MilestoneHandshakeConfirmed)
+	require.False(t, ok)
+}
+
+func TestSummarizeMetrics(t *testing.T) {
+	s := Summarize(newTestTrace(
+		qlog.MetricsUpdated{CongestionWindow: 10000},
+		qlog.MetricsUpdated{MinRTT: 10 * time.Millisecond, SmoothedRTT: 12 * time.Millisecond, LatestRTT: 10 * time.Millisecond, BytesInFlight: 1200},
+		qlog.PTOCountUpdated{PTOCount: 2},
+		qlog.MetricsUpdated{LatestRTT: 15 * time.Millisecond, CongestionWindow: 20000},
+		qlog.PTOCountUpdated{PTOCount: 0},
+	))
+	require.Equal(t, []MetricsSample{
+		{Time: 0, CongestionWindow: 10000},
+		{
+			Time:             10 * time.Millisecond,
+			MinRTT:           10 * time.Millisecond,
+			SmoothedRTT:      12 * time.Millisecond,
+			LatestRTT:        10 * time.Millisecond,
+			CongestionWindow: 10000,
+			BytesInFlight:    1200,
+		},
+		{
+			Time:             30 * time.Millisecond,
+			MinRTT:           10 * time.Millisecond,
+			SmoothedRTT:      12 * time.Millisecond,
+			LatestRTT:        15 * time.Millisecond,
+			CongestionWindow: 20000,
+			BytesInFlight:    1200,
+		},
+	}, s.Metrics)
+	require.Equal(t, uint32(2), s.MaxPTOCount)
+}
+
+func TestSummarizeLossEpisodes(t *testing.T) {
+	// events are 10ms apart
+	s := Summarize(newTestTrace(
+		packetLost(), // 0ms: before the first RTT sample, 100ms is used to group losses
+		packetLost(),
+		qlog.MetricsUpdated{SmoothedRTT: 25 * time.Millisecond}, // 20ms
+		packetLost(), // 30ms
+		qlog.MTUUpdated{},
+		qlog.MTUUpdated{},
+		packetLost(), // 60ms: more than one RTT after the previous loss
+		packetLost(),
+		packetLost(),
+	))
+	require.Equal(t, 6, s.PacketsLost)
+	require.Equal(t, []LossEpisode{
+		{Start: 0, End: 30 * time.Millisecond, Packets: 3},
+		{Start: 60 * time.Millisecond, End: 80 * time.Millisecond, Packets: 3},
+	}, s.LossEpisodes)
+}
+
+func TestSummarizeStreams(t *testing.T) {
+	s := Summarize(newTestTrace(
+		packetSent(qlog.PacketType1RTT, qlog.Frame{Frame: &qlog.StreamFrame{StreamID: 4, Length: 100}}),                          // 0ms
+		packetSent(qlog.PacketType1RTT, qlog.Frame{Frame: &qlog.StreamFrame{StreamID: 0, Length: 1000}}),                         // 10ms
+		packetSent(qlog.PacketType1RTT, qlog.Frame{Frame: &qlog.StreamFrame{StreamID: 0, Offset: 1000, Length: 500, Fin: true}}), // 20ms
+		// retransmission
+		packetSent(qlog.PacketType1RTT, qlog.Frame{Frame: &qlog.StreamFrame{StreamID: 0, Offset: 0, Length: 1000}}),   // 30ms
+		packetReceived(qlog.PacketType1RTT, qlog.Frame{Frame: &qlog.StreamFrame{StreamID: 0, Length: 42, Fin: true}}), // 40ms
+		packetReceived(qlog.PacketType1RTT, qlog.Frame{Frame: &qlog.ResetStreamFrame{StreamID: 4}}),                   // 50ms
+		packetSent(qlog.PacketType1RTT, qlog.Frame{Frame: &qlog.ResetStreamFrame{StreamID: 8}}),                       // 60ms
+	))
+	require.Equal(t, []StreamLifetime{
+		{
+			ID:            0,
+			Opened:        10 * time.Millisecond,
+			Closed:        40 * time.Millisecond,
+			BytesSent:     1500,
+			BytesReceived: 42,
+			FinSent:       true,
+			FinReceived:   true,
+		},
+		{
+			ID:            4,
+			Opened:        0,
+			Closed:        50 * time.Millisecond,
+			BytesSent:     100,
+			ResetReceived: true,
+		},
+		{
+			ID:        8,
+			Opened:    60 * time.Millisecond,
+			Closed:    60 * time.Millisecond,
+			ResetSent: true,
+		},
+	}, s.Streams)
+}
+
+func TestSummarizeClose(t *testing.T) {
+	code := qerr.ApplicationErrorCode(42)
+	s := Summarize(newTestTrace(
+		qlog.PacketDropped{Header: qlog.PacketHeader{PacketType: qlog.PacketTypeHandshake, PacketNumber: protocol.InvalidPacketNumber}},
+		qlog.ConnectionClosed{Initiator: qlog.InitiatorRemote, ApplicationError: &code, Reason: "done"},
+	))
+	require.Equal(t, 1, s.PacketsDropped)
+	require.Equal(t, &qlog.ConnectionClosed{Initiator: qlog.InitiatorRemote, ApplicationError: &code, Reason: "done"}, s.Close)
+}
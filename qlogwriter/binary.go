@@ -0,0 +1,256 @@
+package qlogwriter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nukilabs/quic-go/qlogwriter/jsontext"
+)
+
+// BinaryMagic is the magic value at the start of a binary qlog file.
+// The last byte is the version of the encoding.
+const BinaryMagic = "QLOGBIN\x01"
+
+// binaryBufferSize is the size of the buffer used to write the binary trace
+const binaryBufferSize = 1 << 15
+
+// BinaryFileSeq is a qlog trace that uses a compact binary encoding instead of JSON-SEQ.
+// It is intended for tracing every connection in production, where the cost of FileSeq is prohibitive.
+// The resulting file can be converted to a JSON-SEQ qlog file using ConvertBinary.
+//
+// The file starts with BinaryMagic, followed by a sequence of records.
+// Every record is prefixed with its length (encoded as a varint).
+// The first record contains the trace header. Every following record contains an event:
+// the time offset to the reference time in nanoseconds (encoded as a zig-zag varint),
+// followed by the name and the data of the event, encoded using the binary encoding of the jsontext package.
+//
+// Unlike FileSeq, events are encoded synchronously when they are recorded, without spawning a goroutine.
+// Records are buffered, and written to the underlying io.WriteCloser when the buffer is full,
+// and when the last producer is closed.
+type BinaryFileSeq struct {
+	referenceTime time.Time
+	eventSchemas  []string
+
+	mx        sync.Mutex
+	w         io.WriteCloser
+	bw        *bufio.Writer
+	record    bytes.Buffer
+	scratch   [binary.MaxVarintLen64]byte
+	enc       *jsontext.Encoder
+	encodeErr error
+	producers int
+	closed    bool
+}
+
+var _ Trace = &BinaryFileSeq{}
+
+// NewBinaryFileSeq creates a new binary qlog trace to log transport events.
+func NewBinaryFileSeq(w io.WriteCloser) *BinaryFileSeq {
+	return newBinaryFileSeq(w, "transport", nil, nil)
+}
+
+// NewConnectionBinaryFileSeq creates a new binary qlog trace to log connection events.
+func NewConnectionBinaryFileSeq(w io.WriteCloser, isClient bool, odcid ConnectionID, eventSchemas []string) *BinaryFileSeq {
+	pers := "server"
+	if isClient {
+		pers = "client"
+	}
+	return newBinaryFileSeq(w, pers, &odcid, eventSchemas)
+}
+
+func newBinaryFileSeq(w io.WriteCloser, pers string, odcid *ConnectionID, eventSchemas []string) *BinaryFileSeq {
+	t := &BinaryFileSeq{
+		w:             w,
+		bw:            bufio.NewWriterSize(w, binaryBufferSize),
+		referenceTime: time.Now(),
+		eventSchemas:  eventSchemas,
+	}
+	t.enc = jsontext.NewBinaryEncoder(&t.record)
+	if _, err := t.bw.WriteString(BinaryMagic); err != nil {
+		t.encodeErr = err
+		return t
+	}
+	if err := (&traceHeader{
+		VantagePointType: pers,
+		GroupID:          odcid,
+		ReferenceTime:    t.referenceTime,
+		EventSchemas:     eventSchemas,
+	}).Encode(t.enc); err != nil {
+		panic(fmt.Sprintf("qlog encoding into a bytes.Buffer failed: %s", err))
+	}
+	t.writeRecord()
+	return t
+}
+
+func (t *BinaryFileSeq) SupportsSchemas(schema string) bool {
+	return slices.Contains(t.eventSchemas, schema)
+}
+
+func (t *BinaryFileSeq) AddProducer() Recorder {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if t.closed {
+		return nil
+	}
+
+	t.producers++
+
+	return &binaryWriter{t: t}
+}
+
+func (t *BinaryFileSeq) recordEvent(eventTime time.Time, ev Event) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	if t.closed || t.encodeErr != nil {
+		return
+	}
+	t.record.Write(binary.AppendVarint(t.scratch[:0], eventTime.Sub(t.referenceTime).Nanoseconds()))
+	if err := t.enc.WriteToken(jsontext.String(ev.Name())); err != nil {
+		panic(fmt.Sprintf("qlog encoding into a bytes.Buffer failed: %s", err))
+	}
+	if err := ev.Encode(t.enc, eventTime); err != nil {
+		t.encodeErr = err
+		t.record.Reset()
+		return
+	}
+	t.writeRecord()
+}
+
+// writeRecord writes the record that was encoded to t.record.
+func (t *BinaryFileSeq) writeRecord() {
+	if _, err := t.bw.Write(binary.AppendUvarint(t.scratch[:0], uint64(t.record.Len()))); err != nil {
+		t.encodeErr = err
+	} else if _, err := t.bw.Write(t.record.Bytes()); err != nil {
+		t.encodeErr = err
+	}
+	t.record.Reset()
+}
+
+func (t *BinaryFileSeq) removeProducer() {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	t.producers--
+	if t.producers > 0 {
+		return
+	}
+	t.closed = true
+	if t.encodeErr == nil {
+		t.encodeErr = t.bw.Flush()
+	}
+	if t.encodeErr != nil {
+		log.Printf("exporting qlog failed: %s\n", t.encodeErr)
+	}
+	_ = t.w.Close()
+}
+
+type binaryWriter struct {
+	t *BinaryFileSeq
+}
+
+func (w *binaryWriter) Close() error {
+	w.t.removeProducer()
+	return nil
+}
+
+func (w *binaryWriter) RecordEvent(ev Event) {
+	w.t.recordEvent(time.Now(), ev)
+}
+
+// ConvertBinary converts a binary qlog file written by BinaryFileSeq to a JSON-SEQ qlog file.
+// The output is identical to the output of FileSeq for the same events.
+//
+// If the binary file is truncated (e.g. because the application crashed),
+// all complete records are converted, and io.ErrUnexpectedEOF is returned.
+func ConvertBinary(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(BinaryMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != BinaryMagic {
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		return errors.New("qlogwriter: not a binary qlog file")
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := jsontext.NewEncoder(bw)
+	dec := jsontext.NewBinaryDecoder(nil)
+	var buf bytes.Buffer
+	for i := 0; ; i++ {
+		l, err := binary.ReadUvarint(br)
+		if err == io.EOF && i > 0 {
+			return bw.Flush()
+		}
+		if err == nil && l > math.MaxInt64 {
+			err = fmt.Errorf("invalid record length: %d", l)
+		}
+		if err == nil {
+			// The buffer grows as data is read, such that a corrupted length doesn't cause a huge allocation.
+			buf.Reset()
+			var n int64
+			n, err = io.CopyN(&buf, br, int64(l))
+			if err == io.EOF && n < int64(l) {
+				err = io.ErrUnexpectedEOF
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return errors.Join(err, bw.Flush())
+		}
+		dec.Reset(&buf)
+
+		if _, err := bw.Write(recordSeparator); err != nil {
+			return err
+		}
+		if i == 0 {
+			err = jsontext.CopyValue(enc, dec)
+		} else {
+			err = convertEvent(enc, dec, &buf)
+		}
+		if err == nil && buf.Len() > 0 {
+			err = errors.New("unexpected data at the end of the record")
+		}
+		if err != nil {
+			return errors.Join(fmt.Errorf("qlogwriter: invalid record %d: %w", i, err), bw.Flush())
+		}
+	}
+}
+
+func convertEvent(enc *jsontext.Encoder, dec *jsontext.Decoder, record io.ByteReader) error {
+	offset, err := binary.ReadVarint(record)
+	if err != nil {
+		return err
+	}
+	h := encoderHelper{enc: enc}
+	h.WriteToken(jsontext.BeginObject)
+	h.WriteToken(jsontext.String("time"))
+	h.WriteToken(jsontext.Float(float64(offset) / 1e6))
+	h.WriteToken(jsontext.String("name"))
+	if h.err != nil {
+		return h.err
+	}
+	if err := jsontext.CopyValue(enc, dec); err != nil {
+		return err
+	}
+	h.WriteToken(jsontext.String("data"))
+	if h.err != nil {
+		return h.err
+	}
+	if err := jsontext.CopyValue(enc, dec); err != nil {
+		return err
+	}
+	h.WriteToken(jsontext.EndObject)
+	return h.err
+}
@@ -0,0 +1,114 @@
+package qlogwriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryConversion(t *testing.T) {
+	var jsonBuf, binBuf bytes.Buffer
+	odcid := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})
+	fileSeq := NewConnectionFileSeq(nopWriteCloser(&jsonBuf), true, odcid, []string{"urn:ietf:params:qlog:events:quic-12"})
+	binSeq := NewConnectionBinaryFileSeq(nopWriteCloser(&binBuf), true, odcid, []string{"urn:ietf:params:qlog:events:quic-12"})
+	binSeq.referenceTime = fileSeq.referenceTime
+	go fileSeq.Run()
+	fileSeqRecorder := fileSeq.AddProducer()
+	binRecorder := binSeq.AddProducer()
+
+	for i := range 100 {
+		ev := testEvent{message: fmt.Sprintf("message %d", i%10)}
+		if i == 50 {
+			ev.message = strings.Repeat("long message with \"special\" characters\n", 10)
+		}
+		eventTime := fileSeq.referenceTime.Add(time.Duration(i) * 1337 * time.Microsecond)
+		fileSeq.record(eventTime, ev)
+		binSeq.recordEvent(eventTime, ev)
+	}
+	require.NoError(t, fileSeqRecorder.Close())
+	require.NoError(t, binRecorder.Close())
+	require.Less(t, binBuf.Len(), jsonBuf.Len()/2)
+
+	var converted bytes.Buffer
+	require.NoError(t, ConvertBinary(&converted, &binBuf))
+	expected := bytes.Split(jsonBuf.Bytes(), recordSeparator)
+	actual := bytes.Split(converted.Bytes(), recordSeparator)
+	require.Len(t, actual, 102)
+	require.Equal(t, len(expected), len(actual))
+	// The reference time of the header is different, since the traces were created at different times.
+	var expectedHeader, actualHeader map[string]any
+	require.NoError(t, json.Unmarshal(expected[1], &expectedHeader))
+	require.NoError(t, json.Unmarshal(actual[1], &actualHeader))
+	delete(expectedHeader["trace"].(map[string]any)["common_fields"].(map[string]any), "reference_time")
+	delete(actualHeader["trace"].(map[string]any)["common_fields"].(map[string]any), "reference_time")
+	require.Equal(t, expectedHeader, actualHeader)
+	for i := 2; i < len(expected); i++ {
+		require.Equal(t, string(expected[i]), string(actual[i]))
+	}
+}
+
+func TestBinaryConversionTruncated(t *testing.T) {
+	var binBuf bytes.Buffer
+	binSeq := NewBinaryFileSeq(nopWriteCloser(&binBuf))
+	recorder := binSeq.AddProducer()
+	recorder.RecordEvent(testEvent{message: "foo"})
+	recorder.RecordEvent(testEvent{message: "bar"})
+	require.NoError(t, recorder.Close())
+
+	for _, l := range []int{0, 4} {
+		require.ErrorContains(t, ConvertBinary(io.Discard, bytes.NewReader(binBuf.Bytes()[:l])), "not a binary qlog file")
+	}
+	require.ErrorIs(t, ConvertBinary(io.Discard, bytes.NewReader(binBuf.Bytes()[:len(BinaryMagic)])), io.ErrUnexpectedEOF)
+	// the last event is truncated
+	var converted bytes.Buffer
+	require.ErrorIs(t, ConvertBinary(&converted, bytes.NewReader(binBuf.Bytes()[:binBuf.Len()-1])), io.ErrUnexpectedEOF)
+	records := bytes.Split(converted.Bytes(), recordSeparator)
+	require.Len(t, records, 3)
+	require.Contains(t, string(records[2]), `"message":"foo"`)
+}
+
+func TestBinaryConversionInvalid(t *testing.T) {
+	require.ErrorContains(t, ConvertBinary(io.Discard, strings.NewReader("not a qlog file")), "not a binary qlog file")
+
+	var binBuf bytes.Buffer
+	binSeq := NewBinaryFileSeq(nopWriteCloser(&binBuf))
+	recorder := binSeq.AddProducer()
+	recorder.RecordEvent(testEvent{message: "foo"})
+	require.NoError(t, recorder.Close())
+	// corrupt the tag of the last token of the event
+	data := binBuf.Bytes()
+	data[len(data)-1] = 0xff
+	require.ErrorContains(t, ConvertBinary(io.Discard, bytes.NewReader(data)), "invalid record 1")
+}
+
+func TestBinaryWritingStopping(t *testing.T) {
+	binSeq := NewBinaryFileSeq(&limitedWriter{WriteCloser: nopWriteCloser(io.Discard), N: binaryBufferSize})
+	writer := binSeq.AddProducer()
+
+	for i := range 10000 {
+		writer.RecordEvent(testEvent{message: fmt.Sprintf("test message %d", i)})
+	}
+
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(os.Stdout)
+
+	require.NoError(t, writer.Close())
+	require.Contains(t, logBuf.String(), "writer full")
+
+	// events after closing are ignored
+	logBuf.Reset()
+	writer.RecordEvent(testEvent{message: "foobar"})
+	require.Empty(t, logBuf.String())
+	require.Nil(t, binSeq.AddProducer())
+}
@@ -0,0 +1,268 @@
+package qlogwriter
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nukilabs/quic-go/qlogwriter/jsontext"
+)
+
+// DefaultFlightRecorderMaxEvents is the default number of events a FlightRecorder keeps in memory.
+const DefaultFlightRecorderMaxEvents = 1000
+
+// FlightRecorderConfig configures a FlightRecorder.
+type FlightRecorderConfig struct {
+	// MaxEvents is the maximum number of events kept in memory.
+	// When the limit is reached, the oldest event is discarded.
+	// If zero, DefaultFlightRecorderMaxEvents is used.
+	MaxEvents int
+	// MaxAge is the maximum age of the events kept in memory.
+	// If zero, events are only discarded when MaxEvents is reached.
+	MaxAge time.Duration
+	// ShouldFlush is called for every recorded event.
+	// Once it returns true, the events are written to the Sink when the trace is closed,
+	// i.e. when the last producer is closed.
+	// qlog.ConnectionFailed can be used to write the events of connections that failed.
+	// If nil, events are only written when Dump is called.
+	ShouldFlush func(Event) bool
+	// Sink returns the io.WriteCloser that the events are written to.
+	// It is called every time the events are written.
+	// The events are written as a JSON-SEQ qlog file.
+	Sink func() (io.WriteCloser, error)
+}
+
+// FlightRecorder is a qlog trace that keeps the most recent events in memory.
+// The events are only written if the connection fails (see FlightRecorderConfig.ShouldFlush),
+// or when the application requests it by calling Dump or WriteTo.
+// This allows keeping the qlogs of failed connections, without paying the cost of writing qlogs for every connection.
+//
+// When used as the quic.Config.Tracer, the FlightRecorder of a connection can be obtained
+// by type-asserting the trace returned by quic.Conn.QlogTrace.
+type FlightRecorder struct {
+	config        FlightRecorderConfig
+	maxEvents     int
+	pers          string
+	odcid         *ConnectionID
+	referenceTime time.Time
+	eventSchemas  []string
+
+	mx sync.Mutex
+	// events is used as a ring buffer once it contains maxEvents events
+	events      []event
+	head        int
+	shouldFlush bool
+	producers   int
+	closed      bool
+}
+
+var (
+	_ Trace       = &FlightRecorder{}
+	_ io.WriterTo = &FlightRecorder{}
+)
+
+// NewFlightRecorder creates a new FlightRecorder to record transport events.
+func NewFlightRecorder(conf *FlightRecorderConfig) *FlightRecorder {
+	return newFlightRecorder("transport", nil, nil, conf)
+}
+
+// NewConnectionFlightRecorder creates a new FlightRecorder to record connection events.
+// Events from all schemas in eventSchemas are recorded.
+func NewConnectionFlightRecorder(isClient bool, odcid ConnectionID, eventSchemas []string, conf *FlightRecorderConfig) *FlightRecorder {
+	pers := "server"
+	if isClient {
+		pers = "client"
+	}
+	return newFlightRecorder(pers, &odcid, eventSchemas, conf)
+}
+
+func newFlightRecorder(pers string, odcid *ConnectionID, eventSchemas []string, conf *FlightRecorderConfig) *FlightRecorder {
+	if conf == nil {
+		conf = &FlightRecorderConfig{}
+	}
+	maxEvents := conf.MaxEvents
+	if maxEvents <= 0 {
+		maxEvents = DefaultFlightRecorderMaxEvents
+	}
+	return &FlightRecorder{
+		config:        *conf,
+		maxEvents:     maxEvents,
+		pers:          pers,
+		odcid:         odcid,
+		referenceTime: time.Now(),
+		eventSchemas:  eventSchemas,
+	}
+}
+
+func (r *FlightRecorder) SupportsSchemas(schema string) bool {
+	return slices.Contains(r.eventSchemas, schema)
+}
+
+func (r *FlightRecorder) AddProducer() Recorder {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.closed {
+		return nil
+	}
+
+	r.producers++
+
+	return &flightRecorderWriter{r: r}
+}
+
+func (r *FlightRecorder) record(eventTime time.Time, ev Event) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.closed {
+		return
+	}
+	if !r.shouldFlush && r.config.ShouldFlush != nil && r.config.ShouldFlush(ev) {
+		r.shouldFlush = true
+	}
+	r.discardOldEvents(eventTime)
+	e := event{Time: eventTime, Event: ev}
+	if len(r.events) < r.maxEvents {
+		r.events = append(r.events, e)
+		return
+	}
+	r.events[r.head] = e
+	r.head = (r.head + 1) % len(r.events)
+}
+
+// discardOldEvents discards the events older than MaxAge.
+// Since the buffer is only compacted when events are discarded, this is cheap in the common case.
+func (r *FlightRecorder) discardOldEvents(now time.Time) {
+	if r.config.MaxAge <= 0 || len(r.events) == 0 {
+		return
+	}
+	deadline := now.Add(-r.config.MaxAge)
+	if !r.events[r.head].Time.Before(deadline) {
+		return
+	}
+	events := r.orderedEvents()
+	i, _ := slices.BinarySearchFunc(events, deadline, func(e event, t time.Time) int { return e.Time.Compare(t) })
+	// move the remaining events to the start of the buffer, and release the discarded events
+	n := copy(r.events, events[i:])
+	clear(r.events[n:])
+	r.events = r.events[:n]
+	r.head = 0
+}
+
+// orderedEvents returns the recorded events, ordered from the oldest to the newest.
+// The returned slice may alias the buffer.
+func (r *FlightRecorder) orderedEvents() []event {
+	if r.head == 0 {
+		return r.events
+	}
+	return append(slices.Clone(r.events[r.head:]), r.events[:r.head]...)
+}
+
+// snapshot returns a copy of the recorded events, ordered from the oldest to the newest.
+func (r *FlightRecorder) snapshot() []event {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.discardOldEvents(time.Now())
+	return slices.Clone(r.orderedEvents())
+}
+
+// WriteTo writes the events that are currently kept in memory to w, as a JSON-SEQ qlog file.
+// The events are retained.
+func (r *FlightRecorder) WriteTo(w io.Writer) (int64, error) {
+	return r.writeEvents(w, r.snapshot())
+}
+
+// Dump writes the events that are currently kept in memory to a new io.WriteCloser obtained from the Sink.
+// The events are retained.
+func (r *FlightRecorder) Dump() error {
+	return r.dump(r.snapshot())
+}
+
+func (r *FlightRecorder) dump(events []event) error {
+	if r.config.Sink == nil {
+		return errors.New("qlogwriter: no sink configured")
+	}
+	w, err := r.config.Sink()
+	if err != nil {
+		return err
+	}
+	_, err = r.writeEvents(w, events)
+	return errors.Join(err, w.Close())
+}
+
+func (r *FlightRecorder) writeEvents(w io.Writer, events []event) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	enc := jsontext.NewEncoder(bw)
+	if _, err := bw.Write(recordSeparator); err != nil {
+		return cw.n, err
+	}
+	if err := (&traceHeader{
+		VantagePointType: r.pers,
+		GroupID:          r.odcid,
+		ReferenceTime:    r.referenceTime,
+		EventSchemas:     r.eventSchemas,
+	}).Encode(enc); err != nil {
+		return cw.n, err
+	}
+	for _, e := range events {
+		if err := encodeEvent(bw, enc, r.referenceTime, e); err != nil {
+			return cw.n, err
+		}
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func (r *FlightRecorder) removeProducer() {
+	r.mx.Lock()
+	r.producers--
+	if r.producers > 0 {
+		r.mx.Unlock()
+		return
+	}
+	r.closed = true
+	var events []event
+	if r.shouldFlush {
+		events = r.orderedEvents()
+	}
+	// release the memory, the events can't be dumped anymore once the trace is closed
+	r.events = nil
+	r.head = 0
+	r.mx.Unlock()
+
+	if events != nil {
+		if err := r.dump(events); err != nil {
+			log.Printf("exporting qlog failed: %s\n", err)
+		}
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+type flightRecorderWriter struct {
+	r *FlightRecorder
+}
+
+func (w *flightRecorderWriter) Close() error {
+	w.r.removeProducer()
+	return nil
+}
+
+func (w *flightRecorderWriter) RecordEvent(ev Event) {
+	w.r.record(time.Now(), ev)
+}
@@ -0,0 +1,194 @@
+package qlogwriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+type closeSignalingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeSignalingBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+// recordedMessages parses a JSON-SEQ qlog file written by the FlightRecorder,
+// and returns the messages of the testEvents.
+func recordedMessages(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	records := bytes.Split(data, recordSeparator)
+	require.GreaterOrEqual(t, len(records), 2)
+	require.Empty(t, records[0])
+	var header map[string]any
+	require.NoError(t, json.Unmarshal(records[1], &header))
+	require.Contains(t, header, "trace")
+
+	var messages []string
+	for _, record := range records[2:] {
+		var ev struct {
+			Name string
+			Data struct{ Message string }
+		}
+		require.NoError(t, json.Unmarshal(record, &ev))
+		require.Equal(t, "transport:test_event", ev.Name)
+		messages = append(messages, ev.Data.Message)
+	}
+	return messages
+}
+
+func TestFlightRecorderMaxEvents(t *testing.T) {
+	r := NewConnectionFlightRecorder(
+		true,
+		protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		[]string{"urn:ietf:params:qlog:events:quic-12"},
+		&FlightRecorderConfig{MaxEvents: 3},
+	)
+	require.True(t, r.SupportsSchemas("urn:ietf:params:qlog:events:quic-12"))
+	require.False(t, r.SupportsSchemas("urn:ietf:params:qlog:events:http3-12"))
+	recorder := r.AddProducer()
+	defer recorder.Close()
+
+	var buf bytes.Buffer
+	_, err := r.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Empty(t, recordedMessages(t, buf.Bytes()))
+
+	recorder.RecordEvent(testEvent{message: "foo"})
+	recorder.RecordEvent(testEvent{message: "bar"})
+	buf.Reset()
+	n, err := r.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(buf.Len()), n)
+	require.Equal(t, []string{"foo", "bar"}, recordedMessages(t, buf.Bytes()))
+
+	for i := range 10 {
+		recorder.RecordEvent(testEvent{message: fmt.Sprintf("event %d", i)})
+		buf.Reset()
+		_, err = r.WriteTo(&buf)
+		require.NoError(t, err)
+		var expected []string
+		for j := max(0, i-2); j <= i; j++ {
+			expected = append(expected, fmt.Sprintf("event %d", j))
+		}
+		if i < 2 {
+			expected = append([]string{"foo", "bar"}[i:], expected...)
+		}
+		require.Equal(t, expected, recordedMessages(t, buf.Bytes()))
+	}
+}
+
+func TestFlightRecorderMaxAge(t *testing.T) {
+	r := NewFlightRecorder(&FlightRecorderConfig{MaxEvents: 5, MaxAge: time.Second})
+	recorder := r.AddProducer()
+	defer recorder.Close()
+
+	start := time.Now().Add(-time.Hour)
+	for i := range 8 {
+		r.record(start.Add(time.Duration(i)*300*time.Millisecond), testEvent{message: fmt.Sprintf("event %d", i)})
+	}
+	// The last event was recorded at 2.1s. Events recorded before 1.1s are discarded.
+	r.mx.Lock()
+	r.discardOldEvents(start.Add(2100 * time.Millisecond))
+	events := r.orderedEvents()
+	r.mx.Unlock()
+	var messages []string
+	for _, e := range events {
+		messages = append(messages, e.Event.(testEvent).message)
+	}
+	require.Equal(t, []string{"event 4", "event 5", "event 6", "event 7"}, messages)
+
+	// when dumping, events are discarded based on the current time
+	var buf bytes.Buffer
+	_, err := r.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Empty(t, recordedMessages(t, buf.Bytes()))
+}
+
+func TestFlightRecorderFlushOnClose(t *testing.T) {
+	for _, flush := range []bool{true, false} {
+		t.Run(fmt.Sprintf("flush: %t", flush), func(t *testing.T) {
+			var sinks []*closeSignalingBuffer
+			r := NewFlightRecorder(&FlightRecorderConfig{
+				ShouldFlush: func(ev Event) bool { return flush && ev.(testEvent).message == "failed" },
+				Sink: func() (io.WriteCloser, error) {
+					sinks = append(sinks, &closeSignalingBuffer{})
+					return sinks[len(sinks)-1], nil
+				},
+			})
+			recorder1 := r.AddProducer()
+			recorder2 := r.AddProducer()
+			recorder1.RecordEvent(testEvent{message: "foo"})
+			recorder2.RecordEvent(testEvent{message: "failed"})
+			recorder2.RecordEvent(testEvent{message: "bar"})
+			require.NoError(t, recorder1.Close())
+			require.Empty(t, sinks)
+			require.NoError(t, recorder2.Close())
+			require.Nil(t, r.AddProducer())
+
+			if !flush {
+				require.Empty(t, sinks)
+				return
+			}
+			require.Len(t, sinks, 1)
+			require.True(t, sinks[0].closed)
+			require.Equal(t, []string{"foo", "failed", "bar"}, recordedMessages(t, sinks[0].Bytes()))
+		})
+	}
+}
+
+func TestFlightRecorderDump(t *testing.T) {
+	var sinks []*closeSignalingBuffer
+	r := NewFlightRecorder(&FlightRecorderConfig{
+		Sink: func() (io.WriteCloser, error) {
+			sinks = append(sinks, &closeSignalingBuffer{})
+			return sinks[len(sinks)-1], nil
+		},
+	})
+	recorder := r.AddProducer()
+	recorder.RecordEvent(testEvent{message: "foo"})
+	require.NoError(t, r.Dump())
+	recorder.RecordEvent(testEvent{message: "bar"})
+	require.NoError(t, r.Dump())
+	require.NoError(t, recorder.Close())
+
+	require.Len(t, sinks, 2)
+	require.True(t, sinks[0].closed)
+	require.Equal(t, []string{"foo"}, recordedMessages(t, sinks[0].Bytes()))
+	require.True(t, sinks[1].closed)
+	require.Equal(t, []string{"foo", "bar"}, recordedMessages(t, sinks[1].Bytes()))
+}
+
+func TestFlightRecorderSinkErrors(t *testing.T) {
+	require.ErrorContains(t, NewFlightRecorder(nil).Dump(), "no sink configured")
+
+	testErr := errors.New("test error")
+	r := NewFlightRecorder(&FlightRecorderConfig{
+		ShouldFlush: func(Event) bool { return true },
+		Sink:        func() (io.WriteCloser, error) { return nil, testErr },
+	})
+	require.ErrorIs(t, r.Dump(), testErr)
+
+	recorder := r.AddProducer()
+	recorder.RecordEvent(testEvent{message: "foo"})
+
+	var logBuf bytes.Buffer
+	log.SetOutput(&logBuf)
+	defer log.SetOutput(os.Stdout)
+	require.NoError(t, recorder.Close())
+	require.Contains(t, logBuf.String(), "test error")
+}
@@ -0,0 +1,309 @@
+package jsontext
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unsafe"
+)
+
+// The binary encoding is a compact representation of a stream of JSON tokens.
+// Every token starts with a tag byte, followed by the token's payload (if any).
+// Integers are encoded as (zig-zag) varints, floats as 8 byte IEEE 754 numbers.
+//
+// Short strings are interned: The first occurrence of a string is added to the string table,
+// subsequent occurrences reference the table by index. Since qlog events repeat the same keys
+// and values over and over, this makes up for most of the size reduction compared to JSON.
+// The string table is shared by all tokens written to (and read from) the same Encoder (Decoder).
+const (
+	binaryTagNull byte = iota
+	binaryTagFalse
+	binaryTagTrue
+	binaryTagInt          // zig-zag varint
+	binaryTagUint         // varint
+	binaryTagFloat        // 8 bytes, little endian
+	binaryTagString       // varint length, followed by the string
+	binaryTagStringIntern // like binaryTagString, and the string is added to the string table
+	binaryTagStringRef    // varint index into the string table
+	binaryTagBeginObject
+	binaryTagEndObject
+	binaryTagBeginArray
+	binaryTagEndArray
+)
+
+const (
+	// maxInternedStringLen is the maximum length of a string that is added to the string table.
+	// Longer strings (e.g. error reasons) are unlikely to be repeated.
+	maxInternedStringLen = 64
+	// maxInternedStrings is the maximum size of the string table.
+	maxInternedStrings = 1 << 16
+)
+
+// Kind represents the kind of a token.
+// The values are the same as for the standard library's encoding/json/jsontext package.
+type Kind byte
+
+// Kind returns the kind of the token.
+func (t Token) Kind() Kind {
+	switch t.kind {
+	case kindString:
+		return '"'
+	case kindInt, kindUint, kindFloat:
+		return '0'
+	case kindBool:
+		if t.b {
+			return 't'
+		}
+		return 'f'
+	case kindNull:
+		return 'n'
+	case kindObjectStart:
+		return '{'
+	case kindObjectEnd:
+		return '}'
+	case kindArrayStart:
+		return '['
+	case kindArrayEnd:
+		return ']'
+	default:
+		return 0
+	}
+}
+
+// stringCacheBits is the log2 of the number of entries of the string cache.
+const stringCacheBits = 8
+
+type stringCacheEntry struct {
+	str string
+	idx uint64
+}
+
+type binaryEncoder struct {
+	strings map[string]uint64
+	// Most strings are string literals (e.g. keys), so the same string is passed over and over.
+	// The cache is indexed by the address of the string, which is cheaper than hashing the string.
+	// It references the string, so the memory can't be reused for a different string.
+	cache [1 << stringCacheBits]stringCacheEntry
+}
+
+func (e *binaryEncoder) lookupString(s string) (uint64, bool) {
+	if len(s) == 0 {
+		idx, ok := e.strings[s]
+		return idx, ok
+	}
+	p := unsafe.StringData(s)
+	entry := &e.cache[(uint64(uintptr(unsafe.Pointer(p)))*0x9e3779b97f4a7c15)>>(64-stringCacheBits)]
+	if unsafe.StringData(entry.str) == p && len(entry.str) == len(s) {
+		return entry.idx, true
+	}
+	idx, ok := e.strings[s]
+	if ok {
+		*entry = stringCacheEntry{str: s, idx: idx}
+	}
+	return idx, ok
+}
+
+// NewBinaryEncoder creates a new Encoder that uses the binary encoding instead of JSON.
+// It can be decoded using a Decoder.
+func NewBinaryEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:   w,
+		bin: &binaryEncoder{strings: make(map[string]uint64)},
+	}
+}
+
+func (e *Encoder) writeBinaryToken(t Token) error {
+	b := e.buf[:0]
+	switch t.kind {
+	case kindString:
+		if idx, ok := e.bin.lookupString(t.str); ok {
+			b = append(b, binaryTagStringRef)
+			b = binary.AppendUvarint(b, idx)
+			break
+		}
+		if len(t.str) <= maxInternedStringLen && len(e.bin.strings) < maxInternedStrings {
+			e.bin.strings[strings.Clone(t.str)] = uint64(len(e.bin.strings))
+			b = append(b, binaryTagStringIntern)
+		} else {
+			b = append(b, binaryTagString)
+		}
+		b = binary.AppendUvarint(b, uint64(len(t.str)))
+		if len(t.str) > cap(b)-len(b) {
+			// avoid copying long strings
+			if _, err := e.w.Write(b); err != nil {
+				return err
+			}
+			_, err := e.w.Write(stringToBytes(t.str))
+			return err
+		}
+		b = append(b, t.str...)
+	case kindInt:
+		b = append(b, binaryTagInt)
+		b = binary.AppendVarint(b, t.i64)
+	case kindUint:
+		b = append(b, binaryTagUint)
+		b = binary.AppendUvarint(b, t.u64)
+	case kindFloat:
+		b = append(b, binaryTagFloat)
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(t.f64))
+	case kindBool:
+		if t.b {
+			b = append(b, binaryTagTrue)
+		} else {
+			b = append(b, binaryTagFalse)
+		}
+	case kindNull:
+		b = append(b, binaryTagNull)
+	case kindObjectStart:
+		b = append(b, binaryTagBeginObject)
+	case kindObjectEnd:
+		b = append(b, binaryTagEndObject)
+	case kindArrayStart:
+		b = append(b, binaryTagBeginArray)
+	case kindArrayEnd:
+		b = append(b, binaryTagEndArray)
+	default:
+		return fmt.Errorf("unknown token kind")
+	}
+	_, err := e.w.Write(b)
+	return err
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// A Decoder reads tokens written by an Encoder created by NewBinaryEncoder.
+type Decoder struct {
+	r       byteReader
+	strings []string
+}
+
+// NewBinaryDecoder creates a new Decoder reading from r.
+func NewBinaryDecoder(r io.Reader) *Decoder {
+	d := &Decoder{}
+	d.Reset(r)
+	return d
+}
+
+// Reset resets the Decoder to read from r.
+// The string table is preserved: r continues the token stream read so far.
+func (d *Decoder) Reset(r io.Reader) {
+	if br, ok := r.(byteReader); ok {
+		d.r = br
+	} else {
+		d.r = bufio.NewReader(r)
+	}
+}
+
+// ReadToken reads the next token.
+// It returns io.EOF if the reader is at EOF before the start of a token,
+// and io.ErrUnexpectedEOF if the reader reaches EOF in the middle of a token.
+func (d *Decoder) ReadToken() (Token, error) {
+	tag, err := d.r.ReadByte()
+	if err != nil {
+		return Token{}, err
+	}
+	t, err := d.readPayload(tag)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return t, err
+}
+
+func (d *Decoder) readPayload(tag byte) (Token, error) {
+	switch tag {
+	case binaryTagNull:
+		return Null, nil
+	case binaryTagFalse:
+		return False, nil
+	case binaryTagTrue:
+		return True, nil
+	case binaryTagInt:
+		i, err := binary.ReadVarint(d.r)
+		return Int(i), err
+	case binaryTagUint:
+		u, err := binary.ReadUvarint(d.r)
+		return Uint(u), err
+	case binaryTagFloat:
+		var b [8]byte
+		if _, err := io.ReadFull(d.r, b[:]); err != nil {
+			return Token{}, err
+		}
+		return Float(math.Float64frombits(binary.LittleEndian.Uint64(b[:]))), nil
+	case binaryTagString, binaryTagStringIntern:
+		l, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return Token{}, err
+		}
+		var sb strings.Builder
+		if n, err := io.CopyN(&sb, d.r, int64(l)); err != nil {
+			if n < int64(l) && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return Token{}, err
+		}
+		s := sb.String()
+		if tag == binaryTagStringIntern {
+			d.strings = append(d.strings, s)
+		}
+		return String(s), nil
+	case binaryTagStringRef:
+		idx, err := binary.ReadUvarint(d.r)
+		if err != nil {
+			return Token{}, err
+		}
+		if idx >= uint64(len(d.strings)) {
+			return Token{}, fmt.Errorf("invalid string reference: %d (string table has %d entries)", idx, len(d.strings))
+		}
+		return String(d.strings[idx]), nil
+	case binaryTagBeginObject:
+		return BeginObject, nil
+	case binaryTagEndObject:
+		return EndObject, nil
+	case binaryTagBeginArray:
+		return BeginArray, nil
+	case binaryTagEndArray:
+		return EndArray, nil
+	default:
+		return Token{}, fmt.Errorf("unknown tag: %#x", tag)
+	}
+}
+
+// errInvalidNesting is returned by CopyValue if an object or array is closed that wasn't opened.
+var errInvalidNesting = errors.New("invalid nesting")
+
+// CopyValue reads the next value (i.e. a single token, or a complete object or array)
+// from the Decoder and writes it to the Encoder.
+func CopyValue(enc *Encoder, dec *Decoder) error {
+	var depth int
+	for {
+		t, err := dec.ReadToken()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		switch t.Kind() {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		}
+		if depth < 0 {
+			return errInvalidNesting
+		}
+		if err := enc.WriteToken(t); err != nil {
+			return err
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package jsontext_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nukilabs/quic-go/qlogwriter/jsontext"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	tokens := []jsontext.Token{
+		jsontext.BeginObject,
+		jsontext.String("string"),
+		jsontext.String("foo\"bar\n"),
+		jsontext.String("int"),
+		jsontext.Int(-1337),
+		jsontext.String("uint"),
+		jsontext.Uint(math.MaxUint64),
+		jsontext.String("float"),
+		jsontext.Float(3.14159),
+		jsontext.String("bool"),
+		jsontext.True,
+		jsontext.String("array"),
+		jsontext.BeginArray,
+		jsontext.False,
+		jsontext.Null,
+		jsontext.String("foo\"bar\n"),
+		jsontext.String(strings.Repeat("a", 100)),
+		jsontext.String(strings.Repeat("a", 100)),
+		jsontext.EndArray,
+		jsontext.EndObject,
+	}
+
+	var binBuf, jsonBuf bytes.Buffer
+	binEnc := jsontext.NewBinaryEncoder(&binBuf)
+	jsonEnc := jsontext.NewEncoder(&jsonBuf)
+	for _, tok := range tokens {
+		require.NoError(t, binEnc.WriteToken(tok))
+		require.NoError(t, jsonEnc.WriteToken(tok))
+	}
+
+	var converted bytes.Buffer
+	dec := jsontext.NewBinaryDecoder(&binBuf)
+	require.NoError(t, jsontext.CopyValue(jsontext.NewEncoder(&converted), dec))
+	require.Equal(t, jsonBuf.String(), converted.String())
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(converted.Bytes(), &m))
+	require.Equal(t, "foo\"bar\n", m["string"])
+
+	_, err := dec.ReadToken()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestBinaryStringInterning(t *testing.T) {
+	var buf bytes.Buffer
+	enc := jsontext.NewBinaryEncoder(&buf)
+	require.NoError(t, enc.WriteToken(jsontext.String("packet_type")))
+	l := buf.Len()
+	require.Greater(t, l, len("packet_type"))
+	// subsequent occurrences only reference the string table
+	require.NoError(t, enc.WriteToken(jsontext.String("packet_type")))
+	require.Equal(t, 2, buf.Len()-l)
+	// long strings are not interned
+	long := strings.Repeat("a", 100)
+	require.NoError(t, enc.WriteToken(jsontext.String(long)))
+	l = buf.Len()
+	require.NoError(t, enc.WriteToken(jsontext.String(long)))
+	require.Greater(t, buf.Len()-l, 100)
+
+	// The string table is preserved when the decoder is reset.
+	data := buf.Bytes()
+	dec := jsontext.NewBinaryDecoder(bytes.NewReader(data[:l]))
+	for range 3 {
+		_, err := dec.ReadToken()
+		require.NoError(t, err)
+	}
+	dec.Reset(bytes.NewReader(data[l:]))
+	tok, err := dec.ReadToken()
+	require.NoError(t, err)
+	require.Equal(t, jsontext.String(long), tok)
+}
+
+func TestBinaryDecoderErrors(t *testing.T) {
+	var buf bytes.Buffer
+	enc := jsontext.NewBinaryEncoder(&buf)
+	require.NoError(t, enc.WriteToken(jsontext.String("foobar")))
+	require.NoError(t, enc.WriteToken(jsontext.String("foobar")))
+	data := buf.Bytes()
+
+	t.Run("truncated", func(t *testing.T) {
+		dec := jsontext.NewBinaryDecoder(bytes.NewReader(data[:3]))
+		_, err := dec.ReadToken()
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("invalid string reference", func(t *testing.T) {
+		// skip the definition of the string
+		dec := jsontext.NewBinaryDecoder(bytes.NewReader(data[len(data)-2:]))
+		_, err := dec.ReadToken()
+		require.ErrorContains(t, err, "invalid string reference")
+	})
+
+	t.Run("unknown tag", func(t *testing.T) {
+		dec := jsontext.NewBinaryDecoder(bytes.NewReader([]byte{0xff}))
+		_, err := dec.ReadToken()
+		require.ErrorContains(t, err, "unknown tag")
+	})
+
+	t.Run("incomplete value", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := jsontext.NewBinaryEncoder(&buf)
+		require.NoError(t, enc.WriteToken(jsontext.BeginArray))
+		require.NoError(t, enc.WriteToken(jsontext.Int(42)))
+		err := jsontext.CopyValue(jsontext.NewEncoder(io.Discard), jsontext.NewBinaryDecoder(&buf))
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
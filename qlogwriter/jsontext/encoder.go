@@ -117,6 +117,8 @@ type Encoder struct {
 	w     io.Writer
 	buf   [64]byte // scratch buffer for number formatting
 	stack []context
+
+	bin *binaryEncoder // only set when using the binary encoding
 }
 
 // NewEncoder creates a new Encoder.
@@ -131,6 +133,9 @@ func NewEncoder(w io.Writer) *Encoder {
 
 // WriteToken writes a token to the encoder.
 func (e *Encoder) WriteToken(t Token) error {
+	if e.bin != nil {
+		return e.writeBinaryToken(t)
+	}
 	if len(e.stack) == 0 {
 		return fmt.Errorf("empty stack")
 	}
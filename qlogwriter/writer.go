@@ -178,25 +178,27 @@ func (t *FileSeq) encodeEvent(e event) {
 	if t.encodeErr != nil {
 		return
 	}
-	if _, err := t.w.Write(recordSeparator); err != nil {
-		t.encodeErr = err
-		return
+	t.encodeErr = encodeEvent(t.w, t.enc, t.referenceTime, e)
+}
+
+// encodeEvent writes a JSON-SEQ record containing the event.
+// The encoder must write to w.
+func encodeEvent(w io.Writer, enc *jsontext.Encoder, referenceTime time.Time, e event) error {
+	if _, err := w.Write(recordSeparator); err != nil {
+		return err
 	}
-	h := encoderHelper{enc: t.enc}
+	h := encoderHelper{enc: enc}
 	h.WriteToken(jsontext.BeginObject)
 	h.WriteToken(jsontext.String("time"))
-	h.WriteToken(jsontext.Float(float64(e.Time.Sub(t.referenceTime).Nanoseconds()) / 1e6))
+	h.WriteToken(jsontext.Float(float64(e.Time.Sub(referenceTime).Nanoseconds()) / 1e6))
 	h.WriteToken(jsontext.String("name"))
 	h.WriteToken(jsontext.String(e.Event.Name()))
 	h.WriteToken(jsontext.String("data"))
-	if err := e.Event.Encode(t.enc, e.Time); err != nil {
-		t.encodeErr = err
-		return
+	if err := e.Event.Encode(enc, e.Time); err != nil {
+		return err
 	}
 	h.WriteToken(jsontext.EndObject)
-	if h.err != nil {
-		t.encodeErr = h.err
-	}
+	return h.err
 }
 
 func (t *FileSeq) removeProducer() {
@@ -0,0 +1,104 @@
+package quiclb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+)
+
+// cidCipher encrypts and decrypts the server ID and nonce of a connection ID.
+// If the combined length is 16 octets, a single AES-ECB pass is used.
+// Otherwise, the four-pass algorithm (a four-round Feistel network) is used.
+type cidCipher struct {
+	block        cipher.Block
+	plaintextLen int
+}
+
+func newCIDCipher(key []byte, plaintextLen int) (*cidCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &cidCipher{block: block, plaintextLen: plaintextLen}, nil
+}
+
+// Encrypt encrypts the plaintext (server ID and nonce) into dst.
+// dst and plaintext must have the same length, and may overlap entirely.
+func (c *cidCipher) Encrypt(dst, plaintext []byte) {
+	if c.plaintextLen == aes.BlockSize {
+		c.block.Encrypt(dst, plaintext)
+		return
+	}
+	left, right := c.split(plaintext)
+	c.xorRound(right, left, 1, false)
+	c.xorRound(left, right, 2, true)
+	c.xorRound(right, left, 3, false)
+	c.xorRound(left, right, 4, true)
+	c.join(dst, left, right)
+}
+
+// Decrypt decrypts the ciphertext into dst.
+// dst and ciphertext must have the same length, and may overlap entirely.
+func (c *cidCipher) Decrypt(dst, ciphertext []byte) {
+	if c.plaintextLen == aes.BlockSize {
+		c.block.Decrypt(dst, ciphertext)
+		return
+	}
+	left, right := c.split(ciphertext)
+	c.xorRound(left, right, 4, true)
+	c.xorRound(right, left, 3, false)
+	c.xorRound(left, right, 2, true)
+	c.xorRound(right, left, 1, false)
+	c.join(dst, left, right)
+}
+
+func (c *cidCipher) halfLen() int { return (c.plaintextLen + 1) / 2 }
+
+func (c *cidCipher) isOdd() bool { return c.plaintextLen%2 == 1 }
+
+// split splits the input into two halves of equal length.
+// If the input has an odd length, the middle octet is split:
+// Its four most significant bits belong to the left half, the four least significant bits to the right half.
+func (c *cidCipher) split(b []byte) (left, right []byte) {
+	halfLen := c.halfLen()
+	left = make([]byte, halfLen)
+	right = make([]byte, halfLen)
+	copy(left, b[:halfLen])
+	copy(right, b[len(b)-halfLen:])
+	if c.isOdd() {
+		left[halfLen-1] &= 0xf0
+		right[0] &= 0x0f
+	}
+	return left, right
+}
+
+func (c *cidCipher) join(dst, left, right []byte) {
+	halfLen := c.halfLen()
+	if c.isOdd() {
+		copy(dst, left)
+		dst[halfLen-1] |= right[0]
+		copy(dst[halfLen:], right[1:])
+		return
+	}
+	copy(dst, left)
+	copy(dst[halfLen:], right)
+}
+
+// xorRound expands the input half into an AES block, encrypts it,
+// and XORs the (truncated) result into the output half.
+func (c *cidCipher) xorRound(out, in []byte, pass byte, outIsLeft bool) {
+	var block [aes.BlockSize]byte
+	block[0] = byte(c.plaintextLen)
+	block[1] = pass
+	copy(block[2:], in)
+	c.block.Encrypt(block[:], block[:])
+	for i := range out {
+		out[i] ^= block[i]
+	}
+	if c.isOdd() {
+		if outIsLeft {
+			out[len(out)-1] &= 0xf0
+		} else {
+			out[0] &= 0x0f
+		}
+	}
+}
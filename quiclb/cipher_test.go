@@ -0,0 +1,108 @@
+package quiclb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// Test vectors from Appendix B of draft-ietf-quic-load-balancers.
+func TestTestVectors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		key      string
+		configID uint8
+		serverID string
+		nonce    string
+		connID   string
+	}{
+		{
+			name:     "unencrypted",
+			configID: 0,
+			serverID: "c4605e",
+			nonce:    "4504cc4f",
+			connID:   "07c4605e4504cc4f",
+		},
+		{
+			name:     "four-pass, odd length",
+			key:      "8f95f09245765f80256934e50c66207f",
+			configID: 0,
+			serverID: "ed793a",
+			nonce:    "ee080dbf",
+			connID:   "074126ee38bf5454",
+		},
+		{
+			name:     "four-pass, server ID longer than nonce",
+			key:      "8f95f09245765f80256934e50c66207f",
+			configID: 1,
+			serverID: "ed793a51d49b8f5fab65",
+			nonce:    "ee080dbf48",
+			connID:   "2fcd3f572d4eefb046fdb51d164efccc",
+		},
+		{
+			name:     "single-pass",
+			key:      "8f95f09245765f80256934e50c66207f",
+			configID: 2,
+			serverID: "ed793a51d49b8f5f",
+			nonce:    "ee080dbf48c0d1e5",
+			connID:   "504dd2d05a7b0de9b2b9907afb5ecf8cc3",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			serverID := decodeHex(t, tc.serverID)
+			nonce := decodeHex(t, tc.nonce)
+			conf := &Config{
+				ID:           tc.configID,
+				ServerIDLen:  len(serverID),
+				NonceLen:     len(nonce),
+				EncodeLength: true,
+			}
+			if tc.key != "" {
+				conf.Key = decodeHex(t, tc.key)
+			}
+			g, err := NewGenerator(conf, serverID)
+			require.NoError(t, err)
+			connID := g.encode(0, nonce)
+			require.Equal(t, tc.connID, hex.EncodeToString(connID.Bytes()))
+
+			d, err := NewDecoder(conf)
+			require.NoError(t, err)
+			id, err := d.ServerID(connID)
+			require.NoError(t, err)
+			require.Equal(t, serverID, id)
+		})
+	}
+}
+
+func TestCIDCipher(t *testing.T) {
+	key := make([]byte, 16)
+	rand.Read(key)
+	for l := 5; l <= 19; l++ {
+		c, err := newCIDCipher(key, l)
+		require.NoError(t, err)
+		plaintext := make([]byte, l)
+		rand.Read(plaintext)
+		ciphertext := make([]byte, l)
+		c.Encrypt(ciphertext, plaintext)
+		require.NotEqual(t, plaintext, ciphertext)
+		decrypted := make([]byte, l)
+		c.Decrypt(decrypted, ciphertext)
+		require.Equal(t, plaintext, decrypted)
+
+		// encrypting in place
+		b := append([]byte(nil), plaintext...)
+		c.Encrypt(b, b)
+		require.Equal(t, ciphertext, b)
+		c.Decrypt(b, b)
+		require.Equal(t, plaintext, b)
+	}
+}
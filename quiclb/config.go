@@ -0,0 +1,78 @@
+// Package quiclb implements QUIC-LB connection IDs, as specified in draft-ietf-quic-load-balancers.
+//
+// QUIC-LB connection IDs encode a server ID, which allows a load balancer to route packets
+// to the server that generated the connection ID without keeping per-connection state.
+// Servers use a [Generator] as the ConnectionIDGenerator of their quic.Transport,
+// load balancers use a [Decoder] to extract the server ID from incoming packets.
+package quiclb
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+)
+
+// MaxConfigID is the largest config rotation ID that can be used.
+// The config rotation ID 0b111 is reserved for unroutable connection IDs.
+const MaxConfigID = 6
+
+const unroutableConfigID = 0b111
+
+const (
+	minNonceLen = 4
+	maxNonceLen = 18
+	keyLen      = 16
+)
+
+// ErrUnroutable is returned by the [Decoder] if a connection ID doesn't belong to any of its configurations.
+// Load balancers should use a fallback algorithm to route such packets,
+// for example by hashing the connection ID.
+var ErrUnroutable = errors.New("quiclb: unroutable connection ID")
+
+// A Config is a QUIC-LB configuration.
+// Servers and load balancers need to use the same configuration.
+type Config struct {
+	// ID is the config rotation ID.
+	// It is encoded in the three most significant bits of the first octet of the connection ID,
+	// and allows changing the configuration without disrupting existing connections.
+	// It must not be larger than MaxConfigID.
+	ID uint8
+	// ServerIDLen is the length of the server ID, between 1 and 15 octets.
+	ServerIDLen int
+	// NonceLen is the length of the nonce, between 4 and 18 octets.
+	// The sum of ServerIDLen and NonceLen must not exceed 19 octets.
+	NonceLen int
+	// Key is the 16 byte AES-128 key used to encrypt the server ID and the nonce.
+	// If no key is set, the server ID is encoded in plaintext,
+	// which allows observers to link connection IDs of the same connection.
+	Key []byte
+	// EncodeLength encodes the length of the connection ID into the first octet.
+	// This is necessary if the connection ID needs to be parsed by hardware that
+	// doesn't know the QUIC-LB configuration.
+	EncodeLength bool
+}
+
+// ConnectionIDLen returns the length of the connection IDs.
+func (c *Config) ConnectionIDLen() int {
+	return 1 + c.ServerIDLen + c.NonceLen
+}
+
+func (c *Config) validate() error {
+	if c.ID > MaxConfigID {
+		return fmt.Errorf("quiclb: invalid config ID: %d", c.ID)
+	}
+	if c.ServerIDLen < 1 || c.ServerIDLen > 15 {
+		return fmt.Errorf("quiclb: invalid server ID length: %d", c.ServerIDLen)
+	}
+	if c.NonceLen < minNonceLen || c.NonceLen > maxNonceLen {
+		return fmt.Errorf("quiclb: invalid nonce length: %d", c.NonceLen)
+	}
+	if c.ConnectionIDLen() > protocol.MaxConnIDLen {
+		return fmt.Errorf("quiclb: connection ID too long: %d bytes", c.ConnectionIDLen())
+	}
+	if c.Key != nil && len(c.Key) != keyLen {
+		return fmt.Errorf("quiclb: invalid key length: %d", len(c.Key))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package quiclb
+
+import (
+	"fmt"
+
+	"github.com/nukilabs/quic-go"
+)
+
+type decoderConfig struct {
+	config Config
+	cipher *cidCipher // nil for plaintext connection IDs
+}
+
+// A Decoder extracts the server ID from QUIC-LB connection IDs.
+// It is used by load balancers, and can use multiple configurations at the same time,
+// as long as they use different config rotation IDs.
+// It is safe for concurrent use.
+type Decoder struct {
+	configs [MaxConfigID + 1]*decoderConfig
+}
+
+// NewDecoder creates a new Decoder.
+func NewDecoder(configs ...*Config) (*Decoder, error) {
+	d := &Decoder{}
+	for _, conf := range configs {
+		if err := conf.validate(); err != nil {
+			return nil, err
+		}
+		if d.configs[conf.ID] != nil {
+			return nil, fmt.Errorf("quiclb: duplicate config ID: %d", conf.ID)
+		}
+		dc := &decoderConfig{config: *conf}
+		if conf.Key != nil {
+			c, err := newCIDCipher(conf.Key, conf.ServerIDLen+conf.NonceLen)
+			if err != nil {
+				return nil, err
+			}
+			dc.cipher = c
+		}
+		d.configs[conf.ID] = dc
+	}
+	return d, nil
+}
+
+// ServerID returns the server ID encoded in the connection ID.
+// If the connection ID doesn't belong to any of the configurations, ErrUnroutable is returned.
+func (d *Decoder) ServerID(connID quic.ConnectionID) ([]byte, error) {
+	return d.serverID(connID.Bytes())
+}
+
+// ServerIDFromPacket returns the server ID encoded in the Destination Connection ID of a QUIC packet.
+// It can be used with both long and short header packets.
+// Note that the Destination Connection ID of the client's first Initial packets is chosen randomly by the client,
+// and doesn't encode a server ID. ErrUnroutable is returned for these packets (unless the client's choice
+// happens to be a valid connection ID), and the load balancer should use its fallback algorithm.
+func (d *Decoder) ServerIDFromPacket(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, ErrUnroutable
+	}
+	// Long header packets, see section 5.1 of RFC 8999.
+	if data[0]&0x80 > 0 {
+		if len(data) < 6 {
+			return nil, ErrUnroutable
+		}
+		connIDLen := int(data[5])
+		if len(data) < 6+connIDLen {
+			return nil, ErrUnroutable
+		}
+		return d.serverID(data[6 : 6+connIDLen])
+	}
+	// Short header packets, see section 5.2 of RFC 8999.
+	// The length of the connection ID is determined by the configuration.
+	if len(data) < 2 {
+		return nil, ErrUnroutable
+	}
+	configID := data[1] >> 5
+	if configID == unroutableConfigID || d.configs[configID] == nil {
+		return nil, ErrUnroutable
+	}
+	connIDLen := d.configs[configID].config.ConnectionIDLen()
+	if len(data) < 1+connIDLen {
+		return nil, ErrUnroutable
+	}
+	return d.serverID(data[1 : 1+connIDLen])
+}
+
+func (d *Decoder) serverID(connID []byte) ([]byte, error) {
+	if len(connID) == 0 {
+		return nil, ErrUnroutable
+	}
+	configID := connID[0] >> 5
+	if configID == unroutableConfigID {
+		return nil, ErrUnroutable
+	}
+	conf := d.configs[configID]
+	if conf == nil || len(connID) != conf.config.ConnectionIDLen() {
+		return nil, ErrUnroutable
+	}
+	if conf.config.EncodeLength && int(connID[0]&0x1f) != len(connID)-1 {
+		return nil, ErrUnroutable
+	}
+	serverIDLen := conf.config.ServerIDLen
+	if conf.cipher == nil {
+		return append([]byte(nil), connID[1:1+serverIDLen]...), nil
+	}
+	plaintext := make([]byte, len(connID)-1)
+	conf.cipher.Decrypt(plaintext, connID[1:])
+	return plaintext[:serverIDLen], nil
+}
@@ -0,0 +1,148 @@
+package quiclb
+
+import (
+	"testing"
+
+	"github.com/nukilabs/quic-go"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newLongHeaderPacket(dest, src quic.ConnectionID) []byte {
+	b := []byte{0xc0, 0, 0, 0, 1}
+	b = append(b, byte(dest.Len()))
+	b = append(b, dest.Bytes()...)
+	b = append(b, byte(src.Len()))
+	b = append(b, src.Bytes()...)
+	return append(b, []byte("payload")...)
+}
+
+func newShortHeaderPacket(dest quic.ConnectionID) []byte {
+	b := []byte{0x40}
+	b = append(b, dest.Bytes()...)
+	return append(b, []byte("payload")...)
+}
+
+func TestDecoderServerID(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		key  []byte
+	}{
+		{name: "plaintext"},
+		{name: "encrypted", key: []byte("0123456789abcdef")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := &Config{ID: 2, ServerIDLen: 4, NonceLen: 6, Key: tc.key}
+			g, err := NewGenerator(conf, []byte{1, 2, 3, 4})
+			require.NoError(t, err)
+			d, err := NewDecoder(conf)
+			require.NoError(t, err)
+
+			connID, err := g.GenerateConnectionID()
+			require.NoError(t, err)
+			serverID, err := d.ServerID(connID)
+			require.NoError(t, err)
+			require.Equal(t, []byte{1, 2, 3, 4}, serverID)
+
+			src := quic.ConnectionIDFromBytes([]byte{0xa, 0xb})
+			serverID, err = d.ServerIDFromPacket(newLongHeaderPacket(connID, src))
+			require.NoError(t, err)
+			require.Equal(t, []byte{1, 2, 3, 4}, serverID)
+
+			serverID, err = d.ServerIDFromPacket(newShortHeaderPacket(connID))
+			require.NoError(t, err)
+			require.Equal(t, []byte{1, 2, 3, 4}, serverID)
+		})
+	}
+}
+
+func TestDecoderMultipleConfigs(t *testing.T) {
+	conf1 := &Config{ID: 0, ServerIDLen: 2, NonceLen: 4}
+	conf2 := &Config{ID: 1, ServerIDLen: 3, NonceLen: 8, Key: make([]byte, 16)}
+	g1, err := NewGenerator(conf1, []byte{0xa, 0xb})
+	require.NoError(t, err)
+	g2, err := NewGenerator(conf2, []byte{0xc, 0xd, 0xe})
+	require.NoError(t, err)
+
+	d, err := NewDecoder(conf1, conf2)
+	require.NoError(t, err)
+
+	connID1, err := g1.GenerateConnectionID()
+	require.NoError(t, err)
+	connID2, err := g2.GenerateConnectionID()
+	require.NoError(t, err)
+
+	serverID, err := d.ServerIDFromPacket(newShortHeaderPacket(connID1))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xa, 0xb}, serverID)
+	serverID, err = d.ServerIDFromPacket(newShortHeaderPacket(connID2))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xc, 0xd, 0xe}, serverID)
+
+	// the old configuration is retired
+	d, err = NewDecoder(conf2)
+	require.NoError(t, err)
+	_, err = d.ServerIDFromPacket(newShortHeaderPacket(connID1))
+	require.ErrorIs(t, err, ErrUnroutable)
+	serverID, err = d.ServerIDFromPacket(newShortHeaderPacket(connID2))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xc, 0xd, 0xe}, serverID)
+}
+
+func TestDecoderDuplicateConfigID(t *testing.T) {
+	_, err := NewDecoder(
+		&Config{ID: 4, ServerIDLen: 2, NonceLen: 4},
+		&Config{ID: 4, ServerIDLen: 3, NonceLen: 5},
+	)
+	require.EqualError(t, err, "quiclb: duplicate config ID: 4")
+}
+
+func TestDecoderUnroutable(t *testing.T) {
+	conf := &Config{ID: 1, ServerIDLen: 2, NonceLen: 4, EncodeLength: true}
+	d, err := NewDecoder(conf)
+	require.NoError(t, err)
+
+	t.Run("unroutable connection ID", func(t *testing.T) {
+		connID, err := NewUnroutableConnectionID(7)
+		require.NoError(t, err)
+		_, err = d.ServerID(connID)
+		require.ErrorIs(t, err, ErrUnroutable)
+		_, err = d.ServerIDFromPacket(newShortHeaderPacket(connID))
+		require.ErrorIs(t, err, ErrUnroutable)
+	})
+
+	t.Run("unknown config ID", func(t *testing.T) {
+		_, err := d.ServerID(quic.ConnectionIDFromBytes([]byte{2<<5 | 6, 1, 2, 3, 4, 5, 6}))
+		require.ErrorIs(t, err, ErrUnroutable)
+	})
+
+	t.Run("wrong length", func(t *testing.T) {
+		_, err := d.ServerID(quic.ConnectionIDFromBytes([]byte{1<<5 | 7, 1, 2, 3, 4, 5, 6, 7}))
+		require.ErrorIs(t, err, ErrUnroutable)
+	})
+
+	t.Run("length not encoded", func(t *testing.T) {
+		_, err := d.ServerID(quic.ConnectionIDFromBytes([]byte{1<<5 | 3, 1, 2, 3, 4, 5, 6}))
+		require.ErrorIs(t, err, ErrUnroutable)
+	})
+
+	t.Run("client-chosen connection ID", func(t *testing.T) {
+		// the Destination Connection ID of the client's first Initial packet is random
+		dest := quic.ConnectionIDFromBytes([]byte{0xff, 0xfe, 0xfd, 0xfc, 0xfb, 0xfa, 0xf9, 0xf8})
+		_, err := d.ServerIDFromPacket(newLongHeaderPacket(dest, quic.ConnectionID{}))
+		require.ErrorIs(t, err, ErrUnroutable)
+	})
+
+	t.Run("short packets", func(t *testing.T) {
+		for _, b := range [][]byte{
+			nil,
+			{0xc0, 0, 0},
+			{0xc0, 0, 0, 0, 1, 8, 1, 2},
+			{0x40},
+			{0x40, 1<<5 | 6, 1, 2},
+		} {
+			_, err := d.ServerIDFromPacket(b)
+			require.ErrorIs(t, err, ErrUnroutable)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package quiclb
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/internal/protocol"
+)
+
+// ErrNoncesExhausted is returned by the [Generator] when all nonces have been used.
+// The server should switch to a new configuration.
+var ErrNoncesExhausted = errors.New("quiclb: nonces exhausted")
+
+// A Generator generates QUIC-LB connection IDs that encode the server ID.
+// It implements the quic.ConnectionIDGenerator interface.
+type Generator struct {
+	config   Config
+	serverID []byte
+	cipher   *cidCipher // nil for plaintext connection IDs
+
+	mutex sync.Mutex
+	// For encrypted connection IDs, the nonce is a counter, initialized with a random value.
+	// This avoids connection ID collisions without the need to keep track of the nonces that were used.
+	nonce []byte
+	// number of nonces that can still be used, only used for nonces shorter than 8 octets
+	remaining uint64
+}
+
+var _ quic.ConnectionIDGenerator = &Generator{}
+
+// NewGenerator creates a new Generator for the server ID.
+func NewGenerator(config *Config, serverID []byte) (*Generator, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if len(serverID) != config.ServerIDLen {
+		return nil, fmt.Errorf("quiclb: server ID has wrong length (expected %d, got %d)", config.ServerIDLen, len(serverID))
+	}
+	g := &Generator{
+		config:   *config,
+		serverID: append([]byte(nil), serverID...),
+		nonce:    make([]byte, config.NonceLen),
+	}
+	if config.Key != nil {
+		c, err := newCIDCipher(config.Key, config.ServerIDLen+config.NonceLen)
+		if err != nil {
+			return nil, err
+		}
+		g.cipher = c
+		if _, err := rand.Read(g.nonce); err != nil {
+			return nil, err
+		}
+		if config.NonceLen < 8 {
+			g.remaining = 1 << (8 * config.NonceLen)
+		}
+	}
+	return g, nil
+}
+
+// GenerateConnectionID generates a new connection ID.
+func (g *Generator) GenerateConnectionID() (quic.ConnectionID, error) {
+	var random [1]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return quic.ConnectionID{}, err
+	}
+	nonce := make([]byte, g.config.NonceLen)
+	if g.cipher == nil {
+		if _, err := rand.Read(nonce); err != nil {
+			return quic.ConnectionID{}, err
+		}
+	} else if err := g.nextNonce(nonce); err != nil {
+		return quic.ConnectionID{}, err
+	}
+	return g.encode(random[0], nonce), nil
+}
+
+func (g *Generator) encode(random byte, nonce []byte) quic.ConnectionID {
+	b := make([]byte, g.config.ConnectionIDLen())
+	b[0] = g.firstOctet(random)
+	copy(b[1:], g.serverID)
+	copy(b[1+g.config.ServerIDLen:], nonce)
+	if g.cipher != nil {
+		g.cipher.Encrypt(b[1:], b[1:])
+	}
+	return quic.ConnectionIDFromBytes(b)
+}
+
+func (g *Generator) firstOctet(random byte) byte {
+	if g.config.EncodeLength {
+		return g.config.ID<<5 | byte(g.config.ConnectionIDLen()-1)
+	}
+	return g.config.ID<<5 | random&0x1f
+}
+
+func (g *Generator) nextNonce(nonce []byte) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.config.NonceLen < 8 {
+		if g.remaining == 0 {
+			return ErrNoncesExhausted
+		}
+		g.remaining--
+	}
+	copy(nonce, g.nonce)
+	for i := len(g.nonce) - 1; i >= 0; i-- {
+		g.nonce[i]++
+		if g.nonce[i] != 0 {
+			break
+		}
+	}
+	return nil
+}
+
+// ConnectionIDLen returns the length of the generated connection IDs.
+func (g *Generator) ConnectionIDLen() int {
+	return g.config.ConnectionIDLen()
+}
+
+// NewUnroutableConnectionID generates a connection ID that doesn't encode a server ID.
+// Load balancers route packets with such a connection ID using their fallback algorithm.
+// This is useful for servers that don't (yet) have a valid configuration.
+func NewUnroutableConnectionID(length int) (quic.ConnectionID, error) {
+	if length < 1 || length > protocol.MaxConnIDLen {
+		return quic.ConnectionID{}, fmt.Errorf("quiclb: invalid connection ID length: %d", length)
+	}
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return quic.ConnectionID{}, err
+	}
+	b[0] |= unroutableConfigID << 5
+	return quic.ConnectionIDFromBytes(b), nil
+}
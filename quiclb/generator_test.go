@@ -0,0 +1,119 @@
+package quiclb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nukilabs/quic-go"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratorConfigValidation(t *testing.T) {
+	for _, tc := range []struct {
+		config *Config
+		err    string
+	}{
+		{&Config{ID: 7, ServerIDLen: 2, NonceLen: 4}, "quiclb: invalid config ID: 7"},
+		{&Config{ServerIDLen: 0, NonceLen: 4}, "quiclb: invalid server ID length: 0"},
+		{&Config{ServerIDLen: 16, NonceLen: 4}, "quiclb: invalid server ID length: 16"},
+		{&Config{ServerIDLen: 2, NonceLen: 3}, "quiclb: invalid nonce length: 3"},
+		{&Config{ServerIDLen: 2, NonceLen: 19}, "quiclb: invalid nonce length: 19"},
+		{&Config{ServerIDLen: 5, NonceLen: 15}, "quiclb: connection ID too long: 21 bytes"},
+		{&Config{ServerIDLen: 2, NonceLen: 4, Key: make([]byte, 32)}, "quiclb: invalid key length: 32"},
+	} {
+		_, err := NewGenerator(tc.config, make([]byte, tc.config.ServerIDLen))
+		require.EqualError(t, err, tc.err)
+		_, err = NewDecoder(tc.config)
+		require.EqualError(t, err, tc.err)
+	}
+
+	_, err := NewGenerator(&Config{ServerIDLen: 2, NonceLen: 4}, []byte{1, 2, 3})
+	require.EqualError(t, err, "quiclb: server ID has wrong length (expected 2, got 3)")
+}
+
+func TestGeneratorPlaintext(t *testing.T) {
+	conf := &Config{ID: 3, ServerIDLen: 3, NonceLen: 6}
+	g, err := NewGenerator(conf, []byte{0xde, 0xad, 0xbe})
+	require.NoError(t, err)
+	require.Equal(t, 10, g.ConnectionIDLen())
+
+	var firstOctets []byte
+	for range 100 {
+		connID, err := g.GenerateConnectionID()
+		require.NoError(t, err)
+		require.Equal(t, 10, connID.Len())
+		b := connID.Bytes()
+		require.Equal(t, uint8(3), b[0]>>5)
+		require.Equal(t, []byte{0xde, 0xad, 0xbe}, b[1:4])
+		firstOctets = append(firstOctets, b[0])
+	}
+	// the lower bits of the first octet are random
+	require.NotEqual(t, bytes.Repeat(firstOctets[:1], 100), firstOctets)
+}
+
+func TestGeneratorEncodeLength(t *testing.T) {
+	for _, key := range [][]byte{nil, make([]byte, 16)} {
+		g, err := NewGenerator(&Config{ID: 5, ServerIDLen: 4, NonceLen: 8, Key: key, EncodeLength: true}, []byte{1, 2, 3, 4})
+		require.NoError(t, err)
+		for range 10 {
+			connID, err := g.GenerateConnectionID()
+			require.NoError(t, err)
+			require.Equal(t, byte(5<<5|12), connID.Bytes()[0])
+		}
+	}
+}
+
+func TestGeneratorEncrypted(t *testing.T) {
+	for _, nonceLen := range []int{4, 11, 12, 18} {
+		conf := &Config{ID: 1, ServerIDLen: 1, NonceLen: nonceLen, Key: []byte("0123456789abcdef")}
+		g, err := NewGenerator(conf, []byte{42})
+		require.NoError(t, err)
+		d, err := NewDecoder(conf)
+		require.NoError(t, err)
+
+		connIDs := make(map[quic.ConnectionID]struct{})
+		for range 1000 {
+			connID, err := g.GenerateConnectionID()
+			require.NoError(t, err)
+			require.Equal(t, 2+nonceLen, connID.Len())
+			require.Equal(t, uint8(1), connID.Bytes()[0]>>5)
+			connIDs[connID] = struct{}{}
+			serverID, err := d.ServerID(connID)
+			require.NoError(t, err)
+			require.Equal(t, []byte{42}, serverID)
+		}
+		// the nonce is a counter, so there are no collisions
+		require.Len(t, connIDs, 1000)
+	}
+}
+
+func TestGeneratorNonceExhaustion(t *testing.T) {
+	g, err := NewGenerator(&Config{ServerIDLen: 2, NonceLen: 4, Key: make([]byte, 16)}, []byte{1, 2})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1<<32), g.remaining)
+	g.remaining = 2
+	g.nonce = []byte{0xff, 0xff, 0xff, 0xff}
+	_, err = g.GenerateConnectionID()
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0}, g.nonce)
+	_, err = g.GenerateConnectionID()
+	require.NoError(t, err)
+	_, err = g.GenerateConnectionID()
+	require.ErrorIs(t, err, ErrNoncesExhausted)
+}
+
+func TestUnroutableConnectionID(t *testing.T) {
+	d, err := NewDecoder(&Config{ID: 0, ServerIDLen: 2, NonceLen: 5})
+	require.NoError(t, err)
+	for range 10 {
+		connID, err := NewUnroutableConnectionID(8)
+		require.NoError(t, err)
+		require.Equal(t, 8, connID.Len())
+		require.Equal(t, byte(0b111), connID.Bytes()[0]>>5)
+		_, err = d.ServerID(connID)
+		require.ErrorIs(t, err, ErrUnroutable)
+	}
+	_, err = NewUnroutableConnectionID(21)
+	require.EqualError(t, err, "quiclb: invalid connection ID length: 21")
+}
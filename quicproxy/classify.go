@@ -0,0 +1,183 @@
+package quicproxy
+
+import (
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/wire"
+)
+
+// PacketType is the type of a QUIC packet.
+type PacketType uint8
+
+const (
+	// PacketTypeUnknown is used for packets that couldn't be parsed.
+	PacketTypeUnknown PacketType = iota
+	// PacketTypeInitial is the Initial packet type.
+	PacketTypeInitial
+	// PacketType0RTT is the 0-RTT packet type.
+	PacketType0RTT
+	// PacketTypeHandshake is the Handshake packet type.
+	PacketTypeHandshake
+	// PacketTypeRetry is the Retry packet type.
+	PacketTypeRetry
+	// PacketTypeVersionNegotiation is the Version Negotiation packet type.
+	PacketTypeVersionNegotiation
+	// PacketType1RTT is the 1-RTT (short header) packet type.
+	PacketType1RTT
+)
+
+func (t PacketType) String() string {
+	switch t {
+	case PacketTypeInitial:
+		return "Initial"
+	case PacketType0RTT:
+		return "0-RTT"
+	case PacketTypeHandshake:
+		return "Handshake"
+	case PacketTypeRetry:
+		return "Retry"
+	case PacketTypeVersionNegotiation:
+		return "Version Negotiation"
+	case PacketType1RTT:
+		return "1-RTT"
+	default:
+		return "unknown"
+	}
+}
+
+// PacketNumberSpace is the packet number space of a QUIC packet.
+type PacketNumberSpace uint8
+
+const (
+	// PacketNumberSpaceNone is used for packets that don't have a packet number,
+	// i.e. Retry and Version Negotiation packets, and packets that couldn't be parsed.
+	PacketNumberSpaceNone PacketNumberSpace = iota
+	// PacketNumberSpaceInitial is the Initial packet number space.
+	PacketNumberSpaceInitial
+	// PacketNumberSpaceHandshake is the Handshake packet number space.
+	PacketNumberSpaceHandshake
+	// PacketNumberSpaceApplicationData is the packet number space used by 0-RTT and 1-RTT packets.
+	PacketNumberSpaceApplicationData
+)
+
+func (s PacketNumberSpace) String() string {
+	switch s {
+	case PacketNumberSpaceInitial:
+		return "Initial"
+	case PacketNumberSpaceHandshake:
+		return "Handshake"
+	case PacketNumberSpaceApplicationData:
+		return "Application Data"
+	default:
+		return "none"
+	}
+}
+
+// PacketHeader is the parsed header of a QUIC packet.
+// Only the unprotected parts of the header are parsed.
+type PacketHeader struct {
+	Type              PacketType
+	PacketNumberSpace PacketNumberSpace
+	// Version is the QUIC version. It is only set for long header packets.
+	Version quic.Version
+	// DestConnectionID is the Destination Connection ID.
+	// For short header packets, it is only set if the length of the connection ID is known.
+	DestConnectionID quic.ConnectionID
+	// SrcConnectionID is the Source Connection ID. It is only set for long header packets.
+	SrcConnectionID quic.ConnectionID
+
+	// Offset is the offset of the packet in the UDP datagram.
+	Offset int
+	// Length is the length of the packet, including the header.
+	Length int
+	// HeaderLength is the length of the header, up to (but not including) the packet number.
+	// For Retry and Version Negotiation packets, this is the length of the packet.
+	HeaderLength int
+}
+
+// ParseHeaders parses the headers of all QUIC packets coalesced into a UDP datagram.
+// Since the length of the connection ID is not encoded in short header packets,
+// shortHeaderConnIDLen is used as the connection ID length of short header packets.
+// If it is negative, the connection ID of short header packets is not parsed.
+// Parsing stops at the first packet that can't be parsed,
+// which is then returned with type PacketTypeUnknown.
+func ParseHeaders(data []byte, shortHeaderConnIDLen int) []PacketHeader {
+	var hdrs []PacketHeader
+	var offset int
+	for len(data) > 0 {
+		hdr := parseHeader(data, shortHeaderConnIDLen)
+		hdr.Offset = offset
+		hdrs = append(hdrs, hdr)
+		if hdr.Type == PacketTypeUnknown {
+			break
+		}
+		data = data[hdr.Length:]
+		offset += hdr.Length
+	}
+	return hdrs
+}
+
+func parseHeader(data []byte, shortHeaderConnIDLen int) PacketHeader {
+	if !wire.IsLongHeaderPacket(data[0]) {
+		hdr := PacketHeader{
+			Type:              PacketType1RTT,
+			PacketNumberSpace: PacketNumberSpaceApplicationData,
+			Length:            len(data),
+			HeaderLength:      1,
+		}
+		if shortHeaderConnIDLen >= 0 {
+			connID, err := wire.ParseConnectionID(data, shortHeaderConnIDLen)
+			if err != nil {
+				return PacketHeader{Length: len(data)}
+			}
+			hdr.DestConnectionID = connID
+			hdr.HeaderLength += shortHeaderConnIDLen
+		}
+		return hdr
+	}
+	if wire.IsVersionNegotiationPacket(data) {
+		_, dest, src, err := wire.ParseArbitraryLenConnectionIDs(data)
+		if err != nil {
+			return PacketHeader{Length: len(data)}
+		}
+		hdr := PacketHeader{
+			Type:         PacketTypeVersionNegotiation,
+			Length:       len(data),
+			HeaderLength: len(data),
+		}
+		// Version Negotiation packets can contain connection IDs of arbitrary length.
+		if len(dest) <= protocol.MaxConnIDLen {
+			hdr.DestConnectionID = protocol.ParseConnectionID(dest)
+		}
+		if len(src) <= protocol.MaxConnIDLen {
+			hdr.SrcConnectionID = protocol.ParseConnectionID(src)
+		}
+		return hdr
+	}
+	wireHdr, packet, _, err := wire.ParsePacket(data)
+	if err != nil {
+		return PacketHeader{Length: len(data)}
+	}
+	hdr := PacketHeader{
+		Version:          wireHdr.Version,
+		DestConnectionID: wireHdr.DestConnectionID,
+		SrcConnectionID:  wireHdr.SrcConnectionID,
+		Length:           len(packet),
+		HeaderLength:     int(wireHdr.ParsedLen()),
+	}
+	switch wireHdr.Type {
+	case protocol.PacketTypeInitial:
+		hdr.Type = PacketTypeInitial
+		hdr.PacketNumberSpace = PacketNumberSpaceInitial
+	case protocol.PacketType0RTT:
+		hdr.Type = PacketType0RTT
+		hdr.PacketNumberSpace = PacketNumberSpaceApplicationData
+	case protocol.PacketTypeHandshake:
+		hdr.Type = PacketTypeHandshake
+		hdr.PacketNumberSpace = PacketNumberSpaceHandshake
+	case protocol.PacketTypeRetry:
+		hdr.Type = PacketTypeRetry
+		hdr.HeaderLength = len(packet)
+	}
+	return hdr
+}
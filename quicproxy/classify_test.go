@@ -0,0 +1,119 @@
+package quicproxy
+
+import (
+	"testing"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func appendLongHeaderPacket(t *testing.T, b []byte, typ protocol.PacketType, destConnID, srcConnID protocol.ConnectionID, payload []byte) []byte {
+	t.Helper()
+	hdr := wire.ExtendedHeader{
+		Header: wire.Header{
+			Type:             typ,
+			Version:          protocol.Version1,
+			Length:           2 + protocol.ByteCount(len(payload)),
+			DestConnectionID: destConnID,
+			SrcConnectionID:  srcConnID,
+		},
+		PacketNumber:    42,
+		PacketNumberLen: protocol.PacketNumberLen2,
+	}
+	b, err := hdr.Append(b, protocol.Version1)
+	require.NoError(t, err)
+	return append(b, payload...)
+}
+
+func TestParseCoalescedPackets(t *testing.T) {
+	destConnID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+	srcConnID := protocol.ParseConnectionID([]byte{5, 6, 7, 8, 9})
+	b := appendLongHeaderPacket(t, nil, protocol.PacketTypeInitial, destConnID, srcConnID, []byte("initial"))
+	initialLen := len(b)
+	b = appendLongHeaderPacket(t, b, protocol.PacketTypeHandshake, destConnID, srcConnID, []byte("handshake"))
+	handshakeLen := len(b) - initialLen
+	b = append(b, 0x40, 1, 2, 3, 4, 42, 'f', 'o', 'o')
+
+	hdrs := ParseHeaders(b, 4)
+	require.Len(t, hdrs, 3)
+	require.Equal(t, PacketTypeInitial, hdrs[0].Type)
+	require.Equal(t, PacketNumberSpaceInitial, hdrs[0].PacketNumberSpace)
+	require.Equal(t, protocol.Version1, hdrs[0].Version)
+	require.Equal(t, destConnID, hdrs[0].DestConnectionID)
+	require.Equal(t, srcConnID, hdrs[0].SrcConnectionID)
+	require.Zero(t, hdrs[0].Offset)
+	require.Equal(t, initialLen, hdrs[0].Length)
+	require.Equal(t, initialLen-2-len("initial"), hdrs[0].HeaderLength)
+
+	require.Equal(t, PacketTypeHandshake, hdrs[1].Type)
+	require.Equal(t, PacketNumberSpaceHandshake, hdrs[1].PacketNumberSpace)
+	require.Equal(t, initialLen, hdrs[1].Offset)
+	require.Equal(t, handshakeLen, hdrs[1].Length)
+
+	require.Equal(t, PacketType1RTT, hdrs[2].Type)
+	require.Equal(t, PacketNumberSpaceApplicationData, hdrs[2].PacketNumberSpace)
+	require.Equal(t, destConnID, hdrs[2].DestConnectionID)
+	require.Zero(t, hdrs[2].SrcConnectionID.Len())
+	require.Equal(t, initialLen+handshakeLen, hdrs[2].Offset)
+	require.Equal(t, 9, hdrs[2].Length)
+	require.Equal(t, 5, hdrs[2].HeaderLength)
+
+	// the connection ID length of short header packets is unknown
+	hdrs = ParseHeaders(b[initialLen+handshakeLen:], -1)
+	require.Len(t, hdrs, 1)
+	require.Equal(t, PacketType1RTT, hdrs[0].Type)
+	require.Zero(t, hdrs[0].DestConnectionID.Len())
+}
+
+func TestParse0RTTPacket(t *testing.T) {
+	b := appendLongHeaderPacket(t, nil, protocol.PacketType0RTT, protocol.ParseConnectionID([]byte{1, 2, 3, 4}), protocol.ConnectionID{}, []byte("foobar"))
+	hdrs := ParseHeaders(b, 4)
+	require.Len(t, hdrs, 1)
+	require.Equal(t, PacketType0RTT, hdrs[0].Type)
+	require.Equal(t, PacketNumberSpaceApplicationData, hdrs[0].PacketNumberSpace)
+}
+
+func TestParseVersionNegotiationPacket(t *testing.T) {
+	b := wire.ComposeVersionNegotiation(
+		protocol.ArbitraryLenConnectionID{1, 2, 3},
+		protocol.ArbitraryLenConnectionID{4, 5, 6, 7},
+		[]protocol.Version{protocol.Version1},
+	)
+	hdrs := ParseHeaders(b, 4)
+	require.Len(t, hdrs, 1)
+	require.Equal(t, PacketTypeVersionNegotiation, hdrs[0].Type)
+	require.Equal(t, PacketNumberSpaceNone, hdrs[0].PacketNumberSpace)
+	require.Equal(t, protocol.ParseConnectionID([]byte{1, 2, 3}), hdrs[0].DestConnectionID)
+	require.Equal(t, protocol.ParseConnectionID([]byte{4, 5, 6, 7}), hdrs[0].SrcConnectionID)
+	require.Equal(t, len(b), hdrs[0].Length)
+}
+
+func TestParseRetryPacket(t *testing.T) {
+	b := []byte{0xc0 | 0b11<<4}
+	b = append(b, 0, 0, 0, 1)          // version
+	b = append(b, 4, 1, 2, 3, 4)       // dest conn ID
+	b = append(b, 3, 5, 6, 7)          // src conn ID
+	b = append(b, []byte("foobar")...) // token
+	b = append(b, make([]byte, 16)...) // integrity tag
+	hdrs := ParseHeaders(b, 4)
+	require.Len(t, hdrs, 1)
+	require.Equal(t, PacketTypeRetry, hdrs[0].Type)
+	require.Equal(t, PacketNumberSpaceNone, hdrs[0].PacketNumberSpace)
+	require.Equal(t, len(b), hdrs[0].Length)
+}
+
+func TestParseInvalidPacket(t *testing.T) {
+	b := appendLongHeaderPacket(t, nil, protocol.PacketTypeInitial, protocol.ParseConnectionID([]byte{1, 2, 3, 4}), protocol.ConnectionID{}, []byte("foobar"))
+	initialLen := len(b)
+	// a long header packet that's cut short
+	b = append(b, appendLongHeaderPacket(t, nil, protocol.PacketTypeHandshake, protocol.ParseConnectionID([]byte{1, 2, 3, 4}), protocol.ConnectionID{}, []byte("foobar"))[:10]...)
+	hdrs := ParseHeaders(b, 4)
+	require.Len(t, hdrs, 2)
+	require.Equal(t, PacketTypeInitial, hdrs[0].Type)
+	require.Equal(t, PacketTypeUnknown, hdrs[1].Type)
+	require.Equal(t, PacketNumberSpaceNone, hdrs[1].PacketNumberSpace)
+	require.Equal(t, initialLen, hdrs[1].Offset)
+	require.Equal(t, 10, hdrs[1].Length)
+}
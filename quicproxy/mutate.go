@@ -0,0 +1,59 @@
+package quicproxy
+
+import (
+	"math/rand/v2"
+	"slices"
+)
+
+// The following functions can be used to implement a MutateCallback.
+// They don't modify the packet passed to them, but return a modified copy.
+
+// FlipBits flips n randomly chosen bits of the packet.
+// If n is larger than the number of bits in the packet, all bits are flipped.
+func FlipBits(packet []byte, n int) []byte {
+	b := slices.Clone(packet)
+	numBits := 8 * len(b)
+	if n >= numBits {
+		for i := range b {
+			b[i] = ^b[i]
+		}
+		return b
+	}
+	flipped := make(map[int]struct{}, n)
+	for len(flipped) < n {
+		pos := rand.IntN(numBits)
+		if _, ok := flipped[pos]; ok {
+			continue
+		}
+		flipped[pos] = struct{}{}
+		b[pos/8] ^= 1 << (pos % 8)
+	}
+	return b
+}
+
+// Truncate truncates the packet to n bytes.
+func Truncate(packet []byte, n int) []byte {
+	if n >= len(packet) {
+		return slices.Clone(packet)
+	}
+	return slices.Clone(packet[:max(n, 0)])
+}
+
+// CorruptHeader flips a randomly chosen bit in the header of the first QUIC packet of the datagram.
+// The first byte is not modified, such that the header form and the packet type are preserved.
+// For long header packets, this corrupts the version, the connection IDs, the token or the length,
+// for short header packets it corrupts the connection ID.
+// If the header can't be parsed, the packet is returned unmodified.
+func CorruptHeader(p *Packet) []byte {
+	b := slices.Clone(p.Data)
+	if len(p.Headers) == 0 || p.Headers[0].Type == PacketTypeUnknown {
+		return b
+	}
+	hdr := p.Headers[0]
+	if hdr.HeaderLength <= 1 {
+		return b
+	}
+	pos := 8 + rand.IntN(8*(hdr.HeaderLength-1))
+	b[hdr.Offset+pos/8] ^= 1 << (pos % 8)
+	return b
+}
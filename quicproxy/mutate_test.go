@@ -0,0 +1,69 @@
+package quicproxy
+
+import (
+	"bytes"
+	"math/bits"
+	"testing"
+
+	"github.com/nukilabs/quic-go/internal/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func countBitDifferences(a, b []byte) int {
+	var n int
+	for i := range a {
+		n += bits.OnesCount8(a[i] ^ b[i])
+	}
+	return n
+}
+
+func TestFlipBits(t *testing.T) {
+	data := bytes.Repeat([]byte("foobar"), 10)
+	orig := bytes.Clone(data)
+	for _, n := range []int{1, 5, 20} {
+		b := FlipBits(data, n)
+		require.Equal(t, orig, data) // the original packet is not modified
+		require.Len(t, b, len(data))
+		require.Equal(t, n, countBitDifferences(data, b))
+	}
+	require.Equal(t, []byte{0xff, 0}, FlipBits([]byte{0, 0xff}, 100))
+}
+
+func TestTruncate(t *testing.T) {
+	data := []byte("foobar")
+	require.Equal(t, []byte("foo"), Truncate(data, 3))
+	require.Equal(t, []byte("foobar"), Truncate(data, 10))
+	require.Empty(t, Truncate(data, 0))
+	// the original packet is not modified
+	b := Truncate(data, 3)
+	b[0] = 'x'
+	require.Equal(t, []byte("foobar"), data)
+}
+
+func TestCorruptHeader(t *testing.T) {
+	destConnID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+	b := appendLongHeaderPacket(t, nil, protocol.PacketTypeInitial, destConnID, protocol.ConnectionID{}, []byte("initial"))
+	initialLen := len(b)
+	b = append(b, 0x40, 1, 2, 3, 4, 42, 'f', 'o', 'o')
+	orig := bytes.Clone(b)
+
+	for range 100 {
+		p := &Packet{Data: b, Headers: ParseHeaders(b, 4)}
+		corrupted := CorruptHeader(p)
+		require.Equal(t, orig, b)
+		require.Equal(t, 1, countBitDifferences(b, corrupted))
+		// only the header of the first packet is modified
+		require.Equal(t, b[0], corrupted[0])
+		require.Equal(t, b[p.Headers[0].HeaderLength:], corrupted[p.Headers[0].HeaderLength:])
+	}
+
+	// short header packet
+	shortHeader := b[initialLen:]
+	for range 100 {
+		p := &Packet{Data: shortHeader, Headers: ParseHeaders(shortHeader, 4)}
+		corrupted := CorruptHeader(p)
+		require.Equal(t, 1, countBitDifferences(shortHeader, corrupted))
+		require.Equal(t, shortHeader[5:], corrupted[5:])
+	}
+}
@@ -0,0 +1,555 @@
+// Package quicproxy implements a UDP proxy for fault injection testing of QUIC connections.
+// It forwards packets between clients and a server, and can drop, delay, modify,
+// duplicate and reorder them. Callbacks are provided with the parsed QUIC packet headers.
+package quicproxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/monotime"
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/utils"
+	"github.com/nukilabs/quic-go/internal/wire"
+)
+
+// Connection is a UDP connection
+type connection struct {
+	ClientAddr *net.UDPAddr // Address of the client
+	ServerAddr *net.UDPAddr // Address of the server
+
+	mx         sync.Mutex
+	ServerConn *net.UDPConn // UDP connection to server
+
+	incomingPackets chan packetEntry
+	outgoingPackets chan packetEntry
+
+	Incoming *queue
+	Outgoing *queue
+
+	incomingReorder *reorderBuffer
+	outgoingReorder *reorderBuffer
+
+	// The lengths of the connection IDs chosen by the client and the server.
+	// They are learned from the long header packets, and used to parse short header packets.
+	// -1 if not known yet.
+	clientConnIDLen atomic.Int32
+	serverConnIDLen atomic.Int32
+}
+
+// learnConnIDLen learns the connection ID length from long header packets.
+func (c *connection) learnConnIDLen(dir Direction, hdrs []PacketHeader) {
+	for _, hdr := range hdrs {
+		switch hdr.Type {
+		case PacketTypeInitial, PacketType0RTT, PacketTypeHandshake:
+		default:
+			continue
+		}
+		if dir == DirectionIncoming {
+			c.clientConnIDLen.Store(int32(hdr.SrcConnectionID.Len()))
+		} else {
+			c.serverConnIDLen.Store(int32(hdr.SrcConnectionID.Len()))
+		}
+	}
+}
+
+// parseHeaders parses the headers of a packet.
+// Packets sent by the client use the connection ID chosen by the server, and vice versa.
+func (c *connection) parseHeaders(dir Direction, raw []byte) []PacketHeader {
+	connIDLen := c.clientConnIDLen.Load()
+	if dir == DirectionIncoming {
+		connIDLen = c.serverConnIDLen.Load()
+	}
+	return ParseHeaders(raw, int(connIDLen))
+}
+
+func (c *connection) reorderBuffer(dir Direction) *reorderBuffer {
+	if dir == DirectionIncoming {
+		return c.incomingReorder
+	}
+	return c.outgoingReorder
+}
+
+func (c *connection) SwitchConn(conn *net.UDPConn) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	old := c.ServerConn
+	old.SetReadDeadline(time.Now())
+	c.ServerConn = conn
+}
+
+func (c *connection) GetServerConn() *net.UDPConn {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.ServerConn
+}
+
+// Direction is the direction a packet is sent.
+type Direction int
+
+const (
+	// DirectionIncoming is the direction from the client to the server.
+	DirectionIncoming Direction = iota
+	// DirectionOutgoing is the direction from the server to the client.
+	DirectionOutgoing
+	// DirectionBoth is both incoming and outgoing
+	DirectionBoth
+)
+
+type packetEntry struct {
+	Time monotime.Time
+	Raw  []byte
+}
+
+type queue struct {
+	sync.Mutex
+
+	timer   *time.Timer
+	Packets []packetEntry // sorted by the packetEntry.Time
+}
+
+func newQueue() *queue {
+	// there's no way to initialize a time.Timer that's not running
+	return &queue{timer: time.NewTimer(24 * time.Hour)}
+}
+
+func (q *queue) Add(e packetEntry) {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.Packets) == 0 {
+		q.Packets = append(q.Packets, e)
+		q.timer.Reset(monotime.Until(e.Time))
+		return
+	}
+
+	// The packets slice is sorted by the packetEntry.Time.
+	// We only need to insert the packet at the correct position.
+	idx := slices.IndexFunc(q.Packets, func(p packetEntry) bool {
+		return p.Time.After(e.Time)
+	})
+	if idx == -1 {
+		q.Packets = append(q.Packets, e)
+	} else {
+		q.Packets = slices.Insert(q.Packets, idx, e)
+	}
+	if idx == 0 {
+		q.timer.Reset(monotime.Until(q.Packets[0].Time))
+	}
+}
+
+func (q *queue) Get() []byte {
+	q.Lock()
+	raw := q.Packets[0].Raw
+	q.Packets = q.Packets[1:]
+	if len(q.Packets) > 0 {
+		q.timer.Reset(monotime.Until(q.Packets[0].Time))
+	}
+	q.Unlock()
+	return raw
+}
+
+func (q *queue) Timer() <-chan time.Time { return q.timer.C }
+
+func (q *queue) Close() { q.timer.Stop() }
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionIncoming:
+		return "Incoming"
+	case DirectionOutgoing:
+		return "Outgoing"
+	case DirectionBoth:
+		return "both"
+	default:
+		return fmt.Sprintf("unknown direction: %d", int(d))
+	}
+}
+
+// Is says if one direction matches another direction.
+// For example, incoming matches both incoming and both, but not outgoing.
+func (d Direction) Is(dir Direction) bool {
+	if d == DirectionBoth || dir == DirectionBoth {
+		return true
+	}
+	return d == dir
+}
+
+// DropCallback is a callback that determines which packet gets dropped.
+type DropCallback func(dir Direction, from, to net.Addr, packet []byte) bool
+
+// DelayCallback is a callback that determines how much delay to apply to a packet.
+type DelayCallback func(dir Direction, from, to net.Addr, packet []byte) time.Duration
+
+// Packet is a UDP datagram passing through the proxy.
+type Packet struct {
+	Direction Direction
+	From, To  net.Addr
+	Data      []byte
+	// Headers are the headers of the QUIC packets coalesced into this datagram.
+	// The connection ID length of short header packets is learned from the long header packets
+	// sent in the opposite direction. If no long header packet was observed yet,
+	// the Destination Connection ID of short header packets is not set.
+	Headers []PacketHeader
+}
+
+// MutateCallback is a callback that can modify packets.
+// It returns the packet that is forwarded. Returning an empty packet drops the packet.
+// It must not modify the packet in place.
+type MutateCallback func(p *Packet) []byte
+
+// DuplicateCallback is a callback that determines how many duplicates of a packet are sent,
+// in addition to the packet itself.
+type DuplicateCallback func(p *Packet) int
+
+// ReorderCallback is a callback that determines which packets are added to the reordering window.
+type ReorderCallback func(p *Packet) bool
+
+// Proxy is a QUIC proxy that can drop, delay, modify, duplicate and reorder packets.
+//
+// Packets are processed in the following order:
+//  1. DropPacket decides if the packet is dropped.
+//  2. MutatePacket modifies the packet.
+//  3. DuplicatePacket decides if the packet is duplicated.
+//  4. ReorderPacket decides if the packet (and its duplicates) is added to the reordering window.
+//  5. DelayPacket decides how long the packet is delayed.
+type Proxy struct {
+	// Conn is the UDP socket that the proxy listens on for incoming packets from clients.
+	Conn *net.UDPConn
+
+	// ServerAddr is the address of the server that the proxy forwards packets to.
+	ServerAddr *net.UDPAddr
+
+	// DropPacket is a callback that determines which packet gets dropped.
+	DropPacket DropCallback
+
+	// DelayPacket is a callback that determines how much delay to apply to a packet.
+	DelayPacket DelayCallback
+
+	// MutatePacket is a callback that can modify packets.
+	MutatePacket MutateCallback
+
+	// DuplicatePacket is a callback that determines how often a packet is duplicated.
+	DuplicatePacket DuplicateCallback
+
+	// ReorderPacket is a callback that determines which packets are added to the reordering window.
+	// Packets in the reordering window are held back until ReorderWindow packets were collected,
+	// or until ReorderTimeout has passed since the first packet was added.
+	// They are then released in reverse order.
+	// The reordering window is maintained per client and direction.
+	ReorderPacket ReorderCallback
+	// ReorderWindow is the number of packets in the reordering window.
+	// If zero, it defaults to 2.
+	ReorderWindow int
+	// ReorderTimeout is the maximum time packets are held back in the reordering window.
+	// If zero, it defaults to 10ms.
+	ReorderTimeout time.Duration
+
+	closeChan chan struct{}
+	logger    utils.Logger
+
+	// mapping from client addresses (as host:port) to connection
+	mutex      sync.Mutex
+	clientDict map[string]*connection
+}
+
+// Start starts the proxy.
+// Packets received on Conn are forwarded to ServerAddr, and the server's responses are sent back to the client.
+// For every client, packets are sent to the server from a new UDP socket.
+func (p *Proxy) Start() error {
+	p.clientDict = make(map[string]*connection)
+	p.closeChan = make(chan struct{})
+	p.logger = utils.DefaultLogger.WithPrefix("proxy")
+
+	if err := p.Conn.SetReadBuffer(protocol.DesiredReceiveBufferSize); err != nil {
+		return err
+	}
+	if err := p.Conn.SetWriteBuffer(protocol.DesiredSendBufferSize); err != nil {
+		return err
+	}
+
+	p.logger.Debugf("Starting UDP Proxy %s <-> %s", p.Conn.LocalAddr(), p.ServerAddr)
+	go p.runProxy()
+	return nil
+}
+
+// SwitchConn switches the connection for a client,
+// identified the address that the client is sending from.
+func (p *Proxy) SwitchConn(clientAddr *net.UDPAddr, conn *net.UDPConn) error {
+	if err := conn.SetReadBuffer(protocol.DesiredReceiveBufferSize); err != nil {
+		return err
+	}
+	if err := conn.SetWriteBuffer(protocol.DesiredSendBufferSize); err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	c, ok := p.clientDict[clientAddr.String()]
+	if !ok {
+		return fmt.Errorf("client %s not found", clientAddr)
+	}
+	c.SwitchConn(conn)
+	return nil
+}
+
+// Close stops the UDP Proxy
+func (p *Proxy) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	close(p.closeChan)
+	for _, c := range p.clientDict {
+		if err := c.GetServerConn().Close(); err != nil {
+			return err
+		}
+		c.Incoming.Close()
+		c.Outgoing.Close()
+		c.incomingReorder.Close()
+		c.outgoingReorder.Close()
+	}
+	return nil
+}
+
+// LocalAddr is the address the proxy is listening on.
+func (p *Proxy) LocalAddr() net.Addr { return p.Conn.LocalAddr() }
+
+func (p *Proxy) newConnection(cliAddr *net.UDPAddr) (*connection, error) {
+	// Bind to the unspecified address of the server's address family,
+	// such that the operating system chooses the source address used to reach the server.
+	network, laddr := "udp4", &net.UDPAddr{IP: net.IPv4zero}
+	if p.ServerAddr.IP.To4() == nil {
+		network, laddr.IP = "udp6", net.IPv6unspecified
+	}
+	conn, err := net.ListenUDP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadBuffer(protocol.DesiredReceiveBufferSize); err != nil {
+		return nil, err
+	}
+	if err := conn.SetWriteBuffer(protocol.DesiredSendBufferSize); err != nil {
+		return nil, err
+	}
+	c := &connection{
+		ClientAddr:      cliAddr,
+		ServerAddr:      p.ServerAddr,
+		incomingPackets: make(chan packetEntry, 10),
+		outgoingPackets: make(chan packetEntry, 10),
+		Incoming:        newQueue(),
+		Outgoing:        newQueue(),
+		ServerConn:      conn,
+	}
+	c.clientConnIDLen.Store(-1)
+	c.serverConnIDLen.Store(-1)
+	window := p.ReorderWindow
+	if window == 0 {
+		window = defaultReorderWindow
+	}
+	timeout := p.ReorderTimeout
+	if timeout == 0 {
+		timeout = defaultReorderTimeout
+	}
+	c.incomingReorder = newReorderBuffer(window, timeout, func(packets [][]byte) {
+		p.releaseReordered(c, DirectionIncoming, packets)
+	})
+	c.outgoingReorder = newReorderBuffer(window, timeout, func(packets [][]byte) {
+		p.releaseReordered(c, DirectionOutgoing, packets)
+	})
+	return c, nil
+}
+
+// runProxy listens on the proxy address and handles incoming packets.
+func (p *Proxy) runProxy() error {
+	for {
+		buffer := make([]byte, protocol.MaxPacketBufferSize)
+		n, cliaddr, err := p.Conn.ReadFromUDP(buffer)
+		if err != nil {
+			return err
+		}
+		raw := buffer[:n]
+
+		p.mutex.Lock()
+		conn, ok := p.clientDict[cliaddr.String()]
+
+		if !ok {
+			conn, err = p.newConnection(cliaddr)
+			if err != nil {
+				p.mutex.Unlock()
+				return err
+			}
+			p.clientDict[cliaddr.String()] = conn
+			go p.runIncomingConnection(conn)
+			go p.runOutgoingConnection(conn)
+		}
+		p.mutex.Unlock()
+
+		if err := p.handlePacket(conn, DirectionIncoming, cliaddr, conn.ServerAddr, raw); err != nil {
+			return err
+		}
+	}
+}
+
+// runConnection handles packets from server to a single client
+func (p *Proxy) runOutgoingConnection(conn *connection) error {
+	go func() {
+		for {
+			buffer := make([]byte, protocol.MaxPacketBufferSize)
+			n, addr, err := conn.GetServerConn().ReadFrom(buffer)
+			if err != nil {
+				// when the connection is switched out, we set a deadline on the old connection,
+				// in order to return it immediately
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					continue
+				}
+				return
+			}
+			raw := buffer[0:n]
+
+			if err := p.handlePacket(conn, DirectionOutgoing, addr, conn.ClientAddr, raw); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-p.closeChan:
+			return nil
+		case e := <-conn.outgoingPackets:
+			conn.Outgoing.Add(e)
+		case <-conn.Outgoing.Timer():
+			if _, err := p.Conn.WriteTo(conn.Outgoing.Get(), conn.ClientAddr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *Proxy) runIncomingConnection(conn *connection) error {
+	for {
+		select {
+		case <-p.closeChan:
+			return nil
+		case e := <-conn.incomingPackets:
+			// Send the packet to the server
+			conn.Incoming.Add(e)
+		case <-conn.Incoming.Timer():
+			if _, err := conn.GetServerConn().WriteTo(conn.Incoming.Get(), conn.ServerAddr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handlePacket runs a packet through the proxy, and forwards it (unless it's dropped).
+func (p *Proxy) handlePacket(conn *connection, dir Direction, from, to net.Addr, raw []byte) error {
+	var hdrs []PacketHeader
+	if len(raw) > 0 && wire.IsLongHeaderPacket(raw[0]) {
+		hdrs = conn.parseHeaders(dir, raw)
+		conn.learnConnIDLen(dir, hdrs)
+	}
+
+	if p.DropPacket != nil && p.DropPacket(dir, from, to, raw) {
+		if p.logger.Debug() {
+			p.logger.Debugf("dropping %s packet (%d bytes)", dir, len(raw))
+		}
+		return nil
+	}
+
+	if p.MutatePacket == nil && p.DuplicatePacket == nil && p.ReorderPacket == nil {
+		return p.forward(conn, dir, from, to, raw)
+	}
+
+	if hdrs == nil {
+		hdrs = conn.parseHeaders(dir, raw)
+	}
+	pkt := &Packet{Direction: dir, From: from, To: to, Data: raw, Headers: hdrs}
+	if p.MutatePacket != nil {
+		raw = p.MutatePacket(pkt)
+		if len(raw) == 0 {
+			if p.logger.Debug() {
+				p.logger.Debugf("dropping mutated %s packet", dir)
+			}
+			return nil
+		}
+		pkt.Data = raw
+	}
+	numCopies := 1
+	if p.DuplicatePacket != nil {
+		numCopies += max(p.DuplicatePacket(pkt), 0)
+	}
+	reorder := p.ReorderPacket != nil && p.ReorderPacket(pkt)
+	for i := range numCopies {
+		b := raw
+		if i > 0 {
+			b = slices.Clone(raw)
+			if p.logger.Debug() {
+				p.logger.Debugf("duplicating %s packet (%d bytes)", dir, len(raw))
+			}
+		}
+		if reorder {
+			conn.reorderBuffer(dir).Add(b)
+			continue
+		}
+		if err := p.forward(conn, dir, from, to, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Proxy) releaseReordered(conn *connection, dir Direction, packets [][]byte) {
+	from, to := net.Addr(conn.ClientAddr), net.Addr(conn.ServerAddr)
+	if dir == DirectionOutgoing {
+		from, to = to, from
+	}
+	if p.logger.Debug() {
+		p.logger.Debugf("releasing %d reordered %s packets", len(packets), dir)
+	}
+	for _, b := range packets {
+		if err := p.forward(conn, dir, from, to, b); err != nil {
+			p.logger.Errorf("failed to forward reordered packet: %s", err)
+			return
+		}
+	}
+}
+
+// forward delays the packet (if required) and sends it.
+func (p *Proxy) forward(conn *connection, dir Direction, from, to net.Addr, raw []byte) error {
+	var delay time.Duration
+	if p.DelayPacket != nil {
+		delay = p.DelayPacket(dir, from, to, raw)
+	}
+	if delay == 0 {
+		if p.logger.Debug() {
+			p.logger.Debugf("forwarding %s packet (%d bytes) to %s", dir, len(raw), to)
+		}
+		var err error
+		if dir == DirectionIncoming {
+			_, err = conn.GetServerConn().WriteTo(raw, conn.ServerAddr)
+		} else {
+			_, err = p.Conn.WriteToUDP(raw, conn.ClientAddr)
+		}
+		return err
+	}
+	if p.logger.Debug() {
+		p.logger.Debugf("delaying %s packet (%d bytes) to %s by %s", dir, len(raw), to, delay)
+	}
+	packets := conn.incomingPackets
+	if dir == DirectionOutgoing {
+		packets = conn.outgoingPackets
+	}
+	select {
+	case packets <- packetEntry{Time: monotime.Now().Add(delay), Raw: raw}:
+	case <-p.closeChan:
+	}
+	return nil
+}
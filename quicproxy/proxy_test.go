@@ -3,6 +3,7 @@ package quicproxy
 import (
 	"net"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -138,6 +139,38 @@ func TestProxyingBackAndForth(t *testing.T) {
 	require.Contains(t, string(buf[:n]), "decafbad")
 }
 
+func TestProxyingIPv6(t *testing.T) {
+	serverConn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback, Port: 0})
+	if err != nil {
+		t.Skipf("IPv6 not available: %v", err)
+	}
+	defer serverConn.Close()
+	proxy := Proxy{
+		Conn:       newUPDConnLocalhost(t),
+		ServerAddr: serverConn.LocalAddr().(*net.UDPAddr),
+	}
+	require.NoError(t, proxy.Start())
+	defer proxy.Close()
+	clientConn, err := net.DialUDP("udp", nil, proxy.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	_, err = clientConn.Write(makePacket(t, 1, []byte("foobar")))
+	require.NoError(t, err)
+
+	serverConn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	n, addr, err := serverConn.ReadFromUDP(buf)
+	require.NoError(t, err)
+	require.Contains(t, string(buf[:n]), "foobar")
+	require.True(t, addr.IP.Equal(net.IPv6loopback))
+}
+
+func TestDirectionString(t *testing.T) {
+	require.Equal(t, "Incoming", DirectionIncoming.String())
+	require.Equal(t, "Outgoing", DirectionOutgoing.String())
+	require.Equal(t, "both", DirectionBoth.String())
+	require.Equal(t, "unknown direction: 42", Direction(42).String())
+}
+
 func TestDropIncomingPackets(t *testing.T) {
 	const numPackets = 6
 	serverAddr, serverReceivedPackets := runServer(t)
@@ -501,3 +534,173 @@ func TestProxySwitchConn(t *testing.T) {
 	require.Equal(t, "foobaz", string(buf[:n])) // "invalid" is not delivered
 	require.Equal(t, proxy.LocalAddr(), addr)
 }
+
+func receivePackets(t *testing.T, c <-chan []byte, num int) [][]byte {
+	t.Helper()
+	var packets [][]byte
+	for range num {
+		select {
+		case p := <-c:
+			packets = append(packets, p)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for packet %d", len(packets)+1)
+		}
+	}
+	select {
+	case <-c:
+		t.Fatal("received unexpected packet")
+	case <-time.After(50 * time.Millisecond):
+	}
+	return packets
+}
+
+func TestMutatePackets(t *testing.T) {
+	serverAddr, serverReceivedPackets := runServer(t)
+	proxy := Proxy{
+		Conn:       newUPDConnLocalhost(t),
+		ServerAddr: serverAddr,
+		MutatePacket: func(p *Packet) []byte {
+			if p.Direction != DirectionIncoming {
+				return p.Data
+			}
+			switch readPacketNumber(t, p.Data) {
+			case 1:
+				return Truncate(p.Data, 10)
+			case 2:
+				return nil // drop
+			}
+			return p.Data
+		},
+	}
+	require.NoError(t, proxy.Start())
+	defer proxy.Close()
+	clientConn, err := net.DialUDP("udp", nil, proxy.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		_, err := clientConn.Write(makePacket(t, protocol.PacketNumber(i), []byte("foobar")))
+		require.NoError(t, err)
+	}
+	packets := receivePackets(t, serverReceivedPackets, 2)
+	require.Len(t, packets[0], 10)
+	require.Equal(t, protocol.PacketNumber(3), readPacketNumber(t, packets[1]))
+}
+
+func TestDuplicatePackets(t *testing.T) {
+	serverAddr, serverReceivedPackets := runServer(t)
+	proxy := Proxy{
+		Conn:       newUPDConnLocalhost(t),
+		ServerAddr: serverAddr,
+		DuplicatePacket: func(p *Packet) int {
+			if p.Direction == DirectionIncoming && readPacketNumber(t, p.Data) == 2 {
+				return 2
+			}
+			return 0
+		},
+	}
+	require.NoError(t, proxy.Start())
+	defer proxy.Close()
+	clientConn, err := net.DialUDP("udp", nil, proxy.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		_, err := clientConn.Write(makePacket(t, protocol.PacketNumber(i), []byte("foobar")))
+		require.NoError(t, err)
+	}
+	var pns []protocol.PacketNumber
+	for _, p := range receivePackets(t, serverReceivedPackets, 5) {
+		pns = append(pns, readPacketNumber(t, p))
+	}
+	require.Equal(t, []protocol.PacketNumber{1, 2, 2, 2, 3}, pns)
+}
+
+func TestReorderWindow(t *testing.T) {
+	serverAddr, serverReceivedPackets := runServer(t)
+	proxy := Proxy{
+		Conn:       newUPDConnLocalhost(t),
+		ServerAddr: serverAddr,
+		ReorderPacket: func(p *Packet) bool {
+			return p.Direction == DirectionIncoming && readPacketNumber(t, p.Data) > 1
+		},
+		ReorderWindow:  3,
+		ReorderTimeout: 200 * time.Millisecond,
+	}
+	require.NoError(t, proxy.Start())
+	defer proxy.Close()
+	clientConn, err := net.DialUDP("udp", nil, proxy.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+
+	start := time.Now()
+	for i := 1; i <= 6; i++ {
+		_, err := clientConn.Write(makePacket(t, protocol.PacketNumber(i), []byte("foobar")))
+		require.NoError(t, err)
+	}
+	var pns []protocol.PacketNumber
+	for _, p := range receivePackets(t, serverReceivedPackets, 4) {
+		pns = append(pns, readPacketNumber(t, p))
+	}
+	// packet 1 is not reordered, packets 2-4 fill the window
+	require.Equal(t, []protocol.PacketNumber{1, 4, 3, 2}, pns)
+	require.Less(t, time.Since(start), 200*time.Millisecond)
+
+	// packets 5 and 6 are released when the timeout fires
+	pns = pns[:0]
+	for _, p := range receivePackets(t, serverReceivedPackets, 2) {
+		pns = append(pns, readPacketNumber(t, p))
+	}
+	require.Equal(t, []protocol.PacketNumber{6, 5}, pns)
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestPacketClassification(t *testing.T) {
+	serverAddr, _ := runServer(t)
+	var mx sync.Mutex
+	var headers [][]PacketHeader
+	proxy := Proxy{
+		Conn:       newUPDConnLocalhost(t),
+		ServerAddr: serverAddr,
+		DuplicatePacket: func(p *Packet) int {
+			if p.Direction == DirectionIncoming {
+				mx.Lock()
+				headers = append(headers, p.Headers)
+				mx.Unlock()
+			}
+			return 0
+		},
+	}
+	require.NoError(t, proxy.Start())
+	defer proxy.Close()
+	clientConn, err := net.DialUDP("udp", nil, proxy.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+
+	// The server's connection ID is not known yet.
+	// It is learned from the server's (echoed) long header packet.
+	shortHeaderPacket := append([]byte{0x40}, []byte{0xde, 0xad, 0xbe, 0xef, 0, 0, 0x13, 0x37, 1, 2, 3, 4}...)
+	_, err = clientConn.Write(shortHeaderPacket)
+	require.NoError(t, err)
+	_, err = clientConn.Write(makePacket(t, 1, []byte("foobar")))
+	require.NoError(t, err)
+	// wait for the echoed packet
+	_, err = clientConn.Read(make([]byte, 1500))
+	require.NoError(t, err)
+	_, err = clientConn.Read(make([]byte, 1500))
+	require.NoError(t, err)
+	_, err = clientConn.Write(shortHeaderPacket)
+	require.NoError(t, err)
+	_, err = clientConn.Read(make([]byte, 1500))
+	require.NoError(t, err)
+
+	mx.Lock()
+	defer mx.Unlock()
+	require.Len(t, headers, 3)
+	require.Len(t, headers[0], 1)
+	require.Equal(t, PacketType1RTT, headers[0][0].Type)
+	require.Zero(t, headers[0][0].DestConnectionID.Len())
+	require.Len(t, headers[1], 1)
+	require.Equal(t, PacketTypeInitial, headers[1][0].Type)
+	require.Equal(t, PacketNumberSpaceInitial, headers[1][0].PacketNumberSpace)
+	require.Len(t, headers[2], 1)
+	require.Equal(t, PacketType1RTT, headers[2][0].Type)
+	require.Equal(t, PacketNumberSpaceApplicationData, headers[2][0].PacketNumberSpace)
+	require.Equal(t, protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef, 0, 0, 0x13, 0x37}), headers[2][0].DestConnectionID)
+}
@@ -0,0 +1,87 @@
+package quicproxy
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReorderWindow  = 2
+	defaultReorderTimeout = 10 * time.Millisecond
+)
+
+// A reorderBuffer holds back packets, and releases them in reverse order.
+type reorderBuffer struct {
+	window  int
+	timeout time.Duration
+	release func([][]byte)
+
+	mx      sync.Mutex
+	closed  bool
+	packets [][]byte
+	timer   *time.Timer
+}
+
+func newReorderBuffer(window int, timeout time.Duration, release func([][]byte)) *reorderBuffer {
+	return &reorderBuffer{
+		window:  window,
+		timeout: timeout,
+		release: release,
+	}
+}
+
+// Add adds a packet to the reordering window.
+// Once the window is full, all packets are released.
+func (b *reorderBuffer) Add(packet []byte) {
+	b.mx.Lock()
+	if b.closed {
+		b.mx.Unlock()
+		return
+	}
+	b.packets = append(b.packets, packet)
+	if len(b.packets) < b.window {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.timeout, b.flush)
+		}
+		b.mx.Unlock()
+		return
+	}
+	packets := b.take()
+	b.mx.Unlock()
+
+	b.release(packets)
+}
+
+func (b *reorderBuffer) flush() {
+	b.mx.Lock()
+	if b.closed || len(b.packets) == 0 {
+		b.mx.Unlock()
+		return
+	}
+	packets := b.take()
+	b.mx.Unlock()
+
+	b.release(packets)
+}
+
+// take empties the reordering window, and returns the packets in reverse order.
+// It must be called with the mutex held.
+func (b *reorderBuffer) take() [][]byte {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	packets := b.packets
+	b.packets = nil
+	slices.Reverse(packets)
+	return packets
+}
+
+// Close discards all packets in the reordering window.
+func (b *reorderBuffer) Close() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.closed = true
+	b.take()
+}
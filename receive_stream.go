@@ -42,8 +42,10 @@ type ReceiveStream struct {
 	cancelErr           *StreamError
 	closeForShutdownErr error
 
-	readPos      protocol.ByteCount
-	reliableSize protocol.ByteCount
+	readPos         protocol.ByteCount
+	highestReceived protocol.ByteCount
+	reliableSize    protocol.ByteCount
+	receivedIn0RTT  bool // set if a STREAM frame was received in a 0-RTT packet
 
 	readChan chan struct{}
 	readOnce chan struct{} // cap: 1, to protect against concurrent use of Read
@@ -53,6 +55,7 @@ type ReceiveStream struct {
 }
 
 var (
+	_ io.WriterTo               = &ReceiveStream{}
 	_ streamControlFrameGetter  = &ReceiveStream{}
 	_ receiveStreamFrameHandler = &ReceiveStream{}
 )
@@ -78,6 +81,40 @@ func (s *ReceiveStream) StreamID() protocol.StreamID {
 	return s.streamID
 }
 
+// ReceivedIn0RTT says if any data of the stream was received in 0-RTT packets.
+// 0-RTT data can be replayed by an attacker.
+// This is only relevant for the server, since the client never receives 0-RTT packets.
+func (s *ReceiveStream) ReceivedIn0RTT() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.receivedIn0RTT
+}
+
+// ReceiveStreamStats contains statistics about the receive direction of a stream.
+type ReceiveStreamStats struct {
+	// BytesRead is the number of bytes of stream data read by the application.
+	BytesRead uint64
+	// HighestReceivedOffset is the highest offset of stream data received from the peer.
+	// Data below this offset might not have been received yet, if packets were lost or reordered.
+	HighestReceivedOffset uint64
+	// FinalSizeKnown says if the final size of the stream is known,
+	// i.e. if a STREAM frame with the FIN bit or a RESET_STREAM frame was received.
+	FinalSizeKnown bool
+}
+
+// Stats returns statistics about the receive direction of the stream.
+// It is safe to call Stats concurrently with other methods on the stream.
+func (s *ReceiveStream) Stats() ReceiveStreamStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return ReceiveStreamStats{
+		BytesRead:             uint64(s.readPos),
+		HighestReceivedOffset: uint64(s.highestReceived),
+		FinalSizeKnown:        s.finalOffset != protocol.MaxByteCount,
+	}
+}
+
 // Read reads data from the stream.
 // Read can be made to time out using [ReceiveStream.SetReadDeadline].
 // If the stream was canceled, the error is a [StreamError].
@@ -194,42 +231,182 @@ func (s *ReceiveStream) readImpl(p []byte) (hasStreamWindowUpdate bool, hasConnW
 			return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, fmt.Errorf("BUG: readPosInFrame (%d) > frame.DataLen (%d) in stream.Read", s.readPosInFrame, len(s.currentFrame))
 		}
 		m := copy(p[bytesRead:], s.currentFrame[s.readPosInFrame:])
+		hasStream, hasConn, isLast := s.consume(m)
+		hasStreamWindowUpdate = hasStreamWindowUpdate || hasStream
+		hasConnWindowUpdate = hasConnWindowUpdate || hasConn
+		bytesRead += m
+		if isLast {
+			return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, io.EOF
+		}
+	}
+	if s.isRemoteCancellationEffective() {
+		s.errorRead = true
+		return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, s.cancelErr
+	}
+	return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, nil
+}
 
-		// when a RESET_STREAM was received, the flow controller was already
-		// informed about the final offset for this stream
-		if !s.isRemoteCancellationEffective() {
-			hasStream, hasConn := s.flowController.AddBytesRead(protocol.ByteCount(m))
-			if hasStream {
-				s.queuedMaxStreamData = true
-				hasStreamWindowUpdate = true
-			}
-			if hasConn {
-				hasConnWindowUpdate = true
-			}
+// consume marks n bytes of the current frame as read by the application.
+// It returns true if the end of the stream was reached.
+func (s *ReceiveStream) consume(n int) (hasStreamWindowUpdate, hasConnWindowUpdate, isLast bool) {
+	// when a RESET_STREAM was received, the flow controller was already
+	// informed about the final offset for this stream
+	if !s.isRemoteCancellationEffective() {
+		hasStreamWindowUpdate, hasConnWindowUpdate = s.flowController.AddBytesRead(protocol.ByteCount(n))
+		if hasStreamWindowUpdate {
+			s.queuedMaxStreamData = true
 		}
+	}
 
-		s.readPosInFrame += m
-		s.readPos += protocol.ByteCount(m)
-		bytesRead += m
+	s.readPosInFrame += n
+	s.readPos += protocol.ByteCount(n)
 
-		if s.isRemoteCancellationEffective() {
-			s.flowController.Abandon()
+	if s.isRemoteCancellationEffective() {
+		s.flowController.Abandon()
+	}
+
+	if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
+		s.currentFrame = nil
+		if s.currentFrameDone != nil {
+			s.currentFrameDone()
 		}
+		s.errorRead = true
+		return hasStreamWindowUpdate, hasConnWindowUpdate, true
+	}
+	return hasStreamWindowUpdate, hasConnWindowUpdate, false
+}
+
+// WriteTo writes stream data to w until the end of the stream is reached or an error occurs.
+// It implements the io.WriterTo interface, and is used by io.Copy.
+// The data of the received STREAM frames is passed to w directly, without copying it into an intermediate buffer.
+// It returns the number of bytes written. Reaching the end of the stream is not reported as an error.
+// WriteTo can be made to time out using [ReceiveStream.SetReadDeadline].
+// If the stream was canceled, the error is a [StreamError].
+func (s *ReceiveStream) WriteTo(w io.Writer) (int64, error) {
+	s.readOnce <- struct{}{}
+	defer func() { <-s.readOnce }()
 
-		if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
-			s.currentFrame = nil
-			if s.currentFrameDone != nil {
-				s.currentFrameDone()
+	var written int64
+	for {
+		s.mutex.Lock()
+		data, err := s.waitForData()
+		s.mutex.Unlock()
+		if err != nil {
+			s.onRead(false, false)
+			if err == io.EOF {
+				return written, nil
 			}
+			return written, err
+		}
+
+		// The current frame is only dequeued while holding readOnce,
+		// so it is safe to access its data without holding the mutex.
+		var n int
+		var writeErr error
+		if len(data) > 0 {
+			n, writeErr = w.Write(data)
+			if n > len(data) {
+				n = len(data)
+			}
+		}
+		written += int64(n)
+
+		s.mutex.Lock()
+		var hasStreamWindowUpdate, hasConnWindowUpdate, isLast bool
+		switch {
+		case s.cancelledLocally:
+			// CancelRead was called during the call to Write
 			s.errorRead = true
-			return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, io.EOF
+			err = s.cancelErr
+		case s.closeForShutdownErr != nil:
+			err = s.closeForShutdownErr
+		default:
+			hasStreamWindowUpdate, hasConnWindowUpdate, isLast = s.consume(n)
+		}
+		s.mutex.Unlock()
+		s.onRead(hasStreamWindowUpdate, hasConnWindowUpdate)
+
+		if err != nil {
+			return written, err
+		}
+		if writeErr != nil {
+			return written, writeErr
+		}
+		if isLast {
+			return written, nil
+		}
+		if n < len(data) {
+			return written, io.ErrShortWrite
 		}
 	}
-	if s.isRemoteCancellationEffective() {
+}
+
+// waitForData blocks until stream data is available to be read, or an error occurs.
+// It returns the unread part of the current frame.
+// It must be called with the mutex held.
+func (s *ReceiveStream) waitForData() ([]byte, error) {
+	if s.currentFrameIsLast && s.currentFrame == nil {
 		s.errorRead = true
-		return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, s.cancelErr
+		return nil, io.EOF
+	}
+	if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
+		s.dequeueNextFrame()
+	}
+
+	var deadlineTimer *time.Timer
+	for {
+		if s.closeForShutdownErr != nil {
+			return nil, s.closeForShutdownErr
+		}
+		if s.cancelledLocally || s.isRemoteCancellationEffective() {
+			s.errorRead = true
+			return nil, s.cancelErr
+		}
+
+		deadline := s.deadline
+		if !deadline.IsZero() && !monotime.Now().Before(deadline) {
+			return nil, errDeadline
+		}
+
+		if s.currentFrame != nil || s.currentFrameIsLast {
+			return s.currentFrame[s.readPosInFrame:], nil
+		}
+
+		s.mutex.Unlock()
+		if deadline.IsZero() {
+			<-s.readChan
+		} else {
+			if deadlineTimer == nil {
+				deadlineTimer = time.NewTimer(monotime.Until(deadline))
+				defer deadlineTimer.Stop()
+			} else {
+				deadlineTimer.Reset(monotime.Until(deadline))
+			}
+			select {
+			case <-s.readChan:
+			case <-deadlineTimer.C:
+			}
+		}
+		s.mutex.Lock()
+		s.dequeueNextFrame()
+	}
+}
+
+// onRead notifies the stream sender after data was read.
+func (s *ReceiveStream) onRead(hasStreamWindowUpdate, hasConnWindowUpdate bool) {
+	s.mutex.Lock()
+	completed := s.isNewlyCompleted()
+	s.mutex.Unlock()
+
+	if completed {
+		s.sender.onStreamCompleted(s.streamID)
+	}
+	if hasStreamWindowUpdate {
+		s.sender.onHasStreamControlFrame(s.streamID, s)
+	}
+	if hasConnWindowUpdate {
+		s.sender.onHasConnectionData()
 	}
-	return hasStreamWindowUpdate, hasConnWindowUpdate, bytesRead, nil
 }
 
 // isRemoteCancellationEffective returns whether the stream was cancelled remotely
@@ -400,6 +577,13 @@ func (s *ReceiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) (queuedNe
 	return true
 }
 
+// markReceivedIn0RTT is called before handling a STREAM frame that was received in a 0-RTT packet.
+func (s *ReceiveStream) markReceivedIn0RTT() {
+	s.mutex.Lock()
+	s.receivedIn0RTT = true
+	s.mutex.Unlock()
+}
+
 func (s *ReceiveStream) handleStreamFrame(frame *wire.StreamFrame, now monotime.Time) error {
 	s.mutex.Lock()
 	err := s.handleStreamFrameImpl(frame, now)
@@ -418,6 +602,7 @@ func (s *ReceiveStream) handleStreamFrameImpl(frame *wire.StreamFrame, now monot
 	if err := s.flowController.UpdateHighestReceived(maxOffset, frame.Fin, now); err != nil {
 		return err
 	}
+	s.highestReceived = max(s.highestReceived, maxOffset)
 	if frame.Fin {
 		s.finalOffset = maxOffset
 	}
@@ -450,6 +635,7 @@ func (s *ReceiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame,
 	if err := s.flowController.UpdateHighestReceived(frame.FinalSize, true, now); err != nil {
 		return err
 	}
+	s.highestReceived = max(s.highestReceived, frame.FinalSize)
 	s.finalOffset = frame.FinalSize
 
 	// senders are allowed to reduce the reliable size, but frames might have been reordered
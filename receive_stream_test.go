@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -157,6 +158,130 @@ func TestReceiveStreamPeekData(t *testing.T) {
 	require.Equal(t, []byte("obar"), b)
 }
 
+type recordingWriter struct {
+	writes [][]byte
+	n      int // if set, the maximum number of bytes accepted by Write
+	err    error
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.n > 0 && len(p) > w.n {
+		p = p[:w.n]
+	}
+	w.writes = append(w.writes, append([]byte(nil), p...))
+	return len(p), w.err
+}
+
+func TestReceiveStreamWriteTo(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newReceiveStream(42, mockSender, mockFC)
+
+		w := &recordingWriter{}
+		type result struct {
+			n   int64
+			err error
+		}
+		resultChan := make(chan result, 1)
+		go func() {
+			n, err := str.WriteTo(w)
+			resultChan <- result{n, err}
+		}()
+
+		synctest.Wait()
+
+		mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		gomock.InOrder(
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3)).Return(true, false),
+			mockSender.EXPECT().onHasStreamControlFrame(protocol.StreamID(42), str),
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3)).Return(false, true),
+			mockSender.EXPECT().onHasConnectionData(),
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4)),
+			mockSender.EXPECT().onStreamCompleted(protocol.StreamID(42)),
+		)
+		now := monotime.Now()
+		require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar")}, now))
+		require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foo")}, now))
+		synctest.Wait()
+		require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte("baz!"), Fin: true}, now))
+		synctest.Wait()
+
+		select {
+		case res := <-resultChan:
+			require.NoError(t, res.err)
+			require.Equal(t, int64(10), res.n)
+		default:
+			t.Fatal("WriteTo should have returned")
+		}
+		// every frame is passed to the writer in a single call
+		require.Equal(t, [][]byte{[]byte("foo"), []byte("bar"), []byte("baz!")}, w.writes)
+
+		// further calls return immediately
+		n, err := str.WriteTo(w)
+		require.NoError(t, err)
+		require.Zero(t, n)
+	})
+}
+
+func TestReceiveStreamWriteToErrors(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newReceiveStream(42, mockSender, mockFC)
+
+		mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")}, monotime.Now()))
+
+		// errors returned by the writer are passed through, and only the bytes written are consumed
+		testErr := errors.New("test error")
+		mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+		n, err := str.WriteTo(&recordingWriter{n: 2, err: testErr})
+		require.ErrorIs(t, err, testErr)
+		require.Equal(t, int64(2), n)
+
+		// short writes
+		mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+		n, err = str.WriteTo(&recordingWriter{n: 3})
+		require.ErrorIs(t, err, io.ErrShortWrite)
+		require.Equal(t, int64(3), n)
+
+		mockFC.EXPECT().AddBytesRead(protocol.ByteCount(1))
+		b := make([]byte, 10)
+		m, err := str.Read(b)
+		require.NoError(t, err)
+		require.Equal(t, []byte("r"), b[:m])
+
+		// the deadline is respected
+		require.NoError(t, str.SetReadDeadline(time.Now().Add(time.Second)))
+		start := time.Now()
+		n, err = str.WriteTo(&recordingWriter{})
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+		require.Zero(t, n)
+		require.Equal(t, time.Second, time.Since(start))
+
+		// CancelRead unblocks WriteTo
+		require.NoError(t, str.SetReadDeadline(time.Time{}))
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := str.WriteTo(&recordingWriter{})
+			errChan <- err
+		}()
+		synctest.Wait()
+		mockSender.EXPECT().onHasStreamControlFrame(protocol.StreamID(42), str)
+		str.CancelRead(1337)
+		synctest.Wait()
+		select {
+		case err := <-errChan:
+			require.ErrorIs(t, err, &StreamError{StreamID: 42, ErrorCode: 1337, Remote: false})
+		default:
+			t.Fatal("WriteTo should have returned")
+		}
+	})
+}
+
 func TestReceiveStreamBlockRead(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
@@ -309,6 +434,29 @@ func testReceiveStreamFlowControlUpdates(t *testing.T, hasStreamWindowUpdate, ha
 	}
 }
 
+func TestReceiveStreamStats(t *testing.T) {
+	const streamID protocol.StreamID = 42
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	mockSender := NewMockStreamSender(mockCtrl)
+	str := newReceiveStream(streamID, mockSender, mockFC)
+	require.Equal(t, ReceiveStreamStats{}, str.Stats())
+
+	now := monotime.Now()
+	mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(8), true, now)
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte("barz"), Fin: true}, now))
+	require.Equal(t, ReceiveStreamStats{HighestReceivedOffset: 8, FinalSizeKnown: true}, str.Stats())
+
+	// reordered data doesn't decrease the highest received offset
+	mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false, now)
+	require.NoError(t, str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foo!")}, now))
+	mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+	n, err := (&readerWithTimeout{Reader: str, Timeout: time.Second}).Read(make([]byte, 4))
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, ReceiveStreamStats{BytesRead: 4, HighestReceivedOffset: 8, FinalSizeKnown: true}, str.Stats())
+}
+
 func TestReceiveStreamDeadlineInThePast(t *testing.T) {
 	t.Run("read", func(t *testing.T) {
 		testReceiveStreamDeadlineInThePast(t, true, func(str *ReceiveStream, b []byte) (int, error) {
@@ -3,7 +3,9 @@ package quic
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nukilabs/quic-go/internal/ackhandler"
@@ -33,6 +35,8 @@ type SendStream struct {
 	// This method returns 0 if the peer doesn't support the RESET_STREAM_AT extension.
 	reliableSize protocol.ByteCount
 	writeOffset  protocol.ByteCount
+	// the number of bytes of stream data that were retransmitted
+	bytesRetransmitted protocol.ByteCount
 
 	shutdownErr            error
 	resetErr               *StreamError
@@ -41,6 +45,8 @@ type SendStream struct {
 	supportsResetStreamAt bool
 	finishedWriting       bool // set once Close() is called
 	finSent               bool // set when a STREAM_FRAME with FIN bit has been sent
+	// set when the stream has data to send, but the send window is exhausted
+	flowControlBlocked bool
 	// Set when the application knows about the cancellation.
 	// This can happen because the application called CancelWrite,
 	// or because Write returned the error (for remote cancellations).
@@ -48,7 +54,13 @@ type SendStream struct {
 	completed           bool // set when this stream has been reported to the streamSender as completed
 
 	dataForWriting []byte // during a Write() call, this slice is the part of p that still needs to be sent out
-	nextFrame      *wire.StreamFrame
+	// During a WriteBuffers() call, the buffers that need to be sent out after dataForWriting.
+	moreDataForWriting    [][]byte
+	moreDataForWritingLen int
+	// During a ReadFrom() call, the chunk that dataForWriting belongs to.
+	// STREAM frames then reference the chunk, instead of copying the data.
+	dataForWritingChunk *sendChunk
+	nextFrame           *wire.StreamFrame
 
 	writeChan chan struct{}
 	writeOnce chan struct{}
@@ -58,6 +70,7 @@ type SendStream struct {
 }
 
 var (
+	_ io.ReaderFrom            = &SendStream{}
 	_ streamControlFrameGetter = &SendStream{}
 	_ outgoingStream           = &SendStream{}
 	_ sendStreamFrameHandler   = &SendStream{}
@@ -87,6 +100,33 @@ func (s *SendStream) StreamID() StreamID {
 	return s.streamID // same for receiveStream and sendStream
 }
 
+// SendStreamStats contains statistics about the send direction of a stream.
+type SendStreamStats struct {
+	// BytesSent is the number of bytes of stream data sent, not counting retransmissions.
+	BytesSent uint64
+	// BytesRetransmitted is the number of bytes of stream data that were retransmitted.
+	BytesRetransmitted uint64
+	// FlowControlBlockedTime is the total time that sending was blocked by stream-level flow control.
+	FlowControlBlockedTime time.Duration
+	// FlowControlBlocked says if the stream has data to send, but is currently blocked by
+	// (stream-level or connection-level) flow control.
+	FlowControlBlocked bool
+}
+
+// Stats returns statistics about the send direction of the stream.
+// It is safe to call Stats concurrently with other methods on the stream.
+func (s *SendStream) Stats() SendStreamStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return SendStreamStats{
+		BytesSent:              uint64(s.writeOffset),
+		BytesRetransmitted:     uint64(s.bytesRetransmitted),
+		FlowControlBlockedTime: s.flowController.BlockedTime(),
+		FlowControlBlocked:     s.flowControlBlocked,
+	}
+}
+
 // Write writes data to the stream.
 // Write can be made to time out using [SendStream.SetWriteDeadline].
 // If the stream was canceled, the error is a [StreamError].
@@ -97,17 +137,87 @@ func (s *SendStream) Write(p []byte) (int, error) {
 	s.writeOnce <- struct{}{}
 	defer func() { <-s.writeOnce }()
 
-	isNewlyCompleted, n, err := s.write(p)
+	isNewlyCompleted, n, err := s.write(p, nil, nil)
+	if isNewlyCompleted {
+		s.sender.onStreamCompleted(s.streamID)
+	}
+	return n, err
+}
+
+// WriteBuffers writes the contents of bufs to the stream, as if they were concatenated and passed to a single Write call.
+// STREAM frames are filled from multiple buffers, so small buffers don't result in small frames.
+// It returns the total number of bytes written.
+// WriteBuffers can be made to time out using [SendStream.SetWriteDeadline].
+// If the stream was canceled, the error is a [StreamError].
+func (s *SendStream) WriteBuffers(bufs [][]byte) (int, error) {
+	if len(bufs) == 0 {
+		return 0, nil
+	}
+
+	s.writeOnce <- struct{}{}
+	defer func() { <-s.writeOnce }()
+
+	isNewlyCompleted, n, err := s.write(bufs[0], bufs[1:], nil)
 	if isNewlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
 	}
 	return n, err
 }
 
-func (s *SendStream) write(p []byte) (bool /* is newly completed */, int, error) {
+// ReadFrom reads data from r until io.EOF or an error occurs, and writes it to the stream.
+// It implements the io.ReaderFrom interface, and is used by io.Copy.
+// The data read from r is sent directly in STREAM frames, without copying it into an intermediate buffer.
+// It returns the number of bytes written to the stream. An io.EOF returned by r is not reported as an error.
+// ReadFrom can be made to time out using [SendStream.SetWriteDeadline].
+// If the stream was canceled, the error is a [StreamError].
+func (s *SendStream) ReadFrom(r io.Reader) (int64, error) {
+	s.writeOnce <- struct{}{}
+	defer func() { <-s.writeOnce }()
+
+	var written int64
+	for {
+		c := getSendChunk()
+		n, rerr := r.Read(c.data)
+		if n == 0 {
+			c.release()
+		} else {
+			// ownership of the chunk is passed to write
+			isNewlyCompleted, m, err := s.write(c.data[:n], nil, c)
+			written += int64(m)
+			if isNewlyCompleted {
+				s.sender.onStreamCompleted(s.streamID)
+			}
+			if err != nil {
+				return written, err
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// write writes p, followed by the buffers in more, to the stream.
+// If p belongs to a chunk, the caller's reference to the chunk is released once write returns.
+func (s *SendStream) write(p []byte, more [][]byte, chunk *sendChunk) (bool /* is newly completed */, int, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	if chunk != nil {
+		defer func() {
+			// If STREAM frames might still be created from dataForWriting (e.g. after a reset with a reliable size),
+			// the chunk is never returned to the pool, and is garbage collected instead.
+			if s.dataForWritingChunk == chunk && s.dataForWriting != nil {
+				return
+			}
+			s.dataForWritingChunk = nil
+			chunk.release()
+		}()
+	}
+
 	if s.resetErr != nil {
 		s.cancellationFlagged = true
 		return s.isNewlyCompleted(), 0, s.resetErr
@@ -121,11 +231,21 @@ func (s *SendStream) write(p []byte) (bool /* is newly completed */, int, error)
 	if !s.deadline.IsZero() && !monotime.Now().Before(s.deadline) {
 		return false, 0, errDeadline
 	}
-	if len(p) == 0 {
+	total := len(p)
+	for _, b := range more {
+		total += len(b)
+	}
+	if total == 0 {
 		return false, 0, nil
 	}
 
 	s.dataForWriting = p
+	s.dataForWritingChunk = chunk
+	s.moreDataForWriting = more
+	s.moreDataForWritingLen = total - len(p)
+	if len(p) == 0 {
+		s.nextDataForWriting()
+	}
 
 	var (
 		deadlineTimer  *time.Timer
@@ -140,29 +260,28 @@ func (s *SendStream) write(p []byte) (bool /* is newly completed */, int, error)
 		// This allows us to return Write() when all data but x bytes have been sent out.
 		// When the user now calls Close(), this is much more likely to happen before we popped that last STREAM frame,
 		// allowing us to set the FIN bit on that frame (instead of sending an empty STREAM frame with FIN).
-		if s.canBufferStreamFrame() && len(s.dataForWriting) > 0 {
+		if s.canBufferStreamFrame() && s.dataForWriting != nil {
 			if s.nextFrame == nil {
 				f := wire.GetStreamFrame()
 				f.Offset = s.writeOffset
 				f.StreamID = s.streamID
 				f.DataLenPresent = true
-				f.Data = f.Data[:len(s.dataForWriting)]
-				copy(f.Data, s.dataForWriting)
+				f.Data = f.Data[:0]
 				s.nextFrame = f
-			} else {
-				l := len(s.nextFrame.Data)
-				s.nextFrame.Data = s.nextFrame.Data[:l+len(s.dataForWriting)]
-				copy(s.nextFrame.Data[l:], s.dataForWriting)
 			}
-			s.dataForWriting = nil
-			bytesWritten = len(p)
+			l := len(s.nextFrame.Data)
+			s.nextFrame.Data = s.nextFrame.Data[:l+s.lenDataForWriting()]
+			s.copyDataForWriting(s.nextFrame.Data[l:])
+			bytesWritten = total
 			copied = true
 		} else {
-			bytesWritten = len(p) - len(s.dataForWriting)
+			bytesWritten = total - s.lenDataForWriting()
 			deadline = s.deadline
 			if !deadline.IsZero() {
 				if !monotime.Now().Before(deadline) {
 					s.dataForWriting = nil
+					s.moreDataForWriting = nil
+					s.moreDataForWritingLen = 0
 					return false, bytesWritten, errDeadline
 				}
 				if deadlineTimer == nil {
@@ -197,7 +316,7 @@ func (s *SendStream) write(p []byte) (bool /* is newly completed */, int, error)
 		s.mutex.Lock()
 	}
 
-	if bytesWritten == len(p) {
+	if bytesWritten == total {
 		return false, bytesWritten, nil
 	}
 	if s.shutdownErr != nil {
@@ -215,7 +334,7 @@ func (s *SendStream) canBufferStreamFrame() bool {
 	if s.nextFrame != nil {
 		l = s.nextFrame.DataLen()
 	}
-	return l+protocol.ByteCount(len(s.dataForWriting)) <= protocol.MaxPacketBufferSize
+	return l+protocol.ByteCount(s.lenDataForWriting()) <= protocol.MaxPacketBufferSize
 }
 
 // popStreamFrame returns the next STREAM frame that is supposed to be sent on this stream
@@ -254,6 +373,7 @@ func (s *SendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 			if f == nil {
 				return nil, nil, true
 			}
+			s.onDataRetransmitted(f)
 			// We always claim that we have more data to send.
 			// This might be incorrect, in which case there'll be a spurious call to popStreamFrame in the future.
 			return f, nil, true
@@ -275,6 +395,7 @@ func (s *SendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 
 	maxDataLen := s.flowController.SendWindowSize()
 	if maxDataLen == 0 {
+		s.flowControlBlocked = true
 		return nil, nil, true
 	}
 
@@ -294,6 +415,7 @@ func (s *SendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 	if s.resetErr != nil && s.writeOffset >= reliableOffset {
 		hasMoreData = false
 	}
+	s.flowControlBlocked = hasMoreData && f.DataLen() == maxDataLen
 	var blocked *wire.StreamDataBlockedFrame
 	// If the entire send window is used, the stream might have become blocked on stream-level flow control.
 	// This is not guaranteed though, because the stream might also have been blocked on connection-level flow control.
@@ -331,7 +453,13 @@ func (s *SendStream) popNewStreamFrame(maxBytes, maxDataLen protocol.ByteCount,
 		return nextFrame, s.nextFrame != nil || s.dataForWriting != nil
 	}
 
-	f := wire.GetStreamFrame()
+	var f *wire.StreamFrame
+	if s.dataForWritingChunk != nil {
+		// the frame references the chunk, see getDataForWriting
+		f = &wire.StreamFrame{}
+	} else {
+		f = wire.GetStreamFrame()
+	}
 	f.Fin = false
 	f.StreamID = s.streamID
 	f.Offset = s.writeOffset
@@ -366,22 +494,67 @@ func (s *SendStream) maybeGetRetransmission(maxBytes protocol.ByteCount, v proto
 	return f, len(s.retransmissionQueue) > 0
 }
 
+func (s *SendStream) onDataRetransmitted(f *wire.StreamFrame) {
+	s.bytesRetransmitted += f.DataLen()
+	s.sender.onStreamDataRetransmitted(f.DataLen())
+}
+
 func (s *SendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.ByteCount) {
-	if protocol.ByteCount(len(s.dataForWriting)) <= maxBytes {
-		f.Data = f.Data[:len(s.dataForWriting)]
-		copy(f.Data, s.dataForWriting)
-		s.dataForWriting = nil
+	n := min(protocol.ByteCount(s.lenDataForWriting()), maxBytes)
+	if s.dataForWritingChunk != nil {
+		f.Data = s.dataForWriting[:n:n]
+		f.SetReleaseFunc(s.dataForWritingChunk.retain())
+		s.dataForWriting = s.dataForWriting[n:]
+		if len(s.dataForWriting) == 0 {
+			s.dataForWriting = nil
+		}
+	} else {
+		f.Data = f.Data[:n]
+		s.copyDataForWriting(f.Data)
+	}
+	if s.dataForWriting == nil {
 		s.signalWrite()
 		return
 	}
-	f.Data = f.Data[:maxBytes]
-	copy(f.Data, s.dataForWriting)
-	s.dataForWriting = s.dataForWriting[maxBytes:]
 	if s.canBufferStreamFrame() {
 		s.signalWrite()
 	}
 }
 
+// lenDataForWriting returns the number of bytes of the current Write() call that still need to be sent out.
+func (s *SendStream) lenDataForWriting() int {
+	return len(s.dataForWriting) + s.moreDataForWritingLen
+}
+
+// copyDataForWriting fills b with the data that still needs to be sent out.
+// It must not be called with more than lenDataForWriting bytes.
+func (s *SendStream) copyDataForWriting(b []byte) {
+	for len(b) > 0 {
+		n := copy(b, s.dataForWriting)
+		b = b[n:]
+		s.dataForWriting = s.dataForWriting[n:]
+		if len(s.dataForWriting) == 0 {
+			s.nextDataForWriting()
+		}
+	}
+}
+
+// nextDataForWriting moves on to the next non-empty buffer passed to WriteBuffers.
+// dataForWriting is set to nil if there are no more buffers.
+func (s *SendStream) nextDataForWriting() {
+	s.dataForWriting = nil
+	for len(s.moreDataForWriting) > 0 {
+		b := s.moreDataForWriting[0]
+		s.moreDataForWriting = s.moreDataForWriting[1:]
+		s.moreDataForWritingLen -= len(b)
+		if len(b) > 0 {
+			s.dataForWriting = b
+			return
+		}
+	}
+	s.moreDataForWriting = nil
+}
+
 func (s *SendStream) isNewlyCompleted() bool {
 	if s.completed {
 		return false
@@ -551,6 +724,7 @@ func (s *SendStream) updateSendWindow(limit protocol.ByteCount) {
 		return
 	}
 	s.mutex.Lock()
+	s.flowControlBlocked = false
 	hasStreamData := s.dataForWriting != nil || s.nextFrame != nil
 	s.mutex.Unlock()
 	if hasStreamData {
@@ -765,3 +939,43 @@ func (s *sendStreamResetStreamHandler) OnLost(f wire.Frame) {
 	s.mutex.Unlock()
 	s.sender.onHasStreamControlFrame(s.streamID, (*SendStream)(s))
 }
+
+// sendChunkSize is the size of the buffers that ReadFrom reads into.
+const sendChunkSize = 32 << 10
+
+// A sendChunk is a buffer used by ReadFrom.
+// It is reference counted, since STREAM frames reference the data until they're acknowledged.
+type sendChunk struct {
+	data []byte
+	refs atomic.Int32
+
+	releaseFunc func() // c.release, saves an allocation for every STREAM frame
+}
+
+var sendChunkPool sync.Pool
+
+func init() {
+	sendChunkPool.New = func() any {
+		c := &sendChunk{data: make([]byte, sendChunkSize)}
+		c.releaseFunc = c.release
+		return c
+	}
+}
+
+func getSendChunk() *sendChunk {
+	c := sendChunkPool.Get().(*sendChunk)
+	c.refs.Store(1)
+	return c
+}
+
+// retain adds a reference to the chunk, and returns the function to release it
+func (c *sendChunk) retain() func() {
+	c.refs.Add(1)
+	return c.releaseFunc
+}
+
+func (c *sendChunk) release() {
+	if c.refs.Add(-1) == 0 {
+		sendChunkPool.Put(c)
+	}
+}
@@ -12,7 +12,9 @@ import (
 	"os"
 	"runtime"
 	"slices"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/nukilabs/quic-go/internal/ackhandler"
@@ -286,6 +288,172 @@ func TestSendStreamCopyData(t *testing.T) {
 	)
 }
 
+func TestSendStreamWriteBuffers(t *testing.T) {
+	const streamID protocol.StreamID = 42
+	mockCtrl := gomock.NewController(t)
+	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+	mockSender := NewMockStreamSender(mockCtrl)
+	str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+
+	mockSender.EXPECT().onHasStreamData(streamID, str)
+	n, err := str.WriteBuffers([][]byte{[]byte("foo"), nil, []byte("bar"), []byte("baz")})
+	require.NoError(t, err)
+	require.Equal(t, 9, n)
+
+	// the buffers are coalesced into a single frame
+	mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+	mockFC.EXPECT().AddBytesSent(protocol.ByteCount(9))
+	frame, _, hasMore := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+	require.False(t, hasMore)
+	require.EqualExportedValues(t,
+		&wire.StreamFrame{StreamID: streamID, Data: []byte("foobarbaz"), DataLenPresent: true},
+		frame.Frame,
+	)
+
+	// WriteBuffers fails once the stream is closed
+	mockSender.EXPECT().onHasStreamData(streamID, str)
+	require.NoError(t, str.Close())
+	n, err = str.WriteBuffers([][]byte{[]byte("foo")})
+	require.EqualError(t, err, "write on closed stream 42")
+	require.Zero(t, n)
+}
+
+func TestSendStreamWriteBuffersFraming(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const streamID protocol.StreamID = 42
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+
+		bufs := make([][]byte, 10)
+		var data []byte
+		for i := range bufs {
+			bufs[i] = make([]byte, 500)
+			rand.Read(bufs[i])
+			data = append(data, bufs[i]...)
+		}
+		mockSender.EXPECT().onHasStreamData(streamID, str)
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := str.WriteBuffers(bufs)
+			errChan <- err
+		}()
+		synctest.Wait()
+
+		// frames are filled from multiple buffers
+		mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+		mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		var received []byte
+		for len(received) < len(data) {
+			frame, _, _ := str.popStreamFrame(1200, protocol.Version1)
+			require.NotNil(t, frame.Frame)
+			require.Equal(t, protocol.ByteCount(len(received)), frame.Frame.Offset)
+			received = append(received, frame.Frame.Data...)
+			if len(received) < len(data) {
+				require.Equal(t, protocol.ByteCount(1200), frame.Frame.Length(protocol.Version1))
+			}
+			synctest.Wait()
+		}
+		require.Equal(t, data, received)
+		require.NoError(t, <-errChan)
+	})
+}
+
+func TestSendStreamReadFrom(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const streamID protocol.StreamID = 1337
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+
+		mockSender.EXPECT().onHasStreamData(streamID, str).AnyTimes()
+		data := make([]byte, 3*sendChunkSize+1234)
+		rand.Read(data)
+		errChan := make(chan error, 1)
+		go func() {
+			n, err := str.ReadFrom(bytes.NewReader(data))
+			if err == nil && n != int64(len(data)) {
+				err = fmt.Errorf("wrote %d bytes, expected %d", n, len(data))
+			}
+			str.Close()
+			errChan <- err
+		}()
+
+		synctest.Wait()
+
+		mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+		mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		var received []byte
+		var frames []ackhandler.StreamFrame
+		for {
+			frame, _, hasMore := str.popStreamFrame(protocol.MaxPacketBufferSize, protocol.Version1)
+			if frame.Frame != nil {
+				require.Equal(t, protocol.ByteCount(len(received)), frame.Frame.Offset)
+				received = append(received, frame.Frame.Data...)
+				frames = append(frames, frame)
+				if frame.Frame.Fin {
+					break
+				}
+			}
+			if !hasMore {
+				synctest.Wait()
+			}
+		}
+		require.Equal(t, data, received)
+		select {
+		case err := <-errChan:
+			require.NoError(t, err)
+		default:
+			t.Fatal("ReadFrom should have returned")
+		}
+
+		// the frames reference the chunks that the data was read into
+		var numReferencing int
+		for _, f := range frames {
+			if f.Frame.DataLen() > 0 && cap(f.Frame.Data) == len(f.Frame.Data) {
+				numReferencing++
+			}
+		}
+		require.Greater(t, numReferencing, len(frames)/2)
+
+		mockSender.EXPECT().onStreamCompleted(streamID)
+		for _, f := range frames {
+			f.Handler.OnAcked(f.Frame)
+		}
+	})
+}
+
+func TestSendStreamReadFromErrors(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const streamID protocol.StreamID = 1337
+		mockCtrl := gomock.NewController(t)
+		mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+		mockSender := NewMockStreamSender(mockCtrl)
+		str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
+
+		// errors returned by the reader are passed through
+		mockSender.EXPECT().onHasStreamData(streamID, str)
+		testErr := errors.New("test error")
+		n, err := str.ReadFrom(io.MultiReader(strings.NewReader("foobar"), iotest.ErrReader(testErr)))
+		require.ErrorIs(t, err, testErr)
+		require.Equal(t, int64(6), n)
+
+		// the deadline is respected
+		mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+		mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		frame, _, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+		require.Equal(t, []byte("foobar"), frame.Frame.Data)
+
+		mockSender.EXPECT().onHasStreamData(streamID, str)
+		require.NoError(t, str.SetWriteDeadline(time.Now().Add(time.Second)))
+		n, err = str.ReadFrom(bytes.NewReader(make([]byte, 10*1000)))
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+		require.Zero(t, n)
+	})
+}
+
 func TestSendStreamDeadlineInThePast(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
@@ -506,15 +674,24 @@ func TestSendStreamFlowControlBlocked(t *testing.T) {
 		frame.Frame,
 	)
 	require.Equal(t, &wire.StreamDataBlockedFrame{StreamID: streamID, MaximumStreamData: 3}, blocked)
+	mockFC.EXPECT().BlockedTime().AnyTimes()
+	require.True(t, str.Stats().FlowControlBlocked)
 
 	frame, blocked, hasMore = str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
 	require.Nil(t, frame.Frame)
 	require.Nil(t, blocked)
 	require.True(t, hasMore)
+	require.True(t, str.Stats().FlowControlBlocked)
 
 	_, ok, hasMore := str.getControlFrame(monotime.Now())
 	require.False(t, ok)
 	require.False(t, hasMore)
+
+	// the stream is unblocked when the peer increases the send window
+	mockFC.EXPECT().UpdateSendWindow(protocol.ByteCount(6)).Return(true)
+	mockSender.EXPECT().onHasStreamData(streamID, str)
+	str.updateSendWindow(6)
+	require.False(t, str.Stats().FlowControlBlocked)
 }
 
 func TestSendStreamCloseForShutdown(t *testing.T) {
@@ -1035,10 +1212,13 @@ func TestSendStreamRetransmissions(t *testing.T) {
 	require.True(t, mockCtrl.Satisfied())
 
 	// when popping a new frame, we first get the retransmission...
+	mockSender.EXPECT().onStreamDataRetransmitted(protocol.ByteCount(3))
 	f2, _, hasMoreData := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
 	require.EqualExportedValues(t, &wire.StreamFrame{StreamID: streamID, Data: []byte("foo"), DataLenPresent: true}, f2.Frame)
 	require.True(t, hasMoreData)
 	require.True(t, mockCtrl.Satisfied())
+	mockFC.EXPECT().BlockedTime().Return(time.Second)
+	require.Equal(t, SendStreamStats{BytesSent: 3, BytesRetransmitted: 3, FlowControlBlockedTime: time.Second}, str.Stats())
 
 	// ... then we get the new data
 	mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
@@ -1060,6 +1240,7 @@ func TestSendStreamRetransmissionFraming(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
 	mockSender := NewMockStreamSender(mockCtrl)
+	mockSender.EXPECT().onStreamDataRetransmitted(gomock.Any()).AnyTimes()
 	str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
 
 	mockSender.EXPECT().onHasStreamData(streamID, str)
@@ -1109,6 +1290,7 @@ func TestSendStreamRetransmitDataUntilAcknowledged(t *testing.T) {
 	const dataLen = 1 << 22 // 4 MB
 	mockCtrl := gomock.NewController(t)
 	mockSender := NewMockStreamSender(mockCtrl)
+	mockSender.EXPECT().onStreamDataRetransmitted(gomock.Any()).AnyTimes()
 	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
 	str := newSendStream(context.Background(), streamID, mockSender, mockFC, false)
 
@@ -1172,6 +1354,7 @@ func TestSendStreamResetStreamAtCancelBeforeSend(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
 	mockSender := NewMockStreamSender(mockCtrl)
+	mockSender.EXPECT().onStreamDataRetransmitted(gomock.Any()).AnyTimes()
 	str := newSendStream(context.Background(), 1337, mockSender, mockFC, true)
 
 	mockSender.EXPECT().onHasStreamData(protocol.StreamID(1337), str).Times(2)
@@ -1228,6 +1411,7 @@ func TestSendStreamResetStreamAtCancelAfterSend(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
 	mockSender := NewMockStreamSender(mockCtrl)
+	mockSender.EXPECT().onStreamDataRetransmitted(gomock.Any()).AnyTimes()
 	str := newSendStream(context.Background(), 1337, mockSender, mockFC, true)
 
 	mockSender.EXPECT().onHasStreamData(protocol.StreamID(1337), str).Times(2)
@@ -1296,6 +1480,7 @@ func TestSendStreamResetStreamAtRetransmissions(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
 	mockSender := NewMockStreamSender(mockCtrl)
+	mockSender.EXPECT().onStreamDataRetransmitted(gomock.Any()).AnyTimes()
 	str := newSendStream(context.Background(), 1337, mockSender, mockFC, true)
 
 	// f1: lorem
@@ -1504,6 +1689,7 @@ func TestSendStreamResetStreamAtRandomized(t *testing.T) {
 
 	mockCtrl := gomock.NewController(t)
 	mockSender := NewMockStreamSender(mockCtrl)
+	mockSender.EXPECT().onStreamDataRetransmitted(gomock.Any()).AnyTimes()
 	mockFC := mocks.NewMockStreamFlowController(mockCtrl)
 	str := newSendStream(context.Background(), streamID, mockSender, mockFC, true)
 
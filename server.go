@@ -7,6 +7,7 @@ import (
 	tls "github.com/nukilabs/utls"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nukilabs/quic-go/internal/handshake"
@@ -42,6 +43,8 @@ type zeroRTTQueue struct {
 type rejectedPacket struct {
 	receivedPacket
 	hdr *wire.Header
+	// reason is sent as the reason phrase of the CONNECTION_CLOSE frame
+	reason string
 }
 
 // A Listener of QUIC
@@ -93,10 +96,12 @@ type baseServer struct {
 	) *wrappedConn
 
 	closeMx sync.Mutex
-	// errorChan is closed when Close is called. This has two effects:
-	// 1. it cancels handshakes that are still in flight (using CONNECTION_REFUSED) errors
-	// 2. it stops handling of packets passed to this server
+	// errorChan is closed when Close is called. It stops handling of packets passed to this server.
 	errorChan chan struct{}
+	// abortHandshakes is closed when Close is called, unless the server is shut down gracefully.
+	// It cancels handshakes that are still in flight (using CONNECTION_REFUSED errors).
+	abortHandshakes     chan struct{}
+	abortHandshakesOnce sync.Once
 	// acceptChan is closed when Close returns.
 	// This only happens once all handshake in flight have either completed and canceled.
 	// Calls to Accept will first drain the queue of connections that have completed the handshake,
@@ -110,8 +115,11 @@ type baseServer struct {
 	connectionRefusedQueue  chan rejectedPacket
 	retryQueue              chan rejectedPacket
 	handshakingCount        sync.WaitGroup
+	// the number of connections that are currently handshaking, used for admission control
+	handshakesInFlight atomic.Int64
 
 	verifySourceAddress func(net.Addr) bool
+	admissionController AdmissionController
 
 	connQueue chan *Conn
 
@@ -246,9 +254,10 @@ func newServer(
 	config *Config,
 	qlogger qlogwriter.Recorder,
 	onClose func(),
-	tokenGeneratorKey TokenGeneratorKey,
+	tokenGenerator *handshake.TokenGenerator,
 	maxTokenAge time.Duration,
 	verifySourceAddress func(net.Addr) bool,
+	admissionController AdmissionController,
 	disableVersionNegotiation bool,
 	acceptEarly bool,
 ) *baseServer {
@@ -258,13 +267,15 @@ func newServer(
 		tr:                        tr,
 		tlsConf:                   tlsConf,
 		config:                    config,
-		tokenGenerator:            handshake.NewTokenGenerator(tokenGeneratorKey),
+		tokenGenerator:            tokenGenerator,
 		maxTokenAge:               maxTokenAge,
 		verifySourceAddress:       verifySourceAddress,
+		admissionController:       admissionController,
 		connIDGenerator:           connIDGenerator,
 		statelessResetter:         statelessResetter,
 		connQueue:                 make(chan *Conn, protocol.MaxAcceptQueueSize),
 		errorChan:                 make(chan struct{}),
+		abortHandshakes:           make(chan struct{}),
 		stopAccepting:             make(chan struct{}),
 		running:                   make(chan struct{}),
 		receivedPackets:           make(chan receivedPacket, protocol.MaxServerUnprocessedPackets),
@@ -352,9 +363,23 @@ func (s *baseServer) Close() error {
 	return nil
 }
 
+// shutdown closes the server, but lets the handshakes in flight complete.
+// Connections that complete the handshake can still be accepted.
+// It returns once all handshakes have completed or were canceled.
+func (s *baseServer) shutdown() {
+	s.closeImpl(ErrServerClosed, false, true)
+}
+
 // close closes the server. The Transport mutex must not be held while calling this method.
 // This method closes any handshaking connections which requires the tranpsort mutex.
 func (s *baseServer) close(e error, transportClose bool) {
+	s.closeImpl(e, transportClose, false)
+}
+
+func (s *baseServer) closeImpl(e error, transportClose, graceful bool) {
+	if !graceful {
+		s.abortHandshakesOnce.Do(func() { close(s.abortHandshakes) })
+	}
 	s.closeMx.Lock()
 	if s.closeErr != nil {
 		s.closeMx.Unlock()
@@ -752,6 +777,12 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 		return nil
 	}
 
+	if s.admissionController != nil {
+		if handled := s.admitNewConn(p, hdr, clientAddrVerified); handled {
+			return nil
+		}
+	}
+
 	// restore RTT from token
 	var rtt time.Duration
 	if token != nil && !token.IsRetryToken {
@@ -854,18 +885,84 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 	}
 
 	s.handshakingCount.Add(1)
+	s.handshakesInFlight.Add(1)
 	go func() {
 		defer s.handshakingCount.Done()
+		defer s.handshakesInFlight.Add(-1)
 		s.handleNewConn(conn)
 	}()
 	go conn.run()
 	return nil
 }
 
+// admitNewConn asks the admission controller if the connection attempt should be accepted.
+// It returns true if the packet was handled, i.e. if no connection should be created.
+func (s *baseServer) admitNewConn(p receivedPacket, hdr *wire.Header, clientAddrVerified bool) (handled bool) {
+	handshakesInFlight := int(s.handshakesInFlight.Load())
+	decision := s.admissionController.Admit(&AdmissionInfo{
+		RemoteAddr:         p.remoteAddr,
+		AddrVerified:       clientAddrVerified,
+		HandshakesInFlight: handshakesInFlight,
+	})
+	var action qlog.AdmissionAction
+	switch decision.Action {
+	case AdmissionAccept:
+		return false
+	case AdmissionRetry:
+		// If the client already sent a valid token, there's no need to send another Retry.
+		if clientAddrVerified {
+			return false
+		}
+		action = qlog.AdmissionActionRetry
+		s.logger.Debugf("Admission control: sending Retry to %s", p.remoteAddr)
+		// Retry invalidates all 0-RTT packets sent.
+		delete(s.zeroRTTQueues, hdr.DestConnectionID)
+		select {
+		case s.retryQueue <- rejectedPacket{receivedPacket: p, hdr: hdr}:
+		default:
+			// drop packet if we can't send out Retry packets fast enough
+			p.buffer.Release()
+		}
+	case AdmissionRefuse:
+		action = qlog.AdmissionActionRefuse
+		s.logger.Debugf("Admission control: refusing connection from %s: %s", p.remoteAddr, decision.Reason)
+		s.refuseNewConnWithReason(p, hdr, decision.Reason)
+	case AdmissionDrop:
+		action = qlog.AdmissionActionDrop
+		s.logger.Debugf("Admission control: dropping Initial packet from %s", p.remoteAddr)
+		delete(s.zeroRTTQueues, hdr.DestConnectionID)
+		p.buffer.Release()
+	default:
+		// The AdmissionAction is returned by the application, and might be invalid.
+		action = qlog.AdmissionActionDrop
+		s.logger.Errorf("Admission control: unknown admission action %d, dropping Initial packet from %s", decision.Action, p.remoteAddr)
+		delete(s.zeroRTTQueues, hdr.DestConnectionID)
+		p.buffer.Release()
+	}
+	if s.qlogger != nil {
+		var remote qlog.PathEndpointInfo
+		if addr, ok := p.remoteAddr.(*net.UDPAddr); ok {
+			remote = toPathEndpointInfo(addr)
+		}
+		s.qlogger.RecordEvent(qlog.ConnectionAdmission{
+			Remote:             remote,
+			DestConnectionID:   hdr.DestConnectionID,
+			Action:             action,
+			Reason:             decision.Reason,
+			HandshakesInFlight: handshakesInFlight,
+		})
+	}
+	return true
+}
+
 func (s *baseServer) refuseNewConn(p receivedPacket, hdr *wire.Header) {
+	s.refuseNewConnWithReason(p, hdr, "")
+}
+
+func (s *baseServer) refuseNewConnWithReason(p receivedPacket, hdr *wire.Header, reason string) {
 	delete(s.zeroRTTQueues, hdr.DestConnectionID)
 	select {
-	case s.connectionRefusedQueue <- rejectedPacket{receivedPacket: p, hdr: hdr}:
+	case s.connectionRefusedQueue <- rejectedPacket{receivedPacket: p, hdr: hdr, reason: reason}:
 	default:
 		// drop packet if we can't send out the CONNECTION_REFUSED fast enough
 		p.buffer.Release()
@@ -876,7 +973,7 @@ func (s *baseServer) handleNewConn(conn *wrappedConn) {
 	if s.acceptEarlyConns {
 		// wait until the early connection is ready, the handshake fails, or the server is closed
 		select {
-		case <-s.errorChan:
+		case <-s.abortHandshakes:
 			conn.closeWithTransportError(ConnectionRefused)
 			return
 		case <-conn.Context().Done():
@@ -886,7 +983,7 @@ func (s *baseServer) handleNewConn(conn *wrappedConn) {
 	} else {
 		// wait until the handshake completes, fails, or the server is closed
 		select {
-		case <-s.errorChan:
+		case <-s.abortHandshakes:
 			conn.closeWithTransportError(ConnectionRefused)
 			return
 		case <-conn.Context().Done():
@@ -1003,7 +1100,7 @@ func (s *baseServer) maybeSendInvalidToken(p rejectedPacket) {
 	if s.logger.Debug() {
 		s.logger.Debugf("Client sent an invalid retry token. Sending INVALID_TOKEN to %s.", p.remoteAddr)
 	}
-	if err := s.sendError(p.remoteAddr, hdr, sealer, InvalidToken, p.info); err != nil {
+	if err := s.sendError(p.remoteAddr, hdr, sealer, InvalidToken, "", p.info); err != nil {
 		s.logger.Debugf("Error sending INVALID_TOKEN error: %s", err)
 	}
 }
@@ -1011,13 +1108,13 @@ func (s *baseServer) maybeSendInvalidToken(p rejectedPacket) {
 func (s *baseServer) sendConnectionRefused(p rejectedPacket) {
 	defer p.buffer.Release()
 	sealer, _ := handshake.NewInitialAEAD(p.hdr.DestConnectionID, protocol.PerspectiveServer, p.hdr.Version)
-	if err := s.sendError(p.remoteAddr, p.hdr, sealer, ConnectionRefused, p.info); err != nil {
+	if err := s.sendError(p.remoteAddr, p.hdr, sealer, ConnectionRefused, p.reason, p.info); err != nil {
 		s.logger.Debugf("Error sending CONNECTION_REFUSED error: %s", err)
 	}
 }
 
 // sendError sends the error as a response to the packet received with header hdr
-func (s *baseServer) sendError(remoteAddr net.Addr, hdr *wire.Header, sealer handshake.LongHeaderSealer, errorCode qerr.TransportErrorCode, info packetInfo) error {
+func (s *baseServer) sendError(remoteAddr net.Addr, hdr *wire.Header, sealer handshake.LongHeaderSealer, errorCode qerr.TransportErrorCode, reason string, info packetInfo) error {
 	b := getPacketBuffer()
 	defer b.Release()
 
@@ -1029,6 +1126,14 @@ func (s *baseServer) sendError(remoteAddr net.Addr, hdr *wire.Header, sealer han
 	replyHdr.SrcConnectionID = hdr.DestConnectionID
 	replyHdr.DestConnectionID = hdr.SrcConnectionID
 	replyHdr.PacketNumberLen = protocol.PacketNumberLen4
+	// The reason might have been provided by the application (see AdmissionDecision.Reason).
+	// Truncate it, such that the packet doesn't exceed the minimum Initial packet size.
+	// The additional byte accounts for the length of the reason phrase, which might need a 2-byte varint.
+	maxReasonLen := protocol.MinInitialPacketSize - int(replyHdr.GetLength(hdr.Version)+ccf.Length(hdr.Version)) - 1 - sealer.Overhead()
+	if len(reason) > maxReasonLen {
+		reason = reason[:maxReasonLen]
+	}
+	ccf.ReasonPhrase = reason
 	replyHdr.Length = 4 /* packet number len */ + ccf.Length(hdr.Version) + protocol.ByteCount(sealer.Overhead())
 	var err error
 	b.Data, err = replyHdr.Append(b.Data, hdr.Version)
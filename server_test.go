@@ -6,7 +6,9 @@ import (
 	"errors"
 	tls "github.com/nukilabs/utls"
 	"net"
+	"os"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -32,6 +34,7 @@ type serverOpts struct {
 	tokenGeneratorKey         TokenGeneratorKey
 	maxTokenAge               time.Duration
 	useRetry                  bool
+	admissionController       AdmissionController
 	disableVersionNegotiation bool
 	acceptEarly               bool
 	newConn                   func(
@@ -75,9 +78,10 @@ func newTestServer(t *testing.T, serverOpts *serverOpts) *testServer {
 		config,
 		serverOpts.eventRecorder,
 		func() {},
-		serverOpts.tokenGeneratorKey,
+		handshake.NewTokenGenerator(serverOpts.tokenGeneratorKey),
 		serverOpts.maxTokenAge,
 		verifySourceAddress,
+		serverOpts.admissionController,
 		serverOpts.disableVersionNegotiation,
 		serverOpts.acceptEarly,
 	)
@@ -884,6 +888,182 @@ func TestServerGetConfigForClientReject(t *testing.T) {
 	checkConnectionClose(t, conn, &eventRecorder, destConnID, srcConnID, qerr.ConnectionRefused)
 }
 
+type admissionControllerFunc func(*AdmissionInfo) AdmissionDecision
+
+func (f admissionControllerFunc) Admit(info *AdmissionInfo) AdmissionDecision { return f(info) }
+
+func TestServerAdmissionControlRefuse(t *testing.T) {
+	var eventRecorder events.Recorder
+	admissionInfoChan := make(chan *AdmissionInfo, 1)
+	server := newTestServer(t, &serverOpts{
+		eventRecorder: &eventRecorder,
+		admissionController: admissionControllerFunc(func(info *AdmissionInfo) AdmissionDecision {
+			admissionInfoChan <- info
+			return AdmissionDecision{Action: AdmissionRefuse, Reason: "go away"}
+		}),
+	})
+
+	conn := newUDPConnLocalhost(t)
+	srcConnID := randConnID(6)
+	destConnID := randConnID(8)
+	server.handlePacket(getValidInitialPacket(t, conn.LocalAddr(), srcConnID, destConnID))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 1500)
+	n, _, err := conn.ReadFromUDP(b)
+	require.NoError(t, err)
+	parsedHdr, _, _, err := wire.ParsePacket(b[:n])
+	require.NoError(t, err)
+	require.Equal(t, protocol.PacketTypeInitial, parsedHdr.Type)
+	require.Equal(t, destConnID, parsedHdr.SrcConnectionID)
+	require.Equal(t, srcConnID, parsedHdr.DestConnectionID)
+
+	var admissionInfo *AdmissionInfo
+	select {
+	case admissionInfo = <-admissionInfoChan:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	require.Equal(t, conn.LocalAddr(), admissionInfo.RemoteAddr)
+	require.False(t, admissionInfo.AddrVerified)
+	require.Zero(t, admissionInfo.HandshakesInFlight)
+
+	require.Equal(t,
+		[]qlogwriter.Event{
+			qlog.ConnectionAdmission{
+				Remote:           toPathEndpointInfo(conn.LocalAddr().(*net.UDPAddr)),
+				DestConnectionID: destConnID,
+				Action:           qlog.AdmissionActionRefuse,
+				Reason:           "go away",
+			},
+		},
+		eventRecorder.Events(qlog.ConnectionAdmission{}),
+	)
+	require.Equal(t,
+		[]qlog.Frame{{Frame: &qlog.ConnectionCloseFrame{ErrorCode: uint64(qerr.ConnectionRefused), ReasonPhrase: "go away"}}},
+		eventRecorder.Events(qlog.PacketSent{})[0].(qlog.PacketSent).Frames,
+	)
+}
+
+func TestServerAdmissionControlRefuseLongReason(t *testing.T) {
+	var eventRecorder events.Recorder
+	reason := strings.Repeat("a", 5000)
+	server := newTestServer(t, &serverOpts{
+		eventRecorder: &eventRecorder,
+		admissionController: admissionControllerFunc(func(*AdmissionInfo) AdmissionDecision {
+			return AdmissionDecision{Action: AdmissionRefuse, Reason: reason}
+		}),
+	})
+
+	conn := newUDPConnLocalhost(t)
+	srcConnID := randConnID(6)
+	destConnID := randConnID(8)
+	server.handlePacket(getValidInitialPacket(t, conn.LocalAddr(), srcConnID, destConnID))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 10000)
+	n, _, err := conn.ReadFromUDP(b)
+	require.NoError(t, err)
+	require.LessOrEqual(t, n, protocol.MinInitialPacketSize)
+	parsedHdr, _, _, err := wire.ParsePacket(b[:n])
+	require.NoError(t, err)
+	require.Equal(t, protocol.PacketTypeInitial, parsedHdr.Type)
+
+	// the reason phrase is truncated
+	frames := eventRecorder.Events(qlog.PacketSent{})[0].(qlog.PacketSent).Frames
+	require.Len(t, frames, 1)
+	ccf := frames[0].Frame.(*qlog.ConnectionCloseFrame)
+	require.Equal(t, uint64(qerr.ConnectionRefused), ccf.ErrorCode)
+	require.NotEmpty(t, ccf.ReasonPhrase)
+	require.True(t, strings.HasPrefix(reason, ccf.ReasonPhrase))
+	require.Less(t, len(ccf.ReasonPhrase), protocol.MinInitialPacketSize)
+}
+
+func TestServerAdmissionControlRetry(t *testing.T) {
+	var eventRecorder events.Recorder
+	recorder := newConnConstructorRecorder(&connTestHooks{})
+	server := newTestServer(t, &serverOpts{
+		eventRecorder: &eventRecorder,
+		newConn:       recorder.NewConn,
+		admissionController: admissionControllerFunc(func(*AdmissionInfo) AdmissionDecision {
+			return AdmissionDecision{Action: AdmissionRetry}
+		}),
+	})
+
+	conn := newUDPConnLocalhost(t)
+	srcConnID := randConnID(6)
+	destConnID := randConnID(8)
+	server.handlePacket(getValidInitialPacket(t, conn.LocalAddr(), srcConnID, destConnID))
+	checkRetry(t, conn, &eventRecorder, srcConnID)
+	require.Len(t, eventRecorder.Events(qlog.ConnectionAdmission{}), 1)
+
+	// clients that already validated their address are accepted
+	token, err := server.tokenGenerator.NewRetryToken(conn.LocalAddr(), destConnID, randConnID(8))
+	require.NoError(t, err)
+	packet := getLongHeaderPacket(t, conn.LocalAddr(),
+		&wire.ExtendedHeader{
+			Header: wire.Header{
+				Type:             protocol.PacketTypeInitial,
+				SrcConnectionID:  srcConnID,
+				DestConnectionID: randConnID(8),
+				Length:           protocol.MinInitialPacketSize,
+				Token:            token,
+				Version:          protocol.Version1,
+			},
+			PacketNumberLen: protocol.PacketNumberLen4,
+		},
+		make([]byte, protocol.MinInitialPacketSize),
+	)
+	server.handlePacket(packet)
+	select {
+	case args := <-recorder.Args():
+		require.NotNil(t, args.retrySrcConnID)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	require.Len(t, eventRecorder.Events(qlog.ConnectionAdmission{}), 1)
+}
+
+func TestServerAdmissionControlDrop(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		testServerAdmissionControlDrop(t, AdmissionDrop)
+	})
+	// unknown actions returned by the AdmissionController are treated like AdmissionDrop
+	t.Run("unknown action", func(t *testing.T) {
+		testServerAdmissionControlDrop(t, AdmissionAction(42))
+	})
+}
+
+func testServerAdmissionControlDrop(t *testing.T, admissionAction AdmissionAction) {
+	var eventRecorder events.Recorder
+	server := newTestServer(t, &serverOpts{
+		eventRecorder: &eventRecorder,
+		admissionController: admissionControllerFunc(func(*AdmissionInfo) AdmissionDecision {
+			return AdmissionDecision{Action: admissionAction}
+		}),
+	})
+
+	conn := newUDPConnLocalhost(t)
+	destConnID := randConnID(8)
+	server.handlePacket(getValidInitialPacket(t, conn.LocalAddr(), randConnID(6), destConnID))
+
+	conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, _, err := conn.ReadFromUDP(make([]byte, 1500))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.Eventually(t, func() bool {
+		return len(eventRecorder.Events(qlog.ConnectionAdmission{})) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t,
+		qlog.ConnectionAdmission{
+			Remote:           toPathEndpointInfo(conn.LocalAddr().(*net.UDPAddr)),
+			DestConnectionID: destConnID,
+			Action:           qlog.AdmissionActionDrop,
+		},
+		eventRecorder.Events(qlog.ConnectionAdmission{})[0],
+	)
+	require.Empty(t, eventRecorder.Events(qlog.PacketSent{}))
+}
+
 func TestServerReceiveQueue(t *testing.T) {
 	var eventRecorder events.Recorder
 	acceptConn := make(chan struct{})
@@ -15,11 +15,14 @@ import (
 	"golang.org/x/crypto/cryptobyte"
 
 	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/qtls"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+const extTypeECH = 0xfe0d
+
 func getClientHelloWithECH(t testing.TB, serverName string) []byte {
 	t.Helper()
 
@@ -167,7 +170,7 @@ func shuffleClientHelloExtensions(t testing.TB, clientHello []byte) []byte {
 func TestFindSNIWithECH(t *testing.T) {
 	const serverName = "public.example"
 	clientHello := shuffleClientHelloExtensions(t, getClientHelloWithECH(t, serverName))
-	sniPos, sniLen, echPos, err := findSNIAndECH(clientHello)
+	sniPos, sniLen, echPos, err := qtls.FindSNIAndECH(clientHello)
 	require.NoError(t, err)
 	require.NotEqual(t, -1, echPos)
 	require.Equal(t, uint16(extTypeECH), binary.BigEndian.Uint16(clientHello[echPos:echPos+2]))
@@ -176,7 +179,7 @@ func TestFindSNIWithECH(t *testing.T) {
 	require.Equal(t, serverName, string(clientHello[sniPos:sniPos+sniLen]))
 
 	for i := range clientHello {
-		_, _, _, err := findSNIAndECH(clientHello[:i])
+		_, _, _, err := qtls.FindSNIAndECH(clientHello[:i])
 		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
 	}
 }
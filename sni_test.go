@@ -8,6 +8,7 @@ import (
 	mrand "math/rand/v2"
 	"testing"
 
+	"github.com/nukilabs/quic-go/internal/qtls"
 	"github.com/nukilabs/quic-go/internal/testdata"
 
 	"github.com/stretchr/testify/assert"
@@ -62,7 +63,7 @@ func TestFindSNI(t *testing.T) {
 
 func testFindSNI(t *testing.T, serverName string) {
 	clientHello := getClientHello(t, serverName)
-	sniPos, sniLen, echPos, err := findSNIAndECH(clientHello)
+	sniPos, sniLen, echPos, err := qtls.FindSNIAndECH(clientHello)
 	require.NoError(t, err)
 	assert.Equal(t, -1, echPos)
 	if serverName == "" {
@@ -75,7 +76,7 @@ func testFindSNI(t *testing.T, serverName string) {
 
 	// incomplete ClientHellos result in an io.ErrUnexpectedEOF
 	for i := range clientHello {
-		_, _, _, err := findSNIAndECH(clientHello[:i])
+		_, _, _, err := qtls.FindSNIAndECH(clientHello[:i])
 		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
 	}
 }
@@ -0,0 +1,28 @@
+package sniproxy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nukilabs/quic-go/internal/qtls"
+)
+
+var errInvalidClientHello = errors.New("sniproxy: invalid ClientHello")
+
+// parseClientHello parses a TLS ClientHello message (including the handshake message header),
+// and extracts the server name and the offered application protocols.
+func parseClientHello(data []byte, info *ClientHelloInfo) error {
+	sniPos, sniLen, _, err := qtls.FindSNIAndECH(data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidClientHello, err)
+	}
+	protos, err := qtls.FindALPN(data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidClientHello, err)
+	}
+	if sniPos != -1 {
+		info.ServerName = string(data[sniPos : sniPos+sniLen])
+	}
+	info.SupportedProtos = protos
+	return nil
+}
@@ -0,0 +1,55 @@
+package sniproxy
+
+import (
+	"context"
+	tls "github.com/nukilabs/utls"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func getClientHello(t *testing.T, serverName string, alpn []string) []byte {
+	t.Helper()
+
+	c := tls.QUICClient(&tls.QUICConfig{
+		TLSConfig: &tls.Config{
+			ServerName:         serverName,
+			NextProtos:         alpn,
+			MinVersion:         tls.VersionTLS13,
+			InsecureSkipVerify: serverName == "",
+		},
+	})
+	c.SetTransportParameters(nil)
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() { c.Close() })
+
+	ev := c.NextEvent()
+	require.Equal(t, tls.QUICWriteData, ev.Kind)
+	return ev.Data
+}
+
+func TestParseClientHello(t *testing.T) {
+	t.Run("with SNI and ALPN", func(t *testing.T) {
+		var info ClientHelloInfo
+		require.NoError(t, parseClientHello(getClientHello(t, "quic-go.net", []string{"h3", "foo"}), &info))
+		require.Equal(t, "quic-go.net", info.ServerName)
+		require.Equal(t, []string{"h3", "foo"}, info.SupportedProtos)
+	})
+
+	t.Run("without SNI and ALPN", func(t *testing.T) {
+		var info ClientHelloInfo
+		require.NoError(t, parseClientHello(getClientHello(t, "", nil), &info))
+		require.Empty(t, info.ServerName)
+		require.Empty(t, info.SupportedProtos)
+	})
+
+	t.Run("invalid ClientHellos", func(t *testing.T) {
+		clientHello := getClientHello(t, "quic-go.net", []string{"h3"})
+		for i := range len(clientHello) {
+			require.ErrorIs(t, parseClientHello(clientHello[:i], &ClientHelloInfo{}), errInvalidClientHello)
+		}
+		b := append([]byte{}, clientHello...)
+		b[0] = 2 // ServerHello
+		require.ErrorIs(t, parseClientHello(b, &ClientHelloInfo{}), errInvalidClientHello)
+	})
+}
@@ -0,0 +1,162 @@
+package sniproxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/nukilabs/quic-go/internal/handshake"
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/wire"
+)
+
+const (
+	// maxClientHelloSize is the maximum size of a ClientHello that is reassembled.
+	// Post-quantum key shares make ClientHellos larger than a single packet,
+	// but they are still far smaller than this limit.
+	maxClientHelloSize = 1 << 16
+	// maxCryptoFrames is the maximum number of CRYPTO frames that are buffered for a single connection.
+	maxCryptoFrames = 64
+)
+
+var errClientHelloTooLarge = errors.New("sniproxy: ClientHello too large")
+
+type cryptoChunk struct {
+	offset protocol.ByteCount
+	data   []byte
+}
+
+// An initialParser decrypts the client's Initial packets,
+// and reassembles the ClientHello from the CRYPTO frames they contain.
+// The CRYPTO frames may be spread over multiple packets, and they may arrive out of order.
+type initialParser struct {
+	version protocol.Version
+	opener  handshake.LongHeaderOpener
+	parser  *wire.FrameParser
+
+	chunks []cryptoChunk
+}
+
+func newInitialParser(connID protocol.ConnectionID, v protocol.Version) *initialParser {
+	_, opener := handshake.NewInitialAEAD(connID, protocol.PerspectiveServer, v)
+	return &initialParser{
+		version: v,
+		opener:  opener,
+		parser:  wire.NewFrameParser(false, false, false),
+	}
+}
+
+// HandlePacket processes an Initial packet.
+// The packet data is not modified.
+// It returns the ClientHello once all CRYPTO frames needed to reassemble it have been received.
+func (p *initialParser) HandlePacket(hdr *wire.Header, packetData []byte) ([]byte, error) {
+	data := slices.Clone(packetData)
+	extHdr, err := p.unpackHeader(hdr, data)
+	if err != nil {
+		return nil, err
+	}
+	hdrLen := extHdr.ParsedLen()
+	pn := p.opener.DecodePacketNumber(extHdr.PacketNumber, extHdr.PacketNumberLen)
+	payload, err := p.opener.Open(data[hdrLen:hdrLen], data[hdrLen:], pn, data[:hdrLen])
+	if err != nil {
+		return nil, err
+	}
+	if err := p.handleFrames(payload); err != nil {
+		return nil, err
+	}
+	return p.clientHello()
+}
+
+func (p *initialParser) unpackHeader(hdr *wire.Header, data []byte) (*wire.ExtendedHeader, error) {
+	hdrLen := hdr.ParsedLen()
+	if protocol.ByteCount(len(data)) < hdrLen+4+16 {
+		return nil, fmt.Errorf("packet too small, expected at least 20 bytes after the header, got %d", protocol.ByteCount(len(data))-hdrLen)
+	}
+	// The packet number can be up to 4 bytes long, but we won't know the length until we decrypt it.
+	origPNBytes := make([]byte, 4)
+	copy(origPNBytes, data[hdrLen:hdrLen+4])
+	p.opener.DecryptHeader(data[hdrLen+4:hdrLen+4+16], &data[0], data[hdrLen:hdrLen+4])
+	extHdr, err := hdr.ParseExtended(data)
+	if err != nil && err != wire.ErrInvalidReservedBits {
+		return nil, err
+	}
+	if extHdr.PacketNumberLen != protocol.PacketNumberLen4 {
+		copy(data[extHdr.ParsedLen():hdrLen+4], origPNBytes[int(extHdr.PacketNumberLen):])
+	}
+	return extHdr, nil
+}
+
+func (p *initialParser) handleFrames(data []byte) error {
+	for len(data) > 0 {
+		frameType, l, err := p.parser.ParseType(data, protocol.EncryptionInitial)
+		if err != nil {
+			if err == io.EOF { // only PADDING frames left
+				return nil
+			}
+			return err
+		}
+		data = data[l:]
+		if frameType.IsAckFrameType() {
+			_, l, err = p.parser.ParseAckFrame(frameType, data, protocol.EncryptionInitial, p.version)
+			if err != nil {
+				return err
+			}
+			data = data[l:]
+			continue
+		}
+		frame, l, err := p.parser.ParseLessCommonFrame(frameType, data, p.version)
+		if err != nil {
+			return err
+		}
+		data = data[l:]
+		if f, ok := frame.(*wire.CryptoFrame); ok {
+			if err := p.addCryptoData(f.Offset, f.Data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *initialParser) addCryptoData(offset protocol.ByteCount, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if offset+protocol.ByteCount(len(data)) > maxClientHelloSize {
+		return errClientHelloTooLarge
+	}
+	if len(p.chunks) >= maxCryptoFrames {
+		return errors.New("sniproxy: too many CRYPTO frames")
+	}
+	p.chunks = append(p.chunks, cryptoChunk{offset: offset, data: slices.Clone(data)})
+	return nil
+}
+
+// clientHello returns the ClientHello, if it has been received completely.
+// It returns nil if data is still missing.
+func (p *initialParser) clientHello() ([]byte, error) {
+	slices.SortFunc(p.chunks, func(a, b cryptoChunk) int { return int(a.offset) - int(b.offset) })
+	var buf []byte
+	for _, c := range p.chunks {
+		if c.offset > protocol.ByteCount(len(buf)) { // gap
+			break
+		}
+		end := c.offset + protocol.ByteCount(len(c.data))
+		if end <= protocol.ByteCount(len(buf)) { // retransmission
+			continue
+		}
+		buf = append(buf, c.data[protocol.ByteCount(len(buf))-c.offset:]...)
+	}
+	if len(buf) < 4 {
+		return nil, nil
+	}
+	msgLen := 4 + (int(buf[1])<<16 | int(buf[2])<<8 | int(buf[3]))
+	if msgLen > maxClientHelloSize {
+		return nil, errClientHelloTooLarge
+	}
+	if len(buf) < msgLen {
+		return nil, nil
+	}
+	return buf[:msgLen], nil
+}
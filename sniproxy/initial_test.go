@@ -0,0 +1,141 @@
+package sniproxy
+
+import (
+	"context"
+	tls "github.com/nukilabs/utls"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/wire"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newUDPConnLocalhost(t testing.TB) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// getInitialPackets dials a server that never responds,
+// and returns the client's Initial packets that carry the ClientHello.
+func getInitialPackets(t *testing.T, v quic.Version, curves []tls.CurveID) [][]byte {
+	t.Helper()
+
+	server := newUDPConnLocalhost(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go quic.Dial(ctx, newUDPConnLocalhost(t), server.LocalAddr(), &tls.Config{
+		ServerName:       "quic-go.net",
+		NextProtos:       []string{"proxied"},
+		CurvePreferences: curves,
+	}, &quic.Config{Versions: []quic.Version{v}})
+
+	var packets [][]byte
+	var parser *initialParser
+	for {
+		server.SetReadDeadline(time.Now().Add(time.Second))
+		b := make([]byte, protocol.MaxPacketBufferSize)
+		n, _, err := server.ReadFrom(b)
+		require.NoError(t, err)
+		hdr, data, _, err := wire.ParsePacket(b[:n])
+		require.NoError(t, err)
+		require.Equal(t, protocol.PacketTypeInitial, hdr.Type)
+		if parser == nil {
+			parser = newInitialParser(hdr.DestConnectionID, hdr.Version)
+		}
+		packets = append(packets, b[:n])
+		clientHello, err := parser.HandlePacket(hdr, data)
+		require.NoError(t, err)
+		if clientHello != nil {
+			return packets
+		}
+	}
+}
+
+func parseInitialPackets(t *testing.T, packets [][]byte) []byte {
+	t.Helper()
+
+	var parser *initialParser
+	for _, p := range packets {
+		hdr, data, _, err := wire.ParsePacket(p)
+		require.NoError(t, err)
+		if parser == nil {
+			parser = newInitialParser(hdr.DestConnectionID, hdr.Version)
+		}
+		orig := slices.Clone(p)
+		clientHello, err := parser.HandlePacket(hdr, data)
+		require.NoError(t, err)
+		require.Equal(t, orig, p, "packet was modified")
+		if clientHello != nil {
+			return clientHello
+		}
+	}
+	t.Fatal("ClientHello not complete")
+	return nil
+}
+
+func TestInitialParser(t *testing.T) {
+	for _, v := range []quic.Version{quic.Version1, quic.Version2} {
+		t.Run(v.String(), func(t *testing.T) {
+			packets := getInitialPackets(t, v, []tls.CurveID{tls.CurveP256})
+			var info ClientHelloInfo
+			require.NoError(t, parseClientHello(parseInitialPackets(t, packets), &info))
+			require.Equal(t, "quic-go.net", info.ServerName)
+			require.Equal(t, []string{"proxied"}, info.SupportedProtos)
+		})
+	}
+}
+
+func TestInitialParserMultiplePackets(t *testing.T) {
+	// post-quantum key shares result in a ClientHello that doesn't fit into a single packet
+	packets := getInitialPackets(t, quic.Version1, []tls.CurveID{tls.X25519MLKEM768})
+	require.GreaterOrEqual(t, len(packets), 2)
+
+	clientHello := parseInitialPackets(t, packets)
+	var info ClientHelloInfo
+	require.NoError(t, parseClientHello(clientHello, &info))
+	require.Equal(t, "quic-go.net", info.ServerName)
+
+	// the packets might be reordered
+	slices.Reverse(packets)
+	require.Equal(t, clientHello, parseInitialPackets(t, packets))
+}
+
+func TestInitialParserReassembly(t *testing.T) {
+	p := &initialParser{}
+	msg := []byte{1, 0, 0, 8, 'f', 'o', 'o', 'b', 'a', 'r', '!', '!', 0xff /* trailing data */}
+
+	require.NoError(t, p.addCryptoData(6, msg[6:10]))
+	ch, err := p.clientHello()
+	require.NoError(t, err)
+	require.Nil(t, ch)
+	require.NoError(t, p.addCryptoData(0, msg[0:3]))
+	ch, err = p.clientHello()
+	require.NoError(t, err)
+	require.Nil(t, ch)
+	// overlapping
+	require.NoError(t, p.addCryptoData(2, msg[2:7]))
+	ch, err = p.clientHello()
+	require.NoError(t, err)
+	require.Nil(t, ch)
+	require.NoError(t, p.addCryptoData(9, msg[9:]))
+	ch, err = p.clientHello()
+	require.NoError(t, err)
+	require.Equal(t, msg[:12], ch)
+}
+
+func TestInitialParserLimits(t *testing.T) {
+	p := &initialParser{}
+	require.ErrorIs(t, p.addCryptoData(maxClientHelloSize-10, make([]byte, 11)), errClientHelloTooLarge)
+
+	require.NoError(t, p.addCryptoData(0, []byte{1, 0xff, 0xff, 0xff}))
+	_, err := p.clientHello()
+	require.ErrorIs(t, err, errClientHelloTooLarge)
+}
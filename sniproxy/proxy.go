@@ -0,0 +1,490 @@
+// Package sniproxy implements a QUIC proxy that routes connections based on the ClientHello,
+// without terminating them.
+//
+// The proxy decrypts the client's Initial packets (which are only protected with keys derived
+// from the connection ID), and reassembles the ClientHello to learn the Server Name Indication (SNI)
+// and the offered ALPN protocols. The application then selects a backend, and the proxy forwards
+// all packets of the connection to this backend, and the responses back to the client.
+// The proxy never has access to the TLS keys, and doesn't need the backends' certificates.
+package sniproxy
+
+import (
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/utils"
+	"github.com/nukilabs/quic-go/internal/wire"
+)
+
+const (
+	// DefaultIdleTimeout is the default duration after which an idle flow is removed.
+	DefaultIdleTimeout = 3 * time.Minute
+
+	// maxPendingFlows is the maximum number of clients for which the ClientHello is being reassembled.
+	maxPendingFlows = 1024
+	// maxBufferedPackets is the maximum number of packets buffered until the backend is selected.
+	maxBufferedPackets = 16
+	// pendingFlowTimeout is the time after which incomplete ClientHellos are discarded.
+	pendingFlowTimeout = protocol.DefaultHandshakeIdleTimeout
+	// maxPathsPerFlow is the maximum number of additional client addresses a flow is forwarded for.
+	maxPathsPerFlow = 3
+)
+
+// ErrProxyClosed is returned by [Proxy.Serve] after a call to [Proxy.Close].
+var ErrProxyClosed = errors.New("sniproxy: proxy closed")
+
+// ClientHelloInfo contains information from the client's ClientHello.
+type ClientHelloInfo struct {
+	// ServerName is the value of the Server Name Indication extension.
+	// It is empty if the client didn't send the extension.
+	// If the client uses Encrypted Client Hello, this is the public name of the outer ClientHello.
+	ServerName string
+	// SupportedProtos are the ALPN protocols offered by the client.
+	SupportedProtos []string
+	// Version is the QUIC version of the connection.
+	Version quic.Version
+	// RemoteAddr is the address of the client.
+	RemoteAddr net.Addr
+}
+
+// A Proxy routes QUIC connections to backends, based on the client's ClientHello.
+//
+// Packets are associated with a connection by the client's address.
+// The proxy also learns the connection IDs that the backend chooses during the handshake,
+// which allows it to keep forwarding packets when the client's address changes due to NAT rebinding.
+// Packets from a new client address are forwarded to the backend from a new socket, such that
+// the backend observes the address change and validates the new path, as it would without the proxy.
+// The packets that the backend sends on this socket are forwarded to the new client address.
+// However, connection IDs issued after the handshake are encrypted and can't be observed by the proxy,
+// and clients usually switch to one of those right after completing the handshake.
+// To support address changes after that, backends need to use connection IDs that encode
+// routing information (for example using the quiclb package), and RoutePacket needs to be set.
+type Proxy struct {
+	// Conn is the socket that the proxy receives the clients' packets on.
+	Conn net.PacketConn
+
+	// Route is called once the ClientHello of a new connection has been received.
+	// It returns the address of the backend that the connection is forwarded to.
+	// If it returns an error, the client's packets are dropped.
+	// Route is called on the proxy's receive loop, and should return quickly.
+	Route func(*ClientHelloInfo) (net.Addr, error)
+
+	// RoutePacket is called for packets received from an unknown address that are not Initial packets.
+	// This happens when a client's address changes, either due to NAT rebinding or due to connection migration.
+	// It returns the address of the backend that the packet is forwarded to.
+	// If it returns an error, or if it is nil, the packet is dropped.
+	// Like Route, it is called on the proxy's receive loop, and should return quickly.
+	RoutePacket func(packet []byte) (net.Addr, error)
+
+	// ListenPacket creates the socket used to forward the packets of a connection to the backend.
+	// Every connection uses its own socket, such that the backend can distinguish clients by their address.
+	// If nil, a UDP socket is created on an unspecified address.
+	ListenPacket func(backend net.Addr) (net.PacketConn, error)
+
+	// IdleTimeout is the duration after which a flow is removed if no packets were sent in either direction.
+	// It should be larger than the idle timeout used by clients and backends.
+	// If zero, DefaultIdleTimeout is used.
+	IdleTimeout time.Duration
+
+	initOnce  sync.Once
+	closeOnce sync.Once
+	closed    chan struct{}
+	logger    utils.Logger
+
+	mutex   sync.Mutex
+	flows   map[string]*flow        // indexed by the client's address
+	pending map[string]*pendingFlow // indexed by the client's address
+	connIDs map[string]*flow        // indexed by the backend's connection IDs
+	// number of connection IDs of every length, used to parse short header packets
+	connIDLens map[int]int
+}
+
+func (p *Proxy) init() {
+	p.initOnce.Do(func() {
+		p.closed = make(chan struct{})
+		p.logger = utils.DefaultLogger.WithPrefix("sniproxy")
+		p.flows = make(map[string]*flow)
+		p.pending = make(map[string]*pendingFlow)
+		p.connIDs = make(map[string]*flow)
+		p.connIDLens = make(map[int]int)
+	})
+}
+
+// Serve receives packets on the Conn and forwards them.
+// It blocks until the proxy is closed, or until reading from the Conn fails.
+// After a call to Close, it returns ErrProxyClosed.
+func (p *Proxy) Serve() error {
+	if p.Route == nil {
+		return errors.New("sniproxy: no Route callback")
+	}
+	p.init()
+	buf := make([]byte, protocol.MaxPacketBufferSize)
+	for {
+		n, addr, err := p.Conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-p.closed:
+				return ErrProxyClosed
+			default:
+			}
+			return err
+		}
+		p.handlePacket(buf[:n], addr)
+	}
+}
+
+// Close closes the proxy and all flows.
+// It doesn't close the Conn.
+func (p *Proxy) Close() error {
+	p.init()
+	p.closeOnce.Do(func() {
+		close(p.closed)
+		// unblock Serve
+		p.Conn.SetReadDeadline(time.Now())
+
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		for _, f := range p.flows {
+			f.conn.Close()
+		}
+		clear(p.pending)
+	})
+	return nil
+}
+
+func (p *Proxy) idleTimeout() time.Duration {
+	if p.IdleTimeout == 0 {
+		return DefaultIdleTimeout
+	}
+	return p.IdleTimeout
+}
+
+func (p *Proxy) handlePacket(data []byte, addr net.Addr) {
+	if len(data) == 0 {
+		return
+	}
+	key := addr.String()
+
+	p.mutex.Lock()
+	if f, ok := p.flows[key]; ok {
+		p.mutex.Unlock()
+		f.forward(data)
+		return
+	}
+	origin := p.flowForConnID(data)
+	if origin != nil {
+		if origin.origin != nil {
+			origin = origin.origin
+		}
+		if origin.paths >= maxPathsPerFlow {
+			p.mutex.Unlock()
+			p.logger.Debugf("too many paths for connection to %s, dropping packet from %s", origin.backendAddr, addr)
+			return
+		}
+		origin.paths++
+	}
+	p.mutex.Unlock()
+
+	if origin != nil {
+		p.handleNewPath(data, addr, key, origin)
+		return
+	}
+	p.handleUnknownPacket(data, addr, key)
+}
+
+// handleNewPath handles a packet that belongs to an existing flow, but was received from a new address,
+// e.g. due to NAT rebinding or due to connection migration.
+// The packet is forwarded to the backend from a new socket. This way, the backend observes
+// the address change, and only switches to the new path after it has validated it.
+func (p *Proxy) handleNewPath(data []byte, addr net.Addr, key string, origin *flow) {
+	f, err := p.newFlow(addr, key, origin.backendAddr, origin)
+	if err != nil {
+		p.mutex.Lock()
+		origin.paths--
+		p.mutex.Unlock()
+		p.logger.Errorf("failed to create flow to %s: %s", origin.backendAddr, err)
+		return
+	}
+	if p.logger.Debug() {
+		p.logger.Debugf("new path for connection from %s: forwarding packets from %s to %s", origin.clientAddr, addr, origin.backendAddr)
+	}
+	f.forward(data)
+}
+
+// flowForConnID returns the flow that the Destination Connection ID of the packet belongs to.
+// It must be called with the mutex held.
+func (p *Proxy) flowForConnID(data []byte) *flow {
+	if wire.IsLongHeaderPacket(data[0]) {
+		connID, err := wire.ParseConnectionID(data, 0)
+		if err != nil {
+			return nil
+		}
+		return p.connIDs[string(connID.Bytes())]
+	}
+	for l := range p.connIDLens {
+		if len(data) < 1+l {
+			continue
+		}
+		if f, ok := p.connIDs[string(data[1:1+l])]; ok {
+			return f
+		}
+	}
+	return nil
+}
+
+func (p *Proxy) handleUnknownPacket(data []byte, addr net.Addr, key string) {
+	if !wire.IsLongHeaderPacket(data[0]) {
+		p.routePacket(data, addr, key)
+		return
+	}
+	hdr, packetData, _, err := wire.ParsePacket(data)
+	if err != nil {
+		return
+	}
+
+	p.mutex.Lock()
+	pf, ok := p.pending[key]
+	if !ok {
+		if hdr.Type != protocol.PacketTypeInitial {
+			p.mutex.Unlock()
+			p.routePacket(data, addr, key)
+			return
+		}
+		if !protocol.IsSupportedVersion(protocol.SupportedVersions, hdr.Version) ||
+			len(data) < protocol.MinInitialPacketSize {
+			p.mutex.Unlock()
+			return
+		}
+		p.removeExpiredPendingFlows()
+		if len(p.pending) >= maxPendingFlows {
+			p.mutex.Unlock()
+			p.logger.Debugf("too many pending flows, dropping Initial packet from %s", addr)
+			return
+		}
+		pf = &pendingFlow{
+			connID:  hdr.DestConnectionID,
+			parser:  newInitialParser(hdr.DestConnectionID, hdr.Version),
+			created: time.Now(),
+		}
+		p.pending[key] = pf
+	}
+	if len(pf.packets) >= maxBufferedPackets {
+		p.mutex.Unlock()
+		return
+	}
+	pf.packets = append(pf.packets, slices.Clone(data))
+	if hdr.Type != protocol.PacketTypeInitial || hdr.Version != pf.parser.version || hdr.DestConnectionID != pf.connID {
+		p.mutex.Unlock()
+		return
+	}
+	clientHello, err := pf.parser.HandlePacket(hdr, packetData)
+	if err != nil {
+		delete(p.pending, key)
+		p.mutex.Unlock()
+		p.logger.Debugf("failed to parse Initial packet from %s: %s", addr, err)
+		return
+	}
+	if clientHello == nil { // more CRYPTO data needed
+		p.mutex.Unlock()
+		return
+	}
+	delete(p.pending, key)
+	p.mutex.Unlock()
+
+	info := &ClientHelloInfo{Version: hdr.Version, RemoteAddr: addr}
+	if err := parseClientHello(clientHello, info); err != nil {
+		p.logger.Debugf("failed to parse ClientHello from %s: %s", addr, err)
+		return
+	}
+	backend, err := p.Route(info)
+	if err != nil {
+		p.logger.Debugf("not routing connection from %s (SNI: %q): %s", addr, info.ServerName, err)
+		return
+	}
+	f, err := p.newFlow(addr, key, backend, nil)
+	if err != nil {
+		p.logger.Errorf("failed to create flow to %s: %s", backend, err)
+		return
+	}
+	if p.logger.Debug() {
+		p.logger.Debugf("routing connection from %s (SNI: %q, ALPN: %v) to %s", addr, info.ServerName, info.SupportedProtos, backend)
+	}
+	for _, b := range pf.packets {
+		f.forward(b)
+	}
+}
+
+// routePacket routes a packet of an existing connection that was received from a new address.
+func (p *Proxy) routePacket(data []byte, addr net.Addr, key string) {
+	if p.RoutePacket == nil {
+		return
+	}
+	backend, err := p.RoutePacket(data)
+	if err != nil {
+		p.logger.Debugf("not routing packet from %s: %s", addr, err)
+		return
+	}
+	f, err := p.newFlow(addr, key, backend, nil)
+	if err != nil {
+		p.logger.Errorf("failed to create flow to %s: %s", backend, err)
+		return
+	}
+	if p.logger.Debug() {
+		p.logger.Debugf("routing packets from %s to %s", addr, backend)
+	}
+	f.forward(data)
+}
+
+// removeExpiredPendingFlows must be called with the mutex held.
+func (p *Proxy) removeExpiredPendingFlows() {
+	for key, pf := range p.pending {
+		if time.Since(pf.created) > pendingFlowTimeout {
+			delete(p.pending, key)
+		}
+	}
+}
+
+// newFlow creates a new flow.
+// If the flow is an additional path of an existing flow, origin is the flow that was created for the connection.
+func (p *Proxy) newFlow(clientAddr net.Addr, key string, backend net.Addr, origin *flow) (*flow, error) {
+	var conn net.PacketConn
+	var err error
+	if p.ListenPacket != nil {
+		conn, err = p.ListenPacket(backend)
+	} else {
+		conn, err = net.ListenUDP("udp", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f := &flow{
+		proxy:       p,
+		conn:        conn,
+		backendAddr: backend,
+		clientAddr:  clientAddr,
+		key:         key,
+		origin:      origin,
+	}
+	f.lastActivity.Store(time.Now().UnixNano())
+
+	p.mutex.Lock()
+	select {
+	case <-p.closed:
+		p.mutex.Unlock()
+		conn.Close()
+		return nil, ErrProxyClosed
+	default:
+	}
+	p.flows[key] = f
+	p.mutex.Unlock()
+
+	go f.run()
+	return f, nil
+}
+
+// addConnID must be called with the mutex held.
+func (p *Proxy) addConnID(f *flow, connID []byte) {
+	if _, ok := p.connIDs[string(connID)]; ok {
+		return
+	}
+	p.connIDs[string(connID)] = f
+	p.connIDLens[len(connID)]++
+	f.connIDs = append(f.connIDs, string(connID))
+}
+
+func (p *Proxy) removeFlow(f *flow) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.flows[f.key] == f {
+		delete(p.flows, f.key)
+	}
+	if f.origin != nil {
+		f.origin.paths--
+	}
+	for _, connID := range f.connIDs {
+		delete(p.connIDs, connID)
+		p.connIDLens[len(connID)]--
+		if p.connIDLens[len(connID)] == 0 {
+			delete(p.connIDLens, len(connID))
+		}
+	}
+}
+
+type pendingFlow struct {
+	connID  protocol.ConnectionID
+	parser  *initialParser
+	packets [][]byte
+	created time.Time
+}
+
+// A flow forwards the packets of a single connection between a client and a backend.
+type flow struct {
+	proxy       *Proxy
+	conn        net.PacketConn
+	backendAddr net.Addr
+	clientAddr  net.Addr
+	key         string // the key of the client's address in the proxy's flows map
+	// Set if this flow was created for a new address of an existing connection.
+	origin *flow
+
+	// The following fields are protected by the proxy's mutex.
+	connIDs []string // the connection IDs chosen by the backend
+	paths   int      // the number of flows created for new addresses of this connection
+
+	lastActivity atomic.Int64 // unix nanoseconds
+}
+
+// forward forwards a packet from the client to the backend.
+func (f *flow) forward(data []byte) {
+	f.lastActivity.Store(time.Now().UnixNano())
+	if _, err := f.conn.WriteTo(data, f.backendAddr); err != nil {
+		f.proxy.logger.Debugf("failed to forward packet to %s: %s", f.backendAddr, err)
+	}
+}
+
+// run forwards packets from the backend to the client, until the flow becomes idle.
+func (f *flow) run() {
+	defer f.proxy.removeFlow(f)
+	defer f.conn.Close()
+
+	idleTimeout := f.proxy.idleTimeout()
+	buf := make([]byte, protocol.MaxPacketBufferSize)
+	for {
+		lastActivity := time.Unix(0, f.lastActivity.Load())
+		f.conn.SetReadDeadline(lastActivity.Add(idleTimeout))
+		n, _, err := f.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) && time.Since(time.Unix(0, f.lastActivity.Load())) < idleTimeout {
+				continue
+			}
+			return
+		}
+		f.lastActivity.Store(time.Now().UnixNano())
+		data := buf[:n]
+		if len(data) > 0 && wire.IsLongHeaderPacket(data[0]) {
+			f.learnConnID(data)
+		}
+		if _, err := f.proxy.Conn.WriteTo(data, f.clientAddr); err != nil {
+			f.proxy.logger.Debugf("failed to forward packet to %s: %s", f.clientAddr, err)
+		}
+	}
+}
+
+// learnConnID learns the connection ID chosen by the backend from a long header packet.
+func (f *flow) learnConnID(data []byte) {
+	_, _, src, err := wire.ParseArbitraryLenConnectionIDs(data)
+	if err != nil || len(src) == 0 || len(src) > protocol.MaxConnIDLen {
+		return
+	}
+	f.proxy.mutex.Lock()
+	defer f.proxy.mutex.Unlock()
+	f.proxy.addConnID(f, src)
+}
@@ -0,0 +1,281 @@
+package sniproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	tls "github.com/nukilabs/utls"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go"
+	"github.com/nukilabs/quic-go/internal/testdata"
+	"github.com/nukilabs/quic-go/quiclb"
+	"github.com/nukilabs/quic-go/quicproxy"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newBackend(t *testing.T, alpn string) *quic.Listener {
+	t.Helper()
+	return newBackendWithConnIDGenerator(t, alpn, nil)
+}
+
+func newBackendWithConnIDGenerator(t *testing.T, alpn string, connIDGenerator quic.ConnectionIDGenerator) *quic.Listener {
+	t.Helper()
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{alpn}
+	tr := &quic.Transport{
+		Conn:                  newUDPConnLocalhost(t),
+		ConnectionIDGenerator: connIDGenerator,
+	}
+	t.Cleanup(func() { tr.Close() })
+	ln, err := tr.Listen(tlsConf, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				for {
+					str, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					go func() {
+						defer str.Close()
+						// echo the data, prefixed with the backend's ALPN
+						str.Write([]byte(alpn + ":"))
+						io.Copy(str, str)
+					}()
+				}
+			}()
+		}
+	}()
+	return ln
+}
+
+func startProxy(t *testing.T, p *Proxy) *Proxy {
+	t.Helper()
+	errChan := make(chan error, 1)
+	go func() { errChan <- p.Serve() }()
+	t.Cleanup(func() {
+		p.Close()
+		select {
+		case err := <-errChan:
+			require.ErrorIs(t, err, ErrProxyClosed)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	})
+	return p
+}
+
+func dialAndEcho(t *testing.T, conn net.PacketConn, addr net.Addr, alpn, msg string) (*quic.Conn, string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	c, err := quic.Dial(ctx, conn, addr, &tls.Config{
+		ServerName: "localhost",
+		RootCAs:    testdata.GetRootCA(),
+		NextProtos: []string{alpn},
+	}, &quic.Config{MaxIdleTimeout: 10 * time.Second})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.CloseWithError(0, "") })
+	str, err := c.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte(msg))
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	data, err := io.ReadAll(str)
+	require.NoError(t, err)
+	return c, string(data)
+}
+
+func TestProxyRouting(t *testing.T) {
+	backendA := newBackend(t, "a")
+	backendB := newBackend(t, "b")
+
+	infos := make(chan *ClientHelloInfo, 2)
+	p := startProxy(t, &Proxy{
+		Conn: newUDPConnLocalhost(t),
+		Route: func(info *ClientHelloInfo) (net.Addr, error) {
+			infos <- info
+			switch info.SupportedProtos[0] {
+			case "a":
+				return backendA.Addr(), nil
+			case "b":
+				return backendB.Addr(), nil
+			}
+			return nil, errors.New("unknown ALPN")
+		},
+	})
+
+	clientConn := newUDPConnLocalhost(t)
+	c, data := dialAndEcho(t, clientConn, p.Conn.LocalAddr(), "b", "foobar")
+	require.Equal(t, "b:foobar", data)
+	require.Equal(t, "b", c.ConnectionState().TLS.NegotiatedProtocol)
+	info := <-infos
+	require.Equal(t, "localhost", info.ServerName)
+	require.Equal(t, []string{"b"}, info.SupportedProtos)
+	require.Equal(t, quic.Version1, info.Version)
+	require.Equal(t, clientConn.LocalAddr().String(), info.RemoteAddr.String())
+
+	_, data = dialAndEcho(t, newUDPConnLocalhost(t), p.Conn.LocalAddr(), "a", "raboof")
+	require.Equal(t, "a:raboof", data)
+	require.Len(t, infos, 1)
+}
+
+func TestProxyRejectedConnection(t *testing.T) {
+	var counter atomic.Int32
+	p := startProxy(t, &Proxy{
+		Conn: newUDPConnLocalhost(t),
+		Route: func(info *ClientHelloInfo) (net.Addr, error) {
+			counter.Add(1)
+			return nil, errors.New("rejected")
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	_, err := quic.Dial(ctx, newUDPConnLocalhost(t), p.Conn.LocalAddr(), &tls.Config{
+		ServerName: "localhost",
+		RootCAs:    testdata.GetRootCA(),
+		NextProtos: []string{"a"},
+	}, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotZero(t, counter.Load())
+}
+
+func TestProxyNATRebinding(t *testing.T) {
+	lbConfig := &quiclb.Config{ID: 1, ServerIDLen: 2, NonceLen: 6, Key: make([]byte, 16)}
+	connIDGenerator, err := quiclb.NewGenerator(lbConfig, []byte{0x13, 0x37})
+	require.NoError(t, err)
+	decoder, err := quiclb.NewDecoder(lbConfig)
+	require.NoError(t, err)
+
+	backend := newBackendWithConnIDGenerator(t, "a", connIDGenerator)
+	var routedPackets atomic.Int32
+	p := startProxy(t, &Proxy{
+		Conn:  newUDPConnLocalhost(t),
+		Route: func(*ClientHelloInfo) (net.Addr, error) { return backend.Addr(), nil },
+		RoutePacket: func(b []byte) (net.Addr, error) {
+			serverID, err := decoder.ServerIDFromPacket(b)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(serverID, []byte{0x13, 0x37}) {
+				return nil, errors.New("unknown server")
+			}
+			routedPackets.Add(1)
+			return backend.Addr(), nil
+		},
+	})
+
+	// The NAT sits between the client and the SNI proxy, and changes the client's address.
+	nat := &quicproxy.Proxy{
+		Conn:       newUDPConnLocalhost(t),
+		ServerAddr: p.Conn.LocalAddr().(*net.UDPAddr),
+	}
+	require.NoError(t, nat.Start())
+	t.Cleanup(func() { nat.Close() })
+
+	clientConn := newUDPConnLocalhost(t)
+	c, data := dialAndEcho(t, clientConn, nat.LocalAddr(), "a", "foo")
+	require.Equal(t, "a:foo", data)
+
+	require.NoError(t, nat.SwitchConn(clientConn.LocalAddr().(*net.UDPAddr), newUDPConnLocalhost(t)))
+
+	str, err := c.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("bar"))
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	str.SetReadDeadline(time.Now().Add(2 * time.Second))
+	b, err := io.ReadAll(str)
+	require.NoError(t, err)
+	require.Equal(t, "a:bar", string(b))
+	require.NotZero(t, routedPackets.Load())
+}
+
+func TestProxySpoofedConnectionID(t *testing.T) {
+	backend := newBackend(t, "a")
+	p := startProxy(t, &Proxy{
+		Conn:  newUDPConnLocalhost(t),
+		Route: func(*ClientHelloInfo) (net.Addr, error) { return backend.Addr(), nil },
+	})
+
+	c, data := dialAndEcho(t, newUDPConnLocalhost(t), p.Conn.LocalAddr(), "a", "foo")
+	require.Equal(t, "a:foo", data)
+
+	p.mutex.Lock()
+	require.Len(t, p.connIDs, 1)
+	var connID []byte
+	for id := range p.connIDs {
+		connID = []byte(id)
+	}
+	p.mutex.Unlock()
+
+	// An attacker sends packets using the connection ID of the connection from different addresses.
+	// These packets are forwarded to the backend from new sockets, and the backend drops them.
+	attackers := make([]*net.UDPConn, maxPathsPerFlow+2)
+	for i := range attackers {
+		attackers[i] = newUDPConnLocalhost(t)
+		packet := append([]byte{0x40}, connID...)
+		packet = append(packet, make([]byte, 100)...)
+		_, err := attackers[i].WriteTo(packet, p.Conn.LocalAddr())
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		return len(p.flows) == 1+maxPathsPerFlow
+	}, time.Second, 10*time.Millisecond)
+
+	// the connection is not redirected to the attacker's addresses
+	str, err := c.OpenStream()
+	require.NoError(t, err)
+	_, err = str.Write([]byte("bar"))
+	require.NoError(t, err)
+	require.NoError(t, str.Close())
+	str.SetReadDeadline(time.Now().Add(2 * time.Second))
+	b, err := io.ReadAll(str)
+	require.NoError(t, err)
+	require.Equal(t, "a:bar", string(b))
+
+	for _, attacker := range attackers {
+		attacker.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		_, _, err := attacker.ReadFrom(make([]byte, 1500))
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	require.Len(t, p.flows, 1+maxPathsPerFlow)
+}
+
+func TestProxyIdleTimeout(t *testing.T) {
+	backend := newBackend(t, "a")
+	p := &Proxy{
+		Conn:        newUDPConnLocalhost(t),
+		Route:       func(*ClientHelloInfo) (net.Addr, error) { return backend.Addr(), nil },
+		IdleTimeout: 100 * time.Millisecond,
+	}
+	go p.Serve()
+	t.Cleanup(func() { p.Close() })
+
+	c, _ := dialAndEcho(t, newUDPConnLocalhost(t), p.Conn.LocalAddr(), "a", "foo")
+	c.CloseWithError(0, "")
+
+	require.Eventually(t, func() bool {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		return len(p.flows) == 0 && len(p.connIDs) == 0 && len(p.connIDLens) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -6,37 +6,150 @@ import (
 	"crypto/sha256"
 	"hash"
 	"sync"
+	"time"
 
 	"github.com/nukilabs/quic-go/internal/protocol"
 )
 
+// defaultStatelessResetKeyGracePeriod is the duration for which stateless resets are also sent using
+// a stateless reset key that was replaced by a key obtained from the KeySource.
+const defaultStatelessResetKeyGracePeriod = 10 * time.Minute
+
+type expiringResetHash struct {
+	h          hash.Hash
+	generation uint64
+	expiry     time.Time
+}
+
 type statelessResetter struct {
 	mx sync.Mutex
 	h  hash.Hash
+	// Set if the application configured a key.
+	// If the key was randomly generated, sending stateless resets is pointless.
+	hasKey bool
+	key    StatelessResetKey
+	// generation is incremented every time the key is replaced
+	generation uint64
+	// The keys that were replaced by setKey.
+	// Until they expire, stateless resets are also sent using these keys,
+	// since the peer might still use a connection ID that was issued before the key was rotated.
+	previous []expiringResetHash
+	// The key generation that was used to issue connection IDs.
+	// It is only recorded while there are previous keys that haven't expired yet.
+	// Connection IDs that were issued before that used the oldest of the previous keys.
+	issued map[protocol.ConnectionID]uint64
 }
 
 // newStatelessRetter creates a new stateless reset generator.
 // It is valid to use a nil key. In that case, a random key will be used.
 // This makes is impossible for on-path attackers to shut down established connections.
 func newStatelessResetter(key *StatelessResetKey) *statelessResetter {
-	var h hash.Hash
+	r := &statelessResetter{}
 	if key != nil {
-		h = hmac.New(sha256.New, key[:])
+		r.hasKey = true
+		r.key = *key
 	} else {
-		b := make([]byte, 32)
-		_, _ = rand.Read(b)
-		h = hmac.New(sha256.New, b)
+		_, _ = rand.Read(r.key[:])
+	}
+	r.h = hmac.New(sha256.New, r.key[:])
+	return r
+}
+
+// setKey replaces the key used to generate stateless reset tokens.
+// For the duration of the grace period, stateless resets are also sent using the previous key.
+// All replaced keys are kept until their grace period expires.
+func (r *statelessResetter) setKey(key StatelessResetKey, gracePeriod time.Duration) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.hasKey && key == r.key {
+		return
+	}
+	r.removeExpiredKeys(time.Now())
+	if gracePeriod > 0 {
+		r.previous = append(r.previous, expiringResetHash{h: r.h, generation: r.generation, expiry: time.Now().Add(gracePeriod)})
+		if r.issued == nil {
+			r.issued = make(map[protocol.ConnectionID]uint64)
+		}
+	}
+	r.generation++
+	r.hasKey = true
+	r.key = key
+	r.h = hmac.New(sha256.New, key[:])
+}
+
+// removeExpiredKeys must be called with the mutex held.
+func (r *statelessResetter) removeExpiredKeys(now time.Time) {
+	var removed bool
+	for len(r.previous) > 0 && !now.Before(r.previous[0].expiry) {
+		r.previous = r.previous[1:]
+		removed = true
+	}
+	if !removed {
+		return
+	}
+	if len(r.previous) == 0 {
+		r.previous = nil
+		r.issued = nil
+		return
+	}
+	for connID, gen := range r.issued {
+		if gen < r.previous[0].generation {
+			delete(r.issued, connID)
+		}
 	}
-	return &statelessResetter{h: h}
 }
 
+// CanSendStatelessResets says if stateless resets should be sent.
+// This is only the case if a key was configured.
+func (r *statelessResetter) CanSendStatelessResets() bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.hasKey
+}
+
+// GetStatelessResetToken returns the stateless reset token for a newly issued connection ID.
 func (r *statelessResetter) GetStatelessResetToken(connID protocol.ConnectionID) protocol.StatelessResetToken {
 	r.mx.Lock()
 	defer r.mx.Unlock()
 
+	r.removeExpiredKeys(time.Now())
+	if r.issued != nil {
+		r.issued[connID] = r.generation
+	}
+	return statelessResetToken(r.h, connID)
+}
+
+// StatelessResetTokenToSend returns the token used for a stateless reset
+// sent in response to a packet with the given connection ID.
+// Only a single stateless reset is sent per packet received (see section 10.3.3 of RFC 9000).
+// While previous keys are within their grace period, the token is derived from the key
+// that was used when the connection ID was issued.
+func (r *statelessResetter) StatelessResetTokenToSend(connID protocol.ConnectionID) protocol.StatelessResetToken {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.removeExpiredKeys(time.Now())
+	if len(r.previous) == 0 {
+		return statelessResetToken(r.h, connID)
+	}
+	gen, ok := r.issued[connID]
+	if !ok {
+		// The connection ID was issued before the oldest key that is still valid was replaced.
+		return statelessResetToken(r.previous[0].h, connID)
+	}
+	for _, prev := range r.previous {
+		if prev.generation == gen {
+			return statelessResetToken(prev.h, connID)
+		}
+	}
+	return statelessResetToken(r.h, connID)
+}
+
+func statelessResetToken(h hash.Hash, connID protocol.ConnectionID) protocol.StatelessResetToken {
 	var token protocol.StatelessResetToken
-	r.h.Write(connID.Bytes())
-	copy(token[:], r.h.Sum(nil))
-	r.h.Reset()
+	h.Write(connID.Bytes())
+	copy(token[:], h.Sum(nil))
+	h.Reset()
 	return token
 }
@@ -3,8 +3,11 @@ package quic
 import (
 	"crypto/rand"
 	"testing"
+	"time"
 
 	"github.com/nukilabs/quic-go/internal/protocol"
+	"github.com/nukilabs/quic-go/internal/synctest"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -39,4 +42,54 @@ func TestStatelessResetter(t *testing.T) {
 		connID2 := protocol.ParseConnectionID(b)
 		require.NotEqual(t, token, m.GetStatelessResetToken(connID2))
 	})
+	t.Run("key rotation", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			key1 := StatelessResetKey{1, 2, 3}
+			key2 := StatelessResetKey{4, 5, 6}
+			key3 := StatelessResetKey{7, 8, 9}
+			connID1 := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+			connID2 := protocol.ParseConnectionID([]byte{5, 6, 7, 8})
+			connID3 := protocol.ParseConnectionID([]byte{9, 10, 11, 12})
+			token := func(key StatelessResetKey, connID protocol.ConnectionID) protocol.StatelessResetToken {
+				return newStatelessResetter(&key).GetStatelessResetToken(connID)
+			}
+
+			m := newStatelessResetter(&key1)
+			require.Equal(t, token(key1, connID1), m.GetStatelessResetToken(connID1))
+			m.setKey(key2, time.Minute)
+			require.Equal(t, token(key2, connID2), m.GetStatelessResetToken(connID2))
+			// the token is derived from the key that was used when the connection ID was issued
+			for range 3 {
+				require.Equal(t, token(key1, connID1), m.StatelessResetTokenToSend(connID1))
+				require.Equal(t, token(key2, connID2), m.StatelessResetTokenToSend(connID2))
+			}
+
+			time.Sleep(30 * time.Second)
+			// all previous keys are kept until they expire
+			m.setKey(key3, time.Minute)
+			require.Equal(t, token(key3, connID3), m.GetStatelessResetToken(connID3))
+			require.Equal(t, token(key1, connID1), m.StatelessResetTokenToSend(connID1))
+			require.Equal(t, token(key2, connID2), m.StatelessResetTokenToSend(connID2))
+			require.Equal(t, token(key3, connID3), m.StatelessResetTokenToSend(connID3))
+
+			// key1 expires
+			time.Sleep(30 * time.Second)
+			require.Equal(t, token(key2, connID2), m.StatelessResetTokenToSend(connID2))
+			require.Equal(t, token(key3, connID3), m.StatelessResetTokenToSend(connID3))
+
+			// key2 expires
+			time.Sleep(30 * time.Second)
+			require.Equal(t, token(key3, connID1), m.StatelessResetTokenToSend(connID1))
+			require.Equal(t, token(key3, connID2), m.StatelessResetTokenToSend(connID2))
+			require.Equal(t, token(key3, connID3), m.StatelessResetTokenToSend(connID3))
+			require.Nil(t, m.issued)
+		})
+	})
+
+	t.Run("enabling stateless resets", func(t *testing.T) {
+		m := newStatelessResetter(nil)
+		require.False(t, m.CanSendStatelessResets())
+		m.setKey(StatelessResetKey{1, 2, 3}, time.Minute)
+		require.True(t, m.CanSendStatelessResets())
+	})
 }
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"context"
+	"io"
 	"net"
 	"os"
 	"sync"
@@ -28,6 +29,7 @@ type streamSender interface {
 	onHasConnectionData()
 	onHasStreamData(protocol.StreamID, *SendStream)
 	onHasStreamControlFrame(protocol.StreamID, streamControlFrameGetter)
+	onStreamDataRetransmitted(protocol.ByteCount)
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
 }
@@ -61,6 +63,8 @@ type Stream struct {
 }
 
 var (
+	_ io.ReaderFrom             = &Stream{}
+	_ io.WriterTo               = &Stream{}
 	_ outgoingStream            = &Stream{}
 	_ sendStreamFrameHandler    = &Stream{}
 	_ receiveStreamFrameHandler = &Stream{}
@@ -110,6 +114,32 @@ func (s *Stream) StreamID() protocol.StreamID {
 	return s.sendStr.StreamID()
 }
 
+// StreamStats contains statistics about a bidirectional stream.
+type StreamStats struct {
+	Send    SendStreamStats
+	Receive ReceiveStreamStats
+}
+
+// StreamInfo contains information about an open stream, as returned by [Conn.Streams].
+type StreamInfo struct {
+	ID StreamID
+	// Send contains statistics about the send direction of the stream.
+	// It is nil for unidirectional streams opened by the peer.
+	Send *SendStreamStats
+	// Receive contains statistics about the receive direction of the stream.
+	// It is nil for unidirectional streams opened by this endpoint.
+	Receive *ReceiveStreamStats
+}
+
+// Stats returns statistics about the stream.
+// It is safe to call Stats concurrently with other methods on the stream.
+func (s *Stream) Stats() StreamStats {
+	return StreamStats{
+		Send:    s.sendStr.Stats(),
+		Receive: s.receiveStr.Stats(),
+	}
+}
+
 // Read reads data from the stream.
 // Read can be made to time out using [Stream.SetReadDeadline] and [Stream.SetDeadline].
 // If the stream was canceled, the error is a [StreamError].
@@ -133,6 +163,24 @@ func (s *Stream) Write(p []byte) (int, error) {
 	return s.sendStr.Write(p)
 }
 
+// WriteBuffers writes the contents of bufs to the stream, as if they were passed to a single Write call.
+// See [SendStream.WriteBuffers] for details.
+func (s *Stream) WriteBuffers(bufs [][]byte) (int, error) {
+	return s.sendStr.WriteBuffers(bufs)
+}
+
+// ReadFrom reads data from r until io.EOF or an error occurs, and writes it to the stream.
+// See [SendStream.ReadFrom] for details.
+func (s *Stream) ReadFrom(r io.Reader) (int64, error) {
+	return s.sendStr.ReadFrom(r)
+}
+
+// WriteTo writes stream data to w until the end of the stream is reached or an error occurs.
+// See [ReceiveStream.WriteTo] for details.
+func (s *Stream) WriteTo(w io.Writer) (int64, error) {
+	return s.receiveStr.WriteTo(w)
+}
+
 // SetReliableBoundary marks the data written to this stream so far as reliable.
 // It is valid to call this function multiple times, thereby increasing the reliable size.
 // It only has an effect if the peer enabled support for the RESET_STREAM_AT extension,
@@ -169,6 +217,16 @@ func (s *Stream) handleResetStreamFrame(frame *wire.ResetStreamFrame, rcvTime mo
 	return s.receiveStr.handleResetStreamFrame(frame, rcvTime)
 }
 
+// ReceivedIn0RTT says if any data of the stream was received in 0-RTT packets.
+// See [ReceiveStream.ReceivedIn0RTT] for more details.
+func (s *Stream) ReceivedIn0RTT() bool {
+	return s.receiveStr.ReceivedIn0RTT()
+}
+
+func (s *Stream) markReceivedIn0RTT() {
+	s.receiveStr.markReceivedIn0RTT()
+}
+
 func (s *Stream) handleStreamFrame(frame *wire.StreamFrame, rcvTime monotime.Time) error {
 	return s.receiveStr.handleStreamFrame(frame, rcvTime)
 }
@@ -1,8 +1,10 @@
 package quic
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 
 	"github.com/nukilabs/quic-go/internal/flowcontrol"
@@ -253,6 +255,7 @@ func (m *streamsMap) HandleStopSendingFrame(f *wire.StopSendingFrame) error {
 type receiveStreamFrameHandler interface {
 	handleResetStreamFrame(*wire.ResetStreamFrame, monotime.Time) error
 	handleStreamFrame(*wire.StreamFrame, monotime.Time) error
+	markReceivedIn0RTT()
 }
 
 func (m *streamsMap) getReceiveStream(id protocol.StreamID) (receiveStreamFrameHandler, error) {
@@ -317,6 +320,20 @@ func (m *streamsMap) HandleStreamFrame(f *wire.StreamFrame, rcvTime monotime.Tim
 	return str.handleStreamFrame(f, rcvTime)
 }
 
+// Handle0RTTStreamFrame handles a STREAM frame received in a 0-RTT packet.
+// The stream is marked before the data is made available to the application.
+func (m *streamsMap) Handle0RTTStreamFrame(f *wire.StreamFrame, rcvTime monotime.Time) error {
+	str, err := m.getReceiveStream(f.StreamID)
+	if err != nil {
+		return err
+	}
+	if str == nil { // stream already deleted
+		return nil
+	}
+	str.markReceivedIn0RTT()
+	return str.handleStreamFrame(f, rcvTime)
+}
+
 func (m *streamsMap) HandleTransportParameters(p *wire.TransportParameters) {
 	m.supportsResetStreamAt = p.EnableResetStreamAt
 	m.outgoingBidiStreams.EnableResetStreamAt()
@@ -327,6 +344,30 @@ func (m *streamsMap) HandleTransportParameters(p *wire.TransportParameters) {
 	m.outgoingUniStreams.SetMaxStream(p.MaxUniStreamNum.StreamID(protocol.StreamTypeUni, m.perspective))
 }
 
+// Streams returns information about all open streams, ordered by stream ID.
+func (m *streamsMap) Streams() []StreamInfo {
+	m.mutex.Lock()
+	outgoingBidiStreams, incomingBidiStreams := m.outgoingBidiStreams, m.incomingBidiStreams
+	outgoingUniStreams, incomingUniStreams := m.outgoingUniStreams, m.incomingUniStreams
+	m.mutex.Unlock()
+
+	var infos []StreamInfo
+	for _, str := range append(outgoingBidiStreams.Streams(), incomingBidiStreams.Streams()...) {
+		stats := str.Stats()
+		infos = append(infos, StreamInfo{ID: str.StreamID(), Send: &stats.Send, Receive: &stats.Receive})
+	}
+	for _, str := range outgoingUniStreams.Streams() {
+		stats := str.Stats()
+		infos = append(infos, StreamInfo{ID: str.StreamID(), Send: &stats})
+	}
+	for _, str := range incomingUniStreams.Streams() {
+		stats := str.Stats()
+		infos = append(infos, StreamInfo{ID: str.StreamID(), Receive: &stats})
+	}
+	slices.SortFunc(infos, func(a, b StreamInfo) int { return cmp.Compare(a.ID, b.ID) })
+	return infos
+}
+
 func (m *streamsMap) CloseWithError(err error) {
 	m.outgoingBidiStreams.CloseWithError(err)
 	m.outgoingUniStreams.CloseWithError(err)
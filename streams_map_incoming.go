@@ -198,6 +198,21 @@ func (m *incomingStreamsMap[T]) deleteStream(id protocol.StreamID) error {
 	return nil
 }
 
+// Streams returns all streams that haven't been deleted yet.
+// This includes streams that haven't been accepted yet.
+func (m *incomingStreamsMap[T]) Streams() []T {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	streams := make([]T, 0, len(m.streams))
+	for _, entry := range m.streams {
+		if !entry.shouldDelete {
+			streams = append(streams, entry.stream)
+		}
+	}
+	return streams
+}
+
 func (m *incomingStreamsMap[T]) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
@@ -3,6 +3,7 @@ package quic
 import (
 	"context"
 	"fmt"
+	"maps"
 	"slices"
 	"sync"
 
@@ -179,6 +180,14 @@ func (m *outgoingStreamsMap[T]) DeleteStream(id protocol.StreamID) error {
 	return nil
 }
 
+// Streams returns all streams that haven't been deleted yet.
+func (m *outgoingStreamsMap[T]) Streams() []T {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return slices.Collect(maps.Values(m.streams))
+}
+
 func (m *outgoingStreamsMap[T]) SetMaxStream(id protocol.StreamID) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
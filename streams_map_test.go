@@ -197,6 +197,54 @@ func testStreamsMapDeletingStreams(t *testing.T,
 	frameQueue = frameQueue[:0]
 }
 
+func TestStreamsMapStreams(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockSender := NewMockStreamSender(mockCtrl)
+	mockSender.EXPECT().onHasStreamControlFrame(gomock.Any(), gomock.Any()).AnyTimes()
+	m := newStreamsMap(
+		context.Background(),
+		mockSender,
+		func(wire.Frame) {},
+		func(protocol.StreamID) flowcontrol.StreamFlowController {
+			fc := mocks.NewMockStreamFlowController(mockCtrl)
+			fc.EXPECT().UpdateHighestReceived(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			fc.EXPECT().BlockedTime().AnyTimes()
+			return fc
+		},
+		100,
+		100,
+		protocol.PerspectiveServer,
+	)
+	m.HandleTransportParameters(&wire.TransportParameters{
+		MaxBidiStreamNum: 10,
+		MaxUniStreamNum:  10,
+	})
+	require.Empty(t, m.Streams())
+
+	_, err := m.OpenStream()
+	require.NoError(t, err)
+	_, err = m.OpenUniStream()
+	require.NoError(t, err)
+	require.NoError(t, m.HandleStreamFrame(&wire.StreamFrame{StreamID: 2, Data: []byte("foobar")}, monotime.Now()))
+	require.NoError(t, m.HandleStreamFrame(&wire.StreamFrame{StreamID: 4}, monotime.Now()))
+	// stream 0 was implicitly opened, stream 4 was deleted before it was accepted
+	require.NoError(t, m.DeleteStream(4))
+
+	streams := m.Streams()
+	require.Len(t, streams, 4)
+	for i, id := range []protocol.StreamID{0, 1, 2, 3} {
+		require.Equal(t, id, streams[i].ID)
+	}
+	require.Equal(t, &SendStreamStats{}, streams[0].Send)
+	require.Equal(t, &ReceiveStreamStats{}, streams[0].Receive)
+	require.NotNil(t, streams[1].Send)
+	require.NotNil(t, streams[1].Receive)
+	require.Nil(t, streams[2].Send)
+	require.Equal(t, &ReceiveStreamStats{HighestReceivedOffset: 6}, streams[2].Receive)
+	require.NotNil(t, streams[3].Send)
+	require.Nil(t, streams[3].Receive)
+}
+
 func TestStreamsMapStreamLimits(t *testing.T) {
 	t.Run("client", func(t *testing.T) {
 		testStreamsMapStreamLimits(t, protocol.PerspectiveClient)
@@ -601,3 +649,37 @@ func TestStreamsMap0RTTRejection(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorIs(t, err, &StreamLimitReachedError{})
 }
+
+func TestStreamsMap0RTTStreamFrames(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	m := newStreamsMap(
+		context.Background(),
+		NewMockStreamSender(mockCtrl),
+		func(wire.Frame) {},
+		func(protocol.StreamID) flowcontrol.StreamFlowController {
+			fc := mocks.NewMockStreamFlowController(mockCtrl)
+			fc.EXPECT().UpdateHighestReceived(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			return fc
+		},
+		100,
+		100,
+		protocol.PerspectiveServer,
+	)
+	require.NoError(t, m.Handle0RTTStreamFrame(&wire.StreamFrame{StreamID: 0, Data: []byte("foo")}, monotime.Now()))
+	require.NoError(t, m.HandleStreamFrame(&wire.StreamFrame{StreamID: 4, Data: []byte("bar")}, monotime.Now()))
+	// data received in 1-RTT packets doesn't reset the flag
+	require.NoError(t, m.HandleStreamFrame(&wire.StreamFrame{StreamID: 0, Offset: 3, Data: []byte("baz")}, monotime.Now()))
+
+	str, err := m.AcceptStream(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, protocol.StreamID(0), str.StreamID())
+	require.True(t, str.ReceivedIn0RTT())
+	str, err = m.AcceptStream(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, protocol.StreamID(4), str.StreamID())
+	require.False(t, str.ReceivedIn0RTT())
+
+	// frames for streams that were already deleted are ignored
+	require.NoError(t, m.DeleteStream(4))
+	require.NoError(t, m.Handle0RTTStreamFrame(&wire.StreamFrame{StreamID: 4}, monotime.Now()))
+}
@@ -0,0 +1,256 @@
+package simnet
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCoDelTarget   = 5 * time.Millisecond
+	defaultCoDelInterval = 100 * time.Millisecond
+	defaultReorderDelay  = 10 * time.Millisecond
+)
+
+// CoDelSettings configures the CoDel active queue management algorithm (RFC 8289).
+type CoDelSettings struct {
+	// Target is the acceptable standing queue delay.
+	// If zero, it defaults to 5ms.
+	Target time.Duration
+	// Interval is the time window over which the queue delay is observed.
+	// It should be on the order of the worst-case RTT through the bottleneck.
+	// If zero, it defaults to 100ms.
+	Interval time.Duration
+}
+
+func (s *CoDelSettings) target() time.Duration {
+	if s.Target == 0 {
+		return defaultCoDelTarget
+	}
+	return s.Target
+}
+
+func (s *CoDelSettings) interval() time.Duration {
+	if s.Interval == 0 {
+		return defaultCoDelInterval
+	}
+	return s.Interval
+}
+
+// GilbertElliott configures a Gilbert-Elliott burst loss model.
+// The model has two states, good and bad, each with its own loss probability.
+// Before each packet, the model transitions between the states with the given probabilities.
+type GilbertElliott struct {
+	// P is the probability of transitioning from the good to the bad state.
+	P float64
+	// R is the probability of transitioning from the bad to the good state.
+	R float64
+	// LossGood is the loss probability in the good state.
+	LossGood float64
+	// LossBad is the loss probability in the bad state.
+	LossBad float64
+}
+
+type queuedPacket struct {
+	departure time.Time
+	size      int
+}
+
+// linkDirection applies the LinkSettings to packets sent in one direction of a link.
+// It computes the delivery time of every packet when the packet is sent.
+type linkDirection struct {
+	settings LinkSettings
+
+	mx  sync.Mutex
+	rng *rand.Rand
+
+	// the bottleneck queue, ordered by departure time
+	queue         []queuedPacket
+	queuedBytes   int
+	lastDeparture time.Time
+	codel         codelState
+
+	gilbertElliottBad bool
+}
+
+func newLinkDirection(settings LinkSettings) *linkDirection {
+	return &linkDirection{
+		settings: settings,
+		rng:      rand.New(rand.NewPCG(settings.Seed, settings.Seed^0x5eed)),
+	}
+}
+
+// setSettings changes the settings.
+// The state of the bottleneck queue is preserved.
+func (d *linkDirection) setSettings(settings LinkSettings) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	d.settings = settings
+	if settings.BurstLoss == nil {
+		d.gilbertElliottBad = false
+	}
+}
+
+// schedule returns the packets to deliver, together with their delivery times.
+// It returns no packets if the packet is dropped, and multiple packets if it is duplicated.
+func (d *linkDirection) schedule(p Packet, now time.Time, latency time.Duration) []*packetWithDeliveryTime {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if len(p.Data) > d.settings.MTU {
+		// Drop packet if it's too large
+		return nil
+	}
+
+	departure := now
+	if d.settings.Bandwidth > 0 {
+		var ok bool
+		departure, ok = d.enqueueBottleneck(len(p.Data), now)
+		if !ok {
+			return nil
+		}
+	}
+	if d.isLost() {
+		return nil
+	}
+
+	packets := []*packetWithDeliveryTime{{
+		Packet:       p,
+		DeliveryTime: departure.Add(latency + d.delay()),
+	}}
+	if d.settings.DuplicateRate > 0 && d.rng.Float64() < d.settings.DuplicateRate {
+		dup := p
+		dup.Data = make([]byte, len(p.Data))
+		copy(dup.Data, p.Data)
+		packets = append(packets, &packetWithDeliveryTime{
+			Packet:       dup,
+			DeliveryTime: departure.Add(latency + d.delay()),
+		})
+	}
+	return packets
+}
+
+// enqueueBottleneck adds a packet to the bottleneck queue.
+// It returns the time when the packet has been fully serialized onto the link,
+// or false if the packet was dropped by the queue.
+func (d *linkDirection) enqueueBottleneck(size int, now time.Time) (time.Time, bool) {
+	// remove all packets that already left the queue
+	var i int
+	for i < len(d.queue) && !d.queue[i].departure.After(now) {
+		d.queuedBytes -= d.queue[i].size
+		i++
+	}
+	d.queue = d.queue[i:]
+
+	if d.settings.QueueSize > 0 && d.queuedBytes+size > d.settings.QueueSize {
+		// tail drop
+		return time.Time{}, false
+	}
+	start := now
+	if d.lastDeparture.After(now) {
+		start = d.lastDeparture
+	}
+	// Packets leave the queue in FIFO order, so we can run CoDel for this packet now,
+	// using the time when the packet reaches the head of the queue.
+	if d.settings.CoDel != nil && d.codel.shouldDrop(d.settings.CoDel, start, start.Sub(now), d.queuedBytes, d.settings.MTU) {
+		return time.Time{}, false
+	}
+	departure := start.Add(time.Duration(float64(size*8) / float64(d.settings.Bandwidth) * float64(time.Second)))
+	d.lastDeparture = departure
+	d.queue = append(d.queue, queuedPacket{departure: departure, size: size})
+	d.queuedBytes += size
+	return departure, true
+}
+
+func (d *linkDirection) isLost() bool {
+	lost := d.settings.LossRate > 0 && d.rng.Float64() < d.settings.LossRate
+	if ge := d.settings.BurstLoss; ge != nil {
+		if d.gilbertElliottBad {
+			if d.rng.Float64() < ge.R {
+				d.gilbertElliottBad = false
+			}
+		} else if d.rng.Float64() < ge.P {
+			d.gilbertElliottBad = true
+		}
+		lossProb := ge.LossGood
+		if d.gilbertElliottBad {
+			lossProb = ge.LossBad
+		}
+		if lossProb > 0 && d.rng.Float64() < lossProb {
+			lost = true
+		}
+	}
+	return lost
+}
+
+// delay returns the additional delay due to jitter and reordering.
+func (d *linkDirection) delay() time.Duration {
+	var delay time.Duration
+	if d.settings.Jitter > 0 {
+		delay += time.Duration(d.rng.Int64N(int64(d.settings.Jitter) + 1))
+	}
+	if d.settings.ReorderRate > 0 && d.rng.Float64() < d.settings.ReorderRate {
+		if d.settings.ReorderDelay > 0 {
+			delay += d.settings.ReorderDelay
+		} else {
+			delay += defaultReorderDelay
+		}
+	}
+	return delay
+}
+
+// codelState is the state of the CoDel algorithm, see section 5 of RFC 8289.
+type codelState struct {
+	firstAboveTime time.Time
+	dropNext       time.Time
+	count          uint32
+	lastCount      uint32
+	dropping       bool
+}
+
+// shouldDrop is called when a packet reaches the head of the queue.
+func (c *codelState) shouldDrop(s *CoDelSettings, now time.Time, sojourn time.Duration, queuedBytes, mtu int) bool {
+	interval := s.interval()
+	var okToDrop bool
+	switch {
+	case sojourn < s.target() || queuedBytes <= mtu:
+		c.firstAboveTime = time.Time{}
+	case c.firstAboveTime.IsZero():
+		c.firstAboveTime = now.Add(interval)
+	case !now.Before(c.firstAboveTime):
+		okToDrop = true
+	}
+
+	if c.dropping {
+		if !okToDrop {
+			c.dropping = false
+			return false
+		}
+		if now.Before(c.dropNext) {
+			return false
+		}
+		c.count++
+		c.dropNext = codelControlLaw(c.dropNext, interval, c.count)
+		return true
+	}
+	if !okToDrop {
+		return false
+	}
+	c.dropping = true
+	// If we're in a drop cycle, the drop rate that controlled the queue
+	// on the last cycle is a good starting point to control it now.
+	delta := c.count - c.lastCount
+	c.count = 1
+	if delta > 1 && now.Sub(c.dropNext) < 16*interval {
+		c.count = delta
+	}
+	c.dropNext = codelControlLaw(now, interval, c.count)
+	c.lastCount = c.count
+	return true
+}
+
+func codelControlLaw(t time.Time, interval time.Duration, count uint32) time.Time {
+	return t.Add(time.Duration(float64(interval) / math.Sqrt(float64(count))))
+}
@@ -0,0 +1,218 @@
+package simnet
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/synctest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func scheduleAll(d *linkDirection, numPackets, size int, now time.Time) []*packetWithDeliveryTime {
+	var delivered []*packetWithDeliveryTime
+	for i := range numPackets {
+		data := make([]byte, size)
+		data[0] = byte(i)
+		delivered = append(delivered, d.schedule(Packet{Data: data}, now, 0)...)
+	}
+	return delivered
+}
+
+func TestLinkBandwidth(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mx sync.Mutex
+		var received []time.Time
+		router := &testRouter{onRecv: func(Packet) {
+			mx.Lock()
+			defer mx.Unlock()
+			received = append(received, time.Now())
+		}}
+		link := SimulatedLink{
+			DownlinkSettings: LinkSettings{MTU: 1500, Bandwidth: 8_000_000}, // 1 MB/s
+			Latency:          10 * time.Millisecond,
+			UploadPacket:     router,
+			downloadPacket:   router,
+		}
+		link.Start()
+		defer link.Close()
+
+		start := time.Now()
+		for range 10 {
+			link.RecvPacket(Packet{Data: make([]byte, 1000)})
+		}
+		time.Sleep(time.Second)
+
+		mx.Lock()
+		defer mx.Unlock()
+		require.Len(t, received, 10)
+		for i, rcvTime := range received {
+			// every packet takes 1ms to serialize
+			require.Equal(t, time.Duration(i+1)*time.Millisecond+10*time.Millisecond, rcvTime.Sub(start))
+		}
+	})
+}
+
+func TestLinkTailDrop(t *testing.T) {
+	now := time.Now()
+	d := newLinkDirection(LinkSettings{MTU: 1500, Bandwidth: 8_000_000, QueueSize: 5000})
+	delivered := scheduleAll(d, 10, 1000, now)
+	require.Len(t, delivered, 5)
+	require.Equal(t, now.Add(5*time.Millisecond), delivered[4].DeliveryTime)
+
+	// once the queue drains, packets are accepted again
+	delivered = scheduleAll(d, 10, 1000, now.Add(2*time.Millisecond))
+	require.Len(t, delivered, 2)
+	require.Equal(t, now.Add(7*time.Millisecond), delivered[1].DeliveryTime)
+}
+
+func TestLinkCoDel(t *testing.T) {
+	// send 1.1 MB/s into a 1 MB/s link for 10s
+	const numPackets = 11000
+	queueingDelays := func(codel *CoDelSettings) (delays []time.Duration, numDropped int) {
+		d := newLinkDirection(LinkSettings{MTU: 1500, Bandwidth: 8_000_000, CoDel: codel})
+		now := time.Now()
+		for i := range numPackets {
+			sendTime := now.Add(time.Duration(i) * time.Second / 1100)
+			packets := d.schedule(Packet{Data: make([]byte, 1000)}, sendTime, 0)
+			if len(packets) == 0 {
+				numDropped++
+				continue
+			}
+			delays = append(delays, packets[0].DeliveryTime.Sub(sendTime))
+		}
+		return delays, numDropped
+	}
+
+	delays, numDropped := queueingDelays(nil)
+	require.Zero(t, numDropped)
+	// the queue grows without bounds
+	t.Logf("without CoDel: final queueing delay %s", delays[len(delays)-1])
+	require.Greater(t, delays[len(delays)-1], 800*time.Millisecond)
+
+	delays, numDropped = queueingDelays(&CoDelSettings{})
+	t.Logf("with CoDel: final queueing delay %s, max %s, %d packets dropped", delays[len(delays)-1], slices.Max(delays), numDropped)
+	require.NotZero(t, numDropped)
+	// CoDel keeps the queueing delay in check
+	require.Less(t, slices.Max(delays), 200*time.Millisecond)
+}
+
+func TestCoDelControlLaw(t *testing.T) {
+	var c codelState
+	s := &CoDelSettings{}
+	now := time.Now()
+	// below target, no drops
+	require.False(t, c.shouldDrop(s, now, time.Millisecond, 10000, 1500))
+	// above target, but not for an entire interval
+	require.False(t, c.shouldDrop(s, now, 10*time.Millisecond, 10000, 1500))
+	require.False(t, c.shouldDrop(s, now.Add(99*time.Millisecond), 10*time.Millisecond, 10000, 1500))
+	// above target for an entire interval
+	now = now.Add(100 * time.Millisecond)
+	require.True(t, c.shouldDrop(s, now, 10*time.Millisecond, 10000, 1500))
+	require.False(t, c.shouldDrop(s, now.Add(99*time.Millisecond), 10*time.Millisecond, 10000, 1500))
+	// the next drop happens after interval/sqrt(count)
+	now = now.Add(100 * time.Millisecond)
+	require.True(t, c.shouldDrop(s, now, 10*time.Millisecond, 10000, 1500))
+	require.Equal(t, uint32(2), c.count)
+	require.Equal(t, codelControlLaw(now, 100*time.Millisecond, 2), c.dropNext)
+	// once the queue delay drops below the target, CoDel leaves the dropping state
+	require.False(t, c.shouldDrop(s, now.Add(time.Second), time.Millisecond, 10000, 1500))
+	require.False(t, c.dropping)
+}
+
+func TestLinkRandomLoss(t *testing.T) {
+	const numPackets = 10000
+	lossPattern := func(seed uint64) []bool {
+		d := newLinkDirection(LinkSettings{MTU: 1500, LossRate: 0.1, Seed: seed})
+		var lost []bool
+		for range numPackets {
+			lost = append(lost, len(d.schedule(Packet{Data: []byte("foo")}, time.Now(), 0)) == 0)
+		}
+		return lost
+	}
+
+	lost := lossPattern(42)
+	numLost := 0
+	for _, l := range lost {
+		if l {
+			numLost++
+		}
+	}
+	require.InDelta(t, numPackets/10, numLost, numPackets/50)
+	// the same seed leads to the same loss pattern
+	require.Equal(t, lost, lossPattern(42))
+	require.NotEqual(t, lost, lossPattern(1337))
+}
+
+func TestLinkBurstLoss(t *testing.T) {
+	const numPackets = 100000
+	d := newLinkDirection(LinkSettings{
+		MTU:       1500,
+		BurstLoss: &GilbertElliott{P: 0.01, R: 0.25, LossBad: 1},
+	})
+	var numLost, numBursts int
+	var lastLost bool
+	for range numPackets {
+		lost := len(d.schedule(Packet{Data: []byte("foo")}, time.Now(), 0)) == 0
+		if lost {
+			numLost++
+			if !lastLost {
+				numBursts++
+			}
+		}
+		lastLost = lost
+	}
+	// stationary probability of the bad state: P/(P+R)
+	require.InDelta(t, 0.01/0.26, float64(numLost)/numPackets, 0.01)
+	// mean burst length: 1/R
+	require.InDelta(t, 4, float64(numLost)/float64(numBursts), 0.5)
+}
+
+func TestLinkReordering(t *testing.T) {
+	now := time.Now()
+	d := newLinkDirection(LinkSettings{MTU: 1500, ReorderRate: 0.2, ReorderDelay: 5 * time.Millisecond})
+	var numReordered int
+	for i := range 1000 {
+		packets := d.schedule(Packet{Data: []byte{byte(i)}}, now.Add(time.Duration(i)*time.Millisecond), 10*time.Millisecond)
+		require.Len(t, packets, 1)
+		switch delay := packets[0].DeliveryTime.Sub(now.Add(time.Duration(i) * time.Millisecond)); delay {
+		case 10 * time.Millisecond:
+		case 15 * time.Millisecond:
+			numReordered++
+		default:
+			t.Fatalf("unexpected delay: %s", delay)
+		}
+	}
+	require.InDelta(t, 200, numReordered, 40)
+}
+
+func TestLinkDuplication(t *testing.T) {
+	d := newLinkDirection(LinkSettings{MTU: 1500, DuplicateRate: 1})
+	data := []byte("foobar")
+	packets := d.schedule(Packet{Data: data}, time.Now(), 0)
+	require.Len(t, packets, 2)
+	require.Equal(t, data, packets[0].Data)
+	require.Equal(t, data, packets[1].Data)
+	// the duplicate uses a copy of the data
+	data[0] = 'x'
+	require.Equal(t, []byte("foobar"), packets[1].Data)
+}
+
+func TestLinkJitter(t *testing.T) {
+	now := time.Now()
+	d := newLinkDirection(LinkSettings{MTU: 1500, Jitter: 10 * time.Millisecond})
+	var minDelay, maxDelay time.Duration = time.Hour, 0
+	for range 1000 {
+		packets := d.schedule(Packet{Data: []byte("foo")}, now, 20*time.Millisecond)
+		require.Len(t, packets, 1)
+		delay := packets[0].DeliveryTime.Sub(now)
+		minDelay = min(minDelay, delay)
+		maxDelay = max(maxDelay, delay)
+	}
+	require.GreaterOrEqual(t, minDelay, 20*time.Millisecond)
+	require.Less(t, minDelay, 21*time.Millisecond)
+	require.LessOrEqual(t, maxDelay, 30*time.Millisecond)
+	require.Greater(t, maxDelay, 29*time.Millisecond)
+}
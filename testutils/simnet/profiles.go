@@ -0,0 +1,103 @@
+package simnet
+
+import (
+	"net"
+	"time"
+)
+
+// The profiles below describe the access link of a single endpoint.
+// Since simnet only applies latency on the downlink, the RTT between two endpoints
+// is the sum of their Latency values.
+// The profiles therefore use the RTT contributed by the access link as Latency.
+// The parameters are typical values, they're not meant to model any specific network.
+
+// Profile3G returns the link settings of a 3G (HSPA) mobile connection.
+func Profile3G() NodeBiDiLinkSettings {
+	return NodeBiDiLinkSettings{
+		Downlink: LinkSettings{
+			MTU:       1400,
+			Bandwidth: 2_000_000,
+			QueueSize: 64 << 10,
+			Jitter:    20 * time.Millisecond,
+			LossRate:  0.005,
+		},
+		Uplink: LinkSettings{
+			MTU:       1400,
+			Bandwidth: 384_000,
+			QueueSize: 32 << 10,
+		},
+		Latency: 150 * time.Millisecond,
+	}
+}
+
+// ProfileLTE returns the link settings of an LTE mobile connection.
+func ProfileLTE() NodeBiDiLinkSettings {
+	return NodeBiDiLinkSettings{
+		Downlink: LinkSettings{
+			MTU:       1400,
+			Bandwidth: 20_000_000,
+			QueueSize: 256 << 10,
+			Jitter:    5 * time.Millisecond,
+		},
+		Uplink: LinkSettings{
+			MTU:       1400,
+			Bandwidth: 5_000_000,
+			QueueSize: 128 << 10,
+		},
+		Latency: 50 * time.Millisecond,
+	}
+}
+
+// ProfileSatellite returns the link settings of a geostationary satellite connection.
+func ProfileSatellite() NodeBiDiLinkSettings {
+	return NodeBiDiLinkSettings{
+		Downlink: LinkSettings{
+			MTU:       1400,
+			Bandwidth: 20_000_000,
+			QueueSize: 1 << 20,
+			LossRate:  0.001,
+		},
+		Uplink: LinkSettings{
+			MTU:       1400,
+			Bandwidth: 2_000_000,
+			QueueSize: 256 << 10,
+		},
+		Latency: 600 * time.Millisecond,
+	}
+}
+
+// ProfileLossyWiFi returns the link settings of a congested Wi-Fi network,
+// with bursty loss and some reordering.
+func ProfileLossyWiFi() NodeBiDiLinkSettings {
+	return NodeBiDiLinkSettings{
+		Downlink: LinkSettings{
+			MTU:         1400,
+			Bandwidth:   50_000_000,
+			CoDel:       &CoDelSettings{},
+			LossRate:    0.01,
+			BurstLoss:   &GilbertElliott{P: 0.01, R: 0.3, LossBad: 0.5},
+			ReorderRate: 0.01,
+			Jitter:      5 * time.Millisecond,
+		},
+		Uplink: LinkSettings{
+			MTU:       1400,
+			Bandwidth: 50_000_000,
+			CoDel:     &CoDelSettings{},
+			LossRate:  0.01,
+			BurstLoss: &GilbertElliott{P: 0.01, R: 0.3, LossBad: 0.5},
+		},
+		Latency: 10 * time.Millisecond,
+	}
+}
+
+// LTEHandover returns a scenario that simulates a handover between two LTE cells at the given time.
+// The link is interrupted for 50ms, and the endpoint is rebound to a new address.
+// The link of the endpoint should use ProfileLTE.
+func LTEHandover(n *Simnet, c *SimConn, at time.Duration, newAddr *net.UDPAddr) *Scenario {
+	const interruption = 50 * time.Millisecond
+	link := n.Link(c)
+	s := &Scenario{}
+	// rebind before the link comes back up
+	s.Rebind(n, c, at+interruption, newAddr)
+	return s.Outage(link, at, interruption)
+}
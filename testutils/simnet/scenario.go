@@ -0,0 +1,160 @@
+package simnet
+
+import (
+	"cmp"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A Scenario changes the network conditions over time.
+// Events are scheduled relative to the time the scenario is started,
+// and are executed using the (possibly simulated) clock, so scenarios can be used with synctest.
+//
+// A Scenario is built by adding events, and then started using Start.
+// Events must not be added after the scenario was started.
+type Scenario struct {
+	events []scenarioEvent
+
+	mx      sync.Mutex
+	started bool
+	stopped bool
+	timer   *time.Timer
+}
+
+type scenarioEvent struct {
+	at     time.Duration
+	action func()
+}
+
+// At schedules an action to run at the given offset from the start of the scenario.
+// Actions scheduled for the same time run in the order they were added.
+func (s *Scenario) At(at time.Duration, action func()) *Scenario {
+	s.events = append(s.events, scenarioEvent{at: at, action: action})
+	return s
+}
+
+// Outage takes the link down at the given time, and brings it back up after the given duration.
+func (s *Scenario) Outage(link *SimulatedLink, at, duration time.Duration) *Scenario {
+	s.At(at, func() { link.SetDown(true) })
+	return s.At(at+duration, func() { link.SetDown(false) })
+}
+
+// LatencySpike changes the latency of the link at the given time,
+// and restores the previous latency after the given duration.
+func (s *Scenario) LatencySpike(link *SimulatedLink, at, duration, latency time.Duration) *Scenario {
+	var prev time.Duration
+	s.At(at, func() {
+		prev = link.GetLatency()
+		link.SetLatency(latency)
+	})
+	return s.At(at+duration, func() { link.SetLatency(prev) })
+}
+
+// SetLatency changes the latency of the link at the given time.
+func (s *Scenario) SetLatency(link *SimulatedLink, at, latency time.Duration) *Scenario {
+	return s.At(at, func() { link.SetLatency(latency) })
+}
+
+// SetBandwidth changes the downlink and uplink bandwidth (in bits per second) of the link at the given time.
+// All other link settings are preserved.
+func (s *Scenario) SetBandwidth(link *SimulatedLink, at time.Duration, downlink, uplink int64) *Scenario {
+	return s.At(at, func() {
+		down := link.GetDownlinkSettings()
+		down.Bandwidth = downlink
+		link.SetDownlinkSettings(down)
+		up := link.GetUplinkSettings()
+		up.Bandwidth = uplink
+		link.SetUplinkSettings(up)
+	})
+}
+
+// SetLinkSettings changes the downlink and uplink settings of the link at the given time.
+func (s *Scenario) SetLinkSettings(link *SimulatedLink, at time.Duration, downlink, uplink LinkSettings) *Scenario {
+	return s.At(at, func() {
+		link.SetDownlinkSettings(downlink)
+		link.SetUplinkSettings(uplink)
+	})
+}
+
+// Rebind changes the address of an endpoint at the given time, simulating a NAT rebinding.
+// See Simnet.Rebind for details.
+func (s *Scenario) Rebind(n *Simnet, c *SimConn, at time.Duration, addr *net.UDPAddr) *Scenario {
+	return s.At(at, func() {
+		if err := n.Rebind(c, addr); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// RemoveNode removes a node from the router at the given time.
+func (s *Scenario) RemoveNode(router *PerfectRouter, at time.Duration, addr net.Addr) *Scenario {
+	return s.At(at, func() { router.RemoveNode(addr) })
+}
+
+// AddNode adds a node to the router at the given time.
+func (s *Scenario) AddNode(router Router, at time.Duration, addr net.Addr, receiver PacketReceiver) *Scenario {
+	return s.At(at, func() { router.AddNode(addr, receiver) })
+}
+
+// Start starts the scenario.
+// Events scheduled at time 0 are run synchronously.
+func (s *Scenario) Start() {
+	s.mx.Lock()
+	if s.started {
+		s.mx.Unlock()
+		panic("scenario already started")
+	}
+	s.started = true
+	start := time.Now()
+	events := slices.Clone(s.events)
+	slices.SortStableFunc(events, func(a, b scenarioEvent) int { return cmp.Compare(a.at, b.at) })
+	var immediate []func()
+	for len(events) > 0 && events[0].at <= 0 {
+		immediate = append(immediate, events[0].action)
+		events = events[1:]
+	}
+	if len(events) > 0 {
+		s.timer = time.AfterFunc(events[0].at, func() { s.run(events, start) })
+	}
+	s.mx.Unlock()
+
+	for _, action := range immediate {
+		action()
+	}
+}
+
+// run runs all events that are due, and schedules a timer for the next event.
+// Using a single timer at a time guarantees that events run in order.
+func (s *Scenario) run(events []scenarioEvent, start time.Time) {
+	for len(events) > 0 {
+		if d := time.Until(start.Add(events[0].at)); d > 0 {
+			s.mx.Lock()
+			if !s.stopped {
+				s.timer = time.AfterFunc(d, func() { s.run(events, start) })
+			}
+			s.mx.Unlock()
+			return
+		}
+		s.mx.Lock()
+		stopped := s.stopped
+		s.mx.Unlock()
+		if stopped {
+			return
+		}
+		events[0].action()
+		events = events[1:]
+	}
+}
+
+// Stop stops the scenario. Events that haven't run yet are discarded.
+// It doesn't revert events that already ran.
+func (s *Scenario) Stop() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
@@ -0,0 +1,180 @@
+package simnet
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nukilabs/quic-go/internal/synctest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenarioEventOrder(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var mx sync.Mutex
+		var events []string
+		var times []time.Duration
+		start := time.Now()
+		record := func(name string) func() {
+			return func() {
+				mx.Lock()
+				defer mx.Unlock()
+				events = append(events, name)
+				times = append(times, time.Since(start))
+			}
+		}
+		getEvents := func() []string {
+			mx.Lock()
+			defer mx.Unlock()
+			return events
+		}
+
+		s := &Scenario{}
+		s.At(2*time.Second, record("c"))
+		s.At(time.Second, record("a"))
+		s.At(time.Second, record("b"))
+		s.At(0, record("start"))
+		s.At(3*time.Second, record("d"))
+		s.Start()
+		// events at time 0 run synchronously
+		require.Equal(t, []string{"start"}, getEvents())
+
+		time.Sleep(2500 * time.Millisecond)
+		require.Equal(t, []string{"start", "a", "b", "c"}, getEvents())
+		mx.Lock()
+		require.Equal(t, []time.Duration{0, time.Second, time.Second, 2 * time.Second}, times)
+		mx.Unlock()
+
+		// stopping the scenario discards all remaining events
+		s.Stop()
+		time.Sleep(time.Hour)
+		require.Len(t, getEvents(), 4)
+	})
+}
+
+func newTestSimnet(t *testing.T, settings NodeBiDiLinkSettings) (n *Simnet, connA, connB *SimConn) {
+	t.Helper()
+	n = &Simnet{Router: &PerfectRouter{}}
+	connA = n.NewEndpoint(&net.UDPAddr{IP: net.ParseIP("1.0.0.1"), Port: 8000}, settings)
+	connB = n.NewEndpoint(&net.UDPAddr{IP: net.ParseIP("1.0.0.2"), Port: 8000}, settings)
+	require.NoError(t, n.Start())
+	t.Cleanup(func() {
+		connA.Close()
+		connB.Close()
+		n.Close()
+	})
+	return n, connA, connB
+}
+
+// sendAndMeasure sends a 1000 byte packet from a to b, and returns the time it took to arrive,
+// or 0 if it didn't arrive within one second.
+func sendAndMeasure(t *testing.T, a, b *SimConn) time.Duration {
+	t.Helper()
+	start := time.Now()
+	_, err := a.WriteTo(make([]byte, 1000), b.UnicastAddr())
+	require.NoError(t, err)
+	b.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := b.ReadFrom(make([]byte, 1500)); err != nil {
+		require.ErrorIs(t, err, ErrDeadlineExceeded)
+		return 0
+	}
+	return time.Since(start)
+}
+
+func TestScenarioOutageAndLatencySpike(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		n, connA, connB := newTestSimnet(t, NodeBiDiLinkSettings{Latency: 10 * time.Millisecond})
+		link := n.Link(connB)
+		require.NotNil(t, link)
+
+		s := &Scenario{}
+		s.Outage(link, time.Second, 2500*time.Millisecond)
+		s.LatencySpike(link, 5*time.Second, time.Second, 100*time.Millisecond)
+		start := time.Now()
+		s.Start()
+		defer s.Stop()
+		sleepUntil := func(d time.Duration) { time.Sleep(time.Until(start.Add(d))) }
+
+		require.Equal(t, 10*time.Millisecond, sendAndMeasure(t, connA, connB))
+		// the link is down
+		sleepUntil(time.Second + time.Millisecond)
+		require.Zero(t, sendAndMeasure(t, connA, connB))
+		require.Zero(t, sendAndMeasure(t, connB, connA))
+		// the link is back up
+		sleepUntil(4 * time.Second)
+		require.Equal(t, 10*time.Millisecond, sendAndMeasure(t, connA, connB))
+		// the latency spike
+		sleepUntil(5*time.Second + time.Millisecond)
+		require.Equal(t, 100*time.Millisecond, sendAndMeasure(t, connA, connB))
+		sleepUntil(6*time.Second + time.Millisecond)
+		require.Equal(t, 10*time.Millisecond, sendAndMeasure(t, connA, connB))
+	})
+}
+
+func TestScenarioBandwidthStep(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		n, connA, connB := newTestSimnet(t, NodeBiDiLinkSettings{
+			Downlink: LinkSettings{MTU: 1500, Bandwidth: 8_000_000}, // 1 MB/s
+		})
+		s := &Scenario{}
+		s.SetBandwidth(n.Link(connB), time.Second, 800_000, 0) // 100 kB/s
+		s.Start()
+		defer s.Stop()
+
+		// serializing a 1000 byte packet takes 1ms
+		require.Equal(t, time.Millisecond, sendAndMeasure(t, connA, connB))
+		time.Sleep(time.Second)
+		// serializing a 1000 byte packet now takes 10ms
+		require.Equal(t, 10*time.Millisecond, sendAndMeasure(t, connA, connB))
+	})
+}
+
+func TestSimnetRebind(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		n, connA, connB := newTestSimnet(t, NodeBiDiLinkSettings{Latency: 10 * time.Millisecond})
+		oldAddr := connA.UnicastAddr()
+		newAddr := &net.UDPAddr{IP: net.ParseIP("1.0.0.3"), Port: 9000}
+
+		s := (&Scenario{}).Rebind(n, connA, time.Second, newAddr)
+		s.Start()
+		defer s.Stop()
+		time.Sleep(time.Second + time.Millisecond)
+
+		// the local address doesn't change
+		require.Equal(t, oldAddr, connA.LocalAddr())
+		require.Equal(t, newAddr, connA.UnicastAddr())
+
+		_, err := connA.WriteTo([]byte("foobar"), connB.UnicastAddr())
+		require.NoError(t, err)
+		_, from, err := connB.ReadFrom(make([]byte, 100))
+		require.NoError(t, err)
+		require.Equal(t, newAddr.String(), from.String())
+
+		// packets sent to the old address are dropped
+		_, err = connB.WriteTo([]byte("foobar"), oldAddr)
+		require.NoError(t, err)
+		connA.SetReadDeadline(time.Now().Add(time.Second))
+		_, _, err = connA.ReadFrom(make([]byte, 100))
+		require.ErrorIs(t, err, ErrDeadlineExceeded)
+		// packets sent to the new address are delivered
+		require.Equal(t, 10*time.Millisecond, sendAndMeasure(t, connB, connA))
+	})
+}
+
+func TestLTEHandover(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		n, connA, connB := newTestSimnet(t, ProfileLTE())
+		newAddr := &net.UDPAddr{IP: net.ParseIP("1.0.0.3"), Port: 9000}
+		s := LTEHandover(n, connA, time.Second, newAddr)
+		s.Start()
+		defer s.Stop()
+
+		time.Sleep(time.Second + time.Millisecond)
+		require.Zero(t, sendAndMeasure(t, connB, connA))
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, newAddr, connA.UnicastAddr())
+		require.NotZero(t, sendAndMeasure(t, connB, connA))
+	})
+}
@@ -191,6 +191,7 @@ func (c *SimConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 		return 0, net.ErrClosed
 	}
 	deadline := c.writeDeadline
+	from := c.myAddr
 	c.mu.Unlock()
 
 	if !deadline.IsZero() && !time.Now().Before(deadline) {
@@ -201,7 +202,7 @@ func (c *SimConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 	c.bytesSent.Add(int64(len(p)))
 
 	pkt := Packet{
-		From: c.myAddr,
+		From: from,
 		To:   addr,
 		Data: slices.Clone(p),
 	}
@@ -209,16 +210,33 @@ func (c *SimConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 }
 
 func (c *SimConn) UnicastAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.myAddr
 }
 
 func (c *SimConn) LocalAddr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.myLocalAddr != nil {
 		return c.myLocalAddr
 	}
 	return c.myAddr
 }
 
+// rebind changes the address that packets are sent from, without changing the local address.
+// This is what happens when a NAT rebinds the connection to a new external address.
+func (c *SimConn) rebind(addr *net.UDPAddr) (oldAddr *net.UDPAddr) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.myLocalAddr == nil {
+		c.myLocalAddr = c.myAddr
+	}
+	oldAddr = c.myAddr
+	c.myAddr = addr
+	return oldAddr
+}
+
 func (c *SimConn) SetDeadline(t time.Time) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -3,6 +3,7 @@ package simnet
 import (
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,6 +17,40 @@ type packetWithDeliveryTime struct {
 type LinkSettings struct {
 	// MTU (Maximum Transmission Unit) specifies the maximum packet size in bytes
 	MTU int
+
+	// Bandwidth is the capacity of the link in bits per second.
+	// Packets are queued in a bottleneck queue and serialized onto the link at this rate.
+	// If zero, the bandwidth is unlimited.
+	Bandwidth int64
+	// QueueSize is the size of the bottleneck queue in bytes.
+	// Packets that don't fit into the queue are dropped (tail drop).
+	// Only used if Bandwidth is set. If zero, the queue size is unlimited.
+	QueueSize int
+	// CoDel enables CoDel active queue management on the bottleneck queue.
+	// Only used if Bandwidth is set.
+	CoDel *CoDelSettings
+
+	// LossRate is the probability that a packet is lost, independent of other packets.
+	LossRate float64
+	// BurstLoss configures correlated packet loss using a Gilbert-Elliott model.
+	// It can be combined with LossRate.
+	BurstLoss *GilbertElliott
+	// ReorderRate is the probability that a packet is delayed by ReorderDelay,
+	// causing it to arrive after packets sent later.
+	ReorderRate float64
+	// ReorderDelay is the additional delay of reordered packets.
+	// If zero, it defaults to 10ms.
+	ReorderDelay time.Duration
+	// DuplicateRate is the probability that a packet is duplicated.
+	DuplicateRate float64
+	// Jitter is the maximum of a uniformly distributed random delay added to every packet.
+	// Jitter can cause packets to be reordered.
+	Jitter time.Duration
+
+	// Seed seeds the random number generator used for loss, reordering, duplication and jitter.
+	// Using the same seed (and the same sequence of packets) results in the same behavior,
+	// which makes tests using synctest deterministic.
+	Seed uint64
 }
 
 // SimulatedLink simulates a bidirectional network link with variable latency and MTU constraints
@@ -23,10 +58,18 @@ type SimulatedLink struct {
 	// Internal state for lifecycle management
 	wg sync.WaitGroup
 
+	// mx protects the settings, which can be changed while the link is running
+	mx   sync.Mutex
+	down atomic.Bool
+
 	// Queues for packet delivery timing
 	downstreamQueue *queue
 	upstreamQueue   *queue
 
+	// Impairments (bandwidth, loss, reordering, etc.) for each direction
+	downlink *linkDirection
+	uplink   *linkDirection
+
 	// Configuration for link characteristics
 	UplinkSettings   LinkSettings
 	DownlinkSettings LinkSettings
@@ -54,22 +97,93 @@ func (l *SimulatedLink) Start() {
 		panic("SimulatedLink.Start() called without having added a packet receiver")
 	}
 
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	// Sane defaults
-	if l.DownlinkSettings.MTU == 0 {
-		l.DownlinkSettings.MTU = 1400
-	}
-	if l.UplinkSettings.MTU == 0 {
-		l.UplinkSettings.MTU = 1400
-	}
+	setDefaultMTU(&l.DownlinkSettings)
+	setDefaultMTU(&l.UplinkSettings)
 
 	l.downstreamQueue = newQueue()
 	l.upstreamQueue = newQueue()
+	l.downlink = newLinkDirection(l.DownlinkSettings)
+	l.uplink = newLinkDirection(l.UplinkSettings)
 
 	l.wg.Add(2)
 	go l.backgroundDownlink()
 	go l.backgroundUplink()
 }
 
+func setDefaultMTU(s *LinkSettings) {
+	if s.MTU == 0 {
+		s.MTU = 1400
+	}
+}
+
+// SetDownlinkSettings changes the downlink settings.
+// It can be called while the link is running.
+// Packets that are already in flight are not affected.
+func (l *SimulatedLink) SetDownlinkSettings(s LinkSettings) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.DownlinkSettings = s
+	if l.downlink != nil {
+		setDefaultMTU(&l.DownlinkSettings)
+		l.downlink.setSettings(l.DownlinkSettings)
+	}
+}
+
+// SetUplinkSettings changes the uplink settings.
+// It can be called while the link is running.
+// Packets that are already in flight are not affected.
+func (l *SimulatedLink) SetUplinkSettings(s LinkSettings) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.UplinkSettings = s
+	if l.uplink != nil {
+		setDefaultMTU(&l.UplinkSettings)
+		l.uplink.setSettings(l.UplinkSettings)
+	}
+}
+
+// GetDownlinkSettings returns the current downlink settings.
+func (l *SimulatedLink) GetDownlinkSettings() LinkSettings {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	return l.DownlinkSettings
+}
+
+// GetUplinkSettings returns the current uplink settings.
+func (l *SimulatedLink) GetUplinkSettings() LinkSettings {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	return l.UplinkSettings
+}
+
+// SetLatency changes the downlink latency.
+// It can be called while the link is running.
+// It has no effect if LatencyFunc is set.
+func (l *SimulatedLink) SetLatency(latency time.Duration) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	l.Latency = latency
+}
+
+// GetLatency returns the current downlink latency.
+func (l *SimulatedLink) GetLatency() time.Duration {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	return l.Latency
+}
+
+// SetDown takes the link down, or brings it back up.
+// While the link is down, all packets sent in either direction are dropped.
+func (l *SimulatedLink) SetDown(down bool) {
+	l.down.Store(down)
+}
+
 func (l *SimulatedLink) Close() error {
 	l.downstreamQueue.Close()
 	l.upstreamQueue.Close()
@@ -110,41 +224,29 @@ func (l *SimulatedLink) backgroundUplink() {
 }
 
 func (l *SimulatedLink) SendPacket(p Packet) error {
-	if len(p.Data) > l.UplinkSettings.MTU {
-		// Drop packet if it's too large
+	if l.down.Load() {
 		return nil
 	}
-
-	// Uplink has no latency - packets are delivered immediately
-	deliveryTime := time.Now()
-
-	// Enqueue packet with delivery time
-	l.upstreamQueue.Enqueue(&packetWithDeliveryTime{
-		Packet:       p,
-		DeliveryTime: deliveryTime,
-	})
-
+	// Uplink has no latency - packets are delivered immediately,
+	// unless they are delayed by the uplink's impairments.
+	for _, dp := range l.uplink.schedule(p, time.Now(), 0) {
+		l.upstreamQueue.Enqueue(dp)
+	}
 	return nil
 }
 
 func (l *SimulatedLink) RecvPacket(p Packet) {
-	if len(p.Data) > l.DownlinkSettings.MTU {
-		// Drop packet if it's too large
+	if l.down.Load() {
 		return
 	}
-
 	// Calculate delivery time based on downlink latency
 	var latency time.Duration
 	if l.LatencyFunc != nil {
 		latency = l.LatencyFunc(p)
 	} else {
-		latency = l.Latency
+		latency = l.GetLatency()
+	}
+	for _, dp := range l.downlink.schedule(p, time.Now(), latency) {
+		l.downstreamQueue.Enqueue(dp)
 	}
-	deliveryTime := time.Now().Add(latency)
-
-	// Enqueue packet with delivery time
-	l.downstreamQueue.Enqueue(&packetWithDeliveryTime{
-		Packet:       p,
-		DeliveryTime: deliveryTime,
-	})
 }
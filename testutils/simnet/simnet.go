@@ -69,3 +69,33 @@ func (n *Simnet) NewEndpoint(addr *net.UDPAddr, linkSettings NodeBiDiLinkSetting
 	n.Router.AddNode(addr, link)
 	return c
 }
+
+// Link returns the link that connects the endpoint to the router.
+// It returns nil if the endpoint wasn't created by this Simnet.
+func (n *Simnet) Link(c *SimConn) *SimulatedLink {
+	for _, link := range n.links {
+		if link.downloadPacket == c {
+			return link
+		}
+	}
+	return nil
+}
+
+// Rebind changes the address of an endpoint, as seen by the router and all other endpoints.
+// The local address of the endpoint is not changed, simulating a NAT rebinding.
+// Packets sent to the old address are dropped.
+// The Router must implement RemoveNode, as PerfectRouter does.
+func (n *Simnet) Rebind(c *SimConn, addr *net.UDPAddr) error {
+	router, ok := n.Router.(interface{ RemoveNode(net.Addr) })
+	if !ok {
+		return fmt.Errorf("router %T doesn't support removing nodes", n.Router)
+	}
+	link := n.Link(c)
+	if link == nil {
+		return errors.New("unknown endpoint")
+	}
+	oldAddr := c.rebind(addr)
+	router.RemoveNode(oldAddr)
+	n.Router.AddNode(addr, link)
+	return nil
+}
@@ -1,16 +1,19 @@
 package quic
 
 import (
+	"cmp"
 	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
 	tls "github.com/nukilabs/utls"
 	"net"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/nukilabs/quic-go/internal/handshake"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/utils"
 	"github.com/nukilabs/quic-go/internal/wire"
@@ -39,7 +42,10 @@ func (e *errTransportClosed) Is(target error) bool {
 	return ok
 }
 
-var errListenerAlreadySet = errors.New("listener already set")
+var (
+	errListenerAlreadySet    = errors.New("listener already set")
+	errTransportShuttingDown = errors.New("quic: transport is shutting down")
+)
 
 type closePacket struct {
 	payload []byte
@@ -95,6 +101,26 @@ type Transport struct {
 	// see section 8.1.3 of RFC 9000 for details.
 	TokenGeneratorKey *TokenGeneratorKey
 
+	// KeySource is used to obtain the StatelessResetKey and the TokenGeneratorKey.
+	// This allows a fleet of servers to share the keys, and to rotate them without restarting.
+	// It is called when the Transport is initialized, and then every KeyRefreshInterval.
+	// Keys returned by the KeySource take precedence over the StatelessResetKey and the TokenGeneratorKey.
+	// A nil key leaves the respective key unchanged.
+	// If the initial call returns an error, initializing the Transport fails.
+	// Errors returned by subsequent calls are logged, and the current keys remain in use.
+	KeySource func() (TransportKeys, error)
+
+	// KeyRefreshInterval is the interval at which the KeySource is called.
+	// If not set, it defaults to 1 minute.
+	KeyRefreshInterval time.Duration
+
+	// KeyGracePeriod is the duration for which a key is still accepted after it was replaced
+	// by a new key obtained from the KeySource.
+	// All replaced keys are kept until their grace period expires.
+	// If not set, it defaults to the MaxTokenAge for the TokenGeneratorKey,
+	// and to 10 minutes for the StatelessResetKey.
+	KeyGracePeriod time.Duration
+
 	// MaxTokenAge is the maximum age of the resumption token presented during the handshake.
 	// These tokens allow skipping address resumption when resuming a QUIC connection,
 	// and are especially useful when using 0-RTT.
@@ -118,6 +144,30 @@ type Transport struct {
 	// implementation of this callback (negating its return value).
 	VerifySourceAddress func(net.Addr) bool
 
+	// AdmissionController decides if new connection attempts are accepted, refused,
+	// dropped, or need to go through source address validation first.
+	// It can be used to protect the server from handshake floods.
+	// The [AdmissionLimiter] implements per-source rate limiting and a limit on concurrent handshakes.
+	// If nil, all connection attempts are accepted.
+	AdmissionController AdmissionController
+
+	// ForwardPacket is called for QUIC packets with a destination connection ID that is unknown to this Transport,
+	// before the packet is passed to the listener (if any), and before a stateless reset is sent in response.
+	// It allows two Transports that share the same UDP socket (e.g. an old and a new process during
+	// a zero-downtime restart) to pass packets for each other's connections to each other.
+	// The receiving Transport injects the packet using [Transport.InjectPacket].
+	// If the callback returns true, the packet is considered handled, and won't be processed any further.
+	// The callback is called from the Transport's packet handling loop, and should therefore return quickly.
+	//
+	// Both Transports need to use connection IDs of the same length.
+	// Using a ConnectionIDGenerator that encodes the process (or generation) into the connection ID
+	// makes it easy to decide which Transport a packet belongs to.
+	ForwardPacket func(*ForwardedPacket) bool
+
+	// ShutdownErrorCode is the application error code used to close connections
+	// that are still open when the context passed to Shutdown is done.
+	ShutdownErrorCode ApplicationErrorCode
+
 	// ConnContext is called when the server accepts a new connection. To reject a connection return
 	// a non-nil error.
 	// The context is closed when the connection is closed, or when the handshake fails for any reason.
@@ -147,6 +197,7 @@ type Transport struct {
 	// If no ConnectionIDGenerator is set, this is set to a default.
 	connIDGenerator   ConnectionIDGenerator
 	statelessResetter *statelessResetter
+	tokenGenerator    *handshake.TokenGenerator
 
 	server *baseServer
 
@@ -160,6 +211,13 @@ type Transport struct {
 	createdConn bool
 	isSingleUse bool // was created for a single server or client, i.e. by calling quic.Listen or quic.Dial
 
+	// set when Shutdown is called
+	shuttingDown bool
+	// the time when the last connection that was replaced by a closed connection expires
+	closedConnsExpiry time.Time
+	// receives a notification when handlers are removed or replaced by closed connections
+	handlersChanged chan struct{}
+
 	readingNonQUICPackets atomic.Bool
 	nonQUICPackets        chan receivedPacket
 
@@ -202,6 +260,9 @@ func (t *Transport) createServer(tlsConf *tls.Config, conf *Config, allow0RTT bo
 	if t.closeErr != nil {
 		return nil, t.closeErr
 	}
+	if t.shuttingDown {
+		return nil, errTransportShuttingDown
+	}
 	if t.server != nil {
 		return nil, errListenerAlreadySet
 	}
@@ -209,10 +270,6 @@ func (t *Transport) createServer(tlsConf *tls.Config, conf *Config, allow0RTT bo
 	if err := t.init(false); err != nil {
 		return nil, err
 	}
-	maxTokenAge := t.MaxTokenAge
-	if maxTokenAge == 0 {
-		maxTokenAge = 24 * time.Hour
-	}
 	s := newServer(
 		t.conn,
 		(*packetHandlerMap)(t),
@@ -223,9 +280,10 @@ func (t *Transport) createServer(tlsConf *tls.Config, conf *Config, allow0RTT bo
 		conf,
 		t.Tracer,
 		t.closeServer,
-		*t.TokenGeneratorKey,
-		maxTokenAge,
+		t.tokenGenerator,
+		t.maxTokenAge(),
 		t.VerifySourceAddress,
+		t.AdmissionController,
 		t.DisableVersionNegotiationPackets,
 		allow0RTT,
 	)
@@ -288,6 +346,10 @@ func (t *Transport) doDial(
 		t.mutex.Unlock()
 		return nil, t.closeErr
 	}
+	if t.shuttingDown {
+		t.mutex.Unlock()
+		return nil, errTransportShuttingDown
+	}
 
 	var qlogTrace qlogwriter.Trace
 	if config.Tracer != nil {
@@ -386,14 +448,20 @@ func (t *Transport) init(allowZeroLengthConnIDs bool) error {
 			}
 		}
 
-		t.logger = utils.DefaultLogger // TODO: make this configurable
-		t.conn = conn
-		t.handlers = make(map[protocol.ConnectionID]packetHandler)
-		t.resetTokens = make(map[protocol.StatelessResetToken]packetHandler)
-		t.listening = make(chan struct{})
-
-		t.closeQueue = make(chan closePacket, 4)
-		t.statelessResetQueue = make(chan receivedPacket, 4)
+		statelessResetKey := t.StatelessResetKey
+		if t.KeySource != nil {
+			keys, err := t.KeySource()
+			if err != nil {
+				t.initErr = fmt.Errorf("quic: obtaining keys failed: %w", err)
+				return
+			}
+			if keys.StatelessResetKey != nil {
+				statelessResetKey = keys.StatelessResetKey
+			}
+			if keys.TokenGeneratorKey != nil {
+				t.TokenGeneratorKey = keys.TokenGeneratorKey
+			}
+		}
 		if t.TokenGeneratorKey == nil {
 			var key TokenGeneratorKey
 			if _, err := rand.Read(key[:]); err != nil {
@@ -402,7 +470,17 @@ func (t *Transport) init(allowZeroLengthConnIDs bool) error {
 			}
 			t.TokenGeneratorKey = &key
 		}
+		t.tokenGenerator = handshake.NewTokenGenerator(*t.TokenGeneratorKey)
 
+		t.logger = utils.DefaultLogger // TODO: make this configurable
+		t.conn = conn
+		t.handlers = make(map[protocol.ConnectionID]packetHandler)
+		t.resetTokens = make(map[protocol.StatelessResetToken]packetHandler)
+		t.listening = make(chan struct{})
+		t.handlersChanged = make(chan struct{}, 1)
+
+		t.closeQueue = make(chan closePacket, 4)
+		t.statelessResetQueue = make(chan receivedPacket, 4)
 		if t.ConnectionIDGenerator != nil {
 			t.connIDGenerator = t.ConnectionIDGenerator
 			t.connIDLen = t.ConnectionIDGenerator.ConnectionIDLen()
@@ -414,7 +492,7 @@ func (t *Transport) init(allowZeroLengthConnIDs bool) error {
 			t.connIDLen = connIDLen
 			t.connIDGenerator = &protocol.DefaultConnectionIDGenerator{ConnLen: t.connIDLen}
 		}
-		t.statelessResetter = newStatelessResetter(t.StatelessResetKey)
+		t.statelessResetter = newStatelessResetter(statelessResetKey)
 
 		go func() {
 			defer close(t.listening)
@@ -425,6 +503,9 @@ func (t *Transport) init(allowZeroLengthConnIDs bool) error {
 			}
 		}()
 		go t.runSendQueue()
+		if t.KeySource != nil {
+			go t.runKeyRefresh()
+		}
 	})
 	return t.initErr
 }
@@ -450,6 +531,75 @@ func (t *Transport) runSendQueue() {
 	}
 }
 
+func (t *Transport) maxTokenAge() time.Duration {
+	if t.MaxTokenAge == 0 {
+		return 24 * time.Hour
+	}
+	return t.MaxTokenAge
+}
+
+func (t *Transport) runKeyRefresh() {
+	interval := t.KeyRefreshInterval
+	if interval == 0 {
+		interval = time.Minute
+	}
+	resetKeyGracePeriod := t.KeyGracePeriod
+	tokenKeyGracePeriod := t.KeyGracePeriod
+	if t.KeyGracePeriod == 0 {
+		resetKeyGracePeriod = defaultStatelessResetKeyGracePeriod
+		tokenKeyGracePeriod = t.maxTokenAge()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.listening:
+			return
+		case <-ticker.C:
+			keys, err := t.KeySource()
+			if err != nil {
+				t.logger.Errorf("Obtaining keys failed: %s", err)
+				continue
+			}
+			if keys.StatelessResetKey != nil {
+				t.statelessResetter.setKey(*keys.StatelessResetKey, resetKeyGracePeriod)
+			}
+			if keys.TokenGeneratorKey != nil {
+				t.tokenGenerator.SetKey(*keys.TokenGeneratorKey, tokenKeyGracePeriod)
+			}
+		}
+	}
+}
+
+// RotateStatelessResetKey replaces the key used to generate stateless reset tokens.
+// Connection IDs issued from now on use stateless reset tokens derived from the new key.
+// For the duration of the grace period, stateless resets for connection IDs that were issued
+// before the key was rotated are sent using the previous key.
+// All replaced keys are kept until their grace period expires.
+// If no StatelessResetKey was configured, this enables the sending of stateless resets.
+// The StatelessResetKey field is not modified.
+func (t *Transport) RotateStatelessResetKey(key StatelessResetKey, gracePeriod time.Duration) error {
+	if err := t.init(false); err != nil {
+		return err
+	}
+	t.statelessResetter.setKey(key, gracePeriod)
+	return nil
+}
+
+// RotateTokenGeneratorKey replaces the key used to encrypt Retry and session resumption tokens.
+// For the duration of the grace period, tokens encrypted with the previous key are still accepted.
+// To accept all resumption tokens that were issued before the rotation,
+// the grace period should be at least the MaxTokenAge.
+// The TokenGeneratorKey field is not modified.
+func (t *Transport) RotateTokenGeneratorKey(key TokenGeneratorKey, gracePeriod time.Duration) error {
+	if err := t.init(false); err != nil {
+		return err
+	}
+	t.tokenGenerator.SetKey(key, gracePeriod)
+	return nil
+}
+
 // Close stops listening for UDP datagrams on the Transport.Conn.
 // It abruptly terminates all existing connections, without sending a CONNECTION_CLOSE
 // to the peers. It is the application's responsibility to cleanly terminate existing
@@ -476,6 +626,179 @@ func (t *Transport) Close() error {
 	return nil
 }
 
+// maxShutdownDrainPeriod is the maximum time that Shutdown waits for connections
+// that were closed after the context was done to expire.
+const maxShutdownDrainPeriod = 3 * time.Second
+
+// Shutdown gracefully shuts down the Transport.
+// It closes the listener (if any), and stops dialing new connections.
+// Handshakes that are already in flight are completed, and the resulting connections
+// can still be accepted from the listener.
+//
+// Shutdown then waits for all existing connections to be closed.
+// Afterwards, the Transport keeps handling packets for the closed connections, retransmitting
+// CONNECTION_CLOSE frames and sending stateless resets (if a StatelessResetKey is configured),
+// until the closed connections' IDs have expired or the context is done.
+// Finally, the Transport is closed.
+//
+// Once the context is done, the remaining connections are closed with the ShutdownErrorCode.
+// The Transport then keeps handling packets for these connections for a drain period
+// of three times the Probe Timeout (PTO), such that lost CONNECTION_CLOSE frames are retransmitted.
+// Shutdown returns after this drain period, which is limited to a few seconds.
+//
+// If connections had to be closed because the context was done, the context's error is returned.
+func (t *Transport) Shutdown(ctx context.Context) error {
+	if err := t.init(false); err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	t.shuttingDown = true
+	server := t.server
+	t.mutex.Unlock()
+	if server != nil {
+		serverClosed := make(chan struct{})
+		go func() {
+			defer close(serverClosed)
+			server.shutdown()
+		}()
+		defer func() { <-serverClosed }()
+	}
+
+	var err error
+	if conns := t.waitForConnsToClose(ctx); len(conns) > 0 {
+		err = context.Cause(ctx)
+		t.logger.Debugf("Closing %d connections after shutdown timeout.", len(conns))
+		var wg sync.WaitGroup
+		for _, conn := range conns {
+			wg.Add(1)
+			go func(conn packetHandler) {
+				defer wg.Done()
+				if c, ok := conn.(interface {
+					CloseWithError(ApplicationErrorCode, string) error
+				}); ok {
+					c.CloseWithError(t.ShutdownErrorCode, "shutdown")
+				} else {
+					conn.closeWithTransportError(NoError)
+				}
+			}(conn)
+		}
+		wg.Wait()
+		// The closed connections expire after the drain period.
+		t.mutex.Lock()
+		drainDeadline := t.closedConnsExpiry
+		t.mutex.Unlock()
+		if maxDeadline := time.Now().Add(maxShutdownDrainPeriod); drainDeadline.After(maxDeadline) {
+			drainDeadline = maxDeadline
+		}
+		drainCtx, cancel := context.WithDeadline(context.Background(), drainDeadline)
+		t.waitForClosedConnsToExpire(drainCtx)
+		cancel()
+	} else {
+		t.waitForClosedConnsToExpire(ctx)
+	}
+	if cerr := t.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// waitForConnsToClose waits until all connections are closed, or until the context is done.
+// It returns the connections that are still open.
+func (t *Transport) waitForConnsToClose(ctx context.Context) []packetHandler {
+	for {
+		t.mutex.Lock()
+		conns := t.activeHandlers()
+		closed := t.closeErr != nil
+		t.mutex.Unlock()
+		if len(conns) == 0 || closed {
+			return nil
+		}
+		select {
+		case <-t.handlersChanged:
+		case <-ctx.Done():
+			return conns
+		}
+	}
+}
+
+// waitForClosedConnsToExpire waits until the connection IDs of all closed connections have expired,
+// or until the context is done.
+func (t *Transport) waitForClosedConnsToExpire(ctx context.Context) {
+	for {
+		t.mutex.Lock()
+		done := len(t.handlers) == 0 || t.closeErr != nil
+		t.mutex.Unlock()
+		if done {
+			return
+		}
+		select {
+		case <-t.handlersChanged:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Conns returns the connections handled by the Transport that haven't been closed yet,
+// ordered by their [ConnectionTracingID].
+// This includes connections that are still handshaking, as well as connections
+// that use the Transport for a path added using [Conn.AddPath].
+func (t *Transport) Conns() []*Conn {
+	t.mutex.Lock()
+	handlers := t.activeHandlers()
+	t.mutex.Unlock()
+
+	conns := make([]*Conn, 0, len(handlers))
+	seen := make(map[*Conn]struct{}, len(handlers))
+	for _, h := range handlers {
+		var conn *Conn
+		switch h := h.(type) {
+		case *Conn:
+			conn = h
+		case *wrappedConn:
+			conn = h.Conn
+		case *pathPacketHandler:
+			conn = h.Conn
+		default:
+			continue
+		}
+		if _, ok := seen[conn]; ok {
+			continue
+		}
+		seen[conn] = struct{}{}
+		conns = append(conns, conn)
+	}
+	slices.SortFunc(conns, func(a, b *Conn) int { return cmp.Compare(a.tracingID, b.tracingID) })
+	return conns
+}
+
+// activeHandlers returns all connections that haven't been closed yet.
+// The Transport mutex must be held when calling this method.
+func (t *Transport) activeHandlers() []packetHandler {
+	var conns []packetHandler
+	seen := make(map[packetHandler]struct{}, len(t.handlers))
+	for _, h := range t.handlers {
+		switch h.(type) {
+		case *closedLocalConn, *closedRemoteConn:
+			continue
+		}
+		if _, ok := seen[h]; ok {
+			continue
+		}
+		seen[h] = struct{}{}
+		conns = append(conns, h)
+	}
+	return conns
+}
+
+func (t *Transport) notifyHandlersChanged() {
+	select {
+	case t.handlersChanged <- struct{}{}:
+	default:
+	}
+}
+
 func (t *Transport) closeServer() {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
@@ -564,6 +887,10 @@ func (t *Transport) maybeStopListening() {
 }
 
 func (t *Transport) handlePacket(p receivedPacket) {
+	t.handlePacketImpl(p, true)
+}
+
+func (t *Transport) handlePacketImpl(p receivedPacket, allowForwarding bool) {
 	if len(p.data) == 0 {
 		return
 	}
@@ -599,6 +926,9 @@ func (t *Transport) handlePacket(p receivedPacket) {
 	if isStatelessReset := t.maybeHandleStatelessReset(p.data); isStatelessReset {
 		return
 	}
+	if allowForwarding && t.maybeForwardPacket(p, connID) {
+		return
+	}
 	if !wire.IsLongHeaderPacket(p.data[0]) {
 		if statelessResetQueued := t.maybeSendStatelessReset(p); !statelessResetQueued {
 			if t.Tracer != nil {
@@ -630,7 +960,7 @@ func (t *Transport) handlePacket(p receivedPacket) {
 }
 
 func (t *Transport) maybeSendStatelessReset(p receivedPacket) (statelessResetQueued bool) {
-	if t.StatelessResetKey == nil {
+	if !t.statelessResetter.CanSendStatelessResets() {
 		return false
 	}
 
@@ -657,7 +987,7 @@ func (t *Transport) sendStatelessReset(p receivedPacket) {
 		t.logger.Errorf("error parsing connection ID on packet from %s: %s", p.remoteAddr, err)
 		return
 	}
-	token := t.statelessResetter.GetStatelessResetToken(connID)
+	token := t.statelessResetter.StatelessResetTokenToSend(connID)
 	t.logger.Debugf("Sending stateless reset to %s (connection ID: %s). Token: %#x", p.remoteAddr, connID, token)
 	data := make([]byte, protocol.MinStatelessResetSize-16, protocol.MinStatelessResetSize)
 	rand.Read(data)
@@ -804,6 +1134,7 @@ func (h *packetHandlerMap) AddWithConnID(clientDestConnID, newConnID protocol.Co
 func (h *packetHandlerMap) Remove(id protocol.ConnectionID) {
 	h.mutex.Lock()
 	delete(h.handlers, id)
+	(*Transport)(h).notifyHandlersChanged()
 	h.mutex.Unlock()
 	h.logger.Debugf("Removing connection ID %s.", id)
 }
@@ -834,6 +1165,10 @@ func (h *packetHandlerMap) ReplaceWithClosed(ids []protocol.ConnectionID, connCl
 	for _, id := range ids {
 		h.handlers[id] = handler
 	}
+	if e := time.Now().Add(expiry); e.After(h.closedConnsExpiry) {
+		h.closedConnsExpiry = e
+	}
+	(*Transport)(h).notifyHandlersChanged()
 	h.mutex.Unlock()
 	h.logger.Debugf("Replacing connection for connection IDs %s with a closed connection.", ids)
 
@@ -842,8 +1177,9 @@ func (h *packetHandlerMap) ReplaceWithClosed(ids []protocol.ConnectionID, connCl
 		for _, id := range ids {
 			delete(h.handlers, id)
 		}
+		t := (*Transport)(h)
+		t.notifyHandlersChanged()
 		if len(h.handlers) == 0 {
-			t := (*Transport)(h)
 			t.maybeStopListening()
 		}
 		h.mutex.Unlock()
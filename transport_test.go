@@ -7,13 +7,17 @@ import (
 	tls "github.com/nukilabs/utls"
 	"math"
 	"net"
+	"net/netip"
 	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
 
+	"github.com/nukilabs/quic-go/internal/handshake"
 	"github.com/nukilabs/quic-go/internal/protocol"
 	"github.com/nukilabs/quic-go/internal/qerr"
 	"github.com/nukilabs/quic-go/internal/synctest"
@@ -120,6 +124,29 @@ func TestTransportPacketHandling(t *testing.T) {
 	}
 }
 
+func TestTransportConns(t *testing.T) {
+	tr := &Transport{Conn: newUDPConnLocalhost(t)}
+	tr.init(true)
+	defer tr.Close()
+	require.Empty(t, tr.Conns())
+
+	conn1 := &Conn{tracingID: 1}
+	conn2 := &Conn{tracingID: 2}
+	m := (*packetHandlerMap)(tr)
+	require.True(t, m.Add(protocol.ParseConnectionID([]byte{1}), conn2))
+	require.True(t, m.Add(protocol.ParseConnectionID([]byte{2}), conn2))
+	require.True(t, m.Add(protocol.ParseConnectionID([]byte{3}), &wrappedConn{Conn: conn1}))
+	require.True(t, m.Add(protocol.ParseConnectionID([]byte{4}), &pathPacketHandler{Conn: conn1}))
+	require.True(t, m.Add(protocol.ParseConnectionID([]byte{5}), &mockPacketHandler{}))
+	require.Equal(t, []*Conn{conn1, conn2}, tr.Conns())
+
+	// closed connections are not returned
+	m.ReplaceWithClosed([]protocol.ConnectionID{protocol.ParseConnectionID([]byte{1}), protocol.ParseConnectionID([]byte{2})}, nil, time.Hour)
+	require.Equal(t, []*Conn{conn1}, tr.Conns())
+	m.ReplaceWithClosed([]protocol.ConnectionID{protocol.ParseConnectionID([]byte{3}), protocol.ParseConnectionID([]byte{4})}, nil, time.Hour)
+	require.Empty(t, tr.Conns())
+}
+
 func TestTransportAndListenerConcurrentClose(t *testing.T) {
 	tr := &Transport{Conn: newUDPConnLocalhost(t)}
 	ln, err := tr.Listen(&tls.Config{}, nil)
@@ -282,6 +309,279 @@ func TestTransportStatelessResetSending(t *testing.T) {
 	})
 }
 
+func TestTransportForwardPacket(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 10 * time.Millisecond
+		clientConn, serverConn, closeFn := newSimnetLink(t, rtt)
+		defer closeFn()
+
+		forwarded := make(chan *ForwardedPacket, 2)
+		tr := &Transport{
+			Conn:               serverConn,
+			ConnectionIDLength: 4,
+			StatelessResetKey:  &StatelessResetKey{1, 2, 3, 4},
+			ForwardPacket: func(p *ForwardedPacket) bool {
+				p.Data = slices.Clone(p.Data)
+				forwarded <- p
+				return true
+			},
+		}
+		tr.init(true)
+		defer tr.Close()
+
+		connID := protocol.ParseConnectionID([]byte{9, 10, 11, 12})
+		b, err := wire.AppendShortHeader(nil, connID, 1337, 2, protocol.KeyPhaseOne)
+		require.NoError(t, err)
+		b = append(b, make([]byte, protocol.MinStatelessResetSize)...)
+		_, err = clientConn.WriteTo(b, tr.Conn.LocalAddr())
+		require.NoError(t, err)
+
+		time.Sleep(rtt) // so that the packet arrives at the server
+
+		select {
+		case p := <-forwarded:
+			require.Equal(t, b, p.Data)
+			require.Equal(t, clientConn.LocalAddr(), p.RemoteAddr)
+			require.Equal(t, connID, p.DestConnectionID)
+			require.False(t, p.IsLongHeader)
+		default:
+			t.Fatal("packet should have been forwarded")
+		}
+		// no stateless reset is sent for forwarded packets
+		clientConn.SetReadDeadline(time.Now().Add(time.Second))
+		_, _, err = clientConn.ReadFrom(make([]byte, 1024))
+		require.ErrorIs(t, err, simnet.ErrDeadlineExceeded)
+
+		// injected packets are never forwarded
+		require.NoError(t, tr.InjectPacket(&ForwardedPacket{Data: b, RemoteAddr: clientConn.LocalAddr()}))
+		require.Empty(t, forwarded)
+		clientConn.SetReadDeadline(time.Now().Add(time.Second))
+		p := make([]byte, 1024)
+		n, _, err := clientConn.ReadFrom(p)
+		require.NoError(t, err)
+		srt := newStatelessResetter(tr.StatelessResetKey).GetStatelessResetToken(connID)
+		require.Contains(t, string(p[:n]), string(srt[:]))
+	})
+}
+
+func TestTransportForwardPacketLocalAddr(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only Linux allows sending from any address in 127.0.0.0/8")
+	}
+
+	udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	require.NoError(t, err)
+	forwarded := make(chan *ForwardedPacket, 1)
+	tr := &Transport{
+		Conn:               udpConn,
+		ConnectionIDLength: 4,
+		StatelessResetKey:  &StatelessResetKey{1, 2, 3, 4},
+		ForwardPacket: func(p *ForwardedPacket) bool {
+			p.Data = slices.Clone(p.Data)
+			forwarded <- p
+			return true
+		},
+	}
+	require.NoError(t, tr.init(true))
+	defer tr.Close()
+	port := udpConn.LocalAddr().(*net.UDPAddr).Port
+
+	clientConn := newUDPConnLocalhost(t)
+	connID := protocol.ParseConnectionID([]byte{9, 10, 11, 12})
+	b, err := wire.AppendShortHeader(nil, connID, 1337, 2, protocol.KeyPhaseOne)
+	require.NoError(t, err)
+	b = append(b, make([]byte, protocol.MinStatelessResetSize)...)
+	_, err = clientConn.WriteTo(b, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	require.NoError(t, err)
+
+	var p *ForwardedPacket
+	select {
+	case p = <-forwarded:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the packet to be forwarded")
+	}
+	require.Equal(t, netip.AddrFrom4([4]byte{127, 0, 0, 1}), p.LocalAddr)
+
+	// the response to an injected packet is sent from the packet's local address
+	p.LocalAddr = netip.AddrFrom4([4]byte{127, 0, 0, 2})
+	require.NoError(t, tr.InjectPacket(p))
+	clientConn.SetReadDeadline(time.Now().Add(time.Second))
+	n, addr, err := clientConn.ReadFrom(make([]byte, 1024))
+	require.NoError(t, err)
+	require.NotZero(t, n)
+	require.Equal(t, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2).To4(), Port: port}, addr)
+}
+
+func TestTransportStatelessResetKeyRotation(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const rtt = 10 * time.Millisecond
+		clientConn, serverConn, closeFn := newSimnetLink(t, rtt)
+		defer closeFn()
+
+		key1 := StatelessResetKey{1, 2, 3, 4}
+		key2 := StatelessResetKey{5, 6, 7, 8}
+		tr := &Transport{
+			Conn:               serverConn,
+			ConnectionIDLength: 4,
+			StatelessResetKey:  &key1,
+		}
+		require.NoError(t, tr.init(true))
+		defer tr.Close()
+		// connID1 was issued before the key was rotated, connID2 after
+		connID1 := protocol.ParseConnectionID([]byte{9, 10, 11, 12})
+		connID2 := protocol.ParseConnectionID([]byte{13, 14, 15, 16})
+		token1 := tr.statelessResetter.GetStatelessResetToken(connID1)
+		require.NoError(t, tr.RotateStatelessResetKey(key2, time.Minute))
+		token2 := tr.statelessResetter.GetStatelessResetToken(connID2)
+		require.Equal(t, newStatelessResetter(&key1).GetStatelessResetToken(connID1), token1)
+		require.Equal(t, newStatelessResetter(&key2).GetStatelessResetToken(connID2), token2)
+
+		receiveResets := func(connID protocol.ConnectionID) []protocol.StatelessResetToken {
+			b, err := wire.AppendShortHeader(nil, connID, 1337, 2, protocol.KeyPhaseOne)
+			require.NoError(t, err)
+			b = append(b, make([]byte, protocol.MinStatelessResetSize-len(b)+1)...)
+			_, err = clientConn.WriteTo(b, tr.Conn.LocalAddr())
+			require.NoError(t, err)
+
+			var tokens []protocol.StatelessResetToken
+			clientConn.SetReadDeadline(time.Now().Add(time.Second))
+			for {
+				p := make([]byte, 1024)
+				n, _, err := clientConn.ReadFrom(p)
+				if err != nil {
+					return tokens
+				}
+				tokens = append(tokens, protocol.StatelessResetToken(p[n-16:n]))
+			}
+		}
+
+		// During the grace period, stateless resets are sent using the key that issued the connection ID.
+		// Only a single stateless reset is sent per packet.
+		for range 2 {
+			require.Equal(t, []protocol.StatelessResetToken{token1}, receiveResets(connID1))
+			require.Equal(t, []protocol.StatelessResetToken{token2}, receiveResets(connID2))
+		}
+
+		time.Sleep(time.Minute)
+		require.Equal(t,
+			[]protocol.StatelessResetToken{newStatelessResetter(&key2).GetStatelessResetToken(connID1)},
+			receiveResets(connID1),
+		)
+	})
+}
+
+func TestTransportStatelessResetKeyRotationEnablesResets(t *testing.T) {
+	tr := &Transport{Conn: newUDPConnLocalhost(t)}
+	defer tr.Close()
+	require.NoError(t, tr.init(true))
+	require.False(t, tr.statelessResetter.CanSendStatelessResets())
+	require.NoError(t, tr.RotateStatelessResetKey(StatelessResetKey{1, 2, 3}, time.Minute))
+	require.True(t, tr.statelessResetter.CanSendStatelessResets())
+}
+
+func TestTransportTokenGeneratorKeyRotation(t *testing.T) {
+	key1 := TokenGeneratorKey{1, 2, 3, 4}
+	tr := &Transport{Conn: newUDPConnLocalhost(t), TokenGeneratorKey: &key1}
+	defer tr.Close()
+	require.NoError(t, tr.init(true))
+
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+	token1, err := tr.tokenGenerator.NewToken(addr, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, tr.RotateTokenGeneratorKey(TokenGeneratorKey{5, 6, 7, 8}, time.Minute))
+	token2, err := tr.tokenGenerator.NewToken(addr, time.Second)
+	require.NoError(t, err)
+
+	// the previous key is still accepted ...
+	_, err = tr.tokenGenerator.DecodeToken(token1)
+	require.NoError(t, err)
+	// ... but new tokens are encrypted using the new key
+	_, err = handshake.NewTokenGenerator(key1).DecodeToken(token2)
+	require.Error(t, err)
+	_, err = handshake.NewTokenGenerator(TokenGeneratorKey{5, 6, 7, 8}).DecodeToken(token2)
+	require.NoError(t, err)
+}
+
+func TestTransportKeySource(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		_, serverConn, closeFn := newSimnetLink(t, 10*time.Millisecond)
+		defer closeFn()
+
+		var mx sync.Mutex
+		var calls int
+		keys := TransportKeys{
+			StatelessResetKey: &StatelessResetKey{1, 2, 3, 4},
+			TokenGeneratorKey: &TokenGeneratorKey{1, 2, 3, 4},
+		}
+		tr := &Transport{
+			Conn: serverConn,
+			KeySource: func() (TransportKeys, error) {
+				mx.Lock()
+				defer mx.Unlock()
+				calls++
+				return keys, nil
+			},
+			KeyRefreshInterval: 10 * time.Second,
+			KeyGracePeriod:     time.Minute,
+		}
+		require.NoError(t, tr.init(true))
+		defer tr.Close()
+
+		connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+		addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+		require.True(t, tr.statelessResetter.CanSendStatelessResets())
+		require.Equal(t,
+			newStatelessResetter(&StatelessResetKey{1, 2, 3, 4}).GetStatelessResetToken(connID),
+			tr.statelessResetter.GetStatelessResetToken(connID),
+		)
+		token1, err := tr.tokenGenerator.NewToken(addr, time.Second)
+		require.NoError(t, err)
+		_, err = handshake.NewTokenGenerator(TokenGeneratorKey{1, 2, 3, 4}).DecodeToken(token1)
+		require.NoError(t, err)
+
+		mx.Lock()
+		require.Equal(t, 1, calls)
+		keys = TransportKeys{
+			StatelessResetKey: &StatelessResetKey{5, 6, 7, 8},
+			TokenGeneratorKey: &TokenGeneratorKey{5, 6, 7, 8},
+		}
+		mx.Unlock()
+
+		time.Sleep(10 * time.Second)
+		synctest.Wait()
+		mx.Lock()
+		require.Equal(t, 2, calls)
+		mx.Unlock()
+		require.Equal(t,
+			newStatelessResetter(&StatelessResetKey{5, 6, 7, 8}).GetStatelessResetToken(connID),
+			tr.statelessResetter.GetStatelessResetToken(connID),
+		)
+		require.Len(t, tr.statelessResetter.previous, 1)
+		_, err = tr.tokenGenerator.DecodeToken(token1)
+		require.NoError(t, err)
+
+		// after the grace period, the previous keys are not accepted anymore
+		time.Sleep(time.Minute)
+		require.Equal(t,
+			newStatelessResetter(&StatelessResetKey{5, 6, 7, 8}).GetStatelessResetToken(connID),
+			tr.statelessResetter.StatelessResetTokenToSend(connID),
+		)
+		require.Empty(t, tr.statelessResetter.previous)
+		_, err = tr.tokenGenerator.DecodeToken(token1)
+		require.Error(t, err)
+	})
+}
+
+func TestTransportKeySourceError(t *testing.T) {
+	tr := &Transport{
+		Conn:      newUDPConnLocalhost(t),
+		KeySource: func() (TransportKeys, error) { return TransportKeys{}, errors.New("test error") },
+	}
+	defer tr.Close()
+	_, err := tr.Listen(&tls.Config{}, nil)
+	require.ErrorContains(t, err, "test error")
+}
+
 func TestTransportUnparseableQUICPackets(t *testing.T) {
 	synctest.Test(t, func(t *testing.T) {
 		const rtt = 10 * time.Millisecond